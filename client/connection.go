@@ -14,6 +14,9 @@ import (
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/oci"
+	"github.com/lxc/lxd/shared/oidc"
+	"github.com/lxc/lxd/shared/osarch"
 	"github.com/lxc/lxd/shared/simplestreams"
 )
 
@@ -40,6 +43,13 @@ type ConnectionArgs struct {
 	// Authentication interactor
 	AuthInteractor []httpbakery.Interactor
 
+	// OpenID Connect tokens (refreshed in place when the access token expires)
+	OIDCTokens *oidc.Tokens
+
+	// Function called with each request before it is sent, e.g. to add headers
+	// derived from the request context
+	RequestHook func(*http.Request)
+
 	// Custom proxy
 	Proxy func(*http.Request) (*url.URL, error)
 
@@ -237,6 +247,46 @@ func ConnectSimpleStreams(url string, args *ConnectionArgs) (ImageServer, error)
 	return &server, nil
 }
 
+// ConnectOCI lets you connect to a remote OCI (Docker) image registry over HTTPs.
+//
+// Unless the remote server is trusted by the system CA, the remote certificate must be provided (TLSServerCert).
+func ConnectOCI(url string, args *ConnectionArgs) (ImageServer, error) {
+	logger.Debugf("Connecting to a remote OCI registry")
+
+	// Cleanup URL
+	url = strings.TrimSuffix(url, "/")
+
+	// Use empty args if not specified
+	if args == nil {
+		args = &ConnectionArgs{}
+	}
+
+	// Initialize the client struct
+	server := ProtocolOCI{
+		httpHost:        url,
+		httpUserAgent:   args.UserAgent,
+		httpCertificate: args.TLSServerCert,
+	}
+
+	// Setup the HTTP client
+	httpClient, err := tlsHTTPClient(args.HTTPClient, args.TLSClientCert, args.TLSClientKey, args.TLSCA, args.TLSServerCert, args.InsecureSkipVerify, args.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	server.http = httpClient
+
+	// Images are looked up for the local architecture
+	server.architecture, err = osarch.ArchitectureGetLocal()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get OCI registry client
+	server.ociClient = oci.NewClient(url, *httpClient, args.UserAgent)
+
+	return &server, nil
+}
+
 // Internal function called by ConnectLXD and ConnectPublicLXD
 func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
 	// Use empty args if not specified
@@ -251,6 +301,7 @@ func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
 		httpProtocol:     "https",
 		httpUserAgent:    args.UserAgent,
 		bakeryInteractor: args.AuthInteractor,
+		requestHook:      args.RequestHook,
 		chConnected:      make(chan struct{}, 1),
 	}
 
@@ -258,6 +309,11 @@ func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
 		server.RequireAuthenticated(true)
 	}
 
+	if args.AuthType == "oidc" && args.OIDCTokens != nil {
+		server.oidcAuth = &oidcAuth{tokens: args.OIDCTokens}
+		server.RequireAuthenticated(true)
+	}
+
 	// Setup the HTTP client
 	httpClient, err := tlsHTTPClient(args.HTTPClient, args.TLSClientCert, args.TLSClientKey, args.TLSCA, args.TLSServerCert, args.InsecureSkipVerify, args.Proxy)
 	if err != nil {
@@ -1,8 +1,10 @@
 package lxd
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -67,18 +69,21 @@ type InstanceServer interface {
 	// Server functions
 	GetServer() (server *api.Server, ETag string, err error)
 	GetServerResources() (resources *api.Resources, err error)
+	GetServerHealth() (health *api.Health, err error)
 	UpdateServer(server api.ServerPut, ETag string) (err error)
 	HasExtension(extension string) (exists bool)
 	RequireAuthenticated(authenticated bool)
 	IsClustered() (clustered bool)
 	UseTarget(name string) (client InstanceServer)
 	UseProject(name string) (client InstanceServer)
+	UseContext(ctx context.Context) (client InstanceServer)
 
 	// Certificate functions
 	GetCertificateFingerprints() (fingerprints []string, err error)
 	GetCertificates() (certificates []api.Certificate, err error)
 	GetCertificate(fingerprint string) (certificate *api.Certificate, ETag string, err error)
 	CreateCertificate(certificate api.CertificatesPost) (err error)
+	CreateCertificateToken(certificate api.CertificatesPost) (op Operation, err error)
 	UpdateCertificate(fingerprint string, certificate api.CertificatePut, ETag string) (err error)
 	DeleteCertificate(fingerprint string) (err error)
 
@@ -181,6 +186,7 @@ type InstanceServer interface {
 	CreateInstanceFromBackup(args InstanceBackupArgs) (op Operation, err error)
 
 	GetInstanceState(name string) (state *api.InstanceState, ETag string, err error)
+	GetInstanceStateHistory(name string, since time.Time) (samples []api.InstanceUsageSample, err error)
 	UpdateInstanceState(name string, state api.InstanceStatePut, ETag string) (op Operation, err error)
 
 	GetInstanceLogfiles(name string) (logfiles []string, err error)
@@ -198,6 +204,7 @@ type InstanceServer interface {
 
 	// Event handling functions
 	GetEvents() (listener *EventListener, err error)
+	GetEventsWithFilter(filter EventsFilter) (listener *EventListener, err error)
 
 	// Image functions
 	CreateImage(image api.ImagesPost, args *ImageCreateArgs) (op Operation, err error)
@@ -225,6 +232,7 @@ type InstanceServer interface {
 	// Operation functions
 	GetOperationUUIDs() (uuids []string, err error)
 	GetOperations() (operations []api.Operation, err error)
+	GetOperationsHistory(filter OperationsHistoryFilter) (operations []api.Operation, err error)
 	GetOperation(uuid string) (op *api.Operation, ETag string, err error)
 	GetOperationWait(uuid string, timeout int) (op *api.Operation, ETag string, err error)
 	GetOperationWebsocket(uuid string, secret string) (conn *websocket.Conn, err error)
@@ -256,6 +264,7 @@ type InstanceServer interface {
 	CreateStoragePool(pool api.StoragePoolsPost) (err error)
 	UpdateStoragePool(name string, pool api.StoragePoolPut, ETag string) (err error)
 	DeleteStoragePool(name string) (err error)
+	MoveStoragePoolVolumes(name string, pool api.StoragePoolPost) (op Operation, err error)
 
 	// Storage volume functions ("storage" API extension)
 	GetStoragePoolVolumeNames(pool string) (names []string, err error)
@@ -278,6 +287,19 @@ type InstanceServer interface {
 	RenameStoragePoolVolumeSnapshot(pool string, volumeType string, volumeName string, snapshotName string, snapshot api.StorageVolumeSnapshotPost) (op Operation, err error)
 	UpdateStoragePoolVolumeSnapshot(pool string, volumeType string, volumeName string, snapshotName string, volume api.StorageVolumeSnapshotPut, ETag string) (err error)
 
+	// Storage volume backup functions ("custom_volume_backup" API extension)
+	GetStoragePoolVolumeBackupNames(pool string, volName string) (names []string, err error)
+	GetStoragePoolVolumeBackups(pool string, volName string) (backups []api.StoragePoolVolumeBackup, err error)
+	GetStoragePoolVolumeBackup(pool string, volName string, name string) (backup *api.StoragePoolVolumeBackup, ETag string, err error)
+	CreateStoragePoolVolumeBackup(pool string, volName string, backup api.StoragePoolVolumeBackupsPost) (op Operation, err error)
+	RenameStoragePoolVolumeBackup(pool string, volName string, name string, backup api.StoragePoolVolumeBackupPost) (op Operation, err error)
+	DeleteStoragePoolVolumeBackup(pool string, volName string, name string) (op Operation, err error)
+	GetStoragePoolVolumeBackupFile(pool string, volName string, name string, req *BackupFileRequest) (resp *BackupFileResponse, err error)
+	CreateStoragePoolVolumeFromBackup(pool string, args StoragePoolVolumeBackupArgs) (op Operation, err error)
+
+	// Storage volume disk image functions ("custom_block_volumes" API extension)
+	CreateStoragePoolVolumeFromDiskImage(pool string, args StoragePoolVolumeDiskImageArgs) (op Operation, err error)
+
 	// Cluster functions ("cluster" API extensions)
 	GetCluster() (cluster *api.Cluster, ETag string, err error)
 	UpdateCluster(cluster api.ClusterPut, ETag string) (op Operation, err error)
@@ -286,6 +308,22 @@ type InstanceServer interface {
 	GetClusterMembers() (members []api.ClusterMember, err error)
 	GetClusterMember(name string) (member *api.ClusterMember, ETag string, err error)
 	RenameClusterMember(name string, member api.ClusterMemberPost) (err error)
+	CreateClusterMember(member api.ClusterMembersPost) (op Operation, err error)
+
+	// Warning functions ("warnings" API extension)
+	GetWarningUUIDs() (uuids []string, err error)
+	GetWarnings() (warnings []api.Warning, err error)
+	GetWarning(uuid string) (warning *api.Warning, ETag string, err error)
+	UpdateWarning(uuid string, warning api.WarningPut, ETag string) (err error)
+	DeleteWarning(uuid string) (err error)
+
+	// Webhook functions ("webhooks" API extension)
+	GetWebhookNames() (names []string, err error)
+	GetWebhooks() (webhooks []api.Webhook, err error)
+	GetWebhook(name string) (webhook *api.Webhook, ETag string, err error)
+	CreateWebhook(webhook api.WebhooksPost) (err error)
+	UpdateWebhook(name string, webhook api.WebhookPut, ETag string) (err error)
+	DeleteWebhook(name string) (err error)
 
 	// Internal functions (for internal use)
 	RawQuery(method string, path string, data interface{}, queryETag string) (resp *api.Response, ETag string, err error)
@@ -303,6 +341,33 @@ type ConnectionInfo struct {
 	Project     string
 }
 
+// The OperationsHistoryFilter struct is used to only get some of the completed operations of a server ("operations_history" API extension).
+type OperationsHistoryFilter struct {
+	// Statuses of the operations to get, like "success" or "failure" (all by default)
+	Statuses []string
+
+	// Classes of the operations to get, like "task" (all by default)
+	Classes []string
+
+	// Only get the operations which finished after this time, if set
+	Since time.Time
+}
+
+// The EventsFilter struct is used to only get some of the events of a server ("event_filters" API extension).
+type EventsFilter struct {
+	// Event types to get (all by default)
+	Types []string
+
+	// Only get the events about these entities or their children, given by URL
+	EntityURLs []string
+
+	// Only get the lifecycle events with these actions
+	Actions []string
+
+	// If positive, first replay the recent events following the one with this ID
+	Since int64
+}
+
 // The BackupFileRequest struct is used for a backup download request.
 type BackupFileRequest struct {
 	// Writer for the backup file
@@ -340,6 +405,12 @@ type ImageCreateArgs struct {
 
 	// Type of the image (container or virtual-machine)
 	Type string
+
+	// Detached signature of the image (base64 encoded)
+	Signature string
+
+	// Whether MetaFile is a standalone raw, qcow2 or vmdk disk image to turn into a virtual-machine image
+	DiskImage bool
 }
 
 // The ImageFileRequest struct is used for an image download request.
@@ -405,6 +476,9 @@ type StoragePoolVolumeCopyArgs struct {
 
 	// API extension: storage_api_volume_snapshots
 	VolumeOnly bool
+
+	// API extension: custom_volume_refresh
+	Refresh bool
 }
 
 // The StoragePoolVolumeMoveArgs struct is used to pass additional options
@@ -413,6 +487,24 @@ type StoragePoolVolumeMoveArgs struct {
 	StoragePoolVolumeCopyArgs
 }
 
+// The StoragePoolVolumeBackupArgs struct is used when creating a storage volume from a backup.
+type StoragePoolVolumeBackupArgs struct {
+	// The backup file
+	BackupFile io.Reader
+
+	// Name to import the backup as
+	Name string
+}
+
+// The StoragePoolVolumeDiskImageArgs struct is used when creating a block storage volume from a disk image.
+type StoragePoolVolumeDiskImageArgs struct {
+	// The raw or qcow2 disk image file
+	DiskImageFile io.Reader
+
+	// Name of the new storage volume
+	Name string
+}
+
 // The InstanceBackupArgs struct is used when creating a instance from a backup.
 type InstanceBackupArgs struct {
 	// The backup file
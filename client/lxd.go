@@ -2,6 +2,7 @@ package lxd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,6 +17,7 @@ import (
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/oidc"
 
 	neturl "net/url"
 )
@@ -39,8 +41,15 @@ type ProtocolLXD struct {
 	bakeryInteractor     []httpbakery.Interactor
 	requireAuthenticated bool
 
+	oidcAuth *oidcAuth
+
 	clusterTarget string
 	project       string
+
+	// Context of the requests, and function called with each of them before
+	// it is sent
+	ctx         context.Context
+	requestHook func(*http.Request)
 }
 
 // Disconnect gets rid of any background goroutines
@@ -93,8 +102,23 @@ func (r *ProtocolLXD) GetHTTPClient() (*http.Client, error) {
 	return r.http, nil
 }
 
-// Do performs a Request, using macaroon authentication if set.
+// Do performs a Request, using macaroon or OpenID Connect authentication if set.
 func (r *ProtocolLXD) do(req *http.Request) (*http.Response, error) {
+	if r.ctx != nil {
+		req = req.WithContext(r.ctx)
+	}
+
+	if r.requestHook != nil {
+		r.requestHook(req)
+	}
+
+	if r.oidcAuth != nil {
+		err := r.oidcAuth.addHeaders(req.Header)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if r.bakeryClient != nil {
 		r.addMacaroonHeaders(req)
 		return r.bakeryClient.Do(req)
@@ -111,6 +135,29 @@ func (r *ProtocolLXD) addMacaroonHeaders(req *http.Request) {
 	}
 }
 
+// OpenID Connect tokens shared by all the clients derived from a connection.
+type oidcAuth struct {
+	tokens *oidc.Tokens
+	lock   sync.Mutex
+}
+
+func (a *oidcAuth) addHeaders(headers http.Header) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	// Refresh the access token before it expires. The issuer isn't the LXD
+	// server, so don't use the LXD HTTP client for this.
+	if a.tokens.Expired() && a.tokens.RefreshToken != "" {
+		err := a.tokens.Refresh(nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	headers.Set("Authorization", fmt.Sprintf("Bearer %s", a.tokens.AccessToken))
+	return nil
+}
+
 // RequireAuthenticated sets whether we expect to be authenticated with the server
 func (r *ProtocolLXD) RequireAuthenticated(authenticated bool) {
 	r.requireAuthenticated = authenticated
@@ -363,6 +410,14 @@ func (r *ProtocolLXD) rawWebsocket(url string) (*websocket.Conn, error) {
 		headers.Set("X-LXD-authenticated", "true")
 	}
 
+	// Set OpenID Connect headers if needed
+	if r.oidcAuth != nil {
+		err := r.oidcAuth.addHeaders(headers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Set macaroon headers if needed
 	if r.bakeryClient != nil {
 		u, err := neturl.Parse(r.httpHost) // use the http url, not the ws one
@@ -58,6 +58,10 @@ func (r *ProtocolLXD) GetCertificate(fingerprint string) (*api.Certificate, stri
 
 // CreateCertificate adds a new certificate to the LXD trust store
 func (r *ProtocolLXD) CreateCertificate(certificate api.CertificatesPost) error {
+	if (certificate.Restricted || len(certificate.Projects) > 0 || certificate.Type != "client") && !r.HasExtension("certificate_project") {
+		return fmt.Errorf("The server is missing the required \"certificate_project\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/certificates", certificate, "")
 	if err != nil {
@@ -67,6 +71,26 @@ func (r *ProtocolLXD) CreateCertificate(certificate api.CertificatesPost) error
 	return nil
 }
 
+// CreateCertificateToken requests a single-use token allowing a new client to
+// add its certificate to the LXD trust store
+func (r *ProtocolLXD) CreateCertificateToken(certificate api.CertificatesPost) (Operation, error) {
+	if !r.HasExtension("certificate_token") {
+		return nil, fmt.Errorf("The server is missing the required \"certificate_token\" API extension")
+	}
+
+	if !certificate.Token {
+		return nil, fmt.Errorf("Token needs to be true when requesting a token")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", "/certificates", certificate, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // UpdateCertificate updates the certificate definition
 func (r *ProtocolLXD) UpdateCertificate(fingerprint string, certificate api.CertificatePut, ETag string) error {
 	if !r.HasExtension("certificate_update") {
@@ -2,6 +2,9 @@ package lxd
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/lxc/lxd/shared"
@@ -12,6 +15,41 @@ import (
 
 // GetEvents connects to the LXD monitoring interface
 func (r *ProtocolLXD) GetEvents() (*EventListener, error) {
+	return r.getEvents("/events")
+}
+
+// GetEventsWithFilter connects to the LXD monitoring interface, only getting
+// the events matching the filter. Unlike GetEvents, this always uses a new
+// connection.
+func (r *ProtocolLXD) GetEventsWithFilter(filter EventsFilter) (*EventListener, error) {
+	if !r.HasExtension("event_filters") {
+		return nil, fmt.Errorf("The server is missing the required \"event_filters\" API extension")
+	}
+
+	values := url.Values{}
+	if len(filter.Types) > 0 {
+		values.Set("type", strings.Join(filter.Types, ","))
+	}
+
+	if len(filter.EntityURLs) > 0 {
+		values.Set("entity", strings.Join(filter.EntityURLs, ","))
+	}
+
+	if len(filter.Actions) > 0 {
+		values.Set("action", strings.Join(filter.Actions, ","))
+	}
+
+	if filter.Since > 0 {
+		values.Set("since", fmt.Sprintf("%d", filter.Since))
+	}
+
+	// Use a copy of the client, holding its own event listeners
+	client := r.UseProject(r.project).(*ProtocolLXD)
+
+	return client.getEvents(fmt.Sprintf("/events?%s", values.Encode()))
+}
+
+func (r *ProtocolLXD) getEvents(path string) (*EventListener, error) {
 	// Prevent anything else from interacting with the listeners
 	r.eventListenersLock.Lock()
 	defer r.eventListenersLock.Unlock()
@@ -29,12 +67,12 @@ func (r *ProtocolLXD) GetEvents() (*EventListener, error) {
 	}
 
 	// Setup a new connection with LXD
-	url, err := r.setQueryAttributes("/events")
+	uri, err := r.setQueryAttributes(path)
 	if err != nil {
 		return nil, err
 	}
 
-	conn, err := r.websocket(url)
+	conn, err := r.websocket(uri)
 	if err != nil {
 		return nil, err
 	}
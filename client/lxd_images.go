@@ -356,6 +356,12 @@ func (r *ProtocolLXD) CreateImage(image api.ImagesPost, args *ImageCreateArgs) (
 		}
 	}
 
+	if image.Sign || (args != nil && args.Signature != "") {
+		if !r.HasExtension("image_signatures") {
+			return nil, fmt.Errorf("The server is missing the required \"image_signatures\" API extension")
+		}
+	}
+
 	// Send the JSON based request
 	if args == nil {
 		op, _, err := r.queryOperation("POST", "/images", image, "")
@@ -371,6 +377,16 @@ func (r *ProtocolLXD) CreateImage(image api.ImagesPost, args *ImageCreateArgs) (
 		return nil, fmt.Errorf("Metadata file is required")
 	}
 
+	if args.DiskImage {
+		if !r.HasExtension("image_import_disk") {
+			return nil, fmt.Errorf("The server is missing the required \"image_import_disk\" API extension")
+		}
+
+		if args.RootfsFile != nil {
+			return nil, fmt.Errorf("Disk images can't have a separate rootfs file")
+		}
+	}
+
 	// Prepare the body
 	var body io.Reader
 	var contentType string
@@ -469,6 +485,14 @@ func (r *ProtocolLXD) CreateImage(image api.ImagesPost, args *ImageCreateArgs) (
 		req.Header.Set("X-LXD-filename", image.Filename)
 	}
 
+	if args.Signature != "" {
+		req.Header.Set("X-LXD-signature", args.Signature)
+	}
+
+	if args.DiskImage {
+		req.Header.Set("X-LXD-disk-image", "true")
+	}
+
 	if len(image.Properties) > 0 {
 		imgProps := url.Values{}
 
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -1587,6 +1588,36 @@ func (r *ProtocolLXD) GetInstanceState(name string) (*api.InstanceState, string,
 	return &state, etag, nil
 }
 
+// GetInstanceStateHistory returns the resource usage samples of the instance taken after the
+// given time, or all of them if the time is zero.
+func (r *ProtocolLXD) GetInstanceStateHistory(name string, since time.Time) ([]api.InstanceUsageSample, error) {
+	if !r.HasExtension("instance_usage_history") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_usage_history\" API extension")
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	uri := fmt.Sprintf("%s/%s/state/history", path, url.PathEscape(name))
+	if !since.IsZero() {
+		values := url.Values{}
+		values.Set("since", since.UTC().Format(time.RFC3339))
+		uri = fmt.Sprintf("%s?%s", uri, values.Encode())
+	}
+
+	samples := []api.InstanceUsageSample{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", uri, nil, "", &samples)
+	if err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
 // UpdateInstanceState updates the instance to match the requested state.
 func (r *ProtocolLXD) UpdateInstanceState(name string, state api.InstanceStatePut, ETag string) (Operation, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -51,6 +52,44 @@ func (r *ProtocolLXD) GetOperations() ([]api.Operation, error) {
 	return operations, nil
 }
 
+// GetOperationsHistory returns the list of completed operations matching the filter
+func (r *ProtocolLXD) GetOperationsHistory(filter OperationsHistoryFilter) ([]api.Operation, error) {
+	if !r.HasExtension("operations_history") {
+		return nil, fmt.Errorf("The server is missing the required \"operations_history\" API extension")
+	}
+
+	values := url.Values{}
+	values.Set("recursion", "1")
+	values.Set("all-history", "1")
+	if len(filter.Statuses) > 0 {
+		values.Set("status", strings.Join(filter.Statuses, ","))
+	}
+
+	if len(filter.Classes) > 0 {
+		values.Set("class", strings.Join(filter.Classes, ","))
+	}
+
+	if !filter.Since.IsZero() {
+		values.Set("since", filter.Since.UTC().Format(time.RFC3339))
+	}
+
+	apiOperations := map[string][]api.Operation{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/operations?%s", values.Encode()), nil, "", &apiOperations)
+	if err != nil {
+		return nil, err
+	}
+
+	// Turn it into just a list of operations
+	operations := []api.Operation{}
+	for _, v := range apiOperations {
+		operations = append(operations, v...)
+	}
+
+	return operations, nil
+}
+
 // GetOperation returns an Operation entry for the provided uuid
 func (r *ProtocolLXD) GetOperation(uuid string) (*api.Operation, string, error) {
 	op := api.Operation{}
@@ -1,6 +1,7 @@
 package lxd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lxc/lxd/shared"
@@ -89,6 +90,23 @@ func (r *ProtocolLXD) GetServerResources() (*api.Resources, error) {
 	return &resources, nil
 }
 
+// GetServerHealth returns the health of a given LXD server
+func (r *ProtocolLXD) GetServerHealth() (*api.Health, error) {
+	if !r.HasExtension("health") {
+		return nil, fmt.Errorf("The server is missing the required \"health\" API extension")
+	}
+
+	health := api.Health{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/health", nil, "", &health)
+	if err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
+
 // UseProject returns a client that will use a specific project.
 func (r *ProtocolLXD) UseProject(name string) InstanceServer {
 	return &ProtocolLXD{
@@ -100,9 +118,12 @@ func (r *ProtocolLXD) UseProject(name string) InstanceServer {
 		httpUserAgent:        r.httpUserAgent,
 		bakeryClient:         r.bakeryClient,
 		bakeryInteractor:     r.bakeryInteractor,
+		oidcAuth:             r.oidcAuth,
 		requireAuthenticated: r.requireAuthenticated,
 		clusterTarget:        r.clusterTarget,
 		project:              name,
+		ctx:                  r.ctx,
+		requestHook:          r.requestHook,
 	}
 }
 
@@ -119,9 +140,34 @@ func (r *ProtocolLXD) UseTarget(name string) InstanceServer {
 		httpUserAgent:        r.httpUserAgent,
 		bakeryClient:         r.bakeryClient,
 		bakeryInteractor:     r.bakeryInteractor,
+		oidcAuth:             r.oidcAuth,
 		requireAuthenticated: r.requireAuthenticated,
 		project:              r.project,
 		clusterTarget:        name,
+		ctx:                  r.ctx,
+		requestHook:          r.requestHook,
+	}
+}
+
+// UseContext returns a client whose requests are bound to the given context,
+// which is available to the request hook (e.g. to propagate its trace span to
+// the server).
+func (r *ProtocolLXD) UseContext(ctx context.Context) InstanceServer {
+	return &ProtocolLXD{
+		server:               r.server,
+		http:                 r.http,
+		httpCertificate:      r.httpCertificate,
+		httpHost:             r.httpHost,
+		httpProtocol:         r.httpProtocol,
+		httpUserAgent:        r.httpUserAgent,
+		bakeryClient:         r.bakeryClient,
+		bakeryInteractor:     r.bakeryInteractor,
+		oidcAuth:             r.oidcAuth,
+		requireAuthenticated: r.requireAuthenticated,
+		project:              r.project,
+		clusterTarget:        r.clusterTarget,
+		ctx:                  ctx,
+		requestHook:          r.requestHook,
 	}
 }
 
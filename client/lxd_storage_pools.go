@@ -117,6 +117,21 @@ func (r *ProtocolLXD) DeleteStoragePool(name string) error {
 	return nil
 }
 
+// MoveStoragePoolVolumes moves all instance and custom volumes of a storage pool onto another pool
+func (r *ProtocolLXD) MoveStoragePoolVolumes(name string, pool api.StoragePoolPost) (Operation, error) {
+	if !r.HasExtension("storage_pool_move_volumes") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_pool_move_volumes\" API extension")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("/storage-pools/%s", url.PathEscape(name)), pool, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // GetStoragePoolResources gets the resources available to a given storage pool
 func (r *ProtocolLXD) GetStoragePoolResources(name string) (*api.ResourcesStoragePool, error) {
 	if !r.HasExtension("resources") {
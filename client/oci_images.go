@@ -0,0 +1,109 @@
+package lxd
+
+import (
+	"fmt"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Image handling functions
+
+// GetImages isn't supported by OCI registries
+func (r *ProtocolOCI) GetImages() ([]api.Image, error) {
+	return nil, fmt.Errorf("Listing images isn't supported by the OCI protocol")
+}
+
+// GetImageFingerprints isn't supported by OCI registries
+func (r *ProtocolOCI) GetImageFingerprints() ([]string, error) {
+	return nil, fmt.Errorf("Listing images isn't supported by the OCI protocol")
+}
+
+// GetImage returns an Image struct for the provided image name.
+//
+// OCI images are converted by the LXD server when downloaded, so the image name is used as the fingerprint.
+func (r *ProtocolOCI) GetImage(name string) (*api.Image, string, error) {
+	image, err := r.ociClient.GetImage(name, r.architecture)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return image, "", nil
+}
+
+// GetImageFile isn't supported by OCI registries, the LXD server retrieves the image itself
+func (r *ProtocolOCI) GetImageFile(name string, req ImageFileRequest) (*ImageFileResponse, error) {
+	return nil, fmt.Errorf("OCI images can only be retrieved by a LXD server")
+}
+
+// GetImageSecret isn't relevant for the OCI protocol
+func (r *ProtocolOCI) GetImageSecret(fingerprint string) (string, error) {
+	return "", fmt.Errorf("Private images aren't supported by the OCI protocol")
+}
+
+// GetPrivateImage isn't relevant for the OCI protocol
+func (r *ProtocolOCI) GetPrivateImage(fingerprint string, secret string) (*api.Image, string, error) {
+	return nil, "", fmt.Errorf("Private images aren't supported by the OCI protocol")
+}
+
+// GetPrivateImageFile isn't relevant for the OCI protocol
+func (r *ProtocolOCI) GetPrivateImageFile(fingerprint string, secret string, req ImageFileRequest) (*ImageFileResponse, error) {
+	return nil, fmt.Errorf("Private images aren't supported by the OCI protocol")
+}
+
+// GetImageAliases isn't supported by OCI registries
+func (r *ProtocolOCI) GetImageAliases() ([]api.ImageAliasesEntry, error) {
+	return nil, fmt.Errorf("Listing aliases isn't supported by the OCI protocol")
+}
+
+// GetImageAliasNames isn't supported by OCI registries
+func (r *ProtocolOCI) GetImageAliasNames() ([]string, error) {
+	return nil, fmt.Errorf("Listing aliases isn't supported by the OCI protocol")
+}
+
+// GetImageAlias returns an ImageAliasesEntry struct for an image name, which acts as its own alias
+func (r *ProtocolOCI) GetImageAlias(name string) (*api.ImageAliasesEntry, string, error) {
+	return r.GetImageAliasType("container", name)
+}
+
+// GetImageAliasType returns an ImageAliasesEntry struct for an image name, which acts as its own alias
+func (r *ProtocolOCI) GetImageAliasType(imageType string, name string) (*api.ImageAliasesEntry, string, error) {
+	if imageType != "" && imageType != "container" {
+		return nil, "", fmt.Errorf("OCI images can only be used for containers")
+	}
+
+	_, _, err := r.ociClient.GetManifest(name, r.architecture)
+	if err != nil {
+		return nil, "", err
+	}
+
+	alias := api.ImageAliasesEntry{}
+	alias.Name = name
+	alias.Target = name
+	alias.Type = "container"
+
+	return &alias, "", nil
+}
+
+// GetImageAliasArchitectures returns a map of architectures / targets
+func (r *ProtocolOCI) GetImageAliasArchitectures(imageType string, name string) (map[string]*api.ImageAliasesEntry, error) {
+	if imageType != "" && imageType != "container" {
+		return nil, fmt.Errorf("OCI images can only be used for containers")
+	}
+
+	architectures, err := r.ociClient.GetArchitectures(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]*api.ImageAliasesEntry{}
+	for _, architecture := range architectures {
+		alias := api.ImageAliasesEntry{}
+		alias.Name = name
+		alias.Target = name
+		alias.Type = "container"
+
+		entries[architecture] = &alias
+	}
+
+	return entries, nil
+}
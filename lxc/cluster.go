@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
@@ -49,6 +50,10 @@ func (c *cmdCluster) Command() *cobra.Command {
 	clusterEnableCmd := cmdClusterEnable{global: c.global, cluster: c}
 	cmd.AddCommand(clusterEnableCmd.Command())
 
+	// Add token
+	clusterAddCmd := cmdClusterAdd{global: c.global, cluster: c}
+	cmd.AddCommand(clusterAddCmd.Command())
+
 	return cmd
 }
 
@@ -229,6 +234,71 @@ func (c *cmdClusterRename) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Add
+type cmdClusterAdd struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<name>")
+	cmd.Short = i18n.G("Request a join token for adding a cluster member")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Request a join token for adding a cluster member
+
+The token is single-use and is passed to "lxd init" on the new member.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	// Request the join token
+	op, err := resource.server.CreateClusterMember(api.ClusterMembersPost{ServerName: resource.name})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(op.Get().Metadata)
+	if err != nil {
+		return err
+	}
+
+	joinToken := api.ClusterMemberJoinToken{}
+	err = json.Unmarshal(data, &joinToken)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Member %s join token:")+"\n", resource.name)
+	}
+
+	fmt.Println(joinToken.String())
+
+	return nil
+}
+
 // Remove
 type cmdClusterRemove struct {
 	global  *cmdGlobal
@@ -1,10 +1,16 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	"github.com/juju/persistent-cookiejar"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/oidc"
 )
 
 // Config holds settings to be used by a client or daemon
@@ -34,6 +40,9 @@ type Config struct {
 
 	// Cookie jars
 	cookieJars map[string]*cookiejar.Jar
+
+	// OpenID Connect tokens
+	oidcTokens map[string]*oidc.Tokens
 }
 
 // ConfigPath returns a joined path of the configuration directory and passed arguments
@@ -49,6 +58,11 @@ func (c *Config) CookiesPath(remote string) string {
 	return c.ConfigPath("jars", remote)
 }
 
+// OIDCTokensPath returns the path for the remote's OpenID Connect tokens
+func (c *Config) OIDCTokensPath(remote string) string {
+	return c.ConfigPath("oidctokens", fmt.Sprintf("%s.json", remote))
+}
+
 // ServerCertPath returns the path for the remote's server certificate
 func (c *Config) ServerCertPath(remote string) string {
 	return c.ConfigPath("servercerts", fmt.Sprintf("%s.crt", remote))
@@ -61,6 +75,64 @@ func (c *Config) SaveCookies() {
 	}
 }
 
+// OIDCTokens returns the OpenID Connect tokens of the remote, loading them
+// from disk if needed. It returns nil if the remote has no tokens.
+func (c *Config) OIDCTokens(remote string) (*oidc.Tokens, error) {
+	tokens, ok := c.oidcTokens[remote]
+	if ok {
+		return tokens, nil
+	}
+
+	if !shared.PathExists(c.OIDCTokensPath(remote)) {
+		return nil, nil
+	}
+
+	content, err := ioutil.ReadFile(c.OIDCTokensPath(remote))
+	if err != nil {
+		return nil, err
+	}
+
+	tokens = &oidc.Tokens{}
+	err = json.Unmarshal(content, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decode the OpenID Connect tokens of %q: %v", remote, err)
+	}
+
+	c.SetOIDCTokens(remote, tokens)
+	return tokens, nil
+}
+
+// SetOIDCTokens sets the OpenID Connect tokens of the remote.
+func (c *Config) SetOIDCTokens(remote string, tokens *oidc.Tokens) {
+	if c.oidcTokens == nil {
+		c.oidcTokens = map[string]*oidc.Tokens{}
+	}
+
+	c.oidcTokens[remote] = tokens
+}
+
+// SaveOIDCTokens saves OpenID Connect tokens to file
+func (c *Config) SaveOIDCTokens() error {
+	for remote, tokens := range c.oidcTokens {
+		err := os.MkdirAll(c.ConfigPath("oidctokens"), 0700)
+		if err != nil {
+			return err
+		}
+
+		content, err := json.Marshal(tokens)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(c.OIDCTokensPath(remote), content, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // NewConfig returns a Config, optionally using default remotes.
 func NewConfig(configDir string, defaults bool) *Config {
 	config := &Config{ConfigDir: configDir}
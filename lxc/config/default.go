@@ -30,6 +30,13 @@ var UbuntuDailyRemote = Remote{
 	Protocol: "simplestreams",
 }
 
+// DockerRemote is the Docker Hub registry (over OCI)
+var DockerRemote = Remote{
+	Addr:     "https://docker.io",
+	Public:   true,
+	Protocol: "oci",
+}
+
 // StaticRemotes is the list of remotes which can't be removed
 var StaticRemotes = map[string]Remote{
 	"local":        LocalRemote,
@@ -39,6 +46,7 @@ var StaticRemotes = map[string]Remote{
 
 // DefaultRemotes is the list of default remotes
 var DefaultRemotes = map[string]Remote{
+	"docker":       DockerRemote,
 	"images":       ImagesRemote,
 	"local":        LocalRemote,
 	"ubuntu":       UbuntuRemote,
@@ -64,7 +64,7 @@ func (c *Config) GetInstanceServer(name string) (lxd.InstanceServer, error) {
 	}
 
 	// Sanity checks
-	if remote.Public || remote.Protocol == "simplestreams" {
+	if remote.Public || remote.Protocol == "simplestreams" || remote.Protocol == "oci" {
 		return nil, fmt.Errorf("The remote isn't a private LXD server")
 	}
 
@@ -93,7 +93,7 @@ func (c *Config) GetInstanceServer(name string) (lxd.InstanceServer, error) {
 	}
 
 	// HTTPs
-	if remote.AuthType != "candid" && (args.TLSClientCert == "" || args.TLSClientKey == "") {
+	if remote.AuthType != "candid" && remote.AuthType != "oidc" && (args.TLSClientCert == "" || args.TLSClientKey == "") {
 		return nil, fmt.Errorf("Missing TLS client certificate and key")
 	}
 
@@ -160,6 +160,16 @@ func (c *Config) GetImageServer(name string) (lxd.ImageServer, error) {
 		return d, nil
 	}
 
+	// HTTPs (OCI registry)
+	if remote.Protocol == "oci" {
+		d, err := lxd.ConnectOCI(remote.Addr, args)
+		if err != nil {
+			return nil, err
+		}
+
+		return d, nil
+	}
+
 	// HTTPs (public LXD)
 	if remote.Public {
 		d, err := lxd.ConnectPublicLXD(remote.Addr, args)
@@ -244,6 +254,15 @@ func (c *Config) getConnectionArgs(name string) (*lxd.ConnectionArgs, error) {
 		args.CookieJar = c.cookieJars[name]
 	}
 
+	if args.AuthType == "oidc" {
+		tokens, err := c.OIDCTokens(name)
+		if err != nil {
+			return nil, err
+		}
+
+		args.OIDCTokens = tokens
+	}
+
 	// Stop here if no TLS involved
 	if strings.HasPrefix(remote.Addr, "unix:") {
 		return &args, nil
@@ -260,7 +279,7 @@ func (c *Config) getConnectionArgs(name string) (*lxd.ConnectionArgs, error) {
 	}
 
 	// Stop here if no client certificate involved
-	if remote.Protocol == "simplestreams" || remote.AuthType == "candid" {
+	if remote.Protocol == "simplestreams" || remote.Protocol == "oci" || remote.AuthType == "candid" || remote.AuthType == "oidc" {
 		return &args, nil
 	}
 
@@ -3,9 +3,11 @@ package main
 import (
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -49,14 +51,35 @@ type cmdConfigTrustAdd struct {
 	global      *cmdGlobal
 	config      *cmdConfig
 	configTrust *cmdConfigTrust
+
+	flagType       string
+	flagRestricted bool
+	flagProjects   string
+	flagName       string
 }
 
 func (c *cmdConfigTrustAdd) Command() *cobra.Command {
 	cmd := &cobra.Command{}
-	cmd.Use = i18n.G("add [<remote>:] <cert>")
+	cmd.Use = i18n.G("add [<remote>:] [<cert>]")
 	cmd.Short = i18n.G("Add new trusted clients")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Add new trusted clients`))
+		`Add new trusted clients
+
+Restricted clients only get access to the listed projects.
+
+When no certificate is provided, a single-use token is generated instead for
+the client named with --name. The client can then add itself with
+"lxc remote add <remote> <token>".`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc config trust add client.crt --restricted --projects foo,bar
+    Add a client certificate only allowed to access the foo and bar projects.
+
+lxc config trust add --name laptop
+    Generate a token for a new client named laptop.`))
+	cmd.Flags().StringVar(&c.flagType, "type", "client", i18n.G("Type of certificate (client or metrics)")+"``")
+	cmd.Flags().BoolVar(&c.flagRestricted, "restricted", false, i18n.G("Restrict the certificate to the listed projects"))
+	cmd.Flags().StringVar(&c.flagProjects, "projects", "", i18n.G("Comma separated list of projects to restrict the certificate to")+"``")
+	cmd.Flags().StringVar(&c.flagName, "name", "", i18n.G("Name of the new client, generates a token when no certificate is provided")+"``")
 
 	cmd.RunE = c.Run
 
@@ -65,14 +88,22 @@ func (c *cmdConfigTrustAdd) Command() *cobra.Command {
 
 func (c *cmdConfigTrustAdd) Run(cmd *cobra.Command, args []string) error {
 	// Sanity checks
-	exit, err := c.global.CheckArgs(cmd, args, 1, 2)
+	minArgs := 1
+	if c.flagName != "" {
+		minArgs = 0
+	}
+
+	exit, err := c.global.CheckArgs(cmd, args, minArgs, 2)
 	if exit {
 		return err
 	}
 
+	// A token is generated when only the client name is provided
+	useToken := c.flagName != "" && (len(args) == 0 || (len(args) == 1 && strings.HasSuffix(args[0], ":")))
+
 	// Parse remote
 	remote := ""
-	if len(args) > 0 {
+	if len(args) > 1 || (useToken && len(args) == 1) {
 		remote = args[0]
 	}
 
@@ -83,6 +114,33 @@ func (c *cmdConfigTrustAdd) Run(cmd *cobra.Command, args []string) error {
 
 	resource := resources[0]
 
+	cert := api.CertificatesPost{}
+	cert.Type = c.flagType
+	cert.Restricted = c.flagRestricted
+	if c.flagProjects != "" {
+		cert.Projects = strings.Split(c.flagProjects, ",")
+	}
+
+	if useToken {
+		cert.Name = c.flagName
+		cert.Token = true
+
+		op, err := resource.server.CreateCertificateToken(cert)
+		if err != nil {
+			return err
+		}
+
+		token, err := certificateAddTokenFromMetadata(op.Get().Metadata)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf(i18n.G("Client %s certificate add token:")+"\n", cert.Name)
+		fmt.Println(token.String())
+
+		return nil
+	}
+
 	// Add trust relationship
 	fname := args[len(args)-1]
 	x509Cert, err := shared.ReadCert(shared.HostPath(fname))
@@ -90,15 +148,32 @@ func (c *cmdConfigTrustAdd) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	name, _ := shared.SplitExt(fname)
+	if c.flagName != "" {
+		name = c.flagName
+	}
 
-	cert := api.CertificatesPost{}
 	cert.Certificate = base64.StdEncoding.EncodeToString(x509Cert.Raw)
 	cert.Name = name
-	cert.Type = "client"
 
 	return resource.server.CreateCertificate(cert)
 }
 
+// Extract a certificate add token from the metadata of the token operation.
+func certificateAddTokenFromMetadata(metadata map[string]interface{}) (*api.CertificateAddToken, error) {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	token := api.CertificateAddToken{}
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
 // List
 type cmdConfigTrustList struct {
 	global      *cmdGlobal
@@ -151,6 +226,11 @@ func (c *cmdConfigTrustList) Run(cmd *cobra.Command, args []string) error {
 	data := [][]string{}
 	for _, cert := range trust {
 		fp := cert.Fingerprint[0:12]
+		certType := cert.Type
+		projects := ""
+		if cert.Restricted {
+			projects = strings.Join(cert.Projects, "\n")
+		}
 
 		certBlock, _ := pem.Decode([]byte(cert.Certificate))
 		if certBlock == nil {
@@ -165,15 +245,17 @@ func (c *cmdConfigTrustList) Run(cmd *cobra.Command, args []string) error {
 		const layout = "Jan 2, 2006 at 3:04pm (MST)"
 		issue := cert.NotBefore.Format(layout)
 		expiry := cert.NotAfter.Format(layout)
-		data = append(data, []string{fp, cert.Subject.CommonName, issue, expiry})
+		data = append(data, []string{fp, certType, cert.Subject.CommonName, issue, expiry, projects})
 	}
 	sort.Sort(stringList(data))
 
 	header := []string{
 		i18n.G("FINGERPRINT"),
+		i18n.G("TYPE"),
 		i18n.G("COMMON NAME"),
 		i18n.G("ISSUE DATE"),
 		i18n.G("EXPIRY DATE"),
+		i18n.G("PROJECTS"),
 	}
 
 	return utils.RenderTable(c.flagFormat, header, data, trust)
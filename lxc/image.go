@@ -182,8 +182,8 @@ func (c *cmdImageCopy) Run(cmd *cobra.Command, args []string) error {
 	// Copy the image
 	var imgInfo *api.Image
 	var fp string
-	if conf.Remotes[remoteName].Protocol == "simplestreams" && !c.flagCopyAliases && len(c.flagAliases) == 0 {
-		// All simplestreams images are always public, so unless we
+	if shared.StringInSlice(conf.Remotes[remoteName].Protocol, []string{"simplestreams", "oci"}) && !c.flagCopyAliases && len(c.flagAliases) == 0 {
+		// All simplestreams and OCI images are always public, so unless we
 		// need the aliases list too or the real fingerprint, we can skip the otherwise very expensive
 		// alias resolution and image info retrieval step.
 		imgInfo = &api.Image{}
@@ -594,21 +594,31 @@ type cmdImageImport struct {
 	global *cmdGlobal
 	image  *cmdImage
 
-	flagPublic  bool
-	flagAliases []string
+	flagPublic    bool
+	flagAliases   []string
+	flagSignature string
+	flagVM        bool
 }
 
 func (c *cmdImageImport) Command() *cobra.Command {
 	cmd := &cobra.Command{}
-	cmd.Use = i18n.G("import <tarball>|<directory>|<URL> [<rootfs tarball>] [<remote>:] [key=value...]")
+	cmd.Use = i18n.G("import <tarball>|<directory>|<URL>|<disk image> [<rootfs tarball>] [<remote>:] [key=value...]")
 	cmd.Short = i18n.G("Import images into the image store")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Import image into the image store
 
-Directory import is only available on Linux and must be performed as root.`))
+Directory import is only available on Linux and must be performed as root.
+
+With --vm, a standalone raw, qcow2 or vmdk disk image is converted into a
+virtual-machine image. Its architecture defaults to the one of the server and
+can be set, like the other image properties, with key=value pairs.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image import appliance.vmdk --vm --alias appliance os=Debian release=buster`))
 
 	cmd.Flags().BoolVar(&c.flagPublic, "public", false, i18n.G("Make image public"))
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New aliases to add to the image")+"``")
+	cmd.Flags().StringVar(&c.flagSignature, "signature", "", i18n.G("File holding a detached signature of the image")+"``")
+	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Import a disk image as a virtual-machine image"))
 	cmd.RunE = c.Run
 
 	return cmd
@@ -695,6 +705,10 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("Only https:// is supported for remote image import"))
 	}
 
+	if c.flagVM && (rootfsFile != "" || strings.HasPrefix(imageFile, "https://") || shared.IsDir(imageFile)) {
+		return fmt.Errorf(i18n.G("Only a local disk image file can be imported with --vm"))
+	}
+
 	createArgs := &lxd.ImageCreateArgs{}
 	image := api.ImagesPost{}
 	image.Public = c.flagPublic
@@ -728,6 +742,10 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 
 	imageType := "container"
 	if strings.HasPrefix(imageFile, "https://") {
+		if c.flagSignature != "" {
+			return fmt.Errorf(i18n.G("Signatures of images imported from a URL are provided by the web server"))
+		}
+
 		image.Source = &api.ImagesPostSource{}
 		image.Source.Type = "url"
 		image.Source.Mode = "pull"
@@ -773,6 +791,10 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 			}
 		}
 
+		if c.flagVM {
+			imageType = "virtual-machine"
+		}
+
 		createArgs = &lxd.ImageCreateArgs{
 			MetaFile:        meta,
 			MetaName:        filepath.Base(imageFile),
@@ -780,8 +802,18 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 			RootfsName:      filepath.Base(rootfsFile),
 			ProgressHandler: progress.UpdateProgress,
 			Type:            imageType,
+			DiskImage:       c.flagVM,
 		}
 		image.Filename = createArgs.MetaName
+
+		if c.flagSignature != "" {
+			signature, err := ioutil.ReadFile(shared.HostPath(c.flagSignature))
+			if err != nil {
+				return err
+			}
+
+			createArgs.Signature = strings.TrimSpace(string(signature))
+		}
 	}
 
 	// Start the transfer
@@ -932,6 +964,10 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 	fmt.Printf(i18n.G("Cached: %s")+"\n", cached)
 	fmt.Printf(i18n.G("Auto update: %s")+"\n", autoUpdate)
 
+	if info.SignatureStatus != "" {
+		fmt.Printf(i18n.G("Signature: %s")+"\n", info.SignatureStatus)
+	}
+
 	if info.UpdateSource != nil {
 		fmt.Println(i18n.G("Source:"))
 		fmt.Printf("    Server: %s\n", info.UpdateSource.Server)
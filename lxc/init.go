@@ -253,8 +253,8 @@ func (c *cmdInit) create(conf *config.Config, args []string) (lxd.InstanceServer
 			image = "default"
 		}
 
-		// Optimisation for simplestreams
-		if conf.Remotes[iremote].Protocol == "simplestreams" {
+		// Optimisation for simplestreams and OCI registries
+		if conf.Remotes[iremote].Protocol == "simplestreams" || conf.Remotes[iremote].Protocol == "oci" {
 			imgInfo = &api.Image{}
 			imgInfo.Fingerprint = image
 			imgInfo.Public = true
@@ -205,10 +205,22 @@ For help with any of those, simply call them with --help.`))
 	stopCmd := cmdStop{global: &globalCmd}
 	app.AddCommand(stopCmd.Command())
 
+	// top sub-command
+	topCmd := cmdTop{global: &globalCmd}
+	app.AddCommand(topCmd.Command())
+
 	// version sub-command
 	versionCmd := cmdVersion{global: &globalCmd}
 	app.AddCommand(versionCmd.Command())
 
+	// warning sub-command
+	warningCmd := cmdWarning{global: &globalCmd}
+	app.AddCommand(warningCmd.Command())
+
+	// webhook sub-command
+	webhookCmd := cmdWebhook{global: &globalCmd}
+	app.AddCommand(webhookCmd.Command())
+
 	// Get help command
 	app.InitDefaultHelpCmd()
 	var help *cobra.Command
@@ -368,6 +380,12 @@ func (c *cmdGlobal) PostRun(cmd *cobra.Command, args []string) error {
 	if c.conf != nil && shared.PathExists(c.confPath) {
 		// Save cookies on exit
 		c.conf.SaveCookies()
+
+		// Save refreshed OpenID Connect tokens on exit
+		err := c.conf.SaveOIDCTokens()
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
@@ -21,6 +22,9 @@ type cmdMonitor struct {
 	flagType     []string
 	flagPretty   bool
 	flagLogLevel string
+	flagEntity   []string
+	flagAction   []string
+	flagSince    int64
 }
 
 func (c *cmdMonitor) Command() *cobra.Command {
@@ -39,13 +43,22 @@ lxc monitor --pretty --type=logging --loglevel=info
     Show a pretty log of messages with info level or higher.
 
 lxc monitor --type=lifecycle
-    Only show lifecycle events.`))
+    Only show lifecycle events.
+
+lxc monitor --entity=/1.0/containers/c1 --action=container-started
+    Only show the start events of container c1.
+
+lxc monitor --since=42
+    Replay the recent events following the one with ID 42, then show new events.`))
 	cmd.Hidden = true
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagPretty, "pretty", false, i18n.G("Pretty rendering"))
 	cmd.Flags().StringArrayVar(&c.flagType, "type", nil, i18n.G("Event type to listen for")+"``")
 	cmd.Flags().StringVar(&c.flagLogLevel, "loglevel", "", i18n.G("Minimum level for log messages")+"``")
+	cmd.Flags().StringArrayVar(&c.flagEntity, "entity", nil, i18n.G("Only show events about this entity URL or its children")+"``")
+	cmd.Flags().StringArrayVar(&c.flagAction, "action", nil, i18n.G("Only show lifecycle events with this action")+"``")
+	cmd.Flags().Int64Var(&c.flagSince, "since", 0, i18n.G("Replay the recent events following the one with this ID")+"``")
 
 	return cmd
 }
@@ -79,7 +92,18 @@ func (c *cmdMonitor) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	listener, err := d.GetEvents()
+	var listener *lxd.EventListener
+	if len(c.flagEntity) > 0 || len(c.flagAction) > 0 || c.flagSince > 0 {
+		listener, err = d.GetEventsWithFilter(lxd.EventsFilter{
+			Types:      c.flagType,
+			EntityURLs: c.flagEntity,
+			Actions:    c.flagAction,
+			Since:      c.flagSince,
+		})
+	} else {
+		listener, err = d.GetEvents()
+	}
+
 	if err != nil {
 		return err
 	}
@@ -8,7 +8,9 @@ import (
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
 )
@@ -91,7 +93,9 @@ type cmdOperationList struct {
 	global    *cmdGlobal
 	operation *cmdOperation
 
-	flagFormat string
+	flagFormat  string
+	flagHistory bool
+	flagStatus  string
 }
 
 func (c *cmdOperationList) Command() *cobra.Command {
@@ -100,8 +104,15 @@ func (c *cmdOperationList) Command() *cobra.Command {
 	cmd.Aliases = []string{"ls"}
 	cmd.Short = i18n.G("List background operations")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`List background operations`))
+		`List background operations
+
+With --history, the completed operations kept by the server are listed instead.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc operation list --history --status=failure
+    List the operations which failed recently.`))
 	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().BoolVar(&c.flagHistory, "history", false, i18n.G("List completed operations"))
+	cmd.Flags().StringVar(&c.flagStatus, "status", "", i18n.G("Only list completed operations with these comma separated statuses")+"``")
 
 	cmd.RunE = c.Run
 
@@ -131,8 +142,23 @@ func (c *cmdOperationList) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
 	}
 
+	if c.flagStatus != "" && !c.flagHistory {
+		return fmt.Errorf(i18n.G("--status can only be used with --history"))
+	}
+
 	// Get operations
-	operations, err := resource.server.GetOperations()
+	var operations []api.Operation
+	if c.flagHistory {
+		filter := lxd.OperationsHistoryFilter{}
+		if c.flagStatus != "" {
+			filter.Statuses = strings.Split(c.flagStatus, ",")
+		}
+
+		operations, err = resource.server.GetOperationsHistory(filter)
+	} else {
+		operations, err = resource.server.GetOperations()
+	}
+
 	if err != nil {
 		return err
 	}
@@ -22,6 +22,7 @@ type cmdPublish struct {
 	flagCompressionAlgorithm string
 	flagMakePublic           bool
 	flagForce                bool
+	flagSign                 bool
 }
 
 func (c *cmdPublish) showByDefault() bool {
@@ -40,6 +41,7 @@ func (c *cmdPublish) Command() *cobra.Command {
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New alias to define at target")+"``")
 	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Stop the container if currently running"))
 	cmd.Flags().StringVar(&c.flagCompressionAlgorithm, "compression", "", i18n.G("Define a compression algorithm: for image or none")+"``")
+	cmd.Flags().BoolVar(&c.flagSign, "sign", false, i18n.G("Sign the image with the server key"))
 
 	return cmd
 }
@@ -201,6 +203,7 @@ func (c *cmdPublish) Run(cmd *cobra.Command, args []string) error {
 			Name: cName,
 		},
 		CompressionAlgorithm: c.flagCompressionAlgorithm,
+		Sign:                 c.flagSign,
 	}
 	req.Properties = properties
 
@@ -57,6 +57,10 @@ func (c *cmdStorage) Command() *cobra.Command {
 	storageListCmd := cmdStorageList{global: c.global, storage: c}
 	cmd.AddCommand(storageListCmd.Command())
 
+	// Move volumes
+	storageMoveVolumesCmd := cmdStorageMoveVolumes{global: c.global, storage: c}
+	cmd.AddCommand(storageMoveVolumesCmd.Command())
+
 	// Set
 	storageSetCmd := cmdStorageSet{global: c.global, storage: c}
 	cmd.AddCommand(storageSetCmd.Command())
@@ -569,6 +573,81 @@ func (c *cmdStorageList) Run(cmd *cobra.Command, args []string) error {
 	return utils.RenderTable(c.flagFormat, header, data, pools)
 }
 
+// Move volumes
+type cmdStorageMoveVolumes struct {
+	global  *cmdGlobal
+	storage *cmdStorage
+}
+
+func (c *cmdStorageMoveVolumes) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("move-volumes [<remote>:]<pool> <target pool>")
+	cmd.Short = i18n.G("Move all volumes of a storage pool to another pool")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Move all volumes of a storage pool to another pool
+
+Instance volumes, custom volumes and their snapshots are moved. Instances
+using the pool must be stopped and their root disk devices are updated to
+point at the target pool.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageMoveVolumes) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing pool name"))
+	}
+
+	// Move the volumes
+	op, err := resource.server.MoveStoragePoolVolumes(resource.name, api.StoragePoolPost{Pool: args[1]})
+	if err != nil {
+		return err
+	}
+
+	// Register progress handler
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Moving the storage volumes: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	// Wait for operation to finish
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Storage volumes moved from %s to %s")+"\n", resource.name, args[1])
+	}
+
+	return nil
+}
+
 // Set
 type cmdStorageSet struct {
 	global  *cmdGlobal
@@ -2,12 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
@@ -17,7 +20,9 @@ import (
 	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/ioprogress"
 	"github.com/lxc/lxd/shared/termios"
+	"github.com/lxc/lxd/shared/units"
 )
 
 type cmdStorageVolume struct {
@@ -66,10 +71,18 @@ Unless specified through a prefix, all volume operations affect "custom" (user c
 	storageVolumeEditCmd := cmdStorageVolumeEdit{global: c.global, storage: c.storage, storageVolume: c}
 	cmd.AddCommand(storageVolumeEditCmd.Command())
 
+	// Export
+	storageVolumeExportCmd := cmdStorageVolumeExport{global: c.global, storage: c.storage, storageVolume: c}
+	cmd.AddCommand(storageVolumeExportCmd.Command())
+
 	// Get
 	storageVolumeGetCmd := cmdStorageVolumeGet{global: c.global, storage: c.storage, storageVolume: c}
 	cmd.AddCommand(storageVolumeGetCmd.Command())
 
+	// Import
+	storageVolumeImportCmd := cmdStorageVolumeImport{global: c.global, storage: c.storage, storageVolume: c}
+	cmd.AddCommand(storageVolumeImportCmd.Command())
+
 	// List
 	storageVolumeListCmd := cmdStorageVolumeList{global: c.global, storage: c.storage, storageVolume: c}
 	cmd.AddCommand(storageVolumeListCmd.Command())
@@ -134,10 +147,12 @@ type cmdStorageVolumeAttach struct {
 
 func (c *cmdStorageVolumeAttach) Command() *cobra.Command {
 	cmd := &cobra.Command{}
-	cmd.Use = i18n.G("attach [<remote>:]<pool> <volume> <container> [<device name>] <path>")
-	cmd.Short = i18n.G("Attach new storage volumes to containers")
+	cmd.Use = i18n.G("attach [<remote>:]<pool> <volume> <instance> [<device name>] [<path>]")
+	cmd.Short = i18n.G("Attach new storage volumes to instances")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Attach new storage volumes to containers`))
+		`Attach new storage volumes to instances
+
+Block volumes are attached to virtual machines as disks and don't take a path.`))
 
 	cmd.RunE = c.Run
 
@@ -146,7 +161,7 @@ func (c *cmdStorageVolumeAttach) Command() *cobra.Command {
 
 func (c *cmdStorageVolumeAttach) Run(cmd *cobra.Command, args []string) error {
 	// Sanity checks
-	exit, err := c.global.CheckArgs(cmd, args, 4, 5)
+	exit, err := c.global.CheckArgs(cmd, args, 3, 5)
 	if exit {
 		return err
 	}
@@ -163,22 +178,9 @@ func (c *cmdStorageVolumeAttach) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("Missing pool name"))
 	}
 
-	// Attach the volume
-	devPath := ""
-	devName := ""
-	if len(args) == 4 {
-		// Only the path has been given to us.
-		devPath = args[3]
-		devName = args[1]
-	} else if len(args) == 5 {
-		// Path and device name have been given to us.
-		devName = args[3]
-		devPath = args[4]
-	}
-
 	volName, volType := c.storageVolume.parseVolume("custom", args[1])
 	if volType != "custom" {
-		return fmt.Errorf(i18n.G("Only \"custom\" volumes can be attached to containers"))
+		return fmt.Errorf(i18n.G("Only \"custom\" volumes can be attached to instances"))
 	}
 
 	// Check if the requested storage volume actually exists
@@ -187,15 +189,42 @@ func (c *cmdStorageVolumeAttach) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Prepare the container's device entry
+	// Attach the volume
+	devPath := ""
+	devName := args[1]
+	if vol.ContentType == "block" {
+		// Block volumes don't have a path, only the device name may be given to us.
+		if len(args) == 5 {
+			return fmt.Errorf(i18n.G("Block volumes cannot be attached to a path"))
+		}
+
+		if len(args) == 4 {
+			devName = args[3]
+		}
+	} else if len(args) == 4 {
+		// Only the path has been given to us.
+		devPath = args[3]
+	} else if len(args) == 5 {
+		// Path and device name have been given to us.
+		devName = args[3]
+		devPath = args[4]
+	} else {
+		cmd.Help()
+		return fmt.Errorf(i18n.G("Missing path"))
+	}
+
+	// Prepare the instance's device entry
 	device := map[string]string{
 		"type":   "disk",
 		"pool":   resource.name,
-		"path":   devPath,
 		"source": vol.Name,
 	}
 
-	// Add the device to the container
+	if devPath != "" {
+		device["path"] = devPath
+	}
+
+	// Add the device to the instance
 	err = containerDeviceAdd(resource.server, args[2], devName, device)
 	if err != nil {
 		return err
@@ -291,6 +320,7 @@ type cmdStorageVolumeCopy struct {
 
 	flagMode       string
 	flagVolumeOnly bool
+	flagRefresh    bool
 }
 
 func (c *cmdStorageVolumeCopy) Command() *cobra.Command {
@@ -304,6 +334,7 @@ func (c *cmdStorageVolumeCopy) Command() *cobra.Command {
 	cmd.Flags().StringVar(&c.flagMode, "mode", "pull", i18n.G("Transfer mode. One of pull (default), push or relay.")+"``")
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
 	cmd.Flags().BoolVar(&c.flagVolumeOnly, "volume-only", false, i18n.G("Copy the volume without its snapshots"))
+	cmd.Flags().BoolVar(&c.flagRefresh, "refresh", false, i18n.G("Refresh and update the existing storage volume copies"))
 	cmd.RunE = c.Run
 
 	return cmd
@@ -406,6 +437,7 @@ func (c *cmdStorageVolumeCopy) Run(cmd *cobra.Command, args []string) error {
 		args.Name = dstVolName
 		args.Mode = mode
 		args.VolumeOnly = c.flagVolumeOnly
+		args.Refresh = c.flagRefresh
 
 		if isSnapshot {
 			srcVol.Name = srcVolName
@@ -453,6 +485,8 @@ type cmdStorageVolumeCreate struct {
 	global        *cmdGlobal
 	storage       *cmdStorage
 	storageVolume *cmdStorageVolume
+
+	flagContentType string
 }
 
 func (c *cmdStorageVolumeCreate) Command() *cobra.Command {
@@ -463,6 +497,7 @@ func (c *cmdStorageVolumeCreate) Command() *cobra.Command {
 		`Create new custom storage volumes`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.Flags().StringVar(&c.flagContentType, "type", "filesystem", i18n.G("Content type, block or filesystem")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -496,6 +531,7 @@ func (c *cmdStorageVolumeCreate) Run(cmd *cobra.Command, args []string) error {
 	vol := api.StorageVolumesPost{}
 	vol.Name = volName
 	vol.Type = volType
+	vol.ContentType = c.flagContentType
 	vol.Config = map[string]string{}
 
 	for i := 2; i < len(args); i++ {
@@ -1605,3 +1641,253 @@ func (c *cmdStorageVolumeRestore) Run(cmd *cobra.Command, args []string) error {
 
 	return client.UpdateStoragePoolVolume(resource.name, "custom", args[1], req, etag)
 }
+
+// Export
+type cmdStorageVolumeExport struct {
+	global        *cmdGlobal
+	storage       *cmdStorage
+	storageVolume *cmdStorageVolume
+
+	flagVolumeOnly           bool
+	flagOptimizedStorage     bool
+	flagCompressionAlgorithm string
+}
+
+func (c *cmdStorageVolumeExport) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("export [<remote>:]<pool> <volume> [<path>]")
+	cmd.Short = i18n.G("Export custom storage volume")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Export custom storage volume`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc storage volume export default vol1 backup0.tar.gz
+    Download a backup tarball of the vol1 volume from the default pool.`))
+
+	cmd.Flags().BoolVar(&c.flagVolumeOnly, "volume-only", false, i18n.G("Export the volume without its snapshots"))
+	cmd.Flags().BoolVar(&c.flagOptimizedStorage, "optimized-storage", false,
+		i18n.G("Use storage driver optimized format (can only be restored on a similar pool)"))
+	cmd.Flags().StringVar(&c.flagCompressionAlgorithm, "compression", "", i18n.G("Define a compression algorithm: for backup or none")+"``")
+	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageVolumeExport) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing pool name"))
+	}
+
+	client := resource.server
+
+	// Use the target when in a cluster
+	if c.storage.flagTarget != "" {
+		client = client.UseTarget(c.storage.flagTarget)
+	}
+
+	volName, volType := c.storageVolume.parseVolume("custom", args[1])
+	if volType != "custom" {
+		return fmt.Errorf(i18n.G("Only \"custom\" volumes can be exported"))
+	}
+
+	req := api.StoragePoolVolumeBackupsPost{
+		Name:                 "",
+		ExpiresAt:            time.Now().Add(24 * time.Hour),
+		VolumeOnly:           c.flagVolumeOnly,
+		OptimizedStorage:     c.flagOptimizedStorage,
+		CompressionAlgorithm: c.flagCompressionAlgorithm,
+	}
+
+	op, err := client.CreateStoragePoolVolumeBackup(resource.name, volName, req)
+	if err != nil {
+		return errors.Wrap(err, "Create storage volume backup")
+	}
+
+	// Wait until backup is done
+	err = op.Wait()
+	if err != nil {
+		return err
+	}
+
+	// Get name of backup
+	backupName := op.Get().Resources["backups"][0]
+	backupName = backupName[strings.LastIndex(backupName, "/")+1:]
+
+	defer func() {
+		// Delete backup after we're done
+		op, err = client.DeleteStoragePoolVolumeBackup(resource.name, volName, backupName)
+		if err == nil {
+			op.Wait()
+		}
+	}()
+
+	var targetName string
+	if len(args) > 2 {
+		targetName = args[2]
+	} else {
+		targetName = "backup.tar.gz"
+	}
+
+	target, err := os.Create(shared.HostPath(targetName))
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	// Prepare the download request
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Exporting the backup: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+	backupFileRequest := lxd.BackupFileRequest{
+		BackupFile:      io.WriteSeeker(target),
+		ProgressHandler: progress.UpdateProgress,
+	}
+
+	// Export tarball
+	_, err = client.GetStoragePoolVolumeBackupFile(resource.name, volName, backupName, &backupFileRequest)
+	if err != nil {
+		os.Remove(targetName)
+		progress.Done("")
+		return errors.Wrap(err, "Fetch storage volume backup file")
+	}
+
+	progress.Done(i18n.G("Backup exported successfully!"))
+	return nil
+}
+
+// Import
+type cmdStorageVolumeImport struct {
+	global        *cmdGlobal
+	storage       *cmdStorage
+	storageVolume *cmdStorageVolume
+
+	flagType string
+}
+
+func (c *cmdStorageVolumeImport) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("import [<remote>:]<pool> <backup file> [<volume name>]")
+	cmd.Short = i18n.G("Import custom storage volumes")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Import backups of custom volumes including their snapshots.
+
+With --type=block, a raw or qcow2 disk image is imported as a new block custom volume.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc storage volume import default backup0.tar.gz
+    Create a new custom volume using backup0.tar.gz as the source.
+
+lxc storage volume import default disk.qcow2 data --type=block
+    Create a new block custom volume named data from the disk.qcow2 image.`))
+
+	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.Flags().StringVar(&c.flagType, "type", "backup", i18n.G("Import type, backup or block")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageVolumeImport) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing pool name"))
+	}
+
+	client := resource.server
+
+	// Use the target when in a cluster
+	if c.storage.flagTarget != "" {
+		client = client.UseTarget(c.storage.flagTarget)
+	}
+
+	file, err := os.Open(shared.HostPath(args[1]))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fstat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Importing custom volume: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	fileReader := &ioprogress.ProgressReader{
+		ReadCloser: file,
+		Tracker: &ioprogress.ProgressTracker{
+			Length: fstat.Size(),
+			Handler: func(percent int64, speed int64) {
+				progress.UpdateProgress(ioprogress.ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2))})
+			},
+		},
+	}
+
+	var op lxd.Operation
+	switch c.flagType {
+	case "backup":
+		createArgs := lxd.StoragePoolVolumeBackupArgs{
+			BackupFile: fileReader,
+		}
+
+		if len(args) > 2 {
+			createArgs.Name = args[2]
+		}
+
+		op, err = client.CreateStoragePoolVolumeFromBackup(resource.name, createArgs)
+	case "block":
+		if len(args) < 3 {
+			return fmt.Errorf(i18n.G("A volume name is required when importing a disk image"))
+		}
+
+		op, err = client.CreateStoragePoolVolumeFromDiskImage(resource.name, lxd.StoragePoolVolumeDiskImageArgs{
+			DiskImageFile: fileReader,
+			Name:          args[2],
+		})
+	default:
+		return fmt.Errorf(i18n.G("Invalid import type %q"), c.flagType)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Wait for operation to finish
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+	"github.com/lxc/lxd/shared/units"
+)
+
+type cmdTop struct {
+	global *cmdGlobal
+
+	flagSort   string
+	flagDelay  float64
+	flagCount  int
+	flagFormat string
+}
+
+// Columns the instances can be sorted by.
+var topSortKeys = []string{"name", "cpu", "memory", "swap", "rx", "tx", "processes"}
+
+// topUsage is the resource usage of an instance between two refreshes.
+type topUsage struct {
+	Name                string  `json:"name" yaml:"name"`
+	CPUUsage            float64 `json:"cpu_usage" yaml:"cpu_usage"`
+	MemoryUsage         int64   `json:"memory_usage" yaml:"memory_usage"`
+	SwapUsage           int64   `json:"swap_usage" yaml:"swap_usage"`
+	NetworkReceiveRate  int64   `json:"network_receive_rate" yaml:"network_receive_rate"`
+	NetworkTransmitRate int64   `json:"network_transmit_rate" yaml:"network_transmit_rate"`
+	Processes           int64   `json:"processes" yaml:"processes"`
+}
+
+// topSnapshot holds the state of the running instances at a point in time.
+type topSnapshot struct {
+	time   time.Time
+	states map[string]api.InstanceState
+}
+
+func (c *cmdTop) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("top [<remote>:]")
+	cmd.Short = i18n.G("Show the resource usage of the running instances")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show the resource usage of the running instances
+
+The table is refreshed every few seconds, the CPU usage and network rates
+being averaged since the previous refresh. The CPU usage is a percentage of
+one CPU.
+
+The instances can be sorted by:
+  name, cpu, memory, swap, rx, tx or processes`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc top
+    Show the instances using the most CPU first, refreshing every 3 seconds.
+
+lxc top --sort=memory --delay=10
+    Show the instances using the most memory first, refreshing every 10 seconds.
+
+lxc top --count=1 --format=csv
+    Show the usage over 3 seconds once.`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().StringVarP(&c.flagSort, "sort", "s", "cpu", i18n.G("Column to sort the instances by")+"``")
+	cmd.Flags().Float64VarP(&c.flagDelay, "delay", "d", 3, i18n.G("Number of seconds between refreshes")+"``")
+	cmd.Flags().IntVarP(&c.flagCount, "count", "n", 0, i18n.G("Number of refreshes before exiting (0 for no limit)")+"``")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	return cmd
+}
+
+func (c *cmdTop) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	if !shared.StringInSlice(c.flagSort, topSortKeys) {
+		return fmt.Errorf(i18n.G("Invalid sort column %q, must be one of: %s"), c.flagSort, strings.Join(topSortKeys, ", "))
+	}
+
+	if c.flagDelay <= 0 {
+		return fmt.Errorf(i18n.G("The delay must be greater than zero"))
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name != "" {
+		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
+	}
+
+	clear := c.flagFormat == "table" && termios.IsTerminal(int(os.Stdout.Fd()))
+	delay := time.Duration(c.flagDelay * float64(time.Second))
+
+	previous, err := c.snapshot(resource.server)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; c.flagCount == 0 || i < c.flagCount; i++ {
+		time.Sleep(delay)
+
+		current, err := c.snapshot(resource.server)
+		if err != nil {
+			return err
+		}
+
+		usages := topUsages(previous, current)
+		topSort(usages, c.flagSort)
+
+		if clear {
+			// Move to the top left corner and clear the screen.
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf(i18n.G("%s - %d running instances")+"\n", current.time.Format("15:04:05"), len(usages))
+		}
+
+		err = c.render(usages)
+		if err != nil {
+			return err
+		}
+
+		previous = current
+	}
+
+	return nil
+}
+
+func (c *cmdTop) snapshot(server lxd.InstanceServer) (*topSnapshot, error) {
+	instances, err := server.GetInstancesFull(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &topSnapshot{time: time.Now(), states: map[string]api.InstanceState{}}
+	for _, inst := range instances {
+		if inst.State == nil || inst.State.StatusCode != api.Running {
+			continue
+		}
+
+		snapshot.states[inst.Name] = *inst.State
+	}
+
+	return snapshot, nil
+}
+
+func (c *cmdTop) render(usages []topUsage) error {
+	data := [][]string{}
+	for _, usage := range usages {
+		data = append(data, []string{
+			usage.Name,
+			fmt.Sprintf("%.1f%%", usage.CPUUsage),
+			units.GetByteSizeString(usage.MemoryUsage, 2),
+			units.GetByteSizeString(usage.SwapUsage, 2),
+			units.GetByteSizeString(usage.NetworkReceiveRate, 2) + "/s",
+			units.GetByteSizeString(usage.NetworkTransmitRate, 2) + "/s",
+			fmt.Sprintf("%d", usage.Processes),
+		})
+	}
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("CPU"),
+		i18n.G("MEMORY"),
+		i18n.G("SWAP"),
+		i18n.G("RX"),
+		i18n.G("TX"),
+		i18n.G("PROCESSES"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, usages)
+}
+
+// topUsages computes the usage of the instances running in both snapshots.
+func topUsages(previous *topSnapshot, current *topSnapshot) []topUsage {
+	elapsed := current.time.Sub(previous.time)
+
+	usages := []topUsage{}
+	for name, state := range current.states {
+		usage := topUsage{
+			Name:        name,
+			MemoryUsage: state.Memory.Usage,
+			SwapUsage:   state.Memory.SwapUsage,
+			Processes:   state.Processes,
+		}
+
+		// Instances which just started have no rates yet.
+		prevState, ok := previous.states[name]
+		if ok && elapsed > 0 {
+			rate := func(current int64, previous int64) int64 {
+				if current < previous {
+					return 0
+				}
+
+				return int64(float64(current-previous) / elapsed.Seconds())
+			}
+
+			if state.CPU.Usage >= prevState.CPU.Usage && prevState.CPU.Usage >= 0 {
+				usage.CPUUsage = float64(state.CPU.Usage-prevState.CPU.Usage) / float64(elapsed) * 100
+			}
+
+			received, sent := topNetworkCounters(state)
+			prevReceived, prevSent := topNetworkCounters(prevState)
+			usage.NetworkReceiveRate = rate(received, prevReceived)
+			usage.NetworkTransmitRate = rate(sent, prevSent)
+		}
+
+		usages = append(usages, usage)
+	}
+
+	return usages
+}
+
+// Returns the bytes received and sent on the network interfaces of an
+// instance, other than the loopback one.
+func topNetworkCounters(state api.InstanceState) (int64, int64) {
+	var received, sent int64
+	for _, nic := range state.Network {
+		if nic.Type == "loopback" {
+			continue
+		}
+
+		received += nic.Counters.BytesReceived
+		sent += nic.Counters.BytesSent
+	}
+
+	return received, sent
+}
+
+// topSort sorts the usages by the given column, the highest usage first.
+func topSort(usages []topUsage, column string) {
+	sort.SliceStable(usages, func(i, j int) bool {
+		a, b := usages[i], usages[j]
+
+		var x, y float64
+		switch column {
+		case "cpu":
+			x, y = a.CPUUsage, b.CPUUsage
+		case "memory":
+			x, y = float64(a.MemoryUsage), float64(b.MemoryUsage)
+		case "swap":
+			x, y = float64(a.SwapUsage), float64(b.SwapUsage)
+		case "rx":
+			x, y = float64(a.NetworkReceiveRate), float64(b.NetworkReceiveRate)
+		case "tx":
+			x, y = float64(a.NetworkTransmitRate), float64(b.NetworkTransmitRate)
+		case "processes":
+			x, y = float64(a.Processes), float64(b.Processes)
+		}
+
+		if x != y {
+			return x > y
+		}
+
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdWarning struct {
+	global *cmdGlobal
+}
+
+func (c *cmdWarning) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("warning")
+	cmd.Short = i18n.G("List, show, acknowledge and delete warnings")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List, show, acknowledge and delete warnings
+
+Warnings record problems detected by the server, like missing kernel
+features or failing background tasks. They are resolved automatically
+once the problem is gone.`))
+
+	// Acknowledge
+	warningAckCmd := cmdWarningAck{global: c.global, warning: c}
+	cmd.AddCommand(warningAckCmd.Command())
+
+	// Delete
+	warningDeleteCmd := cmdWarningDelete{global: c.global, warning: c}
+	cmd.AddCommand(warningDeleteCmd.Command())
+
+	// List
+	warningListCmd := cmdWarningList{global: c.global, warning: c}
+	cmd.AddCommand(warningListCmd.Command())
+
+	// Show
+	warningShowCmd := cmdWarningShow{global: c.global, warning: c}
+	cmd.AddCommand(warningShowCmd.Command())
+
+	return cmd
+}
+
+// Acknowledge
+type cmdWarningAck struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+}
+
+func (c *cmdWarningAck) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("ack [<remote>:]<warning>")
+	cmd.Short = i18n.G("Acknowledge a warning")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Acknowledge a warning
+
+Acknowledged warnings are hidden from the default listing until they
+are resolved and happen again.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningAck) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// Acknowledge the warning
+	err = resource.server.UpdateWarning(resource.name, api.WarningPut{Status: "acknowledged"}, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Delete
+type cmdWarningDelete struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+}
+
+func (c *cmdWarningDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<warning>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete a warning")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete a warning`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// Delete the warning
+	err = resource.server.DeleteWarning(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Warning %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// List
+type cmdWarningList struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+
+	flagAll    bool
+	flagFormat string
+}
+
+func (c *cmdWarningList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List warnings")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List warnings
+
+By default, acknowledged and resolved warnings are not shown.`))
+	cmd.Flags().BoolVarP(&c.flagAll, "all", "a", false, i18n.G("List all warnings, including acknowledged and resolved ones"))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name != "" {
+		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
+	}
+
+	// Get warnings
+	allWarnings, err := resource.server.GetWarnings()
+	if err != nil {
+		return err
+	}
+
+	warnings := []api.Warning{}
+	for _, warning := range allWarnings {
+		if !c.flagAll && warning.Status != "new" {
+			continue
+		}
+
+		warnings = append(warnings, warning)
+	}
+
+	// Render the table
+	data := [][]string{}
+	for _, warning := range warnings {
+		entry := []string{warning.UUID, warning.Type, strings.ToUpper(warning.Status), fmt.Sprintf("%d", warning.Count), warning.Project, warning.LastSeenAt.UTC().Format("2006/01/02 15:04 UTC")}
+		if resource.server.IsClustered() {
+			entry = append(entry, warning.Location)
+		}
+
+		data = append(data, entry)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("UUID"),
+		i18n.G("TYPE"),
+		i18n.G("STATUS"),
+		i18n.G("COUNT"),
+		i18n.G("PROJECT"),
+		i18n.G("LAST SEEN")}
+	if resource.server.IsClustered() {
+		header = append(header, i18n.G("LOCATION"))
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, warnings)
+}
+
+// Show
+type cmdWarningShow struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+}
+
+func (c *cmdWarningShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<warning>")
+	cmd.Short = i18n.G("Show details on a warning")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show details on a warning`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// Get the warning
+	warning, _, err := resource.server.GetWarning(resource.name)
+	if err != nil {
+		return err
+	}
+
+	// Render as YAML
+	data, err := yaml.Marshal(&warning)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
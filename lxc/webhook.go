@@ -0,0 +1,457 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdWebhook struct {
+	global *cmdGlobal
+}
+
+func (c *cmdWebhook) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("webhook")
+	cmd.Short = i18n.G("Manage webhooks")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage webhooks
+
+Webhooks are URLs the lifecycle events are POSTed to, optionally only
+the ones of some types, actions or projects.`))
+
+	// Create
+	webhookCreateCmd := cmdWebhookCreate{global: c.global, webhook: c}
+	cmd.AddCommand(webhookCreateCmd.Command())
+
+	// Delete
+	webhookDeleteCmd := cmdWebhookDelete{global: c.global, webhook: c}
+	cmd.AddCommand(webhookDeleteCmd.Command())
+
+	// Edit
+	webhookEditCmd := cmdWebhookEdit{global: c.global, webhook: c}
+	cmd.AddCommand(webhookEditCmd.Command())
+
+	// List
+	webhookListCmd := cmdWebhookList{global: c.global, webhook: c}
+	cmd.AddCommand(webhookListCmd.Command())
+
+	// Show
+	webhookShowCmd := cmdWebhookShow{global: c.global, webhook: c}
+	cmd.AddCommand(webhookShowCmd.Command())
+
+	return cmd
+}
+
+// Splits a comma separated list given on the command line.
+func webhookSplitList(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+
+	return strings.Split(value, ",")
+}
+
+// Create
+type cmdWebhookCreate struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+
+	flagDescription string
+	flagSecret      string
+	flagTypes       string
+	flagActions     string
+	flagProjects    string
+}
+
+func (c *cmdWebhookCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<webhook> <URL>")
+	cmd.Short = i18n.G("Create webhooks")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create webhooks
+
+When a secret is set, the payloads are signed with it using HMAC-SHA256,
+the signature being sent in the X-LXD-Signature-256 header.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc webhook create chat https://chat.example.com/hooks/lxd --secret=s3cret
+    Send all the lifecycle events to the chat, signed.
+
+lxc webhook create tickets https://tickets.example.com/lxd --types=image --projects=default,web
+    Send the events about the images of the default and web projects.`))
+
+	cmd.Flags().StringVar(&c.flagDescription, "description", "", i18n.G("Webhook description")+"``")
+	cmd.Flags().StringVar(&c.flagSecret, "secret", "", i18n.G("Key to sign the payloads with")+"``")
+	cmd.Flags().StringVar(&c.flagTypes, "types", "", i18n.G("Comma separated types of entities to send the events about")+"``")
+	cmd.Flags().StringVar(&c.flagActions, "actions", "", i18n.G("Comma separated actions to send the events of")+"``")
+	cmd.Flags().StringVar(&c.flagProjects, "projects", "", i18n.G("Comma separated projects to send the events of")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing webhook name"))
+	}
+
+	// Create the webhook
+	webhook := api.WebhooksPost{
+		Name: resource.name,
+		WebhookPut: api.WebhookPut{
+			Description: c.flagDescription,
+			URL:         args[1],
+			Secret:      c.flagSecret,
+			Types:       webhookSplitList(c.flagTypes),
+			Actions:     webhookSplitList(c.flagActions),
+			Projects:    webhookSplitList(c.flagProjects),
+		},
+	}
+
+	err = resource.server.CreateWebhook(webhook)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Webhook %s created")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdWebhookDelete struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+}
+
+func (c *cmdWebhookDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<webhook>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete webhooks")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete webhooks`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing webhook name"))
+	}
+
+	// Delete the webhook
+	err = resource.server.DeleteWebhook(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Webhook %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Edit
+type cmdWebhookEdit struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+}
+
+func (c *cmdWebhookEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<webhook>")
+	cmd.Short = i18n.G("Edit webhook configurations as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit webhook configurations as YAML`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc webhook edit <webhook> < webhook.yaml
+    Update a webhook using the content of webhook.yaml`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a yaml representation of the webhook.
+### Any line starting with a '# will be ignored.
+###
+### An example would look like:
+### description: Ticketing system
+### url: https://tickets.example.com/lxd
+### secret: s3cret
+### types:
+### - image
+### actions: []
+### projects:
+### - default
+###
+### Note that the name and delivery status are shown but cannot be changed,
+### and that the secret isn't shown and is kept unless a new one is given`)
+}
+
+func (c *cmdWebhookEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing webhook name"))
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.WebhookPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateWebhook(resource.name, newdata, "")
+	}
+
+	// Extract the current value
+	webhook, etag, err := resource.server.GetWebhook(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&webhook)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.WebhookPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateWebhook(resource.name, newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// List
+type cmdWebhookList struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+
+	flagFormat string
+}
+
+func (c *cmdWebhookList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List webhooks")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List webhooks`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name != "" {
+		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
+	}
+
+	// Get webhooks
+	webhooks, err := resource.server.GetWebhooks()
+	if err != nil {
+		return err
+	}
+
+	// Render the table
+	data := [][]string{}
+	for _, webhook := range webhooks {
+		lastDelivery := ""
+		if !webhook.LastDeliveryAt.IsZero() {
+			lastDelivery = webhook.LastDeliveryAt.UTC().Format("2006/01/02 15:04 UTC")
+		}
+
+		data = append(data, []string{
+			webhook.Name,
+			webhook.URL,
+			strings.Join(webhook.Projects, "\n"),
+			strings.ToUpper(webhook.LastDeliveryStatus),
+			lastDelivery,
+			fmt.Sprintf("%d", webhook.FailedDeliveries),
+		})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("URL"),
+		i18n.G("PROJECTS"),
+		i18n.G("LAST DELIVERY"),
+		i18n.G("DELIVERED AT"),
+		i18n.G("FAILURES"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, webhooks)
+}
+
+// Show
+type cmdWebhookShow struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+}
+
+func (c *cmdWebhookShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<webhook>")
+	cmd.Short = i18n.G("Show webhook configurations and delivery status")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show webhook configurations and delivery status`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing webhook name"))
+	}
+
+	// Get the webhook
+	webhook, _, err := resource.server.GetWebhook(resource.name)
+	if err != nil {
+		return err
+	}
+
+	// Render as YAML
+	data, err := yaml.Marshal(&webhook)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
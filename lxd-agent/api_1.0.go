@@ -23,6 +23,7 @@ var api10 = []APIEndpoint{
 	operationCmd,
 	operationWebsocket,
 	stateCmd,
+	metricsCmd,
 }
 
 func api10Get(d *Daemon, r *http.Request) response.Response {
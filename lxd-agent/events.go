@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -45,7 +46,7 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	// If this request is an internal one initiated by another node wanting
 	// to watch the events on this node, set the listener to broadcast only
 	// local events.
-	listener, err := d.events.AddListener("default", c, strings.Split(typeStr, ","), "lxd-agent", false)
+	listener, err := d.events.AddListener("default", c, strings.Split(typeStr, ","), "lxd-agent", false, false, events.Filter{}, -1)
 	if err != nil {
 		return err
 	}
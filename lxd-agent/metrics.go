@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/metrics"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+)
+
+var metricsCmd = APIEndpoint{
+	Name: "metrics",
+	Path: "metrics",
+
+	Get: APIEndpointAction{Handler: metricsGet},
+}
+
+// Prefixes of the virtual block devices.
+var metricsVirtualDisks = []string{"loop", "ram", "dm-", "md", "zram"}
+
+// Returns the statistics of the disks of the virtual machine, which the host
+// can't tell apart from those of its other processes.
+func metricsGet(d *Daemon, r *http.Request) response.Response {
+	content, err := ioutil.ReadFile("/proc/diskstats")
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	stats, err := metrics.ParseDiskStats(string(content))
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	// Only keep the whole disks, leaving out partitions and virtual devices
+	// which would count the same bytes again or none at all.
+	for name := range stats {
+		if !shared.PathExists(filepath.Join("/sys/block", name)) {
+			delete(stats, name)
+			continue
+		}
+
+		for _, prefix := range metricsVirtualDisks {
+			if strings.HasPrefix(name, prefix) {
+				delete(stats, name)
+				break
+			}
+		}
+	}
+
+	return response.SyncResponse(true, stats)
+}
@@ -52,7 +52,9 @@ var api10 = []APIEndpoint{
 	instanceSnapshotCmd,
 	instanceSnapshotsCmd,
 	instanceStateCmd,
+	instanceStateHistoryCmd,
 	eventsCmd,
+	healthCmd,
 	imageAliasCmd,
 	imageAliasesCmd,
 	imageCmd,
@@ -60,6 +62,7 @@ var api10 = []APIEndpoint{
 	imageRefreshCmd,
 	imagesCmd,
 	imageSecretCmd,
+	metricsCmd,
 	networkCmd,
 	networkLeasesCmd,
 	networksCmd,
@@ -80,13 +83,23 @@ var api10 = []APIEndpoint{
 	storagePoolVolumeSnapshotTypeCmd,
 	storagePoolVolumesTypeCmd,
 	storagePoolVolumeTypeContainerCmd,
+	storagePoolVolumeTypeCustomBackupsCmd,
+	storagePoolVolumeTypeCustomBackupCmd,
+	storagePoolVolumeTypeCustomBackupExportCmd,
 	storagePoolVolumeTypeCustomCmd,
 	storagePoolVolumeTypeImageCmd,
 	storagePoolVolumeTypeVMCmd,
+	warningCmd,
+	warningsCmd,
+	webhookCmd,
+	webhooksCmd,
 }
 
 func api10Get(d *Daemon, r *http.Request) response.Response {
 	authMethods := []string{"tls"}
+	oidcIssuer := ""
+	oidcClientID := ""
+	oidcAudience := ""
 	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		config, err := cluster.ConfigLoad(tx)
 		if err != nil {
@@ -99,6 +112,11 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 			authMethods = append(authMethods, "candid")
 		}
 
+		oidcIssuer, oidcClientID, oidcAudience = config.OIDCServer()
+		if oidcIssuer != "" {
+			authMethods = append(authMethods, "oidc")
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -111,6 +129,9 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 		Public:        false,
 		Auth:          "untrusted",
 		AuthMethods:   authMethods,
+		OIDCIssuer:    oidcIssuer,
+		OIDCClientID:  oidcClientID,
+		OIDCAudience:  oidcAudience,
 	}
 
 	// If untrusted, return now
@@ -239,9 +260,16 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 
 	fullSrv := api.Server{ServerUntrusted: srv}
 	fullSrv.Environment = env
-	fullSrv.Config, err = daemonConfigRender(d.State())
-	if err != nil {
-		return response.InternalError(err)
+
+	// Restricted clients don't get to see the server configuration.
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		fullSrv.Config = map[string]interface{}{}
+	} else {
+		fullSrv.Config, err = daemonConfigRender(d.State())
+		if err != nil {
+			return response.InternalError(err)
+		}
 	}
 
 	return response.SyncResponseETag(true, fullSrv, fullSrv.Config)
@@ -424,7 +452,17 @@ func doApi10Update(d *Daemon, req api.ServerPut, patch bool) response.Response {
 		} else {
 			clusterChanged, err = newClusterConfig.Replace(req.Config)
 		}
-		return err
+		if err != nil {
+			return err
+		}
+
+		// OpenID Connect tokens are always checked against an audience.
+		issuer, clientID, audience := newClusterConfig.OIDCServer()
+		if issuer != "" && clientID == "" && audience == "" {
+			return config.ErrorList{&config.Error{Name: "oidc.issuer", Value: issuer, Reason: "oidc.client.id or oidc.audience must be set too"}}
+		}
+
+		return nil
 	})
 	if err != nil {
 		switch err.(type) {
@@ -471,7 +509,9 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 
 	maasChanged := false
 	candidChanged := false
+	oidcChanged := false
 	rbacChanged := false
+	logForwardChanged := false
 
 	for key := range clusterChanged {
 		switch key {
@@ -481,6 +521,22 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			fallthrough
 		case "core.proxy_ignore_hosts":
 			daemonConfigSetProxy(d, clusterConfig)
+		case "core.audit_targets":
+			err := d.audit.SetTargets(clusterConfig.AuditTargets())
+			if err != nil {
+				return err
+			}
+		case "core.tracing_endpoint":
+			err := d.setupTracing(clusterConfig.TracingEndpoint())
+			if err != nil {
+				return err
+			}
+		case "logging.forward.protocol":
+			fallthrough
+		case "logging.forward.endpoint":
+			fallthrough
+		case "logging.forward.streams":
+			logForwardChanged = true
 		case "maas.api.url":
 			fallthrough
 		case "maas.api.key":
@@ -493,6 +549,14 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			fallthrough
 		case "candid.api.url":
 			candidChanged = true
+		case "oidc.audience":
+			fallthrough
+		case "oidc.client.id":
+			fallthrough
+		case "oidc.issuer":
+			fallthrough
+		case "oidc.subjects":
+			oidcChanged = true
 		case "images.auto_update_interval":
 			if !d.os.MockMode {
 				d.taskAutoUpdate.Reset()
@@ -501,6 +565,13 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			if !d.os.MockMode {
 				d.taskPruneImages.Reset()
 			}
+		case "core.usage_history_interval":
+			if !d.os.MockMode {
+				d.taskUsageHistory.Reset()
+			}
+		case "core.usage_history_size":
+			_, size := clusterConfig.UsageHistory()
+			d.usageHistory.SetSize(size)
 		case "rbac.agent.url":
 			fallthrough
 		case "rbac.agent.username":
@@ -568,6 +639,14 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		}
 	}
 
+	if logForwardChanged {
+		protocol, endpoint, streams := clusterConfig.LogForward()
+		err := d.setupLogForward(protocol, endpoint, streams)
+		if err != nil {
+			return err
+		}
+	}
+
 	if maasChanged {
 		url, key := clusterConfig.MAASController()
 		machine := nodeConfig.MAASMachine()
@@ -585,6 +664,14 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		}
 	}
 
+	if oidcChanged {
+		issuer, clientID, audience := clusterConfig.OIDCServer()
+		err := d.setupOIDC(issuer, clientID, audience, clusterConfig.OIDCSubjects())
+		if err != nil {
+			return err
+		}
+	}
+
 	if rbacChanged {
 		apiURL, apiKey, apiExpiry, agentURL, agentUsername, agentPrivateKey, agentPublicKey := clusterConfig.RBACServer()
 
@@ -39,7 +39,8 @@ var clusterCmd = APIEndpoint{
 var clusterNodesCmd = APIEndpoint{
 	Path: "cluster/members",
 
-	Get: APIEndpointAction{Handler: clusterNodesGet, AccessHandler: AllowAuthenticated},
+	Get:  APIEndpointAction{Handler: clusterNodesGet, AccessHandler: AllowAuthenticated},
+	Post: APIEndpointAction{Handler: clusterNodesPost},
 }
 
 var clusterNodeCmd = APIEndpoint{
@@ -362,7 +363,7 @@ func clusterPutJoin(d *Daemon, req api.ClusterPut) response.Response {
 		// If the user has provided a cluster password, setup the trust
 		// relationship by adding our own certificate to the cluster.
 		if req.ClusterPassword != "" {
-			err = cluster.SetupTrust(string(cert.PublicKey()), req.ClusterAddress,
+			err = cluster.SetupTrust(string(cert.PublicKey()), req.ServerName, req.ClusterAddress,
 				string(req.ClusterCertificate), req.ClusterPassword)
 			if err != nil {
 				return errors.Wrap(err, "Failed to setup cluster trust")
@@ -859,6 +860,81 @@ func clusterNodesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, result)
 }
 
+// Create a single-use join token for a new cluster member.
+func clusterNodesPost(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterMembersPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.ServerName == "" {
+		return response.BadRequest(fmt.Errorf("The cluster member name must be provided"))
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	// Get the addresses of the online members, which the new member can
+	// try to join through.
+	nameInUse := false
+	addresses := []string{}
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		nodes, err := tx.Nodes()
+		if err != nil {
+			return err
+		}
+
+		offlineThreshold, err := tx.NodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			if node.Name == req.ServerName {
+				nameInUse = true
+			}
+
+			if node.IsOffline(offlineThreshold) {
+				continue
+			}
+
+			addresses = append(addresses, node.Address)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if nameInUse {
+		return response.BadRequest(fmt.Errorf("The cluster already has a member with name: %s", req.ServerName))
+	}
+
+	secret, err := shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	meta := map[string]interface{}{
+		"server_name": req.ServerName,
+		"fingerprint": d.endpoints.NetworkCert().Fingerprint(),
+		"addresses":   addresses,
+		"secret":      secret,
+	}
+
+	return tokenOperationCreate(d, db.OperationClusterJoinToken, meta)
+}
+
 func clusterNodeGet(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
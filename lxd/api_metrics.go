@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/instance/qemu"
+	"github.com/lxc/lxd/lxd/metrics"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var metricsCmd = APIEndpoint{
+	Path: "metrics",
+
+	Get: APIEndpointAction{Handler: metricsGet, AccessHandler: allowMetrics, AllowUntrusted: true},
+}
+
+// allowMetrics is an AccessHandler which allows trusted clients as well as
+// clients using a metrics certificate.
+func allowMetrics(d *Daemon, r *http.Request) response.Response {
+	if d.checkTrustedClient(r) == nil {
+		return response.EmptySyncResponse
+	}
+
+	if r.TLS != nil {
+		d.clientCertsLock.RLock()
+		metricsCerts := d.metricsCerts
+		d.clientCertsLock.RUnlock()
+
+		for i := range r.TLS.PeerCertificates {
+			trusted, _ := util.CheckTrustState(*r.TLS.PeerCertificates[i], metricsCerts)
+			if trusted {
+				return response.EmptySyncResponse
+			}
+		}
+	}
+
+	return response.Forbidden(nil)
+}
+
+// metricsResponse renders a metric set in the OpenMetrics text format.
+type metricsResponse struct {
+	set *metrics.MetricSet
+}
+
+func (r *metricsResponse) Render(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	_, err := w.Write([]byte(r.set.String()))
+	return err
+}
+
+func (r *metricsResponse) String() string {
+	return "metrics"
+}
+
+// /1.0/metrics
+// Get the metrics of the local instances and of the daemon
+func metricsGet(d *Daemon, r *http.Request) response.Response {
+	// Restricted clients only get the metrics of the instances of their projects.
+	projects, restricted := d.userRestrictedProjects(r)
+
+	insts, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	set := metrics.NewMetricSet(nil)
+	setLock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for _, inst := range insts {
+		if restricted && !shared.StringInSlice(inst.Project(), projects) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(inst instance.Instance) {
+			defer wg.Done()
+
+			instSet := instanceMetrics(inst)
+
+			setLock.Lock()
+			set.Merge(instSet)
+			setLock.Unlock()
+		}(inst)
+	}
+
+	wg.Wait()
+
+	if !restricted {
+		set.Merge(daemonMetrics(d))
+	}
+
+	return &metricsResponse{set: set}
+}
+
+// instanceMetrics collects the metrics of an instance, relying on the cgroups
+// for containers and on the agent for virtual machines.
+func instanceMetrics(inst instance.Instance) *metrics.MetricSet {
+	set := metrics.NewMetricSet(map[string]string{
+		"name":    inst.Name(),
+		"project": inst.Project(),
+		"type":    inst.Type().String(),
+	})
+
+	if !inst.IsRunning() {
+		set.AddSamples(metrics.InstanceRunning, metrics.Sample{Value: 0})
+		return set
+	}
+
+	set.AddSamples(metrics.InstanceRunning, metrics.Sample{Value: 1})
+
+	state, err := inst.RenderState()
+	if err != nil {
+		logger.Warn("Failed to get instance state for metrics", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+		return set
+	}
+
+	set.AddSamples(metrics.CPUSeconds, metrics.Sample{Value: float64(state.CPU.Usage) / float64(time.Second)})
+	set.AddSamples(metrics.MemoryUsageBytes, metrics.Sample{Value: float64(state.Memory.Usage)})
+	set.AddSamples(metrics.MemoryUsagePeakBytes, metrics.Sample{Value: float64(state.Memory.UsagePeak)})
+	set.AddSamples(metrics.SwapUsageBytes, metrics.Sample{Value: float64(state.Memory.SwapUsage)})
+	set.AddSamples(metrics.SwapUsagePeakBytes, metrics.Sample{Value: float64(state.Memory.SwapUsagePeak)})
+	set.AddSamples(metrics.Processes, metrics.Sample{Value: float64(state.Processes)})
+
+	for name, disk := range state.Disk {
+		set.AddSamples(metrics.FilesystemUsageBytes, metrics.Sample{Labels: map[string]string{"device": name}, Value: float64(disk.Usage)})
+	}
+
+	read, written, err := instanceDiskIO(inst)
+	if err != nil {
+		logger.Debug("Failed to get instance disk I/O for metrics", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+	} else {
+		set.AddSamples(metrics.DiskReadBytes, metrics.Sample{Value: float64(read)})
+		set.AddSamples(metrics.DiskWrittenBytes, metrics.Sample{Value: float64(written)})
+	}
+
+	for name, nic := range state.Network {
+		labels := map[string]string{"device": name}
+		set.AddSamples(metrics.NetworkReceiveBytes, metrics.Sample{Labels: labels, Value: float64(nic.Counters.BytesReceived)})
+		set.AddSamples(metrics.NetworkTransmitBytes, metrics.Sample{Labels: labels, Value: float64(nic.Counters.BytesSent)})
+		set.AddSamples(metrics.NetworkReceivePackets, metrics.Sample{Labels: labels, Value: float64(nic.Counters.PacketsReceived)})
+		set.AddSamples(metrics.NetworkTransmitPackets, metrics.Sample{Labels: labels, Value: float64(nic.Counters.PacketsSent)})
+	}
+
+	return set
+}
+
+// instanceDiskIO returns the bytes read from and written to the disks of a
+// running instance, from its cgroup for containers and from its agent for
+// virtual machines.
+func instanceDiskIO(inst instance.Instance) (int64, int64, error) {
+	switch t := inst.(type) {
+	case *containerLXC:
+		cg, err := t.cgroup(nil)
+		if err != nil {
+			return -1, -1, err
+		}
+
+		return cg.GetIOStats()
+	case *qemu.Qemu:
+		stats, err := t.AgentDiskStats()
+		if err != nil {
+			return -1, -1, err
+		}
+
+		var read, written int64
+		for _, disk := range stats {
+			read += disk.ReadBytes
+			written += disk.WrittenBytes
+		}
+
+		return read, written, nil
+	}
+
+	return -1, -1, fmt.Errorf("Unsupported instance type")
+}
+
+// daemonMetrics collects the metrics of the daemon itself.
+func daemonMetrics(d *Daemon) *metrics.MetricSet {
+	set := metrics.NewMetricSet(nil)
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	set.AddSamples(metrics.Goroutines, metrics.Sample{Value: float64(runtime.NumGoroutine())})
+	set.AddSamples(metrics.GoHeapAllocBytes, metrics.Sample{Value: float64(memStats.HeapAlloc)})
+	set.AddSamples(metrics.GoSysBytes, metrics.Sample{Value: float64(memStats.Sys)})
+	set.AddSamples(metrics.EventListeners, metrics.Sample{Value: float64(d.events.ListenerCount())})
+	set.AddSamples(metrics.UptimeSeconds, metrics.Sample{Value: time.Since(d.startTime).Seconds()})
+
+	// Count the operations by project and status
+	counts := map[[2]string]int{}
+	operations.Lock()
+	for _, op := range operations.Operations() {
+		counts[[2]string{op.Project(), op.Status().String()}]++
+	}
+	operations.Unlock()
+
+	for key, count := range counts {
+		set.AddSamples(metrics.Operations, metrics.Sample{
+			Labels: map[string]string{"project": key[0], "status": key[1]},
+			Value:  float64(count),
+		})
+	}
+
+	return set
+}
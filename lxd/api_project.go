@@ -12,7 +12,10 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/operations"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -96,13 +99,18 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 	if project.Config == nil {
 		project.Config = map[string]string{}
 	}
-	for _, feature := range []string{"features.images", "features.profiles"} {
+	for _, feature := range []string{"features.images", "features.profiles", "features.storage.volumes"} {
 		_, ok := project.Config[feature]
 		if !ok {
 			project.Config[feature] = "true"
 		}
 	}
 
+	_, ok := project.Config["features.networks"]
+	if !ok {
+		project.Config["features.networks"] = "false"
+	}
+
 	err := json.NewDecoder(r.Body).Decode(&project)
 	if err != nil {
 		return response.BadRequest(err)
@@ -206,6 +214,8 @@ func projectGet(d *Daemon, r *http.Request) response.Response {
 		project.Description,
 		project.Config["features.images"],
 		project.Config["features.profiles"],
+		project.Config["features.networks"],
+		project.Config["features.storage.volumes"],
 	}
 
 	return response.SyncResponseETag(true, project, etag)
@@ -235,6 +245,8 @@ func projectPut(d *Daemon, r *http.Request) response.Response {
 		project.Description,
 		project.Config["features.images"],
 		project.Config["features.profiles"],
+		project.Config["features.networks"],
+		project.Config["features.storage.volumes"],
 	}
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -249,6 +261,11 @@ func projectPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	// Only administrators may change the restrictions of a project
+	if projecthelpers.RestrictionsChanged(project.Config, req.Config) && !d.userIsAdmin(r) {
+		return response.Forbidden(fmt.Errorf("Only administrators can change project restrictions"))
+	}
+
 	return projectChange(d, project, req)
 }
 
@@ -276,6 +293,8 @@ func projectPatch(d *Daemon, r *http.Request) response.Response {
 		project.Description,
 		project.Config["features.images"],
 		project.Config["features.profiles"],
+		project.Config["features.networks"],
+		project.Config["features.storage.volumes"],
 	}
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -316,13 +335,34 @@ func projectPatch(d *Daemon, r *http.Request) response.Response {
 		req.Config["features.images"] = project.Config["features.profiles"]
 	}
 
+	_, err = reqRaw.GetBool("features.networks")
+	if err != nil {
+		req.Config["features.networks"] = project.Config["features.networks"]
+	}
+
+	_, err = reqRaw.GetBool("features.storage.volumes")
+	if err != nil {
+		req.Config["features.storage.volumes"] = project.Config["features.storage.volumes"]
+	}
+
+	// Only administrators may change the restrictions of a project
+	if projecthelpers.RestrictionsChanged(project.Config, req.Config) && !d.userIsAdmin(r) {
+		return response.Forbidden(fmt.Errorf("Only administrators can change project restrictions"))
+	}
+
 	return projectChange(d, project, req)
 }
 
 // Common logic between PUT and PATCH.
 func projectChange(d *Daemon, project *api.Project, req api.ProjectPut) response.Response {
 	// Flag indicating if any feature has changed.
-	featuresChanged := req.Config["features.images"] != project.Config["features.images"] || req.Config["features.profiles"] != project.Config["features.profiles"]
+	featuresChanged := false
+	for _, feature := range []string{"features.images", "features.profiles", "features.networks", "features.storage.volumes"} {
+		if req.Config[feature] != project.Config[feature] {
+			featuresChanged = true
+			break
+		}
+	}
 
 	// Sanity checks
 	if project.Name == "default" && featuresChanged {
@@ -339,6 +379,14 @@ func projectChange(d *Daemon, project *api.Project, req api.ProjectPut) response
 		return response.BadRequest(err)
 	}
 
+	// Check that the existing instances and profiles comply with the new restrictions
+	if projecthelpers.IsRestricted(req.Config) && projecthelpers.RestrictionsChanged(project.Config, req.Config) {
+		err = projectCheckExistingRestrictions(d.cluster, project.Name, req.Config)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	// Update the database entry
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		err := tx.ProjectUpdate(project.Name, req)
@@ -428,6 +476,9 @@ func projectPost(d *Daemon, r *http.Request) response.Response {
 			}
 		}
 
+		// Refresh the projects of restricted certificates.
+		readSavedClientCAList(d)
+
 		return nil
 	}
 
@@ -476,6 +527,9 @@ func projectDelete(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	// Refresh the projects of restricted certificates.
+	readSavedClientCAList(d)
+
 	return response.EmptySyncResponse
 }
 
@@ -493,8 +547,10 @@ func projectIsEmpty(project *api.Project) bool {
 
 // Validate the project configuration
 var projectConfigKeys = map[string]func(value string) error{
-	"features.profiles": shared.IsBool,
-	"features.images":   shared.IsBool,
+	"features.profiles":        shared.IsBool,
+	"features.images":          shared.IsBool,
+	"features.networks":        shared.IsBool,
+	"features.storage.volumes": shared.IsBool,
 }
 
 func projectValidateConfig(config map[string]string) error {
@@ -508,6 +564,10 @@ func projectValidateConfig(config map[string]string) error {
 
 		// Then validate
 		validator, ok := projectConfigKeys[key]
+		if !ok {
+			validator, ok = projecthelpers.RestrictionValidators[key]
+		}
+
 		if !ok {
 			return fmt.Errorf("Invalid project configuration key: %s", k)
 		}
@@ -520,3 +580,166 @@ func projectValidateConfig(config map[string]string) error {
 
 	return nil
 }
+
+// Fetch the configuration of a project along with the names of the managed
+// networks, as needed to check the project restrictions.
+func projectRestrictionsData(cluster *db.Cluster, projectName string) (map[string]string, []string, error) {
+	var config map[string]string
+	networks := []string{}
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		project, err := tx.ProjectGet(projectName)
+		if err != nil {
+			return errors.Wrapf(err, "Fetch project %q", projectName)
+		}
+
+		config = project.Config
+		if !projecthelpers.IsRestricted(config) {
+			return nil
+		}
+
+		networks, err = projectNetworkNames(tx, projectName)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return config, networks, nil
+}
+
+// Return the names of the managed networks visible from the given project.
+func projectNetworkNames(tx *db.ClusterTx, projectName string) ([]string, error) {
+	hasNetworks, err := tx.ProjectHasNetworks(projectName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Check project features")
+	}
+
+	networksProject := projectName
+	if !hasNetworks {
+		networksProject = "default"
+	}
+
+	ids, err := tx.NetworkIDsNotPendingByProject(networksProject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fetch managed networks")
+	}
+
+	networks := []string{}
+	for name := range ids {
+		networks = append(networks, name)
+	}
+
+	return networks, nil
+}
+
+// Check that the given instance config and devices, once expanded with the
+// given profiles, are allowed by the restrictions of the project.
+func projectAllowInstance(cluster *db.Cluster, projectName string, config map[string]string, devices deviceConfig.Devices, profiles []string) error {
+	projectConfig, networks, err := projectRestrictionsData(cluster, projectName)
+	if err != nil {
+		return err
+	}
+
+	if !projecthelpers.IsRestricted(projectConfig) {
+		return nil
+	}
+
+	profileList, err := cluster.ProfilesGet(projectName, profiles)
+	if err != nil {
+		return err
+	}
+
+	expandedConfig := db.ProfilesExpandConfig(config, profileList)
+	expandedDevices := db.ProfilesExpandDevices(devices, profileList)
+
+	return projecthelpers.AllowInstance(projectConfig, expandedConfig, expandedDevices.CloneNative(), networks)
+}
+
+// Check that the given profile config and devices are allowed by the
+// restrictions of the project.
+func projectAllowProfile(cluster *db.Cluster, projectName string, config map[string]string, devices map[string]map[string]string) error {
+	projectConfig, networks, err := projectRestrictionsData(cluster, projectName)
+	if err != nil {
+		return err
+	}
+
+	return projecthelpers.AllowProfile(projectConfig, config, devices, networks)
+}
+
+// Return the response for an error of projectAllowInstance or
+// projectAllowProfile. Only restriction violations are forbidden.
+func projectRestrictionResponse(err error) response.Response {
+	_, ok := errors.Cause(err).(projecthelpers.RestrictionError)
+	if ok {
+		return response.Forbidden(err)
+	}
+
+	return response.SmartError(err)
+}
+
+// Check that all the instances and profiles of a project comply with the
+// given new project configuration.
+func projectCheckExistingRestrictions(cluster *db.Cluster, projectName string, projectConfig map[string]string) error {
+	return cluster.Transaction(func(tx *db.ClusterTx) error {
+		networks, err := projectNetworkNames(tx, projectName)
+		if err != nil {
+			return err
+		}
+
+		hasProfiles, err := tx.ProjectHasProfiles(projectName)
+		if err != nil {
+			return errors.Wrap(err, "Check project features")
+		}
+
+		profilesProject := projectName
+		if !hasProfiles {
+			profilesProject = "default"
+		}
+
+		profiles, err := tx.ProfileList(db.ProfileFilter{Project: profilesProject})
+		if err != nil {
+			return errors.Wrap(err, "Fetch profiles")
+		}
+
+		apiProfiles := map[string]api.Profile{}
+		for _, profile := range profiles {
+			apiProfiles[profile.Name] = *db.ProfileToAPI(&profile)
+
+			if profilesProject != projectName {
+				continue
+			}
+
+			err := projecthelpers.AllowProfile(projectConfig, profile.Config, profile.Devices, networks)
+			if err != nil {
+				return errors.Wrapf(err, "Profile %q", profile.Name)
+			}
+		}
+
+		instances, err := tx.InstanceList(db.InstanceFilter{Project: projectName, Type: instancetype.Any})
+		if err != nil {
+			return errors.Wrap(err, "Fetch instances")
+		}
+
+		for _, inst := range instances {
+			profileList := []api.Profile{}
+			for _, name := range inst.Profiles {
+				profileList = append(profileList, apiProfiles[name])
+			}
+
+			expandedConfig := db.ProfilesExpandConfig(inst.Config, profileList)
+			expandedDevices := db.ProfilesExpandDevices(deviceConfig.NewDevices(inst.Devices), profileList)
+
+			err := projecthelpers.AllowInstance(projectConfig, expandedConfig, expandedDevices.CloneNative(), networks)
+			if err != nil {
+				return errors.Wrapf(err, "Instance %q", inst.Name)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/lxc/lxd/lxd/audit"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// auditRequest returns the audit entry of the given request, or nil if the
+// request isn't audited. Only the mutating requests of the public API are,
+// excluding the ones made by other cluster members, which the member the
+// client talked to already audits.
+func auditRequest(d *Daemon, r *http.Request, version string, identity string, protocol string) *api.EventAudit {
+	if !shared.StringInSlice(r.Method, []string{"POST", "PUT", "PATCH", "DELETE"}) {
+		return nil
+	}
+
+	if version == "internal" || protocol == "cluster" || !d.audit.Enabled() {
+		return nil
+	}
+
+	return &api.EventAudit{
+		Identity: identity,
+		Protocol: protocol,
+		Source:   r.RemoteAddr,
+		Method:   r.Method,
+		URL:      r.URL.RequestURI(),
+		Project:  projectParam(r),
+	}
+}
+
+// auditBodyDigest returns the digest of the body of a JSON request, leaving
+// the body in place for the handler. Bodies which aren't valid JSON get an
+// empty digest, the handler being in charge of rejecting them.
+func auditBodyDigest(r *http.Request) (string, error) {
+	if !util.IsJSONRequest(r) {
+		return "", nil
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+
+	r.Body = shared.BytesReadCloser{Buf: bytes.NewBuffer(body)}
+
+	digest, err := audit.BodyDigest(body)
+	if err != nil {
+		return "", nil
+	}
+
+	return digest, nil
+}
+
+// auditLog completes the given audit entry with the result of the request
+// and records it. The entries of the background operations run by this member
+// are only recorded once they're done, with their own result.
+func auditLog(d *Daemon, entry *api.EventAudit, w *auditResponseWriter, resp response.Response) {
+	// Background operations report their URL in the Location header.
+	if w.status == http.StatusAccepted {
+		entry.Operation = w.Header().Get("Location")
+
+		done := operations.OnDone(resp, func(op *operations.Operation) {
+			entry.StatusCode = w.status
+			entry.Result = "success"
+			if op.Status() != api.Success {
+				entry.Result = "failure"
+			}
+
+			d.audit.Log(*entry)
+		})
+		if done {
+			return
+		}
+	}
+
+	auditLogStatus(d, entry, w.status)
+}
+
+// auditLogStatus completes the given audit entry with the given status code
+// and records it.
+func auditLogStatus(d *Daemon, entry *api.EventAudit, status int) {
+	entry.StatusCode = status
+	if status < http.StatusBadRequest {
+		entry.Result = "success"
+	} else {
+		entry.Result = "failure"
+	}
+
+	d.audit.Log(*entry)
+}
+
+// auditResponseWriter records the status code of a response.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Flush() {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if ok {
+		flusher.Flush()
+	}
+}
+
+func (w *auditResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Response writer doesn't support hijacking")
+	}
+
+	return hijacker.Hijack()
+}
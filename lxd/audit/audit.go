@@ -0,0 +1,149 @@
+// Package audit records the mutating requests made to the LXD API.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/lxd/events"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Targets the audit entries can be sent to.
+const (
+	TargetFile   = "file"
+	TargetSyslog = "syslog"
+	TargetEvents = "events"
+)
+
+// Targets lists all the supported audit targets.
+var Targets = []string{TargetFile, TargetSyslog, TargetEvents}
+
+// Logger records audit entries to the configured targets.
+type Logger struct {
+	path   string
+	events *events.Server
+
+	file     *rotatingFile
+	syslog   *syslog.Writer
+	toEvents bool
+	forward  func(entry api.EventAudit)
+
+	mu sync.Mutex
+}
+
+// NewLogger returns a new audit logger, writing to the given file when the
+// file target is enabled and sending the entries to the given event server
+// when the events target is enabled. No target is enabled initially.
+func NewLogger(path string, events *events.Server) *Logger {
+	return &Logger{
+		path:   path,
+		events: events,
+	}
+}
+
+// SetTargets enables the given targets, disabling all others.
+func (l *Logger) SetTargets(targets []string) error {
+	for _, target := range targets {
+		if !shared.StringInSlice(target, Targets) {
+			return fmt.Errorf("Invalid audit target %q", target)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if shared.StringInSlice(TargetFile, targets) {
+		if l.file == nil {
+			file, err := newRotatingFile(l.path, maxFileSize, maxFileBackups)
+			if err != nil {
+				return err
+			}
+
+			l.file = file
+		}
+	} else if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+
+	if shared.StringInSlice(TargetSyslog, targets) {
+		if l.syslog == nil {
+			writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, "lxd-audit")
+			if err != nil {
+				return err
+			}
+
+			l.syslog = writer
+		}
+	} else if l.syslog != nil {
+		l.syslog.Close()
+		l.syslog = nil
+	}
+
+	l.toEvents = shared.StringInSlice(TargetEvents, targets)
+
+	return nil
+}
+
+// SetForward sets a function the audit entries are also passed to, on top of
+// the enabled targets. A nil function disables it.
+func (l *Logger) SetForward(forward func(entry api.EventAudit)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.forward = forward
+}
+
+// Enabled returns whether any target is enabled.
+func (l *Logger) Enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file != nil || l.syslog != nil || l.toEvents || l.forward != nil
+}
+
+// Log records an audit entry to all the enabled targets. Failures are logged
+// but otherwise ignored.
+func (l *Logger) Log(entry api.EventAudit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil && l.syslog == nil && !l.toEvents && l.forward == nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logger.Errorf("Failed to encode audit entry: %v", err)
+		return
+	}
+
+	if l.file != nil {
+		line := fmt.Sprintf("%s %s\n", time.Now().UTC().Format(time.RFC3339), data)
+		_, err := l.file.Write([]byte(line))
+		if err != nil {
+			logger.Errorf("Failed to write audit entry: %v", err)
+		}
+	}
+
+	if l.syslog != nil {
+		err := l.syslog.Info(string(data))
+		if err != nil {
+			logger.Errorf("Failed to send audit entry to syslog: %v", err)
+		}
+	}
+
+	if l.toEvents {
+		l.events.Send(entry.Project, "audit", entry)
+	}
+
+	if l.forward != nil {
+		l.forward(entry)
+	}
+}
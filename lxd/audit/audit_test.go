@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyDigest(t *testing.T) {
+	digest1, err := BodyDigest([]byte(`{"config": {"core.trust_password": "foo", "images.auto_update_interval": "6"}}`))
+	require.NoError(t, err)
+
+	digest2, err := BodyDigest([]byte(`{"config": {"images.auto_update_interval": "6", "core.trust_password": "bar"}}`))
+	require.NoError(t, err)
+
+	// Secrets and field order don't change the digest.
+	assert.Equal(t, digest1, digest2)
+	assert.Len(t, digest1, 64)
+
+	digest3, err := BodyDigest([]byte(`{"config": {"images.auto_update_interval": "12"}}`))
+	require.NoError(t, err)
+	assert.NotEqual(t, digest1, digest3)
+
+	digest, err := BodyDigest(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", digest)
+
+	_, err = BodyDigest([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestRedact(t *testing.T) {
+	value := map[string]interface{}{
+		"password": "foo",
+		"name":     "bar",
+		"members": []interface{}{
+			map[string]interface{}{"cluster_password": "baz"},
+		},
+	}
+
+	expected := map[string]interface{}{
+		"password": redacted,
+		"name":     "bar",
+		"members": []interface{}{
+			map[string]interface{}{"cluster_password": redacted},
+		},
+	}
+
+	assert.Equal(t, expected, Redact(value))
+	assert.Equal(t, "foo", value["password"])
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-audit-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	file, err := newRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	defer file.Close()
+
+	for i := 0; i < 4; i++ {
+		_, err := file.Write([]byte(fmt.Sprintf("entry %d\n", i)))
+		require.NoError(t, err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "entry 3\n", string(data))
+
+	data, err = ioutil.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "entry 2\n", string(data))
+
+	data, err = ioutil.ReadFile(path + ".2")
+	require.NoError(t, err)
+	assert.Equal(t, "entry 1\n", string(data))
+
+	assert.False(t, shared.PathExists(path+".3"))
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// secretKeys lists the request fields and configuration keys whose values are
+// secrets.
+var secretKeys = []string{
+	"candid.api.key",
+	"cluster_password",
+	"core.trust_password",
+	"maas.api.key",
+	"password",
+	"rbac.agent.private_key",
+	"rbac.api.key",
+	"secret",
+	"trust_password",
+}
+
+const redacted = "[redacted]"
+
+// Redact returns a copy of the given decoded JSON value, with the values of
+// all secret keys replaced, at any depth.
+func Redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if shared.StringInSlice(key, secretKeys) {
+				result[key] = redacted
+				continue
+			}
+
+			result[key] = Redact(item)
+		}
+
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = Redact(item)
+		}
+
+		return result
+	default:
+		return value
+	}
+}
+
+// BodyDigest returns the SHA-256 digest of the given JSON request body.
+//
+// Secrets are redacted before hashing, as a digest of a short secret like a
+// trust password could otherwise be brute-forced. Keys are sorted when
+// re-encoding, so the digest doesn't depend on the order of the fields.
+func BodyDigest(body []byte) (string, error) {
+	if len(body) == 0 {
+		return "", nil
+	}
+
+	var value interface{}
+	err := json.Unmarshal(body, &value)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(Redact(value))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+)
+
+// Size after which the audit log file is rotated, and number of rotated
+// files kept around.
+const (
+	maxFileSize    = 10 * 1024 * 1024
+	maxFileBackups = 5
+)
+
+// rotatingFile is an append-only file which is renamed to <path>.1 once it
+// grows past a maximum size, previously rotated files being shifted to
+// <path>.2 and so on.
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	backups int
+
+	file *os.File
+	size int64
+}
+
+func newRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:    path,
+		maxSize: maxSize,
+		backups: backups,
+	}
+
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+
+	return nil
+}
+
+// Write appends the given data, rotating the file first if it would grow
+// past its maximum size.
+func (f *rotatingFile) Write(data []byte) (int, error) {
+	if f.size > 0 && f.size+int64(len(data)) > f.maxSize {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+
+	return n, err
+}
+
+func (f *rotatingFile) rotate() error {
+	err := f.file.Close()
+	if err != nil {
+		return err
+	}
+
+	for i := f.backups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	err = os.Rename(f.path, fmt.Sprintf("%s.1", f.path))
+	if err != nil {
+		return err
+	}
+
+	return f.open()
+}
+
+// Close closes the underlying file.
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
@@ -192,49 +192,191 @@ func backupCreateTarball(s *state.State, path string, b backup.Backup, c instanc
 		return err
 	}
 
-	var compress string
+	err = backupCompressTarball(s, backupPath, b.CompressionAlgorithm())
+	if err != nil {
+		return err
+	}
 
-	if b.CompressionAlgorithm() != "" {
-		compress = b.CompressionAlgorithm()
-	} else {
+	// Set permissions
+	err = os.Chmod(backupPath, 0600)
+	if err != nil {
+		return err
+	}
+
+	success = true
+	return nil
+}
+
+// Compress the backup tarball at the given path in place, using the given
+// algorithm or the server default if none is given.
+func backupCompressTarball(s *state.State, backupPath string, algorithm string) error {
+	var err error
+
+	compress := algorithm
+	if compress == "" {
 		compress, err = cluster.ConfigGetString(s.Cluster, "backups.compression_algorithm")
 		if err != nil {
 			return err
 		}
 	}
 
-	if compress != "none" {
-		infile, err := os.Open(backupPath)
-		if err != nil {
-			return err
+	if compress == "none" {
+		return nil
+	}
+
+	infile, err := os.Open(backupPath)
+	if err != nil {
+		return err
+	}
+	defer infile.Close()
+
+	compressed, err := os.Create(backupPath + ".compressed")
+	if err != nil {
+		return err
+	}
+	compressedName := compressed.Name()
+
+	defer compressed.Close()
+	defer os.Remove(compressedName)
+
+	err = compressFile(compress, infile, compressed)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(backupPath)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(compressedName, backupPath)
+}
+
+// Create a new custom volume backup.
+func volumeBackupCreate(s *state.State, args db.StoragePoolVolumeBackup, pool storagePools.Pool) error {
+	// Create the database entry.
+	err := s.Cluster.StoragePoolVolumeBackupCreate(args)
+	if err != nil {
+		if err == db.ErrAlreadyDefined {
+			return fmt.Errorf("backup '%s' already exists", args.Name)
 		}
-		defer infile.Close()
 
-		compressed, err := os.Create(backupPath + ".compressed")
-		if err != nil {
-			return err
+		return errors.Wrap(err, "Insert backup info into database")
+	}
+
+	revert := true
+	defer func() {
+		if !revert {
+			return
 		}
-		compressedName := compressed.Name()
+		s.Cluster.StoragePoolVolumeBackupRemove(args.VolumeID, args.Name)
+	}()
 
-		defer compressed.Close()
-		defer os.Remove(compressedName)
+	// Get the backup struct.
+	dbBackup, err := s.Cluster.StoragePoolVolumeBackupGet(args.VolumeID, args.Name)
+	if err != nil {
+		return errors.Wrap(err, "Load backup object")
+	}
 
-		err = compressFile(compress, infile, compressed)
+	dbBackup.CompressionAlgorithm = args.CompressionAlgorithm
+	b := backup.NewVolumeBackup(s, dbBackup)
+
+	// Create a temporary path for the backup.
+	tmpPath, err := ioutil.TempDir(shared.VarPath("backups"), "lxd_backup_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	err = pool.BackupCustomVolume(dbBackup.ProjectName, dbBackup.VolumeName, tmpPath, b.OptimizedStorage(), !b.VolumeOnly(), nil)
+	if err != nil {
+		return errors.Wrap(err, "Backup create")
+	}
+
+	// Pack the backup.
+	err = volumeBackupCreateTarball(s, tmpPath, b, dbBackup, pool)
+	if err != nil {
+		return err
+	}
+
+	revert = false
+	return nil
+}
+
+func volumeBackupCreateTarball(s *state.State, path string, b *backup.VolumeBackup, args db.StoragePoolVolumeBackup, pool storagePools.Pool) error {
+	// Create the index
+	_, vol, err := s.Cluster.StoragePoolNodeVolumeGetTypeByProject(args.ProjectName, args.VolumeName, db.StoragePoolVolumeTypeCustom, pool.ID())
+	if err != nil {
+		return err
+	}
+
+	indexFile := backup.Info{
+		Name:      args.VolumeName,
+		Backend:   pool.Driver().Info().Name,
+		Pool:      pool.Name(),
+		Snapshots: []string{},
+		Type:      backup.TypeCustom,
+		Config:    vol.Config,
+	}
+
+	if !b.VolumeOnly() {
+		snapshots, err := storagePools.VolumeSnapshotsGet(s, args.ProjectName, pool.Name(), args.VolumeName, db.StoragePoolVolumeTypeCustom)
 		if err != nil {
 			return err
 		}
 
-		err = os.Remove(backupPath)
-		if err != nil {
-			return err
+		for _, snapshot := range snapshots {
+			_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snapshot.Name)
+			indexFile.Snapshots = append(indexFile.Snapshots, snapName)
 		}
+	}
+
+	data, err := yaml.Marshal(&indexFile)
+	if err != nil {
+		return err
+	}
 
-		err = os.Rename(compressedName, backupPath)
+	err = ioutil.WriteFile(filepath.Join(path, "index.yaml"), data, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Create the target path if needed
+	backupsPath := backup.VolumeBackupsPath(args.ProjectName, pool.Name(), args.VolumeName)
+	if !shared.PathExists(backupsPath) {
+		err := os.MkdirAll(backupsPath, 0700)
 		if err != nil {
 			return err
 		}
 	}
 
+	// Create the tarball
+	backupPath := b.Path()
+	success := false
+	defer func() {
+		if success {
+			return
+		}
+
+		os.RemoveAll(backupPath)
+	}()
+
+	tarArgs := []string{"-cf", backupPath, "--numeric-owner", "--xattrs", "-C", path, "--transform", "s,^./,backup/,", "."}
+	_, err = shared.RunCommand("tar", tarArgs...)
+	if err != nil {
+		return err
+	}
+
+	err = os.RemoveAll(path)
+	if err != nil {
+		return err
+	}
+
+	err = backupCompressTarball(s, backupPath, b.CompressionAlgorithm())
+	if err != nil {
+		return err
+	}
+
 	// Set permissions
 	err = os.Chmod(backupPath, 0600)
 	if err != nil {
@@ -263,6 +405,23 @@ func pruneExpiredContainerBackupsTask(d *Daemon) (task.Func, task.Schedule) {
 			logger.Error("Failed to expire backups", log.Ctx{"err": err})
 		}
 		logger.Info("Done pruning expired container backups")
+
+		opRun = func(op *operations.Operation) error {
+			return pruneExpiredVolumeBackups(ctx, d)
+		}
+
+		op, err = operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationBackupsExpire, nil, nil, opRun, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start expired backups operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Pruning expired custom volume backups")
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to expire backups", log.Ctx{"err": err})
+		}
+		logger.Info("Done pruning expired custom volume backups")
 	}
 
 	f(context.Background())
@@ -303,3 +462,20 @@ func pruneExpiredContainerBackups(ctx context.Context, d *Daemon) error {
 
 	return nil
 }
+
+func pruneExpiredVolumeBackups(ctx context.Context, d *Daemon) error {
+	// Get the list of expired backups.
+	backups, err := d.cluster.StoragePoolVolumeBackupsGetExpired()
+	if err != nil {
+		return errors.Wrap(err, "Unable to retrieve the list of expired custom volume backups")
+	}
+
+	for _, b := range backups {
+		err = backup.NewVolumeBackup(d.State(), b).Delete()
+		if err != nil {
+			return errors.Wrapf(err, "Error deleting custom volume backup %s", b.Name)
+		}
+	}
+
+	return nil
+}
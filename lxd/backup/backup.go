@@ -23,15 +23,24 @@ type Instance interface {
 	Project() string
 }
 
+// Backup types stored in the index file. An empty type means an instance
+// backup, as created before custom volumes could be backed up.
+const (
+	TypeInstance = ""
+	TypeCustom   = "custom"
+)
+
 // Info represents exported backup information.
 type Info struct {
-	Project         string   `json:"project" yaml:"project"`
-	Name            string   `json:"name" yaml:"name"`
-	Backend         string   `json:"backend" yaml:"backend"`
-	Privileged      bool     `json:"privileged" yaml:"privileged"`
-	Pool            string   `json:"pool" yaml:"pool"`
-	Snapshots       []string `json:"snapshots,omitempty" yaml:"snapshots,omitempty"`
-	HasBinaryFormat bool     `json:"-" yaml:"-"`
+	Project         string            `json:"project" yaml:"project"`
+	Name            string            `json:"name" yaml:"name"`
+	Backend         string            `json:"backend" yaml:"backend"`
+	Privileged      bool              `json:"privileged" yaml:"privileged"`
+	Pool            string            `json:"pool" yaml:"pool"`
+	Snapshots       []string          `json:"snapshots,omitempty" yaml:"snapshots,omitempty"`
+	Type            string            `json:"type,omitempty" yaml:"type,omitempty"`
+	Config          map[string]string `json:"config,omitempty" yaml:"config,omitempty"`
+	HasBinaryFormat bool              `json:"-" yaml:"-"`
 }
 
 // GetInfo extracts backup information from a given ReadSeeker.
@@ -92,7 +101,7 @@ func GetInfo(r io.ReadSeeker) (*Info, error) {
 			hasIndexFile = true
 		}
 
-		if hdr.Name == "backup/container.bin" {
+		if hdr.Name == "backup/container.bin" || hdr.Name == "backup/volume.bin" {
 			hasBinaryFormat = true
 		}
 	}
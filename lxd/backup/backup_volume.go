@@ -0,0 +1,134 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// VolumeBackupsPath returns the directory holding the backups of the given
+// custom volume.
+func VolumeBackupsPath(projectName string, poolName string, volumeName string) string {
+	return shared.VarPath("backups", "custom", poolName, project.Prefix(projectName, volumeName))
+}
+
+// VolumeBackup represents a custom storage volume backup.
+type VolumeBackup struct {
+	state *state.State
+
+	// Properties
+	projectName          string
+	poolName             string
+	volumeName           string
+	volumeID             int64
+	name                 string
+	creationDate         time.Time
+	expiryDate           time.Time
+	volumeOnly           bool
+	optimizedStorage     bool
+	compressionAlgorithm string
+}
+
+// NewVolumeBackup instantiates a new VolumeBackup struct from its database
+// record.
+func NewVolumeBackup(state *state.State, args db.StoragePoolVolumeBackup) *VolumeBackup {
+	return &VolumeBackup{
+		state:                state,
+		projectName:          args.ProjectName,
+		poolName:             args.PoolName,
+		volumeName:           args.VolumeName,
+		volumeID:             args.VolumeID,
+		name:                 args.Name,
+		creationDate:         args.CreationDate,
+		expiryDate:           args.ExpiryDate,
+		volumeOnly:           args.VolumeOnly,
+		optimizedStorage:     args.OptimizedStorage,
+		compressionAlgorithm: args.CompressionAlgorithm,
+	}
+}
+
+// CompressionAlgorithm returns the compression used for the tarball.
+func (b *VolumeBackup) CompressionAlgorithm() string {
+	return b.compressionAlgorithm
+}
+
+// Name returns the name of the backup.
+func (b *VolumeBackup) Name() string {
+	return b.name
+}
+
+// VolumeOnly returns whether only the volume itself is to be backed up.
+func (b *VolumeBackup) VolumeOnly() bool {
+	return b.volumeOnly
+}
+
+// OptimizedStorage returns whether the backup is to be performed using
+// optimization supported by the storage driver.
+func (b *VolumeBackup) OptimizedStorage() bool {
+	return b.optimizedStorage
+}
+
+// Path returns the path of the backup tarball.
+func (b *VolumeBackup) Path() string {
+	return filepath.Join(VolumeBackupsPath(b.projectName, b.poolName, b.volumeName), b.name)
+}
+
+// Rename renames a volume backup.
+func (b *VolumeBackup) Rename(newName string) error {
+	newPath := filepath.Join(VolumeBackupsPath(b.projectName, b.poolName, b.volumeName), newName)
+
+	err := os.Rename(b.Path(), newPath)
+	if err != nil {
+		return err
+	}
+
+	err = b.state.Cluster.StoragePoolVolumeBackupRename(b.volumeID, b.name, newName)
+	if err != nil {
+		os.Rename(newPath, b.Path())
+		return err
+	}
+
+	b.name = newName
+	return nil
+}
+
+// Delete removes a volume backup.
+func (b *VolumeBackup) Delete() error {
+	// Delete the on-disk data
+	if shared.PathExists(b.Path()) {
+		err := os.Remove(b.Path())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check if we can remove the volume directory
+	backupsPath := VolumeBackupsPath(b.projectName, b.poolName, b.volumeName)
+	empty, _ := shared.PathIsEmpty(backupsPath)
+	if empty {
+		err := os.Remove(backupsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the database record
+	return b.state.Cluster.StoragePoolVolumeBackupRemove(b.volumeID, b.name)
+}
+
+// Render returns a StoragePoolVolumeBackup struct of the backup.
+func (b *VolumeBackup) Render() *api.StoragePoolVolumeBackup {
+	return &api.StoragePoolVolumeBackup{
+		Name:             b.name,
+		CreatedAt:        b.creationDate,
+		ExpiresAt:        b.expiryDate,
+		VolumeOnly:       b.volumeOnly,
+		OptimizedStorage: b.optimizedStorage,
+	}
+}
@@ -15,6 +15,7 @@ import (
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -44,39 +45,69 @@ var certificateCmd = APIEndpoint{
 func certificatesGet(d *Daemon, r *http.Request) response.Response {
 	recursion := util.IsRecursionRequest(r)
 
-	if recursion {
-		certResponses := []api.Certificate{}
+	baseCerts, err := d.cluster.CertificatesGet()
+	if err != nil {
+		return response.SmartError(err)
+	}
 
-		baseCerts, err := d.cluster.CertificatesGet()
-		if err != nil {
-			return response.SmartError(err)
+	// Restricted clients can only see their own certificate.
+	_, restricted := d.userRestrictedProjects(r)
+
+	certResponses := []api.Certificate{}
+	body := []string{}
+	for _, baseCert := range baseCerts {
+		if restricted && baseCert.Fingerprint != r.Context().Value("username") {
+			continue
 		}
-		for _, baseCert := range baseCerts {
-			resp := api.Certificate{}
-			resp.Fingerprint = baseCert.Fingerprint
-			resp.Certificate = baseCert.Certificate
-			resp.Name = baseCert.Name
-			if baseCert.Type == 1 {
-				resp.Type = "client"
-			} else {
-				resp.Type = "unknown"
-			}
-			certResponses = append(certResponses, resp)
+
+		if recursion {
+			certResponses = append(certResponses, certificateToAPI(baseCert))
+		} else {
+			body = append(body, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, baseCert.Fingerprint))
 		}
-		return response.SyncResponse(true, certResponses)
 	}
 
-	body := []string{}
-	for _, cert := range d.clientCerts {
-		fingerprint := fmt.Sprintf("/%s/certificates/%s", version.APIVersion, shared.CertFingerprint(&cert))
-		body = append(body, fingerprint)
+	if recursion {
+		return response.SyncResponse(true, certResponses)
 	}
 
 	return response.SyncResponse(true, body)
 }
 
+// Convert a certificate database entry to its API representation.
+func certificateToAPI(dbCert *db.CertInfo) api.Certificate {
+	resp := api.Certificate{}
+	resp.Fingerprint = dbCert.Fingerprint
+	resp.Certificate = dbCert.Certificate
+	resp.Name = dbCert.Name
+	resp.Restricted = dbCert.Restricted
+	resp.Projects = dbCert.Projects
+	if resp.Projects == nil {
+		resp.Projects = []string{}
+	}
+
+	certType, err := db.CertificateTypeToName(dbCert.Type)
+	if err != nil {
+		certType = "unknown"
+	}
+	resp.Type = certType
+
+	return resp
+}
+
 func readSavedClientCAList(d *Daemon) {
-	d.clientCerts = map[string]x509.Certificate{}
+	clientCerts := map[string]x509.Certificate{}
+	restrictedCerts := map[string][]string{}
+	metricsCerts := map[string]x509.Certificate{}
+
+	// Swap the caches in one go once read.
+	defer func() {
+		d.clientCertsLock.Lock()
+		d.clientCerts = clientCerts
+		d.restrictedCerts = restrictedCerts
+		d.metricsCerts = metricsCerts
+		d.clientCertsLock.Unlock()
+	}()
 
 	dbCerts, err := d.cluster.CertificatesGet()
 	if err != nil {
@@ -97,7 +128,22 @@ func readSavedClientCAList(d *Daemon) {
 			continue
 		}
 
-		d.clientCerts[shared.CertFingerprint(cert)] = *cert
+		// Metrics certificates only grant access to the metrics.
+		if dbCert.Type == db.CertificateTypeMetrics {
+			metricsCerts[shared.CertFingerprint(cert)] = *cert
+			continue
+		}
+
+		// Only client certificates grant access to the API.
+		if dbCert.Type != db.CertificateTypeClient {
+			continue
+		}
+
+		clientCerts[shared.CertFingerprint(cert)] = *cert
+
+		if dbCert.Restricted {
+			restrictedCerts[shared.CertFingerprint(cert)] = dbCert.Projects
+		}
 	}
 }
 
@@ -119,15 +165,37 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	if (!trusted || (protocol == "candid" && !d.userIsAdmin(r))) && util.PasswordCheck(secret, req.Password) != nil {
-		if req.Password != "" {
-			logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+	_, restricted := d.userRestrictedProjects(r)
+
+	// Handle requests for a certificate add token
+	if req.Token {
+		if !trusted || !d.userIsAdmin(r) {
+			return response.Forbidden(nil)
 		}
-		return response.Forbidden(nil)
+
+		return certificateTokenCreate(d, req)
+	}
+
+	if (!trusted || (protocol == "candid" && !d.userIsAdmin(r)) || restricted) && util.PasswordCheck(secret, req.Password) != nil {
+		// The password may be the secret of a join token instead
+		tokenReq, err := certificateTokenValid(d, req)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if tokenReq == nil {
+			if req.Password != "" {
+				logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+			}
+			return response.Forbidden(nil)
+		}
+
+		req.CertificatePut = *tokenReq
 	}
 
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+	certType, err := db.CertificateNameToType(req.Type)
+	if err != nil {
+		return response.BadRequest(err)
 	}
 
 	// Extract the certificate
@@ -150,30 +218,32 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		}
 		cert = r.TLS.PeerCertificates[len(r.TLS.PeerCertificates)-1]
 
-		remoteHost, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
-			return response.InternalError(err)
-		}
+		name = req.Name
+		if name == "" {
+			remoteHost, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				return response.InternalError(err)
+			}
 
-		name = remoteHost
+			name = remoteHost
+		}
 	} else {
 		return response.BadRequest(fmt.Errorf("Can't use TLS data on non-TLS link"))
 	}
 
 	fingerprint := shared.CertFingerprint(cert)
 
-	if d.clientCerts == nil {
-		d.clientCerts = map[string]x509.Certificate{}
-	}
-
 	if !isClusterNotification(r) {
 		// Check if we already have the certificate
 		existingCert, _ := d.cluster.CertificateGet(fingerprint)
 		if existingCert != nil {
 			// Deal with the cache being potentially out of sync
+			d.clientCertsLock.RLock()
 			_, ok := d.clientCerts[fingerprint]
-			if !ok {
-				d.clientCerts[fingerprint] = *cert
+			d.clientCertsLock.RUnlock()
+
+			if !ok && existingCert.Type == db.CertificateTypeClient {
+				readSavedClientCAList(d)
 				return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 			}
 
@@ -183,9 +253,11 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		// Store the certificate in the cluster database
 		dbCert := db.CertInfo{
 			Fingerprint: shared.CertFingerprint(cert),
-			Type:        1,
+			Type:        certType,
 			Name:        name,
 			Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			Restricted:  req.Restricted,
+			Projects:    req.Projects,
 		}
 
 		err = d.cluster.CertSave(&dbCert)
@@ -203,7 +275,9 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 			Certificate: base64.StdEncoding.EncodeToString(cert.Raw),
 		}
 		req.Name = name
-		req.Type = "client"
+		req.Type = db.CertificateTypeNames[certType]
+		req.Restricted = dbCert.Restricted
+		req.Projects = dbCert.Projects
 
 		err = notifier(func(client lxd.InstanceServer) error {
 			return client.CreateCertificate(req)
@@ -213,11 +287,107 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	d.clientCerts[shared.CertFingerprint(cert)] = *cert
+	readSavedClientCAList(d)
 
 	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 }
 
+// Create a single-use token allowing a new client to add its certificate to the
+// trust store without knowing core.trust_password.
+func certificateTokenCreate(d *Daemon, req api.CertificatesPost) response.Response {
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("A client name must be provided when requesting a token"))
+	}
+
+	if req.Certificate != "" {
+		return response.BadRequest(fmt.Errorf("Can't provide a certificate when requesting a token"))
+	}
+
+	_, err := db.CertificateNameToType(req.Type)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	address, err := node.HTTPSAddress(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	addresses, err := util.ListenAddresses(address)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(addresses) == 0 {
+		return response.BadRequest(fmt.Errorf("Can't issue a token when the server isn't listening on the network"))
+	}
+
+	secret, err := shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	meta := map[string]interface{}{
+		"request":     req.CertificatePut,
+		"client_name": req.Name,
+		"fingerprint": d.endpoints.NetworkCert().Fingerprint(),
+		"addresses":   addresses,
+		"secret":      secret,
+	}
+
+	return tokenOperationCreate(d, db.OperationCertificateAddToken, meta)
+}
+
+// Check whether the password provided by a new client is the secret of a
+// certificate add token or of a cluster join token, and if so return the
+// details of the certificate to add.
+func certificateTokenValid(d *Daemon, req api.CertificatesPost) (*api.CertificatePut, error) {
+	op, err := tokenOperationValid(d, db.OperationCertificateAddToken, req.Password, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if op != nil {
+		// The certificate details are the ones requested when the
+		// token was created, not the ones sent by the new client.
+		data, err := json.Marshal(op.Metadata["request"])
+		if err != nil {
+			return nil, err
+		}
+
+		put := api.CertificatePut{}
+		err = json.Unmarshal(data, &put)
+		if err != nil {
+			return nil, err
+		}
+
+		return &put, nil
+	}
+
+	// Join tokens are only valid for the member they were issued for, which
+	// is checked before using them up.
+	joinTokenMember := func(metadata map[string]interface{}) bool {
+		serverName, _ := metadata["server_name"].(string)
+		if req.Name != fmt.Sprintf("lxd.cluster.%s", serverName) {
+			logger.Warn("Join token used by the wrong cluster member", log.Ctx{"expected": serverName, "name": req.Name})
+			return false
+		}
+
+		return true
+	}
+
+	op, err = tokenOperationValid(d, db.OperationClusterJoinToken, req.Password, joinTokenMember)
+	if err != nil {
+		return nil, err
+	}
+
+	if op != nil {
+		return &api.CertificatePut{Name: req.Name, Type: "client"}, nil
+	}
+
+	return nil, nil
+}
+
 func certificateGet(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
@@ -226,27 +396,22 @@ func certificateGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Restricted clients can only see their own certificate.
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted && cert.Fingerprint != r.Context().Value("username") {
+		return response.Forbidden(nil)
+	}
+
 	return response.SyncResponseETag(true, cert, cert)
 }
 
 func doCertificateGet(db *db.Cluster, fingerprint string) (api.Certificate, error) {
-	resp := api.Certificate{}
-
 	dbCertInfo, err := db.CertificateGet(fingerprint)
 	if err != nil {
-		return resp, err
+		return api.Certificate{}, err
 	}
 
-	resp.Fingerprint = dbCertInfo.Fingerprint
-	resp.Certificate = dbCertInfo.Certificate
-	resp.Name = dbCertInfo.Name
-	if dbCertInfo.Type == 1 {
-		resp.Type = "client"
-	} else {
-		resp.Type = "unknown"
-	}
-
-	return resp, nil
+	return certificateToAPI(dbCertInfo), nil
 }
 
 func certificatePut(d *Daemon, r *http.Request) response.Response {
@@ -268,7 +433,7 @@ func certificatePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
-	return doCertificateUpdate(d, fingerprint, req)
+	return doCertificateUpdate(d, r, fingerprint, req)
 }
 
 func certificatePatch(d *Daemon, r *http.Request) response.Response {
@@ -303,34 +468,93 @@ func certificatePatch(d *Daemon, r *http.Request) response.Response {
 		req.Type = value
 	}
 
-	return doCertificateUpdate(d, fingerprint, req.Writable())
-}
+	// Get restricted
+	restricted, err := reqRaw.GetBool("restricted")
+	if err == nil {
+		req.Restricted = restricted
+	}
+
+	// Get projects
+	projects, ok := reqRaw["projects"]
+	if ok {
+		values, ok := projects.([]interface{})
+		if !ok {
+			return response.BadRequest(fmt.Errorf("Invalid value for projects"))
+		}
+
+		req.Projects = []string{}
+		for _, value := range values {
+			name, ok := value.(string)
+			if !ok {
+				return response.BadRequest(fmt.Errorf("Invalid project name %v", value))
+			}
 
-func doCertificateUpdate(d *Daemon, fingerprint string, req api.CertificatePut) response.Response {
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+			req.Projects = append(req.Projects, name)
+		}
 	}
 
-	err := d.cluster.CertUpdate(fingerprint, req.Name, 1)
+	return doCertificateUpdate(d, r, fingerprint, req.Writable())
+}
+
+func doCertificateUpdate(d *Daemon, r *http.Request, fingerprint string, req api.CertificatePut) response.Response {
+	certType, err := db.CertificateNameToType(req.Type)
 	if err != nil {
-		return response.SmartError(err)
+		return response.BadRequest(err)
+	}
+
+	if !isClusterNotification(r) {
+		err = d.cluster.CertUpdate(fingerprint, req.Name, certType, req.Restricted, req.Projects)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		// Notify other nodes about the updated certificate.
+		notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = notifier(func(client lxd.InstanceServer) error {
+			return client.UpdateCertificate(fingerprint, req, "")
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
 	}
 
+	readSavedClientCAList(d)
+
 	return response.EmptySyncResponse
 }
 
 func certificateDelete(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
-	certInfo, err := d.cluster.CertificateGet(fingerprint)
-	if err != nil {
-		return response.NotFound(err)
-	}
+	if !isClusterNotification(r) {
+		certInfo, err := d.cluster.CertificateGet(fingerprint)
+		if err != nil {
+			return response.NotFound(err)
+		}
 
-	err = d.cluster.CertDelete(certInfo.Fingerprint)
-	if err != nil {
-		return response.SmartError(err)
+		err = d.cluster.CertDelete(certInfo.Fingerprint)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		// Notify other nodes about the removed certificate.
+		notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = notifier(func(client lxd.InstanceServer) error {
+			return client.DeleteCertificate(certInfo.Fingerprint)
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
 	}
+
 	readSavedClientCAList(d)
 
 	return response.EmptySyncResponse
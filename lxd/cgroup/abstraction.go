@@ -258,6 +258,34 @@ func (cg *CGroup) SetBlkioWeight(value string) error {
 
 }
 
+// GetIOStats returns the number of bytes read and written by processes
+func (cg *CGroup) GetIOStats() (int64, int64, error) {
+	if cgControllers["blkio"] == V1 {
+		value, err := cg.rw.Get(V1, "blkio", "blkio.throttle.io_service_bytes")
+		if err != nil {
+			return -1, -1, err
+		}
+
+		return parseIOServiceBytes(value)
+	}
+
+	version := cgControllers["io"]
+	switch version {
+	case Unavailable:
+		return -1, -1, ErrControllerMissing
+	case V1:
+		return -1, -1, ErrControllerMissing
+	case V2:
+		value, err := cg.rw.Get(version, "io", "io.stat")
+		if err != nil {
+			return -1, -1, err
+		}
+
+		return parseIOStat(value)
+	}
+	return -1, -1, ErrUnknownVersion
+}
+
 // SetCPUShare sets the weight of each group in the same hierarchy
 func (cg *CGroup) SetCPUShare(value string) error {
 	//Confirm we have the controller
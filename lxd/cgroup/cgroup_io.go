@@ -0,0 +1,71 @@
+package cgroup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseIOServiceBytes sums the bytes read and written on all devices, as
+// listed in the blkio.throttle.io_service_bytes file of cgroup v1.
+func parseIOServiceBytes(value string) (int64, int64, error) {
+	var read, written int64
+
+	for _, line := range strings.Split(value, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		count, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return -1, -1, fmt.Errorf("Invalid I/O statistics line %q", line)
+		}
+
+		switch fields[1] {
+		case "Read":
+			read += count
+		case "Write":
+			written += count
+		}
+	}
+
+	return read, written, nil
+}
+
+// parseIOStat sums the bytes read and written on all devices, as listed in
+// the io.stat file of cgroup v2.
+func parseIOStat(value string) (int64, int64, error) {
+	var read, written int64
+
+	for _, line := range strings.Split(value, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		for _, field := range fields[1:] {
+			fieldParts := strings.SplitN(field, "=", 2)
+			if len(fieldParts) != 2 {
+				continue
+			}
+
+			if fieldParts[0] != "rbytes" && fieldParts[0] != "wbytes" {
+				continue
+			}
+
+			count, err := strconv.ParseInt(fieldParts[1], 10, 64)
+			if err != nil {
+				return -1, -1, fmt.Errorf("Invalid I/O statistics line %q", line)
+			}
+
+			if fieldParts[0] == "rbytes" {
+				read += count
+			} else {
+				written += count
+			}
+		}
+	}
+
+	return read, written, nil
+}
@@ -7,12 +7,18 @@ import (
 	"io"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/scrypt"
 
+	"github.com/lxc/lxd/lxd/audit"
 	"github.com/lxc/lxd/lxd/config"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/logforward"
+	"github.com/lxc/lxd/lxd/tracing"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
 	"github.com/pkg/errors"
 )
 
@@ -83,6 +89,28 @@ func (c *Config) RBACServer() (string, string, int64, string, string, string, st
 		c.m.GetString("rbac.agent.public_key")
 }
 
+// OIDCServer returns all the OpenID Connect settings needed to validate
+// bearer tokens.
+func (c *Config) OIDCServer() (string, string, string) {
+	return c.m.GetString("oidc.issuer"),
+		c.m.GetString("oidc.client.id"),
+		c.m.GetString("oidc.audience")
+}
+
+// OIDCSubjects returns the OpenID Connect subjects which are trusted when RBAC
+// isn't used.
+func (c *Config) OIDCSubjects() []string {
+	subjects := []string{}
+	for _, subject := range strings.Split(c.m.GetString("oidc.subjects"), ",") {
+		subject = strings.TrimSpace(subject)
+		if subject != "" {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	return subjects
+}
+
 // AutoUpdateInterval returns the configured images auto update interval.
 func (c *Config) AutoUpdateInterval() time.Duration {
 	n := c.m.GetInt64("images.auto_update_interval")
@@ -95,6 +123,24 @@ func (c *Config) RemoteCacheExpiry() int64 {
 	return c.m.GetInt64("images.remote_cache_expiry")
 }
 
+// ImagesSimplestreamsFeed returns whether the public images are published as a
+// simplestreams feed.
+func (c *Config) ImagesSimplestreamsFeed() bool {
+	return c.m.GetBool("images.simplestreams_feed")
+}
+
+// ImagesTrustedKeys returns the PEM encoded certificates and public keys
+// trusted to sign images.
+func (c *Config) ImagesTrustedKeys() string {
+	return c.m.GetString("images.trusted_keys")
+}
+
+// ImagesSignatureEnforced returns whether images which fail signature
+// verification must be rejected rather than only flagged.
+func (c *Config) ImagesSignatureEnforced() bool {
+	return c.m.GetString("images.signature_verification") == "enforce"
+}
+
 // ProxyHTTPS returns the configured HTTPS proxy, if any.
 func (c *Config) ProxyHTTPS() string {
 	return c.m.GetString("core.proxy_https")
@@ -110,6 +156,53 @@ func (c *Config) ProxyIgnoreHosts() string {
 	return c.m.GetString("core.proxy_ignore_hosts")
 }
 
+// AuditTargets returns the targets the audit entries are sent to, if any.
+func (c *Config) AuditTargets() []string {
+	targets := []string{}
+	for _, target := range strings.Split(c.m.GetString("core.audit_targets"), ",") {
+		target = strings.TrimSpace(target)
+		if target != "" {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
+
+// OperationsHistoryExpiry returns the number of days the completed operations
+// are kept in the history for. Zero means the history is disabled.
+func (c *Config) OperationsHistoryExpiry() int64 {
+	return c.m.GetInt64("core.operations_history_expiry")
+}
+
+// LogForward returns the protocol and endpoint the logs are forwarded with,
+// and the streams of logs which are forwarded.
+func (c *Config) LogForward() (string, string, []string) {
+	streams := []string{}
+	for _, stream := range strings.Split(c.m.GetString("logging.forward.streams"), ",") {
+		stream = strings.TrimSpace(stream)
+		if stream != "" {
+			streams = append(streams, stream)
+		}
+	}
+
+	return c.m.GetString("logging.forward.protocol"), c.m.GetString("logging.forward.endpoint"), streams
+}
+
+// TracingEndpoint returns the URL of the OpenTelemetry collector the traces
+// are exported to, if any.
+func (c *Config) TracingEndpoint() string {
+	return c.m.GetString("core.tracing_endpoint")
+}
+
+// UsageHistory returns the interval at which the resource usage of the
+// instances is sampled, zero meaning it isn't, and the number of samples kept
+// per instance.
+func (c *Config) UsageHistory() (time.Duration, int) {
+	interval := time.Duration(c.m.GetInt64("core.usage_history_interval")) * time.Second
+	return interval, int(c.m.GetInt64("core.usage_history_size"))
+}
+
 // MAASController the configured MAAS url and key, if any.
 func (c *Config) MAASController() (string, string) {
 	url := c.m.GetString("maas.api.url")
@@ -222,14 +315,20 @@ var ConfigSchema = config.Schema{
 	"backups.compression_algorithm":  {Default: "gzip", Validator: validateCompression},
 	"cluster.offline_threshold":      {Type: config.Int64, Default: offlineThresholdDefault(), Validator: offlineThresholdValidator},
 	"cluster.images_minimal_replica": {Type: config.Int64, Default: "3", Validator: imageMinimalReplicaValidator},
+	"core.audit_targets":             {Validator: validateAuditTargets},
 	"core.https_allowed_headers":     {},
 	"core.https_allowed_methods":     {},
 	"core.https_allowed_origin":      {},
 	"core.https_allowed_credentials": {Type: config.Bool},
+	"core.operations_history_expiry": {Type: config.Int64, Default: "7", Validator: shared.IsUint32},
 	"core.proxy_http":                {},
 	"core.proxy_https":               {},
 	"core.proxy_ignore_hosts":        {},
+	"core.remote_token_expiry":       {Type: config.Int64, Default: "86400"},
+	"core.tracing_endpoint":          {Validator: tracing.ValidateEndpoint},
 	"core.trust_password":            {Hidden: true, Setter: passwordSetter},
+	"core.usage_history_interval":    {Type: config.Int64, Default: "60", Validator: shared.IsUint32},
+	"core.usage_history_size":        {Type: config.Int64, Default: "1440", Validator: shared.IsUint32},
 	"candid.api.key":                 {},
 	"candid.api.url":                 {},
 	"candid.domains":                 {},
@@ -238,8 +337,18 @@ var ConfigSchema = config.Schema{
 	"images.auto_update_interval":    {Type: config.Int64, Default: "6"},
 	"images.compression_algorithm":   {Default: "gzip", Validator: validateCompression},
 	"images.remote_cache_expiry":     {Type: config.Int64, Default: "10"},
+	"images.signature_verification":  {Default: "flag", Validator: validateSignatureVerification},
+	"images.simplestreams_feed":      {Type: config.Bool},
+	"images.trusted_keys":            {Validator: validateTrustedKeys},
+	"logging.forward.endpoint":       {},
+	"logging.forward.protocol":       {Validator: validateLogForwardProtocol},
+	"logging.forward.streams":        {Default: "daemon,lifecycle", Validator: validateLogForwardStreams},
 	"maas.api.key":                   {},
 	"maas.api.url":                   {},
+	"oidc.audience":                  {},
+	"oidc.client.id":                 {},
+	"oidc.issuer":                    {},
+	"oidc.subjects":                  {},
 	"rbac.agent.url":                 {},
 	"rbac.agent.username":            {},
 	"rbac.agent.private_key":         {},
@@ -330,6 +439,51 @@ func validateCompression(value string) error {
 	return err
 }
 
+func validateSignatureVerification(value string) error {
+	return shared.IsOneOf(value, []string{"flag", "enforce"})
+}
+
+func validateAuditTargets(value string) error {
+	for _, target := range strings.Split(value, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+
+		err := shared.IsOneOf(target, audit.Targets)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateLogForwardProtocol(value string) error {
+	return shared.IsOneOf(value, logforward.Protocols)
+}
+
+func validateLogForwardStreams(value string) error {
+	for _, stream := range strings.Split(value, ",") {
+		stream = strings.TrimSpace(stream)
+		if stream == "" {
+			continue
+		}
+
+		err := shared.IsOneOf(stream, logforward.Streams)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateTrustedKeys(value string) error {
+	_, err := util.ParseTrustedKeys(value)
+	return err
+}
+
 func deprecatedStorage(value string) (string, error) {
 	if value == "" {
 		return "", nil
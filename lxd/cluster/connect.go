@@ -4,11 +4,13 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"net/http"
 	"time"
 
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/tracing"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/pkg/errors"
@@ -47,6 +49,10 @@ func Connect(address string, cert *shared.CertInfo, notify bool) (lxd.InstanceSe
 		TLSClientCert: string(cert.PublicKey()),
 		TLSClientKey:  string(cert.PrivateKey()),
 		SkipGetServer: true,
+		RequestHook: func(req *http.Request) {
+			// Propagate the trace span of the request to the target member.
+			tracing.Inject(req.Context(), req.Header)
+		},
 	}
 	if notify {
 		args.UserAgent = "lxd-cluster-notifier"
@@ -84,11 +90,11 @@ func ConnectIfContainerIsRemote(cluster *db.Cluster, project, name string, cert
 //
 // If there is more than one node with a matching volume name, an error is
 // returned.
-func ConnectIfVolumeIsRemote(cluster *db.Cluster, poolID int64, volumeName string, volumeType int, cert *shared.CertInfo) (lxd.InstanceServer, error) {
+func ConnectIfVolumeIsRemote(cluster *db.Cluster, poolID int64, projectName string, volumeName string, volumeType int, cert *shared.CertInfo) (lxd.InstanceServer, error) {
 	var addresses []string // Node addresses
 	err := cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
-		addresses, err = tx.StorageVolumeNodeAddresses(poolID, "default", volumeName, volumeType)
+		addresses, err = tx.StorageVolumeNodeAddresses(poolID, projectName, volumeName, volumeType)
 		return err
 	})
 	if err != nil {
@@ -123,8 +129,10 @@ func ConnectIfVolumeIsRemote(cluster *db.Cluster, poolID int64, volumeName strin
 
 // SetupTrust is a convenience around InstanceServer.CreateCertificate that
 // adds the given client certificate to the trusted pool of the cluster at the
-// given address, using the given password.
-func SetupTrust(cert, targetAddress, targetCert, targetPassword string) error {
+// given address, using the given password or join token secret. The
+// certificate is named after the joining member, since join tokens are only
+// valid for the member they were issued for.
+func SetupTrust(cert, serverName, targetAddress, targetCert, targetPassword string) error {
 	// Connect to the target cluster node.
 	args := &lxd.ConnectionArgs{
 		TLSServerCert: targetCert,
@@ -142,11 +150,7 @@ func SetupTrust(cert, targetAddress, targetCert, targetPassword string) error {
 		Password:    targetPassword,
 		Certificate: certificate,
 	}
-	fingerprint, err := shared.CertFingerprintStr(cert)
-	if err != nil {
-		return errors.Wrap(err, "failed to calculate fingerprint")
-	}
-	post.Name = fmt.Sprintf("lxd.cluster.%s", fingerprint)
+	post.Name = fmt.Sprintf("lxd.cluster.%s", serverName)
 	post.Type = "client"
 	err = target.CreateCertificate(post)
 	if err != nil && err.Error() != "Certificate already in trust store" {
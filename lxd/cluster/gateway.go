@@ -115,6 +115,12 @@ type Gateway struct {
 
 	// Abstract unix socket that the local dqlite task is listening to.
 	bindAddress string
+
+	// Difference between our clock and the leader's one, as measured when
+	// receiving the last full heartbeat.
+	clockSkew     time.Duration
+	clockSkewDate time.Time
+	clockSkewLock sync.Mutex
 }
 
 // Current dqlite protocol version.
@@ -213,6 +219,14 @@ func (g *Gateway) HandlerFuncs(nodeRefreshTask func(*APIHeartbeat)) map[string]h
 				return
 			}
 
+			sentDate, err := time.Parse(time.RFC3339Nano, r.Header.Get(heartbeatDateHeader))
+			if err == nil {
+				g.clockSkewLock.Lock()
+				g.clockSkewDate = time.Now()
+				g.clockSkew = g.clockSkewDate.Sub(sentDate)
+				g.clockSkewLock.Unlock()
+			}
+
 			// If node refresh task is specified, run it async.
 			if nodeRefreshTask != nil {
 				go nodeRefreshTask(&heartbeatData)
@@ -327,6 +341,17 @@ func (g *Gateway) IsDatabaseNode() bool {
 	return g.raft != nil
 }
 
+// ClockSkew returns the difference between the local clock and the one of the
+// leader, as measured when the last full heartbeat was received, along with
+// the time it was received at. The time is zero if no heartbeat was received
+// yet.
+func (g *Gateway) ClockSkew() (time.Duration, time.Time) {
+	g.clockSkewLock.Lock()
+	defer g.clockSkewLock.Unlock()
+
+	return g.clockSkew, g.clockSkewDate
+}
+
 // DialFunc returns a dial function that can be used to connect to one of the
 // dqlite nodes.
 func (g *Gateway) DialFunc() client.DialFunc {
@@ -334,6 +334,10 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 // heartbeatInterval Number of seconds to wait between to heartbeat rounds.
 const heartbeatInterval = 10
 
+// Header carrying the time a heartbeat was sent at, used by the receiving node
+// to measure its clock skew.
+const heartbeatDateHeader = "X-LXD-Heartbeat-Date"
+
 // HeartbeatNode performs a single heartbeat request against the node with the given address.
 func HeartbeatNode(taskCtx context.Context, address string, cert *shared.CertInfo, heartbeatData *APIHeartbeat) error {
 	logger.Debugf("Sending heartbeat request to %s", address)
@@ -368,6 +372,9 @@ func HeartbeatNode(taskCtx context.Context, address string, cert *shared.CertInf
 	request = request.WithContext(ctx)
 	request.Close = true // Immediately close the connection after the request is done
 
+	// Sends are spread in time, so record when this one actually went out.
+	request.Header.Set(heartbeatDateHeader, time.Now().UTC().Format(time.RFC3339Nano))
+
 	response, err := client.Do(request)
 	if err != nil {
 		return errors.Wrap(err, "failed to send HTTP request")
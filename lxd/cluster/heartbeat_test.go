@@ -62,6 +62,11 @@ func TestHeartbeat(t *testing.T) {
 		return nil
 	})
 	require.NoError(t, err)
+
+	// The followers measured the skew of their clock with the leader's one.
+	skew, date := f.Follower().ClockSkew()
+	assert.False(t, date.IsZero())
+	assert.True(t, skew > -time.Second && skew < time.Second)
 }
 
 // If a certain node does not successfully respond to the heartbeat, its
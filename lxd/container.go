@@ -303,7 +303,7 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 	s := d.State()
 
 	// Get the image properties.
-	_, img, err := s.Cluster.ImageGet(args.Project, hash, false, false)
+	imgID, img, err := s.Cluster.ImageGet(args.Project, hash, false, false)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fetch image %s from database", hash)
 	}
@@ -337,6 +337,9 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 		}
 
 		client = client.UseProject(args.Project)
+		if op != nil {
+			client = client.UseContext(op.Context())
+		}
 
 		err = imageImportFromNode(filepath.Join(d.os.VarDir, "images"), client, hash)
 		if err != nil {
@@ -354,6 +357,20 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 		for k, v := range img.Properties {
 			args.Config[fmt.Sprintf("image.%s", k)] = v
 		}
+
+		// Map the runtime configuration of images converted from OCI,
+		// only trusting the properties of images LXD converted itself.
+		_, source, err := s.Cluster.ImageSourceGet(imgID)
+		if err != nil && err != db.ErrNoSuchObject {
+			return nil, errors.Wrapf(err, "Fetch source of image %s", hash)
+		}
+
+		if err == nil && source.Protocol == "oci" {
+			err = ociInstanceConfig(img.Properties, args.Config)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Set the BaseImage field (regardless of previous value).
@@ -782,6 +799,14 @@ func instanceCreateInternal(s *state.State, args db.InstanceArgs) (instance.Inst
 		return nil, errors.Wrap(err, "Invalid devices")
 	}
 
+	// Check the project restrictions (snapshots inherit those of their parent).
+	if !args.Snapshot {
+		err = projectAllowInstance(s.Cluster, args.Project, args.Config, args.Devices, args.Profiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Validate architecture.
 	_, err = osarch.ArchitectureName(args.Architecture)
 	if err != nil {
@@ -232,7 +232,7 @@ func containerLXCCreate(s *state.State, args db.InstanceArgs) (instance.Instance
 	}
 
 	// Create a new database entry for the container's storage volume
-	_, err = s.Cluster.StoragePoolVolumeCreate(args.Project, args.Name, "", storagePoolVolumeTypeContainer, false, poolID, volumeConfig)
+	_, err = s.Cluster.StoragePoolVolumeCreate(args.Project, args.Name, "", storagePoolVolumeTypeContainer, false, poolID, volumeConfig, db.StoragePoolVolumeContentTypeFS)
 	if err != nil {
 		c.Delete()
 		return nil, err
@@ -1236,6 +1236,29 @@ func (c *containerLXC) initLXC(config bool) error {
 		return err
 	}
 
+	// Setup the OCI entrypoint
+	for key, lxcKey := range map[string]string{
+		"oci.entrypoint":  "lxc.init.cmd",
+		"oci.uid":         "lxc.init.uid",
+		"oci.gid":         "lxc.init.gid",
+		"oci.cwd":         "lxc.init.cwd",
+		"oci.stop_signal": "lxc.signal.halt",
+	} {
+		value := c.expandedConfig[key]
+		if value == "" {
+			continue
+		}
+
+		if key == "oci.cwd" && !util.RuntimeLiblxcVersionAtLeast(2, 1, 0) {
+			return fmt.Errorf("Setting the working directory requires liblxc >= 2.1")
+		}
+
+		err = lxcSetConfigItem(cc, lxcKey, value)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Apply raw.lxc
 	if lxcConfig, ok := c.expandedConfig["raw.lxc"]; ok {
 		f, err := ioutil.TempFile("", "lxd_config_")
@@ -5912,6 +5935,22 @@ func (c *containerLXC) networkState() map[string]api.InstanceStateNetwork {
 	return result
 }
 
+// ioState returns the number of bytes read and written by the container, or
+// -1 if unknown.
+func (c *containerLXC) ioState() (int64, int64) {
+	cg, err := c.cgroup(nil)
+	if err != nil {
+		return -1, -1
+	}
+
+	read, written, err := cg.GetIOStats()
+	if err != nil {
+		return -1, -1
+	}
+
+	return read, written
+}
+
 func (c *containerLXC) processesState() int64 {
 	// Return 0 if not running
 	pid := c.InitPID()
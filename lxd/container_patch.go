@@ -120,6 +120,12 @@ func containerPatch(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	// Check the project restrictions
+	err = projectAllowInstance(d.cluster, project, req.Config, deviceConfig.NewDevices(req.Devices), req.Profiles)
+	if err != nil {
+		return projectRestrictionResponse(err)
+	}
+
 	// Update container configuration
 	args := db.InstanceArgs{
 		Architecture: architecture,
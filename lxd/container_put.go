@@ -65,6 +65,30 @@ func containerPut(d *Daemon, r *http.Request) response.Response {
 		architecture = 0
 	}
 
+	// Check the project restrictions, against the snapshot when restoring
+	// as it may predate them.
+	if configRaw.Restore == "" {
+		err = projectAllowInstance(d.cluster, project, configRaw.Config, deviceConfig.NewDevices(configRaw.Devices), configRaw.Profiles)
+		if err != nil {
+			return projectRestrictionResponse(err)
+		}
+	} else {
+		snapName := configRaw.Restore
+		if !shared.IsSnapshot(snapName) {
+			snapName = name + shared.SnapshotDelimiter + snapName
+		}
+
+		snap, err := instance.LoadByProjectAndName(d.State(), project, snapName)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = projectAllowInstance(d.cluster, project, snap.LocalConfig(), snap.LocalDevices(), snap.Profiles())
+		if err != nil {
+			return projectRestrictionResponse(err)
+		}
+	}
+
 	var do func(*operations.Operation) error
 	var opType db.OperationType
 	if configRaw.Restore == "" {
@@ -46,6 +46,44 @@ func containerState(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, state)
 }
 
+// /1.0/instances/{name}/state/history
+// Get the resource usage samples of the instance, optionally only those taken
+// after the time given with the since query parameter.
+func containerStateHistory(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	// Handle requests targeted to a container on a different node
+	resp, err := ForwardedResponseIfContainerIsRemote(d, r, project, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	if resp != nil {
+		return resp
+	}
+
+	var since time.Time
+	if r.FormValue("since") != "" {
+		since, err = time.Parse(time.RFC3339, r.FormValue("since"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid since date %q, expected RFC3339", r.FormValue("since")))
+		}
+	}
+
+	// Check that the instance exists
+	_, err = instance.LoadByProjectAndName(d.State(), project, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, d.usageHistory.Samples(project, name, since))
+}
+
 func containerStatePut(d *Daemon, r *http.Request) response.Response {
 	instanceType, err := urlInstanceTypeDetect(r)
 	if err != nil {
@@ -55,6 +55,17 @@ var instanceStateCmd = APIEndpoint{
 	Put: APIEndpointAction{Handler: containerStatePut, AccessHandler: AllowProjectPermission("containers", "operate-containers")},
 }
 
+var instanceStateHistoryCmd = APIEndpoint{
+	Name: "instanceStateHistory",
+	Path: "instances/{name}/state/history",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerStateHistory", Path: "containers/{name}/state/history"},
+		{Name: "vmStateHistory", Path: "virtual-machines/{name}/state/history"},
+	},
+
+	Get: APIEndpointAction{Handler: containerStateHistory, AccessHandler: AllowProjectPermission("containers", "view")},
+}
+
 var instanceFileCmd = APIEndpoint{
 	Name: "instanceFile",
 	Path: "instances/{name}/files",
@@ -647,6 +647,11 @@ func createFromBackup(d *Daemon, project string, data io.Reader, pool string) re
 	}
 	bInfo.Project = project
 
+	if bInfo.Type != backup.TypeInstance {
+		backupFile.Close()
+		return response.BadRequest(fmt.Errorf("Backup isn't of an instance"))
+	}
+
 	// Override pool.
 	if pool != "" {
 		bInfo.Pool = pool
@@ -797,6 +802,7 @@ func containersPost(d *Daemon, r *http.Request) response.Response {
 
 			client = client.UseProject(project)
 			client = client.UseTarget(targetNode)
+			client = client.UseContext(r.Context())
 
 			logger.Debugf("Forward instance post request to %s", address)
 			op, err := client.CreateInstance(req)
@@ -28,6 +28,7 @@ import (
 	"gopkg.in/macaroon-bakery.v2/bakery/identchecker"
 	"gopkg.in/macaroon-bakery.v2/httpbakery"
 
+	"github.com/lxc/lxd/lxd/audit"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/daemon"
 	"github.com/lxc/lxd/lxd/db"
@@ -36,16 +37,23 @@ import (
 	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/firewall"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/logforward"
 	"github.com/lxc/lxd/lxd/maas"
 	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/oidc"
+	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/rbac"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/seccomp"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/sys"
 	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/tracing"
+	"github.com/lxc/lxd/lxd/usage"
 	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/lxd/webhook"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/version"
@@ -76,8 +84,9 @@ type Daemon struct {
 	clusterTasks task.Group
 
 	// Indexes of tasks that need to be reset when their execution interval changes
-	taskPruneImages *task.Task
-	taskAutoUpdate  *task.Task
+	taskPruneImages  *task.Task
+	taskAutoUpdate   *task.Task
+	taskUsageHistory *task.Task
 
 	config    *DaemonConfig
 	endpoints *endpoints.Endpoints
@@ -88,6 +97,38 @@ type Daemon struct {
 
 	externalAuth *externalAuth
 
+	// OpenID Connect token verifier and the subjects trusted without RBAC,
+	// replaced when the configuration changes.
+	oidcVerifier *oidc.Verifier
+	oidcSubjects []string
+	oidcLock     sync.RWMutex
+
+	// Projects that restricted client certificates have access to, keyed
+	// by certificate fingerprint.
+	restrictedCerts map[string][]string
+
+	// Certificates only allowed to scrape the metrics, keyed by fingerprint.
+	metricsCerts map[string]x509.Certificate
+
+	// Protects the certificate caches, which are replaced whenever the
+	// certificates change.
+	clientCertsLock sync.RWMutex
+
+	// Time at which the daemon was started.
+	startTime time.Time
+
+	// Audit log of the mutating API requests.
+	audit *audit.Logger
+
+	// Forwarder of the daemon and instance logs to a remote collector.
+	logForward *logforward.Forwarder
+
+	// Recent resource usage of the local instances.
+	usageHistory *usage.Store
+
+	// Sender of the lifecycle events to the webhooks.
+	webhooks *webhook.Dispatcher
+
 	// Stores last heartbeat node information to detect node changes.
 	lastNodeList *cluster.APIHeartbeat
 }
@@ -143,7 +184,7 @@ func NewDaemon(config *DaemonConfig, os *sys.OS) *Daemon {
 	lxdEvents := events.NewServer(daemon.Debug, daemon.Verbose)
 	devlxdEvents := events.NewServer(daemon.Debug, daemon.Verbose)
 
-	return &Daemon{
+	d := &Daemon{
 		config:       config,
 		devlxdEvents: devlxdEvents,
 		events:       lxdEvents,
@@ -151,7 +192,23 @@ func NewDaemon(config *DaemonConfig, os *sys.OS) *Daemon {
 		setupChan:    make(chan struct{}),
 		readyChan:    make(chan struct{}),
 		shutdownChan: make(chan struct{}),
+		startTime:    time.Now(),
+		audit:        audit.NewLogger(shared.LogPath("audit.log"), lxdEvents),
+		logForward:   logforward.NewForwarder(),
+		usageHistory: usage.NewStore(0),
+		webhooks:     webhook.NewDispatcher(),
 	}
+
+	// Forward the daemon logs and lifecycle events, if enabled.
+	lxdEvents.AddHandler(func(group string, event api.Event) {
+		logForwardEvent(d.logForward, group, event)
+	})
+
+	// Send the lifecycle events originating from this member to the
+	// webhooks, so that each event is delivered once per cluster.
+	lxdEvents.AddHandler(d.webhooks.Send)
+
+	return d
 }
 
 // DefaultDaemonConfig returns a DaemonConfig object with default values/
@@ -271,6 +328,27 @@ func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 		return false, "", "", fmt.Errorf("Bad/missing TLS on network query")
 	}
 
+	d.oidcLock.RLock()
+	oidcVerifier := d.oidcVerifier
+	oidcSubjects := d.oidcSubjects
+	d.oidcLock.RUnlock()
+
+	if oidcVerifier != nil && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		// Validate OpenID Connect bearer token, falling back to the other
+		// methods if it isn't valid
+		userName, err := oidcVerifier.Auth(r)
+		if err == nil && d.rbac == nil && !shared.StringInSlice(userName, oidcSubjects) {
+			// Without RBAC, trusted users get full access
+			err = fmt.Errorf("Subject %q isn't in oidc.subjects", userName)
+		}
+
+		if err == nil {
+			return true, userName, "oidc", nil
+		}
+
+		logger.Warn("Failed OpenID Connect client authentication", log.Ctx{"err": err, "ip": r.RemoteAddr})
+	}
+
 	if d.externalAuth != nil && r.Header.Get(httpbakery.BakeryProtocolHeader) != "" {
 		// Validate external authentication
 		ctx := httpbakery.ContextWithRequest(context.TODO(), r)
@@ -297,8 +375,12 @@ func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 	}
 
 	// Validate normal TLS access
+	d.clientCertsLock.RLock()
+	clientCerts := d.clientCerts
+	d.clientCertsLock.RUnlock()
+
 	for i := range r.TLS.PeerCertificates {
-		trusted, username := util.CheckTrustState(*r.TLS.PeerCertificates[i], d.clientCerts)
+		trusted, username := util.CheckTrustState(*r.TLS.PeerCertificates[i], clientCerts)
 		if trusted {
 			return true, username, "tls", nil
 		}
@@ -393,10 +475,14 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			}
 		}
 
+		// Prepare the audit entry, so that rejected requests get recorded too
+		auditEntry := auditRequest(d, r, version, username, protocol)
+
 		untrustedOk := (r.Method == "GET" && c.Get.AllowUntrusted) || (r.Method == "POST" && c.Post.AllowUntrusted)
 		if trusted {
 			logger.Debug("Handling", log.Ctx{"method": r.Method, "url": r.URL.RequestURI(), "ip": r.RemoteAddr, "user": username})
 			r = r.WithContext(context.WithValue(r.Context(), "username", username))
+			r = r.WithContext(context.WithValue(r.Context(), "protocol", protocol))
 		} else if untrustedOk && r.Header.Get("X-LXD-authenticated") == "" {
 			logger.Debug(fmt.Sprintf("Allowing untrusted %s", r.Method), log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
 		} else if derr, ok := err.(*bakery.DischargeRequiredError); ok {
@@ -404,10 +490,24 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			return
 		} else {
 			logger.Warn("Rejecting request from untrusted client", log.Ctx{"ip": r.RemoteAddr})
+			if auditEntry != nil {
+				auditLogStatus(d, auditEntry, http.StatusForbidden)
+			}
+
 			response.Forbidden(nil).Render(w)
 			return
 		}
 
+		// Compute the digest of the request body for the audit log
+		if auditEntry != nil {
+			var err error
+			auditEntry.BodyDigest, err = auditBodyDigest(r)
+			if err != nil {
+				response.InternalError(err).Render(w)
+				return
+			}
+		}
+
 		// Dump full request JSON when in debug mode
 		if daemon.Debug && r.Method != "GET" && util.IsJSONRequest(r) {
 			newBody := &bytes.Buffer{}
@@ -422,6 +522,14 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			shared.DebugJson(captured)
 		}
 
+		// Trace the request, as part of the trace of the client or of
+		// the forwarding cluster member, if any
+		ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), fmt.Sprintf("%s %s", r.Method, uri),
+			tracing.String("http.method", r.Method),
+			tracing.String("http.target", r.URL.RequestURI()),
+			tracing.String("lxd.protocol", protocol))
+		r = r.WithContext(ctx)
+
 		// Actually process the request
 		var resp response.Response
 		resp = response.NotImplemented(nil)
@@ -462,6 +570,39 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			resp = response.NotFound(fmt.Errorf("Method '%s' not found", r.Method))
 		}
 
+		// Record who requested the operation, if any, for the operations history
+		operations.SetRequestor(resp, api.OperationRequestor{
+			Username: username,
+			Protocol: protocol,
+			Address:  r.RemoteAddr,
+		})
+
+		// Trace the operation as part of the request
+		operations.SetContext(resp, r.Context())
+
+		// Record the result of the request in the audit log and in its span
+		if auditEntry != nil || span.IsRecording() {
+			recorder := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			w = recorder
+
+			if auditEntry != nil {
+				defer auditLog(d, auditEntry, recorder, resp)
+			}
+
+			if span.IsRecording() {
+				defer func() {
+					span.SetAttributes(tracing.Int("http.status_code", recorder.status))
+
+					var err error
+					if recorder.status >= http.StatusInternalServerError {
+						err = fmt.Errorf("%s", http.StatusText(recorder.status))
+					}
+
+					tracing.End(span, err)
+				}()
+			}
+		}
+
 		// Handle errors
 		if err := resp.Render(w); err != nil {
 			err := response.InternalError(err).Render(w)
@@ -831,6 +972,9 @@ func (d *Daemon) init() error {
 		return err
 	}
 
+	// Record the problems with the host system
+	warningsCheckSystem(d.State())
+
 	// Cleanup leftover images
 	pruneLeftoverImages(d)
 
@@ -848,10 +992,25 @@ func (d *Daemon) init() error {
 	rbacAgentPublicKey := ""
 	rbacExpiry := int64(0)
 
+	oidcIssuer := ""
+	oidcClientID := ""
+	oidcAudience := ""
+	oidcSubjects := []string{}
+
 	maasAPIURL := ""
 	maasAPIKey := ""
 	maasMachine := ""
 
+	auditTargets := []string{}
+
+	logForwardProtocol := ""
+	logForwardEndpoint := ""
+	logForwardStreams := []string{}
+
+	tracingEndpoint := ""
+
+	usageHistorySize := 0
+
 	err = d.db.Transaction(func(tx *db.NodeTx) error {
 		config, err := node.ConfigLoad(tx)
 		if err != nil {
@@ -877,8 +1036,14 @@ func (d *Daemon) init() error {
 		)
 
 		candidAPIURL, candidAPIKey, candidExpiry, candidDomains = config.CandidServer()
+		oidcIssuer, oidcClientID, oidcAudience = config.OIDCServer()
+		oidcSubjects = config.OIDCSubjects()
 		maasAPIURL, maasAPIKey = config.MAASController()
 		rbacAPIURL, rbacAPIKey, rbacExpiry, rbacAgentURL, rbacAgentUsername, rbacAgentPrivateKey, rbacAgentPublicKey = config.RBACServer()
+		auditTargets = config.AuditTargets()
+		logForwardProtocol, logForwardEndpoint, logForwardStreams = config.LogForward()
+		tracingEndpoint = config.TracingEndpoint()
+		_, usageHistorySize = config.UsageHistory()
 
 		return nil
 	})
@@ -886,6 +1051,25 @@ func (d *Daemon) init() error {
 		return err
 	}
 
+	err = d.audit.SetTargets(auditTargets)
+	if err != nil {
+		logger.Error("Failed to setup the audit log", log.Ctx{"err": err})
+	}
+
+	err = d.setupLogForward(logForwardProtocol, logForwardEndpoint, logForwardStreams)
+	if err != nil {
+		logger.Error("Failed to setup log forwarding", log.Ctx{"err": err})
+	}
+
+	err = d.setupTracing(tracingEndpoint)
+	if err != nil {
+		logger.Error("Failed to setup tracing", log.Ctx{"err": err})
+	}
+
+	d.usageHistory.SetSize(usageHistorySize)
+
+	d.webhooks.Start(webhooksLoad(d.cluster), webhooksRecord(d.cluster))
+
 	if rbacAPIURL != "" {
 		err = d.setupRBACServer(rbacAPIURL, rbacAPIKey, rbacExpiry, rbacAgentURL, rbacAgentUsername, rbacAgentPrivateKey, rbacAgentPublicKey)
 		if err != nil {
@@ -900,6 +1084,11 @@ func (d *Daemon) init() error {
 		}
 	}
 
+	err = d.setupOIDC(oidcIssuer, oidcClientID, oidcAudience, oidcSubjects)
+	if err != nil {
+		logger.Error("Failed to setup OpenID Connect authentication", log.Ctx{"err": err})
+	}
+
 	if !d.os.MockMode {
 		// Start the scheduler
 		go deviceEventListener(d.State())
@@ -1011,11 +1200,26 @@ func (d *Daemon) Ready() error {
 		// Remove expired container backups (hourly)
 		d.tasks.Add(pruneExpiredContainerBackupsTask(d))
 
+		// Remove expired operations from the history (daily)
+		d.tasks.Add(pruneOperationsHistoryTask(d))
+
+		// Forward the new lines of the instance logs (every 10 seconds)
+		d.tasks.Add(logForwardConsoleTask(d))
+
+		// Sample the resource usage of the instances (every minute, configurable)
+		d.taskUsageHistory = d.tasks.Add(instanceUsageTask(d))
+
 		// Take snapshot of containers (minutely check of configurable cron expression)
 		d.tasks.Add(autoCreateContainerSnapshotsTask(d))
 
 		// Remove expired container snapshots (minutely)
 		d.tasks.Add(pruneExpiredContainerSnapshotsTask(d))
+
+		// Take snapshot of custom volumes (minutely check of configurable cron expression)
+		d.tasks.Add(autoCreateCustomVolumeSnapshotsTask(d))
+
+		// Remove expired custom volume snapshots (minutely)
+		d.tasks.Add(pruneExpiredCustomVolumeSnapshotsTask(d))
 	}
 
 	// Start all background tasks
@@ -1081,6 +1285,10 @@ func (d *Daemon) Stop() error {
 	trackError(d.tasks.Stop(3 * time.Second))        // Give tasks a bit of time to cleanup.
 	trackError(d.clusterTasks.Stop(3 * time.Second)) // Give tasks a bit of time to cleanup.
 
+	d.logForward.Close()
+	d.webhooks.Close()
+	tracing.Shutdown()
+
 	shouldUnmount := false
 	if d.cluster != nil {
 		// It might be that database nodes are all down, in that case
@@ -1238,6 +1446,92 @@ func (d *Daemon) setupExternalAuthentication(authEndpoint string, authPubkey str
 	return nil
 }
 
+// Setup OpenID Connect authentication
+func (d *Daemon) setupOIDC(issuer string, clientID string, audience string, subjects []string) error {
+	d.oidcLock.Lock()
+	defer d.oidcLock.Unlock()
+
+	d.oidcSubjects = subjects
+
+	// Allow disabling OpenID Connect authentication
+	if issuer == "" {
+		d.oidcVerifier = nil
+		return nil
+	}
+
+	// Without an audience, the tokens the issuer gave to any other
+	// application would be accepted.
+	if clientID == "" && audience == "" {
+		d.oidcVerifier = nil
+		return fmt.Errorf("OpenID Connect requires oidc.client.id or oidc.audience")
+	}
+
+	d.oidcVerifier = oidc.NewVerifier(issuer, clientID, audience)
+	return nil
+}
+
+// Returns the name identifying this server in the forwarded logs and traces.
+func (d *Daemon) memberName() (string, error) {
+	var member string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		member, err = tx.NodeName()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Standalone servers are identified by their hostname.
+	if member == "none" {
+		return os.Hostname()
+	}
+
+	return member, nil
+}
+
+// Setup log forwarding with the given protocol and endpoint, disabling it
+// until both are given.
+func (d *Daemon) setupLogForward(protocol string, endpoint string, streams []string) error {
+	if endpoint == "" {
+		protocol = ""
+	}
+
+	member, err := d.memberName()
+	if err != nil {
+		return err
+	}
+
+	err = d.logForward.Configure(logforward.Config{
+		Protocol: protocol,
+		Endpoint: endpoint,
+		Streams:  streams,
+		Member:   member,
+	})
+	if err != nil {
+		return err
+	}
+
+	if d.logForward.Wants(logforward.StreamAudit) {
+		d.audit.SetForward(func(entry api.EventAudit) {
+			logForwardAudit(d.logForward, entry)
+		})
+	} else {
+		d.audit.SetForward(nil)
+	}
+
+	return nil
+}
+
+func (d *Daemon) setupTracing(endpoint string) error {
+	member, err := d.memberName()
+	if err != nil {
+		return err
+	}
+
+	return tracing.Configure(endpoint, member)
+}
+
 // Setup RBAC
 func (d *Daemon) setupRBACServer(rbacURL string, rbacKey string, rbacExpiry int64, rbacAgentURL string, rbacAgentUsername string, rbacAgentPrivateKey string, rbacAgentPublicKey string) error {
 	if d.rbac != nil || rbacURL == "" || rbacAgentURL == "" || rbacAgentUsername == "" || rbacAgentPrivateKey == "" || rbacAgentPublicKey == "" {
@@ -1288,7 +1582,32 @@ func (d *Daemon) setupRBACServer(rbacURL string, rbacKey string, rbacExpiry int6
 	return nil
 }
 
+// Return the projects the client certificate used for the request is
+// restricted to, and whether it is restricted at all.
+func (d *Daemon) userRestrictedProjects(r *http.Request) ([]string, bool) {
+	protocol, ok := r.Context().Value("protocol").(string)
+	if !ok || protocol != "tls" {
+		return nil, false
+	}
+
+	fingerprint, ok := r.Context().Value("username").(string)
+	if !ok {
+		return nil, false
+	}
+
+	d.clientCertsLock.RLock()
+	defer d.clientCertsLock.RUnlock()
+
+	projects, restricted := d.restrictedCerts[fingerprint]
+	return projects, restricted
+}
+
 func (d *Daemon) userIsAdmin(r *http.Request) bool {
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		return false
+	}
+
 	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
 		return true
 	}
@@ -1297,6 +1616,12 @@ func (d *Daemon) userIsAdmin(r *http.Request) bool {
 }
 
 func (d *Daemon) userHasPermission(r *http.Request, project string, permission string) bool {
+	projects, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		// Restricted certificates can't reconfigure their projects.
+		return permission != "manage-projects" && shared.StringInSlice(project, projects)
+	}
+
 	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
 		return true
 	}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/tracing"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -31,6 +34,22 @@ var imagesDownloadingLock sync.Mutex
 
 // ImageDownload resolves the image fingerprint and if not in the database, downloads it
 func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol string, certificate string, secret string, alias string, imageType string, forContainer bool, autoUpdate bool, storagePool string, preferCached bool, project string) (*api.Image, error) {
+	ctx := context.Background()
+	if op != nil {
+		ctx = op.Context()
+	}
+
+	_, span := tracing.StartChild(ctx, "image download",
+		tracing.String("lxd.image.server", server),
+		tracing.String("lxd.image.alias", alias))
+
+	info, err := d.imageDownload(op, server, protocol, certificate, secret, alias, imageType, forContainer, autoUpdate, storagePool, preferCached, project)
+	tracing.End(span, err)
+
+	return info, err
+}
+
+func (d *Daemon) imageDownload(op *operations.Operation, server string, protocol string, certificate string, secret string, alias string, imageType string, forContainer bool, autoUpdate bool, storagePool string, preferCached bool, project string) (*api.Image, error) {
 	var err error
 	var ctxMap log.Ctx
 
@@ -46,7 +65,7 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 	fp := alias
 
 	// Attempt to resolve the alias
-	if shared.StringInSlice(protocol, []string{"lxd", "simplestreams"}) {
+	if shared.StringInSlice(protocol, []string{"lxd", "simplestreams", "oci"}) {
 		args := &lxd.ConnectionArgs{
 			TLSServerCert: certificate,
 			UserAgent:     version.UserAgent,
@@ -61,6 +80,12 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			if err != nil {
 				return nil, err
 			}
+		} else if protocol == "oci" {
+			// Setup OCI registry client
+			remote, err = lxd.ConnectOCI(server, args)
+			if err != nil {
+				return nil, err
+			}
 		} else {
 			// Setup simplestreams client
 			remote, err = lxd.ConnectSimpleStreams(server, args)
@@ -84,6 +109,22 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			}
 
 			fp = info.Fingerprint
+
+			// OCI images are identified by their manifest digest until converted
+			if protocol == "oci" {
+				fp = strings.TrimPrefix(info.Properties["oci.digest"], "sha256:")
+			}
+		}
+	}
+
+	// OCI images are converted locally, so look for an existing copy of the same manifest
+	if protocol == "oci" && info != nil {
+		cachedFingerprint, err := d.cluster.ImageSourceGetCachedFingerprint(server, protocol, alias, imageType)
+		if err == nil {
+			_, cachedInfo, err := d.cluster.ImageGetFromAnyProject(cachedFingerprint)
+			if err == nil && cachedInfo.Properties["oci.digest"] == info.Properties["oci.digest"] {
+				fp = cachedFingerprint
+			}
 		}
 	}
 
@@ -109,6 +150,15 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		// Check if the image already exists in some other project.
 		_, imgInfo, err = d.cluster.ImageGetFromAnyProject(fp)
 		if err == nil {
+			// The image may have been added to the other project
+			// without being signed, so check it as if downloaded.
+			err = imageCheckSignature(d, imgInfo.Fingerprint, imgInfo.Signature)
+			if err != nil {
+				return nil, err
+			}
+
+			signature := imgInfo.Signature
+
 			// We just need to insert the database data, no actual download necessary.
 			err = d.cluster.ImageInsert(
 				project, imgInfo.Fingerprint, imgInfo.Filename, imgInfo.Size, false,
@@ -127,6 +177,15 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			if err != nil {
 				return nil, err
 			}
+
+			if signature != "" {
+				err = d.cluster.ImageSignatureUpdate(id, signature)
+				if err != nil {
+					return nil, err
+				}
+
+				imgInfo.Signature = signature
+			}
 		}
 	}
 
@@ -196,16 +255,17 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 	}
 
 	// Add the download to the queue
+	downloadFp := fp
 	imagesDownloadingLock.Lock()
-	imagesDownloading[fp] = make(chan bool)
+	imagesDownloading[downloadFp] = make(chan bool)
 	imagesDownloadingLock.Unlock()
 
 	// Unlock once this func ends.
 	defer func() {
 		imagesDownloadingLock.Lock()
-		if waitChannel, ok := imagesDownloading[fp]; ok {
+		if waitChannel, ok := imagesDownloading[downloadFp]; ok {
 			close(waitChannel)
-			delete(imagesDownloading, fp)
+			delete(imagesDownloading, downloadFp)
 		}
 		imagesDownloadingLock.Unlock()
 	}()
@@ -410,10 +470,35 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		info.ExpiresAt = time.Unix(imageMeta.ExpiryDate, 0)
 		info.Properties = imageMeta.Properties
 		info.Type = imageType
+		info.Signature = raw.Header.Get("LXD-Image-Signature")
+	} else if protocol == "oci" {
+		if secret != "" {
+			return nil, fmt.Errorf("Private images aren't supported by the OCI protocol")
+		}
+
+		info, err = ociImageDownload(d, server, certificate, alias, destName, progress)
+		if err != nil {
+			return nil, err
+		}
+
+		// The fingerprint is only known once the image has been converted
+		fp = info.Fingerprint
+
+		_, imgInfo, err := d.cluster.ImageGet(project, fp, false, true)
+		if err == nil {
+			logger.Debug("Image already exists in the db", log.Ctx{"image": fp})
+			return imgInfo, nil
+		}
 	} else {
 		return nil, fmt.Errorf("Unsupported protocol: %v", protocol)
 	}
 
+	// Verify the image signature against the trusted keys
+	err = imageCheckSignature(d, info.Fingerprint, info.Signature)
+	if err != nil {
+		return nil, err
+	}
+
 	// Override visiblity
 	info.Public = false
 
@@ -433,6 +518,18 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 	// Image is in the DB now, don't wipe on-disk files on failure
 	failure = false
 
+	if info.Signature != "" {
+		id, _, err := d.cluster.ImageGet(project, fp, false, true)
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.cluster.ImageSignatureUpdate(id, info.Signature)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Check if the image path changed (private images)
 	newDestName := filepath.Join(destDir, fp)
 	if newDestName != destName {
@@ -53,7 +53,7 @@ func daemonStorageMount(s *state.State) error {
 				return err
 			}
 
-			_, err = pool.MountCustomVolume(volumeName, nil)
+			_, err = pool.MountCustomVolume("default", volumeName, nil)
 			if err != nil {
 				return errors.Wrapf(err, "Failed to mount storage volume \"%s\"", source)
 			}
@@ -146,7 +146,7 @@ func daemonStorageValidate(s *state.State, target string) error {
 		return errors.Wrapf(err, "Unable to load storage volume \"%s\"", target)
 	}
 
-	snapshots, err := s.Cluster.StoragePoolVolumeSnapshotsGetType(volumeName, storagePoolVolumeTypeCustom, poolID)
+	snapshots, err := s.Cluster.StoragePoolVolumeSnapshotsGetType("default", volumeName, storagePoolVolumeTypeCustom, poolID)
 	if err != nil {
 		return errors.Wrapf(err, "Unable to load storage volume snapshots \"%s\"", target)
 	}
@@ -162,12 +162,12 @@ func daemonStorageValidate(s *state.State, target string) error {
 		}
 
 		// Mount volume
-		ourMount, err := pool.MountCustomVolume(volumeName, nil)
+		ourMount, err := pool.MountCustomVolume("default", volumeName, nil)
 		if err != nil {
 			return errors.Wrapf(err, "Failed to mount storage volume \"%s\"", target)
 		}
 		if ourMount {
-			defer pool.UnmountCustomVolume(volumeName, nil)
+			defer pool.UnmountCustomVolume("default", volumeName, nil)
 		}
 	} else {
 		volume, err := storageInit(s, "default", poolName, volumeName, storagePoolVolumeTypeCustom)
@@ -277,7 +277,7 @@ func daemonStorageMove(s *state.State, storageType string, target string) error
 			}
 
 			// Unmount old volume
-			_, err = pool.UnmountCustomVolume(sourceVolume, nil)
+			_, err = pool.UnmountCustomVolume("default", sourceVolume, nil)
 			if err != nil {
 				return errors.Wrapf(err, "Failed to umount storage volume \"%s/%s\"", sourcePool, sourceVolume)
 			}
@@ -313,7 +313,7 @@ func daemonStorageMove(s *state.State, storageType string, target string) error
 		}
 
 		// Mount volume
-		_, err = pool.MountCustomVolume(volumeName, nil)
+		_, err = pool.MountCustomVolume("default", volumeName, nil)
 		if err != nil {
 			return errors.Wrapf(err, "Failed to mount storage volume \"%s\"", target)
 		}
@@ -371,7 +371,7 @@ func daemonStorageMove(s *state.State, storageType string, target string) error
 			}
 
 			// Unmount old volume
-			_, err = pool.UnmountCustomVolume(sourceVolume, nil)
+			_, err = pool.UnmountCustomVolume("default", sourceVolume, nil)
 			if err != nil {
 				return errors.Wrapf(err, "Failed to umount storage volume \"%s/%s\"", sourcePool, sourceVolume)
 			}
@@ -4,8 +4,45 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/pkg/errors"
+)
+
+// Certificate types.
+const (
+	CertificateTypeClient  = 1
+	CertificateTypeMetrics = 2
 )
 
+// CertificateTypeNames associates a certificate type code to its name.
+var CertificateTypeNames = map[int]string{
+	CertificateTypeClient:  "client",
+	CertificateTypeMetrics: "metrics",
+}
+
+// CertificateTypeToName converts a certificate type code to its name.
+func CertificateTypeToName(certType int) (string, error) {
+	name, ok := CertificateTypeNames[certType]
+	if !ok {
+		return "", fmt.Errorf("Invalid certificate type code %d", certType)
+	}
+
+	return name, nil
+}
+
+// CertificateNameToType converts a certificate type name to its code.
+func CertificateNameToType(name string) (int, error) {
+	for code, typeName := range CertificateTypeNames {
+		if typeName == name {
+			return code, nil
+		}
+	}
+
+	return -1, fmt.Errorf("Unknown certificate type %q", name)
+}
+
 // CertInfo is here to pass the certificates content
 // from the database around
 type CertInfo struct {
@@ -14,13 +51,15 @@ type CertInfo struct {
 	Type        int
 	Name        string
 	Certificate string
+	Restricted  bool
+	Projects    []string
 }
 
 // CertificatesGet returns all certificates from the DB as CertBaseInfo objects.
 func (c *Cluster) CertificatesGet() (certs []*CertInfo, err error) {
 	err = c.Transaction(func(tx *ClusterTx) error {
 		rows, err := tx.tx.Query(
-			"SELECT id, fingerprint, type, name, certificate FROM certificates",
+			"SELECT id, fingerprint, type, name, certificate, restricted FROM certificates",
 		)
 		if err != nil {
 			return err
@@ -36,11 +75,24 @@ func (c *Cluster) CertificatesGet() (certs []*CertInfo, err error) {
 				&cert.Type,
 				&cert.Name,
 				&cert.Certificate,
+				&cert.Restricted,
 			)
 			certs = append(certs, cert)
 		}
 
-		return rows.Err()
+		err = rows.Err()
+		if err != nil {
+			return err
+		}
+
+		for _, cert := range certs {
+			cert.Projects, err = certificateProjects(tx.tx, cert.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
 	if err != nil {
 		return certs, err
@@ -64,11 +116,12 @@ func (c *Cluster) CertificateGet(fingerprint string) (cert *CertInfo, err error)
 		&cert.Type,
 		&cert.Name,
 		&cert.Certificate,
+		&cert.Restricted,
 	}
 
 	query := `
 		SELECT
-			id, fingerprint, type, name, certificate
+			id, fingerprint, type, name, certificate, restricted
 		FROM
 			certificates
 		WHERE fingerprint LIKE ?`
@@ -81,6 +134,14 @@ func (c *Cluster) CertificateGet(fingerprint string) (cert *CertInfo, err error)
 		return nil, err
 	}
 
+	err = c.Transaction(func(tx *ClusterTx) error {
+		cert.Projects, err = certificateProjects(tx.tx, cert.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return cert, err
 }
 
@@ -93,23 +154,31 @@ func (c *Cluster) CertSave(cert *CertInfo) error {
 				fingerprint,
 				type,
 				name,
-				certificate
-			) VALUES (?, ?, ?, ?)`,
+				certificate,
+				restricted
+			) VALUES (?, ?, ?, ?, ?)`,
 		)
 		if err != nil {
 			return err
 		}
 		defer stmt.Close()
-		_, err = stmt.Exec(
+		result, err := stmt.Exec(
 			cert.Fingerprint,
 			cert.Type,
 			cert.Name,
 			cert.Certificate,
+			cert.Restricted,
 		)
 		if err != nil {
 			return err
 		}
-		return nil
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return certificateProjectsUpdate(tx, id, cert.Projects)
 	})
 	return err
 }
@@ -125,10 +194,61 @@ func (c *Cluster) CertDelete(fingerprint string) error {
 }
 
 // CertUpdate updates the certificate with the given fingerprint.
-func (c *Cluster) CertUpdate(fingerprint string, certName string, certType int) error {
+func (c *Cluster) CertUpdate(fingerprint string, certName string, certType int, restricted bool, projects []string) error {
 	err := c.Transaction(func(tx *ClusterTx) error {
-		_, err := tx.tx.Exec("UPDATE certificates SET name=?, type=? WHERE fingerprint=?", certName, certType, fingerprint)
-		return err
+		_, err := tx.tx.Exec("UPDATE certificates SET name=?, type=?, restricted=? WHERE fingerprint=?", certName, certType, restricted, fingerprint)
+		if err != nil {
+			return err
+		}
+
+		ids, err := query.SelectIntegers(tx.tx, "SELECT id FROM certificates WHERE fingerprint=?", fingerprint)
+		if err != nil {
+			return err
+		}
+
+		if len(ids) != 1 {
+			return ErrNoSuchObject
+		}
+
+		return certificateProjectsUpdate(tx, int64(ids[0]), projects)
 	})
 	return err
 }
+
+// Return the names of the projects the certificate with the given ID has
+// access to.
+func certificateProjects(tx *sql.Tx, id int) ([]string, error) {
+	projects, err := query.SelectStrings(tx, `
+SELECT projects.name FROM projects
+  JOIN certificates_projects ON certificates_projects.project_id = projects.id
+ WHERE certificates_projects.certificate_id = ?
+ ORDER BY projects.name`, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fetch certificate projects")
+	}
+
+	return projects, nil
+}
+
+// Replace the list of projects the certificate with the given ID has access
+// to.
+func certificateProjectsUpdate(tx *ClusterTx, id int64, projects []string) error {
+	_, err := tx.tx.Exec("DELETE FROM certificates_projects WHERE certificate_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range projects {
+		projectID, err := tx.ProjectID(name)
+		if err != nil {
+			return errors.Wrapf(err, "Fetch ID of project %q", name)
+		}
+
+		_, err = tx.tx.Exec("INSERT INTO certificates_projects (certificate_id, project_id) VALUES (?, ?)", id, projectID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
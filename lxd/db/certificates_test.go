@@ -0,0 +1,64 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Save, update and fetch a restricted certificate.
+func TestCertificateRestricted(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.ProjectCreate(api.ProjectsPost{Name: "p1"})
+		return err
+	})
+	require.NoError(t, err)
+
+	err = cluster.CertSave(&db.CertInfo{
+		Fingerprint: "abcd",
+		Type:        db.CertificateTypeClient,
+		Name:        "foo",
+		Certificate: "FOO",
+		Restricted:  true,
+		Projects:    []string{"p1"},
+	})
+	require.NoError(t, err)
+
+	cert, err := cluster.CertificateGet("ab")
+	require.NoError(t, err)
+	assert.True(t, cert.Restricted)
+	assert.Equal(t, []string{"p1"}, cert.Projects)
+
+	err = cluster.CertUpdate("abcd", "foo", db.CertificateTypeClient, true, []string{"default", "p1"})
+	require.NoError(t, err)
+
+	certs, err := cluster.CertificatesGet()
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	assert.Equal(t, []string{"default", "p1"}, certs[0].Projects)
+
+	// Projects of a certificate must exist.
+	err = cluster.CertUpdate("abcd", "foo", db.CertificateTypeClient, true, []string{"p2"})
+	assert.Error(t, err)
+}
+
+func TestCertificateTypeNames(t *testing.T) {
+	name, err := db.CertificateTypeToName(db.CertificateTypeMetrics)
+	require.NoError(t, err)
+	assert.Equal(t, "metrics", name)
+
+	code, err := db.CertificateNameToType("client")
+	require.NoError(t, err)
+	assert.Equal(t, db.CertificateTypeClient, code)
+
+	_, err = db.CertificateNameToType("server")
+	assert.Error(t, err)
+}
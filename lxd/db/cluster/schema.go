@@ -12,8 +12,16 @@ CREATE TABLE certificates (
     type INTEGER NOT NULL,
     name TEXT NOT NULL,
     certificate TEXT NOT NULL,
+    restricted INTEGER NOT NULL DEFAULT 0,
     UNIQUE (fingerprint)
 );
+CREATE TABLE certificates_projects (
+    certificate_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (certificate_id) REFERENCES certificates (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (certificate_id, project_id)
+);
 CREATE TABLE config (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     key TEXT NOT NULL,
@@ -35,6 +43,7 @@ CREATE TABLE "images" (
     auto_update INTEGER NOT NULL DEFAULT 0,
     project_id INTEGER NOT NULL,
     type INTEGER NOT NULL DEFAULT 0,
+    signature TEXT NOT NULL DEFAULT '',
     UNIQUE (project_id, fingerprint),
     FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
 );
@@ -275,12 +284,14 @@ CREATE VIEW instances_snapshots_devices_ref (
      JOIN instances ON instances.id=instances_snapshots.instance_id
      JOIN projects ON projects.id=instances.project_id
      JOIN instances_snapshots ON instances_snapshots.id=instances_snapshots_devices.instance_snapshot_id;
-CREATE TABLE networks (
+CREATE TABLE "networks" (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name TEXT NOT NULL,
     description TEXT,
     state INTEGER NOT NULL DEFAULT 0,
-    UNIQUE (name)
+    project_id INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (name),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
 );
 CREATE TABLE networks_config (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
@@ -329,6 +340,26 @@ CREATE TABLE "operations" (
     FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
     FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
 );
+CREATE TABLE operations_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    uuid TEXT NOT NULL,
+    node_id INTEGER NOT NULL,
+    project_id INTEGER,
+    type INTEGER NOT NULL,
+    class TEXT NOT NULL,
+    description TEXT NOT NULL,
+    resources TEXT NOT NULL,
+    requestor_username TEXT NOT NULL DEFAULT '',
+    requestor_protocol TEXT NOT NULL DEFAULT '',
+    requestor_address TEXT NOT NULL DEFAULT '',
+    created_date DATETIME NOT NULL,
+    finished_date DATETIME NOT NULL,
+    status_code INTEGER NOT NULL,
+    err TEXT NOT NULL DEFAULT '',
+    UNIQUE (uuid),
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
 CREATE TABLE "profiles" (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name TEXT NOT NULL,
@@ -444,7 +475,21 @@ CREATE VIEW projects_used_by_ref (name,
     printf('/1.0/profiles/%s?project=%s',
     profiles.name,
     projects.name)
-    FROM profiles JOIN projects ON project_id=projects.id;
+    FROM profiles JOIN projects ON project_id=projects.id UNION
+  SELECT projects.name,
+    printf('/1.0/networks/%s?project=%s',
+    networks.name,
+    projects.name)
+    FROM networks JOIN projects ON project_id=projects.id UNION
+  SELECT projects.name,
+    printf('/1.0/storage-pools/%s/volumes/custom/%s?project=%s',
+    storage_pools.name,
+    storage_volumes.name,
+    projects.name)
+    FROM storage_volumes
+    JOIN storage_pools ON storage_pool_id=storage_pools.id
+    JOIN projects ON project_id=projects.id
+    WHERE storage_volumes.type=2 AND storage_volumes.snapshot=0;
 CREATE TABLE storage_pools (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name TEXT NOT NULL,
@@ -480,11 +525,24 @@ CREATE TABLE "storage_volumes" (
     description TEXT,
     snapshot INTEGER NOT NULL DEFAULT 0,
     project_id INTEGER NOT NULL,
+    expiry_date DATETIME,
+    content_type INTEGER NOT NULL DEFAULT 0,
     UNIQUE (storage_pool_id, node_id, project_id, name, type),
     FOREIGN KEY (storage_pool_id) REFERENCES storage_pools (id) ON DELETE CASCADE,
     FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
     FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
 );
+CREATE TABLE storage_volumes_backups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    storage_volume_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    creation_date DATETIME,
+    expiry_date DATETIME,
+    volume_only INTEGER NOT NULL default 0,
+    optimized_storage INTEGER NOT NULL default 0,
+    FOREIGN KEY (storage_volume_id) REFERENCES "storage_volumes" (id) ON DELETE CASCADE,
+    UNIQUE (storage_volume_id, name)
+);
 CREATE TABLE storage_volumes_config (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     storage_volume_id INTEGER NOT NULL,
@@ -493,6 +551,38 @@ CREATE TABLE storage_volumes_config (
     UNIQUE (storage_volume_id, key),
     FOREIGN KEY (storage_volume_id) REFERENCES storage_volumes (id) ON DELETE CASCADE
 );
+CREATE TABLE warnings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    node_id INTEGER NOT NULL,
+    project_id INTEGER,
+    entity_url TEXT NOT NULL DEFAULT '',
+    uuid TEXT NOT NULL,
+    type_code INTEGER NOT NULL,
+    status INTEGER NOT NULL,
+    first_seen_date DATETIME NOT NULL,
+    last_seen_date DATETIME NOT NULL,
+    updated_date DATETIME,
+    last_message TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    UNIQUE (uuid),
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+CREATE TABLE webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    types TEXT NOT NULL,
+    actions TEXT NOT NULL,
+    projects TEXT NOT NULL,
+    last_delivery_date DATETIME NOT NULL,
+    last_delivery_status TEXT NOT NULL DEFAULT '',
+    last_delivery_error TEXT NOT NULL DEFAULT '',
+    failed_deliveries INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (name)
+);
 
-INSERT INTO schema (version, updated_at) VALUES (21, strftime("%s"))
+INSERT INTO schema (version, updated_at) VALUES (30, strftime("%s"))
 `
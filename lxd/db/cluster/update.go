@@ -57,6 +57,228 @@ var updates = map[int]schema.Update{
 	19: updateFromV18,
 	20: updateFromV19,
 	21: updateFromV20,
+	22: updateFromV21,
+	23: updateFromV22,
+	24: updateFromV23,
+	25: updateFromV24,
+	26: updateFromV25,
+	27: updateFromV26,
+	28: updateFromV27,
+	29: updateFromV28,
+	30: updateFromV29,
+}
+
+// Add a webhooks table, holding the endpoints the lifecycle events are sent to.
+func updateFromV29(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    types TEXT NOT NULL,
+    actions TEXT NOT NULL,
+    projects TEXT NOT NULL,
+    last_delivery_date DATETIME NOT NULL,
+    last_delivery_status TEXT NOT NULL DEFAULT '',
+    last_delivery_error TEXT NOT NULL DEFAULT '',
+    failed_deliveries INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (name)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add an operations_history table, recording the operations once completed.
+func updateFromV28(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE operations_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    uuid TEXT NOT NULL,
+    node_id INTEGER NOT NULL,
+    project_id INTEGER,
+    type INTEGER NOT NULL,
+    class TEXT NOT NULL,
+    description TEXT NOT NULL,
+    resources TEXT NOT NULL,
+    requestor_username TEXT NOT NULL DEFAULT '',
+    requestor_protocol TEXT NOT NULL DEFAULT '',
+    requestor_address TEXT NOT NULL DEFAULT '',
+    created_date DATETIME NOT NULL,
+    finished_date DATETIME NOT NULL,
+    status_code INTEGER NOT NULL,
+    err TEXT NOT NULL DEFAULT '',
+    UNIQUE (uuid),
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add a warnings table, recording problems detected by the nodes.
+func updateFromV27(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE warnings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    node_id INTEGER NOT NULL,
+    project_id INTEGER,
+    entity_url TEXT NOT NULL DEFAULT '',
+    uuid TEXT NOT NULL,
+    type_code INTEGER NOT NULL,
+    status INTEGER NOT NULL,
+    first_seen_date DATETIME NOT NULL,
+    last_seen_date DATETIME NOT NULL,
+    updated_date DATETIME,
+    last_message TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    UNIQUE (uuid),
+    FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add a signature column to images, holding a detached signature of the image.
+func updateFromV26(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE images ADD COLUMN signature TEXT NOT NULL DEFAULT '';")
+	return err
+}
+
+// Add a content_type column to storage_volumes, marking the volumes of
+// virtual machines as block volumes.
+func updateFromV25(tx *sql.Tx) error {
+	stmts := `
+ALTER TABLE storage_volumes ADD COLUMN content_type INTEGER NOT NULL DEFAULT 0;
+UPDATE storage_volumes SET content_type = 1 WHERE type = 3;
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add an expiry_date column to storage_volumes, used by snapshots of custom
+// storage volumes.
+func updateFromV24(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE storage_volumes ADD COLUMN expiry_date DATETIME;")
+	return err
+}
+
+// Add a table holding the backups of custom storage volumes.
+func updateFromV23(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE storage_volumes_backups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    storage_volume_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    creation_date DATETIME,
+    expiry_date DATETIME,
+    volume_only INTEGER NOT NULL default 0,
+    optimized_storage INTEGER NOT NULL default 0,
+    FOREIGN KEY (storage_volume_id) REFERENCES "storage_volumes" (id) ON DELETE CASCADE,
+    UNIQUE (storage_volume_id, name)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add a restricted flag to certificates and a table listing the projects a
+// restricted certificate has access to.
+func updateFromV22(tx *sql.Tx) error {
+	stmts := `
+ALTER TABLE certificates ADD COLUMN restricted INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE certificates_projects (
+    certificate_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (certificate_id) REFERENCES certificates (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (certificate_id, project_id)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add a project_id column to the networks table, moving all existing
+// networks into the default project, and add networks and custom storage
+// volumes to the projects_used_by_ref view.
+func updateFromV21(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE networks_config_copy (
+    id INTEGER NOT NULL,
+    network_id INTEGER NOT NULL,
+    node_id INTEGER,
+    key TEXT NOT NULL,
+    value TEXT
+);
+INSERT INTO networks_config_copy SELECT * FROM networks_config;
+
+CREATE TABLE networks_nodes_copy (
+    id INTEGER NOT NULL,
+    network_id INTEGER NOT NULL,
+    node_id INTEGER NOT NULL
+);
+INSERT INTO networks_nodes_copy SELECT * FROM networks_nodes;
+
+CREATE TABLE new_networks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    state INTEGER NOT NULL DEFAULT 0,
+    project_id INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (name),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+INSERT INTO new_networks (id, name, description, state, project_id)
+    SELECT id, name, description, state, 1 FROM networks;
+
+DROP TABLE networks;
+ALTER TABLE new_networks RENAME TO networks;
+
+INSERT INTO networks_config SELECT * FROM networks_config_copy;
+INSERT INTO networks_nodes SELECT * FROM networks_nodes_copy;
+DROP TABLE networks_config_copy;
+DROP TABLE networks_nodes_copy;
+
+DROP VIEW projects_used_by_ref;
+CREATE VIEW projects_used_by_ref (name,
+    value) AS
+  SELECT projects.name,
+    printf('/1.0/instances/%s?project=%s',
+    "instances".name,
+    projects.name)
+    FROM "instances" JOIN projects ON project_id=projects.id UNION
+  SELECT projects.name,
+    printf('/1.0/images/%s',
+    images.fingerprint)
+    FROM images JOIN projects ON project_id=projects.id UNION
+  SELECT projects.name,
+    printf('/1.0/profiles/%s?project=%s',
+    profiles.name,
+    projects.name)
+    FROM profiles JOIN projects ON project_id=projects.id UNION
+  SELECT projects.name,
+    printf('/1.0/networks/%s?project=%s',
+    networks.name,
+    projects.name)
+    FROM networks JOIN projects ON project_id=projects.id UNION
+  SELECT projects.name,
+    printf('/1.0/storage-pools/%s/volumes/custom/%s?project=%s',
+    storage_pools.name,
+    storage_volumes.name,
+    projects.name)
+    FROM storage_volumes
+    JOIN storage_pools ON storage_pool_id=storage_pools.id
+    JOIN projects ON project_id=projects.id
+    WHERE storage_volumes.type=2 AND storage_volumes.snapshot=0;
+`
+	_, err := tx.Exec(stmts)
+	return err
 }
 
 // Add "images_profiles" table
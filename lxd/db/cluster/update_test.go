@@ -620,3 +620,117 @@ VALUES (2, 'n2', '', '2.2.3.4:666', 1, 32, ?, 0)`, time.Now())
 	require.True(t, ok)
 	assert.Equal(t, sqliteErr.Code, sqlite3.ErrConstraint)
 }
+
+func TestUpdateFromV21(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(22, func(db *sql.DB) {
+		// Insert a node and a network with some config.
+		_, err := db.Exec(
+			"INSERT INTO nodes VALUES (1, 'n1', '', '1.2.3.4:666', 1, 32, ?, 0, 1)",
+			time.Now())
+		require.NoError(t, err)
+
+		_, err = db.Exec("INSERT INTO networks VALUES (1, 'lxdbr0', 'bridge', 1)")
+		require.NoError(t, err)
+
+		_, err = db.Exec("INSERT INTO networks_nodes VALUES (1, 1, 1)")
+		require.NoError(t, err)
+
+		_, err = db.Exec("INSERT INTO networks_config VALUES (1, 1, NULL, 'ipv4.address', 'auto')")
+		require.NoError(t, err)
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The network has been moved to the default project.
+	var projectID int
+	err = db.QueryRow("SELECT project_id FROM networks WHERE name='lxdbr0'").Scan(&projectID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, projectID)
+
+	// The rows referencing the network have been preserved.
+	var value string
+	err = db.QueryRow("SELECT value FROM networks_config WHERE network_id=1 AND key='ipv4.address'").Scan(&value)
+	require.NoError(t, err)
+	assert.Equal(t, "auto", value)
+
+	var count int
+	err = db.QueryRow("SELECT count(*) FROM networks_nodes WHERE network_id=1").Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// The network shows up as used by the default project.
+	err = db.QueryRow("SELECT value FROM projects_used_by_ref WHERE name='default' AND value LIKE '/1.0/networks/%'").Scan(&value)
+	require.NoError(t, err)
+	assert.Equal(t, "/1.0/networks/lxdbr0?project=default", value)
+
+	// Network names are unique across projects, since they're also the
+	// names of the host interfaces.
+	_, err = db.Exec("INSERT INTO projects (id, name, description) VALUES (2, 'p1', '')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO networks (name, description, project_id) VALUES ('lxdbr0', '', 2)")
+	require.Error(t, err)
+}
+
+func TestUpdateFromV22(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(23, func(db *sql.DB) {
+		_, err := db.Exec("INSERT INTO certificates VALUES (1, 'abcd', 1, 'foo', 'cert')")
+		require.NoError(t, err)
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Existing certificates are not restricted.
+	var restricted bool
+	err = db.QueryRow("SELECT restricted FROM certificates WHERE fingerprint='abcd'").Scan(&restricted)
+	require.NoError(t, err)
+	assert.False(t, restricted)
+
+	// A certificate can be granted access to a project.
+	_, err = db.Exec("INSERT INTO certificates_projects VALUES (1, 1)")
+	require.NoError(t, err)
+
+	// Deleting the certificate deletes its project references.
+	_, err = db.Exec("PRAGMA foreign_keys=ON")
+	require.NoError(t, err)
+
+	_, err = db.Exec("DELETE FROM certificates WHERE id=1")
+	require.NoError(t, err)
+
+	var count int
+	err = db.QueryRow("SELECT count(*) FROM certificates_projects").Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestUpdateFromV25(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(26, func(db *sql.DB) {
+		_, err := db.Exec("INSERT INTO nodes (id, name, address, schema, api_extensions, arch) VALUES (1, 'one', '1.1.1.1', 666, 999, 1)")
+		require.NoError(t, err)
+
+		_, err = db.Exec("INSERT INTO storage_pools (id, name, driver) VALUES (1, 'default', 'dir')")
+		require.NoError(t, err)
+
+		// A custom volume and a virtual machine volume.
+		_, err = db.Exec("INSERT INTO storage_volumes (id, name, storage_pool_id, node_id, type, project_id) VALUES (1, 'vol1', 1, 1, 2, 1)")
+		require.NoError(t, err)
+
+		_, err = db.Exec("INSERT INTO storage_volumes (id, name, storage_pool_id, node_id, type, project_id) VALUES (2, 'vm1', 1, 1, 3, 1)")
+		require.NoError(t, err)
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Only the virtual machine volume is a block volume.
+	var contentType int
+	err = db.QueryRow("SELECT content_type FROM storage_volumes WHERE id=1").Scan(&contentType)
+	require.NoError(t, err)
+	assert.Equal(t, 0, contentType)
+
+	err = db.QueryRow("SELECT content_type FROM storage_volumes WHERE id=2").Scan(&contentType)
+	require.NoError(t, err)
+	assert.Equal(t, 1, contentType)
+}
@@ -1,12 +1,15 @@
+//go:build linux && cgo && !agent
 // +build linux,cgo,!agent
 
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
 
@@ -16,6 +19,7 @@ import (
 	"github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/db/node"
 	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/lxd/tracing"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
 )
@@ -97,7 +101,8 @@ func ForLegacyPatches(db *sql.DB) *Node {
 // database.
 //
 // FIXME: this is used for compatibility with some legacy code, and should be
-//        dropped once there are no call sites left.
+//
+//	dropped once there are no call sites left.
 func (n *Node) DB() *sql.DB {
 	return n.db
 }
@@ -381,12 +386,35 @@ func (c *Cluster) transaction(f func(*ClusterTx) error) error {
 		stmts:  c.stmts,
 	}
 
-	return query.Retry(func() error {
+	// Trace the transaction, tagged with the function which started it.
+	span := transactionSpan()
+
+	err := query.Retry(func() error {
 		return query.Transaction(c.db, func(tx *sql.Tx) error {
 			clusterTx.tx = tx
 			return f(clusterTx)
 		})
 	})
+
+	tracing.End(span, err)
+
+	return err
+}
+
+// Start the span of a transaction, named after the caller of Transaction or
+// ExitExclusive. The span does nothing if tracing is disabled.
+func transactionSpan() tracing.Span {
+	caller := "unknown"
+	if tracing.Enabled() {
+		pc, _, _, ok := runtime.Caller(3)
+		fn := runtime.FuncForPC(pc)
+		if ok && fn != nil {
+			caller = fn.Name()
+		}
+	}
+
+	_, span := tracing.Start(context.Background(), "db.transaction", tracing.String("code.function", caller))
+	return span
 }
 
 // NodeID sets the the node NodeID associated with this cluster instance. It's used for
@@ -408,7 +436,8 @@ func (c *Cluster) Close() error {
 // DB returns the low level database handle to the cluster database.
 //
 // FIXME: this is used for compatibility with some legacy code, and should be
-//        dropped once there are no call sites left.
+//
+//	dropped once there are no call sites left.
 func (c *Cluster) DB() *sql.DB {
 	return c.db
 }
@@ -20,6 +20,7 @@ var ImageSourceProtocol = map[int]string{
 	0: "lxd",
 	1: "direct",
 	2: "simplestreams",
+	3: "oci",
 }
 
 // ImagesGetLocal returns the names of all local images.
@@ -332,13 +333,13 @@ func (c *Cluster) ImageGet(project, fingerprint string, public bool, strictMatch
 	// These two humongous things will be filled by the call to DbQueryRowScan
 	outfmt := []interface{}{&id, &image.Fingerprint, &image.Filename,
 		&image.Size, &image.Cached, &image.Public, &image.AutoUpdate, &arch,
-		&create, &expire, &used, &upload, &imageType}
+		&create, &expire, &used, &upload, &imageType, &image.Signature}
 
 	inargs := []interface{}{project}
 	query := `
         SELECT
             images.id, fingerprint, filename, size, cached, public, auto_update, architecture,
-            creation_date, expiry_date, last_use_date, upload_date, type
+            creation_date, expiry_date, last_use_date, upload_date, type, signature
         FROM images
         JOIN projects ON projects.id = images.project_id
        WHERE projects.name = ?`
@@ -412,13 +413,13 @@ func (c *Cluster) ImageGetFromAnyProject(fingerprint string) (int, *api.Image, e
 	// These two humongous things will be filled by the call to DbQueryRowScan
 	outfmt := []interface{}{&id, &image.Fingerprint, &image.Filename,
 		&image.Size, &image.Cached, &image.Public, &image.AutoUpdate, &arch,
-		&create, &expire, &used, &upload, &imageType}
+		&create, &expire, &used, &upload, &imageType, &image.Signature}
 
 	inargs := []interface{}{fingerprint}
 	query := `
         SELECT
             images.id, fingerprint, filename, size, cached, public, auto_update, architecture,
-            creation_date, expiry_date, last_use_date, upload_date, type
+            creation_date, expiry_date, last_use_date, upload_date, type, signature
         FROM images
         WHERE fingerprint = ?
         LIMIT 1`
@@ -1050,6 +1051,12 @@ func (c *Cluster) ImageUploadedAt(id int, uploadedAt time.Time) error {
 	return err
 }
 
+// ImageSignatureUpdate sets the detached signature of an image.
+func (c *Cluster) ImageSignatureUpdate(id int, signature string) error {
+	err := exec(c.db, "UPDATE images SET signature=? WHERE id=?", signature, id)
+	return err
+}
+
 // ImagesGetOnCurrentNode returns all images that the current LXD node instance has.
 func (c *Cluster) ImagesGetOnCurrentNode() (map[string][]string, error) {
 	return c.ImagesGetByNodeID(c.nodeID)
@@ -65,6 +65,59 @@ func (c *ClusterTx) NetworkIDsNotPending() (map[string]int64, error) {
 	return ids, nil
 }
 
+// NetworkIDsNotPendingByProject returns a map associating the name of each
+// network in the given project to its ID.
+//
+// Pending networks are skipped.
+func (c *ClusterTx) NetworkIDsNotPendingByProject(project string) (map[string]int64, error) {
+	ids, err := c.NetworkIDsNotPending()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := query.SelectStrings(c.tx, `
+SELECT networks.name FROM networks
+  JOIN projects ON projects.id = networks.project_id
+ WHERE projects.name = ?
+`, project)
+	if err != nil {
+		return nil, err
+	}
+
+	projectIDs := map[string]int64{}
+	for _, name := range names {
+		id, ok := ids[name]
+		if ok {
+			projectIDs[name] = id
+		}
+	}
+
+	return projectIDs, nil
+}
+
+// NetworkProject returns the name of the project the network with the given
+// name belongs to.
+func (c *ClusterTx) NetworkProject(name string) (string, error) {
+	stmt := `
+SELECT projects.name FROM networks
+  JOIN projects ON projects.id = networks.project_id
+ WHERE networks.name = ?
+`
+	names, err := query.SelectStrings(c.tx, stmt, name)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(names) {
+	case 0:
+		return "", ErrNoSuchObject
+	case 1:
+		return names[0], nil
+	default:
+		return "", fmt.Errorf("more than one network has the given name")
+	}
+}
+
 // NetworkID returns the ID of the network with the given name.
 func (c *ClusterTx) NetworkID(name string) (int64, error) {
 	stmt := "SELECT id FROM networks WHERE name=?"
@@ -147,8 +200,8 @@ WHERE networks.id = ? AND networks.state = ?
 }
 
 // NetworkCreatePending creates a new pending network on the node with
-// the given name.
-func (c *ClusterTx) NetworkCreatePending(node, name string, conf map[string]string) error {
+// the given name, in the given project.
+func (c *ClusterTx) NetworkCreatePending(node, project, name string, conf map[string]string) error {
 	// First check if a network with the given name exists, and, if
 	// so, that it's in the pending state.
 	network := struct {
@@ -181,8 +234,13 @@ func (c *ClusterTx) NetworkCreatePending(node, name string, conf map[string]stri
 	if networkID == 0 {
 		// No existing network with the given name was found, let's create
 		// one.
-		columns := []string{"name"}
-		values := []interface{}{name}
+		projectID, err := c.ProjectID(project)
+		if err != nil {
+			return err
+		}
+
+		columns := []string{"name", "project_id"}
+		values := []interface{}{name, projectID}
 		networkID, err = query.UpsertObject(c.tx, "networks", columns, values)
 		if err != nil {
 			return err
@@ -192,6 +250,16 @@ func (c *ClusterTx) NetworkCreatePending(node, name string, conf map[string]stri
 		if network.state != networkPending {
 			return fmt.Errorf("network is not in pending state")
 		}
+
+		// Check that the existing network belongs to the same project.
+		networkProject, err := c.NetworkProject(name)
+		if err != nil {
+			return err
+		}
+
+		if networkProject != project {
+			return fmt.Errorf("network is pending in another project")
+		}
 	}
 
 	// Get the ID of the node with the given name.
@@ -262,6 +330,11 @@ func (c *Cluster) NetworksNotPending() ([]string, error) {
 	return c.networks("NOT state=?", networkPending)
 }
 
+// NetworksByProject returns the names of the networks in the given project.
+func (c *Cluster) NetworksByProject(project string) ([]string, error) {
+	return c.networks("project_id = (SELECT id FROM projects WHERE name=?)", project)
+}
+
 // Get all networks matching the given WHERE filter (if given).
 func (c *Cluster) networks(where string, args ...interface{}) ([]string, error) {
 	q := "SELECT name FROM networks"
@@ -345,6 +418,26 @@ func (c *Cluster) NetworkGet(name string) (int64, *api.Network, error) {
 	return id, &network, nil
 }
 
+// NetworkGetByProject returns the network with the given name, if it belongs
+// to the given project.
+func (c *Cluster) NetworkGetByProject(project, name string) (int64, *api.Network, error) {
+	var networkProject string
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		networkProject, err = tx.NetworkProject(name)
+		return err
+	})
+	if err != nil {
+		return -1, nil, err
+	}
+
+	if networkProject != project {
+		return -1, nil, ErrNoSuchObject
+	}
+
+	return c.NetworkGet(name)
+}
+
 // Return the names of the nodes the given network is defined on.
 func (c *Cluster) networkNodes(networkID int64) ([]string, error) {
 	stmt := `
@@ -454,11 +547,11 @@ func (c *Cluster) NetworkConfigGet(id int64) (map[string]string, error) {
 	return config, nil
 }
 
-// NetworkCreate creates a new network.
-func (c *Cluster) NetworkCreate(name, description string, config map[string]string) (int64, error) {
+// NetworkCreate creates a new network in the given project.
+func (c *Cluster) NetworkCreate(project, name, description string, config map[string]string) (int64, error) {
 	var id int64
 	err := c.Transaction(func(tx *ClusterTx) error {
-		result, err := tx.tx.Exec("INSERT INTO networks (name, description, state) VALUES (?, ?, ?)", name, description, networkCreated)
+		result, err := tx.tx.Exec("INSERT INTO networks (name, description, state, project_id) VALUES (?, ?, ?, (SELECT id FROM projects WHERE name = ?))", name, description, networkCreated, project)
 		if err != nil {
 			return err
 		}
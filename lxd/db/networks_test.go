@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,7 +16,7 @@ func TestNetworksNodeConfigs(t *testing.T) {
 	cluster, cleanup := db.NewTestCluster(t)
 	defer cleanup()
 
-	_, err := cluster.NetworkCreate("lxdbr0", "", map[string]string{
+	_, err := cluster.NetworkCreate("default", "lxdbr0", "", map[string]string{
 		"dns.mode":                   "none",
 		"bridge.external_interfaces": "vlan0",
 	})
@@ -45,7 +46,7 @@ func TestNetworkCreatePending(t *testing.T) {
 	require.NoError(t, err)
 
 	config := map[string]string{"bridge.external_interfaces": "foo"}
-	err = tx.NetworkCreatePending("buzz", "network1", config)
+	err = tx.NetworkCreatePending("buzz", "default", "network1", config)
 	require.NoError(t, err)
 
 	networkID, err := tx.NetworkID("network1")
@@ -53,7 +54,7 @@ func TestNetworkCreatePending(t *testing.T) {
 	assert.True(t, networkID > 0)
 
 	config = map[string]string{"bridge.external_interfaces": "bar"}
-	err = tx.NetworkCreatePending("rusp", "network1", config)
+	err = tx.NetworkCreatePending("rusp", "default", "network1", config)
 	require.NoError(t, err)
 
 	// The initial node (whose name is 'none' by default) is missing.
@@ -61,7 +62,7 @@ func TestNetworkCreatePending(t *testing.T) {
 	require.EqualError(t, err, "Network not defined on nodes: none")
 
 	config = map[string]string{"bridge.external_interfaces": "egg"}
-	err = tx.NetworkCreatePending("none", "network1", config)
+	err = tx.NetworkCreatePending("none", "default", "network1", config)
 	require.NoError(t, err)
 
 	// Now the storage is defined on all nodes.
@@ -82,10 +83,10 @@ func TestNetworksCreatePending_AlreadyDefined(t *testing.T) {
 	_, err := tx.NodeAdd("buzz", "1.2.3.4:666")
 	require.NoError(t, err)
 
-	err = tx.NetworkCreatePending("buzz", "network1", map[string]string{})
+	err = tx.NetworkCreatePending("buzz", "default", "network1", map[string]string{})
 	require.NoError(t, err)
 
-	err = tx.NetworkCreatePending("buzz", "network1", map[string]string{})
+	err = tx.NetworkCreatePending("buzz", "default", "network1", map[string]string{})
 	require.Equal(t, db.ErrAlreadyDefined, err)
 }
 
@@ -94,6 +95,65 @@ func TestNetworksCreatePending_NonExistingNode(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
 	defer cleanup()
 
-	err := tx.NetworkCreatePending("buzz", "network1", map[string]string{})
+	err := tx.NetworkCreatePending("buzz", "default", "network1", map[string]string{})
 	require.Equal(t, db.ErrNoSuchObject, err)
 }
+
+// Networks can be filtered by project.
+func TestNetworksByProject(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.ProjectCreate(api.ProjectsPost{Name: "p1"})
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = cluster.NetworkCreate("default", "lxdbr0", "", map[string]string{})
+	require.NoError(t, err)
+
+	_, err = cluster.NetworkCreate("p1", "lxdbr1", "", map[string]string{})
+	require.NoError(t, err)
+
+	networks, err := cluster.NetworksByProject("p1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lxdbr1"}, networks)
+
+	_, network, err := cluster.NetworkGetByProject("p1", "lxdbr1")
+	require.NoError(t, err)
+	assert.Equal(t, "lxdbr1", network.Name)
+
+	_, _, err = cluster.NetworkGetByProject("p1", "lxdbr0")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
+// Network names are unique across projects, since they're also the names of
+// the host interfaces.
+func TestNetworkCreate_SameNameInTwoProjects(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.ProjectCreate(api.ProjectsPost{Name: "p1"})
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = cluster.NetworkCreate("default", "lxdbr0", "", map[string]string{})
+	require.NoError(t, err)
+
+	_, err = cluster.NetworkCreate("p1", "lxdbr0", "", map[string]string{})
+	assert.Error(t, err)
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		project, err := tx.NetworkProject("lxdbr0")
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, "default", project)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -16,6 +16,7 @@ type Operation struct {
 	UUID        string        // User-visible identifier
 	NodeAddress string        // Address of the node the operation is running on
 	Type        OperationType // Type of the operation
+	Project     string        // Name of the project the operation belongs to, if any
 }
 
 // Operations returns all operations associated with this node.
@@ -23,6 +24,12 @@ func (c *ClusterTx) Operations() ([]Operation, error) {
 	return c.operations("node_id=?", c.nodeID)
 }
 
+// OperationsOfType returns all operations of the given type, across all
+// nodes.
+func (c *ClusterTx) OperationsOfType(opType OperationType) ([]Operation, error) {
+	return c.operations("type=?", opType)
+}
+
 // OperationsUUIDs returns the UUIDs of all operations associated with this
 // node.
 func (c *ClusterTx) OperationsUUIDs() ([]string, error) {
@@ -114,10 +121,14 @@ func (c *ClusterTx) operations(where string, args ...interface{}) ([]Operation,
 			&operations[i].UUID,
 			&operations[i].NodeAddress,
 			&operations[i].Type,
+			&operations[i].Project,
 		}
 	}
 	sql := `
-SELECT operations.id, uuid, nodes.address, type FROM operations JOIN nodes ON nodes.id = node_id `
+SELECT operations.id, uuid, nodes.address, type, coalesce(projects.name, '')
+  FROM operations
+  JOIN nodes ON nodes.id = node_id
+  LEFT JOIN projects ON projects.id = project_id `
 	if where != "" {
 		sql += fmt.Sprintf("WHERE %s ", where)
 	}
@@ -0,0 +1,164 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/pkg/errors"
+)
+
+// OperationHistory holds information about an operation which completed on a
+// node of the cluster.
+type OperationHistory struct {
+	ID                int64               // Stable database identifier
+	UUID              string              // User-visible identifier
+	Node              string              // Name of the node the operation ran on
+	Project           string              // Name of the project the operation belonged to, if any
+	Type              OperationType       // Type of the operation
+	Class             string              // Class of the operation (task, websocket or token)
+	Description       string              // Description of the operation
+	Resources         map[string][]string // API URLs of the resources affected by the operation
+	RequestorUsername string              // Identity of the client which requested the operation, if any
+	RequestorProtocol string              // Protocol used by the client which requested the operation, if any
+	RequestorAddress  string              // Address of the client which requested the operation, if any
+	CreatedAt         time.Time           // When the operation was created
+	FinishedAt        time.Time           // When the operation completed
+	StatusCode        int                 // Final status code of the operation
+	Err               string              // Error of the operation, if it failed
+}
+
+// OperationsHistory returns the completed operations of the cluster which
+// finished after the given time, belonging to the given project or to no
+// project at all.
+func (c *ClusterTx) OperationsHistory(project string, since time.Time) ([]OperationHistory, error) {
+	return c.operationsHistory("(projects.name=? OR operations_history.project_id IS NULL) AND finished_date>=?", project, since.UTC())
+}
+
+// OperationHistoryByUUID returns the completed operation with the given UUID.
+func (c *ClusterTx) OperationHistoryByUUID(uuid string) (OperationHistory, error) {
+	null := OperationHistory{}
+	operations, err := c.operationsHistory("operations_history.uuid=?", uuid)
+	if err != nil {
+		return null, err
+	}
+
+	switch len(operations) {
+	case 0:
+		return null, ErrNoSuchObject
+	case 1:
+		return operations[0], nil
+	default:
+		return null, fmt.Errorf("More than one operation matches")
+	}
+}
+
+// OperationHistoryAdd records a completed operation of this node.
+func (c *ClusterTx) OperationHistoryAdd(op OperationHistory) error {
+	var projectID interface{}
+	if op.Project != "" {
+		var err error
+		projectID, err = c.ProjectID(op.Project)
+		if err != nil {
+			return errors.Wrap(err, "Fetch project ID")
+		}
+	}
+
+	resources, err := json.Marshal(op.Resources)
+	if err != nil {
+		return err
+	}
+
+	columns := []string{
+		"uuid", "node_id", "project_id", "type", "class", "description", "resources",
+		"requestor_username", "requestor_protocol", "requestor_address",
+		"created_date", "finished_date", "status_code", "err",
+	}
+	values := []interface{}{
+		op.UUID, c.nodeID, projectID, op.Type, op.Class, op.Description, string(resources),
+		op.RequestorUsername, op.RequestorProtocol, op.RequestorAddress,
+		op.CreatedAt.UTC(), op.FinishedAt.UTC(), op.StatusCode, op.Err,
+	}
+
+	_, err = query.UpsertObject(c.tx, "operations_history", columns, values)
+	if err != nil {
+		return errors.Wrap(err, "Failed to record operation")
+	}
+
+	return nil
+}
+
+// OperationHistoryPrune removes the completed operations which finished
+// before the given time.
+func (c *ClusterTx) OperationHistoryPrune(before time.Time) error {
+	_, err := c.tx.Exec("DELETE FROM operations_history WHERE finished_date<?", before.UTC())
+	if err != nil {
+		return errors.Wrap(err, "Failed to prune operations history")
+	}
+
+	return nil
+}
+
+// Returns the completed operations in the cluster, filtered by the given
+// clause.
+func (c *ClusterTx) operationsHistory(where string, args ...interface{}) ([]OperationHistory, error) {
+	operations := []OperationHistory{}
+	resources := []string{}
+	dest := func(i int) []interface{} {
+		operations = append(operations, OperationHistory{})
+		resources = append(resources, "")
+		return []interface{}{
+			&operations[i].ID,
+			&operations[i].UUID,
+			&operations[i].Node,
+			&operations[i].Project,
+			&operations[i].Type,
+			&operations[i].Class,
+			&operations[i].Description,
+			&resources[i],
+			&operations[i].RequestorUsername,
+			&operations[i].RequestorProtocol,
+			&operations[i].RequestorAddress,
+			&operations[i].CreatedAt,
+			&operations[i].FinishedAt,
+			&operations[i].StatusCode,
+			&operations[i].Err,
+		}
+	}
+
+	sql := `
+SELECT operations_history.id, operations_history.uuid, nodes.name, coalesce(projects.name, ''),
+       type, class, operations_history.description, resources,
+       requestor_username, requestor_protocol, requestor_address,
+       created_date, finished_date, status_code, err
+  FROM operations_history
+  JOIN nodes ON nodes.id = operations_history.node_id
+  LEFT JOIN projects ON projects.id = operations_history.project_id `
+	if where != "" {
+		sql += fmt.Sprintf("WHERE %s ", where)
+	}
+	sql += "ORDER BY operations_history.id"
+
+	stmt, err := c.tx.Prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch operations history")
+	}
+
+	for i := range operations {
+		err := json.Unmarshal([]byte(resources[i]), &operations[i].Resources)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to decode resources of operation %s", operations[i].UUID)
+		}
+	}
+
+	return operations, nil
+}
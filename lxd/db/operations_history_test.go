@@ -0,0 +1,66 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Record a completed operation, then prune it.
+func TestOperationHistory(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	finished := time.Now().Add(-time.Hour)
+	err := tx.OperationHistoryAdd(db.OperationHistory{
+		UUID:              "abcd",
+		Project:           "default",
+		Type:              db.OperationImageRefresh,
+		Class:             "task",
+		Description:       "Refreshing image",
+		Resources:         map[string][]string{"images": {"/1.0/images/abcd"}},
+		RequestorUsername: "root",
+		RequestorProtocol: "unix",
+		CreatedAt:         finished.Add(-time.Minute),
+		FinishedAt:        finished,
+		StatusCode:        400,
+		Err:               "Failed to download image",
+	})
+	require.NoError(t, err)
+
+	operations, err := tx.OperationsHistory("default", finished.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+
+	op := operations[0]
+	assert.Equal(t, "abcd", op.UUID)
+	assert.Equal(t, "none", op.Node)
+	assert.Equal(t, "default", op.Project)
+	assert.Equal(t, db.OperationImageRefresh, op.Type)
+	assert.Equal(t, []string{"/1.0/images/abcd"}, op.Resources["images"])
+	assert.Equal(t, "root", op.RequestorUsername)
+	assert.Equal(t, 400, op.StatusCode)
+	assert.Equal(t, "Failed to download image", op.Err)
+
+	// Operations finished before the given time aren't returned.
+	operations, err = tx.OperationsHistory("default", finished.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, operations, 0)
+
+	err = tx.OperationHistoryPrune(finished.Add(-time.Minute))
+	require.NoError(t, err)
+
+	_, err = tx.OperationHistoryByUUID("abcd")
+	require.NoError(t, err)
+
+	err = tx.OperationHistoryPrune(time.Now())
+	require.NoError(t, err)
+
+	_, err = tx.OperationHistoryByUUID("abcd")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
@@ -28,6 +28,7 @@ func TestOperation(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, id, operation.ID)
 	assert.Equal(t, db.OperationContainerCreate, operation.Type)
+	assert.Equal(t, "default", operation.Project)
 
 	uuids, err := tx.OperationsUUIDs()
 	require.NoError(t, err)
@@ -58,6 +59,7 @@ func TestOperationNoProject(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, id, operation.ID)
 	assert.Equal(t, db.OperationContainerCreate, operation.Type)
+	assert.Equal(t, "", operation.Project)
 
 	uuids, err := tx.OperationsUUIDs()
 	require.NoError(t, err)
@@ -69,3 +71,20 @@ func TestOperationNoProject(t *testing.T) {
 	_, err = tx.OperationByUUID("abcd")
 	assert.Equal(t, db.ErrNoSuchObject, err)
 }
+
+// Operations can be filtered by type.
+func TestOperationsOfType(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.OperationAdd("", "abcd", db.OperationCertificateAddToken)
+	require.NoError(t, err)
+
+	_, err = tx.OperationAdd("default", "efgh", db.OperationContainerCreate)
+	require.NoError(t, err)
+
+	operations, err := tx.OperationsOfType(db.OperationCertificateAddToken)
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+	assert.Equal(t, "abcd", operations[0].UUID)
+}
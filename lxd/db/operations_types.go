@@ -55,6 +55,16 @@ const (
 	OperationInstanceTypesUpdate
 	OperationBackupsExpire
 	OperationSnapshotsExpire
+	OperationCertificateAddToken
+	OperationClusterJoinToken
+	OperationCustomVolumeBackupCreate
+	OperationCustomVolumeBackupRemove
+	OperationCustomVolumeBackupRename
+	OperationCustomVolumeBackupRestore
+	OperationCustomVolumeSnapshotsExpire
+	OperationCustomVolumeImport
+	OperationStoragePoolVolumesMove
+	OperationOperationsHistoryExpire
 )
 
 // Description return a human-readable description of the operation type.
@@ -150,6 +160,26 @@ func (t OperationType) Description() string {
 		return "Cleaning up expired backups"
 	case OperationSnapshotsExpire:
 		return "Cleaning up expired snapshots"
+	case OperationCertificateAddToken:
+		return "Certificate add token"
+	case OperationClusterJoinToken:
+		return "Cluster join token"
+	case OperationCustomVolumeBackupCreate:
+		return "Creating custom volume backup"
+	case OperationCustomVolumeBackupRemove:
+		return "Deleting custom volume backup"
+	case OperationCustomVolumeBackupRename:
+		return "Renaming custom volume backup"
+	case OperationCustomVolumeBackupRestore:
+		return "Restoring custom volume backup"
+	case OperationCustomVolumeSnapshotsExpire:
+		return "Cleaning up expired volume snapshots"
+	case OperationCustomVolumeImport:
+		return "Importing custom volume from disk image"
+	case OperationStoragePoolVolumesMove:
+		return "Moving storage pool volumes"
+	case OperationOperationsHistoryExpire:
+		return "Cleaning up expired operations history"
 	default:
 		return "Executing operation"
 	}
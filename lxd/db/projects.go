@@ -128,6 +128,32 @@ func (c *ClusterTx) ProjectHasImages(name string) (bool, error) {
 	return enabled, nil
 }
 
+// ProjectHasNetworks is a helper to check if a project has the networks
+// feature enabled.
+func (c *ClusterTx) ProjectHasNetworks(name string) (bool, error) {
+	project, err := c.ProjectGet(name)
+	if err != nil {
+		return false, errors.Wrap(err, "fetch project")
+	}
+
+	enabled := project.Config["features.networks"] == "true"
+
+	return enabled, nil
+}
+
+// ProjectHasStorageVolumes is a helper to check if a project has the storage
+// volumes feature enabled.
+func (c *ClusterTx) ProjectHasStorageVolumes(name string) (bool, error) {
+	project, err := c.ProjectGet(name)
+	if err != nil {
+		return false, errors.Wrap(err, "fetch project")
+	}
+
+	enabled := project.Config["features.storage.volumes"] == "true"
+
+	return enabled, nil
+}
+
 // ProjectUpdate updates the project matching the given key parameters.
 func (c *ClusterTx) ProjectUpdate(name string, object api.ProjectPut) error {
 	stmt := c.stmt(projectUpdate)
@@ -6,10 +6,12 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/db/query"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
@@ -698,8 +700,8 @@ func (c *Cluster) StoragePoolVolumesGet(project string, poolID int64, volumeType
 SELECT DISTINCT node_id
   FROM storage_volumes
   JOIN projects ON projects.id = storage_volumes.project_id
- WHERE (projects.name=? OR storage_volumes.type=?) AND storage_pool_id=?
-`, project, StoragePoolVolumeTypeCustom, poolID)
+ WHERE projects.name=? AND storage_pool_id=?
+`, project, poolID)
 		return err
 	})
 	if err != nil {
@@ -758,9 +760,9 @@ func (c *Cluster) StoragePoolVolumesGetType(project string, volumeType int, pool
 SELECT storage_volumes.name
   FROM storage_volumes
   JOIN projects ON projects.id=storage_volumes.project_id
- WHERE (projects.name=? OR storage_volumes.type=?) AND storage_pool_id=? AND node_id=? AND type=?
+ WHERE projects.name=? AND storage_pool_id=? AND node_id=? AND type=?
 `
-	inargs := []interface{}{project, StoragePoolVolumeTypeCustom, poolID, nodeID, volumeType}
+	inargs := []interface{}{project, poolID, nodeID, volumeType}
 	outargs := []interface{}{poolName}
 
 	result, err := queryScan(c.db, query, inargs, outargs)
@@ -777,9 +779,10 @@ SELECT storage_volumes.name
 }
 
 // StoragePoolVolumeSnapshotsGetType get all snapshots of a storage volume
-// attached to a given storage pool of a given volume type, on the given node.
+// attached to a given storage pool of a given volume type, on the given node,
+// in the given project.
 // Returns snapshots slice ordered by when they were created, oldest first.
-func (c *Cluster) StoragePoolVolumeSnapshotsGetType(volumeName string, volumeType int, poolID int64) ([]StorageVolumeArgs, error) {
+func (c *Cluster) StoragePoolVolumeSnapshotsGetType(project, volumeName string, volumeType int, poolID int64) ([]StorageVolumeArgs, error) {
 	result := []StorageVolumeArgs{}
 	regexp := volumeName + shared.SnapshotDelimiter
 	length := len(regexp)
@@ -788,8 +791,14 @@ func (c *Cluster) StoragePoolVolumeSnapshotsGetType(volumeName string, volumeTyp
 	// will be returned in the order that the snapshots were created. This is specifically used
 	// during migration to ensure that the storage engines can re-create snapshots using the
 	// correct deltas.
-	query := "SELECT name, description FROM storage_volumes WHERE storage_pool_id=? AND node_id=? AND type=? AND snapshot=? AND SUBSTR(name,1,?)=? ORDER BY id"
-	inargs := []interface{}{poolID, c.nodeID, volumeType, true, length, regexp}
+	query := `
+SELECT storage_volumes.name, storage_volumes.description
+  FROM storage_volumes
+  JOIN projects ON projects.id=storage_volumes.project_id
+ WHERE projects.name=? AND storage_pool_id=? AND node_id=? AND type=? AND snapshot=? AND SUBSTR(storage_volumes.name,1,?)=?
+ ORDER BY storage_volumes.id
+`
+	inargs := []interface{}{project, poolID, c.nodeID, volumeType, true, length, regexp}
 	typeGuide := StorageVolumeArgs{} // StorageVolume struct used to guide the types expected.
 	outfmt := []interface{}{typeGuide.Name, typeGuide.Description}
 	dbResults, err := queryScan(c.db, query, inargs, outfmt)
@@ -811,18 +820,19 @@ func (c *Cluster) StoragePoolVolumeSnapshotsGetType(volumeName string, volumeTyp
 // StoragePoolNodeVolumesGetType returns all storage volumes attached to a
 // given storage pool of a given volume type, on the current node.
 func (c *Cluster) StoragePoolNodeVolumesGetType(volumeType int, poolID int64) ([]string, error) {
-	return c.StoragePoolVolumesGetType("default", volumeType, poolID, c.nodeID)
+	return c.StoragePoolNodeVolumesGetTypeByProject("default", volumeType, poolID)
+}
+
+// StoragePoolNodeVolumesGetTypeByProject returns all storage volumes attached
+// to a given storage pool of a given volume type, on the current node in the
+// given project.
+func (c *Cluster) StoragePoolNodeVolumesGetTypeByProject(project string, volumeType int, poolID int64) ([]string, error) {
+	return c.StoragePoolVolumesGetType(project, volumeType, poolID, c.nodeID)
 }
 
 // StoragePoolVolumeGetType returns a single storage volume attached to a
 // given storage pool of a given type, on the node with the given ID.
 func (c *Cluster) StoragePoolVolumeGetType(project string, volumeName string, volumeType int, poolID, nodeID int64) (int64, *api.StorageVolume, error) {
-	// Custom volumes are "global", i.e. they are associated with the
-	// default project.
-	if volumeType == StoragePoolVolumeTypeCustom {
-		project = "default"
-	}
-
 	volumeID, err := c.StoragePoolVolumeGetTypeID(project, volumeName, volumeType, poolID, nodeID)
 	if err != nil {
 		return -1, nil, err
@@ -843,11 +853,21 @@ func (c *Cluster) StoragePoolVolumeGetType(project string, volumeName string, vo
 		return -1, nil, err
 	}
 
+	volumeContentType, err := c.StorageVolumeContentTypeGet(volumeID)
+	if err != nil {
+		return -1, nil, err
+	}
+
 	volumeTypeName, err := StoragePoolVolumeTypeToName(volumeType)
 	if err != nil {
 		return -1, nil, err
 	}
 
+	volumeContentTypeName, err := StoragePoolVolumeContentTypeToName(volumeContentType)
+	if err != nil {
+		return -1, nil, err
+	}
+
 	storageVolume := api.StorageVolume{
 		Type: volumeTypeName,
 	}
@@ -855,6 +875,7 @@ func (c *Cluster) StoragePoolVolumeGetType(project string, volumeName string, vo
 	storageVolume.Description = volumeDescription
 	storageVolume.Config = volumeConfig
 	storageVolume.Location = volumeNode
+	storageVolume.ContentType = volumeContentTypeName
 
 	return volumeID, &storageVolume, nil
 }
@@ -873,14 +894,14 @@ func (c *Cluster) StoragePoolNodeVolumeGetTypeByProject(project, volumeName stri
 
 // StoragePoolVolumeUpdate updates the storage volume attached to a given storage
 // pool.
-func (c *Cluster) StoragePoolVolumeUpdate(volumeName string, volumeType int, poolID int64, volumeDescription string, volumeConfig map[string]string) error {
-	volumeID, _, err := c.StoragePoolNodeVolumeGetType(volumeName, volumeType, poolID)
+func (c *Cluster) StoragePoolVolumeUpdate(project, volumeName string, volumeType int, poolID int64, volumeDescription string, volumeConfig map[string]string) error {
+	volumeID, _, err := c.StoragePoolNodeVolumeGetTypeByProject(project, volumeName, volumeType, poolID)
 	if err != nil {
 		return err
 	}
 
 	err = c.Transaction(func(tx *ClusterTx) error {
-		err = storagePoolVolumeReplicateIfCeph(tx.tx, volumeID, "default", volumeName, volumeType, poolID, func(volumeID int64) error {
+		err = storagePoolVolumeReplicateIfCeph(tx.tx, volumeID, project, volumeName, volumeType, poolID, func(volumeID int64) error {
 			err = StorageVolumeConfigClear(tx.tx, volumeID)
 			if err != nil {
 				return err
@@ -902,6 +923,23 @@ func (c *Cluster) StoragePoolVolumeUpdate(volumeName string, volumeType int, poo
 	return err
 }
 
+// StoragePoolVolumeSnapshotExpiryUpdate updates the expiry date of the storage
+// volume snapshot attached to a given storage pool.
+func (c *Cluster) StoragePoolVolumeSnapshotExpiryUpdate(project, volumeName string, volumeType int, poolID int64, expiryDate time.Time) error {
+	volumeID, _, err := c.StoragePoolNodeVolumeGetTypeByProject(project, volumeName, volumeType, poolID)
+	if err != nil {
+		return err
+	}
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		return storagePoolVolumeReplicateIfCeph(tx.tx, volumeID, project, volumeName, volumeType, poolID, func(volumeID int64) error {
+			return StorageVolumeExpiryDateUpdate(tx.tx, volumeID, expiryDate)
+		})
+	})
+
+	return err
+}
+
 // StoragePoolVolumeDelete deletes the storage volume attached to a given storage
 // pool.
 func (c *Cluster) StoragePoolVolumeDelete(project, volumeName string, volumeType int, poolID int64) error {
@@ -939,6 +977,156 @@ func (c *Cluster) StoragePoolVolumeRename(project, oldVolumeName string, newVolu
 	return err
 }
 
+// StoragePoolVolumeMoveInstance replaces the local devices of an instance and
+// of its snapshots, given by snapshot ID, in a single transaction. It is used
+// to switch an instance over to the storage pool its volumes have been moved
+// to, before the source volumes get deleted.
+func (c *Cluster) StoragePoolVolumeMoveInstance(instanceID int, devices deviceConfig.Devices, snapshotDevices map[int]deviceConfig.Devices) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(`DELETE FROM instances_devices_config WHERE id IN
+		(SELECT instances_devices_config.id
+		 FROM instances_devices_config JOIN instances_devices
+		 ON instances_devices_config.instance_device_id=instances_devices.id
+		 WHERE instances_devices.instance_id=?)`, instanceID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM instances_devices WHERE instance_id=?", instanceID)
+		if err != nil {
+			return err
+		}
+
+		err = DevicesAdd(tx.tx, "instance", int64(instanceID), devices)
+		if err != nil {
+			return err
+		}
+
+		for snapshotID, devices := range snapshotDevices {
+			err = instanceSnapshotDevicesReplace(tx.tx, snapshotID, devices)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Replace the devices of the instance snapshot with the given ID.
+func instanceSnapshotDevicesReplace(tx *sql.Tx, snapshotID int, devices deviceConfig.Devices) error {
+	_, err := tx.Exec(`DELETE FROM instances_snapshots_devices_config WHERE instance_snapshot_device_id IN
+	(SELECT id FROM instances_snapshots_devices WHERE instance_snapshot_id=?)`, snapshotID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM instances_snapshots_devices WHERE instance_snapshot_id=?", snapshotID)
+	if err != nil {
+		return err
+	}
+
+	for name, device := range devices {
+		deviceType, err := dbDeviceTypeToInt(device["type"])
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec("INSERT INTO instances_snapshots_devices (instance_snapshot_id, name, type) VALUES (?, ?, ?)", snapshotID, name, deviceType)
+		if err != nil {
+			return err
+		}
+
+		deviceID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		for key, value := range device {
+			// The type is stored as int in the parent entry
+			if key == "type" || value == "" {
+				continue
+			}
+
+			_, err = tx.Exec("INSERT INTO instances_snapshots_devices_config (instance_snapshot_device_id, key, value) VALUES (?, ?, ?)", deviceID, key, value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// StoragePoolVolumeMoveCustom points the disk devices of the instances, the
+// instance snapshots and the profiles that use the given custom volume at the
+// new storage pool, all in a single transaction. Volumes of the default
+// project are also used by the projects without their own storage volumes.
+func (c *Cluster) StoragePoolVolumeMoveCustom(project, volumeName string, poolName string, newPoolName string) error {
+	// Matches the projects whose devices refer to the volume.
+	projectFilter := `(projects.name=? OR (?='default' AND projects.id NOT IN (
+    SELECT project_id FROM projects_config WHERE key='features.storage.volumes' AND value='true')))`
+
+	// Matches the device config rows that refer to the volume.
+	sourceFilter := `key='source' AND value IN (?, ?)`
+
+	stmts := []string{`
+UPDATE instances_devices_config SET value=? WHERE key='pool' AND value=? AND instance_device_id IN (
+  SELECT instances_devices.id FROM instances_devices
+    JOIN instances ON instances.id=instances_devices.instance_id
+    JOIN projects ON projects.id=instances.project_id
+   WHERE ` + projectFilter + ` AND instances_devices.id IN (
+     SELECT instance_device_id FROM instances_devices_config WHERE ` + sourceFilter + `))
+`, `
+UPDATE instances_snapshots_devices_config SET value=? WHERE key='pool' AND value=? AND instance_snapshot_device_id IN (
+  SELECT instances_snapshots_devices.id FROM instances_snapshots_devices
+    JOIN instances_snapshots ON instances_snapshots.id=instances_snapshots_devices.instance_snapshot_id
+    JOIN instances ON instances.id=instances_snapshots.instance_id
+    JOIN projects ON projects.id=instances.project_id
+   WHERE ` + projectFilter + ` AND instances_snapshots_devices.id IN (
+     SELECT instance_snapshot_device_id FROM instances_snapshots_devices_config WHERE ` + sourceFilter + `))
+`, `
+UPDATE profiles_devices_config SET value=? WHERE key='pool' AND value=? AND profile_device_id IN (
+  SELECT profiles_devices.id FROM profiles_devices
+    JOIN profiles ON profiles.id=profiles_devices.profile_id
+    JOIN projects ON projects.id=profiles.project_id
+   WHERE ` + projectFilter + ` AND profiles_devices.id IN (
+     SELECT profile_device_id FROM profiles_devices_config WHERE ` + sourceFilter + `))
+`}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		for _, stmt := range stmts {
+			_, err := tx.tx.Exec(stmt, newPoolName, poolName, project, project, volumeName, "custom/"+volumeName)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// StoragePoolVolumeDeleteWithSnapshots deletes the records of a storage volume
+// and of its snapshots from the given storage pool on this node.
+func (c *Cluster) StoragePoolVolumeDeleteWithSnapshots(project, volumeName string, volumeType int, poolID int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		return storagePoolVolumeDeleteWithSnapshots(tx.tx, project, volumeName, volumeType, poolID, c.nodeID)
+	})
+}
+
+// Delete the records of a storage volume and of its snapshots on the given
+// node.
+func storagePoolVolumeDeleteWithSnapshots(tx *sql.Tx, project, volumeName string, volumeType int, poolID int64, nodeID int64) error {
+	prefix := volumeName + shared.SnapshotDelimiter
+	_, err := tx.Exec(`
+DELETE FROM storage_volumes
+ WHERE storage_pool_id=? AND node_id=? AND type=?
+   AND project_id=(SELECT id FROM projects WHERE name=?)
+   AND (name=? OR substr(name, 1, ?)=?)
+`, poolID, nodeID, volumeType, project, volumeName, len(prefix), prefix)
+	return err
+}
+
 // This a convenience to replicate a certain volume change to all nodes if the
 // underlying driver is ceph.
 func storagePoolVolumeReplicateIfCeph(tx *sql.Tx, volumeID int64, project, volumeName string, volumeType int, poolID int64, f func(int64) error) error {
@@ -969,7 +1157,7 @@ func storagePoolVolumeReplicateIfCeph(tx *sql.Tx, volumeID int64, project, volum
 
 // StoragePoolVolumeCreate creates a new storage volume attached to a given
 // storage pool.
-func (c *Cluster) StoragePoolVolumeCreate(project, volumeName, volumeDescription string, volumeType int, snapshot bool, poolID int64, volumeConfig map[string]string) (int64, error) {
+func (c *Cluster) StoragePoolVolumeCreate(project, volumeName, volumeDescription string, volumeType int, snapshot bool, poolID int64, volumeConfig map[string]string, contentType int) (int64, error) {
 	var thisVolumeID int64
 
 	err := c.Transaction(func(tx *ClusterTx) error {
@@ -988,9 +1176,9 @@ func (c *Cluster) StoragePoolVolumeCreate(project, volumeName, volumeDescription
 
 		for _, nodeID := range nodeIDs {
 			result, err := tx.tx.Exec(`
-INSERT INTO storage_volumes (storage_pool_id, node_id, type, snapshot, name, description, project_id) VALUES (?, ?, ?, ?, ?, ?, (SELECT id FROM projects WHERE name = ?))
+INSERT INTO storage_volumes (storage_pool_id, node_id, type, snapshot, name, description, project_id, content_type) VALUES (?, ?, ?, ?, ?, ?, (SELECT id FROM projects WHERE name = ?), ?)
 `,
-				poolID, nodeID, volumeType, snapshot, volumeName, volumeDescription, project)
+				poolID, nodeID, volumeType, snapshot, volumeName, volumeDescription, project, contentType)
 			if err != nil {
 				return err
 			}
@@ -1068,6 +1256,18 @@ const (
 	StoragePoolVolumeTypeNameCustom    string = "custom"
 )
 
+// Content types of storage volumes.
+const (
+	StoragePoolVolumeContentTypeFS = iota
+	StoragePoolVolumeContentTypeBlock
+)
+
+// Content type names of storage volumes.
+const (
+	StoragePoolVolumeContentTypeNameFS    string = "filesystem"
+	StoragePoolVolumeContentTypeNameBlock string = "block"
+)
+
 // StoragePoolNodeConfigKeys lists all storage pool config keys which are
 // node-specific.
 var StoragePoolNodeConfigKeys = []string{
@@ -1096,6 +1296,19 @@ func StoragePoolVolumeTypeToName(volumeType int) (string, error) {
 	return "", fmt.Errorf("invalid storage volume type")
 }
 
+// StoragePoolVolumeContentTypeToName converts a volume integer content type
+// code to its human-readable name.
+func StoragePoolVolumeContentTypeToName(contentType int) (string, error) {
+	switch contentType {
+	case StoragePoolVolumeContentTypeFS:
+		return StoragePoolVolumeContentTypeNameFS, nil
+	case StoragePoolVolumeContentTypeBlock:
+		return StoragePoolVolumeContentTypeNameBlock, nil
+	}
+
+	return "", fmt.Errorf("Invalid storage volume content type")
+}
+
 // StoragePoolInsertZfsDriver replaces the driver of all storage pools without
 // a driver, setting it to 'zfs'.
 func (c *Cluster) StoragePoolInsertZfsDriver() error {
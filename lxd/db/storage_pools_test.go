@@ -6,6 +6,9 @@ import (
 	"testing"
 
 	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -167,7 +170,7 @@ func TestStoragePoolVolume_Ceph(t *testing.T) {
 	require.NoError(t, err)
 
 	config := map[string]string{"k": "v"}
-	volumeID, err := cluster.StoragePoolVolumeCreate("default", "v1", "", 1, false, poolID, config)
+	volumeID, err := cluster.StoragePoolVolumeCreate("default", "v1", "", 1, false, poolID, config, db.StoragePoolVolumeContentTypeFS)
 	require.NoError(t, err)
 
 	// The returned volume ID is the one of the volume created on the local
@@ -184,7 +187,7 @@ func TestStoragePoolVolume_Ceph(t *testing.T) {
 
 	// Update the volume
 	config["k"] = "v2"
-	err = cluster.StoragePoolVolumeUpdate("v1", 1, poolID, "volume 1", config)
+	err = cluster.StoragePoolVolumeUpdate("default", "v1", 1, poolID, "volume 1", config)
 	require.NoError(t, err)
 	for _, nodeID := range []int64{1, 2} {
 		_, volume, err := cluster.StoragePoolVolumeGetType("default", "v1", 1, poolID, nodeID)
@@ -210,3 +213,175 @@ func TestStoragePoolVolume_Ceph(t *testing.T) {
 		assert.Nil(t, volume)
 	}
 }
+
+// Moving a custom volume points the devices using it at the new pool,
+// including the ones of projects sharing the volumes of the default project
+// and of instance snapshots.
+func TestStoragePoolVolumeMoveCustom(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	poolID, err := cluster.StoragePoolCreate("pool1", "", "dir", nil)
+	require.NoError(t, err)
+
+	_, err = cluster.StoragePoolCreate("pool2", "", "dir", nil)
+	require.NoError(t, err)
+
+	for _, name := range []string{"vol1", "vol1/snap0", "vol10"} {
+		_, err := cluster.StoragePoolVolumeCreate("default", name, "", db.StoragePoolVolumeTypeCustom, shared.IsSnapshot(name), poolID, nil, db.StoragePoolVolumeContentTypeFS)
+		require.NoError(t, err)
+	}
+
+	devices := map[string]map[string]string{
+		"data":   {"type": "disk", "path": "/data", "pool": "pool1", "source": "vol1"},
+		"custom": {"type": "disk", "path": "/custom", "pool": "pool1", "source": "custom/vol1"},
+		"other":  {"type": "disk", "path": "/other", "pool": "pool1", "source": "vol10"},
+	}
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		// A project sharing the storage volumes of the default project and
+		// one with its own storage volumes.
+		_, err := tx.ProjectCreate(api.ProjectsPost{Name: "shared"})
+		if err != nil {
+			return err
+		}
+
+		project := api.ProjectsPost{Name: "own"}
+		project.Config = map[string]string{"features.storage.volumes": "true"}
+		_, err = tx.ProjectCreate(project)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ProfileCreate(db.Profile{Project: "default", Name: "p1", Devices: devices})
+		if err != nil {
+			return err
+		}
+
+		for _, project := range []string{"shared", "own"} {
+			_, err = tx.InstanceCreate(db.Instance{
+				Project:      project,
+				Name:         "c1",
+				Node:         "none",
+				Architecture: 1,
+				Devices:      devices,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.InstanceSnapshotCreate(db.InstanceSnapshot{
+			Project:  "shared",
+			Instance: "c1",
+			Name:     "snap0",
+			Devices:  devices,
+		})
+		return err
+	})
+	require.NoError(t, err)
+
+	err = cluster.StoragePoolVolumeMoveCustom("default", "vol1", "pool1", "pool2")
+	require.NoError(t, err)
+
+	moved := func(t *testing.T, devices map[string]map[string]string) {
+		assert.Equal(t, "pool2", devices["data"]["pool"])
+		assert.Equal(t, "pool2", devices["custom"]["pool"])
+		assert.Equal(t, "pool1", devices["other"]["pool"])
+	}
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		profile, err := tx.ProfileGet("default", "p1")
+		require.NoError(t, err)
+		moved(t, profile.Devices)
+
+		instance, err := tx.InstanceGet("shared", "c1")
+		require.NoError(t, err)
+		moved(t, instance.Devices)
+
+		snapshot, err := tx.InstanceSnapshotGet("shared", "c1", "snap0")
+		require.NoError(t, err)
+		moved(t, snapshot.Devices)
+
+		// The devices of a project with its own storage volumes are left
+		// alone.
+		instance, err = tx.InstanceGet("own", "c1")
+		require.NoError(t, err)
+		assert.Equal(t, "pool1", instance.Devices["data"]["pool"])
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The records are removed separately, once the source volume is gone.
+	names, err := cluster.StoragePoolVolumesGetNames(poolID)
+	require.NoError(t, err)
+	assert.Len(t, names, 2)
+
+	err = cluster.StoragePoolVolumeDeleteWithSnapshots("default", "vol1", db.StoragePoolVolumeTypeCustom, poolID)
+	require.NoError(t, err)
+
+	names, err = cluster.StoragePoolVolumesGetNames(poolID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vol10"}, names)
+}
+
+// The devices of an instance and of its snapshots are replaced together.
+func TestStoragePoolVolumeMoveInstance(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	devices := map[string]map[string]string{
+		"root": {"type": "disk", "path": "/", "pool": "pool1"},
+		"eth0": {"type": "nic", "nictype": "bridged", "parent": "lxdbr0"},
+	}
+
+	var instanceID int
+	var snapshotID int64
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		id, err := tx.InstanceCreate(db.Instance{
+			Project:      "default",
+			Name:         "c1",
+			Node:         "none",
+			Architecture: 1,
+			Devices:      devices,
+		})
+		if err != nil {
+			return err
+		}
+
+		instanceID = int(id)
+
+		snapshotID, err = tx.InstanceSnapshotCreate(db.InstanceSnapshot{
+			Project:  "default",
+			Instance: "c1",
+			Name:     "snap0",
+			Devices:  devices,
+		})
+		return err
+	})
+	require.NoError(t, err)
+
+	newDevices := deviceConfig.Devices{
+		"root": {"type": "disk", "path": "/", "pool": "pool2"},
+		"eth0": {"type": "nic", "nictype": "bridged", "parent": "lxdbr0"},
+	}
+
+	err = cluster.StoragePoolVolumeMoveInstance(instanceID, newDevices, map[int]deviceConfig.Devices{int(snapshotID): newDevices})
+	require.NoError(t, err)
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		instance, err := tx.InstanceGet("default", "c1")
+		require.NoError(t, err)
+		assert.Equal(t, "pool2", instance.Devices["root"]["pool"])
+		assert.Equal(t, "lxdbr0", instance.Devices["eth0"]["parent"])
+
+		snapshot, err := tx.InstanceSnapshotGet("default", "c1", "snap0")
+		require.NoError(t, err)
+		assert.Equal(t, "pool2", snapshot.Devices["root"]["pool"])
+		assert.Equal(t, "lxdbr0", snapshot.Devices["eth0"]["parent"])
+
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/lxc/lxd/lxd/db/query"
@@ -31,6 +32,12 @@ type StorageVolumeArgs struct {
 	Config       map[string]string
 	Description  string
 	CreationDate time.Time
+
+	// Only set for snapshots which expire.
+	ExpiryDate time.Time
+
+	// Filled when loading volumes across projects.
+	ProjectName string
 }
 
 // StorageVolumeNodeAddresses returns the addresses of all nodes on which the
@@ -150,6 +157,25 @@ func (c *Cluster) StorageVolumeDescriptionGet(volumeID int64) (string, error) {
 	return description.String, nil
 }
 
+// StorageVolumeContentTypeGet gets the content type of a storage volume.
+func (c *Cluster) StorageVolumeContentTypeGet(volumeID int64) (int, error) {
+	var contentType int
+	query := "SELECT content_type FROM storage_volumes WHERE id=?"
+	inargs := []interface{}{volumeID}
+	outargs := []interface{}{&contentType}
+
+	err := dbQueryRowScan(c.db, query, inargs, outargs)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, ErrNoSuchObject
+		}
+
+		return -1, err
+	}
+
+	return contentType, nil
+}
+
 // StorageVolumeNextSnapshot returns the index the next snapshot of the storage
 // volume with the given name should have.
 //
@@ -187,6 +213,45 @@ func (c *Cluster) StorageVolumeNextSnapshot(name string, typ int) int {
 	return max
 }
 
+// StorageVolumeNextSnapshotByPattern returns the index of the next snapshot
+// of the storage volume with the given name whose name matches the given
+// pattern, which must contain '%d' exactly once.
+func (c *Cluster) StorageVolumeNextSnapshotByPattern(project string, poolID int64, name string, typ int, pattern string) int {
+	base := name + shared.SnapshotDelimiter
+	length := len(base)
+	q := `
+SELECT storage_volumes.name
+  FROM storage_volumes
+  JOIN projects ON projects.id = storage_volumes.project_id
+ WHERE projects.name=? AND storage_volumes.storage_pool_id=? AND storage_volumes.node_id=?
+   AND storage_volumes.type=? AND storage_volumes.snapshot=? AND SUBSTR(storage_volumes.name,1,?)=?
+`
+	var numstr string
+	inargs := []interface{}{project, poolID, c.nodeID, typ, true, length, base}
+	outfmt := []interface{}{numstr}
+	results, err := queryScan(c.db, q, inargs, outfmt)
+	if err != nil {
+		return 0
+	}
+	max := 0
+
+	fields := strings.SplitN(pattern, "%d", 2)
+	for _, r := range results {
+		snapOnlyName := r[0].(string)[length:]
+
+		var num int
+		count, err := fmt.Sscanf(snapOnlyName, fmt.Sprintf("%s%%d%s", fields[0], fields[1]), &num)
+		if err != nil || count != 1 {
+			continue
+		}
+		if num >= max {
+			max = num + 1
+		}
+	}
+
+	return max
+}
+
 // StorageVolumeIsAvailable checks that if a custom volume available for being attached.
 //
 // Always return true for non-Ceph volumes.
@@ -257,6 +322,18 @@ func StorageVolumeDescriptionUpdate(tx *sql.Tx, volumeID int64, description stri
 	return err
 }
 
+// StorageVolumeExpiryDateUpdate updates the expiry date of the storage
+// volume with the given ID. A zero time clears the expiry date.
+func StorageVolumeExpiryDateUpdate(tx *sql.Tx, volumeID int64, expiryDate time.Time) error {
+	var value interface{}
+	if !expiryDate.IsZero() {
+		value = expiryDate
+	}
+
+	_, err := tx.Exec("UPDATE storage_volumes SET expiry_date=? WHERE id=?", value, volumeID)
+	return err
+}
+
 // StorageVolumeConfigAdd adds a new storage volume config into database.
 func StorageVolumeConfigAdd(tx *sql.Tx, volumeID int64, volumeConfig map[string]string) error {
 	str := "INSERT INTO storage_volumes_config (storage_volume_id, key, value) VALUES(?, ?, ?)"
@@ -337,3 +414,302 @@ func (c *Cluster) StorageVolumeMoveToLVMThinPoolNameKey() error {
 
 	return nil
 }
+
+// StorageVolumeExpiryDateGet returns the expiry date of the storage volume
+// with the given ID, or the zero time if it doesn't expire.
+func (c *Cluster) StorageVolumeExpiryDateGet(volumeID int64) (time.Time, error) {
+	var expiryDate *time.Time
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		return tx.tx.QueryRow("SELECT expiry_date FROM storage_volumes WHERE id=?", volumeID).Scan(&expiryDate)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, ErrNoSuchObject
+		}
+
+		return time.Time{}, err
+	}
+
+	if expiryDate == nil {
+		return time.Time{}, nil
+	}
+
+	return *expiryDate, nil
+}
+
+// Filter used to only pick a single row of volumes on remote storage pools
+// (ceph and cephfs), which have a row for every node. The node with the
+// lowest ID is in charge of those.
+const storageVolumesResponsibleNodeFilter = `
+   AND (storage_pools.driver NOT IN ('ceph', 'cephfs') OR storage_volumes.node_id = (
+       SELECT MIN(other.node_id) FROM storage_volumes AS other
+        WHERE other.storage_pool_id=storage_volumes.storage_pool_id
+          AND other.project_id=storage_volumes.project_id
+          AND other.name=storage_volumes.name
+          AND other.type=storage_volumes.type))
+`
+
+// StoragePoolNodeCustomVolumesGet returns all custom storage volumes, across
+// all projects and pools, this node is in charge of. Snapshots are not
+// included.
+func (c *Cluster) StoragePoolNodeCustomVolumesGet() ([]StorageVolumeArgs, error) {
+	volumes := []StorageVolumeArgs{}
+	ids := []int64{}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		rows, err := tx.tx.Query(`
+SELECT storage_volumes.id, storage_volumes.name, storage_volumes.storage_pool_id,
+       storage_pools.name, projects.name
+  FROM storage_volumes
+  JOIN storage_pools ON storage_pools.id=storage_volumes.storage_pool_id
+  JOIN projects ON projects.id=storage_volumes.project_id
+ WHERE storage_volumes.node_id=? AND storage_volumes.type=? AND storage_volumes.snapshot=0
+`+storageVolumesResponsibleNodeFilter, c.nodeID, StoragePoolVolumeTypeCustom)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int64
+			volume := StorageVolumeArgs{
+				Type:     StoragePoolVolumeTypeCustom,
+				TypeName: StoragePoolVolumeTypeNameCustom,
+			}
+
+			err := rows.Scan(&id, &volume.Name, &volume.PoolID, &volume.PoolName, &volume.ProjectName)
+			if err != nil {
+				return err
+			}
+
+			ids = append(ids, id)
+			volumes = append(volumes, volume)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		volumes[i].Config, err = c.StorageVolumeConfigGet(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return volumes, nil
+}
+
+// StoragePoolNodeVolumeSnapshotsGetExpired returns the snapshots of custom
+// storage volumes this node is in charge of which have expired.
+func (c *Cluster) StoragePoolNodeVolumeSnapshotsGetExpired() ([]StorageVolumeArgs, error) {
+	snapshots := []StorageVolumeArgs{}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		rows, err := tx.tx.Query(`
+SELECT storage_volumes.name, storage_volumes.storage_pool_id, storage_pools.name,
+       projects.name, storage_volumes.expiry_date
+  FROM storage_volumes
+  JOIN storage_pools ON storage_pools.id=storage_volumes.storage_pool_id
+  JOIN projects ON projects.id=storage_volumes.project_id
+ WHERE storage_volumes.node_id=? AND storage_volumes.type=? AND storage_volumes.snapshot=1
+   AND storage_volumes.expiry_date IS NOT NULL
+`+storageVolumesResponsibleNodeFilter, c.nodeID, StoragePoolVolumeTypeCustom)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			snapshot := StorageVolumeArgs{
+				Type:     StoragePoolVolumeTypeCustom,
+				TypeName: StoragePoolVolumeTypeNameCustom,
+				Snapshot: true,
+			}
+
+			err := rows.Scan(&snapshot.Name, &snapshot.PoolID, &snapshot.PoolName, &snapshot.ProjectName, &snapshot.ExpiryDate)
+			if err != nil {
+				return err
+			}
+
+			// Since zero time causes some issues due to timezones, we
+			// check the unix timestamp instead of IsZero().
+			if snapshot.ExpiryDate.Unix() <= 0 {
+				continue
+			}
+
+			if time.Now().Unix()-snapshot.ExpiryDate.Unix() >= 0 {
+				snapshots = append(snapshots, snapshot)
+			}
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// StoragePoolVolumeBackup is a value object holding all db-related details
+// about a custom storage volume backup.
+type StoragePoolVolumeBackup struct {
+	ID                   int
+	VolumeID             int64
+	Name                 string
+	CreationDate         time.Time
+	ExpiryDate           time.Time
+	VolumeOnly           bool
+	OptimizedStorage     bool
+	CompressionAlgorithm string
+
+	// Filled when loading from the database.
+	ProjectName string
+	PoolName    string
+	VolumeName  string
+}
+
+const storagePoolVolumeBackupsSelect = `
+SELECT storage_volumes_backups.id, storage_volumes_backups.storage_volume_id,
+       storage_volumes_backups.name, storage_volumes_backups.creation_date,
+       storage_volumes_backups.expiry_date, storage_volumes_backups.volume_only,
+       storage_volumes_backups.optimized_storage,
+       projects.name, storage_pools.name, storage_volumes.name
+  FROM storage_volumes_backups
+  JOIN storage_volumes ON storage_volumes.id=storage_volumes_backups.storage_volume_id
+  JOIN storage_pools ON storage_pools.id=storage_volumes.storage_pool_id
+  JOIN projects ON projects.id=storage_volumes.project_id
+`
+
+// StoragePoolVolumeBackupsGet returns the backups of the storage volume with
+// the given ID.
+func (c *Cluster) StoragePoolVolumeBackupsGet(volumeID int64) ([]StoragePoolVolumeBackup, error) {
+	return c.storagePoolVolumeBackups("WHERE storage_volumes_backups.storage_volume_id=? ORDER BY storage_volumes_backups.id", volumeID)
+}
+
+// StoragePoolVolumeBackupGet returns the backup with the given name of the
+// storage volume with the given ID.
+func (c *Cluster) StoragePoolVolumeBackupGet(volumeID int64, name string) (StoragePoolVolumeBackup, error) {
+	backups, err := c.storagePoolVolumeBackups("WHERE storage_volumes_backups.storage_volume_id=? AND storage_volumes_backups.name=?", volumeID, name)
+	if err != nil {
+		return StoragePoolVolumeBackup{}, err
+	}
+
+	if len(backups) != 1 {
+		return StoragePoolVolumeBackup{}, ErrNoSuchObject
+	}
+
+	return backups[0], nil
+}
+
+// StoragePoolVolumeBackupsGetExpired returns the backups of storage volumes
+// on this node which have expired.
+func (c *Cluster) StoragePoolVolumeBackupsGetExpired() ([]StoragePoolVolumeBackup, error) {
+	backups, err := c.storagePoolVolumeBackups("WHERE storage_volumes.node_id=?", c.nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := []StoragePoolVolumeBackup{}
+	for _, backup := range backups {
+		// Since zero time causes some issues due to timezones, we check the
+		// unix timestamp instead of IsZero().
+		if backup.ExpiryDate.Unix() <= 0 {
+			// Backup doesn't expire
+			continue
+		}
+
+		if time.Now().Unix()-backup.ExpiryDate.Unix() >= 0 {
+			expired = append(expired, backup)
+		}
+	}
+
+	return expired, nil
+}
+
+func (c *Cluster) storagePoolVolumeBackups(where string, args ...interface{}) ([]StoragePoolVolumeBackup, error) {
+	backups := []StoragePoolVolumeBackup{}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		rows, err := tx.tx.Query(storagePoolVolumeBackupsSelect+where, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			backup := StoragePoolVolumeBackup{}
+			err := rows.Scan(
+				&backup.ID, &backup.VolumeID, &backup.Name, &backup.CreationDate,
+				&backup.ExpiryDate, &backup.VolumeOnly, &backup.OptimizedStorage,
+				&backup.ProjectName, &backup.PoolName, &backup.VolumeName)
+			if err != nil {
+				return err
+			}
+
+			backups = append(backups, backup)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
+
+// StoragePoolVolumeBackupCreate creates a new storage volume backup.
+func (c *Cluster) StoragePoolVolumeBackupCreate(args StoragePoolVolumeBackup) error {
+	_, err := c.StoragePoolVolumeBackupGet(args.VolumeID, args.Name)
+	if err == nil {
+		return ErrAlreadyDefined
+	}
+
+	if err != ErrNoSuchObject {
+		return err
+	}
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(`
+INSERT INTO storage_volumes_backups (storage_volume_id, name, creation_date, expiry_date, volume_only, optimized_storage)
+  VALUES (?, ?, ?, ?, ?, ?)`,
+			args.VolumeID, args.Name, args.CreationDate, args.ExpiryDate, args.VolumeOnly, args.OptimizedStorage)
+		return err
+	})
+
+	return err
+}
+
+// StoragePoolVolumeBackupRename renames a storage volume backup.
+func (c *Cluster) StoragePoolVolumeBackupRename(volumeID int64, oldName, newName string) error {
+	err := c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("UPDATE storage_volumes_backups SET name=? WHERE storage_volume_id=? AND name=?", newName, volumeID, oldName)
+		if err != nil {
+			return err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n != 1 {
+			return ErrNoSuchObject
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// StoragePoolVolumeBackupRemove removes a storage volume backup from the
+// database.
+func (c *Cluster) StoragePoolVolumeBackupRemove(volumeID int64, name string) error {
+	return exec(c.db, "DELETE FROM storage_volumes_backups WHERE storage_volume_id=? AND name=?", volumeID, name)
+}
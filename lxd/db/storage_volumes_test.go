@@ -4,6 +4,7 @@ package db_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/stretchr/testify/assert"
@@ -35,6 +36,65 @@ func TestStorageVolumeNodeAddresses(t *testing.T) {
 	assert.Equal(t, []string{"", "1.2.3.4:666"}, addresses)
 }
 
+// Create, list, rename, expire and delete custom volume backups.
+func TestStoragePoolVolumeBackups(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	poolID, err := cluster.StoragePoolCreate("pool1", "", "dir", nil)
+	require.NoError(t, err)
+
+	volumeID, err := cluster.StoragePoolVolumeCreate("default", "vol1", "", db.StoragePoolVolumeTypeCustom, false, poolID, nil, db.StoragePoolVolumeContentTypeFS)
+	require.NoError(t, err)
+
+	err = cluster.StoragePoolVolumeBackupCreate(db.StoragePoolVolumeBackup{
+		VolumeID:     volumeID,
+		Name:         "backup0",
+		CreationDate: time.Now(),
+		ExpiryDate:   time.Now().Add(-time.Minute),
+		VolumeOnly:   true,
+	})
+	require.NoError(t, err)
+
+	err = cluster.StoragePoolVolumeBackupCreate(db.StoragePoolVolumeBackup{
+		VolumeID:     volumeID,
+		Name:         "backup1",
+		CreationDate: time.Now(),
+	})
+	require.NoError(t, err)
+
+	// Backup names are unique per volume.
+	err = cluster.StoragePoolVolumeBackupCreate(db.StoragePoolVolumeBackup{VolumeID: volumeID, Name: "backup1"})
+	assert.Equal(t, db.ErrAlreadyDefined, err)
+
+	backup, err := cluster.StoragePoolVolumeBackupGet(volumeID, "backup0")
+	require.NoError(t, err)
+	assert.True(t, backup.VolumeOnly)
+	assert.Equal(t, "default", backup.ProjectName)
+	assert.Equal(t, "pool1", backup.PoolName)
+	assert.Equal(t, "vol1", backup.VolumeName)
+
+	// Only the first backup has expired.
+	expired, err := cluster.StoragePoolVolumeBackupsGetExpired()
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "backup0", expired[0].Name)
+
+	err = cluster.StoragePoolVolumeBackupRename(volumeID, "backup1", "renamed")
+	require.NoError(t, err)
+
+	err = cluster.StoragePoolVolumeBackupRemove(volumeID, "backup0")
+	require.NoError(t, err)
+
+	backups, err := cluster.StoragePoolVolumeBackupsGet(volumeID)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, "renamed", backups[0].Name)
+
+	_, err = cluster.StoragePoolVolumeBackupGet(volumeID, "backup0")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
 func addPool(t *testing.T, tx *db.ClusterTx, name string) int64 {
 	stmt := `
 INSERT INTO storage_pools(name, driver) VALUES (?, 'dir')
@@ -55,3 +115,54 @@ INSERT INTO storage_volumes(storage_pool_id, node_id, name, type, project_id) VA
 	_, err := tx.Tx().Exec(stmt, poolID, nodeID, name)
 	require.NoError(t, err)
 }
+
+// Set expiry dates on custom volume snapshots and list the expired ones.
+func TestStoragePoolNodeVolumeSnapshotsGetExpired(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	poolID, err := cluster.StoragePoolCreate("pool1", "", "dir", nil)
+	require.NoError(t, err)
+
+	config := map[string]string{"snapshots.schedule": "@hourly"}
+	_, err = cluster.StoragePoolVolumeCreate("default", "vol1", "", db.StoragePoolVolumeTypeCustom, false, poolID, config, db.StoragePoolVolumeContentTypeFS)
+	require.NoError(t, err)
+
+	for _, name := range []string{"vol1/snap0", "vol1/snap1", "vol1/snap2"} {
+		_, err = cluster.StoragePoolVolumeCreate("default", name, "", db.StoragePoolVolumeTypeCustom, true, poolID, nil, db.StoragePoolVolumeContentTypeFS)
+		require.NoError(t, err)
+	}
+
+	err = cluster.StoragePoolVolumeSnapshotExpiryUpdate("default", "vol1/snap0", db.StoragePoolVolumeTypeCustom, poolID, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	err = cluster.StoragePoolVolumeSnapshotExpiryUpdate("default", "vol1/snap1", db.StoragePoolVolumeTypeCustom, poolID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Only the first snapshot has expired.
+	expired, err := cluster.StoragePoolNodeVolumeSnapshotsGetExpired()
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "vol1/snap0", expired[0].Name)
+	assert.Equal(t, "pool1", expired[0].PoolName)
+	assert.Equal(t, "default", expired[0].ProjectName)
+
+	// Snapshots without an expiry date return the zero time.
+	volumeID, _, err := cluster.StoragePoolNodeVolumeGetTypeByProject("default", "vol1/snap2", db.StoragePoolVolumeTypeCustom, poolID)
+	require.NoError(t, err)
+
+	expiryDate, err := cluster.StorageVolumeExpiryDateGet(volumeID)
+	require.NoError(t, err)
+	assert.True(t, expiryDate.IsZero())
+
+	// Only the parent volume is returned for scheduling.
+	volumes, err := cluster.StoragePoolNodeCustomVolumesGet()
+	require.NoError(t, err)
+	require.Len(t, volumes, 1)
+	assert.Equal(t, "vol1", volumes[0].Name)
+	assert.Equal(t, config, volumes[0].Config)
+
+	// The next snapshot index follows the given pattern.
+	assert.Equal(t, 3, cluster.StorageVolumeNextSnapshotByPattern("default", poolID, "vol1", db.StoragePoolVolumeTypeCustom, "snap%d"))
+	assert.Equal(t, 0, cluster.StorageVolumeNextSnapshotByPattern("default", poolID, "vol1", db.StoragePoolVolumeTypeCustom, "daily%d"))
+}
@@ -0,0 +1,323 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+// WarningType is a numeric code identifying the type of a Warning.
+type WarningType int
+
+// Possible values for WarningType
+//
+// WARNING: The type codes are stored in the database, so this list of
+//          definitions should be normally append-only. Any other change
+//          requires a database update.
+const (
+	WarningUndefined WarningType = iota
+	WarningCGroupBlkio
+	WarningCGroupBlkioWeight
+	WarningCGroupCPUController
+	WarningCGroupCPUacctController
+	WarningCGroupCPUsetController
+	WarningCGroupDevicesController
+	WarningCGroupFreezerController
+	WarningCGroupMemoryController
+	WarningCGroupNetPrioController
+	WarningCGroupPidsController
+	WarningCGroupSwapAccounting
+	WarningAppArmorNotAvailable
+	WarningMissingUeventInjection
+	WarningMissingSeccompListener
+	WarningMissingVFS3Fscaps
+	WarningImageUpdateFailed
+	WarningNetworkUnavailable
+)
+
+// WarningTypeNames associates a warning type code to its name.
+var WarningTypeNames = map[WarningType]string{
+	WarningUndefined:               "Undefined warning",
+	WarningCGroupBlkio:             "Couldn't find the CGroup blkio",
+	WarningCGroupBlkioWeight:       "Couldn't find the CGroup blkio.weight",
+	WarningCGroupCPUController:     "Couldn't find the CGroup CPU controller",
+	WarningCGroupCPUacctController: "Couldn't find the CGroup CPUacct controller",
+	WarningCGroupCPUsetController:  "Couldn't find the CGroup CPUset controller",
+	WarningCGroupDevicesController: "Couldn't find the CGroup devices controller",
+	WarningCGroupFreezerController: "Couldn't find the CGroup freezer controller",
+	WarningCGroupMemoryController:  "Couldn't find the CGroup memory controller",
+	WarningCGroupNetPrioController: "Couldn't find the CGroup network class controller",
+	WarningCGroupPidsController:    "Couldn't find the CGroup pids controller",
+	WarningCGroupSwapAccounting:    "CGroup memory swap accounting is disabled",
+	WarningAppArmorNotAvailable:    "AppArmor support is disabled",
+	WarningMissingUeventInjection:  "Missing kernel support for uevent injection",
+	WarningMissingSeccompListener:  "Missing kernel support for the seccomp listener",
+	WarningMissingVFS3Fscaps:       "Missing kernel support for unprivileged file capabilities",
+	WarningImageUpdateFailed:       "Failed to update image",
+	WarningNetworkUnavailable:      "Network unavailable",
+}
+
+// String returns the name of the warning type.
+func (t WarningType) String() string {
+	name, ok := WarningTypeNames[t]
+	if !ok {
+		return WarningTypeNames[WarningUndefined]
+	}
+
+	return name
+}
+
+// WarningStatus is a numeric code identifying the status of a Warning.
+type WarningStatus int
+
+// Warning statuses.
+const (
+	WarningStatusNew          WarningStatus = 1
+	WarningStatusAcknowledged WarningStatus = 2
+	WarningStatusResolved     WarningStatus = 3
+)
+
+// WarningStatusNames associates a warning status code to its name.
+var WarningStatusNames = map[WarningStatus]string{
+	WarningStatusNew:          "new",
+	WarningStatusAcknowledged: "acknowledged",
+	WarningStatusResolved:     "resolved",
+}
+
+// WarningStatusToName converts a warning status code to its name.
+func WarningStatusToName(status WarningStatus) (string, error) {
+	name, ok := WarningStatusNames[status]
+	if !ok {
+		return "", fmt.Errorf("Invalid warning status code %d", status)
+	}
+
+	return name, nil
+}
+
+// WarningNameToStatus converts a warning status name to its code.
+func WarningNameToStatus(name string) (WarningStatus, error) {
+	for code, statusName := range WarningStatusNames {
+		if statusName == name {
+			return code, nil
+		}
+	}
+
+	return -1, fmt.Errorf("Unknown warning status %q", name)
+}
+
+// Warning holds information about a problem detected by a node of the
+// cluster.
+type Warning struct {
+	ID          int64         // Stable database identifier
+	UUID        string        // User-visible identifier
+	Node        string        // Name of the node the warning was raised on
+	Project     string        // Name of the project the warning relates to, if any
+	EntityURL   string        // API URL of the entity the warning relates to, if any
+	Type        WarningType   // Type of the warning
+	Status      WarningStatus // Status of the warning
+	FirstSeenAt time.Time     // When the problem was first detected
+	LastSeenAt  time.Time     // When the problem was last detected
+	UpdatedAt   time.Time     // When the status was last changed
+	LastMessage string        // Details about the last occurrence of the problem
+	Count       int           // Number of times the problem was detected
+}
+
+// Warnings returns all warnings in the cluster.
+func (c *ClusterTx) Warnings() ([]Warning, error) {
+	return c.warnings("")
+}
+
+// WarningsByProject returns all warnings related to the given project.
+func (c *ClusterTx) WarningsByProject(project string) ([]Warning, error) {
+	return c.warnings("projects.name=?", project)
+}
+
+// WarningByUUID returns the warning with the given UUID.
+func (c *ClusterTx) WarningByUUID(uuid string) (Warning, error) {
+	null := Warning{}
+	warnings, err := c.warnings("warnings.uuid=?", uuid)
+	if err != nil {
+		return null, err
+	}
+
+	switch len(warnings) {
+	case 0:
+		return null, ErrNoSuchObject
+	case 1:
+		return warnings[0], nil
+	default:
+		return null, fmt.Errorf("More than one warning matches")
+	}
+}
+
+// WarningUpsert records an occurrence of a problem on this node.
+//
+// Warnings are deduplicated by node, project, entity and type: if a matching
+// warning already exists, its count and last message are updated and it is
+// re-opened if it was resolved. Otherwise a new warning is created.
+func (c *ClusterTx) WarningUpsert(project string, entityURL string, typ WarningType, message string) error {
+	projectID, err := c.warningProjectID(project)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	ids, err := query.SelectIntegers(c.tx, `
+SELECT id FROM warnings
+ WHERE node_id=? AND project_id IS ? AND entity_url=? AND type_code=?`,
+		c.nodeID, projectID, entityURL, typ)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch existing warnings")
+	}
+
+	if len(ids) > 0 {
+		_, err = c.tx.Exec(`
+UPDATE warnings
+   SET last_seen_date=?, last_message=?, count=count+1,
+       status=CASE WHEN status=? THEN ? ELSE status END,
+       updated_date=CASE WHEN status=? THEN ? ELSE updated_date END
+ WHERE id=?`,
+			now, message,
+			WarningStatusResolved, WarningStatusNew,
+			WarningStatusResolved, now,
+			ids[0])
+		if err != nil {
+			return errors.Wrap(err, "Failed to update warning")
+		}
+
+		return nil
+	}
+
+	columns := []string{"node_id", "project_id", "entity_url", "uuid", "type_code", "status", "first_seen_date", "last_seen_date", "updated_date", "last_message", "count"}
+	values := []interface{}{c.nodeID, projectID, entityURL, uuid.NewRandom().String(), typ, WarningStatusNew, now, now, now, message, 1}
+	_, err = query.UpsertObject(c.tx, "warnings", columns, values)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create warning")
+	}
+
+	return nil
+}
+
+// WarningResolve marks as resolved the warnings of this node matching the
+// given project, entity and type.
+func (c *ClusterTx) WarningResolve(project string, entityURL string, typ WarningType) error {
+	projectID, err := c.warningProjectID(project)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec(`
+UPDATE warnings SET status=?, updated_date=?
+ WHERE node_id=? AND project_id IS ? AND entity_url=? AND type_code=? AND status!=?`,
+		WarningStatusResolved, time.Now().UTC(),
+		c.nodeID, projectID, entityURL, typ, WarningStatusResolved)
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve warnings")
+	}
+
+	return nil
+}
+
+// WarningUpdateStatus changes the status of the warning with the given UUID.
+func (c *ClusterTx) WarningUpdateStatus(uuid string, status WarningStatus) error {
+	result, err := c.tx.Exec("UPDATE warnings SET status=?, updated_date=? WHERE uuid=?", status, time.Now().UTC(), uuid)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// WarningRemove removes the warning with the given UUID.
+func (c *ClusterTx) WarningRemove(uuid string) error {
+	result, err := c.tx.Exec("DELETE FROM warnings WHERE uuid=?", uuid)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// Returns the ID of the given project, or nil if no project is given.
+func (c *ClusterTx) warningProjectID(project string) (interface{}, error) {
+	if project == "" {
+		return nil, nil
+	}
+
+	projectID, err := c.ProjectID(project)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fetch project ID")
+	}
+
+	return projectID, nil
+}
+
+// Returns all warnings in the cluster, filtered by the given clause.
+func (c *ClusterTx) warnings(where string, args ...interface{}) ([]Warning, error) {
+	warnings := []Warning{}
+	dest := func(i int) []interface{} {
+		warnings = append(warnings, Warning{})
+		return []interface{}{
+			&warnings[i].ID,
+			&warnings[i].UUID,
+			&warnings[i].Node,
+			&warnings[i].Project,
+			&warnings[i].EntityURL,
+			&warnings[i].Type,
+			&warnings[i].Status,
+			&warnings[i].FirstSeenAt,
+			&warnings[i].LastSeenAt,
+			&warnings[i].UpdatedAt,
+			&warnings[i].LastMessage,
+			&warnings[i].Count,
+		}
+	}
+
+	sql := `
+SELECT warnings.id, warnings.uuid, nodes.name, coalesce(projects.name, ''), entity_url,
+       type_code, status, first_seen_date, last_seen_date, updated_date, last_message, count
+  FROM warnings
+  JOIN nodes ON nodes.id = warnings.node_id
+  LEFT JOIN projects ON projects.id = warnings.project_id `
+	if where != "" {
+		sql += fmt.Sprintf("WHERE %s ", where)
+	}
+	sql += "ORDER BY warnings.id"
+
+	stmt, err := c.tx.Prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch warnings")
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,97 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Record a warning several times, then resolve it.
+func TestWarning(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	err := tx.WarningUpsert("default", "/1.0/images/abcd", db.WarningImageUpdateFailed, "first")
+	require.NoError(t, err)
+
+	err = tx.WarningUpsert("default", "/1.0/images/abcd", db.WarningImageUpdateFailed, "second")
+	require.NoError(t, err)
+
+	warnings, err := tx.Warnings()
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+
+	warning := warnings[0]
+	assert.Equal(t, "default", warning.Project)
+	assert.Equal(t, "/1.0/images/abcd", warning.EntityURL)
+	assert.Equal(t, db.WarningImageUpdateFailed, warning.Type)
+	assert.Equal(t, db.WarningStatusNew, warning.Status)
+	assert.Equal(t, "second", warning.LastMessage)
+	assert.Equal(t, 2, warning.Count)
+
+	err = tx.WarningResolve("default", "/1.0/images/abcd", db.WarningImageUpdateFailed)
+	require.NoError(t, err)
+
+	warning, err = tx.WarningByUUID(warning.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, db.WarningStatusResolved, warning.Status)
+
+	// A resolved warning is re-opened when the problem happens again.
+	err = tx.WarningUpsert("default", "/1.0/images/abcd", db.WarningImageUpdateFailed, "third")
+	require.NoError(t, err)
+
+	warning, err = tx.WarningByUUID(warning.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, db.WarningStatusNew, warning.Status)
+	assert.Equal(t, 3, warning.Count)
+
+	err = tx.WarningRemove(warning.UUID)
+	require.NoError(t, err)
+
+	_, err = tx.WarningByUUID(warning.UUID)
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
+// Warnings not associated with any project are deduplicated by type.
+func TestWarningNoProject(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	err := tx.WarningUpsert("", "", db.WarningCGroupPidsController, "")
+	require.NoError(t, err)
+
+	err = tx.WarningUpsert("", "", db.WarningCGroupPidsController, "")
+	require.NoError(t, err)
+
+	err = tx.WarningUpsert("", "", db.WarningCGroupMemoryController, "")
+	require.NoError(t, err)
+
+	warnings, err := tx.Warnings()
+	require.NoError(t, err)
+	require.Len(t, warnings, 2)
+	assert.Equal(t, "", warnings[0].Project)
+	assert.Equal(t, 2, warnings[0].Count)
+	assert.Equal(t, 1, warnings[1].Count)
+
+	err = tx.WarningUpdateStatus(warnings[0].UUID, db.WarningStatusAcknowledged)
+	require.NoError(t, err)
+
+	warning, err := tx.WarningByUUID(warnings[0].UUID)
+	require.NoError(t, err)
+	assert.Equal(t, db.WarningStatusAcknowledged, warning.Status)
+
+	// Acknowledged warnings stay acknowledged when seen again.
+	err = tx.WarningUpsert("", "", db.WarningCGroupPidsController, "")
+	require.NoError(t, err)
+
+	warning, err = tx.WarningByUUID(warnings[0].UUID)
+	require.NoError(t, err)
+	assert.Equal(t, db.WarningStatusAcknowledged, warning.Status)
+
+	err = tx.WarningUpdateStatus("missing", db.WarningStatusAcknowledged)
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
@@ -0,0 +1,209 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/pkg/errors"
+)
+
+// Webhook holds the endpoint some of the lifecycle events are sent to.
+type Webhook struct {
+	ID          int64    // Stable database identifier
+	Name        string   // User-visible identifier
+	Description string   // Free form description
+	URL         string   // URL the events are POSTed to
+	Secret      string   // Key the payloads are signed with, if any
+	Types       []string // Types of entities the events are sent for, all if empty
+	Actions     []string // Actions the events are sent for, all if empty
+	Projects    []string // Projects the events are sent for, all if empty
+
+	LastDeliveryAt     time.Time // When the last delivery was completed
+	LastDeliveryStatus string    // Outcome of the last delivery
+	LastDeliveryError  string    // Why the last delivery failed, if it did
+	FailedDeliveries   int       // Number of failed deliveries since the last successful one
+}
+
+// Webhooks returns all webhooks.
+func (c *ClusterTx) Webhooks() ([]Webhook, error) {
+	return c.webhooks("")
+}
+
+// WebhookByName returns the webhook with the given name.
+func (c *ClusterTx) WebhookByName(name string) (Webhook, error) {
+	null := Webhook{}
+	webhooks, err := c.webhooks("name=?", name)
+	if err != nil {
+		return null, err
+	}
+
+	switch len(webhooks) {
+	case 0:
+		return null, ErrNoSuchObject
+	case 1:
+		return webhooks[0], nil
+	default:
+		return null, fmt.Errorf("More than one webhook matches")
+	}
+}
+
+// WebhookCreate adds a new webhook, returning its ID.
+func (c *ClusterTx) WebhookCreate(webhook Webhook) (int64, error) {
+	_, err := c.WebhookByName(webhook.Name)
+	if err == nil {
+		return -1, ErrAlreadyDefined
+	}
+	if err != ErrNoSuchObject {
+		return -1, err
+	}
+
+	filters, err := webhookFiltersEncode(webhook)
+	if err != nil {
+		return -1, err
+	}
+
+	columns := []string{"name", "description", "url", "secret", "types", "actions", "projects", "last_delivery_date"}
+	values := []interface{}{webhook.Name, webhook.Description, webhook.URL, webhook.Secret, filters[0], filters[1], filters[2], time.Time{}}
+	id, err := query.UpsertObject(c.tx, "webhooks", columns, values)
+	if err != nil {
+		return -1, errors.Wrap(err, "Failed to create webhook")
+	}
+
+	return id, nil
+}
+
+// WebhookUpdate changes the settings of the webhook with the given name,
+// keeping its delivery status.
+func (c *ClusterTx) WebhookUpdate(name string, webhook Webhook) error {
+	filters, err := webhookFiltersEncode(webhook)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.tx.Exec(`
+UPDATE webhooks SET description=?, url=?, secret=?, types=?, actions=?, projects=?
+ WHERE name=?`,
+		webhook.Description, webhook.URL, webhook.Secret, filters[0], filters[1], filters[2], name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update webhook")
+	}
+
+	return webhookCheckAffected(result.RowsAffected())
+}
+
+// WebhookDeliveryUpdate records the outcome of a delivery of the webhook with
+// the given name, an empty message meaning it succeeded.
+func (c *ClusterTx) WebhookDeliveryUpdate(name string, date time.Time, status string, message string) error {
+	result, err := c.tx.Exec(`
+UPDATE webhooks
+   SET last_delivery_date=?, last_delivery_status=?, last_delivery_error=?,
+       failed_deliveries=CASE WHEN ?='' THEN 0 ELSE failed_deliveries+1 END
+ WHERE name=?`,
+		date.UTC(), status, message, message, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to record webhook delivery")
+	}
+
+	return webhookCheckAffected(result.RowsAffected())
+}
+
+// WebhookDelete removes the webhook with the given name.
+func (c *ClusterTx) WebhookDelete(name string) error {
+	result, err := c.tx.Exec("DELETE FROM webhooks WHERE name=?", name)
+	if err != nil {
+		return err
+	}
+
+	return webhookCheckAffected(result.RowsAffected())
+}
+
+func webhookCheckAffected(n int64, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// Returns the types, actions and projects filters of a webhook, as stored in
+// the database.
+func webhookFiltersEncode(webhook Webhook) ([3]string, error) {
+	filters := [3]string{}
+	for i, filter := range [][]string{webhook.Types, webhook.Actions, webhook.Projects} {
+		if filter == nil {
+			filter = []string{}
+		}
+
+		encoded, err := json.Marshal(filter)
+		if err != nil {
+			return filters, err
+		}
+
+		filters[i] = string(encoded)
+	}
+
+	return filters, nil
+}
+
+// Returns all webhooks, filtered by the given clause.
+func (c *ClusterTx) webhooks(where string, args ...interface{}) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	filters := [][3]string{}
+	dest := func(i int) []interface{} {
+		webhooks = append(webhooks, Webhook{})
+		filters = append(filters, [3]string{})
+		return []interface{}{
+			&webhooks[i].ID,
+			&webhooks[i].Name,
+			&webhooks[i].Description,
+			&webhooks[i].URL,
+			&webhooks[i].Secret,
+			&filters[i][0],
+			&filters[i][1],
+			&filters[i][2],
+			&webhooks[i].LastDeliveryAt,
+			&webhooks[i].LastDeliveryStatus,
+			&webhooks[i].LastDeliveryError,
+			&webhooks[i].FailedDeliveries,
+		}
+	}
+
+	sql := `
+SELECT id, name, description, url, secret, types, actions, projects,
+       last_delivery_date, last_delivery_status, last_delivery_error, failed_deliveries
+  FROM webhooks `
+	if where != "" {
+		sql += fmt.Sprintf("WHERE %s ", where)
+	}
+	sql += "ORDER BY name"
+
+	stmt, err := c.tx.Prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch webhooks")
+	}
+
+	for i := range webhooks {
+		for j, filter := range []*[]string{&webhooks[i].Types, &webhooks[i].Actions, &webhooks[i].Projects} {
+			err := json.Unmarshal([]byte(filters[i][j]), filter)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid filters of webhook %q", webhooks[i].Name)
+			}
+		}
+	}
+
+	return webhooks, nil
+}
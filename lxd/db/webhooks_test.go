@@ -0,0 +1,74 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Create a webhook, update it and record deliveries.
+func TestWebhooks(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.WebhookCreate(db.Webhook{
+		Name:    "chat",
+		URL:     "https://chat.example.com/hook",
+		Secret:  "s3cret",
+		Actions: []string{"container-started"},
+	})
+	require.NoError(t, err)
+
+	_, err = tx.WebhookCreate(db.Webhook{Name: "chat", URL: "https://other.example.com"})
+	assert.Equal(t, db.ErrAlreadyDefined, err)
+
+	webhook, err := tx.WebhookByName("chat")
+	require.NoError(t, err)
+	assert.Equal(t, "https://chat.example.com/hook", webhook.URL)
+	assert.Equal(t, "s3cret", webhook.Secret)
+	assert.Equal(t, []string{}, webhook.Types)
+	assert.Equal(t, []string{"container-started"}, webhook.Actions)
+	assert.Equal(t, []string{}, webhook.Projects)
+	assert.True(t, webhook.LastDeliveryAt.IsZero())
+
+	webhook.Projects = []string{"default"}
+	err = tx.WebhookUpdate("chat", webhook)
+	require.NoError(t, err)
+
+	// Failed deliveries are counted until one succeeds.
+	now := time.Now()
+	err = tx.WebhookDeliveryUpdate("chat", now, "failure", "Connection refused")
+	require.NoError(t, err)
+	err = tx.WebhookDeliveryUpdate("chat", now, "failure", "Connection refused")
+	require.NoError(t, err)
+
+	webhooks, err := tx.Webhooks()
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, []string{"default"}, webhooks[0].Projects)
+	assert.Equal(t, "failure", webhooks[0].LastDeliveryStatus)
+	assert.Equal(t, "Connection refused", webhooks[0].LastDeliveryError)
+	assert.Equal(t, 2, webhooks[0].FailedDeliveries)
+
+	err = tx.WebhookDeliveryUpdate("chat", now, "success", "")
+	require.NoError(t, err)
+
+	webhook, err = tx.WebhookByName("chat")
+	require.NoError(t, err)
+	assert.Equal(t, 0, webhook.FailedDeliveries)
+	assert.Equal(t, "", webhook.LastDeliveryError)
+
+	err = tx.WebhookDelete("chat")
+	require.NoError(t, err)
+
+	_, err = tx.WebhookByName("chat")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	err = tx.WebhookDelete("chat")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
@@ -19,7 +19,7 @@ import (
 var StorageVolumeMount func(s *state.State, poolName string, volumeName string, volumeTypeName string, instance Instance) error
 
 // StorageVolumeUmount unmounts a storage volume.
-var StorageVolumeUmount func(s *state.State, poolName string, volumeName string, volumeType int) error
+var StorageVolumeUmount func(s *state.State, projectName string, poolName string, volumeName string, volumeType int) error
 
 // StorageRootFSApplyQuota applies a new quota.
 var StorageRootFSApplyQuota func(s *state.State, instance Instance, size string) error
@@ -15,7 +15,9 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/project"
 	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -82,8 +84,8 @@ func (d *disk) validateConfig() error {
 		"ceph.user_name":    shared.IsAny,
 	}
 
-	// VMs can have a special cloud-init config drive attached with no path.
-	if d.instance.Type() != instancetype.VM || d.config["source"] != diskSourceCloudInit {
+	// VMs can have a special cloud-init config drive or custom block volumes attached with no path.
+	if d.instance.Type() != instancetype.VM || (d.config["source"] != diskSourceCloudInit && d.config["pool"] == "") {
 		rules["path"] = shared.IsNotEmpty
 	}
 
@@ -127,6 +129,10 @@ func (d *disk) validateConfig() error {
 	// this can still be cleanly removed.
 	pathCount := 0
 	for _, devConfig := range d.instance.LocalDevices() {
+		if d.config["path"] == "" {
+			break
+		}
+
 		if devConfig["type"] == "disk" && devConfig["path"] == d.config["path"] {
 			pathCount++
 			if pathCount > 1 {
@@ -195,6 +201,11 @@ func (d *disk) validateEnvironment() error {
 // CanHotPlug returns whether the device can be managed whilst the instance is running, it also
 // returns a list of fields that can be updated without triggering a device remove & add.
 func (d *disk) CanHotPlug() (bool, []string) {
+	// Custom volumes are attached to VMs as drives when the VM starts.
+	if d.instance.Type() == instancetype.VM && d.config["pool"] != "" && !shared.IsRootDiskDevice(d.config) {
+		return false, []string{}
+	}
+
 	return true, []string{"limits.max", "limits.read", "limits.write", "size"}
 }
 
@@ -294,7 +305,12 @@ func (d *disk) startContainer() (*deviceConfig.RunConfig, error) {
 				return nil, err
 			}
 
-			_, volume, err := d.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(d.instance.Project(), d.config["source"], db.StoragePoolVolumeTypeCustom, poolID)
+			volumeProject, err := project.StorageVolumeProject(d.state.Cluster, d.instance.Project(), db.StoragePoolVolumeTypeCustom)
+			if err != nil {
+				return nil, err
+			}
+
+			_, volume, err := d.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(volumeProject, d.config["source"], db.StoragePoolVolumeTypeCustom, poolID)
 			if err != nil {
 				return nil, err
 			}
@@ -373,9 +389,70 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 		return &runConf, nil
 	}
 
+	// This is a block custom volume from a storage pool attached as an extra drive.
+	if d.config["pool"] != "" {
+		pool, volumeProject, volumeName, err := d.vmPoolVolume()
+		if err != nil {
+			return nil, err
+		}
+
+		// Mount first so that encrypted volumes are unlocked before their disk path is resolved.
+		_, err = pool.MountCustomVolume(volumeProject, volumeName, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed mounting storage volume %q", volumeName)
+		}
+
+		diskPath, err := pool.GetCustomVolumeDisk(volumeProject, volumeName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed getting disk path of storage volume %q", volumeName)
+		}
+
+		opts := []string{}
+		if shared.IsTrue(d.config["readonly"]) {
+			opts = append(opts, "ro")
+		}
+
+		runConf.Mounts = []deviceConfig.MountEntryItem{
+			{
+				DevPath:    diskPath,
+				TargetPath: d.name,
+				Opts:       opts,
+			},
+		}
+
+		return &runConf, nil
+	}
+
 	return nil, fmt.Errorf("Disk type not supported for VMs")
 }
 
+// vmPoolVolume returns the storage pool, project and name of the custom volume used by a VM disk.
+func (d *disk) vmPoolVolume() (storagePools.Pool, string, string, error) {
+	volumeName := d.config["source"]
+	if strings.Contains(volumeName, "/") {
+		fields := strings.SplitN(volumeName, "/", 2)
+		if fields[0] != db.StoragePoolVolumeTypeNameCustom {
+			return nil, "", "", fmt.Errorf("Only custom storage volumes can be attached to VMs")
+		}
+
+		volumeName = fields[1]
+	}
+
+	volumeProject, err := project.StorageVolumeProject(d.state.Cluster, d.instance.Project(), db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	pool, err := storagePools.GetPoolByName(d.state, d.config["pool"])
+	if err == storageDrivers.ErrUnknownDriver {
+		return nil, "", "", fmt.Errorf("Storage pool driver doesn't support block custom volumes")
+	} else if err != nil {
+		return nil, "", "", err
+	}
+
+	return pool, volumeProject, volumeName, nil
+}
+
 // postStart is run after the instance is started.
 func (d *disk) postStart() error {
 	devPath := d.getDevicePath(d.name, d.config)
@@ -392,7 +469,7 @@ func (d *disk) postStart() error {
 // Update applies configuration changes to a started device.
 func (d *disk) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 	if d.instance.Type() == instancetype.VM {
-		if shared.IsRootDiskDevice(d.config) {
+		if shared.IsRootDiskDevice(d.config) || d.config["pool"] != "" {
 			return nil
 		}
 
@@ -666,7 +743,26 @@ func (d *disk) createDevice() (string, error) {
 			volumeTypeName = db.StoragePoolVolumeTypeNameCustom
 			fallthrough
 		case db.StoragePoolVolumeTypeNameCustom:
-			srcPath = shared.VarPath("storage-pools", d.config["pool"], volumeTypeName, volumeName)
+			volumeProject, err := project.StorageVolumeProject(d.state.Cluster, d.instance.Project(), db.StoragePoolVolumeTypeCustom)
+			if err != nil {
+				return "", err
+			}
+
+			poolID, err := d.state.Cluster.StoragePoolGetID(d.config["pool"])
+			if err != nil {
+				return "", err
+			}
+
+			_, volume, err := d.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(volumeProject, volumeName, db.StoragePoolVolumeTypeCustom, poolID)
+			if err != nil {
+				return "", err
+			}
+
+			if volume.ContentType == db.StoragePoolVolumeContentTypeNameBlock {
+				return "", fmt.Errorf("Block custom volumes cannot be attached to containers")
+			}
+
+			srcPath = shared.VarPath("storage-pools", d.config["pool"], volumeTypeName, project.Prefix(volumeProject, volumeName))
 		case db.StoragePoolVolumeTypeNameImage:
 			return "", fmt.Errorf("Using image storage volumes is not supported")
 		default:
@@ -741,6 +837,13 @@ func (d *disk) Stop() (*deviceConfig.RunConfig, error) {
 			return &deviceConfig.RunConfig{}, nil
 		}
 
+		// Block custom volumes are unmounted once the VM has stopped using them.
+		if d.config["pool"] != "" {
+			return &deviceConfig.RunConfig{
+				PostHooks: []func() error{d.postStopVM},
+			}, nil
+		}
+
 		return nil, fmt.Errorf("Non-root disks not supported for VMs")
 	}
 
@@ -769,7 +872,12 @@ func (d *disk) Stop() (*deviceConfig.RunConfig, error) {
 func (d *disk) postStop() error {
 	// Check if pool-specific action should be taken.
 	if d.config["pool"] != "" {
-		err := StorageVolumeUmount(d.state, d.config["pool"], d.config["source"], db.StoragePoolVolumeTypeCustom)
+		volumeProject, err := project.StorageVolumeProject(d.state.Cluster, d.instance.Project(), db.StoragePoolVolumeTypeCustom)
+		if err != nil {
+			return err
+		}
+
+		err = StorageVolumeUmount(d.state, volumeProject, d.config["pool"], d.config["source"], db.StoragePoolVolumeTypeCustom)
 		if err != nil {
 			return err
 		}
@@ -803,6 +911,21 @@ func (d *disk) postStop() error {
 	return nil
 }
 
+// postStopVM is run after a custom volume disk is removed from a VM.
+func (d *disk) postStopVM() error {
+	pool, volumeProject, volumeName, err := d.vmPoolVolume()
+	if err != nil {
+		return err
+	}
+
+	_, err = pool.UnmountCustomVolume(volumeProject, volumeName, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Failed unmounting storage volume %q", volumeName)
+	}
+
+	return nil
+}
+
 // getDiskLimits calculates Block I/O limits.
 func (d *disk) getDiskLimits() (map[string]diskBlockLimit, error) {
 	result := map[string]diskBlockLimit{}
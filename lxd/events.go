@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -18,24 +21,23 @@ var eventsCmd = APIEndpoint{
 }
 
 type eventsServe struct {
-	req *http.Request
-	d   *Daemon
+	req    *http.Request
+	d      *Daemon
+	filter events.Filter
+	since  int64
 }
 
 func (r *eventsServe) Render(w http.ResponseWriter) error {
-	return eventsSocket(r.d, r.req, w)
+	return eventsSocket(r.d, r.req, w, r.filter, r.since)
 }
 
 func (r *eventsServe) String() string {
 	return "event handler"
 }
 
-func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
+func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter, filter events.Filter, since int64) error {
 	project := projectParam(r)
-	typeStr := r.FormValue("type")
-	if typeStr == "" {
-		typeStr = "logging,operation,lifecycle"
-	}
+	typeStr := eventsTypes(d, r)
 
 	// Upgrade the connection to websocket
 	c, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
@@ -59,7 +61,7 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	// If this request is an internal one initiated by another node wanting
 	// to watch the events on this node, set the listener to broadcast only
 	// local events.
-	listener, err := d.events.AddListener(project, c, strings.Split(typeStr, ","), serverName, isClusterNotification(r))
+	listener, err := d.events.AddListener(project, c, strings.Split(typeStr, ","), serverName, isClusterNotification(r), !d.userIsAdmin(r), filter, since)
 	if err != nil {
 		return err
 	}
@@ -93,6 +95,67 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	return nil
 }
 
+// Return the requested event types, defaulting to all the types the user is
+// allowed to get.
+func eventsTypes(d *Daemon, r *http.Request) string {
+	typeStr := r.FormValue("type")
+	if typeStr == "" {
+		typeStr = "logging,operation,lifecycle,audit"
+		if !d.userIsAdmin(r) {
+			typeStr = "operation,lifecycle"
+		}
+	}
+
+	return typeStr
+}
+
 func eventsGet(d *Daemon, r *http.Request) response.Response {
-	return &eventsServe{req: r, d: d}
+	// Logging and audit events and events from all projects are only
+	// available to administrators.
+	if !d.userIsAdmin(r) {
+		project := projectParam(r)
+		if project == "*" || !d.userHasPermission(r, project, "view") {
+			return response.Forbidden(nil)
+		}
+
+		types := strings.Split(eventsTypes(d, r), ",")
+		if shared.StringInSlice("logging", types) || shared.StringInSlice("audit", types) {
+			return response.Forbidden(nil)
+		}
+	}
+
+	filter := events.Filter{
+		EntityURLs: eventsSplitParam(r, "entity"),
+		Actions:    eventsSplitParam(r, "action"),
+	}
+
+	// Resume after the given event by replaying the buffered ones.
+	since := int64(-1)
+	sinceStr := r.FormValue("since")
+	if sinceStr != "" {
+		var err error
+		since, err = strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil || since < 0 {
+			return response.BadRequest(fmt.Errorf("Invalid event ID %q", sinceStr))
+		}
+
+		if !d.events.CanReplay(since) {
+			return response.ErrorResponse(http.StatusGone, fmt.Sprintf("Events following %d are no longer available", since))
+		}
+	}
+
+	return &eventsServe{req: r, d: d, filter: filter, since: since}
+}
+
+// Return the values of a comma-separated query parameter.
+func eventsSplitParam(r *http.Request, key string) []string {
+	values := []string{}
+	for _, value := range strings.Split(r.FormValue(key), ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
 }
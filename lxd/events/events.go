@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,6 +16,10 @@ import (
 	"github.com/lxc/lxd/shared/logger"
 )
 
+// Number of recent events kept around to be replayed to listeners resuming
+// after a disconnection.
+const bufferSize = 1000
+
 // Server represents an instance of an event server.
 type Server struct {
 	debug   bool
@@ -22,6 +27,33 @@ type Server struct {
 
 	listeners map[string]*Listener
 	lock      sync.Mutex
+
+	// Ring buffer of the most recent events, next being the position of
+	// the next event to record and lastID the ID of the last event sent.
+	buffer []bufferedEvent
+	next   int
+	lastID int64
+
+	// Functions called with the events originating from this node.
+	handlers []func(group string, event api.Event)
+}
+
+// An event recorded for replay, along with the parameters of its broadcast.
+type bufferedEvent struct {
+	group     string
+	event     api.Event
+	isForward bool
+}
+
+// Filter restricts the events sent to a listener beyond their type.
+type Filter struct {
+	// Only send events about these entities or their children, given by
+	// URL. Events not tied to any entity are not sent.
+	EntityURLs []string
+
+	// Only send the lifecycle events with these actions. Events of other
+	// types are not affected.
+	Actions []string
 }
 
 // NewServer returns a new event server.
@@ -30,35 +62,102 @@ func NewServer(debug bool, verbose bool) *Server {
 		debug:     debug,
 		verbose:   verbose,
 		listeners: map[string]*Listener{},
+		buffer:    make([]bufferedEvent, 0, bufferSize),
 	}
 
 	return server
 }
 
 // AddListener creates and returns a new event listener.
-func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool) (*Listener, error) {
+//
+// If since isn't negative, the buffered events with a greater ID are replayed
+// to the listener before any new event.
+func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool, groupOnly bool, filter Filter, since int64) (*Listener, error) {
 	listener := &Listener{
 		group:        group,
 		connection:   connection,
 		messageTypes: messageTypes,
+		filter:       filter,
 		location:     location,
 		noForward:    noForward,
+		groupOnly:    groupOnly,
 		active:       make(chan bool, 1),
 		id:           uuid.NewRandom().String(),
+		wake:         make(chan struct{}, 1),
 	}
 
 	s.lock.Lock()
-	defer s.lock.Unlock()
 
 	if s.listeners[listener.id] != nil {
+		s.lock.Unlock()
 		return nil, fmt.Errorf("A listener with id '%s' already exists", listener.id)
 	}
 
 	s.listeners[listener.id] = listener
 
+	// Queue the events to replay while holding the server lock, so that
+	// new events are only queued afterwards.
+	if since >= 0 {
+		for _, buffered := range s.buffered() {
+			if buffered.event.ID > since && listener.wants(buffered.group, buffered.event, buffered.isForward) {
+				listener.enqueue(buffered.group, buffered.event)
+			}
+		}
+	}
+
+	s.lock.Unlock()
+
+	go s.sendQueued(listener)
+
 	return listener, nil
 }
 
+// AddHandler registers a function called with every event originating from
+// this node, the ones forwarded from other nodes being excluded. It's called
+// synchronously and so must not block.
+func (s *Server) AddHandler(handler func(group string, event api.Event)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.handlers = append(s.handlers, handler)
+}
+
+// CanReplay returns whether all the events with an ID greater than the given
+// one are still buffered.
+func (s *Server) CanReplay(since int64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if since >= s.lastID {
+		return since == s.lastID
+	}
+
+	// Only logging events were sent so far, which aren't replayed anyway.
+	events := s.buffered()
+	if len(events) == 0 {
+		return true
+	}
+
+	return events[0].event.ID <= since+1
+}
+
+// Returns the buffered events, oldest first. The server lock must be held.
+func (s *Server) buffered() []bufferedEvent {
+	if len(s.buffer) < bufferSize {
+		return s.buffer
+	}
+
+	return append(append([]bufferedEvent{}, s.buffer[s.next:]...), s.buffer[:s.next]...)
+}
+
+// ListenerCount returns the number of connected listeners.
+func (s *Server) ListenerCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.listeners)
+}
+
 // SendLifecycle broadcasts a lifecycle event.
 func (s *Server) SendLifecycle(group, action, source string,
 	context map[string]interface{}) error {
@@ -103,7 +202,7 @@ func (s *Server) Forward(id int64, event api.Event) {
 		}
 	}
 
-	err := s.broadcast("", event, true)
+	err := s.broadcast(event.Project, event, true)
 	if err != nil {
 		logger.Warnf("Failed to forward event from node %d: %v", id, err)
 	}
@@ -111,70 +210,116 @@ func (s *Server) Forward(id int64, event api.Event) {
 
 func (s *Server) broadcast(group string, event api.Event, isForward bool) error {
 	s.lock.Lock()
-	listeners := s.listeners
-	for _, listener := range listeners {
-		if group != "" && listener.group != "*" && group != listener.group {
-			continue
+
+	// Number the event and record it for replay. Logging events aren't
+	// recorded, as they would quickly push all the others out.
+	s.lastID++
+	event.ID = s.lastID
+
+	if event.Type != "logging" {
+		buffered := bufferedEvent{group: group, event: event, isForward: isForward}
+		if len(s.buffer) < bufferSize {
+			s.buffer = append(s.buffer, buffered)
+		} else {
+			s.buffer[s.next] = buffered
 		}
+		s.next = (s.next + 1) % bufferSize
+	}
 
-		if isForward && listener.noForward {
-			continue
+	if !isForward {
+		for _, handler := range s.handlers {
+			handler(group, event)
 		}
+	}
 
-		if !shared.StringInSlice(event.Type, listener.messageTypes) {
+	listeners := s.listeners
+	for _, listener := range listeners {
+		if !listener.wants(group, event, isForward) {
 			continue
 		}
 
-		go func(listener *Listener, event api.Event) {
-			// Check that the listener still exists
-			if listener == nil {
-				return
+		listener.enqueue(group, event)
+	}
+	s.lock.Unlock()
+
+	return nil
+}
+
+// Send the events queued for a listener in order, until it's done.
+func (s *Server) sendQueued(listener *Listener) {
+	for range listener.wake {
+		for {
+			listener.queueLock.Lock()
+			if len(listener.queue) == 0 {
+				listener.queueLock.Unlock()
+				break
 			}
 
-			// Ensure there is only a single even going out at the time
+			queued := listener.queue[0]
+			listener.queue = listener.queue[1:]
+			listener.queueLock.Unlock()
+
+			// Ensure there is only a single event going out at the time
 			listener.lock.Lock()
-			defer listener.lock.Unlock()
+			s.send(listener, queued.group, queued.event)
+			listener.lock.Unlock()
+		}
 
-			// Make sure we're not done already
-			if listener.done {
-				return
-			}
+		listener.lock.Lock()
+		done := listener.done
+		listener.lock.Unlock()
 
-			// Set the Location to the expected serverName
-			if event.Location == "" {
-				eventCopy := api.Event{}
-				err := shared.DeepCopy(&event, &eventCopy)
-				if err != nil {
-					return
-				}
-				eventCopy.Location = listener.location
+		if done {
+			return
+		}
+	}
+}
 
-				event = eventCopy
-			}
+// Send an event to a listener. The listener lock must be held.
+func (s *Server) send(listener *Listener, group string, event api.Event) {
+	// Make sure we're not done already
+	if listener.done {
+		return
+	}
 
-			body, err := json.Marshal(event)
-			if err != nil {
-				return
-			}
+	// Set the Location to the expected serverName and, for
+	// listeners of all groups, record the event's group.
+	if event.Location == "" || (event.Project == "" && listener.group == "*") {
+		eventCopy := api.Event{}
+		err := shared.DeepCopy(&event, &eventCopy)
+		if err != nil {
+			return
+		}
 
-			err = listener.connection.WriteMessage(websocket.TextMessage, body)
-			if err != nil {
-				// Remove the listener from the list
-				s.lock.Lock()
-				delete(s.listeners, listener.id)
-				s.lock.Unlock()
-
-				// Disconnect the listener
-				listener.connection.Close()
-				listener.active <- false
-				listener.done = true
-				logger.Debugf("Disconnected event listener: %s", listener.id)
-			}
-		}(listener, event)
+		if eventCopy.Location == "" {
+			eventCopy.Location = listener.location
+		}
+
+		if eventCopy.Project == "" && listener.group == "*" {
+			eventCopy.Project = group
+		}
+
+		event = eventCopy
 	}
-	s.lock.Unlock()
 
-	return nil
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	err = listener.connection.WriteMessage(websocket.TextMessage, body)
+	if err != nil {
+		// Remove the listener from the list
+		s.lock.Lock()
+		delete(s.listeners, listener.id)
+		s.lock.Unlock()
+
+		// Disconnect the listener
+		listener.connection.Close()
+		listener.active <- false
+		listener.done = true
+		logger.Debugf("Disconnected event listener: %s", listener.id)
+	}
 }
 
 // Listener describes an event listener.
@@ -182,16 +327,127 @@ type Listener struct {
 	group        string
 	connection   *websocket.Conn
 	messageTypes []string
+	filter       Filter
 	active       chan bool
 	id           string
 	lock         sync.Mutex
 	done         bool
 	location     string
 
+	// Events waiting to be sent, oldest first, and channel waking up the
+	// goroutine sending them.
+	queue     []queuedEvent
+	queueLock sync.Mutex
+	wake      chan struct{}
+
 	// If true, this listener won't get events forwarded from other
 	// nodes. It only used by listeners created internally by LXD nodes
 	// connecting to other LXD nodes to get their local events only.
 	noForward bool
+
+	// If true, this listener only gets events tied to its group and not
+	// the ones that aren't tied to any group.
+	groupOnly bool
+}
+
+// An event waiting to be sent to a listener.
+type queuedEvent struct {
+	group string
+	event api.Event
+}
+
+// Queue an event to be sent to the listener after the ones already queued.
+func (e *Listener) enqueue(group string, event api.Event) {
+	e.queueLock.Lock()
+	e.queue = append(e.queue, queuedEvent{group: group, event: event})
+	e.queueLock.Unlock()
+
+	e.notify()
+}
+
+// Wake up the goroutine sending the queued events, if it's not already.
+func (e *Listener) notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Returns whether the given event, broadcast to the given group, should be
+// sent to the listener.
+func (e *Listener) wants(group string, event api.Event, isForward bool) bool {
+	if group != "" && e.group != "*" && group != e.group {
+		return false
+	}
+
+	if group == "" && e.groupOnly {
+		return false
+	}
+
+	if isForward && e.noForward {
+		return false
+	}
+
+	if !shared.StringInSlice(event.Type, e.messageTypes) {
+		return false
+	}
+
+	return e.filter.matches(event)
+}
+
+// Returns whether the event passes the filter.
+func (f Filter) matches(event api.Event) bool {
+	if len(f.Actions) > 0 && event.Type == "lifecycle" {
+		lifecycle := api.EventLifecycle{}
+		err := json.Unmarshal(event.Metadata, &lifecycle)
+		if err != nil || !shared.StringInSlice(lifecycle.Action, f.Actions) {
+			return false
+		}
+	}
+
+	if len(f.EntityURLs) > 0 {
+		for _, url := range eventEntityURLs(event) {
+			for _, entityURL := range f.EntityURLs {
+				if url == entityURL || strings.HasPrefix(url, strings.TrimSuffix(entityURL, "/")+"/") {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Returns the URLs of the entities the event is about: the source of a
+// lifecycle event or the resources of an operation.
+func eventEntityURLs(event api.Event) []string {
+	urls := []string{}
+
+	switch event.Type {
+	case "lifecycle":
+		lifecycle := api.EventLifecycle{}
+		err := json.Unmarshal(event.Metadata, &lifecycle)
+		if err == nil && lifecycle.Source != "" {
+			urls = append(urls, lifecycle.Source)
+		}
+	case "operation":
+		op := api.Operation{}
+		err := json.Unmarshal(event.Metadata, &op)
+		if err == nil {
+			for _, resources := range op.Resources {
+				urls = append(urls, resources...)
+			}
+		}
+	}
+
+	// Ignore the query string, like the project of the entity
+	for i, url := range urls {
+		urls[i] = strings.SplitN(url, "?", 2)[0]
+	}
+
+	return urls
 }
 
 // MessageTypes returns a list of message types the listener will be notified of.
@@ -236,4 +492,5 @@ func (e *Listener) Unlock() {
 func (e *Listener) Deactivate() {
 	e.active <- false
 	e.done = true
+	e.notify()
 }
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func newEvent(t *testing.T, eventType string, metadata interface{}) api.Event {
+	data, err := json.Marshal(metadata)
+	require.NoError(t, err)
+
+	return api.Event{Type: eventType, Metadata: data}
+}
+
+func TestFilterMatches(t *testing.T) {
+	started := newEvent(t, "lifecycle", api.EventLifecycle{Action: "container-started", Source: "/1.0/containers/c1"})
+	snapshot := newEvent(t, "lifecycle", api.EventLifecycle{Action: "container-snapshot-created", Source: "/1.0/containers/c1/snapshots/snap0"})
+	other := newEvent(t, "lifecycle", api.EventLifecycle{Action: "container-started", Source: "/1.0/containers/c10"})
+	operation := newEvent(t, "operation", api.Operation{Resources: map[string][]string{"containers": {"/1.0/containers/c1?project=foo"}}})
+	logging := newEvent(t, "logging", api.EventLogging{Message: "hello"})
+
+	cases := []struct {
+		filter   Filter
+		event    api.Event
+		expected bool
+	}{
+		{Filter{}, started, true},
+		{Filter{}, logging, true},
+		{Filter{EntityURLs: []string{"/1.0/containers/c1"}}, started, true},
+		{Filter{EntityURLs: []string{"/1.0/containers/c1"}}, snapshot, true},
+		{Filter{EntityURLs: []string{"/1.0/containers/c1"}}, other, false},
+		{Filter{EntityURLs: []string{"/1.0/containers/c1"}}, operation, true},
+		{Filter{EntityURLs: []string{"/1.0/containers/c1"}}, logging, false},
+		{Filter{Actions: []string{"container-started"}}, started, true},
+		{Filter{Actions: []string{"container-started"}}, snapshot, false},
+		{Filter{Actions: []string{"container-started"}}, operation, true},
+		{Filter{EntityURLs: []string{"/1.0/containers/c1"}, Actions: []string{"container-started"}}, other, false},
+	}
+
+	for i, c := range cases {
+		assert.Equal(t, c.expected, c.filter.matches(c.event), "case %d", i)
+	}
+}
+
+func TestServerReplay(t *testing.T) {
+	server := NewServer(false, false)
+	assert.True(t, server.CanReplay(0))
+	assert.False(t, server.CanReplay(1))
+
+	for i := 0; i < bufferSize+10; i++ {
+		server.Send("default", "lifecycle", api.EventLifecycle{Action: "container-started"})
+	}
+
+	// Logging events are numbered but not buffered.
+	server.Send("default", "logging", api.EventLogging{Message: "hello"})
+
+	events := server.buffered()
+	require.Len(t, events, bufferSize)
+	assert.Equal(t, int64(11), events[0].event.ID)
+	assert.Equal(t, int64(bufferSize+10), events[len(events)-1].event.ID)
+
+	assert.False(t, server.CanReplay(9))
+	assert.True(t, server.CanReplay(10))
+	assert.True(t, server.CanReplay(bufferSize+11))
+	assert.False(t, server.CanReplay(bufferSize+12))
+}
+
+// The events are sent to each listener in the order they were broadcast,
+// after the replayed ones.
+func TestServerOrder(t *testing.T) {
+	server := NewServer(false, false)
+	for i := 0; i < 10; i++ {
+		server.Send("default", "lifecycle", api.EventLifecycle{Action: "container-started"})
+	}
+
+	listeners := make(chan *Listener, 1)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		require.NoError(t, err)
+
+		listener, err := server.AddListener("default", conn, []string{"lifecycle"}, "", false, false, Filter{}, 5)
+		require.NoError(t, err)
+
+		listeners <- listener
+	}))
+	defer httpServer.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	<-listeners
+
+	for i := 0; i < 100; i++ {
+		server.Send("default", "lifecycle", api.EventLifecycle{Action: "container-started"})
+	}
+
+	for id := int64(6); id <= 110; id++ {
+		event := api.Event{}
+		err := conn.ReadJSON(&event)
+		require.NoError(t, err)
+		assert.Equal(t, id, event.ID)
+	}
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/units"
+)
+
+var healthCmd = APIEndpoint{
+	Path: "health",
+
+	Get: APIEndpointAction{Handler: healthGet, AllowUntrusted: true},
+}
+
+// Health statuses, from best to worst.
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+	healthFailed   = "failed"
+)
+
+// How long to wait for the database to answer.
+const healthDatabaseTimeout = 5 * time.Second
+
+// How far the local clock may drift from the leader's one.
+const healthClockSkewLimit = 5 * time.Second
+
+// How long without a heartbeat from the leader until the member is
+// considered to be cut off from it.
+const healthHeartbeatTimeout = time.Minute
+
+// Percentage of free disk space below which a pool or the images storage is
+// degraded.
+const healthDiskFreeDegraded = 10
+
+// Storage drivers which always mount the pool on its mount path.
+var healthMountedDrivers = []string{"btrfs", "cephfs"}
+
+// How long the results of the checks are served to non-admin clients before
+// being refreshed, so that they can't load the server by polling it.
+const healthCacheExpiry = 10 * time.Second
+
+// Last results of the checks. The lock isn't held while the checks run, the
+// running channel being closed once they're done instead, so that they never
+// run concurrently.
+var healthCache struct {
+	sync.Mutex
+	checks  []api.HealthCheck
+	date    time.Time
+	running chan struct{}
+}
+
+func healthGet(d *Daemon, r *http.Request) response.Response {
+	// Only admins get to see the names of the pools and networks.
+	admin := d.checkTrustedClient(r) == nil && d.userIsAdmin(r)
+
+	health := api.Health{}
+
+	select {
+	case <-d.readyChan:
+		// Admins always get fresh results.
+		maxAge := healthCacheExpiry
+		if admin {
+			maxAge = 0
+		}
+
+		health.Checks = healthChecksCached(d, maxAge)
+	default:
+		health.Checks = []api.HealthCheck{{Type: "daemon", Status: healthFailed, Message: "LXD daemon not ready yet"}}
+	}
+
+	health.Status = healthOK
+	for _, check := range health.Checks {
+		if healthWorse(check.Status, health.Status) {
+			health.Status = check.Status
+		}
+	}
+
+	// Other clients only get to know the overall status.
+	if !admin {
+		health.Checks = nil
+	}
+
+	// Degraded members are still able to serve requests.
+	code := http.StatusOK
+	if health.Status == healthFailed {
+		code = http.StatusServiceUnavailable
+	}
+
+	return response.SyncResponseCode(true, health, code)
+}
+
+// Returns whether the given status is worse than the other one.
+func healthWorse(status string, other string) bool {
+	rank := func(status string) int {
+		switch status {
+		case healthOK:
+			return 0
+		case healthDegraded:
+			return 1
+		}
+
+		return 2
+	}
+
+	return rank(status) > rank(other)
+}
+
+// Return the results of the checks, running them again if the last ones are
+// older than the given age. While the checks are running, the last results are
+// returned if they're allowed to be cached, and the new ones otherwise.
+func healthChecksCached(d *Daemon, maxAge time.Duration) []api.HealthCheck {
+	healthCache.Lock()
+
+	if healthCache.checks != nil && time.Since(healthCache.date) < maxAge {
+		checks := healthCache.checks
+		healthCache.Unlock()
+		return checks
+	}
+
+	running := healthCache.running
+	if running != nil {
+		checks := healthCache.checks
+		healthCache.Unlock()
+
+		if checks != nil && maxAge > 0 {
+			return checks
+		}
+
+		<-running
+
+		healthCache.Lock()
+		defer healthCache.Unlock()
+		return healthCache.checks
+	}
+
+	running = make(chan struct{})
+	healthCache.running = running
+	healthCache.Unlock()
+
+	checks := healthChecks(d)
+
+	healthCache.Lock()
+	healthCache.checks = checks
+	healthCache.date = time.Now()
+	healthCache.running = nil
+	healthCache.Unlock()
+	close(running)
+
+	return checks
+}
+
+// Run all the checks of the health of this member.
+func healthChecks(d *Daemon) []api.HealthCheck {
+	checks := []api.HealthCheck{healthCheckDatabase(d)}
+	checks = append(checks, healthCheckCluster(d)...)
+	checks = append(checks, healthCheckStoragePools(d)...)
+	checks = append(checks, healthCheckNetworks(d)...)
+	checks = append(checks, healthCheckImages(d))
+
+	return checks
+}
+
+// Check that the cluster database answers queries in a timely manner.
+func healthCheckDatabase(d *Daemon) api.HealthCheck {
+	check := api.HealthCheck{Type: "database", Name: "cluster", Status: healthOK}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			_, err := tx.NodeName()
+			return err
+		})
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			check.Status = healthFailed
+			check.Message = fmt.Sprintf("Failed to query the database: %v", err)
+		}
+	case <-time.After(healthDatabaseTimeout):
+		check.Status = healthFailed
+		check.Message = "Timed out querying the database"
+	}
+
+	return check
+}
+
+// Check that a raft leader is known, and that the local clock is in line with
+// the leader's one. Nothing is checked if this member isn't clustered.
+func healthCheckCluster(d *Daemon) []api.HealthCheck {
+	raft := api.HealthCheck{Type: "raft", Name: "cluster", Status: healthOK}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		raft.Status = healthFailed
+		raft.Message = fmt.Sprintf("Failed to check whether clustered: %v", err)
+		return []api.HealthCheck{raft}
+	}
+
+	if !clustered {
+		return nil
+	}
+
+	leader, err := d.gateway.LeaderAddress()
+	if err != nil {
+		raft.Status = healthFailed
+		raft.Message = fmt.Sprintf("No raft leader: %v", err)
+		return []api.HealthCheck{raft}
+	}
+
+	raft.Message = fmt.Sprintf("Leader is %s", leader)
+	if d.gateway.IsDatabaseNode() {
+		raft.Message = fmt.Sprintf("Database member, leader is %s", leader)
+	}
+
+	clock := api.HealthCheck{Type: "clock", Name: "cluster", Status: healthOK}
+
+	address, err := node.ClusterAddress(d.db)
+	if err != nil {
+		clock.Status = healthFailed
+		clock.Message = fmt.Sprintf("Failed to get cluster address: %v", err)
+		return []api.HealthCheck{raft, clock}
+	}
+
+	// The leader is the reference.
+	if address == leader {
+		clock.Message = "Member is the leader"
+		return []api.HealthCheck{raft, clock}
+	}
+
+	skew, date := d.gateway.ClockSkew()
+	if date.IsZero() {
+		clock.Message = "No heartbeat received yet"
+		return []api.HealthCheck{raft, clock}
+	}
+
+	if time.Since(date) > healthHeartbeatTimeout {
+		clock.Status = healthDegraded
+		clock.Message = fmt.Sprintf("No heartbeat received since %s", date.Format(time.RFC3339))
+		return []api.HealthCheck{raft, clock}
+	}
+
+	clock.Message = fmt.Sprintf("Clock skew with the leader is %s", skew.Round(time.Millisecond))
+	if skew > healthClockSkewLimit || skew < -healthClockSkewLimit {
+		clock.Status = healthDegraded
+	}
+
+	return []api.HealthCheck{raft, clock}
+}
+
+// Check that the storage pools are available and have free space left. The
+// member is at most degraded by them, as it can still serve other requests.
+func healthCheckStoragePools(d *Daemon) []api.HealthCheck {
+	checks := []api.HealthCheck{}
+
+	pools, err := d.cluster.StoragePoolsNotPending()
+	if err != nil && err != db.ErrNoSuchObject {
+		checks = append(checks, api.HealthCheck{
+			Type:    "storage-pool",
+			Status:  healthDegraded,
+			Message: fmt.Sprintf("Failed to list storage pools: %v", err),
+		})
+		return checks
+	}
+
+	for _, name := range pools {
+		check := api.HealthCheck{Type: "storage-pool", Name: name}
+
+		_, pool, err := d.cluster.StoragePoolGet(name)
+		if err != nil {
+			check.Status = healthDegraded
+			check.Message = fmt.Sprintf("Failed to load storage pool: %v", err)
+			checks = append(checks, check)
+			continue
+		}
+
+		if shared.StringInSlice(pool.Driver, healthMountedDrivers) && !shared.IsMountPoint(storageDrivers.GetPoolMountPath(name)) {
+			check.Status = healthDegraded
+			check.Message = "Storage pool isn't mounted"
+			checks = append(checks, check)
+			continue
+		}
+
+		res, err := healthStoragePoolResources(d.State(), name, pool.Driver)
+		if err != nil {
+			check.Status = healthDegraded
+			check.Message = fmt.Sprintf("Failed to get storage pool resources: %v", err)
+			checks = append(checks, check)
+			continue
+		}
+
+		free := uint64(0)
+		if res.Space.Used < res.Space.Total {
+			free = res.Space.Total - res.Space.Used
+		}
+
+		check.Status, check.Message = healthDiskSpace(free, res.Space.Total)
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// Get the resources of a storage pool, which fails if the pool isn't usable.
+// Nothing gets mounted or activated, as the checks run for untrusted clients.
+func healthStoragePoolResources(s *state.State, name string, driver string) (*api.ResourcesStoragePool, error) {
+	pool, err := storagePools.GetPoolByName(s, name)
+	if err != storageDrivers.ErrUnknownDriver {
+		if err != nil {
+			return nil, err
+		}
+
+		return pool.GetResources()
+	}
+
+	// The old storage layer mounts btrfs pools to get their resources, so
+	// look at the mount point, which was checked to be mounted, instead.
+	if driver == "btrfs" {
+		return storagePools.GetStorageResource(storagePools.GetStoragePoolMountPoint(name))
+	}
+
+	// Fallback to old storage layer, which only queries lvs, vgs, zfs or
+	// ceph for the others.
+	legacy, err := storagePoolInit(s, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return legacy.StoragePoolResources()
+}
+
+// Check that the managed networks are up along with their dnsmasq and forkdns
+// processes. Like for the storage pools, the member is at most degraded.
+func healthCheckNetworks(d *Daemon) []api.HealthCheck {
+	checks := []api.HealthCheck{}
+
+	networks, err := d.cluster.NetworksNotPending()
+	if err != nil {
+		checks = append(checks, api.HealthCheck{
+			Type:    "network",
+			Status:  healthDegraded,
+			Message: fmt.Sprintf("Failed to list networks: %v", err),
+		})
+		return checks
+	}
+
+	clusterAddress, err := node.ClusterAddress(d.db)
+	if err != nil {
+		checks = append(checks, api.HealthCheck{
+			Type:    "network",
+			Status:  healthDegraded,
+			Message: fmt.Sprintf("Failed to get cluster address: %v", err),
+		})
+		return checks
+	}
+
+	for _, name := range networks {
+		check := api.HealthCheck{Type: "network", Name: name, Status: healthOK}
+
+		n, err := networkLoadByName(d.State(), name)
+		if err != nil {
+			check.Status = healthDegraded
+			check.Message = fmt.Sprintf("Failed to load network: %v", err)
+			checks = append(checks, check)
+			continue
+		}
+
+		config := n.Config()
+		fan := config["bridge.mode"] == "fan"
+
+		// Same conditions as when starting the network.
+		dnsmasq := fan || !shared.StringInSlice(config["ipv4.address"], []string{"", "none"}) || !shared.StringInSlice(config["ipv6.address"], []string{"", "none"})
+		forkdns := fan && clusterAddress != ""
+
+		if !n.IsRunning() {
+			check.Status = healthDegraded
+			check.Message = "Network interface doesn't exist"
+		} else if dnsmasq && !healthProcessRunning(shared.VarPath("networks", name, "dnsmasq.pid")) {
+			check.Status = healthDegraded
+			check.Message = "dnsmasq isn't running"
+		} else if forkdns && !healthProcessRunning(shared.VarPath("networks", name, "forkdns.pid")) {
+			check.Status = healthDegraded
+			check.Message = "forkdns isn't running"
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// Returns whether the process with the PID in the given file is running.
+func healthProcessRunning(pidPath string) bool {
+	content, err := ioutil.ReadFile(pidPath)
+	if err != nil {
+		return false
+	}
+
+	pid := strings.TrimSpace(string(content))
+	if pid == "" {
+		return false
+	}
+
+	return shared.PathExists(fmt.Sprintf("/proc/%s", pid))
+}
+
+// Check the free space of the images storage, which is the volume set in
+// storage.images_volume if any.
+func healthCheckImages(d *Daemon) api.HealthCheck {
+	check := api.HealthCheck{Type: "images", Name: "local"}
+
+	err := d.db.Transaction(func(tx *db.NodeTx) error {
+		nodeConfig, err := node.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		volume := nodeConfig.StorageImagesVolume()
+		if volume != "" {
+			check.Name = volume
+		}
+
+		return nil
+	})
+	if err != nil {
+		check.Status = healthDegraded
+		check.Message = fmt.Sprintf("Failed to load configuration: %v", err)
+		return check
+	}
+
+	st, err := shared.Statvfs(shared.VarPath("images"))
+	if err != nil {
+		check.Status = healthDegraded
+		check.Message = fmt.Sprintf("Failed to get free space: %v", err)
+		return check
+	}
+
+	check.Status, check.Message = healthDiskSpace(st.Bavail*uint64(st.Bsize), st.Blocks*uint64(st.Bsize))
+	return check
+}
+
+// Returns the status and a message for the given free and total disk space.
+func healthDiskSpace(free uint64, total uint64) (string, string) {
+	message := fmt.Sprintf("%s free out of %s", units.GetByteSizeString(int64(free), 2), units.GetByteSizeString(int64(total), 2))
+
+	// Some drivers don't report the space.
+	if total == 0 {
+		return healthOK, ""
+	}
+
+	percent := free * 100 / total
+	if percent < healthDiskFreeDegraded {
+		return healthDegraded, message
+	}
+
+	return healthOK, message
+}
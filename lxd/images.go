@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -315,6 +316,24 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *operati
 		return nil, err
 	}
 
+	// Sign the image with the server key
+	if req.Sign {
+		id, _, err := d.cluster.ImageGet(c.Project(), info.Fingerprint, false, true)
+		if err != nil {
+			return nil, err
+		}
+
+		info.Signature, err = imageSign(d, info.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.cluster.ImageSignatureUpdate(id, info.Signature)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &info, nil
 }
 
@@ -430,6 +449,7 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 	logger := logging.AddContext(logger.Log, log.Ctx{"function": "getImgPostInfo"})
 
 	info.Public = shared.IsTrue(r.Header.Get("X-LXD-public"))
+	info.Signature = r.Header.Get("X-LXD-signature")
 	propHeaders := r.Header[http.CanonicalHeaderKey("X-LXD-properties")]
 	ctype, ctypeParams, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil {
@@ -511,6 +531,13 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 			return nil, err
 		}
 
+		if !isClusterNotification(r) {
+			err = imageCheckSignature(d, info.Fingerprint, info.Signature)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		imageMeta, _, err = getImageMetadata(imageTarf.Name())
 		if err != nil {
 			logger.Error("Failed to get image metadata", log.Ctx{"err": err})
@@ -536,6 +563,58 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 				"dest":   imgfname})
 			return nil, err
 		}
+	} else if shared.IsTrue(r.Header.Get("X-LXD-disk-image")) {
+		// Build a virtual-machine image out of a standalone disk image
+		properties := map[string]string{}
+		for _, ph := range propHeaders {
+			p, _ := url.ParseQuery(ph)
+			for pkey, pval := range p {
+				properties[pkey] = pval[0]
+			}
+		}
+
+		info.Filename = r.Header.Get("X-LXD-filename")
+		metaPath, rootfsPath, meta, err := diskImagePack(d, builddir, post.Name(), info.Filename, properties)
+		if err != nil {
+			logger.Error("Failed to convert the disk image", log.Ctx{"err": err})
+			return nil, err
+		}
+		imageMeta = meta
+		info.Type = instancetype.VM.String()
+
+		for _, path := range []string{metaPath, rootfsPath} {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+
+			size, err = io.Copy(sha256, f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			info.Size += size
+		}
+
+		info.Fingerprint = fmt.Sprintf("%x", sha256.Sum(nil))
+
+		if !isClusterNotification(r) {
+			err = imageCheckSignature(d, info.Fingerprint, info.Signature)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		imgfname := shared.VarPath("images", info.Fingerprint)
+		err = shared.FileMove(metaPath, imgfname)
+		if err != nil {
+			return nil, err
+		}
+
+		err = shared.FileMove(rootfsPath, imgfname+".rootfs")
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		post.Seek(0, 0)
 		size, err = io.Copy(sha256, post)
@@ -557,6 +636,13 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 			return nil, err
 		}
 
+		if !isClusterNotification(r) {
+			err = imageCheckSignature(d, info.Fingerprint, info.Signature)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		var imageType string
 		imageMeta, imageType, err = getImageMetadata(post.Name())
 		if err != nil {
@@ -613,6 +699,18 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 		if err != nil {
 			return nil, err
 		}
+
+		if info.Signature != "" {
+			id, _, err := d.cluster.ImageGet(project, info.Fingerprint, false, true)
+			if err != nil {
+				return nil, err
+			}
+
+			err = d.cluster.ImageSignatureUpdate(id, info.Signature)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return &info, nil
@@ -922,6 +1020,14 @@ func doImagesGet(d *Daemon, recursion bool, project string, public bool) (interf
 		return []string{}, err
 	}
 
+	var keys []crypto.PublicKey
+	if recursion {
+		keys, _, err = imageTrustedKeys(d)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
 	resultString := make([]string, len(results))
 	resultMap := make([]*api.Image, len(results))
 	i := 0
@@ -934,6 +1040,7 @@ func doImagesGet(d *Daemon, recursion bool, project string, public bool) (interf
 			if response != nil {
 				continue
 			}
+			imageFillSignatureStatus(keys, image)
 			resultMap[i] = image
 		}
 
@@ -1109,9 +1216,12 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 
 		if err != nil {
 			logger.Error("Failed to update the image", log.Ctx{"err": err, "fp": fingerprint})
+			warningCreate(d.State(), project, fmt.Sprintf("/%s/images/%s", version.APIVersion, fingerprint), db.WarningImageUpdateFailed, err.Error())
 			continue
 		}
 
+		warningResolve(d.State(), project, fmt.Sprintf("/%s/images/%s", version.APIVersion, fingerprint), db.WarningImageUpdateFailed)
+
 		hash = newInfo.Fingerprint
 		if hash == fingerprint {
 			logger.Debug("Already up to date", log.Ctx{"fp": fingerprint})
@@ -1516,6 +1626,17 @@ func imageDeleteFromDisk(fingerprint string) {
 			logger.Errorf("Error deleting image file %s: %s", fname, err)
 		}
 	}
+
+	// Remove any delta files generated for the simplestreams feed.
+	deltas, _ := filepath.Glob(shared.VarPath("images", fingerprint) + ".delta-*")
+	srcDeltas, _ := filepath.Glob(shared.VarPath("images", "*.delta-"+fingerprint))
+	deltas = append(deltas, srcDeltas...)
+	for _, fname := range deltas {
+		err := os.Remove(fname)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Errorf("Error deleting image file %s: %s", fname, err)
+		}
+	}
 }
 
 func doImageGet(db *db.Cluster, project, fingerprint string, public bool) (*api.Image, response.Response) {
@@ -1572,6 +1693,12 @@ func imageGet(d *Daemon, r *http.Request) response.Response {
 		return response.NotFound(fmt.Errorf("Image '%s' not found", info.Fingerprint))
 	}
 
+	keys, _, err := imageTrustedKeys(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	imageFillSignatureStatus(keys, info)
+
 	etag := []interface{}{info.Public, info.AutoUpdate, info.Properties}
 	return response.SyncResponseETag(true, info, etag)
 }
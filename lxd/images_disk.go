@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// diskImagePack turns a standalone raw, qcow2 or vmdk disk image into the
+// metadata tarball and qcow2 root disk of a split virtual-machine image.
+//
+// The metadata is synthesized from the given properties, the architecture
+// defaulting to the one of the server. Both files are created in builddir and
+// their paths returned along with the metadata.
+func diskImagePack(d *Daemon, builddir string, srcPath string, filename string, properties map[string]string) (string, string, *api.ImageMetadata, error) {
+	architecture := properties["architecture"]
+	if architecture == "" {
+		var err error
+		architecture, err = osarch.ArchitectureName(d.os.Architectures[0])
+		if err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	_, err := osarch.ArchitectureId(architecture)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if properties["description"] == "" && filename != "" {
+		properties["description"] = filename
+	}
+
+	// Convert the disk image into the root disk
+	rootfsFile, err := ioutil.TempFile(builddir, "lxd_disk_")
+	if err != nil {
+		return "", "", nil, err
+	}
+	rootfsFile.Close()
+
+	_, err = storagePools.DiskImageConvert(srcPath, rootfsFile.Name())
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// Generate the metadata tarball
+	metadata := api.ImageMetadata{}
+	metadata.Architecture = architecture
+	metadata.CreationDate = time.Now().UTC().Unix()
+	metadata.Properties = properties
+
+	data, err := yaml.Marshal(&metadata)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	metaFile, err := ioutil.TempFile(builddir, "lxd_meta_")
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer metaFile.Close()
+
+	gzWriter := gzip.NewWriter(metaFile)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	hdr := &tar.Header{
+		Name:     "metadata.yaml",
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  time.Unix(metadata.CreationDate, 0),
+		Typeflag: tar.TypeReg,
+	}
+
+	err = tarWriter.WriteHeader(hdr)
+	if err != nil {
+		return "", "", nil, errors.Wrap(err, "Failed to write image metadata")
+	}
+
+	_, err = tarWriter.Write(data)
+	if err != nil {
+		return "", "", nil, errors.Wrap(err, "Failed to write image metadata")
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return "", "", nil, errors.Wrap(err, "Failed to write image metadata")
+	}
+
+	err = gzWriter.Close()
+	if err != nil {
+		return "", "", nil, errors.Wrap(err, "Failed to write image metadata")
+	}
+
+	return metaFile.Name(), rootfsFile.Name(), &metadata, nil
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/containerwriter"
+	"github.com/lxc/lxd/shared/ioprogress"
+	"github.com/lxc/lxd/shared/oci"
+	"github.com/lxc/lxd/shared/osarch"
+	"github.com/lxc/lxd/shared/units"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Prefixes of the files used by OCI layers to mark files deleted from lower layers
+const ociWhiteoutPrefix = ".wh."
+const ociWhiteoutOpaque = ".wh..wh..opq"
+
+// ociImageDownload pulls the named image from an OCI registry and converts it into a unified LXD
+// image written to destName.
+func ociImageDownload(d *Daemon, server string, certificate string, name string, destName string, progress func(ioprogress.ProgressData)) (*api.Image, error) {
+	httpClient, err := util.HTTPClient(certificate, d.proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := oci.NewClient(server, *httpClient, version.UserAgent)
+
+	architecture, err := osarch.ArchitectureName(d.os.Architectures[0])
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, digest, err := registry.GetManifest(name, architecture)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := registry.GetConfig(name, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assemble the image next to the image store
+	tmpDir, err := ioutil.TempDir(shared.VarPath("images"), "lxd_oci_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	rootfsPath := filepath.Join(tmpDir, "rootfs")
+	err = os.Mkdir(rootfsPath, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, layer := range manifest.Layers {
+		layerPath := filepath.Join(tmpDir, "layer")
+
+		err := ociDownloadLayer(registry, name, layer, layerPath, func(percent int64, speed int64) {
+			progress(ioprogress.ProgressData{Text: fmt.Sprintf("layer %d/%d: %d%% (%s/s)", i+1, len(manifest.Layers), percent, units.GetByteSizeString(speed, 2))})
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		err = ociUnpackLayer(layerPath, rootfsPath, d.os.RunningInUserNS)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.Remove(layerPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Generate the image metadata
+	properties, err := ociImageProperties(registry.Description(name), digest, config, rootfsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := api.ImageMetadata{}
+	metadata.Architecture = architecture
+	metadata.CreationDate = config.Created.Unix()
+	metadata.Properties = properties
+
+	data, err := yaml.Marshal(&metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "metadata.yaml"), data, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pack the unified image
+	progress(ioprogress.ProgressData{Text: "packing image"})
+	fingerprint, size, err := ociPackImage(tmpDir, destName)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &api.Image{}
+	info.Fingerprint = fingerprint
+	info.Size = size
+	info.Architecture = architecture
+	info.CreatedAt = config.Created
+	info.Properties = properties
+	info.Type = "container"
+
+	return info, nil
+}
+
+// ociDownloadLayer downloads a layer blob to path.
+func ociDownloadLayer(registry *oci.Registry, name string, layer oci.Descriptor, path string, handler func(int64, int64)) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := &ioprogress.ProgressWriter{
+		WriteCloser: f,
+		Tracker: &ioprogress.ProgressTracker{
+			Length:  layer.Size,
+			Handler: handler,
+		},
+	}
+
+	return registry.GetBlob(name, layer, writer)
+}
+
+// ociSafePath returns the path of a layer entry inside rootfsPath, failing if any of its parent
+// directories is a symlink so that whiteouts can't reach outside of the image.
+func ociSafePath(rootfsPath string, entry string) (string, error) {
+	entry = filepath.Clean(fmt.Sprintf("/%s", entry))
+
+	parent := rootfsPath
+	for _, field := range strings.Split(filepath.Dir(entry), "/") {
+		if field == "" {
+			continue
+		}
+
+		parent = filepath.Join(parent, field)
+		fi, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if fi.Mode()&os.ModeSymlink == os.ModeSymlink {
+			return "", fmt.Errorf("Path %q goes through a symlink", entry)
+		}
+	}
+
+	return filepath.Join(rootfsPath, entry), nil
+}
+
+// ociLayerEntries returns the paths of all the entries of a layer.
+func ociLayerEntries(layerPath string) ([]string, error) {
+	_, _, decompress, err := shared.DetectCompression(layerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(layerPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var reader io.Reader = f
+	if len(decompress) > 0 {
+		cmd := exec.Command(decompress[0], decompress[1:]...)
+		cmd.Stdin = f
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+
+		err = cmd.Start()
+		if err != nil {
+			return nil, err
+		}
+
+		// The tar stream ends before the end of the compressed data
+		defer func() {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}()
+
+		reader = stdout
+	}
+
+	entries := []string{}
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, hdr.Name)
+	}
+
+	return entries, nil
+}
+
+// ociUnpackLayer applies a layer on top of rootfsPath, removing the files hidden by its whiteouts.
+func ociUnpackLayer(layerPath string, rootfsPath string, runningInUserns bool) error {
+	entries, err := ociLayerEntries(layerPath)
+	if err != nil {
+		return err
+	}
+
+	// Remove the content of the lower layers hidden by whiteouts
+	whiteouts := []string{}
+	for _, entry := range entries {
+		base := filepath.Base(entry)
+		if !strings.HasPrefix(base, ociWhiteoutPrefix) {
+			continue
+		}
+
+		whiteoutPath, err := ociSafePath(rootfsPath, entry)
+		if err != nil {
+			return err
+		}
+
+		whiteouts = append(whiteouts, entry)
+
+		if base == ociWhiteoutOpaque {
+			children, err := ioutil.ReadDir(filepath.Dir(whiteoutPath))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+			for _, child := range children {
+				err := os.RemoveAll(filepath.Join(filepath.Dir(whiteoutPath), child.Name()))
+				if err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		err = os.RemoveAll(filepath.Join(filepath.Dir(whiteoutPath), strings.TrimPrefix(base, ociWhiteoutPrefix)))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = shared.Unpack(layerPath, rootfsPath, false, runningInUserns, nil)
+	if err != nil {
+		return err
+	}
+
+	// The whiteout markers themselves aren't part of the image
+	for _, entry := range whiteouts {
+		whiteoutPath, err := ociSafePath(rootfsPath, entry)
+		if err != nil {
+			return err
+		}
+
+		err = os.Remove(whiteoutPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ociLookupID returns the ID of the named entry in a passwd or group file of the image.
+func ociLookupID(rootfsPath string, file string, name string) (string, error) {
+	path, err := ociSafePath(rootfsPath, file)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) >= 4 && fields[0] == name {
+			return fields[2], nil
+		}
+	}
+
+	return "", fmt.Errorf("No entry for %q in %s", name, file)
+}
+
+// ociResolveUser converts the user of an image ("user", "uid", "user:group" or "uid:gid") into a
+// numeric "uid:gid" pair using the account databases of the image.
+func ociResolveUser(rootfsPath string, user string) (string, error) {
+	fields := strings.SplitN(user, ":", 2)
+
+	uid := fields[0]
+	gid := ""
+	if len(fields) == 2 {
+		gid = fields[1]
+	}
+
+	isNumeric := func(value string) bool {
+		return strings.Trim(value, "0123456789") == ""
+	}
+
+	if !isNumeric(uid) || gid == "" {
+		path, err := ociSafePath(rootfsPath, "/etc/passwd")
+		if err != nil {
+			return "", err
+		}
+
+		found := false
+		f, err := os.Open(path)
+		if err == nil {
+			defer f.Close()
+
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				entry := strings.Split(scanner.Text(), ":")
+				if len(entry) < 4 || (entry[0] != uid && entry[2] != uid) {
+					continue
+				}
+
+				uid = entry[2]
+				if gid == "" {
+					gid = entry[3]
+				}
+
+				found = true
+				break
+			}
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		if !found {
+			// Numeric users don't have to exist in the image
+			if !isNumeric(uid) {
+				return "", fmt.Errorf("No entry for user %q in /etc/passwd", uid)
+			}
+
+			gid = uid
+		}
+	}
+
+	if !isNumeric(gid) {
+		var err error
+		gid, err = ociLookupID(rootfsPath, "/etc/group", gid)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%s:%s", uid, gid), nil
+}
+
+// ociQuoteCommand joins a command into a single string which LXC splits back into its arguments.
+func ociQuoteCommand(command []string) string {
+	args := []string{}
+	for _, arg := range command {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"") {
+			args = append(args, arg)
+		} else if strings.Contains(arg, "'") {
+			args = append(args, fmt.Sprintf(`"%s"`, arg))
+		} else {
+			args = append(args, fmt.Sprintf("'%s'", arg))
+		}
+	}
+
+	return strings.Join(args, " ")
+}
+
+// ociImageProperties returns the image properties describing an OCI image and its runtime
+// configuration.
+func ociImageProperties(description string, digest string, config *oci.ImageConfig, rootfsPath string) (map[string]string, error) {
+	properties := map[string]string{
+		"description": description,
+		"oci.digest":  digest,
+	}
+
+	command := []string{}
+	command = append(command, config.Config.Entrypoint...)
+	command = append(command, config.Config.Cmd...)
+	if len(command) > 0 {
+		properties["oci.entrypoint"] = ociQuoteCommand(command)
+	}
+
+	if config.Config.User != "" {
+		user, err := ociResolveUser(rootfsPath, config.Config.User)
+		if err != nil {
+			return nil, err
+		}
+
+		properties["oci.user"] = user
+	}
+
+	if config.Config.WorkingDir != "" {
+		properties["oci.cwd"] = config.Config.WorkingDir
+	}
+
+	if config.Config.StopSignal != "" {
+		properties["oci.stop_signal"] = config.Config.StopSignal
+	}
+
+	for _, env := range config.Config.Env {
+		fields := strings.SplitN(env, "=", 2)
+		if len(fields) != 2 || fields[0] == "" {
+			continue
+		}
+
+		properties[fmt.Sprintf("oci.env.%s", fields[0])] = fields[1]
+	}
+
+	err := ociValidateProperties(properties)
+	if err != nil {
+		return nil, err
+	}
+
+	return properties, nil
+}
+
+// ociValidateProperties checks that the runtime configuration of an OCI image doesn't contain
+// control characters, which would otherwise end up in the container configuration.
+func ociValidateProperties(properties map[string]string) error {
+	for key, value := range properties {
+		if !strings.HasPrefix(key, "oci.") {
+			continue
+		}
+
+		if shared.IsPrintable(key) != nil || shared.IsPrintable(value) != nil {
+			return fmt.Errorf("Invalid OCI image configuration %q, control characters aren't allowed", key)
+		}
+	}
+
+	return nil
+}
+
+// ociPackImage packs the rootfs and metadata assembled in path into a unified image at destName,
+// returning its fingerprint and size.
+func ociPackImage(path string, destName string) (string, int64, error) {
+	f, err := os.Create(destName)
+	if err != nil {
+		return "", -1, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	pr, pw := io.Pipe()
+
+	// Walk the tree in order so that the tarball is reproducible
+	chErr := make(chan error, 1)
+	go func() {
+		ctw := containerwriter.NewContainerTarWriter(pw, nil)
+		offset := len(path) + 1
+
+		err := filepath.Walk(path, func(filePath string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if filePath == path {
+				return nil
+			}
+
+			return ctw.WriteFile(offset, filePath, fi)
+		})
+		if err != nil {
+			ctw.Close()
+		} else {
+			err = ctw.Close()
+		}
+
+		pw.CloseWithError(err)
+		chErr <- err
+	}()
+
+	err = compressFile("gzip", pr, io.MultiWriter(f, hash))
+	pr.Close()
+	if err != nil {
+		return "", -1, err
+	}
+
+	err = <-chErr
+	if err != nil {
+		return "", -1, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", -1, err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), fi.Size(), nil
+}
+
+// ociInstanceConfig maps the runtime configuration of an image converted from an OCI image into
+// the configuration of a new instance, leaving keys set by the user untouched.
+func ociInstanceConfig(properties map[string]string, config map[string]string) error {
+	err := ociValidateProperties(properties)
+	if err != nil {
+		return err
+	}
+
+	mapped := map[string]string{
+		"oci.entrypoint":  properties["oci.entrypoint"],
+		"oci.cwd":         properties["oci.cwd"],
+		"oci.stop_signal": properties["oci.stop_signal"],
+	}
+
+	if properties["oci.user"] != "" {
+		fields := strings.SplitN(properties["oci.user"], ":", 2)
+		mapped["oci.uid"] = fields[0]
+		if len(fields) == 2 {
+			mapped["oci.gid"] = fields[1]
+		}
+	}
+
+	for key, value := range properties {
+		if strings.HasPrefix(key, "oci.env.") {
+			mapped[fmt.Sprintf("environment.%s", strings.TrimPrefix(key, "oci.env."))] = value
+		}
+	}
+
+	for key, value := range mapped {
+		if value == "" {
+			continue
+		}
+
+		_, ok := config[key]
+		if ok {
+			continue
+		}
+
+		checker, err := shared.ConfigKeyChecker(key)
+		if err != nil {
+			return err
+		}
+
+		err = checker(value)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid OCI image configuration for %q", key)
+		}
+
+		config[key] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOCIQuoteCommand(t *testing.T) {
+	assert.Equal(t, "/bin/sh", ociQuoteCommand([]string{"/bin/sh"}))
+	assert.Equal(t, "/bin/sh -c 'echo hello'", ociQuoteCommand([]string{"/bin/sh", "-c", "echo hello"}))
+	assert.Equal(t, `echo "it's"`, ociQuoteCommand([]string{"echo", "it's"}))
+	assert.Equal(t, "echo ''", ociQuoteCommand([]string{"echo", ""}))
+}
+
+func TestOCIResolveUser(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "lxd_oci_test_")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootfs)
+
+	require.NoError(t, os.Mkdir(filepath.Join(rootfs, "etc"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootfs, "etc", "passwd"), []byte("root:x:0:0:root:/root:/bin/sh\napp:x:1000:1001::/home/app:/bin/sh\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootfs, "etc", "group"), []byte("root:x:0:\napp:x:1001:\nstaff:x:50:\n"), 0644))
+
+	tests := []struct {
+		user     string
+		expected string
+	}{
+		{"app", "1000:1001"},
+		{"1000", "1000:1001"},
+		{"app:staff", "1000:50"},
+		{"2000", "2000:2000"},
+		{"2000:3000", "2000:3000"},
+	}
+
+	for _, test := range tests {
+		user, err := ociResolveUser(rootfs, test.user)
+		require.NoError(t, err, test.user)
+		assert.Equal(t, test.expected, user, test.user)
+	}
+
+	_, err = ociResolveUser(rootfs, "nobody")
+	assert.Error(t, err)
+
+	_, err = ociResolveUser(rootfs, "app:nogroup")
+	assert.Error(t, err)
+}
+
+func TestOCIInstanceConfig(t *testing.T) {
+	properties := map[string]string{
+		"description":     "library/nginx:latest",
+		"oci.digest":      "sha256:abcd",
+		"oci.entrypoint":  "/docker-entrypoint.sh nginx -g 'daemon off;'",
+		"oci.user":        "101:101",
+		"oci.cwd":         "/srv",
+		"oci.stop_signal": "SIGQUIT",
+		"oci.env.PATH":    "/usr/bin:/bin",
+		"oci.env.NGINX":   "1.19",
+	}
+
+	config := map[string]string{"environment.NGINX": "custom", "oci.cwd": "/"}
+	require.NoError(t, ociInstanceConfig(properties, config))
+
+	assert.Equal(t, map[string]string{
+		"environment.PATH":  "/usr/bin:/bin",
+		"environment.NGINX": "custom",
+		"oci.entrypoint":    "/docker-entrypoint.sh nginx -g 'daemon off;'",
+		"oci.uid":           "101",
+		"oci.gid":           "101",
+		"oci.cwd":           "/",
+		"oci.stop_signal":   "SIGQUIT",
+	}, config)
+
+	// Images without runtime configuration don't change anything
+	config = map[string]string{}
+	require.NoError(t, ociInstanceConfig(map[string]string{"os": "ubuntu"}, config))
+	assert.Empty(t, config)
+
+	// Control characters are rejected
+	for key, value := range map[string]string{
+		"oci.entrypoint":  "/bin/sh\nlxc.hook.pre-start = /tmp/evil",
+		"oci.cwd":         "/srv\rx",
+		"oci.env.PATH":    "/bin\n",
+		"oci.env.A\nB":    "x",
+		"oci.stop_signal": "SIGTERM\n",
+		"oci.user":        "0:0\n",
+	} {
+		err := ociInstanceConfig(map[string]string{"oci.digest": "sha256:abcd", key: value}, map[string]string{})
+		assert.Error(t, err, key)
+	}
+
+	// Invalid values are rejected
+	err := ociInstanceConfig(map[string]string{"oci.digest": "sha256:abcd", "oci.user": "root:root"}, map[string]string{})
+	assert.Error(t, err)
+}
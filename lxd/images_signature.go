@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// imageTrustedKeys returns the keys trusted to sign images and whether images
+// failing verification must be rejected.
+func imageTrustedKeys(d *Daemon) ([]crypto.PublicKey, bool, error) {
+	var value string
+	var enforce bool
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		value = config.ImagesTrustedKeys()
+		enforce = config.ImagesSignatureEnforced()
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	keys, err := util.ParseTrustedKeys(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return keys, enforce, nil
+}
+
+// imageCheckSignature verifies the signature of an image about to be added to
+// the store. Unless verification is enforced, failures are only logged.
+func imageCheckSignature(d *Daemon, fingerprint string, signature string) error {
+	keys, enforce, err := imageTrustedKeys(d)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to verify against
+	if len(keys) == 0 && !enforce {
+		return nil
+	}
+
+	err = util.ImageVerifySignature(keys, fingerprint, signature)
+	if err != nil {
+		if enforce {
+			return err
+		}
+
+		logger.Warn("Image signature verification failed", log.Ctx{"image": fingerprint, "err": err})
+	}
+
+	return nil
+}
+
+// imageSign signs the image with the given fingerprint using the server key.
+func imageSign(d *Daemon, fingerprint string) (string, error) {
+	signer, ok := d.endpoints.NetworkCert().KeyPair().PrivateKey.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("The server key can't be used for signing")
+	}
+
+	return util.ImageSign(signer, fingerprint)
+}
+
+// imageFillSignatureStatus records whether the image is signed by one of the
+// trusted keys.
+func imageFillSignatureStatus(keys []crypto.PublicKey, image *api.Image) {
+	if image.Signature == "" {
+		image.SignatureStatus = "unsigned"
+	} else if util.ImageVerifySignature(keys, image.Fingerprint, image.Signature) == nil {
+		image.SignatureStatus = "trusted"
+	} else {
+		image.SignatureStatus = "untrusted"
+	}
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/simplestreams"
+)
+
+// The simplestreams feed is served outside of the versioned API, at the paths the simplestreams
+// client expects relative to the remote URL. The default project is published at the root of
+// the HTTPS listener and other projects under /streams/projects/<name>.
+var streamsIndexCmd = APIEndpoint{
+	Name: "streamsIndex",
+	Path: "v1/index.json",
+	Aliases: []APIEndpointAlias{
+		{Name: "projectStreamsIndex", Path: "projects/{project}/streams/v1/index.json"},
+	},
+
+	Get: APIEndpointAction{Handler: streamsIndexGet, AllowUntrusted: true},
+}
+
+var streamsImagesCmd = APIEndpoint{
+	Name: "streamsImages",
+	Path: "v1/images.json",
+	Aliases: []APIEndpointAlias{
+		{Name: "projectStreamsImages", Path: "projects/{project}/streams/v1/images.json"},
+	},
+
+	Get: APIEndpointAction{Handler: streamsImagesGet, AllowUntrusted: true},
+}
+
+var streamsImageFileCmd = APIEndpoint{
+	Name: "streamsImageFile",
+	Path: "v1/images/{fingerprint}/{file}",
+	Aliases: []APIEndpointAlias{
+		{Name: "projectStreamsImageFile", Path: "projects/{project}/streams/v1/images/{fingerprint}/{file}"},
+	},
+
+	Get: APIEndpointAction{Handler: streamsImageFileGet, AllowUntrusted: true},
+}
+
+var apiStreams = []APIEndpoint{
+	streamsIndexCmd,
+	streamsImagesCmd,
+	streamsImageFileCmd,
+}
+
+// streamsProductsPath is the path of the products file relative to the remote URL.
+const streamsProductsPath = "streams/v1/images.json"
+
+var streamsFingerprintRegexp = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// streamsHashes caches the SHA256 of image files, which never change for a given fingerprint.
+var streamsHashes = map[string]string{}
+var streamsHashesLock sync.Mutex
+
+// streamsDeltas tracks the delta files being generated in the background.
+var streamsDeltas = map[string]bool{}
+var streamsDeltasLock sync.Mutex
+
+// jsonResponse renders a raw JSON document rather than a LXD API response.
+type jsonResponse struct {
+	data interface{}
+}
+
+func (r *jsonResponse) Render(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(r.data)
+}
+
+func (r *jsonResponse) String() string {
+	return "json document"
+}
+
+// streamsProject returns the project a feed request is for, checking the feed is enabled.
+func streamsProject(d *Daemon, r *http.Request) (string, error) {
+	var enabled bool
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		enabled = config.ImagesSimplestreamsFeed()
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !enabled {
+		return "", db.ErrNoSuchObject
+	}
+
+	project := mux.Vars(r)["project"]
+	if project == "" {
+		project = "default"
+	}
+
+	return project, nil
+}
+
+// streamsImageFile represents one downloadable file of a published image.
+type streamsImageFile struct {
+	name string
+	path string
+}
+
+// streamsImageFiles returns the downloadable metadata and root files of a local image, the root
+// being empty for unified images, along with the simplestreams file type of the root.
+func streamsImageFiles(image *api.Image) (streamsImageFile, streamsImageFile, string) {
+	imagePath := shared.VarPath("images", image.Fingerprint)
+	rootfsPath := imagePath + ".rootfs"
+
+	_, ext, _, err := shared.DetectCompression(imagePath)
+	if err != nil {
+		ext = ""
+	}
+
+	if !shared.PathExists(rootfsPath) {
+		return streamsImageFile{name: "lxd_combined" + ext, path: imagePath}, streamsImageFile{}, ""
+	}
+
+	meta := streamsImageFile{name: "lxd" + ext, path: imagePath}
+	if image.Type == "virtual-machine" {
+		return meta, streamsImageFile{name: "root.img", path: rootfsPath}, "disk-kvm.img"
+	}
+
+	_, ext, _, err = shared.DetectCompression(rootfsPath)
+	if err != nil {
+		ext = ""
+	}
+
+	if ext == ".squashfs" {
+		return meta, streamsImageFile{name: "root.squashfs", path: rootfsPath}, "squashfs"
+	}
+
+	return meta, streamsImageFile{name: "root" + ext, path: rootfsPath}, "root.tar.xz"
+}
+
+// streamsFileHash returns the SHA256 and size of a file, caching the hash.
+func streamsFileHash(path string) (string, int64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", -1, err
+	}
+
+	streamsHashesLock.Lock()
+	hash, ok := streamsHashes[path]
+	streamsHashesLock.Unlock()
+	if ok {
+		return hash, fi.Size(), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", -1, err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, f)
+	if err != nil {
+		return "", -1, err
+	}
+
+	hash = fmt.Sprintf("%x", hasher.Sum(nil))
+
+	streamsHashesLock.Lock()
+	streamsHashes[path] = hash
+	streamsHashesLock.Unlock()
+
+	return hash, fi.Size(), nil
+}
+
+// streamsDeltaPath returns the path of the delta from the root of srcFingerprint to the root of
+// fingerprint.
+func streamsDeltaPath(fingerprint string, srcFingerprint string) string {
+	return shared.VarPath("images", fmt.Sprintf("%s.delta-%s", fingerprint, srcFingerprint))
+}
+
+// streamsDeltaGenerate creates the delta between two squashfs roots in the background so that it
+// can be published the next time the feed is requested.
+func streamsDeltaGenerate(fingerprint string, srcFingerprint string) {
+	_, err := exec.LookPath("xdelta3")
+	if err != nil {
+		return
+	}
+
+	deltaPath := streamsDeltaPath(fingerprint, srcFingerprint)
+
+	streamsDeltasLock.Lock()
+	if streamsDeltas[deltaPath] {
+		streamsDeltasLock.Unlock()
+		return
+	}
+	streamsDeltas[deltaPath] = true
+	streamsDeltasLock.Unlock()
+
+	go func() {
+		defer func() {
+			streamsDeltasLock.Lock()
+			delete(streamsDeltas, deltaPath)
+			streamsDeltasLock.Unlock()
+		}()
+
+		srcPath := shared.VarPath("images", srcFingerprint+".rootfs")
+		dstPath := shared.VarPath("images", fingerprint+".rootfs")
+		tmpPath := deltaPath + ".tmp"
+
+		_, err := shared.RunCommand("xdelta3", "-f", "-e", "-s", srcPath, dstPath, tmpPath)
+		if err != nil {
+			os.Remove(tmpPath)
+			logger.Warn("Failed generating image delta", log.Ctx{"fingerprint": fingerprint, "source": srcFingerprint, "err": err})
+			return
+		}
+
+		err = os.Rename(tmpPath, deltaPath)
+		if err != nil {
+			os.Remove(tmpPath)
+			logger.Warn("Failed generating image delta", log.Ctx{"fingerprint": fingerprint, "source": srcFingerprint, "err": err})
+		}
+	}()
+}
+
+// streamsPublicImages returns the public images of a project which are available on this node.
+func streamsPublicImages(d *Daemon, project string) ([]*api.Image, error) {
+	fingerprints, err := d.cluster.ImagesGet(project, true)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []*api.Image{}
+	for _, fingerprint := range fingerprints {
+		image, resp := doImageGet(d.cluster, project, fingerprint, true)
+		if resp != nil {
+			continue
+		}
+
+		// In a cluster, each node publishes the images it holds.
+		if !shared.PathExists(shared.VarPath("images", image.Fingerprint)) {
+			continue
+		}
+
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+// streamsProductName groups the versions of an image by operating system, release, variant and
+// architecture. Images lacking those properties get a product of their own.
+func streamsProductName(image *api.Image) string {
+	if image.Properties["os"] == "" || image.Properties["release"] == "" {
+		return image.Fingerprint
+	}
+
+	fields := []string{image.Properties["os"], image.Properties["release"], image.Architecture}
+	if image.Properties["variant"] != "" {
+		fields = append(fields, image.Properties["variant"])
+	}
+
+	return strings.ToLower(strings.Join(fields, ":"))
+}
+
+// streamsVersionName returns the simplestreams version of an image, which must start with its
+// creation date.
+func streamsVersionName(image *api.Image) string {
+	createdAt := image.CreatedAt
+	if createdAt.Unix() <= 0 {
+		createdAt = image.UploadedAt
+	}
+
+	return createdAt.UTC().Format("20060102_150405")
+}
+
+// streamsProducts builds the simplestreams products of the public images of a project.
+func streamsProducts(d *Daemon, project string) (*simplestreams.Products, error) {
+	images, err := streamsPublicImages(d, project)
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first, so each version can refer to the previous one for deltas.
+	sort.Slice(images, func(i, j int) bool {
+		return streamsVersionName(images[i]) < streamsVersionName(images[j])
+	})
+
+	products := simplestreams.Products{
+		ContentID: "images",
+		DataType:  "image-downloads",
+		Format:    "products:1.0",
+		Products:  map[string]simplestreams.Product{},
+		Updated:   time.Now().UTC().Format(time.RFC1123Z),
+	}
+
+	// Last squashfs image seen for each product, used as the base of deltas.
+	deltaBases := map[string]*api.Image{}
+
+	for _, image := range images {
+		productName := streamsProductName(image)
+		product, ok := products.Products[productName]
+		if !ok {
+			product = simplestreams.Product{
+				Architecture:    image.Architecture,
+				OperatingSystem: image.Properties["os"],
+				Release:         image.Properties["release"],
+				ReleaseTitle:    image.Properties["release"],
+				Version:         image.Properties["version"],
+				Versions:        map[string]simplestreams.ProductVersion{},
+			}
+		}
+
+		// Aliases apply to the whole product, the client assigns them to its latest version.
+		aliases := []string{}
+		if product.Aliases != "" {
+			aliases = strings.Split(product.Aliases, ",")
+		}
+
+		for _, alias := range image.Aliases {
+			if !shared.StringInSlice(alias.Name, aliases) {
+				aliases = append(aliases, alias.Name)
+			}
+		}
+
+		product.Aliases = strings.Join(aliases, ",")
+
+		versionName := streamsVersionName(image)
+		version := simplestreams.ProductVersion{
+			Items: map[string]simplestreams.ProductVersionItem{},
+			Label: image.Properties["label"],
+		}
+
+		filePath := func(name string) string {
+			return fmt.Sprintf("streams/v1/images/%s/%s", image.Fingerprint, name)
+		}
+
+		meta, root, rootType := streamsImageFiles(image)
+		metaHash, metaSize, err := streamsFileHash(meta.path)
+		if err != nil {
+			return nil, err
+		}
+
+		if rootType == "" {
+			version.Items[meta.name] = simplestreams.ProductVersionItem{
+				FileType:   "lxd_combined.tar.gz",
+				HashSha256: metaHash,
+				Path:       filePath(meta.name),
+				Size:       metaSize,
+			}
+		} else {
+			rootHash, rootSize, err := streamsFileHash(root.path)
+			if err != nil {
+				return nil, err
+			}
+
+			metaItem := simplestreams.ProductVersionItem{
+				FileType:   "lxd.tar.xz",
+				HashSha256: metaHash,
+				Path:       filePath(meta.name),
+				Size:       metaSize,
+			}
+
+			switch rootType {
+			case "squashfs":
+				metaItem.LXDHashSha256SquashFs = image.Fingerprint
+			case "disk-kvm.img":
+				metaItem.LXDHashSha256DiskKvmImg = image.Fingerprint
+			default:
+				metaItem.LXDHashSha256RootXz = image.Fingerprint
+			}
+
+			version.Items[meta.name] = metaItem
+			version.Items[root.name] = simplestreams.ProductVersionItem{
+				FileType:   rootType,
+				HashSha256: rootHash,
+				Path:       filePath(root.name),
+				Size:       rootSize,
+			}
+
+			// Publish the delta from the previous version, generating it if needed.
+			if rootType == "squashfs" {
+				base := deltaBases[productName]
+				if base != nil {
+					deltaPath := streamsDeltaPath(image.Fingerprint, base.Fingerprint)
+					if shared.PathExists(deltaPath) {
+						deltaHash, deltaSize, err := streamsFileHash(deltaPath)
+						if err != nil {
+							return nil, err
+						}
+
+						deltaName := fmt.Sprintf("%s.vcdiff", base.Fingerprint)
+						version.Items[deltaName] = simplestreams.ProductVersionItem{
+							FileType:   "squashfs.vcdiff",
+							HashSha256: deltaHash,
+							Path:       filePath(deltaName),
+							Size:       deltaSize,
+							DeltaBase:  streamsVersionName(base),
+						}
+					} else {
+						streamsDeltaGenerate(image.Fingerprint, base.Fingerprint)
+					}
+				}
+
+				deltaBases[productName] = image
+			}
+		}
+
+		product.Versions[versionName] = version
+		products.Products[productName] = product
+	}
+
+	return &products, nil
+}
+
+func streamsIndexGet(d *Daemon, r *http.Request) response.Response {
+	project, err := streamsProject(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	products, err := streamsProducts(d, project)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	productNames := []string{}
+	for name := range products.Products {
+		productNames = append(productNames, name)
+	}
+
+	sort.Strings(productNames)
+
+	stream := simplestreams.Stream{
+		Format:  "index:1.0",
+		Updated: products.Updated,
+		Index: map[string]simplestreams.StreamIndex{
+			"images": {
+				DataType: products.DataType,
+				Path:     streamsProductsPath,
+				Format:   products.Format,
+				Updated:  products.Updated,
+				Products: productNames,
+			},
+		},
+	}
+
+	return &jsonResponse{data: stream}
+}
+
+func streamsImagesGet(d *Daemon, r *http.Request) response.Response {
+	project, err := streamsProject(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	products, err := streamsProducts(d, project)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return &jsonResponse{data: products}
+}
+
+func streamsImageFileGet(d *Daemon, r *http.Request) response.Response {
+	project, err := streamsProject(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	fingerprint := mux.Vars(r)["fingerprint"]
+	fileName := mux.Vars(r)["file"]
+
+	// Only serve full fingerprints of public images.
+	if !streamsFingerprintRegexp.MatchString(fingerprint) {
+		return response.NotFound(fmt.Errorf("Image '%s' not found", fingerprint))
+	}
+
+	image, resp := doImageGet(d.cluster, project, fingerprint, true)
+	if resp != nil {
+		return resp
+	}
+
+	var path string
+	meta, root, _ := streamsImageFiles(image)
+	switch {
+	case fileName == meta.name:
+		path = meta.path
+	case root.name != "" && fileName == root.name:
+		path = root.path
+	case strings.HasSuffix(fileName, ".vcdiff"):
+		srcFingerprint := strings.TrimSuffix(fileName, ".vcdiff")
+		if streamsFingerprintRegexp.MatchString(srcFingerprint) {
+			path = streamsDeltaPath(fingerprint, srcFingerprint)
+		}
+	}
+
+	if path == "" || !shared.PathExists(path) {
+		return response.NotFound(fmt.Errorf("File '%s' not found", fileName))
+	}
+
+	files := []response.FileResponseEntry{{
+		Identifier: fileName,
+		Path:       path,
+		Filename:   fileName,
+	}}
+
+	return response.FileResponse(r, files, nil, false)
+}
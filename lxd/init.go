@@ -18,6 +18,7 @@ type initDataNode struct {
 
 type initDataCluster struct {
 	api.ClusterPut `yaml:",inline"`
+	ClusterToken   string `json:"cluster_token" yaml:"cluster_token"`
 }
 
 // Helper to initialize node-specific entities on a LXD instance using the
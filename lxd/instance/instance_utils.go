@@ -765,6 +765,16 @@ func SuitableArchitectures(s *state.State, project string, req api.InstancesPost
 				}
 			} else if req.Source.Protocol == "simplestreams" {
 				// Remote simplestreams image server.
+			} else if req.Source.Protocol == "oci" {
+				// Remote OCI registry.
+				remote, err = lxd.ConnectOCI(req.Source.Server, &lxd.ConnectionArgs{
+					TLSServerCert: req.Source.Certificate,
+					UserAgent:     version.UserAgent,
+					Proxy:         s.Proxy,
+				})
+				if err != nil {
+					return nil, err
+				}
 			} else {
 				return nil, fmt.Errorf("Unsupported remote image server protocol: %s", req.Source.Protocol)
 			}
@@ -32,6 +32,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/instance/qemu/qmp"
 	"github.com/lxc/lxd/lxd/maas"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
@@ -216,7 +217,7 @@ func Create(s *state.State, args db.InstanceArgs) (instance.Instance, error) {
 	}
 
 	// Create a new database entry for the instance's storage volume.
-	_, err = s.Cluster.StoragePoolVolumeCreate(args.Project, args.Name, "", db.StoragePoolVolumeTypeVM, false, poolID, volumeConfig)
+	_, err = s.Cluster.StoragePoolVolumeCreate(args.Project, args.Name, "", db.StoragePoolVolumeTypeVM, false, poolID, volumeConfig, db.StoragePoolVolumeContentTypeBlock)
 	if err != nil {
 		return nil, err
 	}
@@ -1143,6 +1144,9 @@ backend = "pty"
 	vm.addMonitorConfig(sb)
 	vm.addConfDriveConfig(sb)
 
+	// Drive index starts at 1 for supplementary drives, as root drive uses index 0.
+	driveIndex := 0
+
 	for _, runConf := range devConfs {
 		// Add root drive device.
 		if runConf.RootFS.Path != "" {
@@ -1154,9 +1158,7 @@ backend = "pty"
 
 		// Add drive devices.
 		if len(runConf.Mounts) > 0 {
-			driveIndex := 0
 			for _, drive := range runConf.Mounts {
-				// Increment so index starts at 1, as root drive uses index 0.
 				driveIndex++
 
 				vm.addDriveConfig(sb, driveIndex, drive)
@@ -1347,6 +1349,11 @@ bootindex = "1"
 func (vm *Qemu) addDriveConfig(sb *strings.Builder, driveIndex int, driveConf deviceConfig.MountEntryItem) {
 	driveName := fmt.Sprintf(driveConf.TargetPath)
 
+	readonly := "off"
+	if shared.StringInSlice("ro", driveConf.Opts) {
+		readonly = "on"
+	}
+
 	// Devices use "lxd_" prefix indicating that this is a user named device.
 	sb.WriteString(fmt.Sprintf(`
 # %s drive
@@ -1356,6 +1363,7 @@ format = "raw"
 if = "none"
 cache = "none"
 aio = "native"
+readonly = "%s"
 
 [device "dev-lxd_%s"]
 driver = "scsi-hd"
@@ -1364,7 +1372,7 @@ channel = "0"
 scsi-id = "%d"
 lun = "1"
 drive = "lxd_%s"
-`, driveName, driveName, driveConf.DevPath, driveName, driveIndex, driveName))
+`, driveName, driveName, driveConf.DevPath, readonly, driveName, driveIndex, driveName))
 
 	return
 }
@@ -2773,6 +2781,44 @@ func (vm *Qemu) agentGetState() (*api.InstanceState, error) {
 	return status, nil
 }
 
+// AgentDiskStats returns the statistics of the disks of the VM, as reported by
+// its agent.
+func (vm *Qemu) AgentDiskStats() (map[string]metrics.DiskStats, error) {
+	// Check if the agent is running.
+	monitor, err := qmp.Connect(vm.getMonitorPath(), vm.getMonitorEventHandler())
+	if err != nil {
+		return nil, err
+	}
+
+	if !monitor.AgentReady() {
+		return nil, errQemuAgentOffline
+	}
+
+	client, err := vm.getAgentClient()
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := lxdClient.ConnectLXDHTTP(nil, client)
+	if err != nil {
+		return nil, err
+	}
+	defer agent.Disconnect()
+
+	resp, _, err := agent.RawQuery("GET", "/1.0/metrics", nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[string]metrics.DiskStats{}
+	err = resp.MetadataAsStruct(&stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
 // IsRunning returns whether or not the instance is running.
 func (vm *Qemu) IsRunning() bool {
 	state := vm.State()
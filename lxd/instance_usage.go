@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/usage"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// This task function samples the resource usage of the local instances into
+// the usage history. It's started by the Daemon and runs at the interval set
+// with core.usage_history_interval.
+func instanceUsageTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		instances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			logger.Warn("Failed to load instances for usage history", log.Ctx{"err": err})
+			return
+		}
+
+		seen := map[[2]string]bool{}
+		for _, inst := range instances {
+			seen[[2]string{inst.Project(), inst.Name()}] = true
+
+			if !inst.IsRunning() {
+				d.usageHistory.Reset(inst.Project(), inst.Name())
+				continue
+			}
+
+			u, err := instanceUsage(inst)
+			if err != nil {
+				logger.Debug("Failed to get instance usage", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+				d.usageHistory.Reset(inst.Project(), inst.Name())
+				continue
+			}
+
+			d.usageHistory.Record(inst.Project(), inst.Name(), u)
+		}
+
+		// Forget about the deleted and renamed instances.
+		d.usageHistory.Retain(func(project string, name string) bool {
+			return seen[[2]string{project, name}]
+		})
+	}
+
+	schedule := func() (time.Duration, error) {
+		var interval time.Duration
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			config, err := cluster.ConfigLoad(tx)
+			if err != nil {
+				return errors.Wrap(err, "failed to load cluster configuration")
+			}
+
+			interval, _ = config.UsageHistory()
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		return interval, nil
+	}
+
+	return f, schedule
+}
+
+// Returns the current resource usage of a running instance.
+func instanceUsage(inst instance.Instance) (usage.Usage, error) {
+	state, err := inst.RenderState()
+	if err != nil {
+		return usage.Usage{}, err
+	}
+
+	u := usage.Usage{
+		Time:        time.Now(),
+		CPUTime:     state.CPU.Usage,
+		MemoryUsage: state.Memory.Usage,
+		SwapUsage:   state.Memory.SwapUsage,
+		DiskRead:    -1,
+		DiskWritten: -1,
+		Processes:   state.Processes,
+	}
+
+	for _, nic := range state.Network {
+		if nic.Type == "loopback" {
+			continue
+		}
+
+		u.NetworkReceived += nic.Counters.BytesReceived
+		u.NetworkSent += nic.Counters.BytesSent
+	}
+
+	// The disk activity is only known for containers.
+	c, ok := inst.(*containerLXC)
+	if ok {
+		u.DiskRead, u.DiskWritten = c.ioState()
+	}
+
+	return u, nil
+}
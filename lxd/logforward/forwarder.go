@@ -0,0 +1,295 @@
+// Package logforward sends the logs of LXD and of its instances to a remote
+// collector.
+package logforward
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Protocols the records can be forwarded with.
+const (
+	ProtocolSyslogTCP = "syslog-tcp"
+	ProtocolSyslogUDP = "syslog-udp"
+	ProtocolLoki      = "loki"
+	ProtocolHTTP      = "http"
+)
+
+// Protocols lists all the supported protocols.
+var Protocols = []string{ProtocolSyslogTCP, ProtocolSyslogUDP, ProtocolLoki, ProtocolHTTP}
+
+// Streams of records which can be forwarded.
+const (
+	StreamDaemon    = "daemon"
+	StreamLifecycle = "lifecycle"
+	StreamConsole   = "console"
+	StreamAudit     = "audit"
+)
+
+// Streams lists all the supported streams.
+var Streams = []string{StreamDaemon, StreamLifecycle, StreamConsole, StreamAudit}
+
+// Number of records kept around while the endpoint is unreachable, the
+// oldest ones being dropped first, and maximum number of records sent at
+// once.
+const (
+	maxQueueSize = 10000
+	maxBatchSize = 100
+)
+
+// Delays between attempts to send records to an unreachable endpoint.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// Record is a log line to forward.
+type Record struct {
+	Time     time.Time         `json:"timestamp"`
+	Stream   string            `json:"stream"`
+	Level    string            `json:"level,omitempty"`
+	Message  string            `json:"message"`
+	Member   string            `json:"member"`
+	Project  string            `json:"project,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Context  map[string]string `json:"context,omitempty"`
+}
+
+// Config holds the settings of a Forwarder.
+type Config struct {
+	// Protocol to forward the records with, forwarding being disabled if
+	// empty.
+	Protocol string
+
+	// Address or URL to send the records to, depending on the protocol.
+	Endpoint string
+
+	// Streams to forward.
+	Streams []string
+
+	// Name of this cluster member, used to tag the records.
+	Member string
+}
+
+// Forwarder queues records and sends them to a remote endpoint in the
+// background, retrying while the endpoint is unreachable.
+type Forwarder struct {
+	config Config
+	sender sender
+	queue  []Record
+
+	// Number of records dropped since the last successful send, and
+	// since the current batch was taken from the queue.
+	dropped      int
+	droppedBatch int
+
+	wakeup chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu sync.Mutex
+}
+
+// sender sends a batch of records to an endpoint.
+type sender interface {
+	Send(records []Record) error
+	Close() error
+}
+
+// NewForwarder returns a new forwarder, initially disabled.
+func NewForwarder() *Forwarder {
+	return &Forwarder{}
+}
+
+// Configure applies the given configuration, discarding the queued records if
+// the protocol or endpoint changes.
+func (f *Forwarder) Configure(config Config) error {
+	for _, stream := range config.Streams {
+		if !shared.StringInSlice(stream, Streams) {
+			return fmt.Errorf("Invalid log stream %q", stream)
+		}
+	}
+
+	// Keep the queue if only the streams or member name change.
+	f.mu.Lock()
+	if f.sender != nil && f.config.Protocol == config.Protocol && f.config.Endpoint == config.Endpoint {
+		f.config = config
+		f.mu.Unlock()
+		return nil
+	}
+	f.mu.Unlock()
+
+	var s sender
+	if config.Protocol != "" {
+		var err error
+		s, err = newSender(config.Protocol, config.Endpoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.Close()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.config = config
+	f.sender = s
+	f.dropped = 0
+	if s == nil {
+		return nil
+	}
+
+	f.wakeup = make(chan struct{}, 1)
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+	go f.run(s, f.wakeup, f.stop, f.done)
+
+	return nil
+}
+
+// Wants returns whether records of the given stream are forwarded.
+func (f *Forwarder) Wants(stream string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.sender != nil && shared.StringInSlice(stream, f.config.Streams)
+}
+
+// Send queues the given record for forwarding, if its stream is forwarded.
+// It never blocks.
+func (f *Forwarder) Send(record Record) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.sender == nil || !shared.StringInSlice(record.Stream, f.config.Streams) {
+		return
+	}
+
+	record.Member = f.config.Member
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	if len(f.queue) >= maxQueueSize {
+		f.queue = f.queue[1:]
+		f.dropped++
+		f.droppedBatch++
+	}
+	f.queue = append(f.queue, record)
+
+	select {
+	case f.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops forwarding, discarding the queued records.
+func (f *Forwarder) Close() {
+	f.mu.Lock()
+	s := f.sender
+	stop := f.stop
+	done := f.done
+	f.sender = nil
+	f.queue = nil
+	f.mu.Unlock()
+
+	if s == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+	s.Close()
+}
+
+// Send the queued records until stopped, backing off while the endpoint is
+// unreachable.
+func (f *Forwarder) run(s sender, wakeup chan struct{}, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	delay := time.Duration(0)
+	failing := false
+	for {
+		if delay > 0 {
+			select {
+			case <-stop:
+				return
+			case <-time.After(delay):
+			}
+		} else {
+			select {
+			case <-stop:
+				return
+			case <-wakeup:
+			}
+		}
+
+		for {
+			f.mu.Lock()
+			n := len(f.queue)
+			if n > maxBatchSize {
+				n = maxBatchSize
+			}
+			batch := make([]Record, n)
+			copy(batch, f.queue)
+			f.droppedBatch = 0
+			f.mu.Unlock()
+
+			if n == 0 {
+				delay = 0
+				break
+			}
+
+			err := s.Send(batch)
+			if err != nil {
+				// Logging happens without the lock held, as daemon
+				// log messages are themselves forwarded.
+				if !failing {
+					logger.Warnf("Failed to forward logs, will retry: %v", err)
+					failing = true
+				}
+
+				if delay == 0 {
+					delay = minRetryDelay
+				} else if delay < maxRetryDelay {
+					delay *= 2
+					if delay > maxRetryDelay {
+						delay = maxRetryDelay
+					}
+				}
+
+				break
+			}
+
+			// Records may have been dropped from the head of the
+			// queue while sending.
+			f.mu.Lock()
+			sent := n - f.droppedBatch
+			if sent > len(f.queue) {
+				sent = len(f.queue)
+			}
+			if sent > 0 {
+				f.queue = f.queue[sent:]
+			}
+			dropped := f.dropped
+			f.dropped = 0
+			f.mu.Unlock()
+
+			if failing {
+				logger.Infof("Forwarding logs again")
+				failing = false
+			}
+
+			if dropped > 0 {
+				logger.Warnf("Dropped %d log records while the forwarding endpoint was unreachable", dropped)
+			}
+
+			delay = 0
+		}
+	}
+}
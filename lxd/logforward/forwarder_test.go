@@ -0,0 +1,173 @@
+package logforward
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// An HTTP endpoint recording the bodies it receives, failing the first
+// requests if asked to.
+type testEndpoint struct {
+	server   *httptest.Server
+	failures int
+	bodies   chan []byte
+	mu       sync.Mutex
+}
+
+func newTestEndpoint(failures int) *testEndpoint {
+	e := &testEndpoint{failures: failures, bodies: make(chan []byte, 10)}
+	e.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		fail := e.failures > 0
+		e.failures--
+		e.mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		e.bodies <- body
+	}))
+
+	return e
+}
+
+func (e *testEndpoint) next(t *testing.T) []byte {
+	select {
+	case body := <-e.bodies:
+		return body
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for forwarded records")
+		return nil
+	}
+}
+
+func TestForwarderHTTP(t *testing.T) {
+	endpoint := newTestEndpoint(0)
+	defer endpoint.server.Close()
+
+	f := NewForwarder()
+	defer f.Close()
+
+	err := f.Configure(Config{
+		Protocol: ProtocolHTTP,
+		Endpoint: endpoint.server.URL,
+		Streams:  []string{StreamLifecycle},
+		Member:   "node1",
+	})
+	require.NoError(t, err)
+
+	assert.True(t, f.Wants(StreamLifecycle))
+	assert.False(t, f.Wants(StreamDaemon))
+
+	// Records of other streams are ignored.
+	f.Send(Record{Stream: StreamDaemon, Message: "ignored"})
+	f.Send(Record{Stream: StreamLifecycle, Message: "container-started", Project: "default", Instance: "c1"})
+
+	records := []Record{}
+	require.NoError(t, json.Unmarshal(endpoint.next(t), &records))
+	require.Len(t, records, 1)
+	assert.Equal(t, "container-started", records[0].Message)
+	assert.Equal(t, "node1", records[0].Member)
+	assert.Equal(t, "default", records[0].Project)
+	assert.Equal(t, "c1", records[0].Instance)
+	assert.False(t, records[0].Time.IsZero())
+}
+
+func TestForwarderRetry(t *testing.T) {
+	endpoint := newTestEndpoint(1)
+	defer endpoint.server.Close()
+
+	f := NewForwarder()
+	defer f.Close()
+
+	err := f.Configure(Config{Protocol: ProtocolHTTP, Endpoint: endpoint.server.URL, Streams: Streams})
+	require.NoError(t, err)
+
+	f.Send(Record{Stream: StreamDaemon, Message: "first"})
+
+	// The record is kept and sent again once the endpoint recovers.
+	records := []Record{}
+	require.NoError(t, json.Unmarshal(endpoint.next(t), &records))
+	require.Len(t, records, 1)
+	assert.Equal(t, "first", records[0].Message)
+}
+
+func TestForwarderLoki(t *testing.T) {
+	endpoint := newTestEndpoint(0)
+	defer endpoint.server.Close()
+
+	f := NewForwarder()
+	defer f.Close()
+
+	err := f.Configure(Config{Protocol: ProtocolLoki, Endpoint: endpoint.server.URL, Streams: Streams, Member: "node1"})
+	require.NoError(t, err)
+
+	f.Send(Record{Stream: StreamDaemon, Level: "warn", Message: "hello", Context: map[string]string{"err": "some error"}})
+
+	body := struct {
+		Streams []lokiStream `json:"streams"`
+	}{}
+	require.NoError(t, json.Unmarshal(endpoint.next(t), &body))
+	require.Len(t, body.Streams, 1)
+
+	stream := body.Streams[0]
+	assert.Equal(t, map[string]string{"app": "lxd", "stream": "daemon", "member": "node1", "level": "warn"}, stream.Stream)
+	require.Len(t, stream.Values, 1)
+	assert.Equal(t, `hello err="some error"`, stream.Values[0][1])
+}
+
+func TestForwarderSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	f := NewForwarder()
+	defer f.Close()
+
+	err = f.Configure(Config{Protocol: ProtocolSyslogUDP, Endpoint: conn.LocalAddr().String(), Streams: Streams, Member: "node1"})
+	require.NoError(t, err)
+
+	f.Send(Record{Stream: StreamConsole, Message: "login:", Project: "default", Instance: "c1"})
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	msg := string(buf[:n])
+	assert.True(t, strings.HasPrefix(msg, "<30>1 "), msg)
+	assert.Contains(t, msg, " node1 lxd ")
+	assert.True(t, strings.HasSuffix(msg, " console - login: project=default instance=c1"), msg)
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	f := NewForwarder()
+
+	assert.Error(t, f.Configure(Config{Protocol: "ftp", Endpoint: "example.com"}))
+	assert.Error(t, f.Configure(Config{Protocol: ProtocolLoki, Endpoint: "example.com:3100"}))
+	assert.Error(t, f.Configure(Config{Protocol: ProtocolSyslogTCP}))
+	assert.Error(t, f.Configure(Config{Protocol: ProtocolHTTP, Endpoint: "http://example.com", Streams: []string{"foo"}}))
+	assert.False(t, f.Wants(StreamDaemon))
+
+	address, err := syslogAddress("example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com:514", address)
+}
@@ -0,0 +1,283 @@
+package logforward
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Timeout of the connections to the endpoint and of each send.
+const sendTimeout = 10 * time.Second
+
+func newSender(protocol string, endpoint string) (sender, error) {
+	switch protocol {
+	case ProtocolSyslogTCP, ProtocolSyslogUDP:
+		address, err := syslogAddress(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		network := "tcp"
+		if protocol == ProtocolSyslogUDP {
+			network = "udp"
+		}
+
+		return &syslogSender{network: network, address: address}, nil
+	case ProtocolLoki, ProtocolHTTP:
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("Invalid log forwarding endpoint %q, expected a HTTP(S) URL", endpoint)
+		}
+
+		if protocol == ProtocolLoki {
+			if u.Path == "" || u.Path == "/" {
+				u.Path = "/loki/api/v1/push"
+			}
+
+			return &lokiSender{url: u.String(), client: &http.Client{Timeout: sendTimeout}}, nil
+		}
+
+		return &httpSender{url: u.String(), client: &http.Client{Timeout: sendTimeout}}, nil
+	default:
+		return nil, fmt.Errorf("Invalid log forwarding protocol %q", protocol)
+	}
+}
+
+// Returns the address of a syslog endpoint, using the standard port if none
+// is given.
+func syslogAddress(endpoint string) (string, error) {
+	if endpoint == "" {
+		return "", fmt.Errorf("A log forwarding endpoint is required")
+	}
+
+	_, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		endpoint = net.JoinHostPort(strings.Trim(endpoint, "[]"), "514")
+		_, _, err = net.SplitHostPort(endpoint)
+		if err != nil {
+			return "", fmt.Errorf("Invalid log forwarding endpoint %q: %v", endpoint, err)
+		}
+	}
+
+	return endpoint, nil
+}
+
+// Returns the text of a record, followed by its tags and context in logfmt.
+func recordLine(record Record, withTags bool) string {
+	var b strings.Builder
+	b.WriteString(record.Message)
+
+	field := func(key string, value string) {
+		if value == "" {
+			return
+		}
+
+		if strings.ContainsAny(value, " \"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+
+		fmt.Fprintf(&b, " %s=%s", key, value)
+	}
+
+	if withTags {
+		field("project", record.Project)
+		field("instance", record.Instance)
+	}
+
+	keys := make([]string, 0, len(record.Context))
+	for key := range record.Context {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field(key, record.Context[key])
+	}
+
+	return b.String()
+}
+
+// syslogSender sends records as RFC5424 messages, with octet counting
+// framing over TCP (RFC6587) and one message per datagram over UDP.
+type syslogSender struct {
+	network string
+	address string
+	conn    net.Conn
+}
+
+// Severities of the log levels, the records without level being
+// informational.
+var syslogSeverities = map[string]int{
+	"crit": 2,
+	"eror": 3,
+	"warn": 4,
+	"info": 6,
+	"dbug": 7,
+}
+
+const syslogFacilityDaemon = 3
+
+func syslogMessage(record Record) string {
+	severity, ok := syslogSeverities[record.Level]
+	if !ok {
+		severity = 6
+	}
+
+	hostname := record.Member
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s lxd %d %s - %s",
+		syslogFacilityDaemon*8+severity,
+		record.Time.UTC().Format(time.RFC3339Nano),
+		strings.Replace(hostname, " ", "_", -1),
+		os.Getpid(),
+		record.Stream,
+		recordLine(record, true))
+}
+
+func (s *syslogSender) Send(records []Record) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, sendTimeout)
+		if err != nil {
+			return err
+		}
+
+		s.conn = conn
+	}
+
+	err := s.conn.SetWriteDeadline(time.Now().Add(sendTimeout))
+	if err != nil {
+		return s.fail(err)
+	}
+
+	for _, record := range records {
+		msg := syslogMessage(record)
+		if s.network == "tcp" {
+			msg = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+
+		_, err := io.WriteString(s.conn, msg)
+		if err != nil {
+			return s.fail(err)
+		}
+	}
+
+	return nil
+}
+
+// Drops the connection after an error, so that the next send reconnects.
+func (s *syslogSender) fail(err error) error {
+	s.conn.Close()
+	s.conn = nil
+
+	return err
+}
+
+func (s *syslogSender) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
+
+// lokiSender sends records to the push API of Loki, labelled with their
+// stream, member, project, instance and level.
+type lokiSender struct {
+	url    string
+	client *http.Client
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func lokiStreams(records []Record) []lokiStream {
+	streams := []lokiStream{}
+	index := map[string]int{}
+
+	for _, record := range records {
+		labels := map[string]string{
+			"app":    "lxd",
+			"stream": record.Stream,
+			"member": record.Member,
+		}
+
+		for key, value := range map[string]string{"project": record.Project, "instance": record.Instance, "level": record.Level} {
+			if value != "" {
+				labels[key] = value
+			}
+		}
+
+		key := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s", record.Stream, record.Member, record.Project, record.Instance, record.Level)
+		i, ok := index[key]
+		if !ok {
+			i = len(streams)
+			index[key] = i
+			streams = append(streams, lokiStream{Stream: labels})
+		}
+
+		value := [2]string{fmt.Sprintf("%d", record.Time.UnixNano()), recordLine(record, false)}
+		streams[i].Values = append(streams[i].Values, value)
+	}
+
+	return streams
+}
+
+func (s *lokiSender) Send(records []Record) error {
+	body := map[string]interface{}{"streams": lokiStreams(records)}
+	return postJSON(s.client, s.url, body)
+}
+
+func (s *lokiSender) Close() error {
+	return nil
+}
+
+// httpSender sends records as a JSON array.
+type httpSender struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSender) Send(records []Record) error {
+	return postJSON(s.client, s.url, records)
+}
+
+func (s *httpSender) Close() error {
+	return nil
+}
+
+func postJSON(client *http.Client, target string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(target, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
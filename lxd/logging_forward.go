@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/lxd/daemon"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/logforward"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Log files of the instances forwarded as part of the console stream.
+var logForwardConsoleFiles = []string{"console.log", "lxc.log", "qemu.log"}
+
+// Maximum amount of an instance log file read at once.
+const logForwardConsoleMaxRead = 1024 * 1024
+
+// logForwardEvent forwards the daemon log messages and the lifecycle events
+// sent on the local event server.
+func logForwardEvent(forwarder *logforward.Forwarder, group string, event api.Event) {
+	switch event.Type {
+	case "logging":
+		if !forwarder.Wants(logforward.StreamDaemon) {
+			return
+		}
+
+		entry := api.EventLogging{}
+		err := json.Unmarshal(event.Metadata, &entry)
+		if err != nil {
+			return
+		}
+
+		// Like the log file, only include debug messages in debug mode.
+		if entry.Level == "dbug" && !daemon.Debug {
+			return
+		}
+
+		forwarder.Send(logforward.Record{
+			Time:    event.Timestamp,
+			Stream:  logforward.StreamDaemon,
+			Level:   entry.Level,
+			Message: entry.Message,
+			Context: entry.Context,
+		})
+	case "lifecycle":
+		if !forwarder.Wants(logforward.StreamLifecycle) {
+			return
+		}
+
+		entry := api.EventLifecycle{}
+		err := json.Unmarshal(event.Metadata, &entry)
+		if err != nil {
+			return
+		}
+
+		forwarder.Send(logforward.Record{
+			Time:     event.Timestamp,
+			Stream:   logforward.StreamLifecycle,
+			Message:  entry.Action,
+			Project:  group,
+			Instance: logForwardInstanceName(entry.Source),
+			Context:  map[string]string{"source": entry.Source},
+		})
+	}
+}
+
+// logForwardAudit forwards an audit entry.
+func logForwardAudit(forwarder *logforward.Forwarder, entry api.EventAudit) {
+	record := logforward.Record{
+		Stream:  logforward.StreamAudit,
+		Message: entry.Method + " " + entry.URL,
+		Project: entry.Project,
+		Context: map[string]string{
+			"identity": entry.Identity,
+			"protocol": entry.Protocol,
+			"source":   entry.Source,
+			"result":   entry.Result,
+		},
+	}
+
+	if entry.Operation != "" {
+		record.Context["operation"] = entry.Operation
+	}
+
+	forwarder.Send(record)
+}
+
+// Returns the name of the instance the given API URL refers to, if any.
+func logForwardInstanceName(source string) string {
+	source = strings.SplitN(source, "?", 2)[0]
+	fields := strings.Split(strings.TrimPrefix(source, "/"), "/")
+	if len(fields) < 3 || fields[0] != "1.0" {
+		return ""
+	}
+
+	switch fields[1] {
+	case "containers", "instances", "virtual-machines":
+		return fields[2]
+	}
+
+	return ""
+}
+
+// logForwardConsoleTailer tracks how far the log files of the instances
+// have been forwarded.
+type logForwardConsoleTailer struct {
+	offsets map[string]int64
+	mu      sync.Mutex
+}
+
+// This task function forwards the new lines of the console and log files of
+// the local instances, if the console stream is forwarded. It's started by
+// the Daemon and runs every 10 seconds.
+func logForwardConsoleTask(d *Daemon) (task.Func, task.Schedule) {
+	tailer := &logForwardConsoleTailer{offsets: map[string]int64{}}
+
+	f := func(ctx context.Context) {
+		if !d.logForward.Wants(logforward.StreamConsole) {
+			// Start from the end of the files when enabled again.
+			tailer.mu.Lock()
+			tailer.offsets = map[string]int64{}
+			tailer.mu.Unlock()
+			return
+		}
+
+		instances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			logger.Warnf("Failed to load instances for log forwarding: %v", err)
+			return
+		}
+
+		tailer.mu.Lock()
+		defer tailer.mu.Unlock()
+
+		seen := map[string]bool{}
+		for _, inst := range instances {
+			for _, name := range logForwardConsoleFiles {
+				path := filepath.Join(inst.LogPath(), name)
+				seen[path] = true
+
+				lines, err := tailer.read(path)
+				if err != nil {
+					logger.Debugf("Failed to read %s for log forwarding: %v", path, err)
+					continue
+				}
+
+				for _, line := range lines {
+					d.logForward.Send(logforward.Record{
+						Stream:   logforward.StreamConsole,
+						Message:  line,
+						Project:  inst.Project(),
+						Instance: inst.Name(),
+						Context:  map[string]string{"file": name},
+					})
+				}
+			}
+		}
+
+		// Forget about the files of deleted instances.
+		for path := range tailer.offsets {
+			if !seen[path] {
+				delete(tailer.offsets, path)
+			}
+		}
+	}
+
+	return f, task.Every(10 * time.Second)
+}
+
+// Returns the complete lines added to the given file since the last call.
+// Files seen for the first time are read from their end, and files which
+// shrank from their start.
+func (t *logForwardConsoleTailer) read(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			delete(t.offsets, path)
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset, ok := t.offsets[path]
+	if !ok {
+		t.offsets[path] = info.Size()
+		return nil, nil
+	}
+
+	if info.Size() < offset {
+		offset = 0
+	}
+
+	if info.Size() == offset {
+		return nil, nil
+	}
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, logForwardConsoleMaxRead)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	buf = buf[:n]
+
+	// Leave incomplete lines for the next time, unless the buffer is full.
+	end := bytes.LastIndexByte(buf, '\n')
+	if end < 0 {
+		if n < logForwardConsoleMaxRead {
+			t.offsets[path] = offset
+			return nil, nil
+		}
+
+		end = n - 1
+	}
+
+	t.offsets[path] = offset + int64(end) + 1
+
+	lines := []string{}
+	for _, line := range strings.Split(string(buf[:end+1]), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, nil
+}
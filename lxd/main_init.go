@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/pem"
 	"fmt"
 
 	"github.com/pkg/errors"
@@ -9,6 +10,7 @@ import (
 	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 )
 
 type cmdInitData struct {
@@ -132,6 +134,19 @@ func (c *cmdInit) Run(cmd *cobra.Command, args []string) error {
 		config.Node.Config["cluster.https_address"] = config.Node.Config["core.https_address"]
 	}
 
+	// Expand a cluster join token into the details needed to join.
+	if config.Cluster != nil && config.Cluster.ClusterToken != "" {
+		joinToken, err := shared.JoinTokenDecode(config.Cluster.ClusterToken)
+		if err != nil {
+			return errors.Wrap(err, "Invalid cluster join token")
+		}
+
+		err = initDataClusterJoinToken(config.Cluster, joinToken)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Detect if the user has chosen to join a cluster using the new
 	// cluster join API format, and use the dedicated API if so.
 	if config.Cluster != nil && config.Cluster.ClusterAddress != "" && config.Cluster.ServerAddress != "" {
@@ -155,6 +170,34 @@ func (c *cmdInit) Run(cmd *cobra.Command, args []string) error {
 	return initDataClusterApply(d, config.Cluster)
 }
 
+// Fill in the details needed to join a cluster from the given join token,
+// using the first cluster member listed in the token which presents the
+// expected certificate.
+func initDataClusterJoinToken(config *initDataCluster, joinToken *api.ClusterMemberJoinToken) error {
+	if config.ServerName != "" && config.ServerName != joinToken.ServerName {
+		return fmt.Errorf("Join token was issued for member %q, not %q", joinToken.ServerName, config.ServerName)
+	}
+
+	for _, address := range joinToken.Addresses {
+		cert, err := shared.GetRemoteCertificate(fmt.Sprintf("https://%s", address))
+		if err != nil {
+			continue
+		}
+
+		if shared.CertFingerprint(cert) != joinToken.Fingerprint {
+			continue
+		}
+
+		config.ServerName = joinToken.ServerName
+		config.ClusterAddress = address
+		config.ClusterCertificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+		config.ClusterPassword = joinToken.Secret
+		return nil
+	}
+
+	return fmt.Errorf("Unable to connect to any of the cluster members specified in the join token")
+}
+
 func (c *cmdInit) availableStorageDrivers(poolType string) []string {
 	drivers := []string{}
 
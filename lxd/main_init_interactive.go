@@ -104,13 +104,15 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 		config.Cluster.Enabled = true
 
 		// Cluster server name
-		serverName, err := os.Hostname()
-		if err != nil {
-			serverName = "lxd"
-		}
+		askServerName := func() {
+			serverName, err := os.Hostname()
+			if err != nil {
+				serverName = "lxd"
+			}
 
-		config.Cluster.ServerName = cli.AskString(
-			fmt.Sprintf("What name should be used to identify this node in the cluster? [default=%s]: ", serverName), serverName, nil)
+			config.Cluster.ServerName = cli.AskString(
+				fmt.Sprintf("What name should be used to identify this node in the cluster? [default=%s]: ", serverName), serverName, nil)
+		}
 
 		// Cluster server address
 		address := util.NetworkInterfaceAddress()
@@ -121,7 +123,30 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 		if cli.AskBool("Are you joining an existing cluster? (yes/no) [default=no]: ", "no") {
 			// Existing cluster
 			config.Cluster.ServerAddress = serverAddress
-			for {
+
+			// The join token carries the name of the new member
+			useToken := cli.AskBool("Do you have a join token? (yes/no) [default=no]: ", "no")
+			for useToken {
+				joinToken, err := shared.JoinTokenDecode(cli.AskString("Please provide join token: ", "", nil))
+				if err != nil {
+					fmt.Printf("Invalid join token: %v\n", err)
+					continue
+				}
+
+				err = initDataClusterJoinToken(config.Cluster, joinToken)
+				if err != nil {
+					fmt.Printf("%v\n", err)
+					continue
+				}
+
+				break
+			}
+
+			if !useToken {
+				askServerName()
+			}
+
+			for !useToken {
 				// Cluster URL
 				clusterAddress := cli.AskString("IP address or FQDN of an existing cluster node: ", "", nil)
 				_, _, err := net.SplitHostPort(clusterAddress)
@@ -166,7 +191,7 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 				return err
 			}
 
-			err = cluster.SetupTrust(string(cert.PublicKey()),
+			err = cluster.SetupTrust(string(cert.PublicKey()), config.Cluster.ServerName,
 				config.Cluster.ClusterAddress,
 				string(config.Cluster.ClusterCertificate), config.Cluster.ClusterPassword)
 			if err != nil {
@@ -199,6 +224,8 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 
 			config.Cluster.MemberConfig = cluster.MemberConfig
 		} else {
+			askServerName()
+
 			// Password authentication
 			if cli.AskBool("Setup password authentication on the cluster? (yes/no) [default=yes]: ", "yes") {
 				config.Node.Config["core.trust_password"] = cli.AskPassword("Trust password for new clients: ")
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DiskStats holds the bytes read from and written to a disk, as reported by
+// the agent of virtual machines.
+type DiskStats struct {
+	ReadBytes    int64 `json:"read_bytes" yaml:"read_bytes"`
+	WrittenBytes int64 `json:"written_bytes" yaml:"written_bytes"`
+}
+
+// ParseDiskStats returns the statistics of each device listed in the content
+// of /proc/diskstats, whose sectors are always 512 bytes long.
+func ParseDiskStats(content string) (map[string]DiskStats, error) {
+	stats := map[string]DiskStats{}
+
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) < 10 {
+			return nil, fmt.Errorf("Invalid disk statistics line %q", line)
+		}
+
+		read, err := strconv.ParseInt(fields[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid disk statistics line %q", line)
+		}
+
+		written, err := strconv.ParseInt(fields[9], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid disk statistics line %q", line)
+		}
+
+		stats[fields[2]] = DiskStats{ReadBytes: read * 512, WrittenBytes: written * 512}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,112 @@
+// Package metrics renders the metrics of LXD in the OpenMetrics text format.
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sample is a single value of a metric along with its labels.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// MetricSet holds the samples of a set of metrics sharing common labels.
+type MetricSet struct {
+	set    map[MetricType][]Sample
+	labels map[string]string
+}
+
+// NewMetricSet returns an empty set whose samples all get the given labels.
+func NewMetricSet(labels map[string]string) *MetricSet {
+	return &MetricSet{
+		set:    map[MetricType][]Sample{},
+		labels: labels,
+	}
+}
+
+// AddSamples adds samples of the given metric to the set.
+func (m *MetricSet) AddSamples(metricType MetricType, samples ...Sample) {
+	for _, sample := range samples {
+		labels := map[string]string{}
+		for k, v := range m.labels {
+			labels[k] = v
+		}
+
+		for k, v := range sample.Labels {
+			labels[k] = v
+		}
+
+		m.set[metricType] = append(m.set[metricType], Sample{Labels: labels, Value: sample.Value})
+	}
+}
+
+// Merge adds the samples of another set to this one.
+func (m *MetricSet) Merge(other *MetricSet) {
+	if other == nil {
+		return
+	}
+
+	for metricType, samples := range other.set {
+		m.set[metricType] = append(m.set[metricType], samples...)
+	}
+}
+
+// String renders the set in the OpenMetrics text format.
+func (m *MetricSet) String() string {
+	metricTypes := make([]int, 0, len(m.set))
+	for metricType := range m.set {
+		metricTypes = append(metricTypes, int(metricType))
+	}
+	sort.Ints(metricTypes)
+
+	var out strings.Builder
+	for _, metricType := range metricTypes {
+		samples := m.set[MetricType(metricType)]
+		if len(samples) == 0 {
+			continue
+		}
+
+		family := metricFamilies[MetricType(metricType)]
+		fmt.Fprintf(&out, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(&out, "# TYPE %s %s\n", family.name, family.kind)
+
+		name := family.name
+		if family.kind == kindCounter {
+			name += "_total"
+		}
+
+		for _, sample := range samples {
+			fmt.Fprintf(&out, "%s%s %s\n", name, formatLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'f', -1, 64))
+		}
+	}
+
+	out.WriteString("# EOF\n")
+
+	return out.String()
+}
+
+// formatLabels renders a set of labels, sorted by name.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, replacer.Replace(labels[name])))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
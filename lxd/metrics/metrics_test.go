@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricSetString(t *testing.T) {
+	set := NewMetricSet(map[string]string{"name": "c1", "project": "default"})
+	set.AddSamples(MemoryUsageBytes, Sample{Value: 1048576})
+	set.AddSamples(CPUSeconds, Sample{Value: 1.5})
+	set.AddSamples(NetworkReceiveBytes,
+		Sample{Labels: map[string]string{"device": "eth0"}, Value: 10},
+		Sample{Labels: map[string]string{"device": "eth1"}, Value: 20})
+
+	daemon := NewMetricSet(nil)
+	daemon.AddSamples(Goroutines, Sample{Value: 42})
+	set.Merge(daemon)
+
+	assert.Equal(t, `# HELP lxd_cpu_seconds The CPU time used by the instance in seconds.
+# TYPE lxd_cpu_seconds counter
+lxd_cpu_seconds_total{name="c1",project="default"} 1.5
+# HELP lxd_memory_usage_bytes The memory used by the instance in bytes.
+# TYPE lxd_memory_usage_bytes gauge
+lxd_memory_usage_bytes{name="c1",project="default"} 1048576
+# HELP lxd_network_receive_bytes The bytes received on the network interface.
+# TYPE lxd_network_receive_bytes counter
+lxd_network_receive_bytes_total{device="eth0",name="c1",project="default"} 10
+lxd_network_receive_bytes_total{device="eth1",name="c1",project="default"} 20
+# HELP lxd_go_goroutines The number of goroutines of the daemon.
+# TYPE lxd_go_goroutines gauge
+lxd_go_goroutines 42
+# EOF
+`, set.String())
+}
+
+func TestMetricSetLabelEscaping(t *testing.T) {
+	set := NewMetricSet(map[string]string{"name": "a\"b\\c\nd"})
+	set.AddSamples(Processes, Sample{Value: 3})
+
+	assert.Contains(t, set.String(), `lxd_procs{name="a\"b\\c\nd"} 3`)
+	assert.Equal(t, "# EOF\n", NewMetricSet(nil).String())
+}
+
+func TestParseDiskStats(t *testing.T) {
+	stats, err := ParseDiskStats(`   8       0 sda 4207 1309 395514 1528 3009 2452 81912 3320 0 2752 4848 0 0 0 0
+   8       1 sda1 4000 1300 390000 1500 3000 2450 81900 3300 0 2700 4800 0 0 0 0
+ 253       0 dm-0 10 0 8 0 0 0 0 0 0 0 0
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]DiskStats{
+		"sda":  {ReadBytes: 395514 * 512, WrittenBytes: 81912 * 512},
+		"sda1": {ReadBytes: 390000 * 512, WrittenBytes: 81900 * 512},
+		"dm-0": {ReadBytes: 8 * 512, WrittenBytes: 0},
+	}, stats)
+
+	_, err = ParseDiskStats("8 0 sda 1 2")
+	assert.Error(t, err)
+
+	_, err = ParseDiskStats("8 0 sda 1 2 x 4 5 6 7")
+	assert.Error(t, err)
+}
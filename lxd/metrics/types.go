@@ -0,0 +1,83 @@
+package metrics
+
+// MetricType is a metric family exposed by LXD.
+type MetricType int
+
+const (
+	// CPUSeconds represents the CPU time used by an instance.
+	CPUSeconds MetricType = iota
+	// MemoryUsageBytes represents the memory used by an instance.
+	MemoryUsageBytes
+	// MemoryUsagePeakBytes represents the peak memory usage of an instance.
+	MemoryUsagePeakBytes
+	// SwapUsageBytes represents the swap used by an instance.
+	SwapUsageBytes
+	// SwapUsagePeakBytes represents the peak swap usage of an instance.
+	SwapUsagePeakBytes
+	// FilesystemUsageBytes represents the space used by an instance disk.
+	FilesystemUsageBytes
+	// DiskReadBytes represents the bytes read from the disks of an instance.
+	DiskReadBytes
+	// DiskWrittenBytes represents the bytes written to the disks of an instance.
+	DiskWrittenBytes
+	// NetworkReceiveBytes represents the bytes received on an instance interface.
+	NetworkReceiveBytes
+	// NetworkTransmitBytes represents the bytes sent on an instance interface.
+	NetworkTransmitBytes
+	// NetworkReceivePackets represents the packets received on an instance interface.
+	NetworkReceivePackets
+	// NetworkTransmitPackets represents the packets sent on an instance interface.
+	NetworkTransmitPackets
+	// Processes represents the number of processes in an instance.
+	Processes
+	// InstanceRunning represents whether an instance is running.
+	InstanceRunning
+	// Goroutines represents the number of goroutines of the daemon.
+	Goroutines
+	// GoHeapAllocBytes represents the heap memory allocated by the daemon.
+	GoHeapAllocBytes
+	// GoSysBytes represents the memory obtained from the system by the daemon.
+	GoSysBytes
+	// Operations represents the number of operations of the daemon.
+	Operations
+	// EventListeners represents the number of event listeners of the daemon.
+	EventListeners
+	// UptimeSeconds represents the time since the daemon started.
+	UptimeSeconds
+)
+
+// Kinds of metric families.
+const (
+	kindCounter = "counter"
+	kindGauge   = "gauge"
+)
+
+// metricFamily describes how a metric is exposed.
+type metricFamily struct {
+	name string
+	kind string
+	help string
+}
+
+var metricFamilies = map[MetricType]metricFamily{
+	CPUSeconds:             {"lxd_cpu_seconds", kindCounter, "The CPU time used by the instance in seconds."},
+	MemoryUsageBytes:       {"lxd_memory_usage_bytes", kindGauge, "The memory used by the instance in bytes."},
+	MemoryUsagePeakBytes:   {"lxd_memory_usage_peak_bytes", kindGauge, "The peak memory usage of the instance in bytes."},
+	SwapUsageBytes:         {"lxd_memory_swap_usage_bytes", kindGauge, "The swap used by the instance in bytes."},
+	SwapUsagePeakBytes:     {"lxd_memory_swap_usage_peak_bytes", kindGauge, "The peak swap usage of the instance in bytes."},
+	FilesystemUsageBytes:   {"lxd_filesystem_usage_bytes", kindGauge, "The space used on the disk of the instance in bytes."},
+	DiskReadBytes:          {"lxd_disk_read_bytes", kindCounter, "The bytes read from the disks of the instance."},
+	DiskWrittenBytes:       {"lxd_disk_written_bytes", kindCounter, "The bytes written to the disks of the instance."},
+	NetworkReceiveBytes:    {"lxd_network_receive_bytes", kindCounter, "The bytes received on the network interface."},
+	NetworkTransmitBytes:   {"lxd_network_transmit_bytes", kindCounter, "The bytes sent on the network interface."},
+	NetworkReceivePackets:  {"lxd_network_receive_packets", kindCounter, "The packets received on the network interface."},
+	NetworkTransmitPackets: {"lxd_network_transmit_packets", kindCounter, "The packets sent on the network interface."},
+	Processes:              {"lxd_procs", kindGauge, "The number of processes running in the instance."},
+	InstanceRunning:        {"lxd_instance_running", kindGauge, "Whether the instance is running."},
+	Goroutines:             {"lxd_go_goroutines", kindGauge, "The number of goroutines of the daemon."},
+	GoHeapAllocBytes:       {"lxd_go_heap_alloc_bytes", kindGauge, "The heap memory allocated by the daemon in bytes."},
+	GoSysBytes:             {"lxd_go_sys_bytes", kindGauge, "The memory obtained from the system by the daemon in bytes."},
+	Operations:             {"lxd_operations", kindGauge, "The number of operations of the daemon."},
+	EventListeners:         {"lxd_event_listeners", kindGauge, "The number of event listeners of the daemon."},
+	UptimeSeconds:          {"lxd_uptime_seconds", kindGauge, "The time since the daemon started in seconds."},
+}
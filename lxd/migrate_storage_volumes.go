@@ -36,7 +36,7 @@ func NewStorageMigrationSource(volumeOnly bool) (*migrationSourceWs, error) {
 	return &ret, nil
 }
 
-func (s *migrationSourceWs) DoStorage(state *state.State, poolName string, volName string, migrateOp *operations.Operation) error {
+func (s *migrationSourceWs) DoStorage(state *state.State, projectName string, poolName string, volName string, migrateOp *operations.Operation) error {
 	<-s.allConnected
 	defer s.disconnect()
 
@@ -58,6 +58,11 @@ func (s *migrationSourceWs) DoStorage(state *state.State, poolName string, volNa
 		// Convert the pool's migration type options to an offer header to target.
 		offerHeader = migration.TypesToHeader(poolMigrationTypes...)
 	} else {
+		err := storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+		if err != nil {
+			return err
+		}
+
 		storage, err := storagePoolVolumeInit(state, "default", poolName, volName, storagePoolVolumeTypeCustom)
 		if err != nil {
 			return err
@@ -99,12 +104,12 @@ func (s *migrationSourceWs) DoStorage(state *state.State, poolName string, volNa
 	// Only send snapshots when requested.
 	if !s.volumeOnly {
 		var err error
-		snaps, err := storagePools.VolumeSnapshotsGet(state, poolName, volName, storagePoolVolumeTypeCustom)
+		snaps, err := storagePools.VolumeSnapshotsGet(state, projectName, poolName, volName, storagePoolVolumeTypeCustom)
 		if err == nil {
 			poolID, err := state.Cluster.StoragePoolGetID(poolName)
 			if err == nil {
 				for _, snap := range snaps {
-					_, snapVolume, err := state.Cluster.StoragePoolNodeVolumeGetType(snap.Name, storagePoolVolumeTypeCustom, poolID)
+					_, snapVolume, err := state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, snap.Name, storagePoolVolumeTypeCustom, poolID)
 					if err != nil {
 						continue
 					}
@@ -149,20 +154,32 @@ func (s *migrationSourceWs) DoStorage(state *state.State, poolName string, volNa
 			return err
 		}
 
+		sendSnapshotNames := snapshotNames
+
+		// If we are in refresh mode, only send the snapshots the target has asked for.
+		if respHeader.GetRefresh() {
+			sendSnapshotNames = respHeader.GetSnapshotNames()
+		}
+
 		volSourceArgs := migration.VolumeSourceArgs{
 			Name:          volName,
 			MigrationType: migrationType,
-			Snapshots:     snapshotNames,
+			Snapshots:     sendSnapshotNames,
 			TrackProgress: true,
 		}
 
-		err = pool.MigrateCustomVolume(&shared.WebsocketIO{Conn: s.fsConn}, volSourceArgs, migrateOp)
+		err = pool.MigrateCustomVolume(projectName, &shared.WebsocketIO{Conn: s.fsConn}, volSourceArgs, migrateOp)
 		if err != nil {
 			go s.sendControl(err)
 			return err
 		}
 	} else {
 		// Use legacy storage layer for migration.
+		if respHeader.GetRefresh() {
+			err := fmt.Errorf("Storage volume refresh isn't supported on legacy storage pools")
+			s.sendControl(err)
+			return err
+		}
 
 		// Get target's rsync options.
 		rsyncFeatures := respHeader.GetRsyncFeaturesSlice()
@@ -232,11 +249,12 @@ func (s *migrationSourceWs) DoStorage(state *state.State, poolName string, volNa
 
 func NewStorageMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
 	sink := migrationSink{
-		src:    migrationFields{storage: args.Storage, volumeOnly: args.VolumeOnly},
-		dest:   migrationFields{storage: args.Storage, volumeOnly: args.VolumeOnly},
-		url:    args.Url,
-		dialer: args.Dialer,
-		push:   args.Push,
+		src:     migrationFields{storage: args.Storage, volumeOnly: args.VolumeOnly},
+		dest:    migrationFields{storage: args.Storage, volumeOnly: args.VolumeOnly},
+		url:     args.Url,
+		dialer:  args.Dialer,
+		push:    args.Push,
+		refresh: args.Refresh,
 	}
 
 	if sink.push {
@@ -274,7 +292,7 @@ func NewStorageMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
 	return &sink, nil
 }
 
-func (c *migrationSink) DoStorage(state *state.State, poolName string, req *api.StorageVolumesPost, op *operations.Operation) error {
+func (c *migrationSink) DoStorage(state *state.State, projectName string, poolName string, req *api.StorageVolumesPost, op *operations.Operation) error {
 	var err error
 
 	if c.push {
@@ -351,6 +369,43 @@ func (c *migrationSink) DoStorage(state *state.State, poolName string, req *api.
 		respHeader = migration.TypesToHeader(respType)
 		respHeader.SnapshotNames = offerHeader.SnapshotNames
 		respHeader.Snapshots = offerHeader.Snapshots
+		respHeader.Refresh = &c.refresh
+
+		if c.refresh {
+			// Get our existing snapshots.
+			targetSnapshots, err := storagePools.VolumeSnapshotsGet(state, projectName, poolName, req.Name, storagePoolVolumeTypeCustom)
+			if err != nil {
+				controller(err)
+				return err
+			}
+
+			targetSnapshotNames := make([]string, 0, len(targetSnapshots))
+			for _, snap := range targetSnapshots {
+				targetSnapshotNames = append(targetSnapshotNames, snap.Name)
+			}
+
+			// Compare the two sets.
+			syncSnapshots, deleteSnapshots := migrationCompareVolumeSnapshots(offerHeader.GetSnapshots(), targetSnapshotNames)
+
+			// Delete the extra local ones.
+			for _, snapName := range deleteSnapshots {
+				err := pool.DeleteCustomVolumeSnapshot(projectName, snapName, op)
+				if err != nil {
+					controller(err)
+					return err
+				}
+			}
+
+			snapshotNames := []string{}
+			for _, snap := range syncSnapshots {
+				snapshotNames = append(snapshotNames, snap.GetName())
+			}
+
+			respHeader.Snapshots = syncSnapshots
+			respHeader.SnapshotNames = snapshotNames
+			offerHeader.Snapshots = syncSnapshots
+			offerHeader.SnapshotNames = snapshotNames
+		}
 
 		// Translate the legacy MigrationSinkArgs to a VolumeTargetArgs suitable for use
 		// with the new storage layer.
@@ -360,6 +415,7 @@ func (c *migrationSink) DoStorage(state *state.State, poolName string, req *api.
 				Config:        req.Config,
 				Description:   req.Description,
 				MigrationType: respType,
+				Refresh:       c.refresh, // Indicate to receiver volume should exist.
 				TrackProgress: true,
 			}
 
@@ -372,11 +428,22 @@ func (c *migrationSink) DoStorage(state *state.State, poolName string, req *api.
 				}
 			}
 
-			return pool.CreateCustomVolumeFromMigration(&shared.WebsocketIO{Conn: conn}, volTargetArgs, op)
+			return pool.CreateCustomVolumeFromMigration(projectName, &shared.WebsocketIO{Conn: conn}, volTargetArgs, op)
 		}
 	} else {
 		// Setup legacy storage migration sink if destination pool isn't supported yet by
 		// new storage layer.
+		if c.refresh {
+			err := fmt.Errorf("Storage volume refresh isn't supported on legacy storage pools")
+			controller(err)
+			return err
+		}
+
+		err := storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+		if err != nil {
+			return err
+		}
+
 		storage, err := storagePoolVolumeDBCreateInternal(state, poolName, req)
 		if err != nil {
 			return err
@@ -532,3 +599,38 @@ func volumeSnapshotToProtobuf(vol *api.StorageVolume) *migration.Snapshot {
 		LastUsedDate: proto.Int64(0),
 	}
 }
+
+// migrationCompareVolumeSnapshots compares the snapshots offered by the source with those already
+// present on the target. Volume snapshots don't carry a creation date, so they are matched by name.
+// Returns the source snapshots that need transferring and the target snapshots (of the form
+// <volume>/<snapshot-name>) that should be deleted.
+func migrationCompareVolumeSnapshots(sourceSnapshots []*migration.Snapshot, targetSnapshots []string) ([]*migration.Snapshot, []string) {
+	sourceSnapshotNames := map[string]struct{}{}
+	targetSnapshotNames := map[string]struct{}{}
+
+	toDelete := []string{}
+	toSync := []*migration.Snapshot{}
+
+	for _, snap := range sourceSnapshots {
+		sourceSnapshotNames[snap.GetName()] = struct{}{}
+	}
+
+	for _, snap := range targetSnapshots {
+		_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snap)
+
+		targetSnapshotNames[snapName] = struct{}{}
+		_, exists := sourceSnapshotNames[snapName]
+		if !exists {
+			toDelete = append(toDelete, snap)
+		}
+	}
+
+	for _, snap := range sourceSnapshots {
+		_, exists := targetSnapshotNames[snap.GetName()]
+		if !exists {
+			toSync = append(toSync, snap)
+		}
+	}
+
+	return toSync, toDelete
+}
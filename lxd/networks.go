@@ -26,6 +26,7 @@ import (
 	firewallConsts "github.com/lxc/lxd/lxd/firewall/consts"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/node"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/util"
@@ -46,7 +47,7 @@ var networkCreateLock sync.Mutex
 var networksCmd = APIEndpoint{
 	Path: "networks",
 
-	Get:  APIEndpointAction{Handler: networksGet, AccessHandler: AllowAuthenticated},
+	Get:  APIEndpointAction{Handler: networksGet, AccessHandler: AllowProjectPermission("networks", "view")},
 	Post: APIEndpointAction{Handler: networksPost},
 }
 
@@ -54,7 +55,7 @@ var networkCmd = APIEndpoint{
 	Path: "networks/{name}",
 
 	Delete: APIEndpointAction{Handler: networkDelete},
-	Get:    APIEndpointAction{Handler: networkGet, AccessHandler: AllowAuthenticated},
+	Get:    APIEndpointAction{Handler: networkGet, AccessHandler: AllowProjectPermission("networks", "view")},
 	Patch:  APIEndpointAction{Handler: networkPatch},
 	Post:   APIEndpointAction{Handler: networkPost},
 	Put:    APIEndpointAction{Handler: networkPut},
@@ -63,20 +64,25 @@ var networkCmd = APIEndpoint{
 var networkLeasesCmd = APIEndpoint{
 	Path: "networks/{name}/leases",
 
-	Get: APIEndpointAction{Handler: networkLeasesGet, AccessHandler: AllowAuthenticated},
+	Get: APIEndpointAction{Handler: networkLeasesGet, AccessHandler: AllowProjectPermission("networks", "view")},
 }
 
 var networkStateCmd = APIEndpoint{
 	Path: "networks/{name}/state",
 
-	Get: APIEndpointAction{Handler: networkStateGet, AccessHandler: AllowAuthenticated},
+	Get: APIEndpointAction{Handler: networkStateGet, AccessHandler: AllowProjectPermission("networks", "view")},
 }
 
 // API endpoints
 func networksGet(d *Daemon, r *http.Request) response.Response {
 	recursion := util.IsRecursionRequest(r)
 
-	ifs, err := networkGetInterfaces(d.cluster)
+	projectName, err := projecthelpers.NetworkProject(d.cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	ifs, err := networkGetProjectInterfaces(d.cluster, projectName)
 	if err != nil {
 		return response.InternalError(err)
 	}
@@ -87,7 +93,7 @@ func networksGet(d *Daemon, r *http.Request) response.Response {
 		if !recursion {
 			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s", version.APIVersion, iface))
 		} else {
-			net, err := doNetworkGet(d, iface)
+			net, err := doNetworkGet(d, projectName, iface)
 			if err != nil {
 				continue
 			}
@@ -137,6 +143,11 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	projectName, err := projecthelpers.NetworkProject(d.cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	url := fmt.Sprintf("/%s/networks/%s", version.APIVersion, req.Name)
 	resp := response.SyncResponseLocation(true, nil, url)
 
@@ -162,7 +173,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 			}
 		}
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-			return tx.NetworkCreatePending(targetNode, req.Name, req.Config)
+			return tx.NetworkCreatePending(targetNode, projectName, req.Name, req.Config)
 		})
 		if err != nil {
 			if err == db.ErrAlreadyDefined {
@@ -180,7 +191,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if count > 1 {
-		err = networksPostCluster(d, req)
+		err = networksPostCluster(d, projectName, req)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -206,7 +217,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Create the database entry
-	_, err = d.cluster.NetworkCreate(req.Name, req.Description, req.Config)
+	_, err = d.cluster.NetworkCreate(projectName, req.Name, req.Description, req.Config)
 	if err != nil {
 		return response.SmartError(fmt.Errorf("Error inserting %s into database: %s", req.Name, err))
 	}
@@ -219,7 +230,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	return resp
 }
 
-func networksPostCluster(d *Daemon, req api.NetworksPost) error {
+func networksPostCluster(d *Daemon, projectName string, req api.NetworksPost) error {
 	// Check that no node-specific config key has been defined.
 	for key := range req.Config {
 		if shared.StringInSlice(key, db.NetworkNodeConfigKeys) {
@@ -228,8 +239,11 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 	}
 
 	// Merge the current config.
-	networkID, dbNetwork, err := d.cluster.NetworkGet(req.Name)
+	networkID, dbNetwork, err := d.cluster.NetworkGetByProject(projectName, req.Name)
 	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return fmt.Errorf("Network not pending on any node (use --target <node> first)")
+		}
 		return err
 	}
 
@@ -378,7 +392,12 @@ func networkGet(d *Daemon, r *http.Request) response.Response {
 
 	name := mux.Vars(r)["name"]
 
-	n, err := doNetworkGet(d, name)
+	projectName, err := networkRequestProject(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	n, err := doNetworkGet(d, projectName, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -402,12 +421,43 @@ func networkGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponseETag(true, &n, etag)
 }
 
-func doNetworkGet(d *Daemon, name string) (api.Network, error) {
+// networkRequestProject returns the project holding the networks visible from
+// the given request. Cluster notifications aren't bound to a project, so the
+// project of the network with the given name is used for them.
+func networkRequestProject(d *Daemon, r *http.Request, name string) (string, error) {
+	if !isClusterNotification(r) {
+		return projecthelpers.NetworkProject(d.cluster, projectParam(r))
+	}
+
+	var projectName string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		projectName, err = tx.NetworkProject(name)
+		return err
+	})
+	if err == db.ErrNoSuchObject {
+		return "default", nil
+	}
+
+	return projectName, err
+}
+
+func doNetworkGet(d *Daemon, projectName string, name string) (api.Network, error) {
 	// Ignore veth pairs (for performance reasons)
 	if strings.HasPrefix(name, "veth") {
 		return api.Network{}, os.ErrNotExist
 	}
 
+	// Check that the network is visible from the project
+	visible, err := networkIsVisible(d.cluster, projectName, name)
+	if err != nil {
+		return api.Network{}, err
+	}
+
+	if !visible {
+		return api.Network{}, os.ErrNotExist
+	}
+
 	// Get some information
 	osInfo, _ := net.InterfaceByName(name)
 	_, dbInfo, _ := d.cluster.NetworkGet(name)
@@ -479,9 +529,14 @@ func networkDelete(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 	state := d.State()
 
+	projectName, err := networkRequestProject(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Check if the network is pending, if so we just need to delete it from
 	// the database.
-	_, network, err := d.cluster.NetworkGet(name)
+	_, network, err := d.cluster.NetworkGetByProject(projectName, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -561,6 +616,17 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	projectName, err := networkRequestProject(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Check that the network belongs to the project
+	_, _, err = d.cluster.NetworkGetByProject(projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get the existing network
 	n, err := networkLoadByName(state, name)
 	if err != nil {
@@ -599,8 +665,13 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 func networkPut(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
+	projectName, err := networkRequestProject(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get the existing network
-	_, dbInfo, err := d.cluster.NetworkGet(name)
+	_, dbInfo, err := d.cluster.NetworkGetByProject(projectName, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -638,8 +709,13 @@ func networkPut(d *Daemon, r *http.Request) response.Response {
 func networkPatch(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
+	projectName, err := networkRequestProject(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get the existing network
-	_, dbInfo, err := d.cluster.NetworkGet(name)
+	_, dbInfo, err := d.cluster.NetworkGetByProject(projectName, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -718,8 +794,13 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 	project := projectParam(r)
 
+	projectName, err := networkRequestProject(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Try to get the network
-	n, err := doNetworkGet(d, name)
+	n, err := doNetworkGet(d, projectName, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -1017,11 +1098,22 @@ func networkStateGet(d *Daemon, r *http.Request) response.Response {
 
 	name := mux.Vars(r)["name"]
 
+	projectName, err := networkRequestProject(d, r, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Check that the network is visible from the project
+	visible, err := networkIsVisible(d.cluster, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get some information
 	osInfo, _ := net.InterfaceByName(name)
 
 	// Sanity check
-	if osInfo == nil {
+	if osInfo == nil || !visible {
 		return response.NotFound(fmt.Errorf("Interface '%s' not found", name))
 	}
 
@@ -1141,7 +1233,21 @@ func (n *network) Start() error {
 	return n.Setup(nil)
 }
 
+// Setup brings up the network, recording a warning if that fails.
 func (n *network) Setup(oldConfig map[string]string) error {
+	entityURL := fmt.Sprintf("/%s/networks/%s", version.APIVersion, n.name)
+
+	err := n.setup(oldConfig)
+	if err != nil {
+		warningCreate(n.state, "", entityURL, db.WarningNetworkUnavailable, err.Error())
+		return err
+	}
+
+	warningResolve(n.state, "", entityURL, db.WarningNetworkUnavailable)
+	return nil
+}
+
+func (n *network) setup(oldConfig map[string]string) error {
 	// If we are in mock mode, just no-op.
 	if n.state.OS.MockMode {
 		return nil
@@ -91,6 +91,66 @@ func networkGetInterfaces(cluster *db.Cluster) ([]string, error) {
 	return networks, nil
 }
 
+// networkGetProjectInterfaces returns the names of the networks visible from
+// the given project. Host interfaces which aren't managed networks are only
+// visible from the default project.
+func networkGetProjectInterfaces(cluster *db.Cluster, projectName string) ([]string, error) {
+	networks, err := cluster.NetworksByProject(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	if projectName != "default" {
+		return networks, nil
+	}
+
+	managed, err := cluster.Networks()
+	if err != nil {
+		return nil, err
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iface := range ifaces {
+		// Ignore veth pairs (for performance reasons)
+		if strings.HasPrefix(iface.Name, "veth") {
+			continue
+		}
+
+		// Skip managed networks, they're only visible from their own project
+		if shared.StringInSlice(iface.Name, managed) {
+			continue
+		}
+
+		networks = append(networks, iface.Name)
+	}
+
+	return networks, nil
+}
+
+// networkIsVisible returns whether the network with the given name can be
+// seen from the given project.
+func networkIsVisible(cluster *db.Cluster, projectName string, name string) (bool, error) {
+	var networkProject string
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		networkProject, err = tx.NetworkProject(name)
+		return err
+	})
+	if err == db.ErrNoSuchObject {
+		return projectName == "default", nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return networkProject == projectName, nil
+}
+
 func networkIsInUse(c instance.Instance, name string) bool {
 	for _, d := range c.ExpandedDevices() {
 		if d["type"] != "nic" {
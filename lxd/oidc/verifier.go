@@ -0,0 +1,342 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared/oidc"
+)
+
+// Minimum delay between two fetches of the issuer's key set, so that tokens
+// with unknown key IDs can't be used to hammer the issuer.
+const keysRefreshInterval = time.Minute
+
+// Allowed clock skew between LXD and the issuer.
+const clockSkew = time.Minute
+
+// Verifier validates bearer tokens issued by an OpenID Connect provider.
+type Verifier struct {
+	issuer   string
+	clientID string
+	audience string
+
+	client *http.Client
+
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+	keysLock      sync.Mutex
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	Expiry    int64           `json:"exp"`
+	NotBefore int64           `json:"nbf"`
+}
+
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// NewVerifier returns a Verifier for tokens issued by the given issuer. The
+// tokens must be meant for the given audience, or for the client ID if no
+// audience is set.
+func NewVerifier(issuer string, clientID string, audience string) *Verifier {
+	return &Verifier{
+		issuer:   issuer,
+		clientID: clientID,
+		audience: audience,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Auth validates the bearer token of the given request and returns the
+// subject it was issued to.
+func (v *Verifier) Auth(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", fmt.Errorf("No bearer token in request")
+	}
+
+	return v.Verify(strings.TrimPrefix(header, "Bearer "))
+}
+
+// Verify validates the given JWT and returns its subject.
+func (v *Verifier) Verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("Malformed token")
+	}
+
+	header := jwtHeader{}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return "", fmt.Errorf("Bad token header: %v", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("Bad token signature: %v", err)
+	}
+
+	key, err := v.key(header.KeyID)
+	if err != nil {
+		return "", err
+	}
+
+	err = verifySignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwtClaims{}
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return "", fmt.Errorf("Bad token claims: %v", err)
+	}
+
+	err = v.checkClaims(&claims)
+	if err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
+
+func (v *Verifier) checkClaims(claims *jwtClaims) error {
+	if claims.Issuer != v.issuer {
+		return fmt.Errorf("Token issued by %q, expected %q", claims.Issuer, v.issuer)
+	}
+
+	if claims.Subject == "" {
+		return fmt.Errorf("Token has no subject")
+	}
+
+	now := time.Now()
+	if claims.Expiry == 0 || now.Add(-clockSkew).After(time.Unix(claims.Expiry, 0)) {
+		return fmt.Errorf("Token has expired")
+	}
+
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("Token isn't valid yet")
+	}
+
+	expected := v.audience
+	if expected == "" {
+		expected = v.clientID
+	}
+
+	if expected == "" {
+		return fmt.Errorf("No audience to check the token against")
+	}
+
+	// The audience is either a single string or a list of strings.
+	audiences := []string{}
+	err := json.Unmarshal(claims.Audience, &audiences)
+	if err != nil {
+		audience := ""
+		err = json.Unmarshal(claims.Audience, &audience)
+		if err != nil {
+			return fmt.Errorf("Bad token audience")
+		}
+
+		audiences = []string{audience}
+	}
+
+	for _, audience := range audiences {
+		if audience == expected {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Token isn't meant for audience %q", expected)
+}
+
+// Return the issuer's public key with the given ID, fetching the key set
+// again if the key isn't known yet.
+func (v *Verifier) key(keyID string) (crypto.PublicKey, error) {
+	v.keysLock.Lock()
+	defer v.keysLock.Unlock()
+
+	key, ok := v.keys[keyID]
+	if ok {
+		return key, nil
+	}
+
+	if time.Since(v.keysFetchedAt) < keysRefreshInterval {
+		return nil, fmt.Errorf("Unknown token key ID %q", keyID)
+	}
+
+	v.keysFetchedAt = time.Now()
+	keys, err := v.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	v.keys = keys
+
+	key, ok = v.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("Unknown token key ID %q", keyID)
+	}
+
+	return key, nil
+}
+
+func (v *Verifier) fetchKeys() (map[string]crypto.PublicKey, error) {
+	provider, err := oidc.Discover(v.client, v.issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.client.Get(provider.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to fetch OpenID key set: %s", resp.Status)
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse OpenID key set: %v", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			// Skip key types we don't support.
+			continue
+		}
+
+		keys[k.KeyID] = key
+	}
+
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("Unsupported curve %q", k.Curve)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported key type %q", k.KeyType)
+}
+
+func verifySignature(algorithm string, key crypto.PublicKey, data []byte, signature []byte) error {
+	hash := sha256.Sum256(data)
+
+	switch algorithm {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Token key doesn't match algorithm %q", algorithm)
+		}
+
+		err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, hash[:], signature)
+		if err != nil {
+			return fmt.Errorf("Invalid token signature")
+		}
+
+		return nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Token key doesn't match algorithm %q", algorithm)
+		}
+
+		if len(signature) != 64 {
+			return fmt.Errorf("Invalid token signature")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, hash[:], r, s) {
+			return fmt.Errorf("Invalid token signature")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("Unsupported token algorithm %q", algorithm)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
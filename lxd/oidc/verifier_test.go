@@ -0,0 +1,151 @@
+package oidc_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/oidc"
+)
+
+// Mock OpenID Connect issuer signing tokens with a single RSA key.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := &mockIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.server.URL,
+			"jwks_uri": issuer.server.URL + "/keys",
+		})
+	})
+
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	return issuer
+}
+
+func (i *mockIssuer) token(t *testing.T, kid string, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	data := fmt.Sprintf("%s.%s", base64.RawURLEncoding.EncodeToString(header), base64.RawURLEncoding.EncodeToString(payload))
+	hash := sha256.Sum256([]byte(data))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, hash[:])
+	require.NoError(t, err)
+
+	return fmt.Sprintf("%s.%s", data, base64.RawURLEncoding.EncodeToString(signature))
+}
+
+func (i *mockIssuer) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss": i.server.URL,
+		"sub": "alice",
+		"aud": []string{"lxd"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// A valid token is accepted and its subject returned.
+func TestVerifier_Auth(t *testing.T) {
+	issuer := newMockIssuer(t)
+	defer issuer.server.Close()
+
+	verifier := oidc.NewVerifier(issuer.server.URL, "lxc", "lxd")
+
+	r := httptest.NewRequest("GET", "/1.0", nil)
+	r.Header.Set("Authorization", "Bearer "+issuer.token(t, "key1", issuer.claims()))
+
+	subject, err := verifier.Auth(r)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", subject)
+}
+
+// Tokens with bad claims or signatures are rejected.
+func TestVerifier_Invalid(t *testing.T) {
+	issuer := newMockIssuer(t)
+	defer issuer.server.Close()
+
+	verifier := oidc.NewVerifier(issuer.server.URL, "lxc", "lxd")
+
+	cases := map[string]func(map[string]interface{}){
+		"expired":        func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"not yet valid":  func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() },
+		"wrong issuer":   func(c map[string]interface{}) { c["iss"] = "https://example.com" },
+		"wrong audience": func(c map[string]interface{}) { c["aud"] = "other" },
+		"no subject":     func(c map[string]interface{}) { delete(c, "sub") },
+	}
+
+	for name, change := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := issuer.claims()
+			change(claims)
+
+			_, err := verifier.Verify(issuer.token(t, "key1", claims))
+			assert.Error(t, err)
+		})
+	}
+
+	// Signature of one token over the payload of another.
+	mallory := issuer.claims()
+	mallory["sub"] = "mallory"
+	good := strings.Split(issuer.token(t, "key1", issuer.claims()), ".")
+	bad := strings.Split(issuer.token(t, "key1", mallory), ".")
+	_, err := verifier.Verify(strings.Join([]string{good[0], bad[1], good[2]}, "."))
+	assert.Error(t, err)
+
+	// Unknown key ID.
+	_, err = verifier.Verify(issuer.token(t, "key2", issuer.claims()))
+	assert.Error(t, err)
+
+	// No bearer token.
+	_, err = verifier.Auth(httptest.NewRequest("GET", "/1.0", nil))
+	assert.Error(t, err)
+}
+
+// Tokens are rejected if there's no audience to check them against.
+func TestVerifier_NoAudience(t *testing.T) {
+	issuer := newMockIssuer(t)
+	defer issuer.server.Close()
+
+	verifier := oidc.NewVerifier(issuer.server.URL, "", "")
+
+	_, err := verifier.Verify(issuer.token(t, "key1", issuer.claims()))
+	assert.Error(t, err)
+}
@@ -1,21 +1,28 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
 )
 
 var operationCmd = APIEndpoint{
@@ -52,6 +59,10 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		if !operationIsVisible(d, r, op.Project(), op.Type()) {
+			return response.Forbidden(nil)
+		}
+
 		_, body, err = op.Render()
 		if err != nil {
 			return response.SmartError(err)
@@ -62,19 +73,46 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
 	var address string
+	var projectName string
+	var opType db.OperationType
+	var history *db.OperationHistory
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		operation, err := tx.OperationByUUID(id)
+		if err == db.ErrNoSuchObject {
+			// Finally check if the operation completed and is in the history
+			entry, err := tx.OperationHistoryByUUID(id)
+			if err != nil {
+				return err
+			}
+
+			history = &entry
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 
 		address = operation.NodeAddress
+		projectName = operation.Project
+		opType = operation.Type
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	if history != nil {
+		if !operationIsVisible(d, r, history.Project, history.Type) {
+			return response.Forbidden(nil)
+		}
+
+		return response.SyncResponse(true, operationHistoryRender(*history))
+	}
+
+	if !operationIsVisible(d, r, projectName, opType) {
+		return response.Forbidden(nil)
+	}
+
 	cert := d.endpoints.NetworkCert()
 	client, err := cluster.Connect(address, cert, false)
 	if err != nil {
@@ -90,6 +128,10 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		if !operationIsVisible(d, r, op.Project(), op.Type()) {
+			return response.Forbidden(nil)
+		}
+
 		if op.Permission() != "" {
 			project := op.Project()
 			if project == "" {
@@ -111,6 +153,8 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
 	var address string
+	var projectName string
+	var opType db.OperationType
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		operation, err := tx.OperationByUUID(id)
 		if err != nil {
@@ -118,12 +162,18 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 		}
 
 		address = operation.NodeAddress
+		projectName = operation.Project
+		opType = operation.Type
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	if !operationIsVisible(d, r, projectName, opType) {
+		return response.Forbidden(nil)
+	}
+
 	cert := d.endpoints.NetworkCert()
 	client, err := cluster.Connect(address, cert, false)
 	if err != nil {
@@ -133,10 +183,50 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 	return response.ForwardedResponse(client, r)
 }
 
+// Return whether the user can see an operation of the given type in the given
+// project.
+func operationIsVisible(d *Daemon, r *http.Request, project string, opType db.OperationType) bool {
+	// Join tokens grant access to the whole server, so only administrators
+	// get to see them.
+	if opType == db.OperationCertificateAddToken || opType == db.OperationClusterJoinToken {
+		return d.userIsAdmin(r) || isClusterNotification(r)
+	}
+
+	if project == "" {
+		project = "default"
+	}
+
+	return d.userHasPermission(r, project, "view")
+}
+
+// Return whether an operation rendered by another cluster member is a client
+// or cluster member join token. Those are the only token operations not tied
+// to an image, and are looked at this way rather than in the database since
+// they're listed by their member before being recorded and after being
+// removed there.
+func operationIsJoinToken(op api.Operation) bool {
+	if op.Class != operations.OperationClassToken.String() {
+		return false
+	}
+
+	_, ok := op.Resources["images"]
+	return !ok
+}
+
 func operationsGet(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	recursion := util.IsRecursionRequest(r)
 
+	if !d.userHasPermission(r, project, "view") {
+		return response.Forbidden(nil)
+	}
+
+	// The completed operations are stored in the cluster database, so no
+	// forwarding is needed.
+	if shared.IsTrue(r.FormValue("all-history")) {
+		return operationsHistoryGet(d, r, project, recursion)
+	}
+
 	localOperationURLs := func() (shared.Jmap, error) {
 		// Get all the operations
 		operations.Lock()
@@ -150,6 +240,11 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			if v.Project() != "" && v.Project() != project {
 				continue
 			}
+
+			if !operationIsVisible(d, r, v.Project(), v.Type()) {
+				continue
+			}
+
 			status := strings.ToLower(v.Status().String())
 			_, ok := body[status]
 			if !ok {
@@ -175,6 +270,11 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			if v.Project() != "" && v.Project() != project {
 				continue
 			}
+
+			if !operationIsVisible(d, r, v.Project(), v.Type()) {
+				continue
+			}
+
 			status := strings.ToLower(v.Status().String())
 			_, ok := body[status]
 			if !ok {
@@ -241,22 +341,20 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		return response.SyncResponse(true, md)
 	}
 
-	// Get all nodes with running operations in this project.
+	// Get all nodes with running operations in this project
 	var nodes []string
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
 
 		nodes, err = tx.OperationNodes(project)
-		if err != nil {
-			return err
-		}
-
-		return nil
+		return err
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	isAdmin := d.userIsAdmin(r)
+
 	// Get local address
 	localAddress, err := node.HTTPSAddress(d.db)
 	if err != nil {
@@ -283,6 +381,10 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 
 		// Merge with existing data
 		for _, op := range ops {
+			if !isAdmin && operationIsJoinToken(op) {
+				continue
+			}
+
 			status := strings.ToLower(op.Status)
 
 			_, ok := md[status]
@@ -305,6 +407,143 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, md)
 }
 
+// operationsHistoryGet returns the completed operations of the given project,
+// filtered by the status, class and since query parameters.
+func operationsHistoryGet(d *Daemon, r *http.Request, project string, recursion bool) response.Response {
+	var statuses []string
+	if r.FormValue("status") != "" {
+		statuses = strings.Split(strings.ToLower(r.FormValue("status")), ",")
+	}
+
+	var classes []string
+	if r.FormValue("class") != "" {
+		classes = strings.Split(strings.ToLower(r.FormValue("class")), ",")
+	}
+
+	var since time.Time
+	if r.FormValue("since") != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, r.FormValue("since"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid since date %q, expected RFC3339", r.FormValue("since")))
+		}
+	}
+
+	var history []db.OperationHistory
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		history, err = tx.OperationsHistory(project, since)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	md := shared.Jmap{}
+	for _, entry := range history {
+		if !operationIsVisible(d, r, entry.Project, entry.Type) {
+			continue
+		}
+
+		op := operationHistoryRender(entry)
+		status := strings.ToLower(op.Status)
+		if statuses != nil && !shared.StringInSlice(status, statuses) {
+			continue
+		}
+
+		if classes != nil && !shared.StringInSlice(op.Class, classes) {
+			continue
+		}
+
+		_, ok := md[status]
+		if !ok {
+			if recursion {
+				md[status] = make([]*api.Operation, 0)
+			} else {
+				md[status] = make([]string, 0)
+			}
+		}
+
+		if recursion {
+			md[status] = append(md[status].([]*api.Operation), op)
+		} else {
+			md[status] = append(md[status].([]string), fmt.Sprintf("/1.0/operations/%s", op.ID))
+		}
+	}
+
+	return response.SyncResponse(true, md)
+}
+
+// operationHistoryRender returns the API representation of a completed
+// operation.
+func operationHistoryRender(entry db.OperationHistory) *api.Operation {
+	status := api.StatusCode(entry.StatusCode)
+
+	op := &api.Operation{
+		ID:          entry.UUID,
+		Class:       entry.Class,
+		Description: entry.Description,
+		CreatedAt:   entry.CreatedAt,
+		UpdatedAt:   entry.FinishedAt,
+		Status:      status.String(),
+		StatusCode:  status,
+		Resources:   entry.Resources,
+		Metadata:    map[string]interface{}{},
+		Err:         entry.Err,
+		Location:    entry.Node,
+	}
+
+	if entry.RequestorUsername != "" || entry.RequestorProtocol != "" {
+		op.Requestor = &api.OperationRequestor{
+			Username: entry.RequestorUsername,
+			Protocol: entry.RequestorProtocol,
+			Address:  entry.RequestorAddress,
+		}
+	}
+
+	return op
+}
+
+// This task function removes the completed operations older than
+// core.operations_history_expiry days from the history. It's started by the
+// Daemon and will run once every 24h.
+func pruneOperationsHistoryTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		opRun := func(op *operations.Operation) error {
+			return pruneOperationsHistory(d)
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationOperationsHistoryExpire, nil, nil, opRun, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start operations history expiry operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Infof("Pruning expired operations history")
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to expire operations history", log.Ctx{"err": err})
+		}
+		logger.Infof("Done pruning expired operations history")
+	}
+
+	return f, task.Daily()
+}
+
+func pruneOperationsHistory(d *Daemon) error {
+	expiry, err := cluster.ConfigGetInt64(d.cluster, "core.operations_history_expiry")
+	if err != nil {
+		return errors.Wrap(err, "Unable to fetch cluster configuration")
+	}
+
+	// With the history disabled, everything is removed.
+	before := time.Now().Add(-time.Duration(expiry) * 24 * time.Hour)
+
+	return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.OperationHistoryPrune(before)
+	})
+}
+
 func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 	id := mux.Vars(r)["id"]
 
@@ -316,6 +555,10 @@ func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		if !operationIsVisible(d, r, op.Project(), op.Type()) {
+			return response.Forbidden(nil)
+		}
+
 		_, err = op.WaitFinal(timeout)
 		if err != nil {
 			return response.InternalError(err)
@@ -331,6 +574,8 @@ func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
 	var address string
+	var projectName string
+	var opType db.OperationType
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		operation, err := tx.OperationByUUID(id)
 		if err != nil {
@@ -338,12 +583,18 @@ func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 		}
 
 		address = operation.NodeAddress
+		projectName = operation.Project
+		opType = operation.Type
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	if !operationIsVisible(d, r, projectName, opType) {
+		return response.Forbidden(nil)
+	}
+
 	cert := d.endpoints.NetworkCert()
 	client, err := cluster.Connect(address, cert, false)
 	if err != nil {
@@ -438,3 +689,122 @@ func operationWebsocketGet(d *Daemon, r *http.Request) response.Response {
 
 	return &forwardedOperationWebSocket{r, id, source}
 }
+
+// Create a token operation of the given type holding the given metadata. The
+// token gets cancelled once core.remote_token_expiry seconds have elapsed.
+func tokenOperationCreate(d *Daemon, opType db.OperationType, meta map[string]interface{}) response.Response {
+	expiry, err := cluster.ConfigGetInt64(d.cluster, "core.remote_token_expiry")
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if expiry > 0 {
+		meta["expires_at"] = time.Now().Add(time.Duration(expiry) * time.Second)
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassToken, opType, nil, meta, nil, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	if expiry > 0 {
+		time.AfterFunc(time.Duration(expiry)*time.Second, func() {
+			op.Cancel()
+		})
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// Look for a running token operation of the given type whose secret matches
+// the given one, both on this node and on the other cluster members. Tokens
+// are single-use, so a matching operation is cancelled before being returned.
+// Cancelling only succeeds once, which makes sure that a token can't be used
+// by concurrent requests. The metadata of a matching operation are first
+// passed to the given function, if any, and the token is left untouched if it
+// returns false.
+func tokenOperationValid(d *Daemon, opType db.OperationType, secret string, accept func(metadata map[string]interface{}) bool) (*api.Operation, error) {
+	if secret == "" {
+		return nil, nil
+	}
+
+	var dbOps []db.Operation
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		dbOps, err = tx.OperationsOfType(opType)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cert := d.endpoints.NetworkCert()
+	for _, dbOp := range dbOps {
+		// First check if the token is a local operation from this node
+		op, err := operations.OperationGetInternal(dbOp.UUID)
+		if err == nil {
+			if !tokenSecretMatches(op.Metadata()["secret"], secret) {
+				continue
+			}
+
+			if accept != nil && !accept(op.Metadata()) {
+				return nil, nil
+			}
+
+			// The operation may have been used or have expired meanwhile.
+			_, err = op.Cancel()
+			if err != nil {
+				continue
+			}
+
+			_, apiOp, err := op.Render()
+			if err != nil {
+				return nil, err
+			}
+
+			return apiOp, nil
+		}
+
+		// Then ask the node the token operation is running on
+		client, err := cluster.Connect(dbOp.NodeAddress, cert, true)
+		if err != nil {
+			return nil, err
+		}
+
+		apiOp, _, err := client.GetOperation(dbOp.UUID)
+		if err != nil {
+			logger.Warn("Failed to fetch token operation", log.Ctx{"operation": dbOp.UUID, "address": dbOp.NodeAddress, "err": err})
+			continue
+		}
+
+		if apiOp.StatusCode != api.Running || !tokenSecretMatches(apiOp.Metadata["secret"], secret) {
+			continue
+		}
+
+		if accept != nil && !accept(apiOp.Metadata) {
+			return nil, nil
+		}
+
+		// The operation is cancelled by its node, which fails if it has
+		// been used or has expired meanwhile.
+		err = client.DeleteOperation(dbOp.UUID)
+		if err != nil {
+			logger.Warn("Failed to cancel token operation", log.Ctx{"operation": dbOp.UUID, "address": dbOp.NodeAddress, "err": err})
+			continue
+		}
+
+		return apiOp, nil
+	}
+
+	return nil, nil
+}
+
+// Compare the secret of a token operation with the given one in constant time.
+func tokenSecretMatches(value interface{}, secret string) bool {
+	opSecret, ok := value.(string)
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(opSecret), []byte(secret)) == 1
+}
@@ -3,8 +3,11 @@
 package operations
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 )
 
@@ -36,6 +39,45 @@ func removeDBOperation(op *Operation) error {
 	return err
 }
 
+func recordDBOperationHistory(op *Operation) error {
+	if op.state == nil {
+		return nil
+	}
+
+	entry := db.OperationHistory{
+		UUID:        op.id,
+		Project:     op.project,
+		Type:        op.dbOpType,
+		Class:       op.class.String(),
+		Description: op.description,
+		Resources:   op.resourceURLs(),
+		CreatedAt:   op.createdAt,
+		FinishedAt:  time.Now(),
+		StatusCode:  int(op.status),
+		Err:         op.err,
+	}
+
+	if op.requestor != nil {
+		entry.RequestorUsername = op.requestor.Username
+		entry.RequestorProtocol = op.requestor.Protocol
+		entry.RequestorAddress = op.requestor.Address
+	}
+
+	return op.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		// The history is disabled
+		if config.OperationsHistoryExpiry() <= 0 {
+			return nil
+		}
+
+		return tx.OperationHistoryAdd(entry)
+	})
+}
+
 func getServerName(op *Operation) (string, error) {
 	if op.state == nil {
 		return "", nil
@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sync"
@@ -12,6 +13,7 @@ import (
 	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/tracing"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/cancel"
@@ -93,12 +95,21 @@ type Operation struct {
 	canceler    *cancel.Canceler
 	description string
 	permission  string
+	dbOpType    db.OperationType
+	requestor   *api.OperationRequestor
+
+	// Context of the operation, holding its span once running
+	ctx  context.Context
+	span tracing.Span
 
 	// Those functions are called at various points in the Operation lifecycle
 	onRun     func(*Operation) error
 	onCancel  func(*Operation) error
 	onConnect func(*Operation, *http.Request, http.ResponseWriter) error
 
+	// Functions called once the operation is done
+	onDone []func(*Operation)
+
 	// Channels used for error reporting and state tracking of background actions
 	chanDone chan error
 
@@ -118,6 +129,7 @@ func OperationCreate(s *state.State, project string, opClass operationClass, opT
 	op.id = uuid.NewRandom().String()
 	op.description = opType.Description()
 	op.permission = opType.Permission()
+	op.dbOpType = opType
 	op.class = opClass
 	op.createdAt = time.Now()
 	op.updatedAt = op.createdAt
@@ -126,6 +138,7 @@ func OperationCreate(s *state.State, project string, opClass operationClass, opT
 	op.resources = opResources
 	op.chanDone = make(chan error)
 	op.state = s
+	op.ctx = context.Background()
 
 	if s != nil {
 		op.SetEventServer(s.Events)
@@ -185,14 +198,34 @@ func (op *Operation) done() {
 		return
 	}
 
+	err := recordDBOperationHistory(op)
+	if err != nil {
+		logger.Warnf("Failed to record operation %s in history: %s", op.id, err)
+	}
+
 	op.lock.Lock()
+	if op.span != nil {
+		var err error
+		if op.err != "" {
+			err = fmt.Errorf("%s", op.err)
+		}
+
+		tracing.End(op.span, err)
+	}
+
 	op.readonly = true
 	op.onRun = nil
 	op.onCancel = nil
 	op.onConnect = nil
+	onDone := op.onDone
+	op.onDone = nil
 	close(op.chanDone)
 	op.lock.Unlock()
 
+	for _, f := range onDone {
+		f(op)
+	}
+
 	time.AfterFunc(time.Second*5, func() {
 		operationsLock.Lock()
 		_, ok := operations[op.id]
@@ -226,6 +259,10 @@ func (op *Operation) Run() (chan error, error) {
 
 	op.lock.Lock()
 	op.status = api.Running
+	op.ctx, op.span = tracing.Start(op.ctx, op.description,
+		tracing.String("lxd.operation.id", op.id),
+		tracing.String("lxd.operation.class", op.class.String()),
+		tracing.String("lxd.project", op.project))
 
 	if op.onRun != nil {
 		go func(op *Operation, chanRun chan error) {
@@ -267,19 +304,22 @@ func (op *Operation) Run() (chan error, error) {
 }
 
 // Cancel cancels a running operation. If the operation cannot be cancelled, it
-// returns an error.
+// returns an error. Only one of concurrent calls can cancel the operation, the
+// others getting an error.
 func (op *Operation) Cancel() (chan error, error) {
+	op.lock.Lock()
 	if op.status != api.Running {
+		op.lock.Unlock()
 		return nil, fmt.Errorf("Only running operations can be cancelled")
 	}
 
 	if !op.mayCancel() {
+		op.lock.Unlock()
 		return nil, fmt.Errorf("This Operation can't be cancelled")
 	}
 
 	chanCancel := make(chan error, 1)
 
-	op.lock.Lock()
 	oldStatus := op.status
 	op.status = api.Cancelling
 	op.lock.Unlock()
@@ -391,18 +431,7 @@ func (op *Operation) mayCancel() bool {
 // Render renders the operation structure.
 func (op *Operation) Render() (string, *api.Operation, error) {
 	// Setup the resource URLs
-	resources := op.resources
-	if resources != nil {
-		tmpResources := make(map[string][]string)
-		for key, value := range resources {
-			var values []string
-			for _, c := range value {
-				values = append(values, fmt.Sprintf("/%s/%s/%s", version.APIVersion, key, c))
-			}
-			tmpResources[key] = values
-		}
-		resources = tmpResources
-	}
+	resources := op.resourceURLs()
 
 	// Local server name
 	var err error
@@ -424,9 +453,27 @@ func (op *Operation) Render() (string, *api.Operation, error) {
 		MayCancel:   op.mayCancel(),
 		Err:         op.err,
 		Location:    serverName,
+		Requestor:   op.requestor,
 	}, nil
 }
 
+func (op *Operation) resourceURLs() map[string][]string {
+	if op.resources == nil {
+		return nil
+	}
+
+	resources := make(map[string][]string)
+	for key, value := range op.resources {
+		var values []string
+		for _, c := range value {
+			values = append(values, fmt.Sprintf("/%s/%s/%s", version.APIVersion, key, c))
+		}
+		resources[key] = values
+	}
+
+	return resources
+}
+
 // WaitFinal waits for the operation to be done. If timeout is -1, it will wait
 // indefinitely otherwise it will timeout after {timeout} seconds.
 func (op *Operation) WaitFinal(timeout int) (bool, error) {
@@ -456,6 +503,20 @@ func (op *Operation) WaitFinal(timeout int) (bool, error) {
 	return false, nil
 }
 
+// OnDone registers a function to call once the operation is done, calling it
+// right away if it already is.
+func (op *Operation) OnDone(f func(*Operation)) {
+	op.lock.Lock()
+	if !op.readonly {
+		op.onDone = append(op.onDone, f)
+		op.lock.Unlock()
+		return
+	}
+	op.lock.Unlock()
+
+	f(op)
+}
+
 // UpdateResources updates the resources of the operation. It returns an error
 // if the operation is not pending or running, or the operation is read-only.
 func (op *Operation) UpdateResources(opResources map[string][]string) error {
@@ -532,11 +593,39 @@ func (op *Operation) SetCanceler(canceler *cancel.Canceler) {
 	op.canceler = canceler
 }
 
+// SetRequestor sets the client which requested the operation.
+func (op *Operation) SetRequestor(requestor api.OperationRequestor) {
+	op.lock.Lock()
+	op.requestor = &requestor
+	op.lock.Unlock()
+}
+
+// SetContext sets the context of the request which created the operation, so
+// that the operation is traced as part of the request.
+func (op *Operation) SetContext(ctx context.Context) {
+	op.lock.Lock()
+	op.ctx = tracing.Detach(ctx)
+	op.lock.Unlock()
+}
+
+// Context returns the context of the operation, to trace the work done by it.
+func (op *Operation) Context() context.Context {
+	op.lock.Lock()
+	defer op.lock.Unlock()
+
+	return op.ctx
+}
+
 // Permission returns the operation permission.
 func (op *Operation) Permission() string {
 	return op.permission
 }
 
+// Type returns the db operation type.
+func (op *Operation) Type() db.OperationType {
+	return op.dbOpType
+}
+
 // Project returns the operation project.
 func (op *Operation) Project() string {
 	return op.project
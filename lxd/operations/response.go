@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -20,6 +21,40 @@ func OperationResponse(op *Operation) response.Response {
 	return &operationResponse{op}
 }
 
+// SetRequestor sets the client which requested the operation of the given
+// response, if it's an operation response.
+func SetRequestor(resp response.Response, requestor api.OperationRequestor) {
+	opResp, ok := resp.(*operationResponse)
+	if !ok {
+		return
+	}
+
+	opResp.op.SetRequestor(requestor)
+}
+
+// SetContext sets the context of the request which created the operation of
+// the given response, if it's an operation response.
+func SetContext(resp response.Response, ctx context.Context) {
+	opResp, ok := resp.(*operationResponse)
+	if !ok {
+		return
+	}
+
+	opResp.op.SetContext(ctx)
+}
+
+// OnDone registers a function to call once the operation of the given
+// response is done, returning false if it isn't an operation response.
+func OnDone(resp response.Response, f func(*Operation)) bool {
+	opResp, ok := resp.(*operationResponse)
+	if !ok {
+		return false
+	}
+
+	opResp.op.OnDone(f)
+	return true
+}
+
 func (r *operationResponse) Render(w http.ResponseWriter) error {
 	_, err := r.op.Run()
 	if err != nil {
@@ -116,6 +116,12 @@ func profilesPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	// Check the project restrictions
+	err = projectAllowProfile(d.cluster, project, req.Config, req.Devices)
+	if err != nil {
+		return projectRestrictionResponse(err)
+	}
+
 	// Update DB entry
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		hasProfiles, err := tx.ProjectHasProfiles(project)
@@ -252,6 +258,12 @@ func profilePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	// Check the project restrictions
+	err = projectAllowProfile(d.cluster, projectParam(r), req.Config, req.Devices)
+	if err != nil {
+		return projectRestrictionResponse(err)
+	}
+
 	err = doProfileUpdate(d, project, name, id, profile, req)
 
 	if err == nil && !isClusterNotification(r) {
@@ -361,6 +373,12 @@ func profilePatch(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	// Check the project restrictions
+	err = projectAllowProfile(d.cluster, projectParam(r), req.Config, req.Devices)
+	if err != nil {
+		return projectRestrictionResponse(err)
+	}
+
 	return response.SmartError(doProfileUpdate(d, project, name, id, profile, req))
 }
 
@@ -0,0 +1,308 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// RestrictionDefaults holds the value used for each restricted.* key when the
+// project is restricted and the key is not set.
+var RestrictionDefaults = map[string]string{
+	"restricted.containers.nesting":        "block",
+	"restricted.containers.lowlevel":       "block",
+	"restricted.containers.privilege":      "unprivileged",
+	"restricted.virtual-machines.lowlevel": "block",
+	"restricted.devices.unix-char":         "block",
+	"restricted.devices.unix-block":        "block",
+	"restricted.devices.unix-hotplug":      "block",
+	"restricted.devices.usb":               "block",
+	"restricted.devices.gpu":               "block",
+	"restricted.devices.infiniband":        "block",
+	"restricted.devices.proxy":             "block",
+	"restricted.devices.nic":               "managed",
+	"restricted.devices.nic.parents":       "",
+	"restricted.devices.disk":              "managed",
+	"restricted.devices.disk.paths":        "",
+}
+
+// RestrictionValidators holds the config validators for the restricted.* keys.
+var RestrictionValidators = map[string]func(value string) error{
+	"restricted": shared.IsBool,
+	"restricted.containers.nesting": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.containers.lowlevel": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.containers.privilege": func(value string) error {
+		return shared.IsOneOf(value, []string{"unprivileged", "isolated", "allow"})
+	},
+	"restricted.virtual-machines.lowlevel": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.devices.unix-char": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.devices.unix-block": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.devices.unix-hotplug": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.devices.usb": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.devices.gpu": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.devices.infiniband": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.devices.proxy": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "allow"})
+	},
+	"restricted.devices.nic": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "managed", "allow"})
+	},
+	"restricted.devices.nic.parents": shared.IsAny,
+	"restricted.devices.disk": func(value string) error {
+		return shared.IsOneOf(value, []string{"block", "managed", "allow"})
+	},
+	"restricted.devices.disk.paths": func(value string) error {
+		for _, path := range restrictionList(value) {
+			if !filepath.IsAbs(path) {
+				return fmt.Errorf("Host path %q is not absolute", path)
+			}
+		}
+
+		return nil
+	},
+}
+
+// IsRestricted returns true if the given project configuration has the
+// restricted mode enabled.
+func IsRestricted(projectConfig map[string]string) bool {
+	return shared.IsTrue(projectConfig["restricted"])
+}
+
+// RestrictionsChanged returns true if any of the restricted keys differ
+// between the two given project configurations.
+func RestrictionsChanged(oldConfig map[string]string, newConfig map[string]string) bool {
+	for key := range RestrictionValidators {
+		if oldConfig[key] != newConfig[key] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RestrictionError is returned when a configuration isn't permitted by the
+// restrictions of a project.
+type RestrictionError struct {
+	err error
+}
+
+func (e RestrictionError) Error() string {
+	return e.err.Error()
+}
+
+// AllowInstance checks that the given expanded instance config and devices
+// are permitted by the restrictions of the project.
+//
+// The managedNetworks argument lists the networks managed by LXD, which are
+// the only valid NIC parents when restricted.devices.nic is "managed".
+func AllowInstance(projectConfig map[string]string, config map[string]string, devices map[string]map[string]string, managedNetworks []string) error {
+	err := checkRestrictions(projectConfig, config, devices, managedNetworks, true)
+	if err != nil {
+		return RestrictionError{err: err}
+	}
+
+	return nil
+}
+
+// AllowProfile checks that the given profile config and devices are
+// permitted by the restrictions of the project.
+//
+// Unlike AllowInstance, this doesn't require keys which may be provided by
+// another profile or by the instance itself (e.g. security.idmap.isolated).
+func AllowProfile(projectConfig map[string]string, config map[string]string, devices map[string]map[string]string, managedNetworks []string) error {
+	err := checkRestrictions(projectConfig, config, devices, managedNetworks, false)
+	if err != nil {
+		return RestrictionError{err: err}
+	}
+
+	return nil
+}
+
+func checkRestrictions(projectConfig map[string]string, config map[string]string, devices map[string]map[string]string, managedNetworks []string, expanded bool) error {
+	if !IsRestricted(projectConfig) {
+		return nil
+	}
+
+	restriction := func(key string) string {
+		value, ok := projectConfig[key]
+		if !ok || value == "" {
+			return RestrictionDefaults[key]
+		}
+
+		return value
+	}
+
+	// Instance and profile configuration keys.
+	for key, value := range config {
+		switch {
+		case key == "security.nesting":
+			if restriction("restricted.containers.nesting") == "block" && shared.IsTrue(value) {
+				return fmt.Errorf("Container nesting is forbidden in restricted project")
+			}
+
+		case key == "security.privileged":
+			if restriction("restricted.containers.privilege") != "allow" && shared.IsTrue(value) {
+				return fmt.Errorf("Privileged containers are forbidden in restricted project")
+			}
+
+		case key == "security.idmap.isolated":
+			if restriction("restricted.containers.privilege") == "isolated" && value != "" && !shared.IsTrue(value) {
+				return fmt.Errorf("Non-isolated containers are forbidden in restricted project")
+			}
+
+		case key == "raw.qemu":
+			if restriction("restricted.virtual-machines.lowlevel") == "block" && value != "" {
+				return fmt.Errorf("Use of low-level option %q is forbidden in restricted project", key)
+			}
+
+		case strings.HasPrefix(key, "raw.") || key == "linux.kernel_modules" || strings.HasPrefix(key, "security.idmap.base"):
+			if restriction("restricted.containers.lowlevel") == "block" && value != "" {
+				return fmt.Errorf("Use of low-level option %q is forbidden in restricted project", key)
+			}
+		}
+	}
+
+	if expanded && restriction("restricted.containers.privilege") == "isolated" && !shared.IsTrue(config["security.idmap.isolated"]) {
+		return fmt.Errorf("Containers in restricted project must use security.idmap.isolated")
+	}
+
+	// Devices.
+	for name, device := range devices {
+		err := checkDeviceRestrictions(restriction, name, device, managedNetworks)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkDeviceRestrictions(restriction func(key string) string, name string, device map[string]string, managedNetworks []string) error {
+	switch device["type"] {
+	case "unix-char", "unix-block", "unix-hotplug", "usb", "gpu", "infiniband", "proxy":
+		if restriction(fmt.Sprintf("restricted.devices.%s", device["type"])) == "block" {
+			return fmt.Errorf("Device %q of type %q is forbidden in restricted project", name, device["type"])
+		}
+
+	case "nic":
+		mode := restriction("restricted.devices.nic")
+		if mode == "block" {
+			return fmt.Errorf("Device %q of type \"nic\" is forbidden in restricted project", name)
+		}
+
+		parent := device["parent"]
+		if mode == "managed" && !shared.StringInSlice(parent, managedNetworks) {
+			return fmt.Errorf("Device %q must use a managed network as parent in restricted project", name)
+		}
+
+		parents := restrictionList(restriction("restricted.devices.nic.parents"))
+		if len(parents) > 0 && !shared.StringInSlice(parent, parents) {
+			return fmt.Errorf("Device %q uses parent %q which isn't allowed in restricted project", name, parent)
+		}
+
+	case "disk":
+		// The root disk and storage volumes are always allowed.
+		if device["pool"] != "" || device["source"] == "" {
+			return nil
+		}
+
+		mode := restriction("restricted.devices.disk")
+		if mode == "block" {
+			return fmt.Errorf("Device %q of type \"disk\" is forbidden in restricted project", name)
+		}
+
+		if mode == "allow" {
+			return nil
+		}
+
+		// Managed mode only allows host paths below one of the allowed prefixes.
+		if !isPathAllowed(device["source"], restrictionList(restriction("restricted.devices.disk.paths"))) {
+			return fmt.Errorf("Disk source path %q of device %q is forbidden in restricted project", device["source"], name)
+		}
+	}
+
+	return nil
+}
+
+// isPathAllowed returns true if the given host path is one of the given
+// prefixes or is located underneath one of them, once symlinks are resolved.
+func isPathAllowed(path string, prefixes []string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+
+	path, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+
+	for _, prefix := range prefixes {
+		prefix, err := resolvePath(prefix)
+		if err != nil {
+			continue
+		}
+
+		if path == prefix || strings.HasPrefix(path, prefix+"/") || prefix == "/" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolvePath returns the given absolute path with all symlinks resolved. The
+// components which don't exist yet are kept as they are.
+func resolvePath(path string) (string, error) {
+	path = filepath.Clean(path)
+	missing := ""
+
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+
+		if !os.IsNotExist(err) || path == "/" {
+			return "", err
+		}
+
+		missing = filepath.Join(filepath.Base(path), missing)
+		path = filepath.Dir(path)
+	}
+}
+
+// restrictionList splits a comma separated restriction value.
+func restrictionList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		list = append(list, item)
+	}
+
+	return list
+}
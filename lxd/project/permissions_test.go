@@ -0,0 +1,168 @@
+package project_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Unrestricted projects allow everything.
+func TestAllowInstance_Unrestricted(t *testing.T) {
+	config := map[string]string{"security.privileged": "true", "raw.lxc": "lxc.aa_profile=unconfined"}
+	devices := map[string]map[string]string{
+		"char": {"type": "unix-char", "path": "/dev/kvm"},
+	}
+
+	assert.NoError(t, project.AllowInstance(map[string]string{}, config, devices, nil))
+}
+
+// Restricted projects forbid privileged and low-level configuration by default.
+func TestAllowInstance_RestrictedConfig(t *testing.T) {
+	restricted := map[string]string{"restricted": "true"}
+
+	cases := []map[string]string{
+		{"security.privileged": "true"},
+		{"security.nesting": "true"},
+		{"raw.lxc": "lxc.aa_profile=unconfined"},
+		{"raw.idmap": "both 1000 1000"},
+		{"raw.qemu": "-S"},
+	}
+
+	for _, config := range cases {
+		assert.Error(t, project.AllowInstance(restricted, config, nil, nil), config)
+	}
+
+	assert.NoError(t, project.AllowInstance(restricted, map[string]string{"security.privileged": "false"}, nil, nil))
+
+	allowed := map[string]string{
+		"restricted":                      "true",
+		"restricted.containers.privilege": "allow",
+		"restricted.containers.nesting":   "allow",
+		"restricted.containers.lowlevel":  "allow",
+	}
+
+	for _, config := range cases[:4] {
+		assert.NoError(t, project.AllowInstance(allowed, config, nil, nil), config)
+	}
+}
+
+// The isolated privilege level requires security.idmap.isolated on the
+// expanded instance config but not on individual profiles.
+func TestAllowInstance_Isolated(t *testing.T) {
+	restricted := map[string]string{"restricted": "true", "restricted.containers.privilege": "isolated"}
+
+	assert.Error(t, project.AllowInstance(restricted, map[string]string{}, nil, nil))
+	assert.NoError(t, project.AllowInstance(restricted, map[string]string{"security.idmap.isolated": "true"}, nil, nil))
+	assert.NoError(t, project.AllowProfile(restricted, map[string]string{}, nil, nil))
+	assert.Error(t, project.AllowProfile(restricted, map[string]string{"security.idmap.isolated": "false"}, nil, nil))
+}
+
+// Devices are checked by type, NIC parent and disk source path.
+func TestAllowInstance_RestrictedDevices(t *testing.T) {
+	restricted := map[string]string{
+		"restricted":                    "true",
+		"restricted.devices.disk.paths": "/srv/shared,/opt/data/",
+	}
+
+	networks := []string{"lxdbr0"}
+
+	cases := []struct {
+		device map[string]string
+		ok     bool
+	}{
+		{map[string]string{"type": "unix-char", "path": "/dev/kvm"}, false},
+		{map[string]string{"type": "gpu"}, false},
+		{map[string]string{"type": "nic", "nictype": "bridged", "parent": "lxdbr0"}, true},
+		{map[string]string{"type": "nic", "nictype": "macvlan", "parent": "eth0"}, false},
+		{map[string]string{"type": "disk", "path": "/", "pool": "default"}, true},
+		{map[string]string{"type": "disk", "path": "/data", "pool": "default", "source": "vol"}, true},
+		{map[string]string{"type": "disk", "path": "/mnt", "source": "/srv/shared/team"}, true},
+		{map[string]string{"type": "disk", "path": "/mnt", "source": "/opt/data"}, true},
+		{map[string]string{"type": "disk", "path": "/mnt", "source": "/srv/sharedfoo"}, false},
+		{map[string]string{"type": "disk", "path": "/mnt", "source": "/srv/shared/../../etc"}, false},
+		{map[string]string{"type": "disk", "path": "/mnt", "source": "/etc"}, false},
+	}
+
+	for _, c := range cases {
+		err := project.AllowInstance(restricted, nil, map[string]map[string]string{"dev": c.device}, networks)
+		if c.ok {
+			assert.NoError(t, err, c.device)
+		} else {
+			assert.Error(t, err, c.device)
+		}
+	}
+}
+
+// Symlinks are resolved before checking disk source paths.
+func TestAllowInstance_DiskSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-project-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	shared := filepath.Join(dir, "shared")
+	require.NoError(t, os.Mkdir(shared, 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "other"), 0755))
+	require.NoError(t, os.Symlink("/etc", filepath.Join(shared, "etc")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "other"), filepath.Join(shared, "other")))
+	require.NoError(t, os.Symlink(shared, filepath.Join(dir, "link")))
+
+	restricted := map[string]string{
+		"restricted":                    "true",
+		"restricted.devices.disk.paths": filepath.Join(dir, "link"),
+	}
+
+	cases := []struct {
+		source string
+		ok     bool
+	}{
+		{shared, true},
+		{filepath.Join(shared, "missing", "dir"), true},
+		{filepath.Join(shared, "etc"), false},
+		{filepath.Join(shared, "etc", "missing"), false},
+		{filepath.Join(shared, "other"), false},
+	}
+
+	for _, c := range cases {
+		disk := map[string]map[string]string{"dev": {"type": "disk", "path": "/mnt", "source": c.source}}
+		err := project.AllowInstance(restricted, nil, disk, nil)
+		if c.ok {
+			assert.NoError(t, err, c.source)
+		} else {
+			assert.Error(t, err, c.source)
+			assert.IsType(t, project.RestrictionError{}, err)
+		}
+	}
+}
+
+// Allowed NIC parents can be further limited with restricted.devices.nic.parents.
+func TestAllowInstance_NICParents(t *testing.T) {
+	restricted := map[string]string{
+		"restricted":                     "true",
+		"restricted.devices.nic":         "allow",
+		"restricted.devices.nic.parents": "eth1, eth2",
+	}
+
+	nic := func(parent string) map[string]map[string]string {
+		return map[string]map[string]string{"eth0": {"type": "nic", "nictype": "macvlan", "parent": parent}}
+	}
+
+	assert.NoError(t, project.AllowInstance(restricted, nil, nic("eth2"), nil))
+	assert.Error(t, project.AllowInstance(restricted, nil, nic("eth0"), nil))
+
+	restricted["restricted.devices.nic"] = "block"
+	assert.Error(t, project.AllowInstance(restricted, nil, nic("eth2"), nil))
+}
+
+// Restriction changes are detected.
+func TestRestrictionsChanged(t *testing.T) {
+	old := map[string]string{"features.images": "true", "restricted": "true"}
+
+	assert.False(t, project.RestrictionsChanged(old, map[string]string{"features.images": "false", "restricted": "true"}))
+	assert.True(t, project.RestrictionsChanged(old, map[string]string{"restricted": "false"}))
+	assert.True(t, project.RestrictionsChanged(old, map[string]string{"restricted": "true", "restricted.devices.gpu": "allow"}))
+}
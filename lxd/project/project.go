@@ -2,6 +2,8 @@ package project
 
 import (
 	"fmt"
+
+	"github.com/lxc/lxd/lxd/db"
 )
 
 // Prefix Add the "<project>_" prefix when the given project name is not "default".
@@ -11,3 +13,63 @@ func Prefix(project string, s string) string {
 	}
 	return s
 }
+
+// StorageVolumeProject returns the name of the project that holds storage
+// volumes of the given type on behalf of the given project.
+//
+// Custom volumes live in the "default" project unless the given project has
+// the "features.storage.volumes" feature enabled. Image volumes are shared
+// and always live in the "default" project, while instance volumes always
+// belong to the project of their instance.
+func StorageVolumeProject(c *db.Cluster, projectName string, volumeType int) (string, error) {
+	if volumeType == db.StoragePoolVolumeTypeImage {
+		return "default", nil
+	}
+
+	if projectName == "default" || volumeType != db.StoragePoolVolumeTypeCustom {
+		return projectName, nil
+	}
+
+	var enabled bool
+	err := c.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		enabled, err = tx.ProjectHasStorageVolumes(projectName)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !enabled {
+		return "default", nil
+	}
+
+	return projectName, nil
+}
+
+// NetworkProject returns the name of the project that holds the networks
+// visible from the given project.
+//
+// Networks live in the "default" project unless the given project has the
+// "features.networks" feature enabled.
+func NetworkProject(c *db.Cluster, projectName string) (string, error) {
+	if projectName == "default" {
+		return projectName, nil
+	}
+
+	var enabled bool
+	err := c.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		enabled, err = tx.ProjectHasNetworks(projectName)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !enabled {
+		return "default", nil
+	}
+
+	return projectName, nil
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/tracing"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -64,6 +65,12 @@ func SyncResponseRedirect(address string) Response {
 	return &syncResponse{success: true, location: address, code: http.StatusPermanentRedirect}
 }
 
+// SyncResponseCode returns a new syncResponse with the given HTTP status
+// code.
+func SyncResponseCode(success bool, metadata interface{}, code int) Response {
+	return &syncResponse{success: success, metadata: metadata, code: code}
+}
+
 // SyncResponseHeaders returns a new syncResponse with headers.
 func SyncResponseHeaders(success bool, metadata interface{}, headers map[string]string) Response {
 	return &syncResponse{success: success, metadata: metadata, headers: headers}
@@ -97,6 +104,8 @@ func (r *syncResponse) Render(w http.ResponseWriter) error {
 			code = 201
 		}
 		w.WriteHeader(code)
+	} else if r.code != 0 {
+		w.WriteHeader(r.code)
 	}
 
 	resp := api.ResponseRaw{
@@ -372,6 +381,9 @@ func (r *forwardedResponse) Render(w http.ResponseWriter) error {
 		forwarded.Header.Set(key, r.request.Header.Get(key))
 	}
 
+	// Trace the request on the other member as part of this one.
+	tracing.Inject(r.request.Context(), forwarded.Header)
+
 	httpClient, err := r.client.GetHTTPClient()
 	if err != nil {
 		return err
@@ -18,6 +18,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/migration"
 	"github.com/lxc/lxd/lxd/operations"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
@@ -414,7 +415,12 @@ func storagePoolInit(s *state.State, poolName string) (storage, error) {
 }
 
 func storagePoolVolumeAttachInit(s *state.State, poolName string, volumeName string, volumeType int, c *containerLXC) (storage, error) {
-	st, err := storageInit(s, "default", poolName, volumeName, volumeType)
+	volumeProject, err := projecthelpers.StorageVolumeProject(s.Cluster, c.Project(), volumeType)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := storageInit(s, volumeProject, poolName, volumeName, volumeType)
 	if err != nil {
 		return nil, err
 	}
@@ -460,24 +466,20 @@ func storagePoolVolumeAttachInit(s *state.State, poolName string, volumeName str
 	poolVolumePut.Config["volatile.idmap.next"] = nextJsonMap
 
 	// get mountpoint of storage volume
-	remapPath := storagePools.GetStoragePoolVolumeMountPoint(poolName, volumeName)
+	remapPath := storagePools.GetStoragePoolVolumeMountPoint(poolName, projecthelpers.Prefix(volumeProject, volumeName))
 
 	if !nextIdmap.Equals(lastIdmap) {
 		logger.Debugf("Shifting storage volume")
 
 		if !shared.IsTrue(poolVolumePut.Config["security.shifted"]) {
-			volumeUsedBy, err := storagePoolVolumeUsedByInstancesGet(s, "default", poolName, volumeName)
+			volumeUsedBy, err := storagePoolVolumeUsedByInstancesGet(s, volumeProject, poolName, volumeName)
 			if err != nil {
 				return nil, err
 			}
 
 			if len(volumeUsedBy) > 1 {
-				for _, ctName := range volumeUsedBy {
-					instt, err := instance.LoadByProjectAndName(s, c.Project(), ctName)
-					if err != nil {
-						continue
-					}
-
+				for _, instt := range volumeUsedBy {
+					ctName := instt.Name()
 					if instt.Type() != instancetype.Container {
 						continue
 					}
@@ -502,7 +504,7 @@ func storagePoolVolumeAttachInit(s *state.State, poolName string, volumeName str
 				// If we're the only one who's attached that container
 				// we can shift the storage volume.
 				// I'm not sure if we want some locking here.
-				if volumeUsedBy[0] != c.Name() {
+				if volumeUsedBy[0].Project() != c.Project() || volumeUsedBy[0].Name() != c.Name() {
 					return nil, fmt.Errorf("idmaps of container and storage volume are not identical")
 				}
 			}
@@ -575,7 +577,7 @@ func storagePoolVolumeAttachInit(s *state.State, poolName string, volumeName str
 	if err != nil {
 		return nil, err
 	}
-	err = s.Cluster.StoragePoolVolumeUpdate(volumeName, volumeType, poolID, poolVolumePut.Description, poolVolumePut.Config)
+	err = s.Cluster.StoragePoolVolumeUpdate(volumeProject, volumeName, volumeType, poolID, poolVolumePut.Description, poolVolumePut.Config)
 	if err != nil {
 		return nil, err
 	}
@@ -848,9 +850,8 @@ func storageVolumeMount(state *state.State, poolName string, volumeName string,
 }
 
 // storageVolumeUmount unmounts a storage volume on a pool.
-func storageVolumeUmount(state *state.State, poolName string, volumeName string, volumeType int) error {
-	// Custom storage volumes do not currently support projects, so hardcode "default" project.
-	s, err := storagePoolVolumeInit(state, "default", poolName, volumeName, volumeType)
+func storageVolumeUmount(state *state.State, projectName string, poolName string, volumeName string, volumeType int) error {
+	s, err := storagePoolVolumeInit(state, projectName, poolName, volumeName, volumeType)
 	if err != nil {
 		return err
 	}
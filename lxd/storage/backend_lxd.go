@@ -7,9 +7,13 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
 
 	"github.com/lxc/lxd/lxd/backup"
 	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/migration"
@@ -118,6 +122,27 @@ func (b *lxdBackend) newVolume(volType drivers.VolumeType, contentType drivers.C
 	return drivers.NewVolume(b.driver, b.name, volType, contentType, volName, volConfig)
 }
 
+// setupVolumeEncryption sets the encryption of a new block volume from the pool's
+// volume.block.encryption default, and picks the LUKS UUID that its key is stored under in the
+// server-side key store.
+func (b *lxdBackend) setupVolumeEncryption(contentType drivers.ContentType, config map[string]string) {
+	if contentType != drivers.ContentTypeBlock {
+		return
+	}
+
+	encryption := config["block.encryption"]
+	if encryption == "" {
+		encryption = b.driver.Config()["volume.block.encryption"]
+	}
+
+	if encryption == "" {
+		return
+	}
+
+	config["block.encryption"] = encryption
+	config["volatile.encryption.uuid"] = uuid.NewRandom().String()
+}
+
 func (b *lxdBackend) GetResources() (*api.ResourcesStoragePool, error) {
 	logger := logging.AddContext(b.logger, nil)
 	logger.Debug("GetResources started")
@@ -301,6 +326,8 @@ func (b *lxdBackend) CreateInstance(inst instance.Instance, op *operations.Opera
 		return err
 	}
 
+	b.setupVolumeEncryption(contentType, rootDiskConf)
+
 	// Get the volume name on storage.
 	volStorageName := project.Prefix(inst.Project(), inst.Name())
 
@@ -488,7 +515,7 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 		// If we are copying snapshots, retrieve a list of snapshots from source volume.
 		snapshotNames := []string{}
 		if snapshots {
-			snapshots, err := VolumeSnapshotsGet(b.state, srcPool.Name(), src.Name(), volDBType)
+			snapshots, err := VolumeSnapshotsGet(b.state, src.Project(), srcPool.Name(), src.Name(), volDBType)
 			if err != nil {
 				return err
 			}
@@ -753,6 +780,8 @@ func (b *lxdBackend) CreateInstanceFromImage(inst instance.Instance, fingerprint
 		return err
 	}
 
+	b.setupVolumeEncryption(contentType, rootDiskConf)
+
 	// Get the volume name on storage.
 	volStorageName := project.Prefix(inst.Project(), inst.Name())
 
@@ -1111,6 +1140,244 @@ func (b *lxdBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCl
 	return nil
 }
 
+// MoveInstanceFromPool moves a stopped instance's volume and its snapshots from srcPool onto this
+// pool. The volumes are streamed over an in-memory pipe using the migration subsystem, after which
+// the root disk devices of the instance and its snapshots are switched over to this pool. The
+// volumes and their records are only removed from the source pool once the switch-over succeeded.
+func (b *lxdBackend) MoveInstanceFromPool(inst instance.Instance, srcPool Pool, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "srcPool": srcPool.Name()})
+	logger.Debug("MoveInstanceFromPool started")
+	defer logger.Debug("MoveInstanceFromPool finished")
+
+	if inst.IsSnapshot() {
+		return fmt.Errorf("Instance cannot be a snapshot")
+	}
+
+	if inst.IsRunning() {
+		return fmt.Errorf("Instance must be stopped to be moved")
+	}
+
+	if srcPool.Name() == b.name {
+		return fmt.Errorf("Source and target storage pools must be different")
+	}
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	volDBType, err := VolumeTypeToDBType(volType)
+	if err != nil {
+		return err
+	}
+
+	contentType := InstanceContentType(inst)
+	volDBContentType, err := VolumeContentTypeToDBContentType(contentType)
+	if err != nil {
+		return err
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.Prefix(inst.Project(), inst.Name())
+
+	if b.driver.HasVolume(volType, volStorageName) {
+		return fmt.Errorf("Cannot move volume, already exists on target")
+	}
+
+	// Override the instance's root disk device (even if it comes from a profile) so that it
+	// points at this pool once the move is complete.
+	rootDiskKey, rootDiskConf, err := shared.GetRootDiskDevice(inst.ExpandedDevices().CloneNative())
+	if err != nil {
+		return err
+	}
+
+	newRootDisk := deviceConfig.Device{}
+	for k, v := range rootDiskConf {
+		newRootDisk[k] = v
+	}
+	newRootDisk["pool"] = b.name
+
+	newDevices := inst.LocalDevices().Clone()
+	newDevices[rootDiskKey] = newRootDisk
+
+	// Do the same for the snapshots, so that restoring them keeps using this pool.
+	instSnapshots, err := inst.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	oldSnapshotDevices := map[int]deviceConfig.Devices{}
+	newSnapshotDevices := map[int]deviceConfig.Devices{}
+	for _, snap := range instSnapshots {
+		snapRootDiskKey, snapRootDiskConf, err := shared.GetRootDiskDevice(snap.ExpandedDevices().CloneNative())
+		if err != nil {
+			continue
+		}
+
+		snapRootDisk := deviceConfig.Device{}
+		for k, v := range snapRootDiskConf {
+			snapRootDisk[k] = v
+		}
+		snapRootDisk["pool"] = b.name
+
+		oldSnapshotDevices[snap.ID()] = snap.LocalDevices()
+		newSnapshotDevices[snap.ID()] = snap.LocalDevices().Clone()
+		newSnapshotDevices[snap.ID()][snapRootDiskKey] = snapRootDisk
+	}
+
+	// Retrieve the list of snapshots we are moving.
+	snapshots, err := VolumeSnapshotsGet(b.state, inst.Project(), srcPool.Name(), inst.Name(), volDBType)
+	if err != nil {
+		return err
+	}
+
+	snapshotNames := []string{}
+	for _, snapshot := range snapshots {
+		_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snapshot.Name)
+		snapshotNames = append(snapshotNames, snapName)
+	}
+
+	_, dbPool, err := b.state.Cluster.StoragePoolGet(b.name)
+	if err != nil {
+		return err
+	}
+
+	vol := b.newVolume(volType, contentType, volStorageName, rootDiskConf)
+
+	// Create slice to record DB volumes created if revert needed later.
+	revertDBVolumes := []string{}
+	defer func() {
+		// Remove any DB volume rows created if we are reverting.
+		for _, volName := range revertDBVolumes {
+			b.state.Cluster.StoragePoolVolumeDelete(inst.Project(), volName, volDBType, b.ID())
+		}
+	}()
+
+	// Create the database entries for the volume and its snapshots on this pool, these are
+	// needed by the driver when receiving the volumes.
+	volNames := []string{inst.Name()}
+	for _, snapshot := range snapshots {
+		volNames = append(volNames, snapshot.Name)
+	}
+
+	for _, volName := range volNames {
+		volumeConfig := map[string]string{}
+		err = VolumeFillDefault(b.name, volumeConfig, dbPool)
+		if err != nil {
+			return err
+		}
+
+		_, err = b.state.Cluster.StoragePoolVolumeCreate(inst.Project(), volName, "", volDBType, shared.IsSnapshot(volName), b.ID(), volumeConfig, volDBContentType)
+		if err != nil {
+			return err
+		}
+
+		revertDBVolumes = append(revertDBVolumes, volName)
+	}
+
+	migrationType, err := b.poolMigrationType(srcPool, contentType)
+	if err != nil {
+		return err
+	}
+
+	// Remove the volumes received on this pool if the move doesn't complete.
+	revert := true
+	defer func() {
+		if !revert {
+			return
+		}
+
+		for _, snapName := range snapshotNames {
+			b.driver.DeleteVolumeSnapshot(volType, volStorageName, snapName, op)
+		}
+
+		b.driver.DeleteVolume(volType, volStorageName, op)
+	}()
+
+	err = pipeVolume(func(conn io.ReadWriteCloser) error {
+		return srcPool.MigrateInstance(inst, conn, migration.VolumeSourceArgs{
+			Name:          inst.Name(),
+			Snapshots:     snapshotNames,
+			MigrationType: migrationType,
+			TrackProgress: true, // Do use a progress tracker on sender.
+		}, op)
+	}, func(conn io.ReadWriteCloser) error {
+		err := b.driver.CreateVolumeFromMigration(vol, conn, migration.VolumeTargetArgs{
+			Name:          inst.Name(),
+			Snapshots:     snapshotNames,
+			MigrationType: migrationType,
+			TrackProgress: false, // Do not use a progress tracker on receiver.
+		}, nil, op)
+		if err != nil {
+			conn.Close()
+		}
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Move instance volume failed: %v", err)
+	}
+
+	// Switch the instance over to this pool, and back to the source pool if the move doesn't
+	// complete.
+	err = b.state.Cluster.StoragePoolVolumeMoveInstance(inst.ID(), newDevices, newSnapshotDevices)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if !revert {
+			return
+		}
+
+		b.state.Cluster.StoragePoolVolumeMoveInstance(inst.ID(), inst.LocalDevices(), oldSnapshotDevices)
+
+		symlinkPath := InstancePath(inst.Type(), inst.Project(), inst.Name(), false)
+		os.Remove(symlinkPath)
+		os.Symlink(drivers.GetVolumeMountPath(srcPool.Name(), volType, volStorageName), symlinkPath)
+
+		if len(snapshotNames) > 0 {
+			snapshotSymlink := InstancePath(inst.Type(), inst.Project(), inst.Name(), true)
+			os.Remove(snapshotSymlink)
+			os.Symlink(drivers.GetVolumeSnapshotDir(srcPool.Name(), volType, volStorageName), snapshotSymlink)
+		}
+	}()
+
+	err = b.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), vol.MountPath())
+	if err != nil {
+		return err
+	}
+
+	if len(snapshotNames) > 0 {
+		err = b.ensureInstanceSnapshotSymlink(inst.Type(), inst.Project(), inst.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	// From here on the volumes are kept on this pool whatever happens, as the source copy is
+	// about to be removed.
+	revert = false
+	revertDBVolumes = nil
+
+	// Remove the volumes from the source pool, this must come before their DB records are
+	// removed as the driver may need their IDs.
+	srcDriver := srcPool.Driver()
+	for _, snapName := range snapshotNames {
+		err = srcDriver.DeleteVolumeSnapshot(volType, volStorageName, snapName, op)
+		if err != nil {
+			return fmt.Errorf("Failed to remove snapshot %q from source pool: %v", snapName, err)
+		}
+	}
+
+	err = srcDriver.DeleteVolume(volType, volStorageName, op)
+	if err != nil {
+		return fmt.Errorf("Failed to remove volume from source pool: %v", err)
+	}
+
+	return b.state.Cluster.StoragePoolVolumeDeleteWithSnapshots(inst.Project(), inst.Name(), volDBType, srcPool.ID())
+}
+
 // BackupInstance creates an instance backup.
 func (b *lxdBackend) BackupInstance(inst instance.Instance, targetPath string, optimized bool, snapshots bool, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "targetPath": targetPath, "optimized": optimized, "snapshots": snapshots})
@@ -1569,19 +1836,36 @@ func (b *lxdBackend) DeleteImage(fingerprint string, op *operations.Operation) e
 }
 
 // CreateCustomVolume creates an empty custom volume.
-func (b *lxdBackend) CreateCustomVolume(volName, desc string, config map[string]string, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName, "desc": desc, "config": config})
+func (b *lxdBackend) CreateCustomVolume(projectName, volName, desc string, config map[string]string, contentType drivers.ContentType, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "desc": desc, "config": config, "contentType": contentType})
 	logger.Debug("CreateCustomVolume started")
 	defer logger.Debug("CreateCustomVolume finished")
 
+	// Block volumes always have a size, use the pool's default volume size if none is specified.
+	if contentType == drivers.ContentTypeBlock && config["size"] == "" {
+		if config == nil {
+			config = map[string]string{}
+		}
+
+		config["size"] = b.driver.Config()["volume.size"]
+		if config["size"] == "" {
+			config["size"] = "10GB"
+		}
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.Prefix(projectName, volName)
+
 	// Validate config.
-	err := b.driver.ValidateVolume(b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, config), false)
+	err := b.driver.ValidateVolume(b.newVolume(drivers.VolumeTypeCustom, contentType, volStorageName, config), false)
 	if err != nil {
 		return err
 	}
 
+	b.setupVolumeEncryption(contentType, config)
+
 	// Create database entry for new storage volume.
-	err = VolumeDBCreate(b.state, b.name, volName, desc, db.StoragePoolVolumeTypeNameCustom, false, config)
+	err = VolumeDBCreate(b.state, projectName, b.name, volName, desc, db.StoragePoolVolumeTypeNameCustom, false, config, contentType)
 	if err != nil {
 		return err
 	}
@@ -1589,12 +1873,12 @@ func (b *lxdBackend) CreateCustomVolume(volName, desc string, config map[string]
 	revertDB := true
 	defer func() {
 		if revertDB {
-			b.state.Cluster.StoragePoolVolumeDelete("default", volName, db.StoragePoolVolumeTypeCustom, b.ID())
+			b.state.Cluster.StoragePoolVolumeDelete(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
 		}
 	}()
 
 	// Create the empty custom volume on the storage device.
-	newVol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, config)
+	newVol := b.newVolume(drivers.VolumeTypeCustom, contentType, volStorageName, config)
 	err = b.driver.CreateVolume(newVol, nil, op)
 	if err != nil {
 		return err
@@ -1604,10 +1888,77 @@ func (b *lxdBackend) CreateCustomVolume(volName, desc string, config map[string]
 	return nil
 }
 
+// CreateCustomVolumeFromDiskImage creates a block custom volume from a raw or qcow2 disk image file.
+// If no size is specified in the config then the virtual size of the disk image is used.
+func (b *lxdBackend) CreateCustomVolumeFromDiskImage(projectName, volName, desc string, config map[string]string, srcPath string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "desc": desc, "config": config, "srcPath": srcPath})
+	logger.Debug("CreateCustomVolumeFromDiskImage started")
+	defer logger.Debug("CreateCustomVolumeFromDiskImage finished")
+
+	imgFormat, imgSize, err := diskImageInfo(srcPath)
+	if err != nil {
+		return err
+	}
+
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	// Default the volume size to the virtual size of the disk image.
+	if config["size"] == "" {
+		config["size"] = fmt.Sprintf("%d", imgSize)
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.Prefix(projectName, volName)
+
+	// Validate config.
+	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeBlock, volStorageName, config)
+	err = b.driver.ValidateVolume(vol, false)
+	if err != nil {
+		return err
+	}
+
+	b.setupVolumeEncryption(drivers.ContentTypeBlock, config)
+
+	// Create database entry for new storage volume.
+	err = VolumeDBCreate(b.state, projectName, b.name, volName, desc, db.StoragePoolVolumeTypeNameCustom, false, config, drivers.ContentTypeBlock)
+	if err != nil {
+		return err
+	}
+
+	revertDB := true
+	defer func() {
+		if revertDB {
+			b.state.Cluster.StoragePoolVolumeDelete(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
+		}
+	}()
+
+	// Convert the disk image to raw into the volume's block file.
+	volFiller := drivers.VolumeFiller{
+		Fill: func(mountPath, rootBlockPath string) error {
+			_, err := shared.RunCommand("qemu-img", "convert", "-f", imgFormat, "-O", "raw", srcPath, rootBlockPath)
+			if err != nil {
+				return fmt.Errorf("Failed converting disk image to raw at %s: %v", rootBlockPath, err)
+			}
+
+			return nil
+		},
+	}
+
+	err = b.driver.CreateVolume(vol, &volFiller, op)
+	if err != nil {
+		return err
+	}
+
+	revertDB = false
+	return nil
+}
+
 // CreateCustomVolumeFromCopy creates a custom volume from an existing custom volume.
 // It copies the snapshots from the source volume by default, but can be disabled if requested.
-func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map[string]string, srcPoolName, srcVolName string, srcVolOnly bool, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName, "desc": desc, "config": config, "srcPoolName": srcPoolName, "srcVolName": srcVolName, "srcVolOnly": srcVolOnly})
+func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName, volName, desc string, config map[string]string, srcPoolName, srcVolName string, srcVolOnly bool, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "desc": desc, "config": config, "srcPoolName": srcPoolName, "srcVolName": srcVolName, "srcVolOnly": srcVolOnly})
 	logger.Debug("CreateCustomVolumeFromCopy started")
 	defer logger.Debug("CreateCustomVolumeFromCopy finished")
 
@@ -1632,7 +1983,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 	}
 
 	// Check source volume exists and is custom type.
-	_, srcVolRow, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject("default", srcVolName, db.StoragePoolVolumeTypeCustom, srcPool.ID())
+	_, srcVolRow, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, srcVolName, db.StoragePoolVolumeTypeCustom, srcPool.ID())
 	if err != nil {
 		if err == db.ErrNoSuchObject {
 			return fmt.Errorf("Source volume doesn't exist")
@@ -1641,6 +1992,11 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 		return err
 	}
 
+	contentType, err := VolumeContentTypeNameToContentType(srcVolRow.ContentType)
+	if err != nil {
+		return err
+	}
+
 	// Use the source volume's config if not supplied.
 	if config == nil {
 		config = srcVolRow.Config
@@ -1654,7 +2010,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 	// If we are copying snapshots, retrieve a list of snapshots from source volume.
 	snapshotNames := []string{}
 	if !srcVolOnly {
-		snapshots, err := VolumeSnapshotsGet(b.state, srcPoolName, srcVolName, db.StoragePoolVolumeTypeCustom)
+		snapshots, err := VolumeSnapshotsGet(b.state, projectName, srcPoolName, srcVolName, db.StoragePoolVolumeTypeCustom)
 		if err != nil {
 			return err
 		}
@@ -1675,12 +2031,12 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 		defer func() {
 			// Remove any DB volume rows created if we are reverting.
 			for _, volName := range revertDBVolumes {
-				b.state.Cluster.StoragePoolVolumeDelete("default", volName, db.StoragePoolVolumeTypeCustom, b.ID())
+				b.state.Cluster.StoragePoolVolumeDelete(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
 			}
 		}()
 
-		vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, config)
-		srcVol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, srcVolName, srcVolRow.Config)
+		vol := b.newVolume(drivers.VolumeTypeCustom, contentType, project.Prefix(projectName, volName), config)
+		srcVol := b.newVolume(drivers.VolumeTypeCustom, contentType, project.Prefix(projectName, srcVolName), srcVolRow.Config)
 
 		// Check the supplied config and remove any fields not relevant for pool type.
 		err := b.driver.ValidateVolume(vol, true)
@@ -1689,7 +2045,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 		}
 
 		// Create database entry for new storage volume.
-		err = VolumeDBCreate(b.state, b.name, volName, desc, db.StoragePoolVolumeTypeNameCustom, false, config)
+		err = VolumeDBCreate(b.state, projectName, b.name, volName, desc, db.StoragePoolVolumeTypeNameCustom, false, config, contentType)
 		if err != nil {
 			return err
 		}
@@ -1701,7 +2057,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 				newSnapshotName := drivers.GetSnapshotVolumeName(volName, snapName)
 
 				// Create database entry for new storage volume snapshot.
-				err = VolumeDBCreate(b.state, b.name, newSnapshotName, desc, db.StoragePoolVolumeTypeNameCustom, true, config)
+				err = VolumeDBCreate(b.state, projectName, b.name, newSnapshotName, desc, db.StoragePoolVolumeTypeNameCustom, true, config, contentType)
 				if err != nil {
 					return err
 				}
@@ -1723,6 +2079,10 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 	// to negotiate a common transfer method between pool types.
 	logger.Debug("CreateCustomVolumeFromCopy cross-pool mode detected")
 
+	if contentType != drivers.ContentTypeFS {
+		return fmt.Errorf("Copying block custom volumes between storage pools is not supported")
+	}
+
 	// Use in-memory pipe pair to simulate a connection between the sender and receiver.
 	aEnd, bEnd := memorypipe.NewPipePair()
 
@@ -1738,7 +2098,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 	aEndErrCh := make(chan error, 1)
 	bEndErrCh := make(chan error, 1)
 	go func() {
-		err := srcPool.MigrateCustomVolume(aEnd, migration.VolumeSourceArgs{
+		err := srcPool.MigrateCustomVolume(projectName, aEnd, migration.VolumeSourceArgs{
 			Name:          srcVolName,
 			Snapshots:     snapshotNames,
 			MigrationType: migrationType,
@@ -1749,7 +2109,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 	}()
 
 	go func() {
-		err := b.CreateCustomVolumeFromMigration(bEnd, migration.VolumeTargetArgs{
+		err := b.CreateCustomVolumeFromMigration(projectName, bEnd, migration.VolumeTargetArgs{
 			Name:          volName,
 			Description:   desc,
 			Config:        config,
@@ -1782,14 +2142,24 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 }
 
 // MigrateCustomVolume sends a volume for migration.
-func (b *lxdBackend) MigrateCustomVolume(conn io.ReadWriteCloser, args migration.VolumeSourceArgs, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": args.Name, "args": args})
+func (b *lxdBackend) MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args migration.VolumeSourceArgs, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": args.Name, "args": args})
 	logger.Debug("MigrateCustomVolume started")
 	defer logger.Debug("MigrateCustomVolume finished")
 
+	_, dbVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, args.Name, db.StoragePoolVolumeTypeCustom, b.ID())
+	if err != nil {
+		return err
+	}
+
+	contentType, err := VolumeContentTypeNameToContentType(dbVol.ContentType)
+	if err != nil {
+		return err
+	}
+
 	// Volume config not needed to send a volume so set to nil.
-	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, args.Name, nil)
-	err := b.driver.MigrateVolume(vol, conn, args, op)
+	vol := b.newVolume(drivers.VolumeTypeCustom, contentType, project.Prefix(projectName, args.Name), nil)
+	err = b.driver.MigrateVolume(vol, conn, args, op)
 	if err != nil {
 		return err
 	}
@@ -1798,8 +2168,8 @@ func (b *lxdBackend) MigrateCustomVolume(conn io.ReadWriteCloser, args migration
 }
 
 // CreateCustomVolumeFromMigration receives a volume being migrated.
-func (b *lxdBackend) CreateCustomVolumeFromMigration(conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": args.Name, "args": args})
+func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": args.Name, "args": args})
 	logger.Debug("CreateCustomVolumeFromMigration started")
 	defer logger.Debug("CreateCustomVolumeFromMigration finished")
 
@@ -1808,30 +2178,50 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(conn io.ReadWriteCloser, ar
 	defer func() {
 		// Remove any DB volume rows created if we are reverting.
 		for _, volName := range revertDBVolumes {
-			b.state.Cluster.StoragePoolVolumeDelete("default", volName, db.StoragePoolVolumeTypeCustom, b.ID())
+			b.state.Cluster.StoragePoolVolumeDelete(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
 		}
 	}()
 
-	// Check the supplied config and remove any fields not relevant for destination pool type.
-	err := b.driver.ValidateVolume(b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, args.Name, args.Config), true)
-	if err != nil {
-		return err
-	}
+	// Get the volume name on storage.
+	volStorageName := project.Prefix(projectName, args.Name)
 
-	// Create database entry for new storage volume.
-	err = VolumeDBCreate(b.state, b.name, args.Name, args.Description, db.StoragePoolVolumeTypeNameCustom, false, args.Config)
-	if err != nil {
-		return err
+	volExists := b.driver.HasVolume(drivers.VolumeTypeCustom, volStorageName)
+	if args.Refresh && !volExists {
+		return fmt.Errorf("Cannot refresh volume, doesn't exist on target")
+	} else if !args.Refresh && volExists {
+		return fmt.Errorf("Cannot create volume, already exists on target")
 	}
 
-	revertDBVolumes = append(revertDBVolumes, args.Name)
+	if args.Refresh {
+		// Keep the existing volume's config, only its content and snapshots are refreshed.
+		_, dbVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, args.Name, db.StoragePoolVolumeTypeCustom, b.ID())
+		if err != nil {
+			return err
+		}
+
+		args.Config = dbVol.Config
+	} else {
+		// Check the supplied config and remove any fields not relevant for destination pool type.
+		err := b.driver.ValidateVolume(b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volStorageName, args.Config), true)
+		if err != nil {
+			return err
+		}
+
+		// Create database entry for new storage volume.
+		err = VolumeDBCreate(b.state, projectName, b.name, args.Name, args.Description, db.StoragePoolVolumeTypeNameCustom, false, args.Config, drivers.ContentTypeFS)
+		if err != nil {
+			return err
+		}
+
+		revertDBVolumes = append(revertDBVolumes, args.Name)
+	}
 
 	if len(args.Snapshots) > 0 {
 		for _, snapName := range args.Snapshots {
 			newSnapshotName := drivers.GetSnapshotVolumeName(args.Name, snapName)
 
 			// Create database entry for new storage volume snapshot.
-			err = VolumeDBCreate(b.state, b.name, newSnapshotName, args.Description, db.StoragePoolVolumeTypeNameCustom, true, args.Config)
+			err := VolumeDBCreate(b.state, projectName, b.name, newSnapshotName, args.Description, db.StoragePoolVolumeTypeNameCustom, true, args.Config, drivers.ContentTypeFS)
 			if err != nil {
 				return err
 			}
@@ -1840,8 +2230,8 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(conn io.ReadWriteCloser, ar
 		}
 	}
 
-	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, args.Name, args.Config)
-	err = b.driver.CreateVolumeFromMigration(vol, conn, args, nil, op)
+	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volStorageName, args.Config)
+	err := b.driver.CreateVolumeFromMigration(vol, conn, args, nil, op)
 	if err != nil {
 		conn.Close()
 		return err
@@ -1851,9 +2241,274 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(conn io.ReadWriteCloser, ar
 	return nil
 }
 
-// RenameCustomVolume renames a custom volume and its snapshots.
-func (b *lxdBackend) RenameCustomVolume(volName string, newVolName string, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName, "newVolName": newVolName})
+// MoveCustomVolumeFromPool moves a custom volume and its snapshots from srcPool onto this pool.
+// The volumes are streamed over an in-memory pipe using the migration subsystem, after which the
+// disk devices using the volume are switched over to this pool. The volumes and their records are
+// only removed from the source pool once the switch-over succeeded.
+func (b *lxdBackend) MoveCustomVolumeFromPool(projectName, volName string, srcPool Pool, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "srcPool": srcPool.Name()})
+	logger.Debug("MoveCustomVolumeFromPool started")
+	defer logger.Debug("MoveCustomVolumeFromPool finished")
+
+	if shared.IsSnapshot(volName) {
+		return fmt.Errorf("Volume name cannot be a snapshot")
+	}
+
+	if srcPool.Name() == b.name {
+		return fmt.Errorf("Source and target storage pools must be different")
+	}
+
+	_, srcVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volName, db.StoragePoolVolumeTypeCustom, srcPool.ID())
+	if err != nil {
+		return err
+	}
+
+	contentType, err := VolumeContentTypeNameToContentType(srcVol.ContentType)
+	if err != nil {
+		return err
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.Prefix(projectName, volName)
+
+	if b.driver.HasVolume(drivers.VolumeTypeCustom, volStorageName) {
+		return fmt.Errorf("Cannot move volume, already exists on target")
+	}
+
+	// Remove any config keys not relevant for this pool's driver.
+	config, err := VolumePropertiesTranslate(srcVol.Config, b.driver.Info().Name)
+	if err != nil {
+		return err
+	}
+
+	// Retrieve the list of snapshots we are moving.
+	snapshots, err := VolumeSnapshotsGet(b.state, projectName, srcPool.Name(), volName, db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	snapshotNames := []string{}
+	for _, snapshot := range snapshots {
+		_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snapshot.Name)
+		snapshotNames = append(snapshotNames, snapName)
+	}
+
+	vol := b.newVolume(drivers.VolumeTypeCustom, contentType, volStorageName, config)
+
+	// Create slice to record DB volumes created if revert needed later.
+	revertDBVolumes := []string{}
+	defer func() {
+		// Remove any DB volume rows created if we are reverting.
+		for _, volName := range revertDBVolumes {
+			b.state.Cluster.StoragePoolVolumeDelete(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
+		}
+	}()
+
+	// Create the database entries for the volume and its snapshots on this pool, these are
+	// needed by the driver when receiving the volumes.
+	err = VolumeDBCreate(b.state, projectName, b.name, volName, srcVol.Description, db.StoragePoolVolumeTypeNameCustom, false, config, contentType)
+	if err != nil {
+		return err
+	}
+
+	revertDBVolumes = append(revertDBVolumes, volName)
+
+	for _, snapshot := range snapshots {
+		err = VolumeDBCreate(b.state, projectName, b.name, snapshot.Name, snapshot.Description, db.StoragePoolVolumeTypeNameCustom, true, config, contentType)
+		if err != nil {
+			return err
+		}
+
+		revertDBVolumes = append(revertDBVolumes, snapshot.Name)
+	}
+
+	migrationType, err := b.poolMigrationType(srcPool, contentType)
+	if err != nil {
+		return err
+	}
+
+	// Remove the volumes received on this pool if the move doesn't complete.
+	revert := true
+	defer func() {
+		if !revert {
+			return
+		}
+
+		for _, snapName := range snapshotNames {
+			b.driver.DeleteVolumeSnapshot(drivers.VolumeTypeCustom, volStorageName, snapName, op)
+		}
+
+		b.driver.DeleteVolume(drivers.VolumeTypeCustom, volStorageName, op)
+	}()
+
+	err = pipeVolume(func(conn io.ReadWriteCloser) error {
+		return srcPool.MigrateCustomVolume(projectName, conn, migration.VolumeSourceArgs{
+			Name:          volName,
+			Snapshots:     snapshotNames,
+			MigrationType: migrationType,
+			TrackProgress: true, // Do use a progress tracker on sender.
+		}, op)
+	}, func(conn io.ReadWriteCloser) error {
+		err := b.driver.CreateVolumeFromMigration(vol, conn, migration.VolumeTargetArgs{
+			Name:          volName,
+			Snapshots:     snapshotNames,
+			MigrationType: migrationType,
+			TrackProgress: false, // Do not use a progress tracker on receiver.
+		}, nil, op)
+		if err != nil {
+			conn.Close()
+		}
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Move custom volume failed: %v", err)
+	}
+
+	// Switch the devices using the volume over to this pool.
+	err = b.state.Cluster.StoragePoolVolumeMoveCustom(projectName, volName, srcPool.Name(), b.name)
+	if err != nil {
+		return err
+	}
+
+	// From here on the volumes are kept on this pool whatever happens, as the source copy is
+	// about to be removed.
+	revert = false
+	revertDBVolumes = nil
+
+	// Remove the volumes from the source pool, this must come before their DB records are
+	// removed as the driver may need their IDs.
+	srcDriver := srcPool.Driver()
+	for _, snapName := range snapshotNames {
+		err = srcDriver.DeleteVolumeSnapshot(drivers.VolumeTypeCustom, volStorageName, snapName, op)
+		if err != nil {
+			return fmt.Errorf("Failed to remove snapshot %q from source pool: %v", snapName, err)
+		}
+	}
+
+	err = srcDriver.DeleteVolume(drivers.VolumeTypeCustom, volStorageName, op)
+	if err != nil {
+		return fmt.Errorf("Failed to remove volume from source pool: %v", err)
+	}
+
+	return b.state.Cluster.StoragePoolVolumeDeleteWithSnapshots(projectName, volName, db.StoragePoolVolumeTypeCustom, srcPool.ID())
+}
+
+// BackupCustomVolume creates a backup of a custom volume and optionally its snapshots in the
+// target path.
+func (b *lxdBackend) BackupCustomVolume(projectName, volName string, targetPath string, optimized bool, snapshots bool, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "targetPath": targetPath, "optimized": optimized, "snapshots": snapshots})
+	logger.Debug("BackupCustomVolume started")
+	defer logger.Debug("BackupCustomVolume finished")
+
+	if shared.IsSnapshot(volName) {
+		return fmt.Errorf("Volume name cannot be a snapshot")
+	}
+
+	// Get the volume config.
+	_, dbVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return fmt.Errorf("Volume doesn't exist")
+		}
+
+		return err
+	}
+
+	if dbVol.ContentType != db.StoragePoolVolumeContentTypeNameFS {
+		return fmt.Errorf("Backups of block custom volumes are not supported")
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.Prefix(projectName, volName)
+
+	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volStorageName, dbVol.Config)
+	err = b.driver.BackupVolume(vol, targetPath, optimized, snapshots, op)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateCustomVolumeFromBackup restores a custom volume and its snapshots from a backup file.
+func (b *lxdBackend) CreateCustomVolumeFromBackup(srcBackup backup.Info, srcData io.ReadSeeker, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": srcBackup.Project, "volName": srcBackup.Name, "snapshots": srcBackup.Snapshots, "hasBinaryFormat": srcBackup.HasBinaryFormat})
+	logger.Debug("CreateCustomVolumeFromBackup started")
+	defer logger.Debug("CreateCustomVolumeFromBackup finished")
+
+	if srcBackup.Type != backup.TypeCustom {
+		return fmt.Errorf("Backup isn't of a custom volume")
+	}
+
+	if srcBackup.Config == nil {
+		srcBackup.Config = map[string]string{}
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.Prefix(srcBackup.Project, srcBackup.Name)
+
+	// Check the supplied config and remove any fields not relevant for destination pool type.
+	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volStorageName, srcBackup.Config)
+	err := b.driver.ValidateVolume(vol, true)
+	if err != nil {
+		return err
+	}
+
+	// Create slice to record DB volumes created if revert needed later.
+	revertDBVolumes := []string{}
+	defer func() {
+		// Remove any DB volume rows created if we are reverting.
+		for _, volName := range revertDBVolumes {
+			b.state.Cluster.StoragePoolVolumeDelete(srcBackup.Project, volName, db.StoragePoolVolumeTypeCustom, b.ID())
+		}
+	}()
+
+	// Create database entry for new storage volume.
+	err = VolumeDBCreate(b.state, srcBackup.Project, b.name, srcBackup.Name, "", db.StoragePoolVolumeTypeNameCustom, false, srcBackup.Config, drivers.ContentTypeFS)
+	if err != nil {
+		return err
+	}
+
+	revertDBVolumes = append(revertDBVolumes, srcBackup.Name)
+
+	for _, snapName := range srcBackup.Snapshots {
+		newSnapshotName := drivers.GetSnapshotVolumeName(srcBackup.Name, snapName)
+
+		// Create database entry for new storage volume snapshot.
+		err = VolumeDBCreate(b.state, srcBackup.Project, b.name, newSnapshotName, "", db.StoragePoolVolumeTypeNameCustom, true, srcBackup.Config, drivers.ContentTypeFS)
+		if err != nil {
+			return err
+		}
+
+		revertDBVolumes = append(revertDBVolumes, newSnapshotName)
+	}
+
+	// Unpack the backup into the new storage volume(s).
+	volPostHook, revertHook, err := b.driver.RestoreBackupVolume(vol, srcBackup.Snapshots, srcData, op)
+	if err != nil {
+		return err
+	}
+
+	// Apply the volume config now that the volume has been unpacked, if the driver needs it.
+	if volPostHook != nil {
+		err = volPostHook(vol)
+		if err != nil {
+			if revertHook != nil {
+				revertHook()
+			}
+
+			return err
+		}
+	}
+
+	revertDBVolumes = nil
+	return nil
+}
+
+// RenameCustomVolume renames a custom volume and its snapshots.
+func (b *lxdBackend) RenameCustomVolume(projectName, volName string, newVolName string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "newVolName": newVolName})
 	logger.Debug("RenameCustomVolume started")
 	defer logger.Debug("RenameCustomVolume finished")
 
@@ -1875,12 +2530,12 @@ func (b *lxdBackend) RenameCustomVolume(volName string, newVolName string, op *o
 	defer func() {
 		// Remove any DB volume rows created if we are reverting.
 		for _, vol := range revertDBVolumes {
-			b.state.Cluster.StoragePoolVolumeRename("default", vol.newName, vol.oldName, db.StoragePoolVolumeTypeCustom, b.ID())
+			b.state.Cluster.StoragePoolVolumeRename(projectName, vol.newName, vol.oldName, db.StoragePoolVolumeTypeCustom, b.ID())
 		}
 	}()
 
 	// Rename each snapshot to have the new parent volume prefix.
-	snapshots, err := VolumeSnapshotsGet(b.state, b.name, volName, db.StoragePoolVolumeTypeCustom)
+	snapshots, err := VolumeSnapshotsGet(b.state, projectName, b.name, volName, db.StoragePoolVolumeTypeCustom)
 	if err != nil {
 		return err
 	}
@@ -1888,7 +2543,7 @@ func (b *lxdBackend) RenameCustomVolume(volName string, newVolName string, op *o
 	for _, srcSnapshot := range snapshots {
 		_, snapName, _ := shared.InstanceGetParentAndSnapshotName(srcSnapshot.Name)
 		newSnapVolName := drivers.GetSnapshotVolumeName(newVolName, snapName)
-		err = b.state.Cluster.StoragePoolVolumeRename("default", srcSnapshot.Name, newSnapVolName, db.StoragePoolVolumeTypeCustom, b.ID())
+		err = b.state.Cluster.StoragePoolVolumeRename(projectName, srcSnapshot.Name, newSnapVolName, db.StoragePoolVolumeTypeCustom, b.ID())
 		if err != nil {
 			return err
 		}
@@ -1899,7 +2554,7 @@ func (b *lxdBackend) RenameCustomVolume(volName string, newVolName string, op *o
 		})
 	}
 
-	err = b.state.Cluster.StoragePoolVolumeRename("default", volName, newVolName, db.StoragePoolVolumeTypeCustom, b.ID())
+	err = b.state.Cluster.StoragePoolVolumeRename(projectName, volName, newVolName, db.StoragePoolVolumeTypeCustom, b.ID())
 	if err != nil {
 		return err
 	}
@@ -1909,18 +2564,28 @@ func (b *lxdBackend) RenameCustomVolume(volName string, newVolName string, op *o
 		oldName: volName,
 	})
 
-	err = b.driver.RenameVolume(drivers.VolumeTypeCustom, volName, newVolName, op)
+	err = b.driver.RenameVolume(drivers.VolumeTypeCustom, project.Prefix(projectName, volName), project.Prefix(projectName, newVolName), op)
 	if err != nil {
 		return err
 	}
 
+	// Move the backups along with the volume.
+	backupsPath := backup.VolumeBackupsPath(projectName, b.name, volName)
+	if shared.PathExists(backupsPath) {
+		err = os.Rename(backupsPath, backup.VolumeBackupsPath(projectName, b.name, newVolName))
+		if err != nil {
+			b.driver.RenameVolume(drivers.VolumeTypeCustom, project.Prefix(projectName, newVolName), project.Prefix(projectName, volName), op)
+			return err
+		}
+	}
+
 	revertDBVolumes = nil
 	return nil
 }
 
 // UpdateCustomVolume applies the supplied config to the custom volume.
-func (b *lxdBackend) UpdateCustomVolume(volName, newDesc string, newConfig map[string]string, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName, "newDesc": newDesc, "newConfig": newConfig})
+func (b *lxdBackend) UpdateCustomVolume(projectName, volName, newDesc string, newConfig map[string]string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "newDesc": newDesc, "newConfig": newConfig})
 	logger.Debug("UpdateCustomVolume started")
 	defer logger.Debug("UpdateCustomVolume finished")
 
@@ -1928,15 +2593,11 @@ func (b *lxdBackend) UpdateCustomVolume(volName, newDesc string, newConfig map[s
 		return fmt.Errorf("Volume name cannot be a snapshot")
 	}
 
-	// Validate config.
-	newVol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, newConfig)
-	err := b.driver.ValidateVolume(newVol, false)
-	if err != nil {
-		return err
-	}
+	// Get the volume name on storage.
+	volStorageName := project.Prefix(projectName, volName)
 
 	// Get current config to compare what has changed.
-	_, curVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject("default", volName, db.StoragePoolVolumeTypeCustom, b.ID())
+	_, curVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
 	if err != nil {
 		if err == db.ErrNoSuchObject {
 			return fmt.Errorf("Volume doesn't exist")
@@ -1945,6 +2606,18 @@ func (b *lxdBackend) UpdateCustomVolume(volName, newDesc string, newConfig map[s
 		return err
 	}
 
+	contentType, err := VolumeContentTypeNameToContentType(curVol.ContentType)
+	if err != nil {
+		return err
+	}
+
+	// Validate config.
+	newVol := b.newVolume(drivers.VolumeTypeCustom, contentType, volStorageName, newConfig)
+	err = b.driver.ValidateVolume(newVol, false)
+	if err != nil {
+		return err
+	}
+
 	// Diff the configurations.
 	changedConfig := make(map[string]string)
 	userOnly := true
@@ -1970,7 +2643,7 @@ func (b *lxdBackend) UpdateCustomVolume(volName, newDesc string, newConfig map[s
 
 	// Apply config changes if there are any.
 	if len(changedConfig) != 0 {
-		curVol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, curVol.Config)
+		curVol := b.newVolume(drivers.VolumeTypeCustom, contentType, volStorageName, curVol.Config)
 		if !userOnly {
 			err = b.driver.UpdateVolume(curVol, changedConfig)
 			if err != nil {
@@ -1986,7 +2659,7 @@ func (b *lxdBackend) UpdateCustomVolume(volName, newDesc string, newConfig map[s
 
 	// Confirm that no instances are running when changing shifted state.
 	if newConfig["security.shifted"] != curVol.Config["security.shifted"] {
-		usingVolume, err := VolumeUsedByInstancesWithProfiles(b.state, b.Name(), volName, db.StoragePoolVolumeTypeNameCustom, true)
+		usingVolume, err := VolumeUsedByInstancesWithProfiles(b.state, projectName, b.Name(), volName, db.StoragePoolVolumeTypeNameCustom, true)
 		if err != nil {
 			return err
 		}
@@ -2004,7 +2677,7 @@ func (b *lxdBackend) UpdateCustomVolume(volName, newDesc string, newConfig map[s
 
 	// Update the database if something changed.
 	if len(changedConfig) != 0 || newDesc != curVol.Description {
-		err = b.state.Cluster.StoragePoolVolumeUpdate(volName, db.StoragePoolVolumeTypeCustom, b.ID(), newDesc, newConfig)
+		err = b.state.Cluster.StoragePoolVolumeUpdate(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID(), newDesc, newConfig)
 		if err != nil {
 			return err
 		}
@@ -2014,8 +2687,8 @@ func (b *lxdBackend) UpdateCustomVolume(volName, newDesc string, newConfig map[s
 }
 
 // DeleteCustomVolume removes a custom volume and its snapshots.
-func (b *lxdBackend) DeleteCustomVolume(volName string, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName})
+func (b *lxdBackend) DeleteCustomVolume(projectName, volName string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName})
 	logger.Debug("DeleteCustomVolume started")
 	defer logger.Debug("DeleteCustomVolume finished")
 
@@ -2025,27 +2698,33 @@ func (b *lxdBackend) DeleteCustomVolume(volName string, op *operations.Operation
 	}
 
 	// Retrieve a list of snapshots.
-	snapshots, err := VolumeSnapshotsGet(b.state, b.name, volName, db.StoragePoolVolumeTypeCustom)
+	snapshots, err := VolumeSnapshotsGet(b.state, projectName, b.name, volName, db.StoragePoolVolumeTypeCustom)
 	if err != nil {
 		return err
 	}
 
 	// Remove each snapshot.
 	for _, snapshot := range snapshots {
-		err = b.DeleteCustomVolumeSnapshot(snapshot.Name, op)
+		err = b.DeleteCustomVolumeSnapshot(projectName, snapshot.Name, op)
 		if err != nil {
 			return err
 		}
 	}
 
 	// Delete the volume from the storage device. Must come after snapshots are removed.
-	err = b.driver.DeleteVolume(drivers.VolumeTypeCustom, volName, op)
+	err = b.driver.DeleteVolume(drivers.VolumeTypeCustom, project.Prefix(projectName, volName), op)
+	if err != nil {
+		return err
+	}
+
+	// Remove any backups of the volume.
+	err = os.RemoveAll(backup.VolumeBackupsPath(projectName, b.name, volName))
 	if err != nil {
 		return err
 	}
 
 	// Finally, remove the volume record from the database.
-	err = b.state.Cluster.StoragePoolVolumeDelete("default", volName, db.StoragePoolVolumeTypeCustom, b.ID())
+	err = b.state.Cluster.StoragePoolVolumeDelete(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
 	if err != nil {
 		return err
 	}
@@ -2054,31 +2733,45 @@ func (b *lxdBackend) DeleteCustomVolume(volName string, op *operations.Operation
 }
 
 // GetCustomVolumeUsage returns the disk space used by the custom volume.
-func (b *lxdBackend) GetCustomVolumeUsage(volName string) (int64, error) {
-	return b.driver.GetVolumeUsage(drivers.VolumeTypeCustom, volName)
+func (b *lxdBackend) GetCustomVolumeUsage(projectName, volName string) (int64, error) {
+	return b.driver.GetVolumeUsage(drivers.VolumeTypeCustom, project.Prefix(projectName, volName))
+}
+
+// GetCustomVolumeDisk returns the location of the disk of a block custom volume.
+func (b *lxdBackend) GetCustomVolumeDisk(projectName, volName string) (string, error) {
+	_, dbVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
+	if err != nil {
+		return "", err
+	}
+
+	if dbVol.ContentType != db.StoragePoolVolumeContentTypeNameBlock {
+		return "", fmt.Errorf("Volume is not of type block")
+	}
+
+	return b.driver.GetVolumeDiskPath(drivers.VolumeTypeCustom, project.Prefix(projectName, volName))
 }
 
 // MountCustomVolume mounts a custom volume.
-func (b *lxdBackend) MountCustomVolume(volName string, op *operations.Operation) (bool, error) {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName})
+func (b *lxdBackend) MountCustomVolume(projectName, volName string, op *operations.Operation) (bool, error) {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName})
 	logger.Debug("MountCustomVolume started")
 	defer logger.Debug("MountCustomVolume finished")
 
-	return b.driver.MountVolume(drivers.VolumeTypeCustom, volName, op)
+	return b.driver.MountVolume(drivers.VolumeTypeCustom, project.Prefix(projectName, volName), op)
 }
 
 // UnmountCustomVolume unmounts a custom volume.
-func (b *lxdBackend) UnmountCustomVolume(volName string, op *operations.Operation) (bool, error) {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName})
+func (b *lxdBackend) UnmountCustomVolume(projectName, volName string, op *operations.Operation) (bool, error) {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName})
 	logger.Debug("UnmountCustomVolume started")
 	defer logger.Debug("UnmountCustomVolume finished")
 
-	return b.driver.UnmountVolume(drivers.VolumeTypeCustom, volName, op)
+	return b.driver.UnmountVolume(drivers.VolumeTypeCustom, project.Prefix(projectName, volName), op)
 }
 
 // CreateCustomVolumeSnapshot creates a snapshot of a custom volume.
-func (b *lxdBackend) CreateCustomVolumeSnapshot(volName string, newSnapshotName string, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName, "newSnapshotName": newSnapshotName})
+func (b *lxdBackend) CreateCustomVolumeSnapshot(projectName, volName string, newSnapshotName string, newExpiryDate time.Time, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "newSnapshotName": newSnapshotName})
 	logger.Debug("CreateCustomVolumeSnapshot started")
 	defer logger.Debug("CreateCustomVolumeSnapshot finished")
 
@@ -2093,7 +2786,7 @@ func (b *lxdBackend) CreateCustomVolumeSnapshot(volName string, newSnapshotName
 	fullSnapshotName := drivers.GetSnapshotVolumeName(volName, newSnapshotName)
 
 	// Check snapshot volume doesn't exist already.
-	_, _, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject("default", fullSnapshotName, db.StoragePoolVolumeTypeCustom, b.ID())
+	_, _, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, fullSnapshotName, db.StoragePoolVolumeTypeCustom, b.ID())
 	if err != db.ErrNoSuchObject {
 		if err != nil {
 			return err
@@ -2103,7 +2796,7 @@ func (b *lxdBackend) CreateCustomVolumeSnapshot(volName string, newSnapshotName
 	}
 
 	// Load parent volume information and check it exists.
-	_, parentVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject("default", volName, db.StoragePoolVolumeTypeCustom, b.ID())
+	_, parentVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
 	if err != nil {
 		if err == db.ErrNoSuchObject {
 			return fmt.Errorf("Parent volume doesn't exist")
@@ -2112,8 +2805,13 @@ func (b *lxdBackend) CreateCustomVolumeSnapshot(volName string, newSnapshotName
 		return err
 	}
 
+	contentType, err := VolumeContentTypeNameToContentType(parentVol.ContentType)
+	if err != nil {
+		return err
+	}
+
 	// Create database entry for new storage volume snapshot.
-	err = VolumeDBCreate(b.state, b.name, fullSnapshotName, parentVol.Description, db.StoragePoolVolumeTypeNameCustom, true, parentVol.Config)
+	err = VolumeDBCreate(b.state, projectName, b.name, fullSnapshotName, parentVol.Description, db.StoragePoolVolumeTypeNameCustom, true, parentVol.Config, contentType)
 	if err != nil {
 		return err
 	}
@@ -2121,12 +2819,19 @@ func (b *lxdBackend) CreateCustomVolumeSnapshot(volName string, newSnapshotName
 	revertDB := true
 	defer func() {
 		if revertDB {
-			b.state.Cluster.StoragePoolVolumeDelete("default", fullSnapshotName, db.StoragePoolVolumeTypeCustom, b.ID())
+			b.state.Cluster.StoragePoolVolumeDelete(projectName, fullSnapshotName, db.StoragePoolVolumeTypeCustom, b.ID())
 		}
 	}()
 
+	if !newExpiryDate.IsZero() {
+		err = b.state.Cluster.StoragePoolVolumeSnapshotExpiryUpdate(projectName, fullSnapshotName, db.StoragePoolVolumeTypeCustom, b.ID(), newExpiryDate)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create the snapshot on the storage device.
-	err = b.driver.CreateVolumeSnapshot(drivers.VolumeTypeCustom, volName, newSnapshotName, op)
+	err = b.driver.CreateVolumeSnapshot(drivers.VolumeTypeCustom, project.Prefix(projectName, volName), newSnapshotName, op)
 	if err != nil {
 		return err
 	}
@@ -2136,8 +2841,8 @@ func (b *lxdBackend) CreateCustomVolumeSnapshot(volName string, newSnapshotName
 }
 
 // RenameCustomVolumeSnapshot renames a custom volume.
-func (b *lxdBackend) RenameCustomVolumeSnapshot(volName string, newSnapshotName string, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName, "newSnapshotName": newSnapshotName})
+func (b *lxdBackend) RenameCustomVolumeSnapshot(projectName, volName string, newSnapshotName string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "newSnapshotName": newSnapshotName})
 	logger.Debug("RenameCustomVolumeSnapshot started")
 	defer logger.Debug("RenameCustomVolumeSnapshot finished")
 
@@ -2150,16 +2855,17 @@ func (b *lxdBackend) RenameCustomVolumeSnapshot(volName string, newSnapshotName
 		return fmt.Errorf("Invalid new snapshot name")
 	}
 
-	err := b.driver.RenameVolumeSnapshot(drivers.VolumeTypeCustom, parentName, oldSnapshotName, newSnapshotName, op)
+	parentStorageName := project.Prefix(projectName, parentName)
+	err := b.driver.RenameVolumeSnapshot(drivers.VolumeTypeCustom, parentStorageName, oldSnapshotName, newSnapshotName, op)
 	if err != nil {
 		return err
 	}
 
 	newVolName := drivers.GetSnapshotVolumeName(parentName, newSnapshotName)
-	err = b.state.Cluster.StoragePoolVolumeRename("default", volName, newVolName, db.StoragePoolVolumeTypeCustom, b.ID())
+	err = b.state.Cluster.StoragePoolVolumeRename(projectName, volName, newVolName, db.StoragePoolVolumeTypeCustom, b.ID())
 	if err != nil {
 		// Revert rename.
-		b.driver.RenameVolumeSnapshot(drivers.VolumeTypeCustom, parentName, newSnapshotName, oldSnapshotName, op)
+		b.driver.RenameVolumeSnapshot(drivers.VolumeTypeCustom, parentStorageName, newSnapshotName, oldSnapshotName, op)
 		return err
 	}
 
@@ -2167,8 +2873,8 @@ func (b *lxdBackend) RenameCustomVolumeSnapshot(volName string, newSnapshotName
 }
 
 // DeleteCustomVolumeSnapshot removes a custom volume snapshot.
-func (b *lxdBackend) DeleteCustomVolumeSnapshot(volName string, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName})
+func (b *lxdBackend) DeleteCustomVolumeSnapshot(projectName, volName string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName})
 	logger.Debug("DeleteCustomVolumeSnapshot started")
 	defer logger.Debug("DeleteCustomVolumeSnapshot finished")
 
@@ -2179,13 +2885,13 @@ func (b *lxdBackend) DeleteCustomVolumeSnapshot(volName string, op *operations.O
 
 	// Delete the snapshot from the storage device.
 	// Must come before DB StoragePoolVolumeDelete so that the volume ID is still available.
-	err := b.driver.DeleteVolumeSnapshot(drivers.VolumeTypeCustom, parentName, snapName, op)
+	err := b.driver.DeleteVolumeSnapshot(drivers.VolumeTypeCustom, project.Prefix(projectName, parentName), snapName, op)
 	if err != nil {
 		return err
 	}
 
 	// Remove the snapshot volume record from the database.
-	err = b.state.Cluster.StoragePoolVolumeDelete("default", volName, db.StoragePoolVolumeTypeCustom, b.ID())
+	err = b.state.Cluster.StoragePoolVolumeDelete(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
 	if err != nil {
 		return err
 	}
@@ -2194,8 +2900,8 @@ func (b *lxdBackend) DeleteCustomVolumeSnapshot(volName string, op *operations.O
 }
 
 // RestoreCustomVolume restores a custom volume from a snapshot.
-func (b *lxdBackend) RestoreCustomVolume(volName string, snapshotName string, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName, "snapshotName": snapshotName})
+func (b *lxdBackend) RestoreCustomVolume(projectName, volName string, snapshotName string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "snapshotName": snapshotName})
 	logger.Debug("RestoreCustomVolume started")
 	defer logger.Debug("RestoreCustomVolume finished")
 
@@ -2207,7 +2913,7 @@ func (b *lxdBackend) RestoreCustomVolume(volName string, snapshotName string, op
 		return fmt.Errorf("Invalid snapshot name")
 	}
 
-	usingVolume, err := VolumeUsedByInstancesWithProfiles(b.state, b.Name(), volName, db.StoragePoolVolumeTypeNameCustom, true)
+	usingVolume, err := VolumeUsedByInstancesWithProfiles(b.state, projectName, b.Name(), volName, db.StoragePoolVolumeTypeNameCustom, true)
 	if err != nil {
 		return err
 	}
@@ -2216,7 +2922,17 @@ func (b *lxdBackend) RestoreCustomVolume(volName string, snapshotName string, op
 		return fmt.Errorf("Cannot restore custom volume used by running instances")
 	}
 
-	err = b.driver.RestoreVolume(b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, nil), snapshotName, op)
+	_, dbVol, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
+	if err != nil {
+		return err
+	}
+
+	contentType, err := VolumeContentTypeNameToContentType(dbVol.ContentType)
+	if err != nil {
+		return err
+	}
+
+	err = b.driver.RestoreVolume(b.newVolume(drivers.VolumeTypeCustom, contentType, project.Prefix(projectName, volName), nil), snapshotName, op)
 	if err != nil {
 		return err
 	}
@@ -2224,6 +2940,53 @@ func (b *lxdBackend) RestoreCustomVolume(volName string, snapshotName string, op
 	return nil
 }
 
+// poolMigrationType negotiates the migration type to use when moving volumes of the given content
+// type from srcPool to this pool.
+func (b *lxdBackend) poolMigrationType(srcPool Pool, contentType drivers.ContentType) (migration.Type, error) {
+	offeredTypes := srcPool.MigrationTypes(contentType)
+	offerHeader := migration.TypesToHeader(offeredTypes...)
+	migrationType, err := migration.MatchTypes(offerHeader, migration.MigrationFSType_RSYNC, b.MigrationTypes(contentType))
+	if err != nil {
+		return migration.Type{}, fmt.Errorf("Failed to negotiate copy migration type: %v", err)
+	}
+
+	return migrationType, nil
+}
+
+// pipeVolume connects the send and receive functions with an in-memory pipe pair and runs them in
+// separate go routines to prevent deadlocks, returning any errors either of them produced.
+func pipeVolume(send func(conn io.ReadWriteCloser) error, receive func(conn io.ReadWriteCloser) error) error {
+	aEnd, bEnd := memorypipe.NewPipePair()
+
+	aEndErrCh := make(chan error, 1)
+	bEndErrCh := make(chan error, 1)
+	go func() {
+		aEndErrCh <- send(aEnd)
+	}()
+
+	go func() {
+		bEndErrCh <- receive(bEnd)
+	}()
+
+	// Capture errors from the sender and receiver from their result channels.
+	errs := []error{}
+	aEndErr := <-aEndErrCh
+	if aEndErr != nil {
+		errs = append(errs, aEndErr)
+	}
+
+	bEndErr := <-bEndErrCh
+	if bEndErr != nil {
+		errs = append(errs, bEndErr)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+
+	return nil
+}
+
 func (b *lxdBackend) createStorageStructure(path string) error {
 	for _, volType := range b.driver.Info().VolumeTypes {
 		for _, name := range baseDirectories[volType] {
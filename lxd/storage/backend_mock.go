@@ -2,6 +2,7 @@ package storage
 
 import (
 	"io"
+	"time"
 
 	"github.com/lxc/lxd/lxd/backup"
 	"github.com/lxc/lxd/lxd/instance"
@@ -92,6 +93,10 @@ func (b *mockBackend) RefreshInstance(i instance.Instance, src instance.Instance
 	return nil
 }
 
+func (b *mockBackend) MoveInstanceFromPool(inst instance.Instance, srcPool Pool, op *operations.Operation) error {
+	return nil
+}
+
 func (b *mockBackend) BackupInstance(inst instance.Instance, targetPath string, optimized bool, snapshots bool, op *operations.Operation) error {
 	return nil
 }
@@ -148,58 +153,78 @@ func (b *mockBackend) DeleteImage(fingerprint string, op *operations.Operation)
 	return nil
 }
 
-func (b *mockBackend) CreateCustomVolume(volName, desc string, config map[string]string, op *operations.Operation) error {
+func (b *mockBackend) CreateCustomVolume(projectName, volName, desc string, config map[string]string, contentType drivers.ContentType, op *operations.Operation) error {
 	return nil
 }
 
-func (b *mockBackend) CreateCustomVolumeFromCopy(volName, desc string, config map[string]string, srcPoolName, srcVolName string, srcVolOnly bool, op *operations.Operation) error {
+func (b *mockBackend) CreateCustomVolumeFromDiskImage(projectName, volName, desc string, config map[string]string, srcPath string, op *operations.Operation) error {
 	return nil
 }
 
-func (b *mockBackend) RenameCustomVolume(volName string, newName string, op *operations.Operation) error {
+func (b *mockBackend) CreateCustomVolumeFromCopy(projectName, volName, desc string, config map[string]string, srcPoolName, srcVolName string, srcVolOnly bool, op *operations.Operation) error {
 	return nil
 }
 
-func (b *mockBackend) UpdateCustomVolume(volName, newDesc string, newConfig map[string]string, op *operations.Operation) error {
+func (b *mockBackend) RenameCustomVolume(projectName, volName string, newName string, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) UpdateCustomVolume(projectName, volName, newDesc string, newConfig map[string]string, op *operations.Operation) error {
 	return ErrNotImplemented
 }
 
-func (b *mockBackend) DeleteCustomVolume(volName string, op *operations.Operation) error {
+func (b *mockBackend) DeleteCustomVolume(projectName, volName string, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args migration.VolumeSourceArgs, op *operations.Operation) error {
 	return nil
 }
 
-func (b *mockBackend) MigrateCustomVolume(conn io.ReadWriteCloser, args migration.VolumeSourceArgs, op *operations.Operation) error {
+func (b *mockBackend) CreateCustomVolumeFromMigration(projectName string, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error {
 	return nil
 }
 
-func (b *mockBackend) CreateCustomVolumeFromMigration(conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error {
+func (b *mockBackend) MoveCustomVolumeFromPool(projectName, volName string, srcPool Pool, op *operations.Operation) error {
 	return nil
 }
 
-func (b *mockBackend) GetCustomVolumeUsage(volName string) (int64, error) {
+func (b *mockBackend) BackupCustomVolume(projectName, volName string, targetPath string, optimized bool, snapshots bool, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) CreateCustomVolumeFromBackup(srcBackup backup.Info, srcData io.ReadSeeker, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) GetCustomVolumeUsage(projectName, volName string) (int64, error) {
 	return 0, nil
 }
 
-func (b *mockBackend) MountCustomVolume(volName string, op *operations.Operation) (bool, error) {
+func (b *mockBackend) GetCustomVolumeDisk(projectName, volName string) (string, error) {
+	return "", nil
+}
+
+func (b *mockBackend) MountCustomVolume(projectName, volName string, op *operations.Operation) (bool, error) {
 	return true, nil
 }
 
-func (b *mockBackend) UnmountCustomVolume(volName string, op *operations.Operation) (bool, error) {
+func (b *mockBackend) UnmountCustomVolume(projectName, volName string, op *operations.Operation) (bool, error) {
 	return true, nil
 }
 
-func (b *mockBackend) CreateCustomVolumeSnapshot(volName string, newSnapshotName string, op *operations.Operation) error {
+func (b *mockBackend) CreateCustomVolumeSnapshot(projectName, volName string, newSnapshotName string, newExpiryDate time.Time, op *operations.Operation) error {
 	return nil
 }
 
-func (b *mockBackend) RenameCustomVolumeSnapshot(volName string, newName string, op *operations.Operation) error {
+func (b *mockBackend) RenameCustomVolumeSnapshot(projectName, volName string, newName string, op *operations.Operation) error {
 	return nil
 }
 
-func (b *mockBackend) DeleteCustomVolumeSnapshot(volName string, op *operations.Operation) error {
+func (b *mockBackend) DeleteCustomVolumeSnapshot(projectName, volName string, op *operations.Operation) error {
 	return nil
 }
 
-func (b *mockBackend) RestoreCustomVolume(volName string, snapshotName string, op *operations.Operation) error {
+func (b *mockBackend) RestoreCustomVolume(projectName, volName string, snapshotName string, op *operations.Operation) error {
 	return nil
 }
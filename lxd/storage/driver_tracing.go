@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/lxc/lxd/lxd/migration"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/tracing"
+)
+
+// tracedDriver traces the calls to the storage driver done as part of an
+// operation, the other calls being passed through as they are.
+type tracedDriver struct {
+	drivers.Driver
+	pool string
+}
+
+func (d tracedDriver) start(op *operations.Operation, name string, volName string) tracing.Span {
+	ctx := context.Background()
+	if op != nil {
+		ctx = op.Context()
+	}
+
+	_, span := tracing.StartChild(ctx, "storage "+name,
+		tracing.String("lxd.storage.pool", d.pool),
+		tracing.String("lxd.storage.driver", d.Info().Name),
+		tracing.String("lxd.storage.volume", volName))
+
+	return span
+}
+
+func (d tracedDriver) Delete(op *operations.Operation) error {
+	span := d.start(op, "Delete", "")
+	err := d.Driver.Delete(op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) CreateVolume(vol drivers.Volume, filler *drivers.VolumeFiller, op *operations.Operation) error {
+	span := d.start(op, "CreateVolume", vol.Name())
+	err := d.Driver.CreateVolume(vol, filler, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) CreateVolumeFromCopy(vol drivers.Volume, srcVol drivers.Volume, copySnapshots bool, op *operations.Operation) error {
+	span := d.start(op, "CreateVolumeFromCopy", vol.Name())
+	err := d.Driver.CreateVolumeFromCopy(vol, srcVol, copySnapshots, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) RefreshVolume(vol drivers.Volume, srcVol drivers.Volume, srcSnapshots []drivers.Volume, op *operations.Operation) error {
+	span := d.start(op, "RefreshVolume", vol.Name())
+	err := d.Driver.RefreshVolume(vol, srcVol, srcSnapshots, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) DeleteVolume(volType drivers.VolumeType, volName string, op *operations.Operation) error {
+	span := d.start(op, "DeleteVolume", volName)
+	err := d.Driver.DeleteVolume(volType, volName, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) RenameVolume(volType drivers.VolumeType, volName string, newName string, op *operations.Operation) error {
+	span := d.start(op, "RenameVolume", volName)
+	err := d.Driver.RenameVolume(volType, volName, newName, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) CreateVolumeSnapshot(volType drivers.VolumeType, volName string, newSnapshotName string, op *operations.Operation) error {
+	span := d.start(op, "CreateVolumeSnapshot", volName)
+	err := d.Driver.CreateVolumeSnapshot(volType, volName, newSnapshotName, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) DeleteVolumeSnapshot(volType drivers.VolumeType, volName string, snapshotName string, op *operations.Operation) error {
+	span := d.start(op, "DeleteVolumeSnapshot", volName)
+	err := d.Driver.DeleteVolumeSnapshot(volType, volName, snapshotName, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) RestoreVolume(vol drivers.Volume, snapshotName string, op *operations.Operation) error {
+	span := d.start(op, "RestoreVolume", vol.Name())
+	err := d.Driver.RestoreVolume(vol, snapshotName, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) MigrateVolume(vol drivers.Volume, conn io.ReadWriteCloser, volSrcArgs migration.VolumeSourceArgs, op *operations.Operation) error {
+	span := d.start(op, "MigrateVolume", vol.Name())
+	err := d.Driver.MigrateVolume(vol, conn, volSrcArgs, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) CreateVolumeFromMigration(vol drivers.Volume, conn io.ReadWriteCloser, volTargetArgs migration.VolumeTargetArgs, preFiller *drivers.VolumeFiller, op *operations.Operation) error {
+	span := d.start(op, "CreateVolumeFromMigration", vol.Name())
+	err := d.Driver.CreateVolumeFromMigration(vol, conn, volTargetArgs, preFiller, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) BackupVolume(vol drivers.Volume, targetPath string, optimized bool, snapshots bool, op *operations.Operation) error {
+	span := d.start(op, "BackupVolume", vol.Name())
+	err := d.Driver.BackupVolume(vol, targetPath, optimized, snapshots, op)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (d tracedDriver) RestoreBackupVolume(vol drivers.Volume, snapshots []string, srcData io.ReadSeeker, op *operations.Operation) (func(vol drivers.Volume) error, func(), error) {
+	span := d.start(op, "RestoreBackupVolume", vol.Name())
+	postHook, revertHook, err := d.Driver.RestoreBackupVolume(vol, snapshots, srcData, op)
+	tracing.End(span, err)
+
+	return postHook, revertHook, err
+}
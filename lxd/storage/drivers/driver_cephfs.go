@@ -829,7 +829,10 @@ func (d *cephfs) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser,
 			d.DeleteVolumeSnapshot(vol.volType, vol.name, snapName, op)
 		}
 
-		os.RemoveAll(volPath)
+		// Only remove the volume itself if it was created by this migration.
+		if !volTargetArgs.Refresh {
+			os.RemoveAll(volPath)
+		}
 	}()
 
 	// Ensure the volume is mounted.
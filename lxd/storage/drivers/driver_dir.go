@@ -156,7 +156,14 @@ func (d *dir) GetVolumeUsage(volType VolumeType, volName string) (int64, error)
 
 // ValidateVolume validates the supplied volume config.
 func (d *dir) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
-	return d.validateVolume(vol, nil, removeUnknownKeys)
+	rules := map[string]func(value string) error{
+		"block.encryption": func(value string) error {
+			return shared.IsOneOf(value, encryptionTypes)
+		},
+		"volatile.encryption.uuid": shared.IsAny,
+	}
+
+	return d.validateVolume(vol, rules, removeUnknownKeys)
 }
 
 // HasVolume indicates whether a specific volume exists on the storage pool.
@@ -168,9 +175,20 @@ func (d *dir) HasVolume(volType VolumeType, volName string) bool {
 	return false
 }
 
-// GetVolumeDiskPath returns the location of a disk volume.
+// GetVolumeDiskPath returns the location of a disk volume. For an unlocked encrypted volume this
+// is the decrypted block device rather than the disk image file.
 func (d *dir) GetVolumeDiskPath(volType VolumeType, volName string) (string, error) {
-	return filepath.Join(GetVolumeMountPath(d.name, volType, volName), "root.img"), nil
+	devPath := LUKSDevicePath(LUKSDeviceName(d.name, volType, volName))
+	if shared.PathExists(devPath) {
+		return devPath, nil
+	}
+
+	return d.blockFilePath(volType, volName), nil
+}
+
+// blockFilePath returns the location of the disk image file of a block volume.
+func (d *dir) blockFilePath(volType VolumeType, volName string) string {
+	return filepath.Join(GetVolumeMountPath(d.name, volType, volName), "root.img")
 }
 
 // setupInitialQuota enables quota on a new volume and sets with an initial quota from config.
@@ -221,6 +239,10 @@ func (d *dir) setupInitialQuota(vol Volume) (func(), error) {
 // CreateVolume creates an empty volume and can optionally fill it by executing the supplied
 // filler function.
 func (d *dir) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Operation) error {
+	if vol.contentType != ContentTypeBlock && vol.config["block.encryption"] != "" {
+		return fmt.Errorf("Encryption is only supported for block volumes")
+	}
+
 	volPath := vol.MountPath()
 	err := vol.CreateMountPath()
 	if err != nil {
@@ -238,10 +260,7 @@ func (d *dir) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 	rootBlockPath := ""
 	if vol.contentType == ContentTypeBlock {
 		// We expect the filler to copy the VM image into this path.
-		rootBlockPath, err = d.GetVolumeDiskPath(vol.volType, vol.name)
-		if err != nil {
-			return err
-		}
+		rootBlockPath = d.blockFilePath(vol.volType, vol.name)
 	} else {
 		revertFunc, err := d.setupInitialQuota(vol)
 		if err != nil {
@@ -289,7 +308,7 @@ func (d *dir) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 			if err != nil {
 				return fmt.Errorf("Failed resizing disk image %s to size %s: %v", rootBlockPath, blockSize, err)
 			}
-		} else {
+		} else if vol.config["block.encryption"] == "" {
 			// If rootBlockPath doesn't exist, then there has been no filler function
 			// supplied to create it from another source. So instead create an empty
 			// volume (use for PXE booting a VM).
@@ -298,12 +317,81 @@ func (d *dir) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 				return fmt.Errorf("Failed creating disk image %s as size %s: %v", rootBlockPath, blockSize, err)
 			}
 		}
+
+		if vol.config["block.encryption"] != "" {
+			err = d.encryptBlockVolume(vol, rootBlockPath, blockSizeBytes)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	revertPath = false
 	return nil
 }
 
+// encryptBlockVolume replaces the disk image of a new block volume with a LUKS2 encrypted disk image
+// of the same usable size. Any data the filler wrote to the disk image is copied onto the new one.
+func (d *dir) encryptBlockVolume(vol Volume, rootBlockPath string, sizeBytes int64) error {
+	luksUUID := vol.config["volatile.encryption.uuid"]
+	if luksUUID == "" {
+		return fmt.Errorf("Missing encryption key for volume %q", vol.name)
+	}
+
+	plainPath := ""
+	if shared.PathExists(rootBlockPath) {
+		plainPath = fmt.Sprintf("%s.plain", rootBlockPath)
+		err := os.Rename(rootBlockPath, plainPath)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(plainPath)
+	}
+
+	err := createSparseFile(rootBlockPath, sizeBytes+luksHeaderSize)
+	if err != nil {
+		return err
+	}
+
+	err = CreateEncryptionKey(luksUUID, rootBlockPath)
+	if err != nil {
+		return err
+	}
+
+	revertKey := true
+	defer func() {
+		if revertKey {
+			ReleaseEncryptionKey(luksUUID, rootBlockPath)
+		}
+	}()
+
+	err = LUKSFormat(rootBlockPath, luksUUID)
+	if err != nil {
+		return err
+	}
+
+	// Nothing more to do for an empty volume.
+	if plainPath == "" {
+		revertKey = false
+		return nil
+	}
+
+	devName := LUKSDeviceName(d.name, vol.volType, vol.name)
+	_, err = LUKSOpen(rootBlockPath, devName)
+	if err != nil {
+		return err
+	}
+	defer LUKSClose(devName)
+
+	_, err = shared.RunCommand("qemu-img", "convert", "-n", "-f", "raw", "-O", "raw", plainPath, LUKSDevicePath(devName))
+	if err != nil {
+		return fmt.Errorf("Failed copying disk image %s onto encrypted device: %v", plainPath, err)
+	}
+
+	revertKey = false
+	return nil
+}
+
 // MigrateVolume sends a volume for migration.
 func (d *dir) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs migration.VolumeSourceArgs, op *operations.Operation) error {
 	if vol.contentType != ContentTypeFS {
@@ -384,7 +472,10 @@ func (d *dir) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser, vol
 			d.DeleteVolumeSnapshot(vol.volType, vol.name, snapName, op)
 		}
 
-		os.RemoveAll(volPath)
+		// Only remove the volume itself if it was created by this migration.
+		if !volTargetArgs.Refresh {
+			os.RemoveAll(volPath)
+		}
 	}()
 
 	// Ensure the volume is mounted.
@@ -495,7 +586,12 @@ func (d *dir) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op
 
 // copyVolume copies a volume and its specific snapshots.
 func (d *dir) copyVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op *operations.Operation) error {
-	if vol.contentType != ContentTypeFS || srcVol.contentType != ContentTypeFS {
+	if vol.contentType != srcVol.contentType {
+		return fmt.Errorf("Content type not supported")
+	}
+
+	// Block volumes are only supported for custom volumes.
+	if vol.contentType == ContentTypeBlock && vol.volType != VolumeTypeCustom {
 		return fmt.Errorf("Content type not supported")
 	}
 
@@ -514,6 +610,15 @@ func (d *dir) copyVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op *o
 		return err
 	}
 
+	// Get the key used by the disk image being refreshed, if any.
+	oldUUID := ""
+	if vol.contentType == ContentTypeBlock {
+		oldUUID, err = LUKSUUID(d.blockFilePath(vol.volType, vol.name))
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create slice of snapshots created if revert needed later.
 	revertSnaps := []string{}
 	defer func() {
@@ -554,16 +659,19 @@ func (d *dir) copyVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op *o
 			}
 		}
 
-		// Initialise the volume's quota using the volume ID.
-		err = d.initQuota(volPath, volID)
-		if err != nil {
-			return err
-		}
+		// Block volumes are sized by their disk image rather than by a quota.
+		if vol.contentType == ContentTypeFS {
+			// Initialise the volume's quota using the volume ID.
+			err = d.initQuota(volPath, volID)
+			if err != nil {
+				return err
+			}
 
-		// Set the quota if specified in volConfig or pool config.
-		err = d.setQuota(volPath, volID, vol.config["size"])
-		if err != nil {
-			return err
+			// Set the quota if specified in volConfig or pool config.
+			err = d.setQuota(volPath, volID, vol.config["size"])
+			if err != nil {
+				return err
+			}
 		}
 
 		// Copy source to destination (mounting each volume if needed).
@@ -576,6 +684,14 @@ func (d *dir) copyVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op *o
 		return err
 	}
 
+	// The copied disk image shares the key of the source.
+	if vol.contentType == ContentTypeBlock {
+		err = UpdateEncryptionKeyRef(oldUUID, d.blockFilePath(vol.volType, vol.name))
+		if err != nil {
+			return err
+		}
+	}
+
 	revertSnaps = nil // Don't revert.
 	return nil
 }
@@ -613,8 +729,22 @@ func (d *dir) VolumeSnapshots(volType VolumeType, volName string, op *operations
 
 // UpdateVolume applies config changes to the volume.
 func (d *dir) UpdateVolume(vol Volume, changedConfig map[string]string) error {
-	if vol.contentType != ContentTypeFS {
-		return fmt.Errorf("Content type not supported")
+	for _, key := range []string{"block.encryption", "volatile.encryption.uuid"} {
+		if _, changed := changedConfig[key]; changed {
+			return fmt.Errorf("The %s property cannot be changed", key)
+		}
+	}
+
+	if vol.contentType == ContentTypeBlock {
+		if vol.volType != VolumeTypeCustom {
+			return fmt.Errorf("Content type not supported")
+		}
+
+		if _, changed := changedConfig["size"]; changed {
+			return d.resizeBlockVolume(vol, changedConfig["size"])
+		}
+
+		return nil
 	}
 
 	if _, changed := changedConfig["size"]; changed {
@@ -633,10 +763,64 @@ func (d *dir) UpdateVolume(vol Volume, changedConfig map[string]string) error {
 	return nil
 }
 
+// resizeBlockVolume grows the disk image of a block volume to the new size.
+// Shrinking a block volume isn't supported as it would likely corrupt the data on it.
+func (d *dir) resizeBlockVolume(vol Volume, size string) error {
+	if size == "" || size == "0" {
+		return fmt.Errorf("Block volumes require a size")
+	}
+
+	sizeBytes, err := units.ParseByteSizeString(size)
+	if err != nil {
+		return err
+	}
+
+	rootBlockPath := d.blockFilePath(vol.volType, vol.name)
+
+	// The LUKS header of an encrypted disk image isn't part of the usable size.
+	encrypted := LUKSIsEncrypted(rootBlockPath)
+	if encrypted {
+		sizeBytes += luksHeaderSize
+	}
+
+	fileInfo, err := os.Stat(rootBlockPath)
+	if err != nil {
+		return err
+	}
+
+	if sizeBytes < fileInfo.Size() {
+		return fmt.Errorf("Block volumes cannot be shrunk")
+	}
+
+	if sizeBytes == fileInfo.Size() {
+		return nil
+	}
+
+	_, err = shared.RunCommand("qemu-img", "resize", "-f", "raw", rootBlockPath, fmt.Sprintf("%d", sizeBytes))
+	if err != nil {
+		return fmt.Errorf("Failed resizing disk image %s to size %s: %v", rootBlockPath, size, err)
+	}
+
+	// Grow the unlocked device too so a running VM sees the new size.
+	if encrypted {
+		err = LUKSResize(rootBlockPath, LUKSDeviceName(d.name, vol.volType, vol.name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // RenameVolume renames a volume and its snapshots.
 func (d *dir) RenameVolume(volType VolumeType, volName string, newVolName string, op *operations.Operation) error {
 	vol := NewVolume(d, d.name, volType, ContentTypeFS, volName, nil)
 
+	// The device of an unlocked encrypted volume is named after the volume.
+	if shared.PathExists(LUKSDevicePath(LUKSDeviceName(d.name, volType, volName))) {
+		return fmt.Errorf("Cannot rename an encrypted volume while it is unlocked")
+	}
+
 	// Create new snapshots directory.
 	snapshotDir := GetVolumeSnapshotDir(d.name, volType, newVolName)
 
@@ -698,6 +882,14 @@ func (d *dir) RenameVolume(volType VolumeType, volName string, newVolName string
 		newPath: newPath,
 	})
 
+	// Record the new paths of the encrypted disk images.
+	for _, vol := range revertPaths {
+		err = moveEncryptionKeyRefs(vol.oldPath, vol.newPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Remove old snapshots directory.
 	oldSnapshotDir := GetVolumeSnapshotDir(d.name, volType, volName)
 
@@ -718,15 +910,23 @@ func (d *dir) RestoreVolume(vol Volume, snapshotName string, op *operations.Oper
 	}
 
 	volPath := vol.MountPath()
+	rootBlockPath := d.blockFilePath(vol.volType, vol.name)
+
+	// Get the key used by the disk image being restored, if any.
+	oldUUID, err := LUKSUUID(rootBlockPath)
+	if err != nil {
+		return err
+	}
 
 	// Restore using rsync.
 	bwlimit := d.config["rsync.bwlimit"]
-	_, err := rsync.LocalCopy(srcPath, volPath, bwlimit, true)
+	_, err = rsync.LocalCopy(srcPath, volPath, bwlimit, true)
 	if err != nil {
 		return fmt.Errorf("Failed to rsync volume: %s", err)
 	}
 
-	return nil
+	// The restored disk image uses the key of the snapshot.
+	return UpdateEncryptionKeyRef(oldUUID, rootBlockPath)
 }
 
 // DeleteVolume deletes a volume of the storage device. If any snapshots of the volume remain then
@@ -760,12 +960,32 @@ func (d *dir) DeleteVolume(volType VolumeType, volName string, op *operations.Op
 		return err
 	}
 
+	// Lock the volume if it is encrypted and still unlocked.
+	_, err = LUKSClose(LUKSDeviceName(d.name, volType, volName))
+	if err != nil {
+		return err
+	}
+
+	rootBlockPath := d.blockFilePath(volType, volName)
+	keyUUID, err := LUKSUUID(rootBlockPath)
+	if err != nil {
+		return err
+	}
+
 	// Remove the volume from the storage device.
 	err = os.RemoveAll(volPath)
 	if err != nil {
 		return err
 	}
 
+	// Remove the key from the key store if no other disk image uses it.
+	if keyUUID != "" {
+		err = ReleaseEncryptionKey(keyUUID, rootBlockPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Although the volume snapshot directory should already be removed, lets remove it here
 	// to just in case the top-level directory is left.
 	err = deleteParentSnapshotDirIfEmpty(d.name, volType, volName)
@@ -777,9 +997,15 @@ func (d *dir) DeleteVolume(volType VolumeType, volName string, op *operations.Op
 }
 
 // MountVolume simulates mounting a volume. As dir driver doesn't have volumes to mount it returns
-// false indicating that there is no need to issue an unmount.
+// false indicating that there is no need to issue an unmount. Encrypted block volumes are unlocked
+// instead, in which case true is returned if this call unlocked the volume.
 func (d *dir) MountVolume(volType VolumeType, volName string, op *operations.Operation) (bool, error) {
-	return false, nil
+	rootBlockPath := d.blockFilePath(volType, volName)
+	if !LUKSIsEncrypted(rootBlockPath) {
+		return false, nil
+	}
+
+	return LUKSOpen(rootBlockPath, LUKSDeviceName(d.name, volType, volName))
 }
 
 // MountVolumeSnapshot sets up a read-only mount on top of the snapshot to avoid accidental modifications.
@@ -789,9 +1015,10 @@ func (d *dir) MountVolumeSnapshot(volType VolumeType, volName, snapshotName stri
 }
 
 // UnmountVolume simulates unmounting a volume. As dir driver doesn't have volumes to unmount it
-// returns false indicating the volume was already unmounted.
+// returns false indicating the volume was already unmounted. Unlocked encrypted block volumes are
+// locked instead, in which case true is returned.
 func (d *dir) UnmountVolume(volType VolumeType, volName string, op *operations.Operation) (bool, error) {
-	return false, nil
+	return LUKSClose(LUKSDeviceName(d.name, volType, volName))
 }
 
 // UnmountVolumeSnapshot removes the read-only mount placed on top of a snapshot.
@@ -922,6 +1149,12 @@ func (d *dir) CreateVolumeSnapshot(volType VolumeType, volName string, newSnapsh
 		return err
 	}
 
+	// The snapshot of an encrypted disk image shares its key.
+	err = UpdateEncryptionKeyRef("", d.blockFilePath(volType, fullSnapName))
+	if err != nil {
+		return err
+	}
+
 	revertPath = false
 	return nil
 }
@@ -931,12 +1164,26 @@ func (d *dir) CreateVolumeSnapshot(volType VolumeType, volName string, newSnapsh
 func (d *dir) DeleteVolumeSnapshot(volType VolumeType, volName string, snapshotName string, op *operations.Operation) error {
 	snapPath := GetVolumeMountPath(d.name, volType, GetSnapshotVolumeName(volName, snapshotName))
 
+	rootBlockPath := d.blockFilePath(volType, GetSnapshotVolumeName(volName, snapshotName))
+	keyUUID, err := LUKSUUID(rootBlockPath)
+	if err != nil {
+		return err
+	}
+
 	// Remove the snapshot from the storage device.
-	err := os.RemoveAll(snapPath)
+	err = os.RemoveAll(snapPath)
 	if err != nil {
 		return err
 	}
 
+	// Remove the key from the key store if no other disk image uses it.
+	if keyUUID != "" {
+		err = ReleaseEncryptionKey(keyUUID, rootBlockPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Remove the parent snapshot directory if this is the last snapshot being removed.
 	err = deleteParentSnapshotDirIfEmpty(d.name, volType, volName)
 	if err != nil {
@@ -955,6 +1202,13 @@ func (d *dir) RenameVolumeSnapshot(volType VolumeType, volName string, snapshotN
 		return err
 	}
 
+	// Record the new path of the encrypted disk image.
+	err = moveEncryptionKeyRefs(oldPath, newPath)
+	if err != nil {
+		os.Rename(newPath, oldPath)
+		return err
+	}
+
 	return nil
 }
 
@@ -963,13 +1217,9 @@ func (d *dir) RenameVolumeSnapshot(volType VolumeType, volName string, snapshotN
 func (d *dir) BackupVolume(vol Volume, targetPath string, _, snapshots bool, op *operations.Operation) error {
 	bwlimit := d.config["rsync.bwlimit"]
 
-	var parentVolDir string
-
-	// Backups only implemented for containers currently.
-	if vol.volType == VolumeTypeContainer {
-		parentVolDir = "container"
-	} else {
-		return ErrNotImplemented
+	parentVolDir, err := backupVolumeDir(vol.volType)
+	if err != nil {
+		return err
 	}
 
 	// Handle snapshots.
@@ -1002,7 +1252,7 @@ func (d *dir) BackupVolume(vol Volume, targetPath string, _, snapshots bool, op
 
 	// Copy the parent volume itself.
 	target := filepath.Join(targetPath, parentVolDir)
-	_, err := rsync.LocalCopy(vol.MountPath(), target, bwlimit, true)
+	_, err = rsync.LocalCopy(vol.MountPath(), target, bwlimit, true)
 	if err != nil {
 		return fmt.Errorf("Failed to rsync: %s", err)
 	}
@@ -1014,6 +1264,7 @@ func (d *dir) BackupVolume(vol Volume, targetPath string, _, snapshots bool, op
 func (d *dir) RestoreBackupVolume(vol Volume, snapshots []string, srcData io.ReadSeeker, op *operations.Operation) (func(vol Volume) error, func(), error) {
 	revert := true
 	revertPaths := []string{}
+	revertKeys := map[string]string{}
 
 	// Define a revert function that will be used both to revert if an error occurs inside this
 	// function but also return it for use from the calling functions if no error internally.
@@ -1021,6 +1272,10 @@ func (d *dir) RestoreBackupVolume(vol Volume, snapshots []string, srcData io.Rea
 		for _, revertPath := range revertPaths {
 			os.RemoveAll(revertPath)
 		}
+
+		for blockPath, keyUUID := range revertKeys {
+			ReleaseEncryptionKey(keyUUID, blockPath)
+		}
 	}
 
 	// Only execute the revert function if we have had an error internally and revert is true.
@@ -1030,8 +1285,13 @@ func (d *dir) RestoreBackupVolume(vol Volume, snapshots []string, srcData io.Rea
 		}
 	}()
 
+	parentVolDir, err := backupVolumeDir(vol.volType)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	volPath := vol.MountPath()
-	err := vol.CreateMountPath()
+	err = vol.CreateMountPath()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1049,10 +1309,10 @@ func (d *dir) RestoreBackupVolume(vol Volume, snapshots []string, srcData io.Rea
 		"-",
 		"--strip-components=2",
 		"--xattrs-include=*",
-		"-C", volPath, "backup/container",
+		"-C", volPath, fmt.Sprintf("backup/%s", parentVolDir),
 	}...)
 
-	// Extract instance.
+	// Extract the volume.
 	srcData.Seek(0, 0)
 	err = shared.RunCommandWithFds(srcData, nil, "tar", args...)
 	if err != nil {
@@ -1085,6 +1345,31 @@ func (d *dir) RestoreBackupVolume(vol Volume, snapshots []string, srcData io.Rea
 		}
 	}
 
+	// Backups don't include the keys of the encrypted disk images, which must already be in the
+	// key store.
+	blockPaths := []string{d.blockFilePath(vol.volType, vol.name)}
+	for _, snapName := range snapshots {
+		blockPaths = append(blockPaths, d.blockFilePath(vol.volType, GetSnapshotVolumeName(vol.name, snapName)))
+	}
+
+	for _, blockPath := range blockPaths {
+		keyUUID, err := LUKSUUID(blockPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if keyUUID == "" {
+			continue
+		}
+
+		err = AcquireEncryptionKey(keyUUID, blockPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed restoring encrypted volume %q: %v", vol.name, err)
+		}
+
+		revertKeys[blockPath] = keyUUID
+	}
+
 	// Define a post hook function that can be run once the backup config has been restored.
 	// This will setup the quota using the restored config.
 	postHook := func(vol Volume) error {
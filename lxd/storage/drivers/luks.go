@@ -0,0 +1,352 @@
+package drivers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// luksHeaderSize is the space reserved at the start of an encrypted disk image for the LUKS2
+// header. This matches the default data offset used by cryptsetup for LUKS2.
+const luksHeaderSize = 16 * 1024 * 1024
+
+// encryptionTypes lists the supported values of the block.encryption volume option.
+var encryptionTypes = []string{"luks2"}
+
+// EncryptionKeysPath returns the path of the server-side key store for encrypted volumes.
+func EncryptionKeysPath() string {
+	return shared.VarPath("storage-keys")
+}
+
+// encryptionKeyPath returns the key file path for the encrypted volume with the given LUKS UUID.
+func encryptionKeyPath(luksUUID string) string {
+	return filepath.Join(EncryptionKeysPath(), fmt.Sprintf("%s.key", luksUUID))
+}
+
+// encryptionKeysLock serializes the changes to the key store.
+var encryptionKeysLock sync.Mutex
+
+// encryptionRefsPath returns the path of the file listing the disk images which use the key with
+// the given LUKS UUID. Snapshots and copies of an encrypted volume share its key, which is only
+// removed from the key store once the last of them is deleted.
+func encryptionRefsPath(luksUUID string) string {
+	return filepath.Join(EncryptionKeysPath(), fmt.Sprintf("%s.refs", luksUUID))
+}
+
+// encryptionRefs returns the paths of the disk images using the key with the given LUKS UUID.
+func encryptionRefs(luksUUID string) ([]string, error) {
+	content, err := ioutil.ReadFile(encryptionRefsPath(luksUUID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+
+		return nil, err
+	}
+
+	refs := []string{}
+	for _, ref := range strings.Split(string(content), "\n") {
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs, nil
+}
+
+// setEncryptionRefs records the paths of the disk images using the key with the given LUKS UUID.
+func setEncryptionRefs(luksUUID string, refs []string) error {
+	content := ""
+	for _, ref := range refs {
+		content += ref + "\n"
+	}
+
+	return ioutil.WriteFile(encryptionRefsPath(luksUUID), []byte(content), 0600)
+}
+
+// CreateEncryptionKey adds a new random key to the key store for the new encrypted disk image at
+// path, which is to be formatted with the given LUKS UUID.
+func CreateEncryptionKey(luksUUID string, path string) error {
+	passphrase, err := shared.RandomCryptoString()
+	if err != nil {
+		return err
+	}
+
+	encryptionKeysLock.Lock()
+	defer encryptionKeysLock.Unlock()
+
+	err = os.MkdirAll(EncryptionKeysPath(), 0700)
+	if err != nil {
+		return err
+	}
+
+	if shared.PathExists(encryptionKeyPath(luksUUID)) {
+		return fmt.Errorf("An encryption key already exists for UUID %s", luksUUID)
+	}
+
+	err = ioutil.WriteFile(encryptionKeyPath(luksUUID), []byte(passphrase), 0600)
+	if err != nil {
+		return err
+	}
+
+	return setEncryptionRefs(luksUUID, []string{path})
+}
+
+// AcquireEncryptionKey records that the disk image at path uses the key with the given LUKS UUID.
+func AcquireEncryptionKey(luksUUID string, path string) error {
+	encryptionKeysLock.Lock()
+	defer encryptionKeysLock.Unlock()
+
+	if !shared.PathExists(encryptionKeyPath(luksUUID)) {
+		return fmt.Errorf("No key found in the key store for UUID %s", luksUUID)
+	}
+
+	refs, err := encryptionRefs(luksUUID)
+	if err != nil {
+		return err
+	}
+
+	if shared.StringInSlice(path, refs) {
+		return nil
+	}
+
+	return setEncryptionRefs(luksUUID, append(refs, path))
+}
+
+// ReleaseEncryptionKey records that the disk image at path doesn't use the key with the given
+// LUKS UUID anymore, and removes the key from the key store once no disk image uses it.
+func ReleaseEncryptionKey(luksUUID string, path string) error {
+	encryptionKeysLock.Lock()
+	defer encryptionKeysLock.Unlock()
+
+	refs, err := encryptionRefs(luksUUID)
+	if err != nil {
+		return err
+	}
+
+	remaining := []string{}
+	for _, ref := range refs {
+		if ref != path {
+			remaining = append(remaining, ref)
+		}
+	}
+
+	if len(remaining) > 0 {
+		return setEncryptionRefs(luksUUID, remaining)
+	}
+
+	err = os.Remove(encryptionKeyPath(luksUUID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Remove(encryptionRefsPath(luksUUID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// moveEncryptionKeyRefs updates the paths recorded in the key store for the disk images which were
+// under oldDir and have been moved to newDir.
+func moveEncryptionKeyRefs(oldDir string, newDir string) error {
+	encryptionKeysLock.Lock()
+	defer encryptionKeysLock.Unlock()
+
+	refsFiles, err := filepath.Glob(filepath.Join(EncryptionKeysPath(), "*.refs"))
+	if err != nil {
+		return err
+	}
+
+	for _, refsFile := range refsFiles {
+		luksUUID := strings.TrimSuffix(filepath.Base(refsFile), ".refs")
+
+		refs, err := encryptionRefs(luksUUID)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for i, ref := range refs {
+			if strings.HasPrefix(ref, oldDir+"/") {
+				refs[i] = filepath.Join(newDir, strings.TrimPrefix(ref, oldDir+"/"))
+				changed = true
+			}
+		}
+
+		if changed {
+			err = setEncryptionRefs(luksUUID, refs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpdateEncryptionKeyRef records the key used by the disk image at path after its content was
+// replaced, given the LUKS UUID of its previous content (empty if it was new or not encrypted).
+func UpdateEncryptionKeyRef(oldUUID string, path string) error {
+	newUUID, err := LUKSUUID(path)
+	if err != nil {
+		return err
+	}
+
+	if newUUID == oldUUID {
+		return nil
+	}
+
+	if newUUID != "" {
+		err = AcquireEncryptionKey(newUUID, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if oldUUID != "" {
+		err = ReleaseEncryptionKey(oldUUID, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RenameEncryptionKeyRef records that the encrypted disk image at oldPath is now at newPath.
+func RenameEncryptionKeyRef(luksUUID string, oldPath string, newPath string) error {
+	err := AcquireEncryptionKey(luksUUID, newPath)
+	if err != nil {
+		return err
+	}
+
+	return ReleaseEncryptionKey(luksUUID, oldPath)
+}
+
+// LUKSDeviceName returns the device mapper name used when a volume is unlocked.
+func LUKSDeviceName(poolName string, volType VolumeType, volName string) string {
+	// Escape the dashes so that the names of snapshots don't clash with the names of volumes.
+	volName = strings.Replace(volName, "-", "--", -1)
+	return fmt.Sprintf("lxd_%s_%s_%s", poolName, volType, strings.Replace(volName, "/", "-", -1))
+}
+
+// LUKSDevicePath returns the path of the unlocked block device for a device mapper name.
+func LUKSDevicePath(devName string) string {
+	return filepath.Join("/dev/mapper", devName)
+}
+
+// LUKSIsEncrypted returns true if the disk image at path has a LUKS header.
+func LUKSIsEncrypted(path string) bool {
+	if !shared.PathExists(path) {
+		return false
+	}
+
+	_, err := shared.RunCommand("cryptsetup", "isLuks", path)
+	return err == nil
+}
+
+// LUKSUUID returns the LUKS UUID of the disk image at path, or an empty string if it isn't
+// encrypted.
+func LUKSUUID(path string) (string, error) {
+	if !LUKSIsEncrypted(path) {
+		return "", nil
+	}
+
+	out, err := shared.RunCommand("cryptsetup", "luksUUID", path)
+	if err != nil {
+		return "", fmt.Errorf("Failed reading LUKS UUID of %s: %v", path, err)
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+// luksKeyFile returns the key file from the key store for the encrypted disk image at path.
+func luksKeyFile(path string) (string, error) {
+	uuid, err := LUKSUUID(path)
+	if err != nil {
+		return "", err
+	}
+
+	keyFile := encryptionKeyPath(uuid)
+	if !shared.PathExists(keyFile) {
+		return "", fmt.Errorf("No key found in the key store for encrypted volume %s", path)
+	}
+
+	return keyFile, nil
+}
+
+// LUKSFormat initialises a LUKS2 header on the disk image at path using the key stored for luksUUID.
+func LUKSFormat(path string, luksUUID string) error {
+	keyFile := encryptionKeyPath(luksUUID)
+	if !shared.PathExists(keyFile) {
+		return fmt.Errorf("No key found in the key store for UUID %s", luksUUID)
+	}
+
+	_, err := shared.RunCommand("cryptsetup", "luksFormat", "--batch-mode", "--type", "luks2", "--uuid", luksUUID, "--key-file", keyFile, path)
+	if err != nil {
+		return fmt.Errorf("Failed formatting encrypted disk image %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// LUKSOpen unlocks the disk image at path as devName. Returns true if the device was unlocked by
+// this call and false if it was already unlocked.
+func LUKSOpen(path string, devName string) (bool, error) {
+	if shared.PathExists(LUKSDevicePath(devName)) {
+		return false, nil
+	}
+
+	keyFile, err := luksKeyFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.RunCommand("cryptsetup", "open", "--type", "luks2", "--key-file", keyFile, path, devName)
+	if err != nil {
+		return false, fmt.Errorf("Failed unlocking encrypted disk image %s: %v", path, err)
+	}
+
+	return true, nil
+}
+
+// LUKSClose locks devName. Returns true if the device was locked by this call and false if it
+// wasn't unlocked.
+func LUKSClose(devName string) (bool, error) {
+	if !shared.PathExists(LUKSDevicePath(devName)) {
+		return false, nil
+	}
+
+	_, err := shared.RunCommand("cryptsetup", "close", devName)
+	if err != nil {
+		return false, fmt.Errorf("Failed locking encrypted device %s: %v", devName, err)
+	}
+
+	return true, nil
+}
+
+// LUKSResize grows an unlocked device to fill the resized disk image at path.
+func LUKSResize(path string, devName string) error {
+	if !shared.PathExists(LUKSDevicePath(devName)) {
+		return nil
+	}
+
+	keyFile, err := luksKeyFile(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("cryptsetup", "resize", "--key-file", keyFile, devName)
+	if err != nil {
+		return fmt.Errorf("Failed resizing encrypted device %s: %v", devName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package drivers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// Keys are removed from the key store once no disk image uses them.
+func TestEncryptionKeyRefs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-luks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldDir := os.Getenv("LXD_DIR")
+	os.Setenv("LXD_DIR", dir)
+	defer os.Setenv("LXD_DIR", oldDir)
+
+	require.NoError(t, CreateEncryptionKey("u1", "/pool/custom/vol1/root.img"))
+	assert.Error(t, CreateEncryptionKey("u1", "/pool/custom/vol2/root.img"))
+
+	// A snapshot shares the key of its volume.
+	require.NoError(t, AcquireEncryptionKey("u1", "/pool/custom-snapshots/vol1/snap0/root.img"))
+	require.NoError(t, ReleaseEncryptionKey("u1", "/pool/custom/vol1/root.img"))
+	assert.True(t, shared.PathExists(encryptionKeyPath("u1")))
+
+	// Disk images which don't use the key can't release it.
+	require.NoError(t, ReleaseEncryptionKey("u1", "/pool/custom/other/root.img"))
+	assert.True(t, shared.PathExists(encryptionKeyPath("u1")))
+
+	// Renamed disk images keep using the key.
+	require.NoError(t, moveEncryptionKeyRefs("/pool/custom-snapshots/vol1", "/pool/custom-snapshots/vol3"))
+	refs, err := encryptionRefs("u1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/pool/custom-snapshots/vol3/snap0/root.img"}, refs)
+
+	require.NoError(t, ReleaseEncryptionKey("u1", "/pool/custom-snapshots/vol3/snap0/root.img"))
+	assert.False(t, shared.PathExists(encryptionKeyPath("u1")))
+	assert.False(t, shared.PathExists(encryptionRefsPath("u1")))
+
+	// Keys can't be acquired once removed.
+	assert.Error(t, AcquireEncryptionKey("u1", "/pool/custom/vol1/root.img"))
+}
@@ -192,6 +192,19 @@ func GetSnapshotVolumeName(parentName, snapshotName string) string {
 	return fmt.Sprintf("%s%s%s", parentName, shared.SnapshotDelimiter, snapshotName)
 }
 
+// backupVolumeDir returns the directory holding the main volume inside a
+// backup tarball for the given volume type.
+func backupVolumeDir(volType VolumeType) (string, error) {
+	switch volType {
+	case VolumeTypeContainer:
+		return "container", nil
+	case VolumeTypeCustom:
+		return "volume", nil
+	}
+
+	return "", ErrNotImplemented
+}
+
 // deleteParentSnapshotDirIfEmpty removes the parent snapshot directory if it is empty.
 // It accepts the pool name, volume type and parent volume name.
 func deleteParentSnapshotDirIfEmpty(poolName string, volType VolumeType, volName string) error {
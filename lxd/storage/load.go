@@ -36,7 +36,7 @@ func volIDFuncMake(state *state.State, poolID int64) func(volType drivers.Volume
 		// the project is default.
 		project := "default"
 
-		// Currently only Containers and VMs support project level volumes.
+		// Currently only Containers, VMs and custom volumes support project level volumes.
 		// This means that other volume types may have underscores in their names that don't
 		// indicate the project name.
 		if volType == drivers.VolumeTypeContainer || volType == drivers.VolumeTypeVM {
@@ -45,6 +45,16 @@ func volIDFuncMake(state *state.State, poolID int64) func(volType drivers.Volume
 				project = volParts[0]
 				volName = volParts[1]
 			}
+		} else if volType == drivers.VolumeTypeCustom {
+			// Custom volumes in the default project may have underscores in their names,
+			// so only use the prefix if it matches an existing volume in that project.
+			volParts := strings.SplitN(volName, "_", 2)
+			if len(volParts) > 1 {
+				volID, _, err := state.Cluster.StoragePoolNodeVolumeGetTypeByProject(volParts[0], volParts[1], volTypeID, poolID)
+				if err == nil {
+					return volID, nil
+				}
+			}
 		}
 
 		volID, _, err := state.Cluster.StoragePoolNodeVolumeGetTypeByProject(project, volName, volTypeID, poolID)
@@ -92,7 +102,7 @@ func CreatePool(state *state.State, poolID int64, dbPool *api.StoragePoolsPost,
 
 	// Setup the pool struct.
 	pool := lxdBackend{}
-	pool.driver = driver
+	pool.driver = tracedDriver{Driver: driver, pool: dbPool.Name}
 	pool.id = poolID
 	pool.name = dbPool.Name
 	pool.state = state
@@ -140,7 +150,7 @@ func GetPoolByName(state *state.State, name string) (Pool, error) {
 
 	// Setup the pool struct.
 	pool := lxdBackend{}
-	pool.driver = driver
+	pool.driver = tracedDriver{Driver: driver, pool: dbPool.Name}
 	pool.id = poolID
 	pool.name = dbPool.Name
 	pool.state = state
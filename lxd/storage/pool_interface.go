@@ -2,6 +2,7 @@ package storage
 
 import (
 	"io"
+	"time"
 
 	"github.com/lxc/lxd/lxd/backup"
 	"github.com/lxc/lxd/lxd/instance"
@@ -35,6 +36,7 @@ type Pool interface {
 
 	MigrateInstance(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeSourceArgs, op *operations.Operation) error
 	RefreshInstance(inst instance.Instance, src instance.Instance, srcSnapshots []instance.Instance, op *operations.Operation) error
+	MoveInstanceFromPool(inst instance.Instance, srcPool Pool, op *operations.Operation) error
 	BackupInstance(inst instance.Instance, targetPath string, optimized bool, snapshots bool, op *operations.Operation) error
 
 	GetInstanceUsage(inst instance.Instance) (int64, error)
@@ -57,23 +59,30 @@ type Pool interface {
 	DeleteImage(fingerprint string, op *operations.Operation) error
 
 	// Custom volumes.
-	CreateCustomVolume(volName, desc string, config map[string]string, op *operations.Operation) error
-	CreateCustomVolumeFromCopy(volName, desc string, config map[string]string, srcPoolName, srcVolName string, srcVolOnly bool, op *operations.Operation) error
-	UpdateCustomVolume(volName, newDesc string, newConfig map[string]string, op *operations.Operation) error
-	RenameCustomVolume(volName string, newVolName string, op *operations.Operation) error
-	DeleteCustomVolume(volName string, op *operations.Operation) error
-	GetCustomVolumeUsage(volName string) (int64, error)
-	MountCustomVolume(volName string, op *operations.Operation) (bool, error)
-	UnmountCustomVolume(volName string, op *operations.Operation) (bool, error)
+	CreateCustomVolume(projectName, volName, desc string, config map[string]string, contentType drivers.ContentType, op *operations.Operation) error
+	CreateCustomVolumeFromDiskImage(projectName, volName, desc string, config map[string]string, srcPath string, op *operations.Operation) error
+	CreateCustomVolumeFromCopy(projectName, volName, desc string, config map[string]string, srcPoolName, srcVolName string, srcVolOnly bool, op *operations.Operation) error
+	UpdateCustomVolume(projectName, volName, newDesc string, newConfig map[string]string, op *operations.Operation) error
+	RenameCustomVolume(projectName, volName string, newVolName string, op *operations.Operation) error
+	DeleteCustomVolume(projectName, volName string, op *operations.Operation) error
+	GetCustomVolumeUsage(projectName, volName string) (int64, error)
+	GetCustomVolumeDisk(projectName, volName string) (string, error)
+	MountCustomVolume(projectName, volName string, op *operations.Operation) (bool, error)
+	UnmountCustomVolume(projectName, volName string, op *operations.Operation) (bool, error)
 
 	// Custom volume snapshots.
-	CreateCustomVolumeSnapshot(volName string, newSnapshotName string, op *operations.Operation) error
-	RenameCustomVolumeSnapshot(volName string, newSnapshotName string, op *operations.Operation) error
-	DeleteCustomVolumeSnapshot(volName string, op *operations.Operation) error
-	RestoreCustomVolume(volName string, snapshotName string, op *operations.Operation) error
+	CreateCustomVolumeSnapshot(projectName, volName string, newSnapshotName string, newExpiryDate time.Time, op *operations.Operation) error
+	RenameCustomVolumeSnapshot(projectName, volName string, newSnapshotName string, op *operations.Operation) error
+	DeleteCustomVolumeSnapshot(projectName, volName string, op *operations.Operation) error
+	RestoreCustomVolume(projectName, volName string, snapshotName string, op *operations.Operation) error
+
+	// Custom volume backups.
+	BackupCustomVolume(projectName, volName string, targetPath string, optimized bool, snapshots bool, op *operations.Operation) error
+	CreateCustomVolumeFromBackup(srcBackup backup.Info, srcData io.ReadSeeker, op *operations.Operation) error
 
 	// Custom volume migration.
 	MigrationTypes(contentType drivers.ContentType) []migration.Type
-	CreateCustomVolumeFromMigration(conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error
-	MigrateCustomVolume(conn io.ReadWriteCloser, args migration.VolumeSourceArgs, op *operations.Operation) error
+	CreateCustomVolumeFromMigration(projectName string, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error
+	MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args migration.VolumeSourceArgs, op *operations.Operation) error
+	MoveCustomVolumeFromPool(projectName, volName string, srcPool Pool, op *operations.Operation) error
 }
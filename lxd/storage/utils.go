@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -29,7 +30,7 @@ var baseDirectories = map[drivers.VolumeType][]string{
 }
 
 // VolumeUsedByInstancesWithProfiles returns a slice containing the names of instances using a volume.
-var VolumeUsedByInstancesWithProfiles func(s *state.State, poolName string, volumeName string, volumeTypeName string, runningOnly bool) ([]string, error)
+var VolumeUsedByInstancesWithProfiles func(s *state.State, projectName string, poolName string, volumeName string, volumeTypeName string, runningOnly bool) ([]string, error)
 
 // MkfsOptions represents options for filesystem creation.
 type MkfsOptions struct {
@@ -410,6 +411,42 @@ func VolumeTypeToDBType(volType drivers.VolumeType) (int, error) {
 	return -1, fmt.Errorf("Invalid storage volume type")
 }
 
+// VolumeContentTypeToDBContentType converts volume content type to internal code.
+func VolumeContentTypeToDBContentType(contentType drivers.ContentType) (int, error) {
+	switch contentType {
+	case drivers.ContentTypeFS:
+		return db.StoragePoolVolumeContentTypeFS, nil
+	case drivers.ContentTypeBlock:
+		return db.StoragePoolVolumeContentTypeBlock, nil
+	}
+
+	return -1, fmt.Errorf("Invalid storage volume content type")
+}
+
+// VolumeDBContentTypeToContentType converts internal content type code to volume content type.
+func VolumeDBContentTypeToContentType(contentType int) (drivers.ContentType, error) {
+	switch contentType {
+	case db.StoragePoolVolumeContentTypeFS:
+		return drivers.ContentTypeFS, nil
+	case db.StoragePoolVolumeContentTypeBlock:
+		return drivers.ContentTypeBlock, nil
+	}
+
+	return "", fmt.Errorf("Invalid storage volume content type")
+}
+
+// VolumeContentTypeNameToContentType converts a volume content type string to volume content type.
+func VolumeContentTypeNameToContentType(contentTypeName string) (drivers.ContentType, error) {
+	switch contentTypeName {
+	case db.StoragePoolVolumeContentTypeNameFS:
+		return drivers.ContentTypeFS, nil
+	case db.StoragePoolVolumeContentTypeNameBlock:
+		return drivers.ContentTypeBlock, nil
+	}
+
+	return "", fmt.Errorf("Invalid storage volume content type name")
+}
+
 // InstanceTypeToVolumeType converts instance type to volume type.
 func InstanceTypeToVolumeType(instType instancetype.Type) (drivers.VolumeType, error) {
 	switch instType {
@@ -423,13 +460,19 @@ func InstanceTypeToVolumeType(instType instancetype.Type) (drivers.VolumeType, e
 }
 
 // VolumeDBCreate creates a volume in the database.
-func VolumeDBCreate(s *state.State, poolName string, volumeName, volumeDescription string, volumeTypeName string, snapshot bool, volumeConfig map[string]string) error {
+func VolumeDBCreate(s *state.State, project string, poolName string, volumeName, volumeDescription string, volumeTypeName string, snapshot bool, volumeConfig map[string]string, contentType drivers.ContentType) error {
 	// Convert the volume type name to our internal integer representation.
 	volumeType, err := VolumeTypeNameToType(volumeTypeName)
 	if err != nil {
 		return err
 	}
 
+	// Convert the volume content type to our internal integer representation.
+	volumeContentType, err := VolumeContentTypeToDBContentType(contentType)
+	if err != nil {
+		return err
+	}
+
 	// Load storage pool the volume will be attached to.
 	poolID, poolStruct, err := s.Cluster.StoragePoolGet(poolName)
 	if err != nil {
@@ -438,7 +481,7 @@ func VolumeDBCreate(s *state.State, poolName string, volumeName, volumeDescripti
 
 	// Check that a storage volume of the same storage volume type does not
 	// already exist.
-	volumeID, _ := s.Cluster.StoragePoolNodeVolumeGetTypeID(volumeName, volumeType, poolID)
+	volumeID, _, _ := s.Cluster.StoragePoolNodeVolumeGetTypeByProject(project, volumeName, volumeType, poolID)
 	if volumeID > 0 {
 		return fmt.Errorf("A storage volume of type %s already exists", volumeTypeName)
 	}
@@ -459,8 +502,37 @@ func VolumeDBCreate(s *state.State, poolName string, volumeName, volumeDescripti
 		return err
 	}
 
+	// New custom volumes on block-backed pools are encrypted if the pool says so.
+	if volumeType == db.StoragePoolVolumeTypeCustom && !snapshot && shared.StringInSlice(poolStruct.Driver, []string{"ceph", "lvm"}) {
+		if volumeConfig["block.encryption"] == "" && poolStruct.Config["volume.block.encryption"] != "" {
+			volumeConfig["block.encryption"] = poolStruct.Config["volume.block.encryption"]
+		}
+	}
+
+	// The keys of encrypted volumes are only kept by the local member, so the ceph volumes which
+	// every member of a cluster uses can't be encrypted.
+	if volumeConfig["block.encryption"] != "" && !snapshot && poolStruct.Driver == "ceph" {
+		clustered := false
+		err = s.Node.Transaction(func(tx *db.NodeTx) error {
+			addresses, err := tx.RaftNodeAddresses()
+			if err != nil {
+				return err
+			}
+
+			clustered = len(addresses) > 0
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if clustered {
+			return fmt.Errorf("Encrypted volumes aren't supported on ceph storage pools of clusters")
+		}
+	}
+
 	// Create the database entry for the storage volume.
-	_, err = s.Cluster.StoragePoolVolumeCreate("default", volumeName, volumeDescription, volumeType, snapshot, poolID, volumeConfig)
+	_, err = s.Cluster.StoragePoolVolumeCreate(project, volumeName, volumeDescription, volumeType, snapshot, poolID, volumeConfig, volumeContentType)
 	if err != nil {
 		return fmt.Errorf("Error inserting %s of type %s into database: %s", poolName, volumeTypeName, err)
 	}
@@ -475,6 +547,14 @@ var SupportedPoolTypes = []string{"btrfs", "ceph", "cephfs", "dir", "lvm", "zfs"
 // StorageVolumeConfigKeys config validation for btrfs, ceph, cephfs, dir, lvm, zfs types.
 // Deprecated: these are being moved to the per-storage-driver implementations.
 var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
+	"block.encryption": func(value string) ([]string, error) {
+		err := shared.IsOneOf(value, []string{"luks2"})
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{"ceph", "dir", "lvm"}, nil
+	},
 	"block.filesystem": func(value string) ([]string, error) {
 		err := shared.IsOneOf(value, []string{"btrfs", "ext4", "xfs"})
 		if err != nil {
@@ -492,6 +572,15 @@ var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
 	"security.unmapped": func(value string) ([]string, error) {
 		return SupportedPoolTypes, shared.IsBool(value)
 	},
+	"snapshots.expiry": func(value string) ([]string, error) {
+		return SupportedPoolTypes, shared.KnownInstanceConfigKeys["snapshots.expiry"](value)
+	},
+	"snapshots.pattern": func(value string) ([]string, error) {
+		return SupportedPoolTypes, shared.IsAny(value)
+	},
+	"snapshots.schedule": func(value string) ([]string, error) {
+		return SupportedPoolTypes, shared.KnownInstanceConfigKeys["snapshots.schedule"](value)
+	},
 	"size": func(value string) ([]string, error) {
 		if value == "" {
 			return SupportedPoolTypes, nil
@@ -504,6 +593,9 @@ var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
 
 		return SupportedPoolTypes, nil
 	},
+	"volatile.encryption.uuid": func(value string) ([]string, error) {
+		return []string{"dir"}, shared.IsAny(value)
+	},
 	"volatile.idmap.last": func(value string) ([]string, error) {
 		return SupportedPoolTypes, shared.IsAny(value)
 	},
@@ -566,6 +658,12 @@ func VolumeValidateConfig(name string, config map[string]string, parentPool *api
 			}
 		}
 
+		if !shared.StringInSlice(parentPool.Driver, []string{"ceph", "dir", "lvm"}) {
+			if config["block.encryption"] != "" {
+				return fmt.Errorf("the key block.encryption can only be used with ceph, dir and lvm storage volumes")
+			}
+		}
+
 		if parentPool.Driver == "dir" {
 			if config["block.mount_options"] != "" {
 				return fmt.Errorf("the key block.mount_options cannot be used with dir storage volumes")
@@ -621,13 +719,13 @@ func VolumeFillDefault(name string, config map[string]string, parentPool *api.St
 }
 
 // VolumeSnapshotsGet returns a list of snapshots of the form <volume>/<snapshot-name>.
-func VolumeSnapshotsGet(s *state.State, pool string, volume string, volType int) ([]db.StorageVolumeArgs, error) {
+func VolumeSnapshotsGet(s *state.State, project string, pool string, volume string, volType int) ([]db.StorageVolumeArgs, error) {
 	poolID, err := s.Cluster.StoragePoolGetID(pool)
 	if err != nil {
 		return nil, err
 	}
 
-	snapshots, err := s.Cluster.StoragePoolVolumeSnapshotsGetType(volume, volType, poolID)
+	snapshots, err := s.Cluster.StoragePoolVolumeSnapshotsGetType(project, volume, volType, poolID)
 	if err != nil {
 		return nil, err
 	}
@@ -672,6 +770,9 @@ func validateVolumeCommonRules() map[string]func(string) error {
 	return map[string]func(string) error{
 		"security.shifted":    shared.IsBool,
 		"security.unmapped":   shared.IsBool,
+		"snapshots.expiry":    shared.KnownInstanceConfigKeys["snapshots.expiry"],
+		"snapshots.pattern":   shared.IsAny,
+		"snapshots.schedule":  shared.KnownInstanceConfigKeys["snapshots.schedule"],
 		"volatile.idmap.last": shared.IsAny,
 		"volatile.idmap.next": shared.IsAny,
 		"size": func(value string) error {
@@ -744,8 +845,18 @@ func ImageUnpack(imageFile, destPath, destBlockFile string, blockBackend, runnin
 		}
 
 		if os.IsNotExist(err) || !fileInfo.IsDir() {
+			// Check the rootfs is a self-contained qcow2 image.
+			format, _, err := diskImageQuery(imageRootfsFile)
+			if err != nil {
+				return err
+			}
+
+			if format != "qcow2" {
+				return fmt.Errorf("Unsupported image rootfs format %q", format)
+			}
+
 			// Convert the qcow2 format to a raw block device.
-			_, err = shared.RunCommand("qemu-img", "convert", "-O", "raw", imageRootfsFile, destBlockFile)
+			_, err = shared.RunCommand("qemu-img", "convert", "-f", "qcow2", "-O", "raw", imageRootfsFile, destBlockFile)
 			if err != nil {
 				return fmt.Errorf("Failed converting image to raw at %s: %v", destBlockFile, err)
 			}
@@ -758,6 +869,86 @@ func ImageUnpack(imageFile, destPath, destBlockFile string, blockBackend, runnin
 	return nil
 }
 
+// diskImageQuery returns the format and virtual size in bytes of a disk image.
+// Images referencing other files, through a backing file, an external data
+// file or external extents, are refused as those files would be read from the
+// host.
+func diskImageQuery(path string) (string, int64, error) {
+	out, err := shared.RunCommand("qemu-img", "info", "--output=json", path)
+	if err != nil {
+		return "", -1, fmt.Errorf("Failed reading disk image information: %v", err)
+	}
+
+	info := struct {
+		Format          string `json:"format"`
+		VirtualSize     int64  `json:"virtual-size"`
+		BackingFilename string `json:"backing-filename"`
+		FormatSpecific  struct {
+			Data struct {
+				DataFile string `json:"data-file"`
+				Extents  []struct {
+					Filename string `json:"filename"`
+				} `json:"extents"`
+			} `json:"data"`
+		} `json:"format-specific"`
+	}{}
+
+	err = json.Unmarshal([]byte(out), &info)
+	if err != nil {
+		return "", -1, fmt.Errorf("Failed parsing disk image information: %v", err)
+	}
+
+	if info.BackingFilename != "" {
+		return "", -1, fmt.Errorf("Disk images with a backing file aren't supported")
+	}
+
+	if info.FormatSpecific.Data.DataFile != "" {
+		return "", -1, fmt.Errorf("Disk images with an external data file aren't supported")
+	}
+
+	for _, extent := range info.FormatSpecific.Data.Extents {
+		if extent.Filename != path {
+			return "", -1, fmt.Errorf("Disk images with external extents aren't supported")
+		}
+	}
+
+	return info.Format, info.VirtualSize, nil
+}
+
+// diskImageInfo returns the format and virtual size in bytes of a raw or qcow2 disk image.
+func diskImageInfo(path string) (string, int64, error) {
+	format, size, err := diskImageQuery(path)
+	if err != nil {
+		return "", -1, err
+	}
+
+	if !shared.StringInSlice(format, []string{"raw", "qcow2"}) {
+		return "", -1, fmt.Errorf("Unsupported disk image format %q", format)
+	}
+
+	return format, size, nil
+}
+
+// DiskImageConvert converts a raw, qcow2 or vmdk disk image into the qcow2 root
+// disk of a virtual-machine image and returns the format of the source image.
+func DiskImageConvert(srcPath string, destPath string) (string, error) {
+	format, _, err := diskImageQuery(srcPath)
+	if err != nil {
+		return "", err
+	}
+
+	if !shared.StringInSlice(format, []string{"raw", "qcow2", "vmdk"}) {
+		return "", fmt.Errorf("Unsupported disk image format %q", format)
+	}
+
+	_, err = shared.RunCommand("qemu-img", "convert", "-f", format, "-O", "qcow2", srcPath, destPath)
+	if err != nil {
+		return "", fmt.Errorf("Failed converting disk image to qcow2: %v", err)
+	}
+
+	return format, nil
+}
+
 // InstanceContentType returns the instance's content type.
 func InstanceContentType(inst instance.Instance) drivers.ContentType {
 	contentType := drivers.ContentTypeFS
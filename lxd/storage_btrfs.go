@@ -775,7 +775,7 @@ func (s *storageBtrfs) StoragePoolVolumeRename(newName string) error {
 	}
 
 	// Get volumes attached to source storage volume
-	volumes, err := s.s.Cluster.StoragePoolVolumeSnapshotsGetType(s.volume.Name,
+	volumes, err := s.s.Cluster.StoragePoolVolumeSnapshotsGetType("default", s.volume.Name,
 		storagePoolVolumeTypeCustom, s.poolID)
 	if err != nil {
 		return err
@@ -2928,7 +2928,7 @@ func (s *storageBtrfs) doCrossPoolVolumeCopy(sourcePool string, sourceName strin
 
 	if !volumeOnly {
 		// Handle snapshots
-		snapshots, err := driver.VolumeSnapshotsGet(s.s, sourcePool, sourceName, storagePoolVolumeTypeCustom)
+		snapshots, err := driver.VolumeSnapshotsGet(s.s, "default", sourcePool, sourceName, storagePoolVolumeTypeCustom)
 		if err != nil {
 			return err
 		}
@@ -23,6 +23,7 @@ import (
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/rsync"
 	driver "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/ioprogress"
@@ -372,11 +373,23 @@ func (s *storageCeph) StoragePoolVolumeCreate() error {
 	RBDFilesystem := s.getRBDFilesystem()
 	logger.Debugf(`Retrieved filesystem type "%s" of RBD storage volume "%s" on storage pool "%s"`, RBDFilesystem, s.volume.Name, s.pool.Name)
 
-	output, err := driver.MakeFSType(RBDDevPath, RBDFilesystem, nil)
+	keyRef := cephRBDEncryptionKeyRef(s.ClusterName, s.OSDPoolName, s.volume.Name)
+	err = storageBlockVolumeMakeFS(RBDDevPath, keyRef, storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, s.volume.Name), RBDFilesystem, s.volume.Config)
 	if err != nil {
-		logger.Errorf(`Failed to create filesystem type "%s" on device path "%s" for RBD storage volume "%s" on storage pool "%s": %v (%s)`, RBDFilesystem, RBDDevPath, s.volume.Name, s.pool.Name, err, output)
+		logger.Errorf(`Failed to create filesystem type "%s" on device path "%s" for RBD storage volume "%s" on storage pool "%s": %v`, RBDFilesystem, RBDDevPath, s.volume.Name, s.pool.Name, err)
 		return err
 	}
+
+	defer func() {
+		if !revert {
+			return
+		}
+
+		keyUUID, _ := storageDrivers.LUKSUUID(RBDDevPath)
+		if keyUUID != "" {
+			storageDrivers.ReleaseEncryptionKey(keyUUID, keyRef)
+		}
+	}()
 	logger.Debugf(`Created filesystem type "%s" on device path "%s" for RBD storage volume "%s" on storage pool "%s"`, RBDFilesystem, RBDDevPath, s.volume.Name, s.pool.Name)
 
 	volumeMntPoint := driver.GetStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
@@ -424,7 +437,7 @@ func (s *storageCeph) StoragePoolVolumeDelete() error {
 		s.volume.Name, s.pool.Name)
 
 	// Delete all snapshots
-	snapshots, err := driver.VolumeSnapshotsGet(s.s, s.pool.Name, s.volume.Name, storagePoolVolumeTypeCustom)
+	snapshots, err := driver.VolumeSnapshotsGet(s.s, "default", s.pool.Name, s.volume.Name, storagePoolVolumeTypeCustom)
 	if err != nil {
 		return err
 	}
@@ -445,12 +458,30 @@ func (s *storageCeph) StoragePoolVolumeDelete() error {
 		logger.Debugf(`Unmounted RBD storage volume "%s" on storage pool "%s"`, s.volume.Name, s.pool.Name)
 	}
 
+	_, err = storageDrivers.LUKSClose(storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, s.volume.Name))
+	if err != nil {
+		return err
+	}
+
 	rbdVolumeExists := cephRBDVolumeExists(s.ClusterName, s.OSDPoolName,
 		s.volume.Name, storagePoolVolumeTypeNameCustom, s.UserName)
 
 	// delete
+	keyUUID := ""
 	if rbdVolumeExists {
-		ret := cephContainerDelete(s.ClusterName, s.OSDPoolName, s.volume.Name,
+		RBDDevPath, ret := getRBDMappedDevPath(s.ClusterName, s.OSDPoolName,
+			storagePoolVolumeTypeNameCustom, s.volume.Name, true,
+			s.UserName)
+		if ret < 0 {
+			return fmt.Errorf("Failed to get mapped RBD path")
+		}
+
+		keyUUID, err = storageDrivers.LUKSUUID(RBDDevPath)
+		if err != nil {
+			return err
+		}
+
+		ret = cephContainerDelete(s.ClusterName, s.OSDPoolName, s.volume.Name,
 			storagePoolVolumeTypeNameCustom, s.UserName)
 		if ret < 0 {
 			msg := fmt.Sprintf(`Failed to delete RBD storage volume "%s" on storage pool "%s"`, s.volume.Name, s.pool.Name)
@@ -460,6 +491,13 @@ func (s *storageCeph) StoragePoolVolumeDelete() error {
 		logger.Debugf(`Deleted RBD storage volume "%s" on storage pool "%s"`, s.volume.Name, s.pool.Name)
 	}
 
+	if keyUUID != "" {
+		err = storageDrivers.ReleaseEncryptionKey(keyUUID, cephRBDEncryptionKeyRef(s.ClusterName, s.OSDPoolName, s.volume.Name))
+		if err != nil {
+			return err
+		}
+	}
+
 	err = s.s.Cluster.StoragePoolVolumeDelete(
 		"default",
 		s.volume.Name,
@@ -522,13 +560,19 @@ func (s *storageCeph) StoragePoolVolumeMount() (bool, error) {
 		RBDDevPath, ret = getRBDMappedDevPath(s.ClusterName, s.OSDPoolName,
 			storagePoolVolumeTypeNameCustom, s.volume.Name, true,
 			s.UserName)
-		mountFlags, mountOptions := driver.LXDResolveMountoptions(s.getRBDMountOptions())
-		customerr = driver.TryMount(
-			RBDDevPath,
-			volumeMntPoint,
-			RBDFilesystem,
-			mountFlags,
-			mountOptions)
+		if ret >= 0 {
+			RBDDevPath, customerr = storageBlockVolumeOpen(RBDDevPath, storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, s.volume.Name))
+		}
+
+		if ret >= 0 && customerr == nil {
+			mountFlags, mountOptions := driver.LXDResolveMountoptions(s.getRBDMountOptions())
+			customerr = driver.TryMount(
+				RBDDevPath,
+				volumeMntPoint,
+				RBDFilesystem,
+				mountFlags,
+				mountOptions)
+		}
 		ourMount = true
 	}
 
@@ -591,8 +635,14 @@ func (s *storageCeph) StoragePoolVolumeUmount() (bool, error) {
 	}
 
 	if ourUmount {
+		// Lock the volume again if it's encrypted
+		_, err := storageDrivers.LUKSClose(storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, s.volume.Name))
+		if err != nil {
+			return ourUmount, err
+		}
+
 		// Attempt to unmap
-		err := cephRBDVolumeUnmap(s.ClusterName, s.OSDPoolName,
+		err = cephRBDVolumeUnmap(s.ClusterName, s.OSDPoolName,
 			s.volume.Name, storagePoolVolumeTypeNameCustom,
 			s.UserName, true)
 		if err != nil {
@@ -709,7 +759,7 @@ func (s *storageCeph) StoragePoolVolumeRename(newName string) error {
 		s.volume.Name, s.pool.Name)
 
 	// map
-	_, err = cephRBDVolumeMap(s.ClusterName, s.OSDPoolName,
+	RBDDevPath, err := cephRBDVolumeMap(s.ClusterName, s.OSDPoolName,
 		newName, storagePoolVolumeTypeNameCustom,
 		s.UserName)
 	if err != nil {
@@ -720,6 +770,18 @@ func (s *storageCeph) StoragePoolVolumeRename(newName string) error {
 	logger.Debugf(`Mapped RBD storage volume for container "%s" on storage pool "%s"`,
 		newName, s.pool.Name)
 
+	keyUUID, err := storageDrivers.LUKSUUID(RBDDevPath)
+	if err != nil {
+		return err
+	}
+
+	if keyUUID != "" {
+		err = storageDrivers.RenameEncryptionKeyRef(keyUUID, cephRBDEncryptionKeyRef(s.ClusterName, s.OSDPoolName, s.volume.Name), cephRBDEncryptionKeyRef(s.ClusterName, s.OSDPoolName, newName))
+		if err != nil {
+			return err
+		}
+	}
+
 	isSnapshot := shared.IsSnapshot(s.volume.Name)
 
 	var oldPath string
@@ -2430,6 +2492,10 @@ func (s *storageCeph) StorageEntitySetQuota(volumeType int, size int64, data int
 	}
 
 	if size < oldSize {
+		if storageDrivers.LUKSIsEncrypted(RBDDevPath) {
+			return fmt.Errorf("Encrypted storage volumes cannot be shrunk")
+		}
+
 		err = s.rbdShrink(RBDDevPath, size, fsType, mountpoint,
 			volumeType, volumeName, data)
 	} else if size > oldSize {
@@ -2443,6 +2509,7 @@ func (s *storageCeph) StorageEntitySetQuota(volumeType int, size int64, data int
 	// Update the database
 	s.volume.Config["size"] = units.GetByteSizeString(size, 0)
 	err = s.s.Cluster.StoragePoolVolumeUpdate(
+		"default",
 		s.volume.Name,
 		volumeType,
 		s.poolID,
@@ -2674,7 +2741,7 @@ func (s *storageCeph) StoragePoolVolumeCopy(source *api.StorageVolumeSource) err
 		}
 		logger.Debugf(`Copied RBD custom storage %s to %s`, sourceVolumeName, targetVolumeName)
 
-		_, err = cephRBDVolumeMap(s.ClusterName, s.OSDPoolName,
+		RBDDevPath, err := cephRBDVolumeMap(s.ClusterName, s.OSDPoolName,
 			s.volume.Name, storagePoolVolumeTypeNameCustom,
 			s.UserName)
 		if err != nil {
@@ -2683,6 +2750,19 @@ func (s *storageCeph) StoragePoolVolumeCopy(source *api.StorageVolumeSource) err
 		}
 		logger.Debugf(`Mapped RBD storage volume for custom volume "%s" on storage pool "%s"`, s.volume.Name, s.pool.Name)
 
+		// A copy of an encrypted volume shares its key
+		keyUUID, err := storageDrivers.LUKSUUID(RBDDevPath)
+		if err != nil {
+			return err
+		}
+
+		if keyUUID != "" {
+			err = storageDrivers.AcquireEncryptionKey(keyUUID, cephRBDEncryptionKeyRef(s.ClusterName, s.OSDPoolName, s.volume.Name))
+			if err != nil {
+				return err
+			}
+		}
+
 		logger.Debugf(`Created non-sparse copy of RBD storage volume for custom volume "%s" to "%s" including snapshots`,
 			source.Name, s.volume.Name)
 	}
@@ -20,6 +20,7 @@ import (
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/rsync"
 	driver "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
@@ -1571,6 +1572,17 @@ func (s *storageCeph) rbdGrow(path string, size int64, fsType string,
 		if ourMount {
 			defer s.StoragePoolVolumeUmount()
 		}
+
+		// The filesystem of an encrypted volume is on its unlocked device
+		if storageDrivers.LUKSIsEncrypted(path) {
+			devName := storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, s.volume.Name)
+			err = storageDrivers.LUKSResize(path, devName)
+			if err != nil {
+				return err
+			}
+
+			path = storageDrivers.LUKSDevicePath(devName)
+		}
 	}
 
 	// Grow the filesystem
@@ -1926,7 +1938,7 @@ func (s *storageCeph) doCrossPoolVolumeCopy(source *api.StorageVolumeSource) err
 		defer srcStorage.StoragePoolUmount()
 	}
 
-	snapshots, err := driver.VolumeSnapshotsGet(s.s, source.Pool, source.Name, storagePoolVolumeTypeCustom)
+	snapshots, err := driver.VolumeSnapshotsGet(s.s, "default", source.Pool, source.Name, storagePoolVolumeTypeCustom)
 	if err != nil {
 		return err
 	}
@@ -2077,6 +2089,26 @@ func (s *storageCeph) cephRBDGenerateUUID(volumeName string, volumeType string)
 	}
 	defer cephRBDVolumeUnmap(s.ClusterName, s.OSDPoolName, volumeName, volumeType, s.UserName, true)
 
+	// A copy of an encrypted custom volume shares its key
+	if volumeType == storagePoolVolumeTypeNameCustom && storageDrivers.LUKSIsEncrypted(RBDDevPath) {
+		keyUUID, err := storageDrivers.LUKSUUID(RBDDevPath)
+		if err != nil {
+			return err
+		}
+
+		err = storageDrivers.AcquireEncryptionKey(keyUUID, cephRBDEncryptionKeyRef(s.ClusterName, s.OSDPoolName, volumeName))
+		if err != nil {
+			return err
+		}
+
+		devName := storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, volumeName)
+		RBDDevPath, err = storageBlockVolumeOpen(RBDDevPath, devName)
+		if err != nil {
+			return err
+		}
+		defer storageDrivers.LUKSClose(devName)
+	}
+
 	// Update the UUID
 	msg, err := driver.FSGenerateNewUUID(s.getRBDFilesystem(), RBDDevPath)
 	if err != nil {
@@ -2085,3 +2117,9 @@ func (s *storageCeph) cephRBDGenerateUUID(volumeName string, volumeType string)
 
 	return nil
 }
+
+// cephRBDEncryptionKeyRef returns the reference recorded in the key store for an encrypted custom
+// volume.
+func cephRBDEncryptionKeyRef(clusterName string, poolName string, volumeName string) string {
+	return fmt.Sprintf("ceph:%s/%s/%s_%s", clusterName, poolName, storagePoolVolumeTypeNameCustom, volumeName)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/rsync"
 	driver "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/ioprogress"
@@ -513,7 +514,7 @@ func (s *storageLvm) StoragePoolVolumeCreate() error {
 		}
 	}
 
-	err = lvmCreateLv("default", poolName, thinPoolName, volumeLvmName, lvFsType, lvSize, volumeType, s.useThinpool)
+	err = lvmCreateBlankLv("default", poolName, thinPoolName, volumeLvmName, lvSize, volumeType, s.useThinpool)
 	if err != nil {
 		return fmt.Errorf("Error Creating LVM LV for new image: %v", err)
 	}
@@ -523,6 +524,12 @@ func (s *storageLvm) StoragePoolVolumeCreate() error {
 		}
 	}()
 
+	lvmVolumePath := getLvmDevPath("default", poolName, volumeType, volumeLvmName)
+	err = storageBlockVolumeMakeFS(lvmVolumePath, lvmVolumePath, storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, s.volume.Name), lvFsType, s.volume.Config)
+	if err != nil {
+		return err
+	}
+
 	customPoolVolumeMntPoint := driver.GetStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
 	err = os.MkdirAll(customPoolVolumeMntPoint, 0711)
 	if err != nil {
@@ -557,11 +564,17 @@ func (s *storageLvm) StoragePoolVolumeDelete() error {
 		storagePoolVolumeAPIEndpointCustom, volumeLvmName)
 	lvExists, _ := storageLVExists(customLvmDevPath)
 
+	keyUUID := ""
 	if lvExists {
 		_, err := s.StoragePoolVolumeUmount()
 		if err != nil {
 			return err
 		}
+
+		keyUUID, err = storageDrivers.LUKSUUID(customLvmDevPath)
+		if err != nil {
+			return err
+		}
 	}
 
 	volumeType, err := storagePoolVolumeTypeNameToAPIEndpoint(s.volume.Type)
@@ -576,6 +589,13 @@ func (s *storageLvm) StoragePoolVolumeDelete() error {
 		}
 	}
 
+	if keyUUID != "" {
+		err = storageDrivers.ReleaseEncryptionKey(keyUUID, customLvmDevPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	customPoolVolumeMntPoint := driver.GetStoragePoolVolumeMountPoint(s.pool.Name, s.volume.Name)
 	if shared.PathExists(customPoolVolumeMntPoint) {
 		err := os.RemoveAll(customPoolVolumeMntPoint)
@@ -628,8 +648,12 @@ func (s *storageLvm) StoragePoolVolumeMount() (bool, error) {
 	var customerr error
 	ourMount := false
 	if !shared.IsMountPoint(customPoolVolumeMntPoint) {
-		mountFlags, mountOptions := driver.LXDResolveMountoptions(s.getLvmMountOptions())
-		customerr = driver.TryMount(lvmVolumePath, customPoolVolumeMntPoint, lvFsType, mountFlags, mountOptions)
+		var fsDevPath string
+		fsDevPath, customerr = storageBlockVolumeOpen(lvmVolumePath, storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, s.volume.Name))
+		if customerr == nil {
+			mountFlags, mountOptions := driver.LXDResolveMountoptions(s.getLvmMountOptions())
+			customerr = driver.TryMount(fsDevPath, customPoolVolumeMntPoint, lvFsType, mountFlags, mountOptions)
+		}
 		ourMount = true
 	}
 
@@ -675,6 +699,11 @@ func (s *storageLvm) StoragePoolVolumeUmount() (bool, error) {
 		ourUmount = true
 	}
 
+	// Lock the volume again if it's encrypted.
+	if customerr == nil {
+		_, customerr = storageDrivers.LUKSClose(storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, s.volume.Name))
+	}
+
 	lxdStorageMapLock.Lock()
 	if waitChannel, ok := lxdStorageOngoingOperationMap[customUmountLockID]; ok {
 		close(waitChannel)
@@ -810,8 +839,14 @@ func (s *storageLvm) StoragePoolVolumeUpdate(writable *api.StorageVolumePut,
 
 		if s.useThinpool {
 			poolName := s.getOnDiskPoolName()
+			targetLvmDevPath := getLvmDevPath("default", poolName, storagePoolVolumeAPIEndpointCustom, targetLvmName)
+
+			oldUUID, err := storageDrivers.LUKSUUID(targetLvmDevPath)
+			if err != nil {
+				return err
+			}
 
-			err := removeLV("default", poolName,
+			err = removeLV("default", poolName,
 				storagePoolVolumeAPIEndpointCustom, targetLvmName)
 			if err != nil {
 				logger.Errorf("Failed to remove \"%s\": %s",
@@ -824,6 +859,11 @@ func (s *storageLvm) StoragePoolVolumeUpdate(writable *api.StorageVolumePut,
 			if err != nil {
 				return fmt.Errorf("Error creating snapshot LV: %v", err)
 			}
+
+			err = storageDrivers.UpdateEncryptionKeyRef(oldUUID, targetLvmDevPath)
+			if err != nil {
+				return err
+			}
 		} else {
 			poolName := s.getOnDiskPoolName()
 			sourceName := fmt.Sprintf("%s/%s", s.volume.Name, writable.Restore)
@@ -906,6 +946,11 @@ func (s *storageLvm) StoragePoolVolumeRename(newName string) error {
 			s.volume.Name, newName, err)
 	}
 
+	err = s.renameEncryptionKeyRef(sourceLVName, targetLVName)
+	if err != nil {
+		return err
+	}
+
 	sourceName, _, ok := shared.InstanceGetParentAndSnapshotName(s.volume.Name)
 	if !ok {
 		return fmt.Errorf("Not a snapshot name")
@@ -2111,6 +2156,10 @@ func (s *storageLvm) StorageEntitySetQuota(volumeType int, size int64, data inte
 	}
 
 	if size < oldSize {
+		if storageDrivers.LUKSIsEncrypted(lvDevPath) {
+			return fmt.Errorf("Encrypted storage volumes cannot be shrunk")
+		}
+
 		err = s.lvReduce(lvDevPath, size, fsType, mountpoint, volumeType, data)
 	} else if size > oldSize {
 		err = s.lvExtend(lvDevPath, size, fsType, mountpoint, volumeType, data)
@@ -2122,6 +2171,7 @@ func (s *storageLvm) StorageEntitySetQuota(volumeType int, size int64, data inte
 	// Update the database
 	s.volume.Config["size"] = units.GetByteSizeString(size, 0)
 	err = s.s.Cluster.StoragePoolVolumeUpdate(
+		"default",
 		s.volume.Name,
 		volumeType,
 		s.poolID,
@@ -2235,7 +2285,7 @@ func (s *storageLvm) StoragePoolVolumeCopy(source *api.StorageVolumeSource) erro
 		return nil
 	}
 
-	snapshots, err := driver.VolumeSnapshotsGet(s.s, source.Pool, source.Name, storagePoolVolumeTypeCustom)
+	snapshots, err := driver.VolumeSnapshotsGet(s.s, "default", source.Pool, source.Name, storagePoolVolumeTypeCustom)
 	if err != nil {
 		return err
 	}
@@ -2280,6 +2330,13 @@ func (s *storageLvm) StoragePoolVolumeSnapshotCreate(target *api.StorageVolumeSn
 		return fmt.Errorf("Failed to create snapshot logical volume %s", err)
 	}
 
+	// The snapshot of an encrypted volume shares its key.
+	err = storageDrivers.UpdateEncryptionKeyRef("", getLvmDevPath("default", poolName, storagePoolVolumeAPIEndpointCustom, targetLvmName))
+	if err != nil {
+		removeLV("default", poolName, storagePoolVolumeAPIEndpointCustom, targetLvmName)
+		return err
+	}
+
 	targetPath := driver.GetStoragePoolVolumeSnapshotMountPoint(s.pool.Name, target.Name)
 	err = os.MkdirAll(targetPath, driver.SnapshotsDirMode)
 	if err != nil {
@@ -2307,10 +2364,22 @@ func (s *storageLvm) StoragePoolVolumeSnapshotDelete() error {
 	snapshotLVDevPath := getLvmDevPath("default", poolName, storagePoolVolumeAPIEndpointCustom, snapshotLVName)
 	lvExists, _ := storageLVExists(snapshotLVDevPath)
 	if lvExists {
-		err := removeLV("default", poolName, storagePoolVolumeAPIEndpointCustom, snapshotLVName)
+		keyUUID, err := storageDrivers.LUKSUUID(snapshotLVDevPath)
 		if err != nil {
 			return err
 		}
+
+		err = removeLV("default", poolName, storagePoolVolumeAPIEndpointCustom, snapshotLVName)
+		if err != nil {
+			return err
+		}
+
+		if keyUUID != "" {
+			err = storageDrivers.ReleaseEncryptionKey(keyUUID, snapshotLVDevPath)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	err := os.Remove(storageVolumeSnapshotPath)
@@ -2362,6 +2431,11 @@ func (s *storageLvm) StoragePoolVolumeSnapshotRename(newName string) error {
 		return fmt.Errorf("Failed to rename logical volume from \"%s\" to \"%s\": %s", s.volume.Name, fullSnapshotName, err)
 	}
 
+	err = s.renameEncryptionKeyRef(sourceLVName, targetLVName)
+	if err != nil {
+		return err
+	}
+
 	oldPath := driver.GetStoragePoolVolumeSnapshotMountPoint(s.pool.Name, s.volume.Name)
 	newPath := driver.GetStoragePoolVolumeSnapshotMountPoint(s.pool.Name, fullSnapshotName)
 	err = os.Rename(oldPath, newPath)
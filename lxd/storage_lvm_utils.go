@@ -17,6 +17,7 @@ import (
 	"github.com/lxc/lxd/lxd/rsync"
 	"github.com/lxc/lxd/lxd/state"
 	driver "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
@@ -57,6 +58,17 @@ func (s *storageLvm) lvExtend(lvPath string, lvSize int64, fsType string, fsMntP
 		if ourMount {
 			defer s.StoragePoolVolumeUmount()
 		}
+
+		// The filesystem of an encrypted volume is on its unlocked device.
+		if storageDrivers.LUKSIsEncrypted(lvPath) {
+			devName := storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, s.volume.Name)
+			err = storageDrivers.LUKSResize(lvPath, devName)
+			if err != nil {
+				return err
+			}
+
+			return driver.GrowFileSystem(fsType, storageDrivers.LUKSDevicePath(devName), fsMntPoint)
+		}
 	default:
 		return fmt.Errorf(`Resizing not implemented for storage `+
 			`volume type %d`, volumeType)
@@ -178,6 +190,21 @@ func (s *storageLvm) renameLVByPath(project, oldName string, newName string, vol
 	return lvmLVRename(poolName, oldLvmName, newLvmName)
 }
 
+// renameEncryptionKeyRef records the new path of a renamed custom volume LV in the key store if
+// it's encrypted.
+func (s *storageLvm) renameEncryptionKeyRef(oldName string, newName string) error {
+	poolName := s.getOnDiskPoolName()
+	oldPath := getLvmDevPath("default", poolName, storagePoolVolumeAPIEndpointCustom, oldName)
+	newPath := getLvmDevPath("default", poolName, storagePoolVolumeAPIEndpointCustom, newName)
+
+	keyUUID, err := storageDrivers.LUKSUUID(newPath)
+	if err != nil || keyUUID == "" {
+		return err
+	}
+
+	return storageDrivers.RenameEncryptionKeyRef(keyUUID, oldPath, newPath)
+}
+
 func removeLV(project, vgName string, volumeType string, lvName string) error {
 	lvmVolumePath := getLvmDevPath(project, vgName, volumeType, lvName)
 
@@ -830,9 +857,24 @@ func getPrefixedLvName(projectName, volumeType string, lvmVolume string) string
 }
 
 func lvmCreateLv(projectName, vgName string, thinPoolName string, lvName string, lvFsType string, lvSize string, volumeType string, makeThinLv bool) error {
-	var output string
-	var err error
+	err := lvmCreateBlankLv(projectName, vgName, thinPoolName, lvName, lvSize, volumeType, makeThinLv)
+	if err != nil {
+		return err
+	}
 
+	fsPath := getLvmDevPath(projectName, vgName, volumeType, lvName)
+
+	output, err := driver.MakeFSType(fsPath, lvFsType, nil)
+	if err != nil {
+		logger.Errorf("Filesystem creation failed: %v (%s)", err, output)
+		return fmt.Errorf("Error making filesystem on image LV: %v (%s)", err, output)
+	}
+
+	return nil
+}
+
+// lvmCreateBlankLv creates an LV without a filesystem.
+func lvmCreateBlankLv(projectName, vgName string, thinPoolName string, lvName string, lvSize string, volumeType string, makeThinLv bool) error {
 	// Round the size to closest 512 bytes
 	lvSizeInt, err := units.ParseByteSizeString(lvSize)
 	if err != nil {
@@ -854,14 +896,6 @@ func lvmCreateLv(projectName, vgName string, thinPoolName string, lvName string,
 		return fmt.Errorf("Could not create thin LV named %s: %v", lvmPoolVolumeName, err)
 	}
 
-	fsPath := getLvmDevPath(projectName, vgName, volumeType, lvName)
-
-	output, err = driver.MakeFSType(fsPath, lvFsType, nil)
-	if err != nil {
-		logger.Errorf("Filesystem creation failed: %v (%s)", err, output)
-		return fmt.Errorf("Error making filesystem on image LV: %v (%s)", err, output)
-	}
-
 	return nil
 }
 
@@ -1080,7 +1114,20 @@ func (s *storageLvm) copyVolumeThinpool(source string, target string, readOnly b
 
 	lvDevPath := getLvmDevPath("default", poolName, storagePoolVolumeAPIEndpointCustom, targetLvmName)
 
-	msg, err := driver.FSGenerateNewUUID(lvFsType, lvDevPath)
+	// A copy of an encrypted volume shares its key.
+	err = storageDrivers.UpdateEncryptionKeyRef("", lvDevPath)
+	if err != nil {
+		return err
+	}
+
+	devName := storageDrivers.LUKSDeviceName(s.pool.Name, storageDrivers.VolumeTypeCustom, target)
+	fsDevPath, err := storageBlockVolumeOpen(lvDevPath, devName)
+	if err != nil {
+		return err
+	}
+	defer storageDrivers.LUKSClose(devName)
+
+	msg, err := driver.FSGenerateNewUUID(lvFsType, fsDevPath)
 	if err != nil {
 		logger.Errorf("Failed to create new UUID for filesystem \"%s\" for RBD storage volume \"%s\" on storage pool \"%s\": %s: %s", lvFsType, s.volume.Name, s.pool.Name, msg, err)
 		return err
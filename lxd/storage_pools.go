@@ -13,6 +13,9 @@ import (
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
@@ -38,6 +41,7 @@ var storagePoolCmd = APIEndpoint{
 	Delete: APIEndpointAction{Handler: storagePoolDelete},
 	Get:    APIEndpointAction{Handler: storagePoolGet, AccessHandler: AllowAuthenticated},
 	Patch:  APIEndpointAction{Handler: storagePoolPatch},
+	Post:   APIEndpointAction{Handler: storagePoolPost},
 	Put:    APIEndpointAction{Handler: storagePoolPut},
 }
 
@@ -491,6 +495,140 @@ func storagePoolPatch(d *Daemon, r *http.Request) response.Response {
 	return response.EmptySyncResponse
 }
 
+// /1.0/storage-pools/{name}
+// Move all instance and custom volumes of the pool onto another pool.
+func storagePoolPost(d *Daemon, r *http.Request) response.Response {
+	poolName := mux.Vars(r)["name"]
+
+	req := api.StoragePoolPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Pool == "" {
+		return response.BadRequest(fmt.Errorf("No target storage pool provided"))
+	}
+
+	if req.Pool == poolName {
+		return response.BadRequest(fmt.Errorf("Source and target storage pools must be different"))
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if clustered {
+		return response.BadRequest(fmt.Errorf("Moving storage pool volumes isn't supported in clusters"))
+	}
+
+	srcPool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err == storageDrivers.ErrUnknownDriver {
+		return response.BadRequest(fmt.Errorf("Storage pool %q doesn't support moving its volumes", poolName))
+	} else if err != nil {
+		return response.SmartError(err)
+	}
+
+	pool, err := storagePools.GetPoolByName(d.State(), req.Pool)
+	if err == storageDrivers.ErrUnknownDriver {
+		return response.BadRequest(fmt.Errorf("Storage pool %q doesn't support moving volumes onto it", req.Pool))
+	} else if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Find the instances using the pool, they all need to be stopped.
+	allInstances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	instances := []instance.Instance{}
+	for _, inst := range allInstances {
+		instPoolName, err := d.cluster.InstancePool(inst.Project(), inst.Name())
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if instPoolName != poolName {
+			continue
+		}
+
+		if inst.IsRunning() {
+			return response.BadRequest(fmt.Errorf("Instance %q in project %q must be stopped", inst.Name(), inst.Project()))
+		}
+
+		instances = append(instances, inst)
+	}
+
+	// Find the custom volumes on the pool, they may belong to any project and
+	// mustn't be used by running instances, wherever their root disks are.
+	var projectNames []string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		projectNames, err = tx.ProjectNames()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	customVolumes := map[string][]string{}
+	for _, projectName := range projectNames {
+		volumes, err := d.cluster.StoragePoolNodeVolumesGet(projectName, srcPool.ID(), []int{db.StoragePoolVolumeTypeCustom})
+		if err != nil && err != db.ErrNoSuchObject {
+			return response.SmartError(err)
+		}
+
+		for _, volume := range volumes {
+			if shared.IsSnapshot(volume.Name) {
+				continue
+			}
+
+			instNames, err := storagePoolVolumeUsedByRunningInstancesWithProfilesGet(d.State(), projectName, poolName, volume.Name, storagePoolVolumeTypeNameCustom, true)
+			if err != nil {
+				return response.SmartError(err)
+			}
+
+			if len(instNames) > 0 {
+				return response.BadRequest(fmt.Errorf("Custom volume %q in project %q is used by running instance %q", volume.Name, projectName, instNames[0]))
+			}
+
+			customVolumes[projectName] = append(customVolumes[projectName], volume.Name)
+		}
+	}
+
+	run := func(op *operations.Operation) error {
+		for _, inst := range instances {
+			err := pool.MoveInstanceFromPool(inst, srcPool, op)
+			if err != nil {
+				return fmt.Errorf("Failed to move instance %q in project %q: %v", inst.Name(), inst.Project(), err)
+			}
+		}
+
+		for projectName, volNames := range customVolumes {
+			for _, volName := range volNames {
+				err := pool.MoveCustomVolumeFromPool(projectName, volName, srcPool, op)
+				if err != nil {
+					return fmt.Errorf("Failed to move custom volume %q in project %q: %v", volName, projectName, err)
+				}
+			}
+		}
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_pools"] = []string{poolName, req.Pool}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationStoragePoolVolumesMove, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
 // This helper makes sure that, when clustered, we're not changing
 // node-specific values.
 //
@@ -673,14 +811,27 @@ func storagePoolDeleteCheckPreconditions(cluster *db.Cluster, poolName string, p
 	}
 
 	if len(volumeNames) > 0 {
-		volumes, err := cluster.StoragePoolVolumesGet("default", poolID, supportedVolumeTypes)
+		var projectNames []string
+		err := cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			projectNames, err = tx.ProjectNames()
+			return err
+		})
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 
-		for _, volume := range volumes {
-			if volume.Type != "image" {
-				return response.BadRequest(fmt.Errorf("storage pool \"%s\" has volumes attached to it", poolName))
+		// Volumes may belong to any project, so check them all.
+		for _, projectName := range projectNames {
+			volumes, err := cluster.StoragePoolVolumesGet(projectName, poolID, supportedVolumeTypes)
+			if err != nil {
+				return response.InternalError(err)
+			}
+
+			for _, volume := range volumes {
+				if volume.Type != "image" {
+					return response.BadRequest(fmt.Errorf("storage pool \"%s\" has volumes attached to it", poolName))
+				}
 			}
 		}
 	}
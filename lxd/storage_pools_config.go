@@ -19,28 +19,47 @@ func updateStoragePoolError(unchangeable []string, driverName string) error {
 var changeableStoragePoolProperties = map[string][]string{
 	"btrfs": {
 		"rsync.bwlimit",
-		"btrfs.mount_options"},
+		"btrfs.mount_options",
+		"volume.snapshots.expiry",
+		"volume.snapshots.pattern",
+		"volume.snapshots.schedule"},
 
 	"ceph": {
 		"volume.block.filesystem",
 		"volume.block.mount_options",
-		"volume.size"},
+		"volume.size",
+		"volume.snapshots.expiry",
+		"volume.snapshots.pattern",
+		"volume.snapshots.schedule"},
 
 	"cephfs": {
-		"rsync.bwlimit"},
+		"rsync.bwlimit",
+		"volume.snapshots.expiry",
+		"volume.snapshots.pattern",
+		"volume.snapshots.schedule"},
 
 	"dir": {
-		"rsync.bwlimit"},
+		"rsync.bwlimit",
+		"volume.block.encryption",
+		"volume.snapshots.expiry",
+		"volume.snapshots.pattern",
+		"volume.snapshots.schedule"},
 
 	"lvm": {
 		"lvm.thinpool_name",
 		"lvm.vg_name",
 		"volume.block.filesystem",
 		"volume.block.mount_options",
-		"volume.size"},
+		"volume.size",
+		"volume.snapshots.expiry",
+		"volume.snapshots.pattern",
+		"volume.snapshots.schedule"},
 
 	"zfs": {
 		"rsync_bwlimit",
+		"volume.snapshots.expiry",
+		"volume.snapshots.pattern",
+		"volume.snapshots.schedule",
 		"volume.zfs.remove_snapshots",
 		"volume.zfs.use_refquota",
 		"zfs.clone_copy"},
@@ -107,6 +126,11 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	},
 	"volume.block.mount_options": shared.IsAny,
 
+	// valid drivers: dir
+	"volume.block.encryption": func(value string) error {
+		return shared.IsOneOf(value, []string{"luks2"})
+	},
+
 	// valid drivers: ceph, lvm
 	"volume.size": func(value string) error {
 		if value == "" {
@@ -117,6 +141,11 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 		return err
 	},
 
+	// valid drivers: btrfs, ceph, cephfs, dir, lvm, zfs
+	"volume.snapshots.expiry":   shared.KnownInstanceConfigKeys["snapshots.expiry"],
+	"volume.snapshots.pattern":  shared.IsAny,
+	"volume.snapshots.schedule": shared.KnownInstanceConfigKeys["snapshots.schedule"],
+
 	// valid drivers: zfs
 	"volume.zfs.remove_snapshots": shared.IsBool,
 	"volume.zfs.use_refquota":     shared.IsBool,
@@ -176,8 +205,12 @@ func storagePoolValidateConfig(name string, driver string, config map[string]str
 			}
 		}
 
+		if !shared.StringInSlice(driver, []string{"ceph", "dir", "lvm"}) && key == "volume.block.encryption" {
+			return fmt.Errorf("the key %s cannot be used with %s storage pools", key, strings.ToUpper(driver))
+		}
+
 		if driver != "lvm" && driver != "ceph" {
-			if prfx(key, "volume.block.") || key == "volume.size" {
+			if (prfx(key, "volume.block.") && key != "volume.block.encryption") || key == "volume.size" {
 				return fmt.Errorf("the key %s cannot be used with %s storage pools", key, strings.ToUpper(driver))
 			}
 		}
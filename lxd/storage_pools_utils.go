@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/lxc/lxd/lxd/db"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
@@ -87,11 +88,24 @@ func storagePoolUpdate(state *state.State, name, newDescription string, newConfi
 // /1.0/profiles/default
 func storagePoolUsedByGet(state *state.State, project string, poolID int64, poolName string) ([]string, error) {
 	// Retrieve all non-custom volumes that exist on this storage pool.
-	volumes, err := state.Cluster.StoragePoolNodeVolumesGet(project, poolID, []int{storagePoolVolumeTypeContainer, storagePoolVolumeTypeImage, storagePoolVolumeTypeCustom, storagePoolVolumeTypeVM})
+	volumes, err := state.Cluster.StoragePoolNodeVolumesGet(project, poolID, []int{storagePoolVolumeTypeContainer, storagePoolVolumeTypeImage, storagePoolVolumeTypeVM})
 	if err != nil && err != db.ErrNoSuchObject {
 		return []string{}, err
 	}
 
+	// Retrieve all custom volumes from the project holding them.
+	customVolumeProject, err := projecthelpers.StorageVolumeProject(state.Cluster, project, storagePoolVolumeTypeCustom)
+	if err != nil {
+		return []string{}, err
+	}
+
+	customVolumes, err := state.Cluster.StoragePoolNodeVolumesGet(customVolumeProject, poolID, []int{storagePoolVolumeTypeCustom})
+	if err != nil && err != db.ErrNoSuchObject {
+		return []string{}, err
+	}
+
+	volumes = append(volumes, customVolumes...)
+
 	// Retrieve all profiles that exist on this storage pool.
 	profiles, err := profilesUsingPoolGetNames(state.Cluster, project, poolName)
 
@@ -127,6 +141,9 @@ func storagePoolUsedByGet(state *state.State, project string, poolID int64, pool
 			poolUsedBy[i] = fmt.Sprintf("/%s/images/%s", version.APIVersion, volumes[i].Name)
 		case storagePoolVolumeAPIEndpointCustom:
 			poolUsedBy[i] = fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s", version.APIVersion, poolName, volumes[i].Type, volumes[i].Name)
+			if customVolumeProject != "default" {
+				poolUsedBy[i] += fmt.Sprintf("?project=%s", customVolumeProject)
+			}
 		default:
 			// If that happens the db is busted, so report an error.
 			return []string{}, fmt.Errorf("invalid storage type for storage volume \"%s\"", volumes[i].Name)
@@ -3,11 +3,76 @@ package main
 import (
 	"fmt"
 
+	"github.com/pborman/uuid"
+
 	"github.com/lxc/lxd/lxd/instance"
 	driver "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/shared/logger"
 )
 
+// storageBlockVolumeMakeFS creates the filesystem of a new custom volume on the block device at
+// devPath. If the volume has block.encryption set, the device is first formatted with LUKS2 under
+// a new key from the server-side key store, recorded as used by ref, and the filesystem is created
+// on the device unlocked as devName.
+func storageBlockVolumeMakeFS(devPath string, ref string, devName string, fsType string, config map[string]string) error {
+	if config["block.encryption"] == "" {
+		output, err := driver.MakeFSType(devPath, fsType, nil)
+		if err != nil {
+			return fmt.Errorf("Error making filesystem on %s: %v (%s)", devPath, err, output)
+		}
+
+		return nil
+	}
+
+	luksUUID := uuid.NewRandom().String()
+	err := storageDrivers.CreateEncryptionKey(luksUUID, ref)
+	if err != nil {
+		return err
+	}
+
+	revert := true
+	defer func() {
+		if revert {
+			storageDrivers.ReleaseEncryptionKey(luksUUID, ref)
+		}
+	}()
+
+	err = storageDrivers.LUKSFormat(devPath, luksUUID)
+	if err != nil {
+		return err
+	}
+
+	_, err = storageDrivers.LUKSOpen(devPath, devName)
+	if err != nil {
+		return err
+	}
+	defer storageDrivers.LUKSClose(devName)
+
+	output, err := driver.MakeFSType(storageDrivers.LUKSDevicePath(devName), fsType, nil)
+	if err != nil {
+		return fmt.Errorf("Error making filesystem on %s: %v (%s)", devPath, err, output)
+	}
+
+	revert = false
+	return nil
+}
+
+// storageBlockVolumeOpen unlocks the block device at devPath as devName if it's encrypted, and
+// returns the path of the device holding the volume's filesystem.
+func storageBlockVolumeOpen(devPath string, devName string) (string, error) {
+	if !storageDrivers.LUKSIsEncrypted(devPath) {
+		return devPath, nil
+	}
+
+	_, err := storageDrivers.LUKSOpen(devPath, devName)
+	if err != nil {
+		return "", err
+	}
+
+	return storageDrivers.LUKSDevicePath(devName), nil
+}
+
 func shrinkVolumeFilesystem(s storage, volumeType int, fsType string, devPath string, mntpoint string, byteSize int64, data interface{}) (func() (bool, error), error) {
 	var cleanupFunc func() (bool, error)
 	switch fsType {
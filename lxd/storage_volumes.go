@@ -6,13 +6,19 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/operations"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
@@ -28,14 +34,14 @@ import (
 var storagePoolVolumesCmd = APIEndpoint{
 	Path: "storage-pools/{name}/volumes",
 
-	Get:  APIEndpointAction{Handler: storagePoolVolumesGet, AccessHandler: AllowAuthenticated},
+	Get:  APIEndpointAction{Handler: storagePoolVolumesGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
 	Post: APIEndpointAction{Handler: storagePoolVolumesPost},
 }
 
 var storagePoolVolumesTypeCmd = APIEndpoint{
 	Path: "storage-pools/{name}/volumes/{type}",
 
-	Get:  APIEndpointAction{Handler: storagePoolVolumesTypeGet, AccessHandler: AllowAuthenticated},
+	Get:  APIEndpointAction{Handler: storagePoolVolumesTypeGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
 	Post: APIEndpointAction{Handler: storagePoolVolumesTypePost},
 }
 
@@ -43,7 +49,7 @@ var storagePoolVolumeTypeContainerCmd = APIEndpoint{
 	Path: "storage-pools/{pool}/volumes/container/{name:.*}",
 
 	Delete: APIEndpointAction{Handler: storagePoolVolumeTypeContainerDelete},
-	Get:    APIEndpointAction{Handler: storagePoolVolumeTypeContainerGet, AccessHandler: AllowAuthenticated},
+	Get:    APIEndpointAction{Handler: storagePoolVolumeTypeContainerGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
 	Patch:  APIEndpointAction{Handler: storagePoolVolumeTypeContainerPatch},
 	Post:   APIEndpointAction{Handler: storagePoolVolumeTypeContainerPost},
 	Put:    APIEndpointAction{Handler: storagePoolVolumeTypeContainerPut},
@@ -53,7 +59,7 @@ var storagePoolVolumeTypeVMCmd = APIEndpoint{
 	Path: "storage-pools/{pool}/volumes/virtual-machine/{name:.*}",
 
 	Delete: APIEndpointAction{Handler: storagePoolVolumeTypeVMDelete},
-	Get:    APIEndpointAction{Handler: storagePoolVolumeTypeVMGet, AccessHandler: AllowAuthenticated},
+	Get:    APIEndpointAction{Handler: storagePoolVolumeTypeVMGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
 	Patch:  APIEndpointAction{Handler: storagePoolVolumeTypeVMPatch},
 	Post:   APIEndpointAction{Handler: storagePoolVolumeTypeVMPost},
 	Put:    APIEndpointAction{Handler: storagePoolVolumeTypeVMPut},
@@ -63,7 +69,7 @@ var storagePoolVolumeTypeCustomCmd = APIEndpoint{
 	Path: "storage-pools/{pool}/volumes/custom/{name}",
 
 	Delete: APIEndpointAction{Handler: storagePoolVolumeTypeCustomDelete},
-	Get:    APIEndpointAction{Handler: storagePoolVolumeTypeCustomGet, AccessHandler: AllowAuthenticated},
+	Get:    APIEndpointAction{Handler: storagePoolVolumeTypeCustomGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
 	Patch:  APIEndpointAction{Handler: storagePoolVolumeTypeCustomPatch},
 	Post:   APIEndpointAction{Handler: storagePoolVolumeTypeCustomPost},
 	Put:    APIEndpointAction{Handler: storagePoolVolumeTypeCustomPut},
@@ -73,7 +79,7 @@ var storagePoolVolumeTypeImageCmd = APIEndpoint{
 	Path: "storage-pools/{pool}/volumes/image/{name}",
 
 	Delete: APIEndpointAction{Handler: storagePoolVolumeTypeImageDelete},
-	Get:    APIEndpointAction{Handler: storagePoolVolumeTypeImageGet, AccessHandler: AllowAuthenticated},
+	Get:    APIEndpointAction{Handler: storagePoolVolumeTypeImageGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
 	Patch:  APIEndpointAction{Handler: storagePoolVolumeTypeImagePatch},
 	Post:   APIEndpointAction{Handler: storagePoolVolumeTypeImagePost},
 	Put:    APIEndpointAction{Handler: storagePoolVolumeTypeImagePut},
@@ -94,6 +100,12 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Get the project holding the custom volumes of the requested project.
+	customProject, err := projecthelpers.StorageVolumeProject(d.cluster, project, storagePoolVolumeTypeCustom)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get all volumes currently attached to the storage pool by ID of the
 	// pool and project.
 	//
@@ -103,11 +115,18 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 	// project. This means that we want to filter image volumes and return
 	// only the ones that have fingerprints matching images actually in use
 	// by the project.
-	volumes, err := d.cluster.StoragePoolVolumesGet(project, poolID, supportedVolumeTypesExceptImages)
+	volumes, err := d.cluster.StoragePoolVolumesGet(project, poolID, []int{storagePoolVolumeTypeContainer, storagePoolVolumeTypeVM})
 	if err != nil && err != db.ErrNoSuchObject {
 		return response.SmartError(err)
 	}
 
+	customVolumes, err := d.cluster.StoragePoolVolumesGet(customProject, poolID, []int{storagePoolVolumeTypeCustom})
+	if err != nil && err != db.ErrNoSuchObject {
+		return response.SmartError(err)
+	}
+
+	volumes = append(volumes, customVolumes...)
+
 	imageVolumes, err := d.cluster.StoragePoolVolumesGet("default", poolID, []int{storagePoolVolumeTypeImage})
 	if err != nil && err != db.ErrNoSuchObject {
 		return response.SmartError(err)
@@ -150,7 +169,12 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 						version.APIVersion, poolName, apiEndpoint, volume.Name))
 			}
 		} else {
-			volumeUsedBy, err := storagePoolVolumeUsedByGet(d.State(), project, poolName, volume.Name, volume.Type)
+			volumeProject := project
+			if volume.Type == storagePoolVolumeTypeNameCustom {
+				volumeProject = customProject
+			}
+
+			volumeUsedBy, err := storagePoolVolumeUsedByGet(d.State(), volumeProject, poolName, volume.Name, volume.Type)
 			if err != nil {
 				return response.InternalError(err)
 			}
@@ -196,9 +220,15 @@ func storagePoolVolumesTypeGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Get the project holding the volumes of the requested type.
+	project, err = projecthelpers.StorageVolumeProject(d.cluster, project, volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get the names of all storage volumes of a given volume type currently
 	// attached to the storage pool.
-	volumes, err := d.cluster.StoragePoolNodeVolumesGetType(volumeType, poolID)
+	volumes, err := d.cluster.StoragePoolNodeVolumesGetTypeByProject(project, volumeType, poolID)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -222,7 +252,7 @@ func storagePoolVolumesTypeGet(d *Daemon, r *http.Request) response.Response {
 
 			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s", version.APIVersion, poolName, apiEndpoint, volume))
 		} else {
-			_, vol, err := d.cluster.StoragePoolNodeVolumeGetType(volume, volumeType, poolID)
+			_, vol, err := d.cluster.StoragePoolNodeVolumeGetTypeByProject(project, volume, volumeType, poolID)
 			if err != nil {
 				continue
 			}
@@ -252,6 +282,24 @@ func storagePoolVolumesTypePost(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	// If we're getting binary content, process separately
+	if r.Header.Get("Content-Type") == "application/octet-stream" {
+		if mux.Vars(r)["type"] != storagePoolVolumeTypeNameCustom {
+			return response.BadRequest(fmt.Errorf("Only custom volumes can be imported from a backup"))
+		}
+
+		projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), storagePoolVolumeTypeCustom)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if r.Header.Get("X-LXD-type") == db.StoragePoolVolumeContentTypeNameBlock {
+			return createStoragePoolVolumeFromDiskImage(d, projectName, mux.Vars(r)["name"], r.Body, r.Header.Get("X-LXD-name"))
+		}
+
+		return createStoragePoolVolumeFromBackup(d, projectName, mux.Vars(r)["name"], r.Body, r.Header.Get("X-LXD-name"))
+	}
+
 	req := api.StorageVolumesPost{}
 
 	// Parse the request.
@@ -279,35 +327,49 @@ func storagePoolVolumesTypePost(d *Daemon, r *http.Request) response.Response {
 			`storage volumes of type %s`, req.Type))
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), storagePoolVolumeTypeCustom)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	poolName := mux.Vars(r)["name"]
 	poolID, err := d.cluster.StoragePoolGetID(poolName)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	if req.Source.Refresh && req.Source.Type != "migration" {
+		return response.BadRequest(fmt.Errorf("Refresh is only supported for volume migrations"))
+	}
+
 	// Check if destination volume exists.
-	_, _, err = d.cluster.StoragePoolNodeVolumeGetTypeByProject("default", req.Name, db.StoragePoolVolumeTypeCustom, poolID)
+	_, _, err = d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, req.Name, db.StoragePoolVolumeTypeCustom, poolID)
 	if err != db.ErrNoSuchObject {
 		if err != nil {
 			return response.SmartError(err)
 		}
 
-		return response.Conflict(fmt.Errorf("Volume by that name already exists"))
+		if !req.Source.Refresh {
+			return response.Conflict(fmt.Errorf("Volume by that name already exists"))
+		}
+	} else {
+		// Nothing to refresh, fall back to a full copy.
+		req.Source.Refresh = false
 	}
 
 	switch req.Source.Type {
 	case "":
-		return doVolumeCreateOrCopy(d, poolName, &req)
+		return doVolumeCreateOrCopy(d, projectName, poolName, &req)
 	case "copy":
-		return doVolumeCreateOrCopy(d, poolName, &req)
+		return doVolumeCreateOrCopy(d, projectName, poolName, &req)
 	case "migration":
-		return doVolumeMigration(d, poolName, &req)
+		return doVolumeMigration(d, projectName, poolName, &req)
 	default:
 		return response.BadRequest(fmt.Errorf("unknown source type %s", req.Source.Type))
 	}
 }
 
-func doVolumeCreateOrCopy(d *Daemon, poolName string, req *api.StorageVolumesPost) response.Response {
+func doVolumeCreateOrCopy(d *Daemon, projectName string, poolName string, req *api.StorageVolumesPost) response.Response {
 	var run func(op *operations.Operation) error
 
 	// Check if we can load new storage layer for both target and source pool driver types.
@@ -318,14 +380,31 @@ func doVolumeCreateOrCopy(d *Daemon, poolName string, req *api.StorageVolumesPos
 			return response.SmartError(err)
 		}
 
+		contentType := storageDrivers.ContentTypeFS
+		if req.ContentType != "" {
+			contentType, err = storagePools.VolumeContentTypeNameToContentType(req.ContentType)
+			if err != nil {
+				return response.BadRequest(err)
+			}
+		}
+
 		run = func(op *operations.Operation) error {
 			if req.Source.Name == "" {
-				return pool.CreateCustomVolume(req.Name, req.Description, req.Config, op)
+				return pool.CreateCustomVolume(projectName, req.Name, req.Description, req.Config, contentType, op)
 			}
 
-			return pool.CreateCustomVolumeFromCopy(req.Name, req.Description, req.Config, req.Source.Pool, req.Source.Name, req.Source.VolumeOnly, op)
+			return pool.CreateCustomVolumeFromCopy(projectName, req.Name, req.Description, req.Config, req.Source.Pool, req.Source.Name, req.Source.VolumeOnly, op)
 		}
 	} else {
+		err := storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if req.ContentType != "" && req.ContentType != db.StoragePoolVolumeContentTypeNameFS {
+			return response.BadRequest(fmt.Errorf("Storage pool driver doesn't support block custom volumes"))
+		}
+
 		run = func(op *operations.Operation) error {
 			return storagePoolVolumeCreateInternal(d.State(), poolName, req)
 		}
@@ -389,35 +468,113 @@ func storagePoolVolumesPost(d *Daemon, r *http.Request) response.Response {
 			`storage volumes of type %s`, req.Type))
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), storagePoolVolumeTypeCustom)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	poolName := mux.Vars(r)["name"]
 	poolID, err := d.cluster.StoragePoolGetID(poolName)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	if req.Source.Refresh && req.Source.Type != "migration" {
+		return response.BadRequest(fmt.Errorf("Refresh is only supported for volume migrations"))
+	}
+
 	// Check if destination volume exists.
-	_, _, err = d.cluster.StoragePoolNodeVolumeGetTypeByProject("default", req.Name, db.StoragePoolVolumeTypeCustom, poolID)
+	_, _, err = d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, req.Name, db.StoragePoolVolumeTypeCustom, poolID)
 	if err != db.ErrNoSuchObject {
 		if err != nil {
 			return response.SmartError(err)
 		}
 
-		return response.Conflict(fmt.Errorf("Volume by that name already exists"))
+		if !req.Source.Refresh {
+			return response.Conflict(fmt.Errorf("Volume by that name already exists"))
+		}
+	} else {
+		// Nothing to refresh, fall back to a full copy.
+		req.Source.Refresh = false
 	}
 
 	switch req.Source.Type {
 	case "":
-		return doVolumeCreateOrCopy(d, poolName, &req)
+		return doVolumeCreateOrCopy(d, projectName, poolName, &req)
 	case "copy":
-		return doVolumeCreateOrCopy(d, poolName, &req)
+		return doVolumeCreateOrCopy(d, projectName, poolName, &req)
 	case "migration":
-		return doVolumeMigration(d, poolName, &req)
+		return doVolumeMigration(d, projectName, poolName, &req)
 	default:
 		return response.BadRequest(fmt.Errorf("unknown source type %s", req.Source.Type))
 	}
 }
 
-func doVolumeMigration(d *Daemon, poolName string, req *api.StorageVolumesPost) response.Response {
+func createStoragePoolVolumeFromDiskImage(d *Daemon, projectName string, poolName string, data io.Reader, volumeName string) response.Response {
+	if volumeName == "" {
+		return response.BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	if strings.Contains(volumeName, "/") {
+		return response.BadRequest(fmt.Errorf("Storage volume names may not contain slashes"))
+	}
+
+	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err == storageDrivers.ErrUnknownDriver {
+		return response.BadRequest(fmt.Errorf("Storage pool driver doesn't support block custom volumes"))
+	} else if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Check if destination volume exists.
+	_, _, err = d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volumeName, db.StoragePoolVolumeTypeCustom, pool.ID())
+	if err != db.ErrNoSuchObject {
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.Conflict(fmt.Errorf("Volume by that name already exists"))
+	}
+
+	// Create temporary file to store uploaded disk image.
+	imageFile, err := ioutil.TempFile(shared.VarPath("storage-pools"), "lxd_disk_image_")
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	// Stream uploaded disk image into temporary file.
+	_, err = io.Copy(imageFile, data)
+	imageFile.Close()
+	if err != nil {
+		os.Remove(imageFile.Name())
+		return response.InternalError(err)
+	}
+
+	run := func(op *operations.Operation) error {
+		defer os.Remove(imageFile.Name())
+
+		err := pool.CreateCustomVolumeFromDiskImage(projectName, volumeName, "", nil, imageFile.Name(), op)
+		if err != nil {
+			return errors.Wrap(err, "Create custom volume from disk image")
+		}
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{fmt.Sprintf("%s/volumes/custom/%s", poolName, volumeName)}
+
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask,
+		db.OperationCustomVolumeImport, resources, nil, run, nil, nil)
+	if err != nil {
+		os.Remove(imageFile.Name())
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+func doVolumeMigration(d *Daemon, projectName string, poolName string, req *api.StorageVolumesPost) response.Response {
 	// Validate migration mode
 	if req.Source.Mode != "pull" && req.Source.Mode != "push" {
 		return response.NotImplemented(fmt.Errorf("Mode '%s' not implemented", req.Source.Mode))
@@ -459,6 +616,7 @@ func doVolumeMigration(d *Daemon, poolName string, req *api.StorageVolumesPost)
 		Secrets:    req.Source.Websockets,
 		Push:       push,
 		VolumeOnly: req.Source.VolumeOnly,
+		Refresh:    req.Source.Refresh,
 	}
 
 	sink, err := NewStorageMigrationSink(&migrationArgs)
@@ -471,7 +629,7 @@ func doVolumeMigration(d *Daemon, poolName string, req *api.StorageVolumesPost)
 
 	run := func(op *operations.Operation) error {
 		// And finally run the migration.
-		err = sink.DoStorage(d.State(), poolName, req, op)
+		err = sink.DoStorage(d.State(), projectName, poolName, req, op)
 		if err != nil {
 			logger.Error("Error during migration sink", log.Ctx{"err": err})
 			return fmt.Errorf("Error transferring storage volume: %s", err)
@@ -565,18 +723,23 @@ func storagePoolVolumeTypePost(d *Daemon, r *http.Request, volumeTypeName string
 		return response.BadRequest(err)
 	}
 
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, volumeName, volumeType)
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, volumeName, volumeType)
 	if resp != nil {
 		return resp
 	}
 
 	// This is a migration request so send back requested secrets.
 	if req.Migration {
-		return storagePoolVolumeTypePostMigration(d.State(), poolName, volumeName, req)
+		return storagePoolVolumeTypePostMigration(d.State(), projectName, poolName, volumeName, req)
 	}
 
 	// Check that the name isn't already in use.
-	_, err = d.cluster.StoragePoolNodeVolumeGetTypeID(req.Name, volumeType, poolID)
+	_, _, err = d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, req.Name, volumeType, poolID)
 	if err != db.ErrNoSuchObject {
 		if err != nil {
 			return response.InternalError(err)
@@ -586,17 +749,19 @@ func storagePoolVolumeTypePost(d *Daemon, r *http.Request, volumeTypeName string
 	}
 
 	// Check if the daemon itself is using it.
-	used, err := daemonStorageUsed(d.State(), poolName, volumeName)
-	if err != nil {
-		return response.SmartError(err)
-	}
+	if projectName == "default" {
+		used, err := daemonStorageUsed(d.State(), poolName, volumeName)
+		if err != nil {
+			return response.SmartError(err)
+		}
 
-	if used {
-		return response.SmartError(fmt.Errorf("Volume is used by LXD itself and cannot be renamed"))
+		if used {
+			return response.SmartError(fmt.Errorf("Volume is used by LXD itself and cannot be renamed"))
+		}
 	}
 
 	// Check if a running container is using it.
-	ctsUsingVolume, err := storagePoolVolumeUsedByRunningInstancesWithProfilesGet(d.State(), poolName, volumeName, volumeTypeName, true)
+	ctsUsingVolume, err := storagePoolVolumeUsedByRunningInstancesWithProfilesGet(d.State(), projectName, poolName, volumeName, volumeTypeName, true)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -607,15 +772,15 @@ func storagePoolVolumeTypePost(d *Daemon, r *http.Request, volumeTypeName string
 
 	// Detect a rename request.
 	if req.Pool == "" || req.Pool == poolName {
-		return storagePoolVolumeTypePostRename(d, poolName, volumeName, volumeType, req)
+		return storagePoolVolumeTypePostRename(d, projectName, poolName, volumeName, volumeType, req)
 	}
 
 	// Otherwise this is a move request.
-	return storagePoolVolumeTypePostMove(d, poolName, volumeName, volumeType, req)
+	return storagePoolVolumeTypePostMove(d, projectName, poolName, volumeName, volumeType, req)
 }
 
 // storagePoolVolumeTypePostMigration handles volume migration type POST requests.
-func storagePoolVolumeTypePostMigration(state *state.State, poolName string, volumeName string, req api.StorageVolumePost) response.Response {
+func storagePoolVolumeTypePostMigration(state *state.State, projectName string, poolName string, volumeName string, req api.StorageVolumePost) response.Response {
 	ws, err := NewStorageMigrationSource(req.VolumeOnly)
 	if err != nil {
 		return response.InternalError(err)
@@ -625,7 +790,7 @@ func storagePoolVolumeTypePostMigration(state *state.State, poolName string, vol
 	resources["storage_volumes"] = []string{fmt.Sprintf("%s/volumes/custom/%s", poolName, volumeName)}
 
 	run := func(op *operations.Operation) error {
-		return ws.DoStorage(state, poolName, volumeName, op)
+		return ws.DoStorage(state, projectName, poolName, volumeName, op)
 	}
 
 	if req.Target != nil {
@@ -653,13 +818,7 @@ func storagePoolVolumeTypePostMigration(state *state.State, poolName string, vol
 }
 
 // storagePoolVolumeTypePostRename handles volume rename type POST requests.
-func storagePoolVolumeTypePostRename(d *Daemon, poolName string, volumeName string, volumeType int, req api.StorageVolumePost) response.Response {
-	// Notify users of the volume that it's name is changing.
-	err := storagePoolVolumeUpdateUsers(d, poolName, volumeName, req.Pool, req.Name)
-	if err != nil {
-		return response.SmartError(err)
-	}
-
+func storagePoolVolumeTypePostRename(d *Daemon, projectName string, poolName string, volumeName string, volumeType int, req api.StorageVolumePost) response.Response {
 	// Check if we can load new storage layer for pool driver type.
 	pool, err := storagePools.GetPoolByName(d.State(), poolName)
 	if err != storageDrivers.ErrUnknownDriver {
@@ -667,13 +826,30 @@ func storagePoolVolumeTypePostRename(d *Daemon, poolName string, volumeName stri
 			return response.SmartError(err)
 		}
 
-		err = pool.RenameCustomVolume(volumeName, req.Name, nil)
+		// Notify users of the volume that it's name is changing.
+		err = storagePoolVolumeUpdateUsers(d, projectName, poolName, volumeName, req.Pool, req.Name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = pool.RenameCustomVolume(projectName, volumeName, req.Name, nil)
 		if err != nil {
 			// Notify users of the volume that it's name is changing back.
-			storagePoolVolumeUpdateUsers(d, req.Pool, req.Name, poolName, volumeName)
+			storagePoolVolumeUpdateUsers(d, projectName, req.Pool, req.Name, poolName, volumeName)
 			return response.SmartError(err)
 		}
 	} else {
+		err = storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		// Notify users of the volume that it's name is changing.
+		err = storagePoolVolumeUpdateUsers(d, projectName, poolName, volumeName, req.Pool, req.Name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
 		s, err := storagePoolVolumeInit(d.State(), "default", poolName, volumeName, volumeType)
 		if err != nil {
 			return response.InternalError(err)
@@ -682,7 +858,7 @@ func storagePoolVolumeTypePostRename(d *Daemon, poolName string, volumeName stri
 		err = s.StoragePoolVolumeRename(req.Name)
 		if err != nil {
 			// Notify users of the volume that it's name is changing back.
-			storagePoolVolumeUpdateUsers(d, req.Pool, req.Name, poolName, volumeName)
+			storagePoolVolumeUpdateUsers(d, projectName, req.Pool, req.Name, poolName, volumeName)
 			return response.SmartError(err)
 		}
 	}
@@ -691,7 +867,7 @@ func storagePoolVolumeTypePostRename(d *Daemon, poolName string, volumeName stri
 }
 
 // storagePoolVolumeTypePostMove handles volume move type POST requests.
-func storagePoolVolumeTypePostMove(d *Daemon, poolName string, volumeName string, volumeType int, req api.StorageVolumePost) response.Response {
+func storagePoolVolumeTypePostMove(d *Daemon, projectName string, poolName string, volumeName string, volumeType int, req api.StorageVolumePost) response.Response {
 	var run func(op *operations.Operation) error
 
 	// Check if we can load new storage layer for both target and source pool driver types.
@@ -704,23 +880,28 @@ func storagePoolVolumeTypePostMove(d *Daemon, poolName string, volumeName string
 
 		run = func(op *operations.Operation) error {
 			// Notify users of the volume that it's name is changing.
-			err := storagePoolVolumeUpdateUsers(d, poolName, volumeName, req.Pool, req.Name)
+			err := storagePoolVolumeUpdateUsers(d, projectName, poolName, volumeName, req.Pool, req.Name)
 			if err != nil {
 				return err
 			}
 
 			// Provide empty description and nil config to instruct
 			// CreateCustomVolumeFromCopy to copy it from source volume.
-			err = pool.CreateCustomVolumeFromCopy(req.Name, "", nil, poolName, volumeName, false, op)
+			err = pool.CreateCustomVolumeFromCopy(projectName, req.Name, "", nil, poolName, volumeName, false, op)
 			if err != nil {
 				// Notify users of the volume that it's name is changing back.
-				storagePoolVolumeUpdateUsers(d, req.Pool, req.Name, poolName, volumeName)
+				storagePoolVolumeUpdateUsers(d, projectName, req.Pool, req.Name, poolName, volumeName)
 				return err
 			}
 
-			return srcPool.DeleteCustomVolume(volumeName, op)
+			return srcPool.DeleteCustomVolume(projectName, volumeName, op)
 		}
 	} else {
+		err := storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
 		// Convert poolName to poolID.
 		poolID, _, err := d.cluster.StoragePoolGet(poolName)
 		if err != nil {
@@ -734,7 +915,7 @@ func storagePoolVolumeTypePostMove(d *Daemon, poolName string, volumeName string
 		}
 
 		// Get storage volume snapshots.
-		snapshots, err := d.cluster.StoragePoolVolumeSnapshotsGetType(volumeName, volumeType, poolID)
+		snapshots, err := d.cluster.StoragePoolVolumeSnapshotsGetType("default", volumeName, volumeType, poolID)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -749,7 +930,7 @@ func storagePoolVolumeTypePostMove(d *Daemon, poolName string, volumeName string
 
 		run = func(op *operations.Operation) error {
 			// Notify users of the volume that it's name is changing.
-			err := storagePoolVolumeUpdateUsers(d, poolName, volumeName, req.Pool, req.Name)
+			err := storagePoolVolumeUpdateUsers(d, projectName, poolName, volumeName, req.Pool, req.Name)
 			if err != nil {
 				return err
 			}
@@ -757,7 +938,7 @@ func storagePoolVolumeTypePostMove(d *Daemon, poolName string, volumeName string
 			err = storagePoolVolumeCreateInternal(d.State(), req.Pool, &moveReq)
 			if err != nil {
 				// Notify users of the volume that it's name is changing back.
-				storagePoolVolumeUpdateUsers(d, req.Pool, req.Name, poolName, volumeName)
+				storagePoolVolumeUpdateUsers(d, projectName, req.Pool, req.Name, poolName, volumeName)
 				return err
 			}
 
@@ -832,8 +1013,6 @@ func storageGetVolumeNameFromURL(r *http.Request) (string, error) {
 // /1.0/storage-pools/{pool}/volumes/{type}/{name}
 // Get storage volume of a given volume type on a given storage pool.
 func storagePoolVolumeTypeGet(d *Daemon, r *http.Request, volumeTypeName string) response.Response {
-	project := projectParam(r)
-
 	// Get the name of the storage volume.
 	volumeName, err := storageGetVolumeNameFromURL(r)
 	if err != nil {
@@ -854,6 +1033,11 @@ func storagePoolVolumeTypeGet(d *Daemon, r *http.Request, volumeTypeName string)
 		return response.BadRequest(fmt.Errorf("Invalid storage volume type %s", volumeTypeName))
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get the ID of the storage pool the storage volume is supposed to be
 	// attached to.
 	poolID, err := d.cluster.StoragePoolGetID(poolName)
@@ -866,18 +1050,18 @@ func storagePoolVolumeTypeGet(d *Daemon, r *http.Request, volumeTypeName string)
 		return resp
 	}
 
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, volumeName, volumeType)
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, volumeName, volumeType)
 	if resp != nil {
 		return resp
 	}
 
 	// Get the storage volume.
-	_, volume, err := d.cluster.StoragePoolNodeVolumeGetType(volumeName, volumeType, poolID)
+	_, volume, err := d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volumeName, volumeType, poolID)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	volumeUsedBy, err := storagePoolVolumeUsedByGet(d.State(), project, poolName, volume.Name, volume.Type)
+	volumeUsedBy, err := storagePoolVolumeUsedByGet(d.State(), projectName, poolName, volume.Name, volume.Type)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -934,18 +1118,23 @@ func storagePoolVolumeTypePut(d *Daemon, r *http.Request, volumeTypeName string)
 		return response.SmartError(err)
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	resp := ForwardedResponseIfTargetIsRemote(d, r)
 	if resp != nil {
 		return resp
 	}
 
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, volumeName, volumeType)
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, volumeName, volumeType)
 	if resp != nil {
 		return resp
 	}
 
 	// Get the existing storage volume.
-	_, vol, err := d.cluster.StoragePoolNodeVolumeGetType(volumeName, volumeType, poolID)
+	_, vol, err := d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volumeName, volumeType, poolID)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -975,14 +1164,14 @@ func storagePoolVolumeTypePut(d *Daemon, r *http.Request, volumeTypeName string)
 			// before applying config changes so that changes are applied to the
 			// restored volume.
 			if req.Restore != "" {
-				err = pool.RestoreCustomVolume(vol.Name, req.Restore, nil)
+				err = pool.RestoreCustomVolume(projectName, vol.Name, req.Restore, nil)
 				if err != nil {
 					return response.SmartError(err)
 				}
 			}
 
 			// Handle update requests.
-			err = pool.UpdateCustomVolume(vol.Name, req.Description, req.Config, nil)
+			err = pool.UpdateCustomVolume(projectName, vol.Name, req.Description, req.Config, nil)
 			if err != nil {
 				return response.SmartError(err)
 			}
@@ -1001,16 +1190,22 @@ func storagePoolVolumeTypePut(d *Daemon, r *http.Request, volumeTypeName string)
 
 			// Update the database if description changed.
 			if req.Description != vol.Description {
-				err = d.cluster.StoragePoolVolumeUpdate(vol.Name, volumeType, poolID, req.Description, vol.Config)
+				err = d.cluster.StoragePoolVolumeUpdate(projectName, vol.Name, volumeType, poolID, req.Description, vol.Config)
 				if err != nil {
 					response.SmartError(err)
 				}
 			}
 		}
 	} else {
+		if volumeType == db.StoragePoolVolumeTypeCustom {
+			err = storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+			if err != nil {
+				return response.BadRequest(err)
+			}
+		}
 
 		if req.Restore != "" {
-			ctsUsingVolume, err := storagePoolVolumeUsedByRunningInstancesWithProfilesGet(d.State(), poolName, vol.Name, storagePoolVolumeTypeNameCustom, true)
+			ctsUsingVolume, err := storagePoolVolumeUsedByRunningInstancesWithProfilesGet(d.State(), projectName, poolName, vol.Name, storagePoolVolumeTypeNameCustom, true)
 			if err != nil {
 				return response.InternalError(err)
 			}
@@ -1085,18 +1280,23 @@ func storagePoolVolumeTypePatch(d *Daemon, r *http.Request, volumeTypeName strin
 		return response.SmartError(err)
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	resp := ForwardedResponseIfTargetIsRemote(d, r)
 	if resp != nil {
 		return resp
 	}
 
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, volumeName, volumeType)
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, volumeName, volumeType)
 	if resp != nil {
 		return resp
 	}
 
 	// Get the existing storage volume.
-	_, vol, err := d.cluster.StoragePoolNodeVolumeGetType(volumeName, volumeType, poolID)
+	_, vol, err := d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volumeName, volumeType, poolID)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -1133,11 +1333,16 @@ func storagePoolVolumeTypePatch(d *Daemon, r *http.Request, volumeTypeName strin
 			return response.SmartError(err)
 		}
 
-		err = pool.UpdateCustomVolume(vol.Name, req.Description, req.Config, nil)
+		err = pool.UpdateCustomVolume(projectName, vol.Name, req.Description, req.Config, nil)
 		if err != nil {
 			return response.SmartError(err)
 		}
 	} else {
+		err = storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
 		// Validate the configuration.
 		err = storagePools.VolumeValidateConfig(volumeName, req.Config, poolRow)
 		if err != nil {
@@ -1171,8 +1376,6 @@ func storagePoolVolumeTypeImagePatch(d *Daemon, r *http.Request) response.Respon
 
 // /1.0/storage-pools/{pool}/volumes/{type}/{name}
 func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request, volumeTypeName string) response.Response {
-	project := projectParam(r)
-
 	// Get the name of the storage volume.
 	volumeName := mux.Vars(r)["name"]
 
@@ -1194,6 +1397,11 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request, volumeTypeName stri
 		return response.BadRequest(fmt.Errorf("Invalid storage volume type %s", volumeTypeName))
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	resp := ForwardedResponseIfTargetIsRemote(d, r)
 	if resp != nil {
 		return resp
@@ -1204,7 +1412,7 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request, volumeTypeName stri
 		return response.SmartError(err)
 	}
 
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, volumeName, volumeType)
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, volumeName, volumeType)
 	if resp != nil {
 		return resp
 	}
@@ -1218,7 +1426,7 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request, volumeTypeName stri
 		return response.BadRequest(fmt.Errorf("storage volumes of type \"%s\" cannot be deleted with the storage api", volumeTypeName))
 	}
 
-	volumeUsedBy, err := storagePoolVolumeUsedByGet(d.State(), project, poolName, volumeName, volumeTypeName)
+	volumeUsedBy, err := storagePoolVolumeUsedByGet(d.State(), projectName, poolName, volumeName, volumeTypeName)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -1243,7 +1451,7 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request, volumeTypeName stri
 
 		switch volumeType {
 		case storagePoolVolumeTypeCustom:
-			err = pool.DeleteCustomVolume(volumeName, nil)
+			err = pool.DeleteCustomVolume(projectName, volumeName, nil)
 		case storagePoolVolumeTypeImage:
 			err = pool.DeleteImage(volumeName, nil)
 		default:
@@ -1253,7 +1461,14 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request, volumeTypeName stri
 			return response.SmartError(err)
 		}
 	} else {
-		s, err := storagePoolVolumeInit(d.State(), project, poolName, volumeName, volumeType)
+		if volumeType == storagePoolVolumeTypeCustom {
+			err = storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+			if err != nil {
+				return response.BadRequest(err)
+			}
+		}
+
+		s, err := storagePoolVolumeInit(d.State(), projectName, poolName, volumeName, volumeType)
 		if err != nil {
 			return response.NotFound(err)
 		}
@@ -1263,13 +1478,13 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request, volumeTypeName stri
 			var snapshots []db.StorageVolumeArgs
 
 			// Delete storage volume snapshots
-			snapshots, err = d.cluster.StoragePoolVolumeSnapshotsGetType(volumeName, volumeType, poolID)
+			snapshots, err = d.cluster.StoragePoolVolumeSnapshotsGetType(projectName, volumeName, volumeType, poolID)
 			if err != nil {
 				return response.SmartError(err)
 			}
 
 			for _, snapshot := range snapshots {
-				s, err := storagePoolVolumeInit(d.State(), project, poolName, snapshot.Name, volumeType)
+				s, err := storagePoolVolumeInit(d.State(), projectName, poolName, snapshot.Name, volumeType)
 				if err != nil {
 					return response.NotFound(err)
 				}
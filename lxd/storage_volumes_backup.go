@@ -0,0 +1,442 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/operations"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var storagePoolVolumeTypeCustomBackupsCmd = APIEndpoint{
+	Path: "storage-pools/{pool}/volumes/{type}/{name}/backups",
+
+	Get:  APIEndpointAction{Handler: storagePoolVolumeTypeCustomBackupsGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
+	Post: APIEndpointAction{Handler: storagePoolVolumeTypeCustomBackupsPost},
+}
+
+var storagePoolVolumeTypeCustomBackupCmd = APIEndpoint{
+	Path: "storage-pools/{pool}/volumes/{type}/{name}/backups/{backupName}",
+
+	Delete: APIEndpointAction{Handler: storagePoolVolumeTypeCustomBackupDelete},
+	Get:    APIEndpointAction{Handler: storagePoolVolumeTypeCustomBackupGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
+	Post:   APIEndpointAction{Handler: storagePoolVolumeTypeCustomBackupPost},
+}
+
+var storagePoolVolumeTypeCustomBackupExportCmd = APIEndpoint{
+	Path: "storage-pools/{pool}/volumes/{type}/{name}/backups/{backupName}/export",
+
+	Get: APIEndpointAction{Handler: storagePoolVolumeTypeCustomBackupExportGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
+}
+
+// Resolve the project and database ID of the custom volume targeted by a
+// backup request. A non-nil response is returned if the request was forwarded
+// to another node or failed.
+func storagePoolVolumeBackupTarget(d *Daemon, r *http.Request) (string, int64, response.Response) {
+	poolName := mux.Vars(r)["pool"]
+	volumeName := mux.Vars(r)["name"]
+
+	// Only custom volumes can be backed up on their own.
+	if mux.Vars(r)["type"] != db.StoragePoolVolumeTypeNameCustom {
+		return "", -1, response.BadRequest(fmt.Errorf("Only custom volumes can be backed up"))
+	}
+
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return "", -1, response.SmartError(err)
+	}
+
+	// Retrieve ID of the storage pool (and check if the storage pool exists).
+	poolID, err := d.cluster.StoragePoolGetID(poolName)
+	if err != nil {
+		return "", -1, response.SmartError(err)
+	}
+
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return "", -1, resp
+	}
+
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, volumeName, db.StoragePoolVolumeTypeCustom)
+	if resp != nil {
+		return "", -1, resp
+	}
+
+	volumeID, _, err := d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volumeName, db.StoragePoolVolumeTypeCustom, poolID)
+	if err != nil {
+		return "", -1, response.SmartError(err)
+	}
+
+	return projectName, volumeID, nil
+}
+
+func storagePoolVolumeTypeCustomBackupsGet(d *Daemon, r *http.Request) response.Response {
+	poolName := mux.Vars(r)["pool"]
+	volumeName := mux.Vars(r)["name"]
+
+	_, volumeID, resp := storagePoolVolumeBackupTarget(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	recursion := util.IsRecursionRequest(r)
+
+	backups, err := d.cluster.StoragePoolVolumeBackupsGet(volumeID)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []*api.StoragePoolVolumeBackup{}
+
+	for _, dbBackup := range backups {
+		if !recursion {
+			url := fmt.Sprintf("/%s/storage-pools/%s/volumes/custom/%s/backups/%s",
+				version.APIVersion, poolName, volumeName, dbBackup.Name)
+			resultString = append(resultString, url)
+		} else {
+			resultMap = append(resultMap, backup.NewVolumeBackup(d.State(), dbBackup).Render())
+		}
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+func storagePoolVolumeTypeCustomBackupsPost(d *Daemon, r *http.Request) response.Response {
+	poolName := mux.Vars(r)["pool"]
+	volumeName := mux.Vars(r)["name"]
+
+	projectName, volumeID, resp := storagePoolVolumeBackupTarget(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err == storageDrivers.ErrUnknownDriver {
+		return response.BadRequest(fmt.Errorf("Storage pool driver doesn't support custom volume backups"))
+	} else if err != nil {
+		return response.SmartError(err)
+	}
+
+	rj := shared.Jmap{}
+	err = json.NewDecoder(r.Body).Decode(&rj)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	expiry, _ := rj.GetString("expires_at")
+	if expiry == "" {
+		// Disable expiration by setting it to zero time.
+		rj["expires_at"] = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	// Create body with correct expiry.
+	body, err := json.Marshal(rj)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	req := api.StoragePoolVolumeBackupsPost{}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Name == "" {
+		// come up with a name.
+		backups, err := d.cluster.StoragePoolVolumeBackupsGet(volumeID)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		max := 0
+
+		for _, dbBackup := range backups {
+			// Ignore backups not following the default naming.
+			if !strings.HasPrefix(dbBackup.Name, "backup") {
+				continue
+			}
+
+			var num int
+			count, err := fmt.Sscanf(dbBackup.Name[len("backup"):], "%d", &num)
+			if err != nil || count != 1 {
+				continue
+			}
+			if num >= max {
+				max = num + 1
+			}
+		}
+
+		req.Name = fmt.Sprintf("backup%d", max)
+	}
+
+	// Validate the name.
+	if strings.Contains(req.Name, "/") {
+		return response.BadRequest(fmt.Errorf("Backup names may not contain slashes"))
+	}
+
+	run := func(op *operations.Operation) error {
+		args := db.StoragePoolVolumeBackup{
+			Name:                 req.Name,
+			VolumeID:             volumeID,
+			CreationDate:         time.Now(),
+			ExpiryDate:           req.ExpiresAt,
+			VolumeOnly:           req.VolumeOnly,
+			OptimizedStorage:     req.OptimizedStorage,
+			CompressionAlgorithm: req.CompressionAlgorithm,
+		}
+
+		err := volumeBackupCreate(d.State(), args, pool)
+		if err != nil {
+			return errors.Wrap(err, "Create backup")
+		}
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{fmt.Sprintf("%s/volumes/custom/%s", poolName, volumeName)}
+	resources["backups"] = []string{req.Name}
+
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask,
+		db.OperationCustomVolumeBackupCreate, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+func storagePoolVolumeTypeCustomBackupGet(d *Daemon, r *http.Request) response.Response {
+	backupName := mux.Vars(r)["backupName"]
+
+	_, volumeID, resp := storagePoolVolumeBackupTarget(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	dbBackup, err := d.cluster.StoragePoolVolumeBackupGet(volumeID, backupName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, backup.NewVolumeBackup(d.State(), dbBackup).Render())
+}
+
+func storagePoolVolumeTypeCustomBackupPost(d *Daemon, r *http.Request) response.Response {
+	poolName := mux.Vars(r)["pool"]
+	volumeName := mux.Vars(r)["name"]
+	backupName := mux.Vars(r)["backupName"]
+
+	projectName, volumeID, resp := storagePoolVolumeBackupTarget(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	req := api.StoragePoolVolumeBackupPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Validate the name
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("No backup name provided"))
+	}
+
+	if strings.Contains(req.Name, "/") {
+		return response.BadRequest(fmt.Errorf("Backup names may not contain slashes"))
+	}
+
+	dbBackup, err := d.cluster.StoragePoolVolumeBackupGet(volumeID, backupName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, err = d.cluster.StoragePoolVolumeBackupGet(volumeID, req.Name)
+	if err != db.ErrNoSuchObject {
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.Conflict(fmt.Errorf("Backup '%s' already exists", req.Name))
+	}
+
+	rename := func(op *operations.Operation) error {
+		return backup.NewVolumeBackup(d.State(), dbBackup).Rename(req.Name)
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{fmt.Sprintf("%s/volumes/custom/%s", poolName, volumeName)}
+
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask,
+		db.OperationCustomVolumeBackupRename, resources, nil, rename, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+func storagePoolVolumeTypeCustomBackupDelete(d *Daemon, r *http.Request) response.Response {
+	poolName := mux.Vars(r)["pool"]
+	volumeName := mux.Vars(r)["name"]
+	backupName := mux.Vars(r)["backupName"]
+
+	projectName, volumeID, resp := storagePoolVolumeBackupTarget(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	dbBackup, err := d.cluster.StoragePoolVolumeBackupGet(volumeID, backupName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	remove := func(op *operations.Operation) error {
+		return backup.NewVolumeBackup(d.State(), dbBackup).Delete()
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{fmt.Sprintf("%s/volumes/custom/%s", poolName, volumeName)}
+
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask,
+		db.OperationCustomVolumeBackupRemove, resources, nil, remove, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+func storagePoolVolumeTypeCustomBackupExportGet(d *Daemon, r *http.Request) response.Response {
+	backupName := mux.Vars(r)["backupName"]
+
+	_, volumeID, resp := storagePoolVolumeBackupTarget(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	dbBackup, err := d.cluster.StoragePoolVolumeBackupGet(volumeID, backupName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	ent := response.FileResponseEntry{
+		Path: backup.NewVolumeBackup(d.State(), dbBackup).Path(),
+	}
+
+	return response.FileResponse(r, []response.FileResponseEntry{ent}, nil, false)
+}
+
+func createStoragePoolVolumeFromBackup(d *Daemon, projectName string, poolName string, data io.Reader, volumeName string) response.Response {
+	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err == storageDrivers.ErrUnknownDriver {
+		return response.BadRequest(fmt.Errorf("Storage pool driver doesn't support custom volume backups"))
+	} else if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Create temporary file to store uploaded backup data.
+	backupFile, err := ioutil.TempFile("", "lxd_backup_")
+	if err != nil {
+		return response.InternalError(err)
+	}
+	defer os.Remove(backupFile.Name())
+
+	// Stream uploaded backup data into temporary file.
+	_, err = io.Copy(backupFile, data)
+	if err != nil {
+		backupFile.Close()
+		return response.InternalError(err)
+	}
+
+	// Parse the backup information.
+	backupFile.Seek(0, 0)
+	bInfo, err := backup.GetInfo(backupFile)
+	if err != nil {
+		backupFile.Close()
+		return response.BadRequest(err)
+	}
+
+	if bInfo.Type != backup.TypeCustom {
+		backupFile.Close()
+		return response.BadRequest(fmt.Errorf("Backup isn't of a custom volume"))
+	}
+
+	bInfo.Project = projectName
+	bInfo.Pool = poolName
+
+	// Override the volume name.
+	if volumeName != "" {
+		bInfo.Name = volumeName
+	}
+
+	if strings.Contains(bInfo.Name, "/") {
+		backupFile.Close()
+		return response.BadRequest(fmt.Errorf("Storage volume names may not contain slashes"))
+	}
+
+	// Optimized backups can only be restored onto the same storage driver.
+	if bInfo.HasBinaryFormat && bInfo.Backend != pool.Driver().Info().Name {
+		backupFile.Close()
+		return response.BadRequest(fmt.Errorf("Optimized backups can only be restored on a %q pool", bInfo.Backend))
+	}
+
+	// Check if destination volume exists.
+	_, _, err = d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, bInfo.Name, db.StoragePoolVolumeTypeCustom, pool.ID())
+	if err != db.ErrNoSuchObject {
+		backupFile.Close()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.Conflict(fmt.Errorf("Volume by that name already exists"))
+	}
+
+	run := func(op *operations.Operation) error {
+		defer backupFile.Close()
+
+		err := pool.CreateCustomVolumeFromBackup(*bInfo, backupFile, op)
+		if err != nil {
+			return errors.Wrap(err, "Create custom volume from backup")
+		}
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{fmt.Sprintf("%s/volumes/custom/%s", poolName, bInfo.Name)}
+
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask,
+		db.OperationCustomVolumeBackupRestore, resources, nil, run, nil, nil)
+	if err != nil {
+		backupFile.Close()
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
@@ -18,6 +18,9 @@ var changeableStoragePoolVolumeProperties = map[string][]string{
 	"btrfs": {
 		"security.shifted",
 		"security.unmapped",
+		"snapshots.expiry",
+		"snapshots.pattern",
+		"snapshots.schedule",
 		"size",
 	},
 
@@ -25,17 +28,26 @@ var changeableStoragePoolVolumeProperties = map[string][]string{
 		"security.shifted",
 		"block.mount_options",
 		"security.unmapped",
+		"snapshots.expiry",
+		"snapshots.pattern",
+		"snapshots.schedule",
 		"size"},
 
 	"cephfs": {
 		"security.shifted",
 		"security.unmapped",
+		"snapshots.expiry",
+		"snapshots.pattern",
+		"snapshots.schedule",
 		"size",
 	},
 
 	"dir": {
 		"security.shifted",
 		"security.unmapped",
+		"snapshots.expiry",
+		"snapshots.pattern",
+		"snapshots.schedule",
 		"size",
 	},
 
@@ -43,12 +55,18 @@ var changeableStoragePoolVolumeProperties = map[string][]string{
 		"block.mount_options",
 		"security.shifted",
 		"security.unmapped",
+		"snapshots.expiry",
+		"snapshots.pattern",
+		"snapshots.schedule",
 		"size",
 	},
 
 	"zfs": {
 		"security.shifted",
 		"security.unmapped",
+		"snapshots.expiry",
+		"snapshots.pattern",
+		"snapshots.schedule",
 		"size",
 		"zfs.remove_snapshots",
 		"zfs.use_refquota",
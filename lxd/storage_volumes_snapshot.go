@@ -1,28 +1,39 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/flosch/pongo2"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	cron "gopkg.in/robfig/cron.v2"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/operations"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/version"
 )
 
 var storagePoolVolumeSnapshotsTypeCmd = APIEndpoint{
 	Path: "storage-pools/{pool}/volumes/{type}/{name}/snapshots",
 
-	Get:  APIEndpointAction{Handler: storagePoolVolumeSnapshotsTypeGet, AccessHandler: AllowAuthenticated},
+	Get:  APIEndpointAction{Handler: storagePoolVolumeSnapshotsTypeGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
 	Post: APIEndpointAction{Handler: storagePoolVolumeSnapshotsTypePost},
 }
 
@@ -30,7 +41,7 @@ var storagePoolVolumeSnapshotTypeCmd = APIEndpoint{
 	Path: "storage-pools/{pool}/volumes/{type}/{name}/snapshots/{snapshotName}",
 
 	Delete: APIEndpointAction{Handler: storagePoolVolumeSnapshotTypeDelete},
-	Get:    APIEndpointAction{Handler: storagePoolVolumeSnapshotTypeGet, AccessHandler: AllowAuthenticated},
+	Get:    APIEndpointAction{Handler: storagePoolVolumeSnapshotTypeGet, AccessHandler: AllowProjectPermission("storage-volumes", "view")},
 	Post:   APIEndpointAction{Handler: storagePoolVolumeSnapshotTypePost},
 	Put:    APIEndpointAction{Handler: storagePoolVolumeSnapshotTypePut},
 }
@@ -63,30 +74,25 @@ func storagePoolVolumeSnapshotsTypePost(d *Daemon, r *http.Request) response.Res
 		return response.BadRequest(fmt.Errorf("Invalid storage volume type \"%d\"", volumeType))
 	}
 
-	// Get a snapshot name.
-	if req.Name == "" {
-		i := d.cluster.StorageVolumeNextSnapshot(volumeName, volumeType)
-		req.Name = fmt.Sprintf("snap%d", i)
-	}
-
-	// Validate the name
-	err = storagePools.ValidName(req.Name)
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
 	if err != nil {
-		return response.BadRequest(err)
+		return response.SmartError(err)
 	}
 
 	// Check that this isn't a restricted volume
-	used, err := daemonStorageUsed(d.State(), poolName, volumeName)
-	if err != nil {
-		return response.InternalError(err)
-	}
+	if projectName == "default" {
+		used, err := daemonStorageUsed(d.State(), poolName, volumeName)
+		if err != nil {
+			return response.InternalError(err)
+		}
 
-	if used {
-		return response.BadRequest(fmt.Errorf("Volumes used by LXD itself cannot have snapshots"))
+		if used {
+			return response.BadRequest(fmt.Errorf("Volumes used by LXD itself cannot have snapshots"))
+		}
 	}
 
 	// Retrieve ID of the storage pool (and check if the storage pool exists).
-	poolID, err := d.cluster.StoragePoolGetID(poolName)
+	poolID, pool, err := d.cluster.StoragePoolGet(poolName)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -96,13 +102,34 @@ func storagePoolVolumeSnapshotsTypePost(d *Daemon, r *http.Request) response.Res
 		return resp
 	}
 
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, volumeName, volumeType)
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, volumeName, volumeType)
 	if resp != nil {
 		return resp
 	}
 
+	// Load the parent volume to get its snapshot settings.
+	_, vol, err := d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volumeName, volumeType, poolID)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Get a snapshot name.
+	if req.Name == "" {
+		pattern := storagePoolVolumeSnapshotsConfig(vol.Config, pool.Config, "snapshots.pattern")
+		req.Name, err = storagePoolVolumeSnapshotDetermineNextName(d, projectName, poolID, volumeName, volumeType, pattern)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	// Validate the name
+	err = storagePools.ValidName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Ensure that the snapshot doesn't already exist.
-	_, _, err = d.cluster.StoragePoolNodeVolumeGetType(fmt.Sprintf("%s/%s", volumeName, req.Name), volumeType, poolID)
+	_, _, err = d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, fmt.Sprintf("%s/%s", volumeName, req.Name), volumeType, poolID)
 	if err != db.ErrNoSuchObject {
 		if err != nil {
 			return response.SmartError(err)
@@ -111,69 +138,207 @@ func storagePoolVolumeSnapshotsTypePost(d *Daemon, r *http.Request) response.Res
 		return response.Conflict(fmt.Errorf("Snapshot '%s' already in use", req.Name))
 	}
 
+	// Get the expiry date, defaulting to the snapshots.expiry setting.
+	var expiry time.Time
+	if req.ExpiresAt != nil {
+		expiry = *req.ExpiresAt
+	} else {
+		expiry, err = shared.GetSnapshotExpiry(time.Now(), storagePoolVolumeSnapshotsConfig(vol.Config, pool.Config, "snapshots.expiry"))
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	snapshot := func(op *operations.Operation) error {
-		// Check if we can load new storage layer for pool driver type.
-		pool, err := storagePools.GetPoolByName(d.State(), poolName)
-		if err != storageDrivers.ErrUnknownDriver {
-			if err != nil {
-				return err
-			}
+		return storagePoolVolumeSnapshotCreate(d.State(), projectName, poolName, volumeTypeName, volumeName, req.Name, expiry, op)
+	}
 
-			err = pool.CreateCustomVolumeSnapshot(volumeName, req.Name, op)
-			if err != nil {
-				return err
-			}
-		} else {
-			// Ensure that the storage volume exists.
-			storage, err := storagePoolVolumeInit(d.State(), "default", poolName, volumeName, volumeType)
-			if err != nil {
-				return err
-			}
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{volumeName}
 
-			// Start the storage.
-			ourMount, err := storage.StoragePoolVolumeMount()
-			if err != nil {
-				return err
-			}
-			if ourMount {
-				defer storage.StoragePoolVolumeUmount()
-			}
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask, db.OperationVolumeSnapshotCreate, resources, nil, snapshot, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
 
-			volWritable := storage.GetStoragePoolVolumeWritable()
-			fullSnapName := fmt.Sprintf("%s%s%s", volumeName, shared.SnapshotDelimiter, req.Name)
-			req.Name = fullSnapName
-			dbArgs := &db.StorageVolumeArgs{
-				Name:        fullSnapName,
-				PoolName:    poolName,
-				TypeName:    volumeTypeName,
-				Snapshot:    true,
-				Config:      volWritable.Config,
-				Description: volWritable.Description,
-			}
+	return operations.OperationResponse(op)
+}
+
+// storagePoolVolumeSnapshotCreate creates a snapshot of a storage volume
+// using whichever storage layer the pool is handled by and sends the matching
+// lifecycle event.
+func storagePoolVolumeSnapshotCreate(s *state.State, projectName, poolName, volumeTypeName, volumeName, snapshotName string, expiry time.Time, op *operations.Operation) error {
+	volumeType, err := storagePools.VolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return err
+	}
 
-			err = storage.StoragePoolVolumeSnapshotCreate(&req)
+	// Check if we can load new storage layer for pool driver type.
+	pool, err := storagePools.GetPoolByName(s, poolName)
+	if err != storageDrivers.ErrUnknownDriver {
+		if err != nil {
+			return err
+		}
+
+		err = pool.CreateCustomVolumeSnapshot(projectName, volumeName, snapshotName, expiry, op)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+		if err != nil {
+			return err
+		}
+
+		// Ensure that the storage volume exists.
+		storage, err := storagePoolVolumeInit(s, "default", poolName, volumeName, volumeType)
+		if err != nil {
+			return err
+		}
+
+		// Start the storage.
+		ourMount, err := storage.StoragePoolVolumeMount()
+		if err != nil {
+			return err
+		}
+		if ourMount {
+			defer storage.StoragePoolVolumeUmount()
+		}
+
+		volWritable := storage.GetStoragePoolVolumeWritable()
+		fullSnapName := fmt.Sprintf("%s%s%s", volumeName, shared.SnapshotDelimiter, snapshotName)
+		req := api.StorageVolumeSnapshotsPost{Name: fullSnapName}
+		dbArgs := &db.StorageVolumeArgs{
+			Name:        fullSnapName,
+			PoolName:    poolName,
+			TypeName:    volumeTypeName,
+			Snapshot:    true,
+			Config:      volWritable.Config,
+			Description: volWritable.Description,
+		}
+
+		err = storage.StoragePoolVolumeSnapshotCreate(&req)
+		if err != nil {
+			return err
+		}
+
+		_, err = storagePoolVolumeSnapshotDBCreateInternal(s, dbArgs)
+		if err != nil {
+			return err
+		}
+
+		if !expiry.IsZero() {
+			poolID, err := s.Cluster.StoragePoolGetID(poolName)
 			if err != nil {
 				return err
 			}
 
-			_, err = storagePoolVolumeSnapshotDBCreateInternal(d.State(), dbArgs)
+			err = s.Cluster.StoragePoolVolumeSnapshotExpiryUpdate(projectName, fullSnapName, volumeType, poolID, expiry)
 			if err != nil {
 				return err
 			}
 		}
+	}
 
-		return nil
+	s.Events.SendLifecycle(projectName, "storage-volume-snapshot-created",
+		fmt.Sprintf("/1.0/storage-pools/%s/volumes/%s/%s", poolName, volumeTypeName, volumeName),
+		map[string]interface{}{
+			"snapshot_name": snapshotName,
+		})
+
+	return nil
+}
+
+// storagePoolVolumeSnapshotDelete deletes a snapshot of a custom storage
+// volume using whichever storage layer the pool is handled by and sends the
+// matching lifecycle event.
+func storagePoolVolumeSnapshotDelete(s *state.State, projectName, poolName, volumeName, snapshotName string, op *operations.Operation) error {
+	fullSnapshotName := fmt.Sprintf("%s/%s", volumeName, snapshotName)
+
+	// Check if we can load new storage layer for pool driver type.
+	pool, err := storagePools.GetPoolByName(s, poolName)
+	if err != storageDrivers.ErrUnknownDriver {
+		if err != nil {
+			return err
+		}
+
+		err = pool.DeleteCustomVolumeSnapshot(projectName, fullSnapshotName, op)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+		if err != nil {
+			return err
+		}
+
+		storage, err := storagePoolVolumeInit(s, "default", poolName, fullSnapshotName, storagePoolVolumeTypeCustom)
+		if err != nil {
+			return err
+		}
+
+		err = storage.StoragePoolVolumeSnapshotDelete()
+		if err != nil {
+			return err
+		}
 	}
 
-	resources := map[string][]string{}
-	resources["storage_volumes"] = []string{volumeName}
+	s.Events.SendLifecycle(projectName, "storage-volume-snapshot-deleted",
+		fmt.Sprintf("/1.0/storage-pools/%s/volumes/%s/%s", poolName, db.StoragePoolVolumeTypeNameCustom, volumeName),
+		map[string]interface{}{
+			"snapshot_name": snapshotName,
+		})
+
+	return nil
+}
+
+// storagePoolVolumeSnapshotsConfig returns the value of one of the
+// snapshots.* keys for a custom volume, falling back to the volume.snapshots.*
+// default of its storage pool.
+func storagePoolVolumeSnapshotsConfig(volumeConfig map[string]string, poolConfig map[string]string, key string) string {
+	value := volumeConfig[key]
+	if value == "" {
+		value = poolConfig[fmt.Sprintf("volume.%s", key)]
+	}
+
+	return value
+}
 
-	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationVolumeSnapshotCreate, resources, nil, snapshot, nil, nil)
+// storagePoolVolumeSnapshotDetermineNextName returns the name of the next
+// snapshot of a storage volume, based on the given snapshots.pattern.
+func storagePoolVolumeSnapshotDetermineNextName(d *Daemon, projectName string, poolID int64, volumeName string, volumeType int, pattern string) (string, error) {
+	var err error
+
+	if pattern == "" {
+		pattern = "snap%d"
+	}
+
+	pattern, err = shared.RenderTemplate(pattern, pongo2.Context{
+		"creation_date": time.Now(),
+	})
 	if err != nil {
-		return response.InternalError(err)
+		return "", err
 	}
 
-	return operations.OperationResponse(op)
+	count := strings.Count(pattern, "%d")
+	if count > 1 {
+		return "", fmt.Errorf("Snapshot pattern may contain '%%d' only once")
+	} else if count == 1 {
+		i := d.cluster.StorageVolumeNextSnapshotByPattern(projectName, poolID, volumeName, volumeType, pattern)
+		return strings.Replace(pattern, "%d", strconv.Itoa(i), 1), nil
+	}
+
+	// Append '-0', '-1', etc. if the actual pattern/snapshot name already exists
+	_, _, err = d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, fmt.Sprintf("%s/%s", volumeName, pattern), volumeType, poolID)
+	if err == nil {
+		pattern = fmt.Sprintf("%s-%%d", pattern)
+		i := d.cluster.StorageVolumeNextSnapshotByPattern(projectName, poolID, volumeName, volumeType, pattern)
+		return strings.Replace(pattern, "%d", strconv.Itoa(i), 1), nil
+	} else if err != db.ErrNoSuchObject {
+		return "", err
+	}
+
+	return pattern, nil
 }
 
 func storagePoolVolumeSnapshotsTypeGet(d *Daemon, r *http.Request) response.Response {
@@ -199,6 +364,11 @@ func storagePoolVolumeSnapshotsTypeGet(d *Daemon, r *http.Request) response.Resp
 		return response.BadRequest(fmt.Errorf("invalid storage volume type %s", volumeTypeName))
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Retrieve ID of the storage pool (and check if the storage pool
 	// exists).
 	poolID, err := d.cluster.StoragePoolGetID(poolName)
@@ -207,7 +377,7 @@ func storagePoolVolumeSnapshotsTypeGet(d *Daemon, r *http.Request) response.Resp
 	}
 
 	// Get the names of all storage volume snapshots of a given volume
-	volumes, err := d.cluster.StoragePoolVolumeSnapshotsGetType(volumeName, volumeType, poolID)
+	volumes, err := d.cluster.StoragePoolVolumeSnapshotsGetType(projectName, volumeName, volumeType, poolID)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -224,12 +394,17 @@ func storagePoolVolumeSnapshotsTypeGet(d *Daemon, r *http.Request) response.Resp
 			}
 			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s/snapshots/%s", version.APIVersion, poolName, apiEndpoint, volumeName, snapshotName))
 		} else {
-			_, vol, err := d.cluster.StoragePoolNodeVolumeGetType(volume.Name, volumeType, poolID)
+			volumeID, vol, err := d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volume.Name, volumeType, poolID)
 			if err != nil {
 				continue
 			}
 
-			volumeUsedBy, err := storagePoolVolumeUsedByGet(d.State(), "default", poolName, vol.Name, vol.Type)
+			expiryDate, err := d.cluster.StorageVolumeExpiryDateGet(volumeID)
+			if err != nil {
+				return response.SmartError(err)
+			}
+
+			volumeUsedBy, err := storagePoolVolumeUsedByGet(d.State(), projectName, poolName, vol.Name, vol.Type)
 			if err != nil {
 				return response.SmartError(err)
 			}
@@ -239,6 +414,7 @@ func storagePoolVolumeSnapshotsTypeGet(d *Daemon, r *http.Request) response.Resp
 			tmp.Config = vol.Config
 			tmp.Description = vol.Description
 			tmp.Name = vol.Name
+			tmp.ExpiresAt = expiryDate
 
 			resultMap = append(resultMap, tmp)
 		}
@@ -293,6 +469,11 @@ func storagePoolVolumeSnapshotTypePost(d *Daemon, r *http.Request) response.Resp
 		return response.BadRequest(fmt.Errorf("invalid storage volume type %s", volumeTypeName))
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	resp := ForwardedResponseIfTargetIsRemote(d, r)
 	if resp != nil {
 		return resp
@@ -304,7 +485,7 @@ func storagePoolVolumeSnapshotTypePost(d *Daemon, r *http.Request) response.Resp
 	}
 
 	fullSnapshotName := fmt.Sprintf("%s/%s", volumeName, snapshotName)
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, fullSnapshotName, volumeType)
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, fullSnapshotName, volumeType)
 	if resp != nil {
 		return resp
 	}
@@ -317,8 +498,13 @@ func storagePoolVolumeSnapshotTypePost(d *Daemon, r *http.Request) response.Resp
 				return err
 			}
 
-			err = pool.RenameCustomVolumeSnapshot(fullSnapshotName, req.Name, op)
+			err = pool.RenameCustomVolumeSnapshot(projectName, fullSnapshotName, req.Name, op)
 		} else {
+			err = storagePoolVolumeLegacyProjectCheck(projectName, poolName)
+			if err != nil {
+				return err
+			}
+
 			var s storage
 			s, err = storagePoolVolumeInit(d.State(), "default", poolName, fullSnapshotName, volumeType)
 			if err != nil {
@@ -334,7 +520,7 @@ func storagePoolVolumeSnapshotTypePost(d *Daemon, r *http.Request) response.Resp
 	resources := map[string][]string{}
 	resources["storage_volume_snapshots"] = []string{volumeName}
 
-	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationVolumeSnapshotDelete, resources, nil, snapshotRename, nil, nil)
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask, db.OperationVolumeSnapshotDelete, resources, nil, snapshotRename, nil, nil)
 	if err != nil {
 		return response.InternalError(err)
 	}
@@ -367,6 +553,11 @@ func storagePoolVolumeSnapshotTypeGet(d *Daemon, r *http.Request) response.Respo
 		return response.BadRequest(fmt.Errorf("invalid storage volume type %s", volumeTypeName))
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	resp := ForwardedResponseIfTargetIsRemote(d, r)
 	if resp != nil {
 		return resp
@@ -378,12 +569,17 @@ func storagePoolVolumeSnapshotTypeGet(d *Daemon, r *http.Request) response.Respo
 	}
 
 	fullSnapshotName := fmt.Sprintf("%s/%s", volumeName, snapshotName)
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, fullSnapshotName, volumeType)
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, fullSnapshotName, volumeType)
 	if resp != nil {
 		return resp
 	}
 
-	_, volume, err := d.cluster.StoragePoolNodeVolumeGetType(fullSnapshotName, volumeType, poolID)
+	volumeID, volume, err := d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, fullSnapshotName, volumeType, poolID)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	expiryDate, err := d.cluster.StorageVolumeExpiryDateGet(volumeID)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -392,13 +588,14 @@ func storagePoolVolumeSnapshotTypeGet(d *Daemon, r *http.Request) response.Respo
 	snapshot.Config = volume.Config
 	snapshot.Description = volume.Description
 	snapshot.Name = snapshotName
+	snapshot.ExpiresAt = expiryDate
 
 	etag := []interface{}{snapshot.Name, snapshot.Description, snapshot.Config}
 
 	return response.SyncResponseETag(true, &snapshot, etag)
 }
 
-// storagePoolVolumeSnapshotTypePut allows a snapshot's description to be changed.
+// storagePoolVolumeSnapshotTypePut allows a snapshot's description and expiry date to be changed.
 func storagePoolVolumeSnapshotTypePut(d *Daemon, r *http.Request) response.Response {
 	// Get the name of the storage pool the volume is supposed to be
 	// attached to.
@@ -424,6 +621,11 @@ func storagePoolVolumeSnapshotTypePut(d *Daemon, r *http.Request) response.Respo
 		return response.BadRequest(fmt.Errorf("Invalid storage volume type %s", volumeTypeName))
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	resp := ForwardedResponseIfTargetIsRemote(d, r)
 	if resp != nil {
 		return resp
@@ -435,12 +637,17 @@ func storagePoolVolumeSnapshotTypePut(d *Daemon, r *http.Request) response.Respo
 	}
 
 	fullSnapshotName := fmt.Sprintf("%s/%s", volumeName, snapshotName)
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, fullSnapshotName, volumeType)
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, fullSnapshotName, volumeType)
 	if resp != nil {
 		return resp
 	}
 
-	_, vol, err := d.cluster.StoragePoolNodeVolumeGetType(fullSnapshotName, volumeType, poolID)
+	volumeID, vol, err := d.cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, fullSnapshotName, volumeType, poolID)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	expiryDate, err := d.cluster.StorageVolumeExpiryDateGet(volumeID)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -461,7 +668,15 @@ func storagePoolVolumeSnapshotTypePut(d *Daemon, r *http.Request) response.Respo
 	do := func(op *operations.Operation) error {
 		// Update the database if description changed.
 		if req.Description != vol.Description {
-			err = d.cluster.StoragePoolVolumeUpdate(vol.Name, volumeType, poolID, req.Description, vol.Config)
+			err = d.cluster.StoragePoolVolumeUpdate(projectName, vol.Name, volumeType, poolID, req.Description, vol.Config)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Update the database if the expiry date changed.
+		if !req.ExpiresAt.Equal(expiryDate) {
+			err = d.cluster.StoragePoolVolumeSnapshotExpiryUpdate(projectName, vol.Name, volumeType, poolID, req.ExpiresAt)
 			if err != nil {
 				return err
 			}
@@ -473,7 +688,7 @@ func storagePoolVolumeSnapshotTypePut(d *Daemon, r *http.Request) response.Respo
 	resources := map[string][]string{}
 	resources["storage_volume_snapshots"] = []string{volumeName}
 
-	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationVolumeSnapshotUpdate, resources, nil, do, nil, nil)
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask, db.OperationVolumeSnapshotUpdate, resources, nil, do, nil, nil)
 	if err != nil {
 		return response.InternalError(err)
 	}
@@ -506,6 +721,11 @@ func storagePoolVolumeSnapshotTypeDelete(d *Daemon, r *http.Request) response.Re
 		return response.BadRequest(fmt.Errorf("invalid storage volume type %s", volumeTypeName))
 	}
 
+	projectName, err := projecthelpers.StorageVolumeProject(d.cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	resp := ForwardedResponseIfTargetIsRemote(d, r)
 	if resp != nil {
 		return resp
@@ -517,40 +737,230 @@ func storagePoolVolumeSnapshotTypeDelete(d *Daemon, r *http.Request) response.Re
 	}
 
 	fullSnapshotName := fmt.Sprintf("%s/%s", volumeName, snapshotName)
-	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, fullSnapshotName, volumeType)
+	resp = ForwardedResponseIfVolumeIsRemote(d, r, poolID, projectName, fullSnapshotName, volumeType)
 	if resp != nil {
 		return resp
 	}
 
 	snapshotDelete := func(op *operations.Operation) error {
-		// Check if we can load new storage layer for pool driver type.
-		pool, err := storagePools.GetPoolByName(d.State(), poolName)
-		if err != storageDrivers.ErrUnknownDriver {
+		return storagePoolVolumeSnapshotDelete(d.State(), projectName, poolName, volumeName, snapshotName, op)
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volume_snapshots"] = []string{volumeName}
+
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask, db.OperationVolumeSnapshotDelete, resources, nil, snapshotDelete, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+func autoCreateCustomVolumeSnapshotsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		// Load all local custom volumes
+		allVolumes, err := d.cluster.StoragePoolNodeCustomVolumesGet()
+		if err != nil {
+			logger.Error("Failed to load custom volumes for scheduled snapshots", log.Ctx{"err": err})
+			return
+		}
+
+		// Figure out which need snapshotting (if any)
+		pools := map[string]*api.StoragePool{}
+		volumes := []db.StorageVolumeArgs{}
+		for _, v := range allVolumes {
+			pool, ok := pools[v.PoolName]
+			if !ok {
+				_, pool, err = d.cluster.StoragePoolGet(v.PoolName)
+				if err != nil {
+					logger.Error("Failed to load storage pool for scheduled snapshots", log.Ctx{"err": err, "pool": v.PoolName})
+					continue
+				}
+
+				pools[v.PoolName] = pool
+			}
+
+			schedule := storagePoolVolumeSnapshotsConfig(v.Config, pool.Config, "snapshots.schedule")
+			if schedule == "" {
+				continue
+			}
+
+			// Extend our schedule to one that is accepted by the used cron parser
+			sched, err := cron.Parse(fmt.Sprintf("* %s", schedule))
 			if err != nil {
-				return err
+				continue
 			}
 
-			err = pool.DeleteCustomVolumeSnapshot(fullSnapshotName, op)
-		} else {
-			var s storage
-			s, err = storagePoolVolumeInit(d.State(), "default", poolName, fullSnapshotName, volumeType)
+			// Check if it's time to snapshot
+			now := time.Now()
+
+			// Truncate the time now back to the start of the minute, before passing to
+			// the cron scheduler, as it will add 1s to the scheduled time and we don't
+			// want the next scheduled time to roll over to the next minute and break
+			// the time comparison below.
+			now = now.Truncate(time.Minute)
+
+			// Calculate the next scheduled time based on the snapshots.schedule
+			// pattern and the time now.
+			next := sched.Next(now)
+
+			// Ignore everything that is more precise than minutes.
+			next = next.Truncate(time.Minute)
+
+			if !now.Equal(next) {
+				continue
+			}
+
+			// Volumes used by LXD itself cannot have snapshots
+			if v.ProjectName == "default" {
+				used, err := daemonStorageUsed(d.State(), v.PoolName, v.Name)
+				if err != nil || used {
+					continue
+				}
+			}
+
+			volumes = append(volumes, v)
+		}
+
+		if len(volumes) == 0 {
+			return
+		}
+
+		opRun := func(op *operations.Operation) error {
+			return autoCreateCustomVolumeSnapshots(ctx, d, volumes, pools)
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationVolumeSnapshotCreate, nil, nil, opRun, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start create volume snapshot operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Creating scheduled volume snapshots")
+
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to create scheduled volume snapshots", log.Ctx{"err": err})
+		}
+
+		logger.Info("Done creating scheduled volume snapshots")
+	}
+
+	first := true
+	schedule := func() (time.Duration, error) {
+		interval := time.Minute
+
+		if first {
+			first = false
+			return interval, task.ErrSkip
+		}
+
+		return interval, nil
+	}
+
+	return f, schedule
+}
+
+func autoCreateCustomVolumeSnapshots(ctx context.Context, d *Daemon, volumes []db.StorageVolumeArgs, pools map[string]*api.StoragePool) error {
+	// Make the snapshots
+	for _, v := range volumes {
+		ch := make(chan error)
+		go func() {
+			pool := pools[v.PoolName]
+			logCtx := log.Ctx{"project": v.ProjectName, "pool": v.PoolName, "volume": v.Name}
+
+			pattern := storagePoolVolumeSnapshotsConfig(v.Config, pool.Config, "snapshots.pattern")
+			snapshotName, err := storagePoolVolumeSnapshotDetermineNextName(d, v.ProjectName, v.PoolID, v.Name, db.StoragePoolVolumeTypeCustom, pattern)
 			if err != nil {
-				return err
+				logCtx["err"] = err
+				logger.Error("Error retrieving next volume snapshot name", logCtx)
+				ch <- nil
+				return
+			}
+
+			expiry, err := shared.GetSnapshotExpiry(time.Now(), storagePoolVolumeSnapshotsConfig(v.Config, pool.Config, "snapshots.expiry"))
+			if err != nil {
+				logCtx["err"] = err
+				logger.Error("Error getting volume snapshot expiry date", logCtx)
+				ch <- nil
+				return
+			}
+
+			err = storagePoolVolumeSnapshotCreate(d.State(), v.ProjectName, v.PoolName, db.StoragePoolVolumeTypeNameCustom, v.Name, snapshotName, expiry, nil)
+			if err != nil {
+				logCtx["err"] = err
+				logger.Error("Error creating volume snapshot", logCtx)
 			}
 
-			err = s.StoragePoolVolumeSnapshotDelete()
+			ch <- nil
+		}()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ch:
 		}
+	}
 
-		return err
+	return nil
+}
+
+func pruneExpiredCustomVolumeSnapshotsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		expiredSnapshots, err := d.cluster.StoragePoolNodeVolumeSnapshotsGetExpired()
+		if err != nil {
+			logger.Error("Failed to load expired volume snapshots", log.Ctx{"err": err})
+			return
+		}
+
+		if len(expiredSnapshots) == 0 {
+			return
+		}
+
+		opRun := func(op *operations.Operation) error {
+			return pruneExpiredCustomVolumeSnapshots(ctx, d, expiredSnapshots)
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationCustomVolumeSnapshotsExpire, nil, nil, opRun, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start expired volume snapshots operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Pruning expired volume snapshots")
+
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to remove expired volume snapshots", log.Ctx{"err": err})
+		}
+
+		logger.Info("Done pruning expired volume snapshots")
 	}
 
-	resources := map[string][]string{}
-	resources["storage_volume_snapshots"] = []string{volumeName}
+	first := true
+	schedule := func() (time.Duration, error) {
+		interval := time.Minute
 
-	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationVolumeSnapshotDelete, resources, nil, snapshotDelete, nil, nil)
-	if err != nil {
-		return response.InternalError(err)
+		if first {
+			first = false
+			return interval, task.ErrSkip
+		}
+
+		return interval, nil
 	}
 
-	return operations.OperationResponse(op)
+	return f, schedule
+}
+
+func pruneExpiredCustomVolumeSnapshots(ctx context.Context, d *Daemon, snapshots []db.StorageVolumeArgs) error {
+	for _, snapshot := range snapshots {
+		volumeName, snapshotName, _ := shared.InstanceGetParentAndSnapshotName(snapshot.Name)
+
+		err := storagePoolVolumeSnapshotDelete(d.State(), snapshot.ProjectName, snapshot.PoolName, volumeName, snapshotName, nil)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to delete expired volume snapshot '%s' in project '%s'", snapshot.Name, snapshot.ProjectName)
+		}
+	}
+
+	return nil
 }
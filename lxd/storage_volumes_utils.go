@@ -6,8 +6,11 @@ import (
 	"strings"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	projecthelpers "github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/version"
@@ -76,6 +79,17 @@ func storagePoolVolumeTypeToAPIEndpoint(volumeType int) (string, error) {
 	return "", fmt.Errorf("invalid storage volume type")
 }
 
+// storagePoolVolumeLegacyProjectCheck returns an error if custom volumes of a
+// project other than "default" are requested on a storage pool which still
+// relies on the legacy storage layer.
+func storagePoolVolumeLegacyProjectCheck(projectName string, poolName string) error {
+	if projectName != "default" {
+		return fmt.Errorf("Storage pool %q doesn't support custom volumes in projects", poolName)
+	}
+
+	return nil
+}
+
 func storagePoolVolumeRestore(state *state.State, poolName string, volumeName string, volumeType int, snapshotName string) error {
 	s, err := storagePoolVolumeInit(state, "default", poolName,
 		fmt.Sprintf("%s/%s", volumeName, snapshotName), volumeType)
@@ -178,7 +192,7 @@ func storagePoolVolumeUpdate(state *state.State, poolName string, volumeName str
 
 	// Confirm that no containers are running when changing shifted state
 	if newConfig["security.shifted"] != oldConfig["security.shifted"] {
-		ctsUsingVolume, err := storagePoolVolumeUsedByRunningInstancesWithProfilesGet(state, poolName, volumeName, storagePoolVolumeTypeNameCustom, true)
+		ctsUsingVolume, err := storagePoolVolumeUsedByRunningInstancesWithProfilesGet(state, "default", poolName, volumeName, storagePoolVolumeTypeNameCustom, true)
 		if err != nil {
 			return err
 		}
@@ -202,7 +216,7 @@ func storagePoolVolumeUpdate(state *state.State, poolName string, volumeName str
 
 	// Update the database if something changed
 	if len(changedConfig) != 0 || newDescription != oldDescription {
-		err = state.Cluster.StoragePoolVolumeUpdate(volumeName, volumeType, poolID, newDescription, newConfig)
+		err = state.Cluster.StoragePoolVolumeUpdate("default", volumeName, volumeType, poolID, newDescription, newConfig)
 		if err != nil {
 			return err
 		}
@@ -214,21 +228,56 @@ func storagePoolVolumeUpdate(state *state.State, poolName string, volumeName str
 	return nil
 }
 
-func storagePoolVolumeUsedByInstancesGet(s *state.State, project, poolName string, volumeName string) ([]string, error) {
-	insts, err := instanceLoadByProject(s, project)
+// storagePoolVolumeProjectGetter returns a function resolving the project
+// holding the custom volumes of a given instance project. Results are cached
+// so that it can be called for every instance in a loop.
+func storagePoolVolumeProjectGetter(s *state.State) func(projectName string) (string, error) {
+	cache := map[string]string{}
+
+	return func(projectName string) (string, error) {
+		volumeProject, ok := cache[projectName]
+		if ok {
+			return volumeProject, nil
+		}
+
+		volumeProject, err := projecthelpers.StorageVolumeProject(s.Cluster, projectName, db.StoragePoolVolumeTypeCustom)
+		if err != nil {
+			return "", err
+		}
+
+		cache[projectName] = volumeProject
+		return volumeProject, nil
+	}
+}
+
+// storagePoolVolumeUsedByInstancesGet returns the instances which have the
+// given custom volume of the given project attached as a local device.
+func storagePoolVolumeUsedByInstancesGet(s *state.State, project, poolName string, volumeName string) ([]instance.Instance, error) {
+	insts, err := instanceLoadAll(s)
 	if err != nil {
-		return []string{}, err
+		return nil, err
 	}
 
-	instUsingVolume := []string{}
+	volumeProject := storagePoolVolumeProjectGetter(s)
+
+	instUsingVolume := []instance.Instance{}
 	for _, inst := range insts {
+		instVolumeProject, err := volumeProject(inst.Project())
+		if err != nil {
+			return nil, err
+		}
+
+		if instVolumeProject != project {
+			continue
+		}
+
 		for _, dev := range inst.LocalDevices() {
 			if dev["type"] != "disk" {
 				continue
 			}
 
 			if dev["pool"] == poolName && dev["source"] == volumeName {
-				instUsingVolume = append(instUsingVolume, inst.Name())
+				instUsingVolume = append(instUsingVolume, inst)
 				break
 			}
 		}
@@ -237,7 +286,7 @@ func storagePoolVolumeUsedByInstancesGet(s *state.State, project, poolName strin
 	return instUsingVolume, nil
 }
 
-func storagePoolVolumeUpdateUsers(d *Daemon, oldPoolName string,
+func storagePoolVolumeUpdateUsers(d *Daemon, projectName string, oldPoolName string,
 	oldVolumeName string, newPoolName string, newVolumeName string) error {
 
 	s := d.State()
@@ -247,7 +296,18 @@ func storagePoolVolumeUpdateUsers(d *Daemon, oldPoolName string,
 		return err
 	}
 
+	volumeProject := storagePoolVolumeProjectGetter(s)
+
 	for _, inst := range insts {
+		instVolumeProject, err := volumeProject(inst.Project())
+		if err != nil {
+			return err
+		}
+
+		if instVolumeProject != projectName {
+			continue
+		}
+
 		devices := inst.LocalDevices()
 		for k := range devices {
 			if devices[k]["type"] != "disk" {
@@ -307,13 +367,13 @@ func storagePoolVolumeUpdateUsers(d *Daemon, oldPoolName string,
 	}
 
 	// update all profiles
-	profiles, err := s.Cluster.Profiles("default")
+	profiles, err := s.Cluster.Profiles(projectName)
 	if err != nil {
 		return err
 	}
 
 	for _, pName := range profiles {
-		id, profile, err := s.Cluster.ProfileGet("default", pName)
+		id, profile, err := s.Cluster.ProfileGet(projectName, pName)
 		if err != nil {
 			return err
 		}
@@ -362,7 +422,7 @@ func storagePoolVolumeUpdateUsers(d *Daemon, oldPoolName string,
 		pUpdate.Config = profile.Config
 		pUpdate.Description = profile.Description
 		pUpdate.Devices = profile.Devices
-		err = doProfileUpdate(d, "default", pName, id, profile, pUpdate)
+		err = doProfileUpdate(d, projectName, pName, id, profile, pUpdate)
 		if err != nil {
 			return err
 		}
@@ -372,13 +432,15 @@ func storagePoolVolumeUpdateUsers(d *Daemon, oldPoolName string,
 }
 
 func storagePoolVolumeUsedByRunningInstancesWithProfilesGet(s *state.State,
-	poolName string, volumeName string, volumeTypeName string,
+	projectName string, poolName string, volumeName string, volumeTypeName string,
 	runningOnly bool) ([]string, error) {
 	insts, err := instanceLoadAll(s)
 	if err != nil {
 		return []string{}, err
 	}
 
+	volumeProject := storagePoolVolumeProjectGetter(s)
+
 	instUsingVolume := []string{}
 	volumeNameWithType := fmt.Sprintf("%s/%s", volumeTypeName, volumeName)
 	for _, inst := range insts {
@@ -386,6 +448,15 @@ func storagePoolVolumeUsedByRunningInstancesWithProfilesGet(s *state.State,
 			continue
 		}
 
+		instVolumeProject, err := volumeProject(inst.Project())
+		if err != nil {
+			return []string{}, err
+		}
+
+		if instVolumeProject != projectName {
+			continue
+		}
+
 		for _, dev := range inst.ExpandedDevices() {
 			if dev["type"] != "disk" {
 				continue
@@ -425,14 +496,16 @@ func storagePoolVolumeUsedByGet(s *state.State, project, poolName string, volume
 		return []string{fmt.Sprintf("/%s/images/%s", version.APIVersion, volumeName)}, nil
 	}
 
-	// Check if the daemon itself is using it
-	used, err := daemonStorageUsed(s, poolName, volumeName)
-	if err != nil {
-		return []string{}, err
-	}
+	// Check if the daemon itself is using it (only volumes of the default project can be)
+	if project == "default" {
+		used, err := daemonStorageUsed(s, poolName, volumeName)
+		if err != nil {
+			return []string{}, err
+		}
 
-	if used {
-		return []string{fmt.Sprintf("/%s", version.APIVersion)}, nil
+		if used {
+			return []string{fmt.Sprintf("/%s", version.APIVersion)}, nil
+		}
 	}
 
 	// Look for containers using this volume
@@ -443,11 +516,15 @@ func storagePoolVolumeUsedByGet(s *state.State, project, poolName string, volume
 
 	volumeUsedBy := []string{}
 	for _, ct := range ctsUsingVolume {
-		volumeUsedBy = append(volumeUsedBy,
-			fmt.Sprintf("/%s/containers/%s", version.APIVersion, ct))
+		uri := fmt.Sprintf("/%s/containers/%s", version.APIVersion, ct.Name())
+		if ct.Project() != "default" {
+			uri += fmt.Sprintf("?project=%s", ct.Project())
+		}
+
+		volumeUsedBy = append(volumeUsedBy, uri)
 	}
 
-	profiles, err := profilesUsingPoolVolumeGetNames(s.Cluster, volumeName, volumeTypeName)
+	profiles, err := profilesUsingPoolVolumeGetNames(s.Cluster, project, volumeName, volumeTypeName)
 	if err != nil {
 		return []string{}, err
 	}
@@ -457,22 +534,27 @@ func storagePoolVolumeUsedByGet(s *state.State, project, poolName string, volume
 	}
 
 	for _, pName := range profiles {
-		volumeUsedBy = append(volumeUsedBy, fmt.Sprintf("/%s/profiles/%s", version.APIVersion, pName))
+		uri := fmt.Sprintf("/%s/profiles/%s", version.APIVersion, pName)
+		if project != "default" {
+			uri += fmt.Sprintf("?project=%s", project)
+		}
+
+		volumeUsedBy = append(volumeUsedBy, uri)
 	}
 
 	return volumeUsedBy, nil
 }
 
-func profilesUsingPoolVolumeGetNames(db *db.Cluster, volumeName string, volumeType string) ([]string, error) {
+func profilesUsingPoolVolumeGetNames(db *db.Cluster, project string, volumeName string, volumeType string) ([]string, error) {
 	usedBy := []string{}
 
-	profiles, err := db.Profiles("default")
+	profiles, err := db.Profiles(project)
 	if err != nil {
 		return usedBy, err
 	}
 
 	for _, pName := range profiles {
-		_, profile, err := db.ProfileGet("default", pName)
+		_, profile, err := db.ProfileGet(project, pName)
 		if err != nil {
 			return usedBy, err
 		}
@@ -526,7 +608,7 @@ func storagePoolVolumeDBCreateInternal(state *state.State, poolName string, vol
 	}
 
 	// Create database entry for new storage volume.
-	err := storagePools.VolumeDBCreate(state, poolName, volumeName, volumeDescription, volumeTypeName, false, volumeConfig)
+	err := storagePools.VolumeDBCreate(state, "default", poolName, volumeName, volumeDescription, volumeTypeName, false, volumeConfig, storageDrivers.ContentTypeFS)
 	if err != nil {
 		return nil, err
 	}
@@ -573,7 +655,7 @@ func storagePoolVolumeCreateInternal(state *state.State, poolName string, vol *a
 		err = s.StoragePoolVolumeCreate()
 	} else {
 		if !vol.Source.VolumeOnly {
-			snapshots, err := storagePools.VolumeSnapshotsGet(state, vol.Source.Pool, vol.Source.Name, volumeType)
+			snapshots, err := storagePools.VolumeSnapshotsGet(state, "default", vol.Source.Pool, vol.Source.Name, volumeType)
 			if err != nil {
 				return err
 			}
@@ -635,7 +717,7 @@ func storagePoolVolumeSnapshotCopyInternal(state *state.State, poolName string,
 
 func storagePoolVolumeSnapshotDBCreateInternal(state *state.State, dbArgs *db.StorageVolumeArgs) (storage, error) {
 	// Create database entry for new storage volume.
-	err := storagePools.VolumeDBCreate(state, dbArgs.PoolName, dbArgs.Name, dbArgs.Description, dbArgs.TypeName, true, dbArgs.Config)
+	err := storagePools.VolumeDBCreate(state, "default", dbArgs.PoolName, dbArgs.Name, dbArgs.Description, dbArgs.TypeName, true, dbArgs.Config, storageDrivers.ContentTypeFS)
 	if err != nil {
 		return nil, err
 	}
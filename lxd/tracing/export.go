@@ -0,0 +1,277 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Maximum delay before the finished spans are exported, and maximum time
+// given to the collector to acknowledge them.
+const (
+	exportDelay   = 2 * time.Second
+	exportTimeout = 10 * time.Second
+)
+
+// Maximum number of finished spans waiting to be exported, the others being
+// dropped if the collector can't keep up.
+const exportQueueSize = 2048
+
+// exporter posts the finished spans to the collector in batches, using the
+// JSON encoding of OTLP/HTTP.
+type exporter struct {
+	url      string
+	resource []otlpAttribute
+	client   *http.Client
+
+	pending []*span
+	stopped bool
+	lock    sync.Mutex
+
+	done     chan struct{}
+	finished chan struct{}
+}
+
+func newExporter(url string, member string) *exporter {
+	e := &exporter{
+		url: url,
+		resource: otlpAttributes([]Attribute{
+			String("service.name", "lxd"),
+			String("service.version", version.Version),
+			String("service.instance.id", member),
+		}),
+		client:   &http.Client{Timeout: exportTimeout},
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go e.run()
+
+	return e
+}
+
+// Queue a finished span for export.
+func (e *exporter) add(s *span) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.stopped || len(e.pending) >= exportQueueSize {
+		return
+	}
+
+	e.pending = append(e.pending, s)
+}
+
+func (e *exporter) run() {
+	ticker := time.NewTicker(exportDelay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.export()
+		case <-e.done:
+			e.export()
+			close(e.finished)
+			return
+		}
+	}
+}
+
+// Export the pending spans and stop, the spans ended later being dropped.
+func (e *exporter) shutdown() {
+	e.lock.Lock()
+	if e.stopped {
+		e.lock.Unlock()
+		return
+	}
+
+	e.stopped = true
+	e.lock.Unlock()
+
+	close(e.done)
+	<-e.finished
+}
+
+func (e *exporter) export() {
+	e.lock.Lock()
+	spans := e.pending
+	e.pending = nil
+	e.lock.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+
+	err := e.post(spans)
+	if err != nil {
+		logger.Warnf("Failed to export %d traces: %v", len(spans), err)
+	}
+}
+
+func (e *exporter) post(spans []*span) error {
+	body := otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: e.resource},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "lxd", Version: version.Version},
+				Spans: make([]otlpSpan, 0, len(spans)),
+			}},
+		}},
+	}
+
+	scope := &body.ResourceSpans[0].ScopeSpans[0]
+	for _, s := range spans {
+		scope.Spans = append(scope.Spans, s.otlp())
+	}
+
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Collector returned %q", resp.Status)
+	}
+
+	return nil
+}
+
+// The subset of the OTLP/JSON trace messages used by LXD.
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// 64 bits integers are encoded as strings in OTLP/JSON.
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+// The OTLP span kind and status codes.
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeError  = 2
+)
+
+func otlpAttributes(attrs []Attribute) []otlpAttribute {
+	result := make([]otlpAttribute, 0, len(attrs))
+	for _, attr := range attrs {
+		value := otlpValue{}
+
+		switch v := attr.Value.(type) {
+		case int64:
+			i := strconv.FormatInt(v, 10)
+			value.IntValue = &i
+		case bool:
+			b := v
+			value.BoolValue = &b
+		default:
+			str := fmt.Sprintf("%v", v)
+			value.StringValue = &str
+		}
+
+		result = append(result, otlpAttribute{Key: attr.Key, Value: value})
+	}
+
+	return result
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// Return the OTLP representation of an ended span, its failure being also
+// recorded as an exception event.
+func (s *span) otlp() otlpSpan {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := otlpSpan{
+		TraceID:           hex.EncodeToString(s.context.traceID[:]),
+		SpanID:            hex.EncodeToString(s.context.spanID[:]),
+		Name:              s.name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: otlpTime(s.start),
+		EndTimeUnixNano:   otlpTime(s.end),
+		Attributes:        otlpAttributes(s.attrs),
+	}
+
+	if s.parentID != [8]byte{} {
+		result.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+
+	if s.err != nil {
+		result.Status = otlpStatus{Code: otlpStatusCodeError, Message: s.err.Error()}
+		result.Events = []otlpEvent{{
+			TimeUnixNano: otlpTime(s.end),
+			Name:         "exception",
+			Attributes:   otlpAttributes([]Attribute{String("exception.message", s.err.Error())}),
+		}}
+	}
+
+	return result
+}
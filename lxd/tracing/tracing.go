@@ -0,0 +1,304 @@
+// Package tracing exports traces of the API requests, and of the work they
+// trigger, to an OpenTelemetry collector over OTLP/HTTP.
+//
+// Only the subset of OpenTelemetry needed by LXD is implemented here, on top
+// of the standard library, so that the rest of the daemon doesn't depend on
+// the OpenTelemetry SDK.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Attribute is a key/value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Span is a timed piece of work, part of a trace.
+type Span interface {
+	// SetAttributes adds the given attributes to the span.
+	SetAttributes(attrs ...Attribute)
+
+	// SetError marks the span as failed with the given error.
+	SetError(err error)
+
+	// IsRecording returns whether the span is exported once ended.
+	IsRecording() bool
+
+	// End ends the span.
+	End()
+}
+
+// The identifiers of a span, as carried across cluster members by the W3C
+// trace context headers.
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	remote  bool
+}
+
+func (sc spanContext) isValid() bool {
+	return sc.traceID != [16]byte{} && sc.spanID != [8]byte{}
+}
+
+type spanContextKey struct{}
+
+func spanContextFromContext(ctx context.Context) spanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(spanContext)
+	return sc
+}
+
+func contextWithSpanContext(ctx context.Context, sc spanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// A span doing nothing, used when tracing is disabled.
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attrs ...Attribute) {}
+func (noopSpan) SetError(err error)               {}
+func (noopSpan) IsRecording() bool                { return false }
+func (noopSpan) End()                             {}
+
+// A span recorded for export.
+type span struct {
+	exporter *exporter
+	name     string
+	context  spanContext
+	parentID [8]byte
+	start    time.Time
+	end      time.Time
+	attrs    []Attribute
+	err      error
+	ended    bool
+	lock     sync.Mutex
+}
+
+func (s *span) SetAttributes(attrs ...Attribute) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attrs = append(s.attrs, attrs...)
+}
+
+func (s *span) SetError(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.err = err
+}
+
+func (s *span) IsRecording() bool {
+	return true
+}
+
+func (s *span) End() {
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+
+	s.ended = true
+	s.end = time.Now()
+	s.lock.Unlock()
+
+	s.exporter.add(s)
+}
+
+var currentExporter *exporter
+var exporterLock sync.RWMutex
+
+// ValidateEndpoint checks that the given value is a valid collector URL.
+func ValidateEndpoint(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	_, err := exportURL(value)
+	return err
+}
+
+// Return the URL the spans are posted to, /v1/traces being the default path.
+func exportURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("Invalid tracing endpoint %q, expected a HTTP(S) URL", endpoint)
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+
+	return u.String(), nil
+}
+
+// Configure exports the spans to the collector at the given URL, tracing
+// being disabled if empty. The member name identifies the spans of this
+// server.
+func Configure(endpoint string, member string) error {
+	var e *exporter
+	if endpoint != "" {
+		u, err := exportURL(endpoint)
+		if err != nil {
+			return err
+		}
+
+		e = newExporter(u, member)
+	}
+
+	exporterLock.Lock()
+	old := currentExporter
+	currentExporter = e
+	exporterLock.Unlock()
+
+	if old != nil {
+		old.shutdown()
+	}
+
+	return nil
+}
+
+// Shutdown exports the pending spans and disables tracing.
+func Shutdown() {
+	exporterLock.Lock()
+	old := currentExporter
+	currentExporter = nil
+	exporterLock.Unlock()
+
+	if old != nil {
+		old.shutdown()
+	}
+}
+
+// Enabled returns whether the spans are exported.
+func Enabled() bool {
+	exporterLock.RLock()
+	defer exporterLock.RUnlock()
+
+	return currentExporter != nil
+}
+
+// Start starts a new span, child of the span of the given context if any.
+// The returned span does nothing if tracing is disabled.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	exporterLock.RLock()
+	e := currentExporter
+	exporterLock.RUnlock()
+
+	if e == nil {
+		return ctx, noopSpan{}
+	}
+
+	s := &span{
+		exporter: e,
+		name:     name,
+		start:    time.Now(),
+		attrs:    attrs,
+	}
+
+	parent := spanContextFromContext(ctx)
+	if parent.isValid() {
+		s.context.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		rand.Read(s.context.traceID[:])
+	}
+
+	rand.Read(s.context.spanID[:])
+
+	return contextWithSpanContext(ctx, s.context), s
+}
+
+// StartChild starts a new span only if the given context has one, so that
+// frequent background work doesn't create a trace each time.
+func StartChild(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	if !spanContextFromContext(ctx).isValid() {
+		return ctx, noopSpan{}
+	}
+
+	return Start(ctx, name, attrs...)
+}
+
+// End ends the given span, marking it as failed if an error is given.
+func End(span Span, err error) {
+	if err != nil {
+		span.SetError(err)
+	}
+
+	span.End()
+}
+
+// Extract returns a context holding the span received in the given headers,
+// if any.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	// The header is version-traceid-spanid-flags, only version 00 being
+	// defined so far.
+	fields := strings.Split(strings.TrimSpace(header.Get("traceparent")), "-")
+	if len(fields) < 4 || len(fields[0]) != 2 || fields[0] == "ff" || (fields[0] == "00" && len(fields) != 4) {
+		return ctx
+	}
+
+	var sc spanContext
+	traceID, err := hex.DecodeString(fields[1])
+	if err != nil || len(traceID) != len(sc.traceID) {
+		return ctx
+	}
+
+	spanID, err := hex.DecodeString(fields[2])
+	if err != nil || len(spanID) != len(sc.spanID) {
+		return ctx
+	}
+
+	copy(sc.traceID[:], traceID)
+	copy(sc.spanID[:], spanID)
+	sc.remote = true
+
+	if !sc.isValid() {
+		return ctx
+	}
+
+	return contextWithSpanContext(ctx, sc)
+}
+
+// Inject adds the span of the given context to the given headers.
+func Inject(ctx context.Context, header http.Header) {
+	sc := spanContextFromContext(ctx)
+	if !sc.isValid() {
+		return
+	}
+
+	// Only the recorded spans are propagated, so they're always sampled.
+	header.Set("traceparent", fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(sc.traceID[:]), hex.EncodeToString(sc.spanID[:])))
+}
+
+// Detach returns a context holding the span of the given context, but none of
+// its values or its cancellation, for work outliving a request.
+func Detach(ctx context.Context) context.Context {
+	sc := spanContextFromContext(ctx)
+	if !sc.isValid() {
+		return context.Background()
+	}
+
+	return contextWithSpanContext(context.Background(), sc)
+}
@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A collector stand-in recording the bodies of the exports it receives.
+type testCollector struct {
+	server *httptest.Server
+	paths  []string
+	bodies [][]byte
+	mu     sync.Mutex
+}
+
+func newTestCollector() *testCollector {
+	c := &testCollector{}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		c.mu.Lock()
+		c.paths = append(c.paths, r.URL.Path)
+		c.bodies = append(c.bodies, body)
+		c.mu.Unlock()
+	}))
+
+	return c
+}
+
+func TestSpansExported(t *testing.T) {
+	collector := newTestCollector()
+	defer collector.server.Close()
+
+	require.NoError(t, Configure(collector.server.URL, "node1"))
+	assert.True(t, Enabled())
+
+	ctx, span := Start(context.Background(), "POST /1.0/instances")
+	assert.True(t, span.IsRecording())
+
+	_, child := StartChild(ctx, "db.transaction")
+	assert.True(t, child.IsRecording())
+	End(child, fmt.Errorf("Some failure"))
+	End(span, nil)
+
+	// The pending spans are exported on shutdown.
+	Shutdown()
+	assert.False(t, Enabled())
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	require.NotEmpty(t, collector.bodies)
+	body := bytes.Join(collector.bodies, nil)
+	assert.Equal(t, "/v1/traces", collector.paths[0])
+	assert.Contains(t, string(body), "POST /1.0/instances")
+	assert.Contains(t, string(body), "db.transaction")
+	assert.Contains(t, string(body), "Some failure")
+	assert.Contains(t, string(body), "node1")
+
+	// The child span is part of the trace of its parent.
+	traces := otlpTraces{}
+	require.NoError(t, json.Unmarshal(collector.bodies[0], &traces))
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	assert.Equal(t, "db.transaction", spans[0].Name)
+	assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.Equal(t, otlpStatusCodeError, spans[0].Status.Code)
+	assert.Equal(t, 0, spans[1].Status.Code)
+}
+
+func TestSpansDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "GET /1.0")
+	assert.False(t, span.IsRecording())
+	assert.False(t, spanContextFromContext(ctx).isValid())
+	span.End()
+}
+
+func TestStartChildWithoutParent(t *testing.T) {
+	collector := newTestCollector()
+	defer collector.server.Close()
+
+	require.NoError(t, Configure(collector.server.URL, "node1"))
+	defer Shutdown()
+
+	_, span := StartChild(context.Background(), "db.transaction")
+	assert.False(t, span.IsRecording())
+}
+
+func TestPropagation(t *testing.T) {
+	collector := newTestCollector()
+	defer collector.server.Close()
+
+	require.NoError(t, Configure(collector.server.URL, "node1"))
+	defer Shutdown()
+
+	ctx, span := Start(context.Background(), "GET /1.0")
+	defer span.End()
+
+	header := http.Header{}
+	Inject(ctx, header)
+	assert.NotEmpty(t, header.Get("traceparent"))
+
+	// The span is received by the other member, and survives the request.
+	received := Detach(Extract(context.Background(), header))
+	assert.Equal(t, spanContextFromContext(ctx).traceID, spanContextFromContext(received).traceID)
+	assert.True(t, spanContextFromContext(received).remote)
+
+	// Invalid headers are ignored.
+	for _, value := range []string{
+		"",
+		"00-00000000000000000000000000000000-0000000000000001-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	} {
+		header.Set("traceparent", value)
+		assert.False(t, spanContextFromContext(Extract(context.Background(), header)).isValid(), value)
+	}
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	assert.NoError(t, ValidateEndpoint(""))
+	assert.NoError(t, ValidateEndpoint("http://127.0.0.1:4318"))
+	assert.NoError(t, ValidateEndpoint("https://collector.example.com/otlp/v1/traces"))
+	assert.Error(t, ValidateEndpoint("127.0.0.1:4318"))
+	assert.Error(t, ValidateEndpoint("grpc://127.0.0.1:4317"))
+
+	u, err := exportURL("https://collector.example.com/otlp/v1/traces")
+	require.NoError(t, err)
+	assert.Equal(t, "https://collector.example.com/otlp/v1/traces", u)
+}
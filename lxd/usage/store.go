@@ -0,0 +1,189 @@
+// Package usage keeps the recent history of the resource usage of the
+// instances.
+package usage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Usage holds the resource usage of an instance at a point in time, the CPU
+// time, disk and network figures being cumulative counters. The disk figures
+// are -1 if unknown.
+type Usage struct {
+	Time time.Time
+
+	// CPU time in nanoseconds
+	CPUTime int64
+
+	MemoryUsage int64
+	SwapUsage   int64
+
+	DiskRead        int64
+	DiskWritten     int64
+	NetworkReceived int64
+	NetworkSent     int64
+
+	Processes int64
+}
+
+// Store keeps a bounded number of usage samples per instance.
+type Store struct {
+	size   int
+	series map[string]*series
+	mu     sync.Mutex
+}
+
+// The samples of an instance, oldest first, and the usage the next sample
+// is computed against.
+type series struct {
+	project string
+	name    string
+	samples []api.InstanceUsageSample
+	last    *Usage
+}
+
+// NewStore returns a new store keeping up to the given number of samples per
+// instance.
+func NewStore(size int) *Store {
+	return &Store{
+		size:   size,
+		series: map[string]*series{},
+	}
+}
+
+func key(project string, name string) string {
+	return project + "/" + name
+}
+
+// SetSize changes the number of samples kept per instance, dropping the
+// oldest ones if needed.
+func (s *Store) SetSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.size = size
+	for _, series := range s.series {
+		series.trim(size)
+	}
+}
+
+func (ss *series) trim(size int) {
+	if len(ss.samples) <= size {
+		return
+	}
+
+	ss.samples = append([]api.InstanceUsageSample{}, ss.samples[len(ss.samples)-size:]...)
+}
+
+// Record records the given usage of an instance, adding a sample with the
+// usage since the previously recorded one. No sample is added for the first
+// usage recorded or if the counters were reset, as when the instance
+// restarted.
+func (s *Store) Record(project string, name string, usage Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss, ok := s.series[key(project, name)]
+	if !ok {
+		ss = &series{project: project, name: name}
+		s.series[key(project, name)] = ss
+	}
+
+	last := ss.last
+	ss.last = &usage
+
+	if last == nil || usage.CPUTime < last.CPUTime {
+		return
+	}
+
+	elapsed := usage.Time.Sub(last.Time).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	rate := func(current int64, previous int64) int64 {
+		if current < previous {
+			return 0
+		}
+
+		return int64(float64(current-previous) / elapsed)
+	}
+
+	// The rate of counters which aren't known is unknown too.
+	knownRate := func(current int64, previous int64) *int64 {
+		if current < 0 || previous < 0 {
+			return nil
+		}
+
+		value := rate(current, previous)
+		return &value
+	}
+
+	sample := api.InstanceUsageSample{
+		Timestamp:           usage.Time,
+		MemoryUsage:         usage.MemoryUsage,
+		SwapUsage:           usage.SwapUsage,
+		DiskReadRate:        knownRate(usage.DiskRead, last.DiskRead),
+		DiskWriteRate:       knownRate(usage.DiskWritten, last.DiskWritten),
+		NetworkReceiveRate:  rate(usage.NetworkReceived, last.NetworkReceived),
+		NetworkTransmitRate: rate(usage.NetworkSent, last.NetworkSent),
+		Processes:           usage.Processes,
+	}
+
+	if last.CPUTime >= 0 {
+		sample.CPUUsage = float64(usage.CPUTime-last.CPUTime) / (elapsed * float64(time.Second)) * 100
+	}
+
+	ss.samples = append(ss.samples, sample)
+	ss.trim(s.size)
+}
+
+// Reset forgets the last usage recorded for an instance, keeping its
+// samples, for example when it stops.
+func (s *Store) Reset(project string, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss, ok := s.series[key(project, name)]
+	if ok {
+		ss.last = nil
+	}
+}
+
+// Samples returns the samples of an instance taken after the given time,
+// oldest first.
+func (s *Store) Samples(project string, name string, since time.Time) []api.InstanceUsageSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples := []api.InstanceUsageSample{}
+
+	ss, ok := s.series[key(project, name)]
+	if !ok {
+		return samples
+	}
+
+	for _, sample := range ss.samples {
+		if sample.Timestamp.After(since) {
+			samples = append(samples, sample)
+		}
+	}
+
+	return samples
+}
+
+// Retain drops the samples of the instances for which the given function
+// returns false, like deleted ones.
+func (s *Store) Retain(keep func(project string, name string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, ss := range s.series {
+		if !keep(ss.project, ss.name) {
+			delete(s.series, k)
+		}
+	}
+}
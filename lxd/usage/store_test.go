@@ -0,0 +1,83 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreRecord(t *testing.T) {
+	s := NewStore(10)
+	start := time.Now()
+
+	s.Record("default", "c1", Usage{Time: start, CPUTime: 0, DiskRead: 1000, DiskWritten: -1, NetworkSent: 500})
+	assert.Len(t, s.Samples("default", "c1", time.Time{}), 0)
+
+	s.Record("default", "c1", Usage{
+		Time:            start.Add(10 * time.Second),
+		CPUTime:         int64(5 * time.Second),
+		MemoryUsage:     1024,
+		DiskRead:        11000,
+		DiskWritten:     -1,  // Unknown
+		NetworkSent:     100, // Lower than before, the device was replaced
+		NetworkReceived: 2000,
+		Processes:       3,
+	})
+
+	samples := s.Samples("default", "c1", time.Time{})
+	require.Len(t, samples, 1)
+	assert.Equal(t, 50.0, samples[0].CPUUsage)
+	assert.Equal(t, int64(1024), samples[0].MemoryUsage)
+	require.NotNil(t, samples[0].DiskReadRate)
+	assert.Equal(t, int64(1000), *samples[0].DiskReadRate)
+	assert.Nil(t, samples[0].DiskWriteRate)
+	assert.Equal(t, int64(200), samples[0].NetworkReceiveRate)
+	assert.Equal(t, int64(0), samples[0].NetworkTransmitRate)
+	assert.Equal(t, int64(3), samples[0].Processes)
+
+	// Samples of other instances are kept apart.
+	assert.Len(t, s.Samples("other", "c1", time.Time{}), 0)
+}
+
+func TestStoreRestart(t *testing.T) {
+	s := NewStore(10)
+	start := time.Now()
+
+	s.Record("default", "c1", Usage{Time: start, CPUTime: int64(time.Minute)})
+
+	// Counters going backwards start a new baseline.
+	s.Record("default", "c1", Usage{Time: start.Add(time.Second), CPUTime: 0})
+	assert.Len(t, s.Samples("default", "c1", time.Time{}), 0)
+
+	s.Record("default", "c1", Usage{Time: start.Add(2 * time.Second), CPUTime: int64(time.Second)})
+	assert.Len(t, s.Samples("default", "c1", time.Time{}), 1)
+
+	// No sample is computed across a reset.
+	s.Reset("default", "c1")
+	s.Record("default", "c1", Usage{Time: start.Add(3 * time.Second), CPUTime: int64(2 * time.Second)})
+	assert.Len(t, s.Samples("default", "c1", time.Time{}), 1)
+}
+
+func TestStoreBounded(t *testing.T) {
+	s := NewStore(3)
+	start := time.Now()
+
+	for i := 0; i < 6; i++ {
+		s.Record("default", "c1", Usage{Time: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	samples := s.Samples("default", "c1", time.Time{})
+	require.Len(t, samples, 3)
+	assert.Equal(t, start.Add(3*time.Second), samples[0].Timestamp)
+	assert.Equal(t, start.Add(5*time.Second), samples[2].Timestamp)
+
+	assert.Len(t, s.Samples("default", "c1", start.Add(4*time.Second)), 1)
+
+	s.SetSize(1)
+	assert.Len(t, s.Samples("default", "c1", time.Time{}), 1)
+
+	s.Retain(func(project string, name string) bool { return name != "c1" })
+	assert.Len(t, s.Samples("default", "c1", time.Time{}), 0)
+}
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// ImageSign produces a base64 encoded detached signature of the image with the
+// given fingerprint.
+//
+// As the fingerprint already is the SHA256 digest of the image content, it is
+// signed directly rather than hashing the image files a second time.
+func ImageSign(signer crypto.Signer, fingerprint string) (string, error) {
+	digest, err := hex.DecodeString(fingerprint)
+	if err != nil || len(digest) != crypto.SHA256.Size() {
+		return "", fmt.Errorf("Invalid image fingerprint %q", fingerprint)
+	}
+
+	signature, err := signer.Sign(rand.Reader, digest, crypto.SHA256)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to sign image")
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// ImageVerifySignature checks that the given base64 encoded signature of the
+// image with the given fingerprint was produced by one of the given keys.
+func ImageVerifySignature(keys []crypto.PublicKey, fingerprint string, signature string) error {
+	if signature == "" {
+		return fmt.Errorf("Image %s isn't signed", fingerprint)
+	}
+
+	digest, err := hex.DecodeString(fingerprint)
+	if err != nil || len(digest) != crypto.SHA256.Size() {
+		return fmt.Errorf("Invalid image fingerprint %q", fingerprint)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(err, "Invalid image signature")
+	}
+
+	for _, key := range keys {
+		switch pub := key.(type) {
+		case *ecdsa.PublicKey:
+			var sig struct {
+				R, S *big.Int
+			}
+
+			_, err := asn1.Unmarshal(raw, &sig)
+			if err != nil {
+				continue
+			}
+
+			if ecdsa.Verify(pub, digest, sig.R, sig.S) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, raw) == nil {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("Image %s isn't signed by a trusted key", fingerprint)
+}
+
+// ParseTrustedKeys parses a list of PEM encoded certificates or public keys,
+// as found in the "images.trusted_keys" configuration key.
+func ParseTrustedKeys(value string) ([]crypto.PublicKey, error) {
+	keys := []crypto.PublicKey{}
+
+	rest := []byte(value)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		var key crypto.PublicKey
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "Invalid certificate")
+			}
+
+			key = cert.PublicKey
+		case "PUBLIC KEY":
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "Invalid public key")
+			}
+
+			key = pub
+		default:
+			return nil, fmt.Errorf("Unsupported PEM block type %q", block.Type)
+		}
+
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey:
+		default:
+			return nil, fmt.Errorf("Unsupported key type %T", key)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 && len(bytes.TrimSpace(rest)) > 0 {
+		return nil, fmt.Errorf("No PEM encoded key found")
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,64 @@
+package util_test
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFingerprint = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestImageSignature(t *testing.T) {
+	cert := shared.TestingKeyPair()
+	signer := cert.KeyPair().PrivateKey.(crypto.Signer)
+
+	signature, err := util.ImageSign(signer, testFingerprint)
+	require.NoError(t, err)
+
+	keys, err := util.ParseTrustedKeys(string(cert.PublicKey()))
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	// Signed by a trusted key
+	assert.NoError(t, util.ImageVerifySignature(keys, testFingerprint, signature))
+
+	// Signature of another image
+	other := "0000000000000000000000000000000000000000000000000000000000000000"
+	assert.Error(t, util.ImageVerifySignature(keys, other, signature))
+
+	// Unsigned image
+	assert.Error(t, util.ImageVerifySignature(keys, testFingerprint, ""))
+
+	// Signed by an untrusted key
+	altKeys, err := util.ParseTrustedKeys(string(shared.TestingAltKeyPair().PublicKey()))
+	require.NoError(t, err)
+	assert.Error(t, util.ImageVerifySignature(altKeys, testFingerprint, signature))
+}
+
+func TestParseTrustedKeys(t *testing.T) {
+	cert := shared.TestingKeyPair()
+
+	der, err := x509.MarshalPKIXPublicKey(cert.KeyPair().PrivateKey.(crypto.Signer).Public())
+	require.NoError(t, err)
+	pub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	keys, err := util.ParseTrustedKeys(string(cert.PublicKey()) + string(pub))
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	keys, err = util.ParseTrustedKeys("")
+	require.NoError(t, err)
+	assert.Len(t, keys, 0)
+
+	_, err = util.ParseTrustedKeys("not a key")
+	assert.Error(t, err)
+
+	_, err = util.ParseTrustedKeys(string(cert.PrivateKey()))
+	assert.Error(t, err)
+}
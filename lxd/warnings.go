@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/sys"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var warningsCmd = APIEndpoint{
+	Path: "warnings",
+
+	Get: APIEndpointAction{Handler: warningsGet},
+}
+
+var warningCmd = APIEndpoint{
+	Path: "warnings/{uuid}",
+
+	Delete: APIEndpointAction{Handler: warningDelete},
+	Get:    APIEndpointAction{Handler: warningGet},
+	Patch:  APIEndpointAction{Handler: warningPut},
+	Put:    APIEndpointAction{Handler: warningPut},
+}
+
+func warningsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	// Only filter by project if one was explicitly requested.
+	project := r.FormValue("project")
+
+	var dbWarnings []db.Warning
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		if project != "" {
+			dbWarnings, err = tx.WarningsByProject(project)
+		} else {
+			dbWarnings, err = tx.Warnings()
+		}
+
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, warning := range dbWarnings {
+			urls = append(urls, fmt.Sprintf("/%s/warnings/%s", version.APIVersion, warning.UUID))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	warnings := []api.Warning{}
+	for _, warning := range dbWarnings {
+		warnings = append(warnings, warningToAPI(warning))
+	}
+
+	return response.SyncResponse(true, warnings)
+}
+
+func warningGet(d *Daemon, r *http.Request) response.Response {
+	warning, err := doWarningGet(d.cluster, mux.Vars(r)["uuid"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, warning, warning)
+}
+
+func doWarningGet(cluster *db.Cluster, uuid string) (api.Warning, error) {
+	var warning db.Warning
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		warning, err = tx.WarningByUUID(uuid)
+		return err
+	})
+	if err != nil {
+		return api.Warning{}, err
+	}
+
+	return warningToAPI(warning), nil
+}
+
+func warningPut(d *Daemon, r *http.Request) response.Response {
+	uuid := mux.Vars(r)["uuid"]
+
+	oldEntry, err := doWarningGet(d.cluster, uuid)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = util.EtagCheck(r, oldEntry)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.WarningPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Resolving is left to the server, once the problem is gone.
+	status, err := db.WarningNameToStatus(req.Status)
+	if err != nil || status == db.WarningStatusResolved {
+		return response.BadRequest(fmt.Errorf("Status must be one of: new, acknowledged"))
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.WarningUpdateStatus(uuid, status)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func warningDelete(d *Daemon, r *http.Request) response.Response {
+	uuid := mux.Vars(r)["uuid"]
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.WarningRemove(uuid)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func warningToAPI(warning db.Warning) api.Warning {
+	status, _ := db.WarningStatusToName(warning.Status)
+
+	return api.Warning{
+		WarningPut: api.WarningPut{
+			Status: status,
+		},
+		UUID:        warning.UUID,
+		Location:    warning.Node,
+		Project:     warning.Project,
+		Type:        warning.Type.String(),
+		Count:       warning.Count,
+		FirstSeenAt: warning.FirstSeenAt,
+		LastSeenAt:  warning.LastSeenAt,
+		LastMessage: warning.LastMessage,
+		EntityURL:   warning.EntityURL,
+	}
+}
+
+// warningCreate records an occurrence of the given problem on this node.
+// Failures are only logged, as warnings are informational.
+func warningCreate(s *state.State, project string, entityURL string, typ db.WarningType, message string) {
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.WarningUpsert(project, entityURL, typ, message)
+	})
+	if err != nil {
+		logger.Warn("Failed to record warning", log.Ctx{"type": typ.String(), "entity": entityURL, "err": err})
+	}
+}
+
+// warningResolve marks the given problem as resolved on this node.
+func warningResolve(s *state.State, project string, entityURL string, typ db.WarningType) {
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.WarningResolve(project, entityURL, typ)
+	})
+	if err != nil {
+		logger.Warn("Failed to resolve warning", log.Ctx{"type": typ.String(), "entity": entityURL, "err": err})
+	}
+}
+
+// systemWarnings lists the problems with the host system detected at startup,
+// each with a check telling whether the problem is present.
+var systemWarnings = []struct {
+	typ     db.WarningType
+	missing func(o *sys.OS) bool
+	message string
+}{
+	{db.WarningCGroupBlkio, func(o *sys.OS) bool { return !o.CGroupBlkioController }, "I/O limits will be ignored"},
+	{db.WarningCGroupBlkioWeight, func(o *sys.OS) bool { return !o.CGroupBlkioWeightController }, "I/O weight limits will be ignored"},
+	{db.WarningCGroupCPUController, func(o *sys.OS) bool { return !o.CGroupCPUController }, "CPU time limits will be ignored"},
+	{db.WarningCGroupCPUacctController, func(o *sys.OS) bool { return !o.CGroupCPUacctController }, "CPU accounting will not be available"},
+	{db.WarningCGroupCPUsetController, func(o *sys.OS) bool { return !o.CGroupCPUsetController }, "CPU pinning will be ignored"},
+	{db.WarningCGroupDevicesController, func(o *sys.OS) bool { return !o.CGroupDevicesController }, "device access control won't work"},
+	{db.WarningCGroupFreezerController, func(o *sys.OS) bool { return !o.CGroupFreezerController }, "pausing/resuming containers won't work"},
+	{db.WarningCGroupMemoryController, func(o *sys.OS) bool { return !o.CGroupMemoryController }, "memory limits will be ignored"},
+	{db.WarningCGroupNetPrioController, func(o *sys.OS) bool { return !o.CGroupNetPrioController }, "network limits will be ignored"},
+	{db.WarningCGroupPidsController, func(o *sys.OS) bool { return !o.CGroupPidsController }, "process limits will be ignored"},
+	{db.WarningCGroupSwapAccounting, func(o *sys.OS) bool { return !o.CGroupSwapAccounting }, "swap limits will be ignored"},
+	{db.WarningAppArmorNotAvailable, func(o *sys.OS) bool { return !o.AppArmorAvailable }, "AppArmor confinement of instances won't work"},
+	{db.WarningMissingUeventInjection, func(o *sys.OS) bool { return !o.UeventInjection }, "hotplugging of USB devices into containers won't work"},
+	{db.WarningMissingSeccompListener, func(o *sys.OS) bool { return !o.SeccompListener }, "system call interception won't work"},
+	{db.WarningMissingVFS3Fscaps, func(o *sys.OS) bool { return !o.VFS3Fscaps }, "file capabilities in unprivileged containers won't work"},
+}
+
+// warningsCheckSystem records the problems with the host system and resolves
+// the ones which are gone since the last start.
+func warningsCheckSystem(s *state.State) {
+	if s.OS.MockMode {
+		return
+	}
+
+	for _, warning := range systemWarnings {
+		if warning.missing(s.OS) {
+			warningCreate(s, "", "", warning.typ, warning.message)
+		} else {
+			warningResolve(s, "", "", warning.typ)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+// Package webhook sends the lifecycle events to the endpoints configured by
+// the administrator.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Outcomes of a delivery, once all the attempts were made.
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Headers set on the requests sent to the endpoints.
+const (
+	HeaderDelivery  = "X-LXD-Delivery"
+	HeaderEvent     = "X-LXD-Event"
+	HeaderSignature = "X-LXD-Signature-256"
+)
+
+// Number of events kept around while they are waiting to be delivered, and
+// maximum number of deliveries in progress at once.
+const (
+	maxQueueSize  = 1000
+	maxDeliveries = 20
+)
+
+// Delays between the attempts to deliver an event, the delivery failing once
+// they are exhausted.
+var retryDelays = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+// Time allowed to an endpoint to reply.
+var requestTimeout = 10 * time.Second
+
+// Webhook holds the settings of an endpoint the events are sent to.
+type Webhook struct {
+	Name     string
+	URL      string
+	Secret   string
+	Types    []string
+	Actions  []string
+	Projects []string
+}
+
+// Matches returns whether the lifecycle event with the given action in the
+// given project should be sent to the webhook.
+func (w Webhook) Matches(project string, action string) bool {
+	if len(w.Projects) > 0 && !shared.StringInSlice(project, w.Projects) {
+		return false
+	}
+
+	if len(w.Actions) > 0 && !shared.StringInSlice(action, w.Actions) {
+		return false
+	}
+
+	if len(w.Types) == 0 {
+		return true
+	}
+
+	for _, typ := range w.Types {
+		if strings.HasPrefix(action, typ+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateURL checks that the given URL can be used as a webhook endpoint.
+func ValidateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("Invalid URL %q: %v", value, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Invalid URL %q: the scheme must be http or https", value)
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("Invalid URL %q: no host", value)
+	}
+
+	return nil
+}
+
+// Sign returns the signature of the given payload with the given secret, as
+// set in the HeaderSignature header.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Loader returns the name of this member, which the events are tagged with,
+// and the configured webhooks.
+type Loader func() (string, []Webhook, error)
+
+// Recorder records the outcome of a delivery to the webhook with the given
+// name, a nil error meaning it succeeded.
+type Recorder func(name string, date time.Time, err error)
+
+// Dispatcher sends the lifecycle events to the matching webhooks in the
+// background, retrying while the endpoints fail.
+type Dispatcher struct {
+	load   Loader
+	record Recorder
+	client *http.Client
+
+	queue   chan api.Event
+	dropped int
+
+	slots chan struct{}
+	ctx   context.Context
+	stop  context.CancelFunc
+	wg    sync.WaitGroup
+
+	mu sync.Mutex
+}
+
+// NewDispatcher returns a new dispatcher, which doesn't send anything until
+// started.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		client: &http.Client{
+			Timeout: requestTimeout,
+			Transport: &http.Transport{
+				Proxy: shared.ProxyFromEnvironment,
+			},
+		},
+	}
+}
+
+// Start starts sending the events.
+func (d *Dispatcher) Start(load Loader, record Recorder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.queue != nil {
+		return
+	}
+
+	d.load = load
+	d.record = record
+	d.queue = make(chan api.Event, maxQueueSize)
+	d.slots = make(chan struct{}, maxDeliveries)
+	d.ctx, d.stop = context.WithCancel(context.Background())
+
+	d.wg.Add(1)
+	go d.run(d.queue)
+}
+
+// Send queues the given event of the given project for delivery, if it's a
+// lifecycle event. It never blocks nor logs, so that it can be called from an
+// event handler.
+func (d *Dispatcher) Send(project string, event api.Event) {
+	if event.Type != "lifecycle" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.queue == nil {
+		return
+	}
+
+	if event.Project == "" {
+		event.Project = project
+	}
+
+	select {
+	case d.queue <- event:
+	default:
+		d.dropped++
+	}
+}
+
+// Close stops sending the events, interrupting the deliveries in progress.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	queue := d.queue
+	stop := d.stop
+	d.queue = nil
+	d.mu.Unlock()
+
+	if queue == nil {
+		return
+	}
+
+	stop()
+	close(queue)
+	d.wg.Wait()
+}
+
+// Deliver the queued events until the queue is closed.
+func (d *Dispatcher) run(queue chan api.Event) {
+	defer d.wg.Done()
+
+	for event := range queue {
+		d.mu.Lock()
+		dropped := d.dropped
+		d.dropped = 0
+		d.mu.Unlock()
+
+		if dropped > 0 {
+			logger.Warnf("Dropped %d events while too many webhook deliveries were pending", dropped)
+		}
+
+		if d.ctx.Err() != nil {
+			continue
+		}
+
+		lifecycle := api.EventLifecycle{}
+		err := json.Unmarshal(event.Metadata, &lifecycle)
+		if err != nil {
+			continue
+		}
+
+		member, webhooks, err := d.load()
+		if err != nil {
+			logger.Warnf("Failed to load webhooks: %v", err)
+			continue
+		}
+
+		if event.Location == "" {
+			event.Location = member
+		}
+
+		for _, webhook := range webhooks {
+			if !webhook.Matches(event.Project, lifecycle.Action) {
+				continue
+			}
+
+			select {
+			case d.slots <- struct{}{}:
+			case <-d.ctx.Done():
+				continue
+			}
+
+			d.wg.Add(1)
+			go func(webhook Webhook, action string, event api.Event) {
+				defer d.wg.Done()
+				defer func() { <-d.slots }()
+
+				err := d.deliver(webhook, action, event)
+				if d.ctx.Err() != nil {
+					// Interrupted by shutdown.
+					return
+				}
+
+				if err != nil {
+					logger.Warn("Failed to deliver event to webhook", log.Ctx{"webhook": webhook.Name, "action": action, "err": err})
+				}
+
+				d.record(webhook.Name, time.Now(), err)
+			}(webhook, lifecycle.Action, event)
+		}
+	}
+}
+
+// Send an event to a webhook, retrying on temporary failures.
+func (d *Dispatcher) deliver(webhook Webhook, action string, event api.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// The same delivery ID is used for all attempts, so that the endpoint
+	// can recognize duplicates.
+	id := uuid.NewRandom().String()
+
+	for attempt := 0; ; attempt++ {
+		retry, err := d.post(webhook, id, action, payload)
+		if err == nil || !retry || attempt >= len(retryDelays) {
+			return err
+		}
+
+		select {
+		case <-time.After(retryDelays[attempt]):
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		}
+	}
+}
+
+// Make a single attempt at sending an event, returning whether it's worth
+// retrying if it failed.
+func (d *Dispatcher) post(webhook Webhook, id string, action string, payload []byte) (bool, error) {
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+
+	req = req.WithContext(d.ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", version.UserAgent)
+	req.Header.Set(HeaderDelivery, id)
+	req.Header.Set(HeaderEvent, action)
+	if webhook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(webhook.Secret, payload))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("Endpoint returned %s", resp.Status)
+
+	// Client errors other than timeouts and rate limiting won't go away
+	// by retrying.
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
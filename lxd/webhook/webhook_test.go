@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestWebhookMatches(t *testing.T) {
+	cases := []struct {
+		webhook Webhook
+		project string
+		action  string
+		matches bool
+	}{
+		{Webhook{}, "default", "container-started", true},
+		{Webhook{Projects: []string{"default"}}, "default", "container-started", true},
+		{Webhook{Projects: []string{"default"}}, "web", "container-started", false},
+		{Webhook{Actions: []string{"container-started"}}, "default", "container-started", true},
+		{Webhook{Actions: []string{"container-started"}}, "default", "container-stopped", false},
+		{Webhook{Types: []string{"image"}}, "default", "image-deleted", true},
+		{Webhook{Types: []string{"image"}}, "default", "container-started", false},
+		{Webhook{Types: []string{"container"}}, "default", "container-snapshot-created", true},
+		{Webhook{Types: []string{"container-snapshot"}}, "default", "container-created", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.matches, c.webhook.Matches(c.project, c.action), "%+v %s %s", c.webhook, c.project, c.action)
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, ValidateURL("https://chat.example.com/hooks/lxd"))
+	assert.NoError(t, ValidateURL("http://127.0.0.1:8080"))
+	assert.Error(t, ValidateURL("ftp://example.com"))
+	assert.Error(t, ValidateURL("https://"))
+	assert.Error(t, ValidateURL("example.com"))
+}
+
+// A delivery record.
+type delivery struct {
+	name string
+	err  error
+}
+
+// Returns a started dispatcher sending to the given webhooks, along with a
+// channel receiving the delivery records and a cleanup function.
+func newTestDispatcher(webhooks []Webhook) (*Dispatcher, chan delivery, func()) {
+	delays := retryDelays
+	retryDelays = []time.Duration{10 * time.Millisecond, 10 * time.Millisecond}
+
+	records := make(chan delivery, 10)
+	d := NewDispatcher()
+	d.Start(func() (string, []Webhook, error) {
+		return "node1", webhooks, nil
+	}, func(name string, date time.Time, err error) {
+		records <- delivery{name: name, err: err}
+	})
+
+	cleanup := func() {
+		d.Close()
+		retryDelays = delays
+	}
+
+	return d, records, cleanup
+}
+
+func lifecycleEvent(t *testing.T, action string) api.Event {
+	metadata, err := json.Marshal(api.EventLifecycle{Action: action, Source: "/1.0/containers/c1"})
+	require.NoError(t, err)
+
+	return api.Event{Type: "lifecycle", Timestamp: time.Now(), Metadata: metadata}
+}
+
+func TestDispatcherSend(t *testing.T) {
+	var mu sync.Mutex
+	requests := []*http.Request{}
+	bodies := [][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+
+		// Fail the first attempt.
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	d, records, cleanup := newTestDispatcher([]Webhook{
+		{Name: "chat", URL: server.URL, Secret: "s3cret", Actions: []string{"container-started"}},
+	})
+	defer cleanup()
+
+	d.Send("default", api.Event{Type: "logging"})
+	d.Send("default", lifecycleEvent(t, "container-stopped"))
+	d.Send("default", lifecycleEvent(t, "container-started"))
+
+	select {
+	case record := <-records:
+		assert.Equal(t, "chat", record.name)
+		assert.NoError(t, record.err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("No delivery recorded")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 2)
+
+	// Both attempts are the same delivery.
+	r := requests[1]
+	assert.Equal(t, requests[0].Header.Get(HeaderDelivery), r.Header.Get(HeaderDelivery))
+	assert.Equal(t, "container-started", r.Header.Get(HeaderEvent))
+	assert.Equal(t, Sign("s3cret", bodies[1]), r.Header.Get(HeaderSignature))
+
+	event := api.Event{}
+	require.NoError(t, json.Unmarshal(bodies[1], &event))
+	assert.Equal(t, "lifecycle", event.Type)
+	assert.Equal(t, "default", event.Project)
+	assert.Equal(t, "node1", event.Location)
+}
+
+func TestDispatcherFailure(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts[r.URL.Path]++
+		mu.Unlock()
+
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d, records, cleanup := newTestDispatcher([]Webhook{
+		{Name: "gone", URL: server.URL + "/gone"},
+		{Name: "broken", URL: server.URL + "/broken"},
+	})
+	defer cleanup()
+
+	d.Send("default", lifecycleEvent(t, "image-deleted"))
+
+	failed := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case record := <-records:
+			assert.Error(t, record.err)
+			failed[record.name] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("No delivery recorded")
+		}
+	}
+
+	assert.True(t, failed["gone"])
+	assert.True(t, failed["broken"])
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Client errors aren't retried.
+	assert.Equal(t, 1, attempts["/gone"])
+	assert.Equal(t, 3, attempts["/broken"])
+}
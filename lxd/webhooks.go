@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/lxd/webhook"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var webhooksCmd = APIEndpoint{
+	Path: "webhooks",
+
+	Get:  APIEndpointAction{Handler: webhooksGet},
+	Post: APIEndpointAction{Handler: webhooksPost},
+}
+
+var webhookCmd = APIEndpoint{
+	Path: "webhooks/{name}",
+
+	Delete: APIEndpointAction{Handler: webhookDelete},
+	Get:    APIEndpointAction{Handler: webhookGet},
+	Patch:  APIEndpointAction{Handler: webhookPatch},
+	Put:    APIEndpointAction{Handler: webhookPut},
+}
+
+func webhooksGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	var dbWebhooks []db.Webhook
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		dbWebhooks, err = tx.Webhooks()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, webhook := range dbWebhooks {
+			urls = append(urls, fmt.Sprintf("/%s/webhooks/%s", version.APIVersion, webhook.Name))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	webhooks := []api.Webhook{}
+	for _, webhook := range dbWebhooks {
+		webhooks = append(webhooks, webhookToAPI(webhook))
+	}
+
+	return response.SyncResponse(true, webhooks)
+}
+
+func webhooksPost(d *Daemon, r *http.Request) response.Response {
+	req := api.WebhooksPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	if strings.Contains(req.Name, "/") {
+		return response.BadRequest(fmt.Errorf("Webhook names may not contain slashes"))
+	}
+
+	resp := webhookValidate(d.cluster, req.WebhookPut)
+	if resp != nil {
+		return resp
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.WebhookCreate(webhookFromAPI(req.Name, req.WebhookPut))
+		return err
+	})
+	if errors.Cause(err) == db.ErrAlreadyDefined {
+		return response.Conflict(fmt.Errorf("Webhook %q already exists", req.Name))
+	}
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/webhooks/%s", version.APIVersion, req.Name))
+}
+
+func webhookGet(d *Daemon, r *http.Request) response.Response {
+	webhook, err := doWebhookGet(d.cluster, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, webhook, webhook.Writable())
+}
+
+func doWebhookGet(cluster *db.Cluster, name string) (api.Webhook, error) {
+	webhook, err := doWebhookGetDB(cluster, name)
+	if err != nil {
+		return api.Webhook{}, err
+	}
+
+	return webhookToAPI(webhook), nil
+}
+
+func doWebhookGetDB(cluster *db.Cluster, name string) (db.Webhook, error) {
+	var webhook db.Webhook
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		webhook, err = tx.WebhookByName(name)
+		return err
+	})
+
+	return webhook, err
+}
+
+func webhookPut(d *Daemon, r *http.Request) response.Response {
+	return doWebhookUpdate(d, r, false)
+}
+
+func webhookPatch(d *Daemon, r *http.Request) response.Response {
+	return doWebhookUpdate(d, r, true)
+}
+
+// Update a webhook, only changing the fields given in the request if patch is
+// true. The secret is left unchanged if none is given, since it can't be read
+// back by the clients.
+func doWebhookUpdate(d *Daemon, r *http.Request, patch bool) response.Response {
+	name := mux.Vars(r)["name"]
+
+	dbEntry, err := doWebhookGetDB(d.cluster, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	oldEntry := webhookToAPI(dbEntry)
+
+	err = util.EtagCheck(r, oldEntry.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.WebhookPut{}
+	if patch {
+		req = oldEntry.Writable()
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// The secret is write-only, so an empty one means keeping the current
+	// one. Removing it requires recreating the webhook.
+	if req.Secret == "" {
+		req.Secret = dbEntry.Secret
+	}
+
+	resp := webhookValidate(d.cluster, req)
+	if resp != nil {
+		return resp
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.WebhookUpdate(name, webhookFromAPI(name, req))
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func webhookDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.WebhookDelete(name)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Check the settings of a webhook, the projects it's scoped to having to
+// exist. Returns nil if they are valid.
+func webhookValidate(cluster *db.Cluster, req api.WebhookPut) response.Response {
+	err := webhook.ValidateURL(req.URL)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	for _, filter := range [][]string{req.Types, req.Actions} {
+		for _, value := range filter {
+			if value == "" || strings.ContainsAny(value, " ,") {
+				return response.BadRequest(fmt.Errorf("Invalid webhook filter %q", value))
+			}
+		}
+	}
+
+	if len(req.Projects) == 0 {
+		return nil
+	}
+
+	var projects []string
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		projects, err = tx.ProjectNames()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	for _, project := range req.Projects {
+		if !shared.StringInSlice(project, projects) {
+			return response.BadRequest(fmt.Errorf("Project %q not found", project))
+		}
+	}
+
+	return nil
+}
+
+func webhookFromAPI(name string, req api.WebhookPut) db.Webhook {
+	return db.Webhook{
+		Name:        name,
+		Description: req.Description,
+		URL:         req.URL,
+		Secret:      req.Secret,
+		Types:       req.Types,
+		Actions:     req.Actions,
+		Projects:    req.Projects,
+	}
+}
+
+// Convert a webhook to its API representation. The secret is write-only, so
+// it's never sent back to the clients nor part of the ETag.
+func webhookToAPI(webhook db.Webhook) api.Webhook {
+	return api.Webhook{
+		WebhookPut: api.WebhookPut{
+			Description: webhook.Description,
+			URL:         webhook.URL,
+			Types:       webhook.Types,
+			Actions:     webhook.Actions,
+			Projects:    webhook.Projects,
+		},
+		Name:               webhook.Name,
+		LastDeliveryAt:     webhook.LastDeliveryAt,
+		LastDeliveryStatus: webhook.LastDeliveryStatus,
+		LastDeliveryError:  webhook.LastDeliveryError,
+		FailedDeliveries:   webhook.FailedDeliveries,
+	}
+}
+
+// webhooksLoad returns a function loading the name of this member and the
+// webhooks from the database.
+func webhooksLoad(cluster *db.Cluster) webhook.Loader {
+	return func() (string, []webhook.Webhook, error) {
+		var member string
+		var dbWebhooks []db.Webhook
+		err := cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			member, err = tx.NodeName()
+			if err != nil {
+				return err
+			}
+
+			dbWebhooks, err = tx.Webhooks()
+			return err
+		})
+		if err != nil {
+			return "", nil, err
+		}
+
+		webhooks := []webhook.Webhook{}
+		for _, w := range dbWebhooks {
+			webhooks = append(webhooks, webhook.Webhook{
+				Name:     w.Name,
+				URL:      w.URL,
+				Secret:   w.Secret,
+				Types:    w.Types,
+				Actions:  w.Actions,
+				Projects: w.Projects,
+			})
+		}
+
+		return member, webhooks, nil
+	}
+}
+
+// webhooksRecord returns a function recording the outcome of the deliveries
+// in the database.
+func webhooksRecord(cluster *db.Cluster) webhook.Recorder {
+	return func(name string, date time.Time, deliveryErr error) {
+		status := webhook.StatusSuccess
+		message := ""
+		if deliveryErr != nil {
+			status = webhook.StatusFailure
+			message = deliveryErr.Error()
+		}
+
+		err := cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.WebhookDeliveryUpdate(name, date, status, message)
+		})
+		if err != nil && err != db.ErrNoSuchObject {
+			logger.Warn("Failed to record webhook delivery", log.Ctx{"webhook": name, "err": err})
+		}
+	}
+}
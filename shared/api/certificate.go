@@ -1,11 +1,19 @@
 package api
 
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
 // CertificatesPost represents the fields of a new LXD certificate
 type CertificatesPost struct {
 	CertificatePut `yaml:",inline"`
 
 	Certificate string `json:"certificate" yaml:"certificate"`
 	Password    string `json:"password" yaml:"password"`
+
+	// API extension: certificate_token
+	Token bool `json:"token" yaml:"token"`
 }
 
 // CertificatePut represents the modifiable fields of a LXD certificate
@@ -14,6 +22,10 @@ type CertificatesPost struct {
 type CertificatePut struct {
 	Name string `json:"name" yaml:"name"`
 	Type string `json:"type" yaml:"type"`
+
+	// API extension: certificate_project
+	Restricted bool     `json:"restricted" yaml:"restricted"`
+	Projects   []string `json:"projects" yaml:"projects"`
 }
 
 // Certificate represents a LXD certificate
@@ -28,3 +40,23 @@ type Certificate struct {
 func (cert *Certificate) Writable() CertificatePut {
 	return cert.CertificatePut
 }
+
+// CertificateAddToken represents the fields contained within an encoded certificate add token.
+//
+// API extension: certificate_token
+type CertificateAddToken struct {
+	ClientName  string   `json:"client_name" yaml:"client_name"`
+	Fingerprint string   `json:"fingerprint" yaml:"fingerprint"`
+	Addresses   []string `json:"addresses" yaml:"addresses"`
+	Secret      string   `json:"secret" yaml:"secret"`
+}
+
+// String encodes the certificate add token as JSON and then base64.
+func (t *CertificateAddToken) String() string {
+	joinTokenJSON, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(joinTokenJSON)
+}
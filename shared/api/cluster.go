@@ -1,5 +1,10 @@
 package api
 
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
 // Cluster represents high-level information about a LXD cluster.
 //
 // API extension: clustering
@@ -41,6 +46,35 @@ type ClusterPut struct {
 	ClusterPassword string `json:"cluster_password" yaml:"cluster_password"`
 }
 
+// ClusterMembersPost represents the fields required to request a join token
+// for a new LXD cluster member.
+//
+// API extension: clustering_join_token
+type ClusterMembersPost struct {
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+// ClusterMemberJoinToken represents the fields contained within an encoded
+// cluster member join token.
+//
+// API extension: clustering_join_token
+type ClusterMemberJoinToken struct {
+	ServerName  string   `json:"server_name" yaml:"server_name"`
+	Fingerprint string   `json:"fingerprint" yaml:"fingerprint"`
+	Addresses   []string `json:"addresses" yaml:"addresses"`
+	Secret      string   `json:"secret" yaml:"secret"`
+}
+
+// String encodes the cluster member join token as JSON and then base64.
+func (t *ClusterMemberJoinToken) String() string {
+	joinTokenJSON, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(joinTokenJSON)
+}
+
 // ClusterMemberPost represents the fields required to rename a LXD node.
 //
 // API extension: clustering
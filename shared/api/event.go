@@ -13,6 +13,12 @@ type Event struct {
 
 	// API extension: event_location
 	Location string `yaml:"location,omitempty" json:"location,omitempty"`
+
+	// API extension: certificate_project
+	Project string `yaml:"project,omitempty" json:"project,omitempty"`
+
+	// API extension: event_filters
+	ID int64 `yaml:"id,omitempty" json:"id,omitempty"`
 }
 
 // EventLogging represents a logging type event entry (admin only)
@@ -30,3 +36,20 @@ type EventLifecycle struct {
 	Source  string                 `yaml:"source" json:"source"`
 	Context map[string]interface{} `yaml:"context,omitempty" json:"context,omitempty"`
 }
+
+// EventAudit represents an audit type event entry, recording a mutating API
+// request (admin only)
+//
+// API extension: audit_log
+type EventAudit struct {
+	Identity   string `yaml:"identity" json:"identity"`
+	Protocol   string `yaml:"protocol" json:"protocol"`
+	Source     string `yaml:"source" json:"source"`
+	Method     string `yaml:"method" json:"method"`
+	URL        string `yaml:"url" json:"url"`
+	Project    string `yaml:"project" json:"project"`
+	BodyDigest string `yaml:"body_digest" json:"body_digest"`
+	StatusCode int    `yaml:"status_code" json:"status_code"`
+	Result     string `yaml:"result" json:"result"`
+	Operation  string `yaml:"operation,omitempty" json:"operation,omitempty"`
+}
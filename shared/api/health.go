@@ -0,0 +1,23 @@
+package api
+
+// Health represents the health of a LXD server
+//
+// API extension: health
+type Health struct {
+	// One of "ok", "degraded" or "failed"
+	Status string `json:"status" yaml:"status"`
+
+	// Only included for trusted clients
+	Location string        `json:"location,omitempty" yaml:"location,omitempty"`
+	Checks   []HealthCheck `json:"checks,omitempty" yaml:"checks,omitempty"`
+}
+
+// HealthCheck represents the result of a single check of a LXD server health
+//
+// API extension: health
+type HealthCheck struct {
+	Type    string `json:"type" yaml:"type"`
+	Name    string `json:"name" yaml:"name"`
+	Status  string `json:"status" yaml:"status"`
+	Message string `json:"message" yaml:"message"`
+}
@@ -16,6 +16,9 @@ type ImagesPost struct {
 
 	// API extension: image_create_aliases
 	Aliases []ImageAlias `json:"aliases" yaml:"aliases"`
+
+	// API extension: image_signatures
+	Sign bool `json:"sign" yaml:"sign"`
 }
 
 // ImagesPostSource represents the source of a new LXD image
@@ -67,6 +70,10 @@ type Image struct {
 	CreatedAt  time.Time `json:"created_at" yaml:"created_at"`
 	LastUsedAt time.Time `json:"last_used_at" yaml:"last_used_at"`
 	UploadedAt time.Time `json:"uploaded_at" yaml:"uploaded_at"`
+
+	// API extension: image_signatures
+	Signature       string `json:"signature" yaml:"signature"`
+	SignatureStatus string `json:"signature_status" yaml:"signature_status"`
 }
 
 // Writable converts a full Image struct into a ImagePut struct (filters read-only fields)
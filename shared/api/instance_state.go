@@ -1,5 +1,9 @@
 package api
 
+import (
+	"time"
+)
+
 // InstanceStatePut represents the modifiable fields of a LXD instance's state.
 //
 // API extension: instances
@@ -82,3 +86,26 @@ type InstanceStateNetworkCounters struct {
 	PacketsReceived int64 `json:"packets_received" yaml:"packets_received"`
 	PacketsSent     int64 `json:"packets_sent" yaml:"packets_sent"`
 }
+
+// InstanceUsageSample represents the resource usage of a LXD instance over a
+// sampling interval, the rates being averaged over the interval.
+//
+// API extension: instance_usage_history
+type InstanceUsageSample struct {
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+
+	// CPU usage in percent of one CPU
+	CPUUsage float64 `json:"cpu_usage" yaml:"cpu_usage"`
+
+	MemoryUsage int64 `json:"memory_usage" yaml:"memory_usage"`
+	SwapUsage   int64 `json:"swap_usage" yaml:"swap_usage"`
+
+	// Rates in bytes per second, the disk ones being null if unknown, as
+	// for virtual machines
+	DiskReadRate        *int64 `json:"disk_read_rate" yaml:"disk_read_rate"`
+	DiskWriteRate       *int64 `json:"disk_write_rate" yaml:"disk_write_rate"`
+	NetworkReceiveRate  int64  `json:"network_receive_rate" yaml:"network_receive_rate"`
+	NetworkTransmitRate int64  `json:"network_transmit_rate" yaml:"network_transmit_rate"`
+
+	Processes int64 `json:"processes" yaml:"processes"`
+}
@@ -49,6 +49,11 @@ type ServerUntrusted struct {
 
 	// API extension: macaroon_authentication
 	AuthMethods []string `json:"auth_methods" yaml:"auth_methods"`
+
+	// API extension: oidc
+	OIDCIssuer   string `json:"oidc_issuer,omitempty" yaml:"oidc_issuer,omitempty"`
+	OIDCClientID string `json:"oidc_client_id,omitempty" yaml:"oidc_client_id,omitempty"`
+	OIDCAudience string `json:"oidc_audience,omitempty" yaml:"oidc_audience,omitempty"`
 }
 
 // Server represents a LXD server
@@ -25,6 +25,14 @@ type StoragePool struct {
 	Locations []string `json:"locations" yaml:"locations"`
 }
 
+// StoragePoolPost represents the fields required to move all volumes of a LXD storage pool
+// onto another storage pool.
+//
+// API extension: storage_pool_move_volumes
+type StoragePoolPost struct {
+	Pool string `json:"pool" yaml:"pool"`
+}
+
 // StoragePoolPut represents the modifiable fields of a LXD storage pool.
 //
 // API extension: storage
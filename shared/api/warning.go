@@ -0,0 +1,34 @@
+package api
+
+import (
+	"time"
+)
+
+// WarningPut represents the modifiable fields of a LXD warning
+//
+// API extension: warnings
+type WarningPut struct {
+	Status string `json:"status" yaml:"status"`
+}
+
+// Warning represents a problem detected by a LXD server
+//
+// API extension: warnings
+type Warning struct {
+	WarningPut `yaml:",inline"`
+
+	UUID        string    `json:"uuid" yaml:"uuid"`
+	Location    string    `json:"location" yaml:"location"`
+	Project     string    `json:"project" yaml:"project"`
+	Type        string    `json:"type" yaml:"type"`
+	Count       int       `json:"count" yaml:"count"`
+	FirstSeenAt time.Time `json:"first_seen_at" yaml:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" yaml:"last_seen_at"`
+	LastMessage string    `json:"last_message" yaml:"last_message"`
+	EntityURL   string    `json:"entity_url" yaml:"entity_url"`
+}
+
+// Writable converts a full Warning struct into a WarningPut struct (filters read-only fields)
+func (w *Warning) Writable() WarningPut {
+	return w.WarningPut
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"time"
+)
+
+// WebhooksPost represents the fields of a new LXD webhook
+//
+// API extension: webhooks
+type WebhooksPost struct {
+	WebhookPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// WebhookPut represents the modifiable fields of a LXD webhook
+//
+// API extension: webhooks
+type WebhookPut struct {
+	Description string `json:"description" yaml:"description"`
+
+	// URL the matching events are POSTed to
+	URL string `json:"url" yaml:"url"`
+
+	// Key the payloads are signed with, if any (write-only, kept on update
+	// when empty)
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+
+	// Only send the events about these types of entities, like "container"
+	// or "image", all of them being sent if empty
+	Types []string `json:"types" yaml:"types"`
+
+	// Only send the events with these actions, like "container-started",
+	// all of them being sent if empty
+	Actions []string `json:"actions" yaml:"actions"`
+
+	// Only send the events of these projects, all of them being sent if
+	// empty
+	Projects []string `json:"projects" yaml:"projects"`
+}
+
+// Webhook represents a LXD webhook
+//
+// API extension: webhooks
+type Webhook struct {
+	WebhookPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+
+	// Outcome of the last delivery, once all the attempts were made
+	LastDeliveryAt     time.Time `json:"last_delivery_at" yaml:"last_delivery_at"`
+	LastDeliveryStatus string    `json:"last_delivery_status" yaml:"last_delivery_status"`
+	LastDeliveryError  string    `json:"last_delivery_error" yaml:"last_delivery_error"`
+
+	// Number of events which couldn't be delivered since the last
+	// successful delivery
+	FailedDeliveries int `json:"failed_deliveries" yaml:"failed_deliveries"`
+}
+
+// Writable converts a full Webhook struct into a WebhookPut struct (filters read-only fields)
+func (w *Webhook) Writable() WebhookPut {
+	return w.WebhookPut
+}
@@ -26,6 +26,7 @@ func DetectCompressionFile(f io.Reader) ([]string, string, []string, error) {
 	// gz - 2 bytes, 0x1f 0x8b
 	// lzma - 6 bytes, { [0x000, 0xE0], '7', 'z', 'X', 'Z', 0x00 } -
 	// xy - 6 bytes,  header format { 0xFD, '7', 'z', 'X', 'Z', 0x00 }
+	// zstd - 4 bytes, 0x28 0xb5 0x2f 0xfd
 	// tar - 263 bytes, trying to get ustar from 257 - 262
 	header := make([]byte, 263)
 	_, err := f.Read(header)
@@ -44,6 +45,8 @@ func DetectCompressionFile(f io.Reader) ([]string, string, []string, error) {
 		return []string{"--lzma", "-xf"}, ".tar.lzma", []string{"lzma", "-d"}, nil
 	case bytes.Equal(header[0:3], []byte{0x5d, 0x00, 0x00}):
 		return []string{"--lzma", "-xf"}, ".tar.lzma", []string{"lzma", "-d"}, nil
+	case bytes.Equal(header[0:4], []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return []string{"--zstd", "-xf"}, ".tar.zst", []string{"zstd", "-d"}, nil
 	case bytes.Equal(header[257:262], []byte{'u', 's', 't', 'a', 'r'}):
 		return []string{"-xf"}, ".tar", []string{}, nil
 	case bytes.Equal(header[0:4], []byte{'h', 's', 'q', 's'}):
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/pkg/errors"
 	"gopkg.in/robfig/cron.v2"
@@ -107,6 +108,17 @@ func IsAny(value string) error {
 	return nil
 }
 
+// IsPrintable validates that the value doesn't contain any control characters.
+func IsPrintable(value string) error {
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("Invalid value, control characters aren't allowed")
+		}
+	}
+
+	return nil
+}
+
 func IsNotEmpty(value string) error {
 	if value == "" {
 		return fmt.Errorf("Required value")
@@ -278,6 +290,32 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"migration.incremental.memory.iterations": IsUint32,
 	"migration.incremental.memory.goal":       IsUint32,
 
+	"oci.entrypoint": IsPrintable,
+	"oci.cwd": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		if !strings.HasPrefix(value, "/") {
+			return fmt.Errorf("Invalid value, must be an absolute path: %s", value)
+		}
+
+		return IsPrintable(value)
+	},
+	"oci.uid": IsUint32,
+	"oci.gid": IsUint32,
+	"oci.stop_signal": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		if !regexp.MustCompile(`^(SIG)?[A-Z0-9]+([+-][0-9]+)?$`).MatchString(value) {
+			return fmt.Errorf("Invalid value for a signal: %s", value)
+		}
+
+		return nil
+	},
+
 	"nvidia.runtime":             IsBool,
 	"nvidia.driver.capabilities": IsAny,
 	"nvidia.require.cuda":        IsAny,
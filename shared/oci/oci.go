@@ -0,0 +1,503 @@
+package oci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// Registry hosts which are only an alias for the Docker Hub API endpoint
+var dockerHubHosts = []string{"docker.io", "index.docker.io", "registry-1.docker.io"}
+
+// NewClient returns an OCI registry client for the provided registry URL
+func NewClient(registryURL string, httpClient http.Client, useragent string) *Registry {
+	registry := &Registry{
+		http:      &httpClient,
+		url:       strings.TrimSuffix(registryURL, "/"),
+		useragent: useragent,
+		tokens:    map[string]string{},
+	}
+
+	u, err := url.Parse(registry.url)
+	if err != nil {
+		return registry
+	}
+
+	// Credentials are used for authentication, not as part of the URL
+	if u.User != nil {
+		registry.username = u.User.Username()
+		registry.password, _ = u.User.Password()
+		u.User = nil
+	}
+
+	for _, host := range dockerHubHosts {
+		if u.Host == host {
+			registry.dockerHub = true
+			u.Host = "registry-1.docker.io"
+			break
+		}
+	}
+
+	registry.url = u.String()
+
+	return registry
+}
+
+// Registry represents an OCI (Docker registry v2 API) client
+type Registry struct {
+	http      *http.Client
+	url       string
+	useragent string
+	username  string
+	password  string
+	dockerHub bool
+
+	tokens     map[string]string
+	tokensLock sync.Mutex
+}
+
+// ParseReference splits an image name into its repository and tag or digest
+func (r *Registry) ParseReference(name string) (string, string) {
+	repository := name
+	reference := "latest"
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		repository = name[:i]
+		reference = name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		repository = name[:i]
+		reference = name[i+1:]
+	}
+
+	// Official images live in the library namespace on the Docker Hub
+	if r.dockerHub && !strings.Contains(repository, "/") {
+		repository = fmt.Sprintf("library/%s", repository)
+	}
+
+	return repository, reference
+}
+
+// Description returns the fully qualified reference of an image name
+func (r *Registry) Description(name string) string {
+	repository, reference := r.ParseReference(name)
+	if strings.HasPrefix(reference, "sha256:") {
+		return fmt.Sprintf("%s@%s", repository, reference)
+	}
+
+	return fmt.Sprintf("%s:%s", repository, reference)
+}
+
+// parseChallenge parses a WWW-Authenticate header into its scheme and parameters
+func parseChallenge(header string) (string, map[string]string) {
+	params := map[string]string{}
+
+	fields := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(fields) < 2 {
+		return strings.ToLower(fields[0]), params
+	}
+
+	// Values may be quoted and contain commas
+	key := ""
+	value := ""
+	inKey := true
+	inQuotes := false
+	for _, c := range fields[1] {
+		switch {
+		case inKey && c == '=':
+			inKey = false
+		case inKey && c != ',' && c != ' ':
+			key += string(c)
+		case !inKey && c == '"':
+			inQuotes = !inQuotes
+		case !inKey && c == ',' && !inQuotes:
+			params[strings.ToLower(key)] = value
+			key = ""
+			value = ""
+			inKey = true
+		case !inKey:
+			value += string(c)
+		}
+	}
+
+	if key != "" {
+		params[strings.ToLower(key)] = value
+	}
+
+	return strings.ToLower(fields[0]), params
+}
+
+// fetchToken retrieves a bearer token for the repository from the authentication service
+func (r *Registry) fetchToken(repository string, params map[string]string) (string, error) {
+	if params["realm"] == "" {
+		return "", fmt.Errorf("Missing realm in authentication challenge")
+	}
+
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", repository)
+	}
+
+	query := url.Values{}
+	query.Set("scope", scope)
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s?%s", params["realm"], query.Encode()), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if r.useragent != "" {
+		req.Header.Set("User-Agent", r.useragent)
+	}
+
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unable to authenticate to %s: %s", params["realm"], resp.Status)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+
+	return token.AccessToken, nil
+}
+
+// get performs a GET request against the repository, authenticating when challenged
+func (r *Registry) get(repository string, path string, accept []string) (*http.Response, error) {
+	uri := fmt.Sprintf("%s/v2/%s/%s", r.url, repository, path)
+
+	do := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.useragent != "" {
+			req.Header.Set("User-Agent", r.useragent)
+		}
+
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		return r.http.Do(req)
+	}
+
+	r.tokensLock.Lock()
+	token := r.tokens[repository]
+	r.tokensLock.Unlock()
+
+	authorization := ""
+	if token != "" {
+		authorization = fmt.Sprintf("Bearer %s", token)
+	}
+
+	resp, err := do(authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+
+		scheme, params := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+		switch scheme {
+		case "bearer":
+			token, err = r.fetchToken(repository, params)
+			if err != nil {
+				return nil, err
+			}
+
+			r.tokensLock.Lock()
+			r.tokens[repository] = token
+			r.tokensLock.Unlock()
+
+			authorization = fmt.Sprintf("Bearer %s", token)
+		case "basic":
+			if r.username == "" {
+				return nil, fmt.Errorf("Unable to fetch %s: %s", uri, resp.Status)
+			}
+
+			req := http.Request{Header: http.Header{}}
+			req.SetBasicAuth(r.username, r.password)
+			authorization = req.Header.Get("Authorization")
+		default:
+			return nil, fmt.Errorf("Unable to fetch %s: %s", uri, resp.Status)
+		}
+
+		resp, err = do(authorization)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Unable to fetch %s: %s", uri, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// getManifest retrieves the raw manifest or index for a tag or digest along with its digest
+func (r *Registry) getManifest(repository string, reference string) ([]byte, string, error) {
+	accept := []string{MediaTypeOCIIndex, MediaTypeOCIManifest, MediaTypeDockerManifestList, MediaTypeDockerManifest}
+
+	resp, err := r.get(repository, fmt.Sprintf("manifests/%s", reference), accept)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	if strings.HasPrefix(reference, "sha256:") && reference != digest {
+		return nil, "", fmt.Errorf("Hash mismatch for manifest %s: %s != %s", repository, digest, reference)
+	}
+
+	return body, digest, nil
+}
+
+// getIndex retrieves the manifest for a reference, returning the index instead if it's a manifest list
+func (r *Registry) getIndex(repository string, reference string) (*Index, *Manifest, string, error) {
+	body, digest, err := r.getManifest(repository, reference)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	// Registries don't always set the content type, so look at the document itself
+	index := Index{}
+	err = json.Unmarshal(body, &index)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if index.MediaType == MediaTypeOCIIndex || index.MediaType == MediaTypeDockerManifestList || index.Manifests != nil {
+		return &index, nil, digest, nil
+	}
+
+	manifest := Manifest{}
+	err = json.Unmarshal(body, &manifest)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if manifest.SchemaVersion != 2 {
+		return nil, nil, "", fmt.Errorf("Unsupported manifest schema version: %d", manifest.SchemaVersion)
+	}
+
+	return nil, &manifest, digest, nil
+}
+
+// platformArchitecture returns the LXD architecture name for an index entry
+func platformArchitecture(platform *Platform) (string, error) {
+	if platform == nil || platform.OS != "linux" {
+		return "", fmt.Errorf("Unsupported platform")
+	}
+
+	// Only ARMv7 maps to a 32bit ARM architecture supported by LXD
+	if platform.Architecture == "arm" && platform.Variant != "" && platform.Variant != "v7" {
+		return "", fmt.Errorf("Unsupported ARM variant: %s", platform.Variant)
+	}
+
+	id, err := osarch.ArchitectureId(platform.Architecture)
+	if err != nil {
+		return "", err
+	}
+
+	return osarch.ArchitectureName(id)
+}
+
+// GetManifest returns the image manifest for the given architecture along with its digest
+func (r *Registry) GetManifest(name string, architecture string) (*Manifest, string, error) {
+	repository, reference := r.ParseReference(name)
+
+	index, manifest, digest, err := r.getIndex(repository, reference)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if manifest != nil {
+		return manifest, digest, nil
+	}
+
+	// Pick the right image from the manifest list
+	for _, entry := range index.Manifests {
+		entryArchitecture, err := platformArchitecture(entry.Platform)
+		if err != nil || entryArchitecture != architecture {
+			continue
+		}
+
+		_, manifest, digest, err := r.getIndex(repository, entry.Digest)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if manifest == nil {
+			return nil, "", fmt.Errorf("Nested manifest lists aren't supported")
+		}
+
+		return manifest, digest, nil
+	}
+
+	return nil, "", fmt.Errorf("No image found for architecture %s", architecture)
+}
+
+// GetArchitectures returns the architectures an image is available for
+func (r *Registry) GetArchitectures(name string) ([]string, error) {
+	repository, reference := r.ParseReference(name)
+
+	index, manifest, _, err := r.getIndex(repository, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest != nil {
+		config, err := r.GetConfig(name, manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		architecture, err := platformArchitecture(&Platform{Architecture: config.Architecture, OS: config.OS})
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{architecture}, nil
+	}
+
+	architectures := []string{}
+	for _, entry := range index.Manifests {
+		architecture, err := platformArchitecture(entry.Platform)
+		if err != nil {
+			continue
+		}
+
+		architectures = append(architectures, architecture)
+	}
+
+	return architectures, nil
+}
+
+// GetBlob downloads a blob into target, validating its digest
+func (r *Registry) GetBlob(name string, blob Descriptor, target io.Writer) error {
+	if !strings.HasPrefix(blob.Digest, "sha256:") {
+		return fmt.Errorf("Unsupported digest: %s", blob.Digest)
+	}
+
+	repository, _ := r.ParseReference(name)
+
+	resp, err := r.get(repository, fmt.Sprintf("blobs/%s", blob.Digest), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(target, hash), resp.Body)
+	if err != nil {
+		return err
+	}
+
+	result := fmt.Sprintf("sha256:%x", hash.Sum(nil))
+	if result != blob.Digest {
+		return fmt.Errorf("Hash mismatch for blob %s: %s != %s", repository, result, blob.Digest)
+	}
+
+	return nil
+}
+
+// GetConfig returns the image configuration referenced by a manifest
+func (r *Registry) GetConfig(name string, manifest *Manifest) (*ImageConfig, error) {
+	buf := bytes.Buffer{}
+	err := r.GetBlob(name, manifest.Config, &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	config := ImageConfig{}
+	err = json.Unmarshal(buf.Bytes(), &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// GetImage returns an Image struct describing the named image for the given architecture
+func (r *Registry) GetImage(name string, architecture string) (*api.Image, error) {
+	manifest, digest, err := r.GetManifest(name, architecture)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := r.GetConfig(name, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	imageArchitecture, err := platformArchitecture(&Platform{Architecture: config.Architecture, OS: config.OS})
+	if err != nil {
+		return nil, err
+	}
+
+	size := manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+
+	// OCI images are unpacked by LXD, so the reference stands in for the fingerprint
+	image := api.Image{}
+	image.Fingerprint = name
+	image.Public = true
+	image.Type = "container"
+	image.Architecture = imageArchitecture
+	image.Size = size
+	image.CreatedAt = config.Created
+	image.UploadedAt = config.Created
+	image.Properties = map[string]string{
+		"description": r.Description(name),
+		"oci.digest":  digest,
+	}
+
+	return &image, nil
+}
@@ -0,0 +1,245 @@
+// Package oidc implements the parts of OpenID Connect shared by the LXD daemon
+// and its clients: provider discovery, the OAuth 2.0 device authorization grant
+// and token refresh.
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Provider holds the endpoints advertised by an OpenID Connect issuer.
+type Provider struct {
+	Issuer                      string `json:"issuer"`
+	JWKSURI                     string `json:"jwks_uri"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// Tokens holds the tokens obtained from an OpenID Connect issuer.
+type Tokens struct {
+	Issuer       string    `json:"issuer"`
+	ClientID     string    `json:"client_id"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// DeviceAuthorization is the issuer's answer to a device authorization
+// request. The user must visit VerificationURI and enter UserCode.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// Discover fetches the OpenID Connect configuration of the given issuer.
+func Discover(client *http.Client, issuer string) (*Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to fetch OpenID configuration of %q: %s", issuer, resp.Status)
+	}
+
+	provider := Provider{}
+	err = json.NewDecoder(resp.Body).Decode(&provider)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse OpenID configuration of %q: %v", issuer, err)
+	}
+
+	if provider.Issuer != issuer {
+		return nil, fmt.Errorf("OpenID issuer mismatch: expected %q, got %q", issuer, provider.Issuer)
+	}
+
+	return &provider, nil
+}
+
+// DeviceLogin runs the device authorization grant against the given issuer.
+// The prompt function is called once the issuer has handed out a user code,
+// and the call then blocks until the user has completed the login.
+func DeviceLogin(client *http.Client, issuer string, clientID string, audience string, prompt func(*DeviceAuthorization)) (*Tokens, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	provider, err := Discover(client, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	if provider.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OpenID issuer %q doesn't support the device authorization grant", issuer)
+	}
+
+	values := url.Values{}
+	values.Set("client_id", clientID)
+	values.Set("scope", "openid offline_access")
+	if audience != "" {
+		values.Set("audience", audience)
+	}
+
+	resp, err := client.PostForm(provider.DeviceAuthorizationEndpoint, values)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Device authorization request failed: %s", resp.Status)
+	}
+
+	auth := DeviceAuthorization{}
+	err = json.NewDecoder(resp.Body).Decode(&auth)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse device authorization response: %v", err)
+	}
+
+	if prompt != nil {
+		prompt(&auth)
+	}
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	deadline := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	if auth.ExpiresIn <= 0 {
+		deadline = time.Now().Add(10 * time.Minute)
+	}
+
+	values = url.Values{}
+	values.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
+	values.Set("device_code", auth.DeviceCode)
+	values.Set("client_id", clientID)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		token, err := requestToken(client, provider.TokenEndpoint, values)
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Error {
+		case "":
+			tokens := &Tokens{Issuer: issuer, ClientID: clientID}
+			tokens.update(token)
+			return tokens, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		default:
+			return nil, fmt.Errorf("Device login failed: %s", token.errorString())
+		}
+	}
+
+	return nil, fmt.Errorf("Device login timed out")
+}
+
+// Expired returns whether the access token has expired, or is about to.
+func (t *Tokens) Expired() bool {
+	if t.Expiry.IsZero() {
+		return false
+	}
+
+	return time.Now().Add(30 * time.Second).After(t.Expiry)
+}
+
+// Refresh obtains a new access token using the refresh token.
+func (t *Tokens) Refresh(client *http.Client) error {
+	if t.RefreshToken == "" {
+		return fmt.Errorf("No OpenID refresh token available")
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	provider, err := Discover(client, t.Issuer)
+	if err != nil {
+		return err
+	}
+
+	values := url.Values{}
+	values.Set("grant_type", "refresh_token")
+	values.Set("refresh_token", t.RefreshToken)
+	values.Set("client_id", t.ClientID)
+
+	token, err := requestToken(client, provider.TokenEndpoint, values)
+	if err != nil {
+		return err
+	}
+
+	if token.Error != "" {
+		return fmt.Errorf("Token refresh failed: %s", token.errorString())
+	}
+
+	t.update(token)
+	return nil
+}
+
+func (t *Tokens) update(token *tokenResponse) {
+	t.AccessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		t.RefreshToken = token.RefreshToken
+	}
+
+	t.Expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+}
+
+func (r *tokenResponse) errorString() string {
+	if r.Description != "" {
+		return fmt.Sprintf("%s (%s)", r.Error, r.Description)
+	}
+
+	return r.Error
+}
+
+func requestToken(client *http.Client, endpoint string, values url.Values) (*tokenResponse, error) {
+	resp, err := client.PostForm(endpoint, values)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	token := tokenResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse token response: %v", err)
+	}
+
+	if token.Error == "" && token.AccessToken == "" {
+		return nil, fmt.Errorf("No access token in token response")
+	}
+
+	return &token, nil
+}
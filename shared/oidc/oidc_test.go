@@ -0,0 +1,91 @@
+package oidc_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/oidc"
+)
+
+// The device login polls the token endpoint until the user has logged in,
+// and the resulting tokens can be refreshed.
+func TestDeviceLogin(t *testing.T) {
+	var server *httptest.Server
+	polls := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                        server.URL,
+			"token_endpoint":                server.URL + "/token",
+			"device_authorization_endpoint": server.URL + "/device",
+		})
+	})
+
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "lxc", r.FormValue("client_id"))
+		assert.Equal(t, "lxd", r.FormValue("audience"))
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": server.URL + "/activate",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("grant_type") {
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			assert.Equal(t, "device", r.FormValue("device_code"))
+
+			polls++
+			if polls < 2 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+				return
+			}
+
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "access1",
+				"refresh_token": "refresh1",
+				"expires_in":    3600,
+			})
+		case "refresh_token":
+			assert.Equal(t, "refresh1", r.FormValue("refresh_token"))
+
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "access2",
+				"expires_in":   3600,
+			})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+		}
+	})
+
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	userCode := ""
+	tokens, err := oidc.DeviceLogin(nil, server.URL, "lxc", "lxd", func(auth *oidc.DeviceAuthorization) {
+		userCode = auth.UserCode
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "ABCD-EFGH", userCode)
+	assert.Equal(t, 2, polls)
+	assert.Equal(t, "access1", tokens.AccessToken)
+	assert.False(t, tokens.Expired())
+
+	err = tokens.Refresh(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "access2", tokens.AccessToken)
+	assert.Equal(t, "refresh1", tokens.RefreshToken)
+}
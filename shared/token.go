@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// CertificateTokenDecode decodes a base64 and JSON encoded certificate add token.
+func CertificateTokenDecode(input string) (*api.CertificateAddToken, error) {
+	joinTokenJSON, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var j api.CertificateAddToken
+	err = json.Unmarshal(joinTokenJSON, &j)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.ClientName == "" {
+		return nil, fmt.Errorf("No client name in certificate add token")
+	}
+
+	if len(j.Addresses) < 1 {
+		return nil, fmt.Errorf("No server addresses in certificate add token")
+	}
+
+	if j.Secret == "" {
+		return nil, fmt.Errorf("No secret in certificate add token")
+	}
+
+	if j.Fingerprint == "" {
+		return nil, fmt.Errorf("No certificate fingerprint in certificate add token")
+	}
+
+	return &j, nil
+}
+
+// JoinTokenDecode decodes a base64 and JSON encoded cluster member join token.
+func JoinTokenDecode(input string) (*api.ClusterMemberJoinToken, error) {
+	joinTokenJSON, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var j api.ClusterMemberJoinToken
+	err = json.Unmarshal(joinTokenJSON, &j)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.ServerName == "" {
+		return nil, fmt.Errorf("No server name in join token")
+	}
+
+	if len(j.Addresses) < 1 {
+		return nil, fmt.Errorf("No cluster member addresses in join token")
+	}
+
+	if j.Secret == "" {
+		return nil, fmt.Errorf("No secret in join token")
+	}
+
+	if j.Fingerprint == "" {
+		return nil, fmt.Errorf("No certificate fingerprint in join token")
+	}
+
+	return &j, nil
+}
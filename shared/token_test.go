@@ -0,0 +1,46 @@
+package shared_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// A certificate add token survives an encode/decode round trip.
+func TestCertificateTokenDecode(t *testing.T) {
+	token := api.CertificateAddToken{
+		ClientName:  "foo",
+		Fingerprint: "abcd",
+		Addresses:   []string{"10.0.0.1:8443"},
+		Secret:      "s3cr3t",
+	}
+
+	decoded, err := shared.CertificateTokenDecode(token.String())
+	require.NoError(t, err)
+	assert.Equal(t, token, *decoded)
+
+	_, err = shared.CertificateTokenDecode("not-a-token")
+	assert.Error(t, err)
+}
+
+// A join token missing any of its fields is rejected.
+func TestJoinTokenDecode(t *testing.T) {
+	token := api.ClusterMemberJoinToken{
+		ServerName:  "node2",
+		Fingerprint: "abcd",
+		Addresses:   []string{"10.0.0.1:8443", "10.0.0.2:8443"},
+		Secret:      "s3cr3t",
+	}
+
+	decoded, err := shared.JoinTokenDecode(token.String())
+	require.NoError(t, err)
+	assert.Equal(t, token, *decoded)
+
+	token.Secret = ""
+	_, err = shared.JoinTokenDecode(token.String())
+	assert.Error(t, err)
+}
@@ -180,6 +180,32 @@ var APIExtensions = []string{
 	"container_disk_ceph",
 	"virtual-machines",
 	"image_profiles",
+	"projects_restrictions",
+	"projects_networks_storage_volumes",
+	"certificate_project",
+	"certificate_token",
+	"clustering_join_token",
+	"oidc",
+	"custom_volume_backup",
+	"custom_volume_snapshot_expiry",
+	"custom_block_volumes",
+	"custom_volume_refresh",
+	"storage_pool_move_volumes",
+	"storage_volume_encryption",
+	"images_simplestreams_feed",
+	"image_protocol_oci",
+	"image_signatures",
+	"image_import_disk",
+	"metrics",
+	"warnings",
+	"audit_log",
+	"event_filters",
+	"operations_history",
+	"log_forwarding",
+	"tracing",
+	"instance_usage_history",
+	"webhooks",
+	"health",
 }
 
 // APIExtensionsCount returns the number of available API extensions.
@@ -164,6 +164,8 @@ run_test test_basic_usage "basic usage"
 run_test test_remote_url "remote url handling"
 run_test test_remote_admin "remote administration"
 run_test test_remote_usage "remote usage"
+run_test test_remote_restricted "restricted remote certificates"
+run_test test_remote_token "remote certificate add tokens"
 run_test test_clustering_enable "clustering enable"
 run_test test_clustering_membership "clustering membership"
 run_test test_clustering_containers "clustering containers"
@@ -172,6 +174,7 @@ run_test test_clustering_network "clustering network"
 run_test test_clustering_publish "clustering publish"
 run_test test_clustering_profiles "clustering profiles"
 run_test test_clustering_join_api "clustering join api"
+run_test test_clustering_join_token "clustering join token"
 run_test test_clustering_shutdown_nodes "clustering shutdown"
 run_test test_clustering_projects "clustering projects"
 run_test test_clustering_address "clustering address"
@@ -189,7 +192,9 @@ run_test test_projects_profiles_default "profiles from the global default projec
 run_test test_projects_images "images inside projects"
 run_test test_projects_images_default "images from the global default project"
 run_test test_projects_storage "projects and storage pools"
+run_test test_projects_storage_volumes "storage volumes inside projects"
 run_test test_projects_network "projects and networks"
+run_test test_projects_restrictions "projects restrictions"
 run_test test_container_devices_disk "container devices - disk"
 run_test test_container_devices_nic_p2p "container devices - nic - p2p"
 run_test test_container_devices_nic_bridged "container devices - nic - bridged"
@@ -212,6 +217,10 @@ run_test test_image_list_all_aliases "image list all aliases"
 run_test test_image_auto_update "image auto-update"
 run_test test_image_prefer_cached "image prefer cached"
 run_test test_image_import_dir "import image from directory"
+run_test test_image_import_disk "import disk image as virtual-machine image"
+run_test test_image_simplestreams "image simplestreams feed"
+run_test test_image_oci "OCI images"
+run_test test_image_signatures "image signatures"
 run_test test_concurrent_exec "concurrent exec"
 run_test test_concurrent "concurrent startup"
 run_test test_snapshots "container snapshots"
@@ -234,15 +243,29 @@ run_test test_migration "migration"
 run_test test_fdleak "fd leak"
 run_test test_storage "storage"
 run_test test_storage_volume_snapshots "storage volume snapshots"
+run_test test_storage_volume_snapshots_expiry "storage volume snapshot expiry"
 run_test test_init_auto "lxd init auto"
 run_test test_init_interactive "lxd init interactive"
 run_test test_init_preseed "lxd init preseed"
 run_test test_storage_profiles "storage profiles"
 run_test test_container_import "container import"
 run_test test_storage_volume_attach "attaching storage volumes"
+run_test test_storage_volume_block "block storage volumes"
+run_test test_storage_volume_encryption "encrypted storage volumes"
+run_test test_storage_pool_move_volumes "moving storage pool volumes"
 run_test test_storage_driver_ceph "ceph storage driver"
 run_test test_storage_driver_cephfs "cephfs storage driver"
 run_test test_resources "resources"
+run_test test_metrics "metrics"
+run_test test_warnings "warnings"
+run_test test_audit "audit log"
+run_test test_event_filters "event filtering and replay"
+run_test test_operations_history "operations history"
+run_test test_log_forwarding "log forwarding"
+run_test test_tracing "tracing"
+run_test test_instance_usage_history "instance usage history"
+run_test test_webhooks "webhooks"
+run_test test_health "health check"
 run_test test_kernel_limits "kernel limits"
 run_test test_macaroon_auth "macaroon authentication"
 run_test test_console "console"
@@ -251,6 +274,7 @@ run_test test_storage_local_volume_handling "storage local volume handling"
 run_test test_backup_import "backup import"
 run_test test_backup_export "backup export"
 run_test test_backup_rename "backup rename"
+run_test test_backup_volume_export_import "custom volume backup export and import"
 run_test test_container_local_cross_pool_handling "container local cross pool handling"
 run_test test_incremental_copy "incremental container copy"
 run_test test_profiles_project_default "profiles in default project"
@@ -0,0 +1,45 @@
+test_audit() {
+  # shellcheck disable=2039
+  local log
+  log="${LXD_DIR}/logs/audit.log"
+
+  ! lxc config set core.audit_targets foo || false
+  lxc config set core.audit_targets file
+
+  # Mutating requests are recorded, reads aren't
+  lxc profile create audit-test
+  lxc profile list
+  grep -q '"method":"POST","url":"/1.0/profiles' "${log}"
+  ! grep -q '"method":"GET"' "${log}" || false
+  grep '"url":"/1.0/profiles' "${log}" | grep -q '"result":"success"'
+
+  # Failed requests are recorded with their status code
+  ! lxc profile create audit-test || false
+  grep '"url":"/1.0/profiles' "${log}" | grep -q '"result":"failure"'
+
+  # Requests of untrusted clients are recorded as failures
+  [ "$(curl -k -s -o /dev/null -w "%{http_code}" -X POST -d '{}' "https://${LXD_ADDR}/1.0/profiles")" = "403" ]
+  grep '"url":"/1.0/profiles' "${log}" | grep '"identity":""' | grep -q '"status_code":403,"result":"failure"'
+
+  # Background operations are recorded once done, with their result
+  ensure_import_testimage
+  lxc init testimage audit-c1
+  sleep 1
+  grep '"method":"POST","url":"/1.0/instances' "${log}" | grep '"result":"success"' | grep -q '"operation":"/1.0/operations/'
+  lxc delete audit-c1
+
+  # Secrets are never written out
+  lxc config set core.trust_password audit-secret
+  ! grep -q "audit-secret" "${log}" || false
+  grep -q '"method":"PUT","url":"/1.0' "${log}"
+  lxc config unset core.trust_password
+
+  lxc profile delete audit-test
+  grep -q '"method":"DELETE","url":"/1.0/profiles/audit-test' "${log}"
+
+  lxc config unset core.audit_targets
+  lxc profile create audit-test
+  ! grep -q '"method":"POST","url":"/1.0/profiles' <(tail -n 1 "${log}") || false
+  lxc profile delete audit-test
+  rm -f "${log}"
+}
@@ -395,3 +395,76 @@ test_backup_rename() {
 
   lxc delete --force c1
 }
+
+test_backup_volume_export_import() {
+  lxd_backend=$(storage_backend "$LXD_DIR")
+
+  # Custom volume backups are only supported by the dir driver for now.
+  if [ "$lxd_backend" != "dir" ]; then
+    echo "==> SKIP: custom volume backups not supported on ${lxd_backend}"
+    return
+  fi
+
+  pool="lxdtest-$(basename "${LXD_DIR}")"
+  pool_path="${LXD_DIR}/storage-pools/${pool}/custom"
+
+  lxc storage volume create "${pool}" vol1
+  echo foo > "${pool_path}/vol1/test"
+  lxc storage volume snapshot "${pool}" vol1
+
+  mkdir "${LXD_DIR}/non-optimized"
+
+  # Volume only
+  lxc storage volume export "${pool}" vol1 "${LXD_DIR}/vol1.tar.gz" --volume-only
+  tar -xzf "${LXD_DIR}/vol1.tar.gz" -C "${LXD_DIR}/non-optimized"
+
+  [ -f "${LXD_DIR}/non-optimized/backup/index.yaml" ]
+  [ -f "${LXD_DIR}/non-optimized/backup/volume/test" ]
+  [ ! -d "${LXD_DIR}/non-optimized/backup/snapshots" ]
+  grep -q "type: custom" "${LXD_DIR}/non-optimized/backup/index.yaml"
+
+  rm -rf "${LXD_DIR}/non-optimized/"*
+
+  # With snapshots
+  lxc storage volume export "${pool}" vol1 "${LXD_DIR}/vol1.tar.gz"
+  tar -xzf "${LXD_DIR}/vol1.tar.gz" -C "${LXD_DIR}/non-optimized"
+
+  [ -f "${LXD_DIR}/non-optimized/backup/volume/test" ]
+  [ -f "${LXD_DIR}/non-optimized/backup/snapshots/snap0/test" ]
+
+  rm -rf "${LXD_DIR}/non-optimized"
+
+  # The temporary backups are removed after export
+  [ -z "$(lxc query "/1.0/storage-pools/${pool}/volumes/custom/vol1/backups" | jq -r '.[]')" ]
+
+  # Importing onto an existing volume fails
+  ! lxc storage volume import "${pool}" "${LXD_DIR}/vol1.tar.gz" || false
+
+  # Import under a new name
+  lxc storage volume import "${pool}" "${LXD_DIR}/vol1.tar.gz" vol2
+  [ "$(cat "${pool_path}/vol2/test")" = "foo" ]
+  lxc storage volume show "${pool}" vol2/snap0
+
+  # Import under the original name
+  lxc storage volume delete "${pool}" vol1
+  lxc storage volume import "${pool}" "${LXD_DIR}/vol1.tar.gz"
+  [ "$(cat "${pool_path}/vol1/test")" = "foo" ]
+
+  # Instance backups can't be imported as volumes and vice versa
+  ! lxc import "${LXD_DIR}/vol1.tar.gz" || false
+
+  # Backups are managed through the API
+  lxc query -X POST "/1.0/storage-pools/${pool}/volumes/custom/vol1/backups" -d '{"name": "backup0"}' --wait
+  lxc query "/1.0/storage-pools/${pool}/volumes/custom/vol1/backups/backup0" | jq -r .name | grep -q "^backup0$"
+  lxc query -X POST "/1.0/storage-pools/${pool}/volumes/custom/vol1/backups/backup0" -d '{"name": "backup1"}' --wait
+  [ -f "${LXD_DIR}/backups/custom/${pool}/vol1/backup1" ]
+
+  # Backups follow volume renames and are removed with the volume
+  lxc storage volume rename "${pool}" vol1 vol3
+  [ -f "${LXD_DIR}/backups/custom/${pool}/vol3/backup1" ]
+  lxc storage volume delete "${pool}" vol3
+  [ ! -d "${LXD_DIR}/backups/custom/${pool}/vol3" ]
+
+  rm "${LXD_DIR}/vol1.tar.gz"
+  lxc storage volume delete "${pool}" vol2
+}
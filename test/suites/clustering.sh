@@ -429,6 +429,9 @@ test_clustering_storage() {
   fi
 
   if [ "${driver}" = "ceph" ]; then
+    # Ceph volumes can't be encrypted in a cluster, the keys being local
+    ! LXD_DIR="${LXD_ONE_DIR}" lxc storage volume create pool1 vol1 block.encryption=luks2 || false
+
     # Test migration of ceph-based containers
     LXD_DIR="${LXD_TWO_DIR}" ensure_import_testimage
     LXD_DIR="${LXD_ONE_DIR}" lxc launch --target node2 -s pool1 testimage foo
@@ -936,6 +939,82 @@ test_clustering_join_api() {
   kill_lxd "${LXD_ONE_DIR}"
 }
 
+test_clustering_join_token() {
+  # shellcheck disable=2039,2034
+  local LXD_DIR LXD_NETNS
+
+  setup_clustering_bridge
+  prefix="lxd$$"
+  bridge="${prefix}"
+
+  setup_clustering_netns 1
+  LXD_ONE_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_ONE_DIR}"
+  ns1="${prefix}1"
+  spawn_lxd_and_bootstrap_cluster "${ns1}" "${bridge}" "${LXD_ONE_DIR}"
+
+  # Join tokens can't be requested for an existing member name.
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster add node1 || false
+
+  # Tokens show up as operations.
+  token="$(LXD_DIR="${LXD_ONE_DIR}" lxc cluster add node2 --quiet)"
+  LXD_DIR="${LXD_ONE_DIR}" lxc operation list | grep -q "Cluster join token"
+
+  setup_clustering_netns 2
+  LXD_TWO_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_TWO_DIR}"
+  ns2="${prefix}2"
+  LXD_ALT_CERT=1 LXD_NETNS="${ns2}" spawn_lxd "${LXD_TWO_DIR}" false
+
+  # A join token is only valid for the member it was issued for.
+  cat > "${LXD_TWO_DIR}/preseed.yaml" <<EOF
+cluster:
+  enabled: true
+  server_name: node3
+  server_address: 10.1.1.102:8443
+  cluster_token: ${token}
+EOF
+  ! LXD_DIR="${LXD_TWO_DIR}" lxd init --preseed < "${LXD_TWO_DIR}/preseed.yaml" || false
+
+  # The member name is checked before using up the token.
+  gen_cert wrong-member
+  curl -k -s -X POST --cert "${LXD_CONF}/wrong-member.crt" --key "${LXD_CONF}/wrong-member.key" \
+    -d "{\"type\": \"client\", \"name\": \"lxd.cluster.node3\", \"password\": \"$(echo "${token}" | base64 -d | jq -r .secret)\"}" \
+    "https://10.1.1.101:8443/1.0/certificates" | jq -r .error_code | grep -q "^403$"
+  LXD_DIR="${LXD_ONE_DIR}" lxc operation list | grep "Cluster join token" | grep -q RUNNING
+
+  # Join using the token, without any cluster password set.
+  LXD_DIR="${LXD_ONE_DIR}" lxc config unset core.trust_password
+  cat > "${LXD_TWO_DIR}/preseed.yaml" <<EOF
+cluster:
+  enabled: true
+  server_address: 10.1.1.102:8443
+  cluster_token: ${token}
+  member_config:
+  - entity: storage-pool
+    name: data
+    key: source
+    value: ""
+EOF
+  LXD_DIR="${LXD_TWO_DIR}" lxd init --preseed < "${LXD_TWO_DIR}/preseed.yaml"
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster show node2 | grep -q "message: fully operational"
+
+  # Tokens are single-use.
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc operation list | grep "Cluster join token" | grep -q RUNNING || false
+
+  LXD_DIR="${LXD_TWO_DIR}" lxd shutdown
+  LXD_DIR="${LXD_ONE_DIR}" lxd shutdown
+  sleep 0.5
+  rm -f "${LXD_TWO_DIR}/unix.socket"
+  rm -f "${LXD_ONE_DIR}/unix.socket"
+
+  teardown_clustering_netns
+  teardown_clustering_bridge
+
+  kill_lxd "${LXD_TWO_DIR}"
+  kill_lxd "${LXD_ONE_DIR}"
+}
+
 test_clustering_shutdown_nodes() {
   # shellcheck disable=2039
   local LXD_DIR
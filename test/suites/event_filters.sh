@@ -0,0 +1,41 @@
+test_event_filters() {
+  ensure_import_testimage
+
+  # shellcheck disable=2039
+  local out
+  out="${TEST_DIR}/event_filters.out"
+
+  # Only the events about the given entity and action are sent
+  lxc monitor --type=lifecycle --entity=/1.0/containers/filtered --action=container-created > "${out}" &
+  monitor_pid=$!
+  sleep 1
+
+  lxc init testimage filtered
+  lxc init testimage unfiltered
+  lxc config set filtered user.foo bar
+  sleep 1
+  kill -9 "${monitor_pid}" || true
+
+  grep -q "action: container-created" "${out}"
+  grep -q "source: /1.0/containers/filtered" "${out}"
+  ! grep -q "source: /1.0/containers/unfiltered" "${out}" || false
+  ! grep -q "action: container-updated" "${out}" || false
+
+  # Missed events can be replayed from the ID of the last one seen
+  id=$(grep "^id:" "${out}" | tail -n 1 | awk '{print $2}')
+  lxc delete unfiltered
+  lxc monitor --type=lifecycle --since="${id}" > "${out}" &
+  monitor_pid=$!
+  sleep 1
+  kill -9 "${monitor_pid}" || true
+
+  grep -q "action: container-updated" "${out}"
+  grep -q "action: container-deleted" "${out}"
+  ! grep -q "^id: ${id}$" "${out}" || false
+
+  # Events which are missing from the buffer can't be replayed
+  ! lxc monitor --since=999999999 || false
+
+  lxc delete filtered
+  rm -f "${out}"
+}
@@ -0,0 +1,26 @@
+test_health() {
+  # Untrusted clients only get the overall status (degraded if the test
+  # system is low on disk space)
+  curl -k -s "https://${LXD_ADDR}/1.0/health" | jq -r .metadata.status | grep -qE "^(ok|degraded)$"
+  [ "$(curl -k -s "https://${LXD_ADDR}/1.0/health" | jq -r .metadata.checks)" = "null" ]
+  [ "$(curl -k -s -o /dev/null -w "%{http_code}" "https://${LXD_ADDR}/1.0/health")" = "200" ]
+
+  # Admins also get the checks
+  lxc query /1.0/health | jq -r '.checks[] | select(.type == "database") | .status' | grep -q "^ok$"
+  lxc query /1.0/health | jq -r '.checks[] | select(.type == "images") | .name' | grep -q "^local$"
+  [ -z "$(lxc query /1.0/health | jq -r '.checks[] | select(.status == "failed") | .name')" ]
+
+  # A network missing its dnsmasq makes the server degraded
+  lxc network create lxdt$$ ipv4.address=192.0.2.1/24 ipv6.address=none
+  lxc query /1.0/health | jq -r ".checks[] | select(.name == \"lxdt$$\") | .status" | grep -q "^ok$"
+
+  kill -9 "$(cat "${LXD_DIR}/networks/lxdt$$/dnsmasq.pid")"
+  sleep 1
+
+  lxc query /1.0/health | jq -r ".checks[] | select(.name == \"lxdt$$\") | .message" | grep -q "dnsmasq isn't running"
+  lxc query /1.0/health | jq -r ".checks[] | select(.name == \"lxdt$$\") | .status" | grep -q "^degraded$"
+  curl -k -s "https://${LXD_ADDR}/1.0/health" | jq -r .metadata.status | grep -q "^degraded$"
+  [ "$(curl -k -s -o /dev/null -w "%{http_code}" "https://${LXD_ADDR}/1.0/health")" = "200" ]
+
+  lxc network delete lxdt$$
+}
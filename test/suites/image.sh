@@ -90,3 +90,44 @@ test_image_import_existing_alias() {
     lxc image import testimage.file --alias newimage
     lxc image delete newimage image2
 }
+
+test_image_import_disk() {
+  if ! which qemu-img >/dev/null 2>&1; then
+    echo "==> SKIP: qemu-img is required to import disk images"
+    return
+  fi
+
+  # shellcheck disable=2039
+  local disk
+  disk=$(mktemp -d -p "${TEST_DIR}" XXX)
+  qemu-img create -f raw "${disk}/disk.raw" 16M
+  qemu-img convert -f raw -O qcow2 "${disk}/disk.raw" "${disk}/disk.qcow2"
+  qemu-img convert -f raw -O vmdk "${disk}/disk.raw" "${disk}/disk.vmdk"
+
+  # Every supported format is turned into a virtual-machine image
+  for format in raw qcow2 vmdk; do
+    lxc image import "${disk}/disk.${format}" --vm --alias "disk-${format}" os=Debian release=buster
+    lxc image info "disk-${format}" | grep -q "^Type: virtual-machine$"
+    lxc image info "disk-${format}" | grep -q "os: Debian"
+    lxc image info "disk-${format}" | grep -q "description: disk.${format}"
+    lxc image delete "disk-${format}"
+  done
+
+  # The architecture can be overridden
+  lxc image import "${disk}/disk.qcow2" --vm --alias disk-arch architecture=aarch64
+  lxc image info disk-arch | grep -q "^Architecture: aarch64$"
+  lxc image delete disk-arch
+  ! lxc image import "${disk}/disk.qcow2" --vm architecture=foo || false
+
+  # Disk images referencing host files are refused
+  qemu-img create -f qcow2 -b "${disk}/disk.raw" -F raw "${disk}/backed.qcow2"
+  ! lxc image import "${disk}/backed.qcow2" --vm || false
+  qemu-img create -f qcow2 -o data_file="${disk}/data.raw" "${disk}/data-file.qcow2" 16M
+  ! lxc image import "${disk}/data-file.qcow2" --vm || false
+
+  # Disk images can't be combined with a rootfs or come from a URL
+  ! lxc image import "${disk}/disk.qcow2" "${disk}/disk.raw" --vm || false
+  ! lxc image import https://example.com/disk.qcow2 --vm || false
+
+  rm -rf "${disk}"
+}
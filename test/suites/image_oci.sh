@@ -0,0 +1,110 @@
+test_image_oci() {
+  # shellcheck disable=2039
+  local registry port pid
+  registry=$(mktemp -d -p "${TEST_DIR}" XXX)
+
+  oci_blob() {
+    # shellcheck disable=2039
+    local digest
+    digest="sha256:$(sha256sum "${1}" | cut -d' ' -f1)"
+    mkdir -p "${registry}/v2/testimage/blobs"
+    cp "${1}" "${registry}/v2/testimage/blobs/${digest}"
+    echo "${digest}"
+  }
+
+  # Base layer with busybox, some accounts and a file removed by the next layer
+  mkdir -p "${registry}/layer1/bin" "${registry}/layer1/etc" "${registry}/layer1/srv"
+  cp /bin/busybox "${registry}/layer1/bin/busybox"
+  for cmd in sh cat ls readlink sleep; do
+    ln -s busybox "${registry}/layer1/bin/${cmd}"
+  done
+  echo "root:x:0:0:root:/root:/bin/sh" > "${registry}/layer1/etc/passwd"
+  echo "app:x:1000:1000::/srv:/bin/sh" >> "${registry}/layer1/etc/passwd"
+  echo "root:x:0:" > "${registry}/layer1/etc/group"
+  echo "app:x:1000:" >> "${registry}/layer1/etc/group"
+  echo "obsolete" > "${registry}/layer1/etc/obsolete"
+  tar -C "${registry}/layer1" -czf "${registry}/layer1.tar.gz" .
+
+  # Second layer adding a file and deleting one through a whiteout
+  mkdir -p "${registry}/layer2/etc" "${registry}/layer2/srv"
+  touch "${registry}/layer2/etc/.wh.obsolete"
+  echo "hello" > "${registry}/layer2/srv/hello"
+  tar -C "${registry}/layer2" -czf "${registry}/layer2.tar.gz" .
+
+  cat > "${registry}/config.json" << EOF
+{"architecture": "$(uname -m)", "os": "linux", "created": "2020-01-01T00:00:00Z",
+ "config": {"User": "app", "Env": ["FOO=bar", "PATH=/bin"], "Entrypoint": ["/bin/sh", "-c"],
+            "Cmd": ["while true; do sleep 1; done"], "WorkingDir": "/srv"}}
+EOF
+
+  config=$(oci_blob "${registry}/config.json")
+  layer1=$(oci_blob "${registry}/layer1.tar.gz")
+  layer2=$(oci_blob "${registry}/layer2.tar.gz")
+
+  mkdir -p "${registry}/v2/testimage/manifests"
+  cat > "${registry}/v2/testimage/manifests/latest" << EOF
+{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json",
+ "config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": "${config}", "size": $(stat -c %s "${registry}/config.json")},
+ "layers": [
+  {"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "${layer1}", "size": $(stat -c %s "${registry}/layer1.tar.gz")},
+  {"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "${layer2}", "size": $(stat -c %s "${registry}/layer2.tar.gz")}
+ ]}
+EOF
+  manifest="sha256:$(sha256sum "${registry}/v2/testimage/manifests/latest" | cut -d' ' -f1)"
+
+  # Serve the image from a static local registry
+  port=$(local_tcp_port)
+  (cd "${registry}" && exec python3 -m http.server --bind 127.0.0.1 "${port}") > /dev/null 2>&1 &
+  pid=$!
+  for _ in $(seq 20); do
+    curl -s -f "http://127.0.0.1:${port}/v2/testimage/manifests/latest" > /dev/null && break
+    sleep 0.5
+  done
+
+  lxc remote add ocitest "http://127.0.0.1:${port}" --protocol=oci
+  lxc remote list | grep ocitest | grep -q oci
+  ! lxc remote add ocibad "ftp://127.0.0.1:${port}" --protocol=oci || false
+
+  # The layers are converted into a unified image and the runtime configuration is mapped
+  lxc launch ocitest:testimage c1
+  lxc config get c1 image.oci.digest | grep -q "^${manifest}$"
+  lxc config get c1 environment.FOO | grep -q "^bar$"
+  lxc config get c1 oci.uid | grep -q "^1000$"
+  [ -z "$(lxc config get c1 raw.lxc)" ]
+  lxc exec c1 -- cat /srv/hello | grep -q "^hello$"
+  ! lxc exec c1 -- ls /etc/obsolete || false
+  lxc exec c1 -- cat /proc/1/cmdline | tr '\0' ' ' | grep -q "while true"
+  lxc exec c1 -- cat /proc/1/status | grep "^Uid:" | grep -q "1000"
+  [ "$(lxc exec c1 -- readlink /proc/1/cwd)" = "/srv" ]
+
+  # Configuration set at creation takes precedence
+  lxc init ocitest:testimage c2 -c environment.FOO=custom
+  lxc config get c2 environment.FOO | grep -q "^custom$"
+
+  # The same manifest isn't converted again
+  [ "$(lxc image list --format csv -c d | grep -c "testimage:latest")" = "1" ]
+
+  fp=$(lxc config get c1 volatile.base_image)
+  lxc delete -f c1 c2
+  lxc image delete "${fp}"
+
+  # Images can also be copied straight from the registry
+  lxc image copy ocitest:testimage local: --alias ocitest
+  lxc image info ocitest | grep -q "oci.digest: ${manifest}"
+
+  # The runtime configuration of uploaded images isn't trusted
+  lxc image export ocitest "${TEST_DIR}/ocitest"
+  lxc image delete ocitest
+  lxc image import "${TEST_DIR}/ocitest.tar.gz" --alias ocitest
+  lxc init ocitest c3
+  lxc config get c3 image.oci.digest | grep -q "^${manifest}$"
+  [ -z "$(lxc config get c3 oci.entrypoint)" ]
+  [ -z "$(lxc config get c3 environment.FOO)" ]
+  lxc delete c3
+  rm "${TEST_DIR}/ocitest.tar.gz"
+  lxc image delete ocitest
+
+  lxc remote remove ocitest
+  kill -9 "${pid}"
+  rm -rf "${registry}"
+}
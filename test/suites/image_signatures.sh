@@ -0,0 +1,39 @@
+test_image_signatures() {
+  # shellcheck disable=2039
+  local fp signature
+  ensure_import_testimage
+
+  lxc init testimage c1
+  lxc publish c1 --alias signed --sign
+  lxc delete c1
+  fp=$(lxc image info signed | awk '/^Fingerprint/ { print $2 }')
+
+  # Signed images are only trusted once the key is in the keyring
+  lxc image info testimage | grep -q "^Signature: unsigned$"
+  lxc image info signed | grep -q "^Signature: untrusted$"
+  ! lxc config set images.trusted_keys "not a key" || false
+  lxc config set images.trusted_keys "$(cat "${LXD_DIR}/server.crt")"
+  lxc image info signed | grep -q "^Signature: trusted$"
+  lxc image list --format json | jq -r ".[] | select(.fingerprint == \"${fp}\") | .signature_status" | grep -q "^trusted$"
+
+  # Signatures travel with the image when imported
+  signature=$(my_curl "https://${LXD_ADDR}/1.0/images/${fp}" | jq -r .metadata.signature)
+  echo "${signature}" > "${TEST_DIR}/signature"
+  lxc image export signed "${TEST_DIR}/signed"
+  lxc image delete signed
+  lxc image import "${TEST_DIR}/signed".tar.* --alias signed --signature "${TEST_DIR}/signature"
+  lxc image info signed | grep -q "^Signature: trusted$"
+  lxc image delete signed
+
+  # Unsigned images are rejected once verification is enforced
+  lxc config set images.signature_verification enforce
+  ! lxc image import "${TEST_DIR}/signed".tar.* --alias signed || false
+  echo "Zm9vYmFy" > "${TEST_DIR}/bad-signature"
+  ! lxc image import "${TEST_DIR}/signed".tar.* --alias signed --signature "${TEST_DIR}/bad-signature" || false
+  lxc image import "${TEST_DIR}/signed".tar.* --alias signed --signature "${TEST_DIR}/signature"
+
+  lxc image delete signed
+  lxc config unset images.signature_verification
+  lxc config unset images.trusted_keys
+  rm -f "${TEST_DIR}/signature" "${TEST_DIR}/bad-signature" "${TEST_DIR}/signed".tar.*
+}
@@ -0,0 +1,47 @@
+test_image_simplestreams() {
+  # shellcheck disable=2039
+  local LXD2_DIR LXD2_ADDR
+  LXD2_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD2_DIR}"
+  spawn_lxd "${LXD2_DIR}" true
+  LXD2_ADDR=$(cat "${LXD2_DIR}/lxd.addr")
+
+  (LXD_DIR=${LXD2_DIR} deps/import-busybox --alias testimage --public)
+  (LXD_DIR=${LXD2_DIR} deps/import-busybox --alias privateimage --template create)
+  (LXD_DIR=${LXD2_DIR} deps/import-busybox --alias splitimage --public --split --template start)
+  fp=$(LXD_DIR=${LXD2_DIR} lxc image info testimage | awk -F: '/^Fingerprint/ { print $2 }' | awk '{ print $1 }')
+  fp_split=$(LXD_DIR=${LXD2_DIR} lxc image info splitimage | awk -F: '/^Fingerprint/ { print $2 }' | awk '{ print $1 }')
+
+  # The feed is disabled by default
+  ! curl -k -s -f "https://${LXD2_ADDR}/streams/v1/index.json" || false
+  (LXD_DIR=${LXD2_DIR} lxc config set images.simplestreams_feed true)
+
+  # Only public images are published, along with their aliases
+  curl -k -s -f "https://${LXD2_ADDR}/streams/v1/index.json" | jq -r '.index.images.path' | grep -q "^streams/v1/images.json$"
+  curl -k -s -f "https://${LXD2_ADDR}/streams/v1/images.json" > "${TEST_DIR}/images.json"
+  jq -r '.products[].aliases' "${TEST_DIR}/images.json" | grep -q "^testimage$"
+  jq -r '.products[].aliases' "${TEST_DIR}/images.json" | grep -q "^splitimage$"
+  ! jq -r '.products[].aliases' "${TEST_DIR}/images.json" | grep -q "privateimage" || false
+
+  # Unified images are a single file whose hash is the fingerprint
+  path=$(jq -r '.products[].versions[].items[] | select(.ftype == "lxd_combined.tar.gz") | .path' "${TEST_DIR}/images.json")
+  [ "$(curl -k -s -f "https://${LXD2_ADDR}/${path}" | sha256sum | cut -d' ' -f1)" = "${fp}" ]
+
+  # Split images are published with the combined fingerprint on their metadata
+  jq -r '.products[].versions[].items[] | select(.ftype == "lxd.tar.xz") | .combined_rootxz_sha256' "${TEST_DIR}/images.json" | grep -q "^${fp_split}$"
+  path=$(jq -r '.products[].versions[].items[] | select(.ftype == "root.tar.xz") | .path' "${TEST_DIR}/images.json")
+  curl -k -s -f "https://${LXD2_ADDR}/${path}" > /dev/null
+
+  # Unknown files and non-public images can't be downloaded
+  ! curl -k -s -f "https://${LXD2_ADDR}/streams/v1/images/${fp}/foo" || false
+  fp_private=$(LXD_DIR=${LXD2_DIR} lxc image info privateimage | awk -F: '/^Fingerprint/ { print $2 }' | awk '{ print $1 }')
+  ! curl -k -s -f "https://${LXD2_ADDR}/streams/v1/images/${fp_private}/lxd_combined.tar.xz" || false
+
+  # Projects are published under their own path
+  (LXD_DIR=${LXD2_DIR} lxc project create foo)
+  curl -k -s -f "https://${LXD2_ADDR}/streams/projects/foo/streams/v1/images.json" | jq -r '.products | length' | grep -q "^0$"
+  (LXD_DIR=${LXD2_DIR} lxc project delete foo)
+
+  rm -f "${TEST_DIR}/images.json"
+  kill_lxd "$LXD2_DIR"
+}
@@ -0,0 +1,31 @@
+test_instance_usage_history() {
+  ensure_import_testimage
+
+  # Invalid settings are rejected
+  ! lxc config set core.usage_history_interval -1 || false
+  ! lxc config set core.usage_history_size foo || false
+
+  lxc config set core.usage_history_interval 1
+  lxc config set core.usage_history_size 3
+
+  lxc launch testimage usage
+  sleep 5
+
+  # Only the most recent samples are kept
+  [ "$(lxc query /1.0/instances/usage/state/history | jq length)" = "3" ]
+  lxc query /1.0/instances/usage/state/history | jq -e '.[0].memory_usage > 0'
+  lxc query /1.0/instances/usage/state/history | jq -e '.[0].processes > 0'
+
+  # Samples can be filtered by time
+  [ "$(lxc query "/1.0/instances/usage/state/history?since=2999-01-01T00:00:00Z" | jq length)" = "0" ]
+  ! lxc query "/1.0/instances/usage/state/history?since=yesterday" || false
+  ! lxc query /1.0/instances/missing/state/history || false
+
+  # lxc top reports the running instances
+  lxc top --count=1 --delay=1 --format=csv | grep -q "^usage,"
+  ! lxc top --sort=foo --count=1 || false
+
+  lxc delete -f usage
+  lxc config unset core.usage_history_interval
+  lxc config unset core.usage_history_size
+}
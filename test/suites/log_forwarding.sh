@@ -0,0 +1,38 @@
+test_log_forwarding() {
+  # shellcheck disable=2039
+  local out port
+  out="${TEST_DIR}/log_forwarding.out"
+  port="$(local_tcp_port)"
+
+  # Invalid settings are rejected
+  ! lxc config set logging.forward.protocol ftp || false
+  ! lxc config set logging.forward.streams daemon,foo || false
+
+  # Forward the lifecycle events to a syslog listener
+  socat -u "UDP-RECV:${port},bind=127.0.0.1" "OPEN:${out},creat,append" &
+  socat_pid=$!
+  sleep 0.5
+
+  lxc config set logging.forward.streams lifecycle,audit
+  lxc config set logging.forward.endpoint "127.0.0.1:${port}"
+  lxc config set logging.forward.protocol syslog-udp
+
+  ensure_import_testimage
+  lxc init testimage forwarded
+  sleep 1
+
+  grep -q "lxd [0-9]* lifecycle - container-created project=default instance=forwarded" "${out}"
+  grep -q "lxd [0-9]* audit - POST /1.0/\(containers\|instances\)" "${out}"
+  ! grep -q "lxd [0-9]* daemon - " "${out}" || false
+
+  # Nothing is forwarded once disabled
+  lxc config unset logging.forward.protocol
+  lxc delete forwarded
+  sleep 1
+  ! grep -q "container-deleted" "${out}" || false
+
+  kill -9 "${socat_pid}" || true
+  lxc config unset logging.forward.endpoint
+  lxc config unset logging.forward.streams
+  rm -f "${out}"
+}
@@ -0,0 +1,41 @@
+test_metrics() {
+  # shellcheck disable=2039
+  local fingerprint
+  ensure_import_testimage
+
+  lxc launch testimage c1
+  lxc init testimage c2
+
+  # Trusted clients get the metrics of the instances and of the daemon
+  my_curl "https://${LXD_ADDR}/1.0/metrics" > "${TEST_DIR}/metrics.txt"
+  grep -q '^lxd_instance_running{name="c1",project="default",type="container"} 1$' "${TEST_DIR}/metrics.txt"
+  grep -q '^lxd_instance_running{name="c2",project="default",type="container"} 0$' "${TEST_DIR}/metrics.txt"
+  grep -q '^lxd_procs{name="c1",project="default",type="container"} [1-9]' "${TEST_DIR}/metrics.txt"
+  grep -q '^lxd_memory_usage_bytes{name="c1",' "${TEST_DIR}/metrics.txt"
+  grep -q '^# TYPE lxd_cpu_seconds counter$' "${TEST_DIR}/metrics.txt"
+  grep -q '^lxd_cpu_seconds_total{name="c1",' "${TEST_DIR}/metrics.txt"
+  if [ -e /sys/fs/cgroup/blkio ] || [ -e /sys/fs/cgroup/io.stat ]; then
+    grep -q '^# TYPE lxd_disk_read_bytes counter$' "${TEST_DIR}/metrics.txt"
+    grep -q '^lxd_disk_written_bytes_total{name="c1",' "${TEST_DIR}/metrics.txt"
+  fi
+  grep -q '^lxd_go_goroutines [1-9]' "${TEST_DIR}/metrics.txt"
+  [ "$(tail -n1 "${TEST_DIR}/metrics.txt")" = "# EOF" ]
+  curl -k -s -o /dev/null -w "%{content_type}" --cert "${LXD_CONF}/client.crt" --key "${LXD_CONF}/client.key" "https://${LXD_ADDR}/1.0/metrics" | grep -q "^application/openmetrics-text"
+
+  # Untrusted clients are rejected
+  [ "$(curl -k -s -o /dev/null -w "%{http_code}" "https://${LXD_ADDR}/1.0/metrics")" = "403" ]
+
+  # Metrics certificates can only access the metrics
+  gen_cert metrics
+  [ "$(curl -k -s -o /dev/null -w "%{http_code}" --cert "${LXD_CONF}/metrics.crt" --key "${LXD_CONF}/metrics.key" "https://${LXD_ADDR}/1.0/metrics")" = "403" ]
+  lxc config trust add "${LXD_CONF}/metrics.crt" --type=metrics
+  curl -k -s --cert "${LXD_CONF}/metrics.crt" --key "${LXD_CONF}/metrics.key" "https://${LXD_ADDR}/1.0/metrics" | grep -q '^lxd_instance_running{name="c1",'
+  [ "$(curl -k -s -o /dev/null -w "%{http_code}" --cert "${LXD_CONF}/metrics.crt" --key "${LXD_CONF}/metrics.key" "https://${LXD_ADDR}/1.0/instances")" = "403" ]
+
+  fingerprint="$(lxc query "/1.0/certificates?recursion=1" | jq -r '.[] | select(.type == "metrics") | .fingerprint')"
+  lxc config trust remove "${fingerprint}"
+  [ "$(curl -k -s -o /dev/null -w "%{http_code}" --cert "${LXD_CONF}/metrics.crt" --key "${LXD_CONF}/metrics.key" "https://${LXD_ADDR}/1.0/metrics")" = "403" ]
+
+  lxc delete -f c1 c2
+  rm -f "${TEST_DIR}/metrics.txt"
+}
@@ -329,6 +329,30 @@ migration() {
   lxc_remote storage volume delete l2:"$remote_pool2" vol5
   lxc_remote storage volume delete l2:"$remote_pool2" vol6
 
+  # remote storage volume refresh (new storage layer only)
+  if [ "$lxd_backend" = "dir" ] && [ "$lxd2_backend" = "dir" ]; then
+    lxc_remote storage volume create l1:"$remote_pool1" vol1
+    lxc_remote storage volume snapshot l1:"$remote_pool1" vol1 snap0
+
+    # Refreshing a missing volume does a full copy.
+    lxc_remote storage volume copy l1:"$remote_pool1/vol1" l2:"$remote_pool2/vol1" --refresh
+    lxc_remote storage volume show l2:"$remote_pool2" vol1/snap0
+
+    # Only the new snapshot is transferred and the removed one is deleted.
+    lxc_remote storage volume snapshot l1:"$remote_pool1" vol1 snap1
+    lxc_remote storage volume delete l1:"$remote_pool1" vol1/snap0
+    ! lxc_remote storage volume copy l1:"$remote_pool1/vol1" l2:"$remote_pool2/vol1" || false
+    lxc_remote storage volume copy l1:"$remote_pool1/vol1" l2:"$remote_pool2/vol1" --refresh
+    lxc_remote storage volume show l2:"$remote_pool2" vol1/snap1
+    ! lxc_remote storage volume show l2:"$remote_pool2" vol1/snap0 || false
+
+    # Refresh is only supported between servers.
+    ! lxc_remote storage volume copy l1:"$remote_pool1/vol1" l1:"$remote_pool1/vol2" --refresh || false
+
+    lxc_remote storage volume delete l1:"$remote_pool1" vol1
+    lxc_remote storage volume delete l2:"$remote_pool2" vol1
+  fi
+
   # Test some migration between projects
   lxc_remote project create l1:proj -c features.images=false -c features.profiles=false
   lxc_remote project switch l1 proj
@@ -0,0 +1,31 @@
+test_operations_history() {
+  ensure_import_testimage
+
+  # Completed operations are kept in the history
+  lxc init testimage history-test
+  lxc operation list --history | grep -q "Creating container"
+  lxc query "/1.0/operations?all-history=1&recursion=1" | jq -r '.success[].description' | grep -q "^Creating container$"
+  lxc query "/1.0/operations?all-history=1&recursion=1" | jq -r '.success[].requestor.protocol' | grep -q "^unix$"
+
+  # Completed operations can still be retrieved individually
+  id=$(lxc query "/1.0/operations?all-history=1&recursion=1" | jq -r '.success[] | select(.description == "Creating container") | .id' | tail -n 1)
+  [ "$(lxc query "/1.0/operations/${id}" | jq -r .status)" = "Success" ]
+
+  # Failed operations record their error
+  ! lxc query -X PUT /1.0/containers/history-test/state -d '{"action": "unfreeze"}' --wait || false
+  lxc operation list --history --status=failure | grep -q "FAILURE"
+  [ -z "$(lxc query "/1.0/operations?all-history=1&status=failure&recursion=1" | jq -r '.success // empty')" ]
+  lxc query "/1.0/operations?all-history=1&status=failure&recursion=1" | jq -r '.failure[].err' | grep -q "The container isn't running"
+
+  # Filters are validated
+  ! lxc query "/1.0/operations?all-history=1&since=yesterday" || false
+  [ -z "$(lxc query "/1.0/operations?all-history=1&since=2100-01-01T00:00:00Z" | jq -r '.[]?')" ]
+
+  # Disabling the history stops recording completed operations
+  lxc config set core.operations_history_expiry 0
+  lxc config set history-test user.foo bar
+  ! lxc query "/1.0/operations?all-history=1&recursion=1" | jq -r '.success[].description' | grep -q "^Updating container$" || false
+  lxc config unset core.operations_history_expiry
+
+  lxc delete history-test
+}
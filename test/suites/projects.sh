@@ -19,6 +19,10 @@ test_projects_crud() {
   # All features are enabled by default
   lxc project show foo | grep -q 'features.images: "true"'
   lxc project get foo "features.profiles" | grep -q 'true'
+  lxc project get foo "features.storage.volumes" | grep -q 'true'
+
+  # Project-specific networks are disabled by default
+  lxc project get foo "features.networks" | grep -q 'false'
 
   # Trying to create a project with the same name fails
   ! lxc project create foo || false
@@ -425,7 +429,9 @@ test_projects_storage() {
 
   lxc storage volume create "${pool}" vol
 
-  lxc project create foo
+  # Without the storage volumes feature, custom volumes come from the default
+  # project.
+  lxc project create foo -c features.storage.volumes=false
   lxc project switch foo
 
   lxc storage volume list "${pool}" | grep custom | grep -q vol
@@ -439,6 +445,40 @@ test_projects_storage() {
   lxc project delete foo
 }
 
+# Custom storage volumes inside projects.
+test_projects_storage_volumes() {
+  pool="lxdtest-$(basename "${LXD_DIR}")"
+
+  # Only the dir and cephfs drivers support volumes in projects.
+  if [ "$(storage_backend "$LXD_DIR")" != "dir" ]; then
+    echo "==> SKIP: project storage volumes are only supported on dir"
+    return
+  fi
+
+  lxc project create foo
+  lxc project switch foo
+
+  # Create a custom volume in the project
+  lxc storage volume create "${pool}" vol
+  lxc storage volume list "${pool}" | grep custom | grep -q vol
+  lxc project show foo | grep -q "/1.0/storage-pools/${pool}/volumes/custom/vol?project=foo"
+
+  # The project can't be deleted while it has volumes
+  ! lxc project delete foo || false
+
+  lxc project switch default
+
+  # The volume isn't visible from the default project
+  ! lxc storage volume list "${pool}" | grep custom | grep -q vol || false
+
+  # The same name can be reused in another project
+  lxc storage volume create "${pool}" vol
+  lxc storage volume delete "${pool}" vol
+
+  lxc storage volume delete "${pool}" vol --project foo
+  lxc project delete foo
+}
+
 # Interaction between projects and networks.
 test_projects_network() {
   # Standard bridge with random subnet and a bunch of options
@@ -467,4 +507,89 @@ test_projects_network() {
   lxc project delete foo
 
   lxc network delete "${network}"
+
+  # Create a project with its own set of networks
+  lxc project create foo -c features.networks=true
+  lxc network create "${network}" --project foo
+  lxc network list --project foo | grep -q "${network}"
+  lxc project show foo | grep -q "/1.0/networks/${network}?project=foo"
+
+  # The network isn't visible from the default project
+  ! lxc network list | grep -q "${network}" || false
+  ! lxc network show "${network}" || false
+
+  # Network names are unique across all projects
+  ! lxc network create "${network}" || false
+
+  lxc network delete "${network}" --project foo
+  lxc project delete foo
+}
+
+# Restricted projects.
+test_projects_restrictions() {
+  # Create a restricted project
+  lxc project create p1 -c restricted=true
+  lxc project switch p1
+
+  # Import an image into the project and add a root device
+  deps/import-busybox --project p1 --alias testimage
+  lxc profile device add default root disk path="/" pool="lxdtest-$(basename "${LXD_DIR}")"
+
+  # Privileged, nested and low-level configuration is forbidden
+  ! lxc init testimage c1 -c security.privileged=true || false
+  ! lxc init testimage c1 -c security.nesting=true || false
+  ! lxc init testimage c1 -c raw.lxc=lxc.console.logfile=/tmp/log || false
+  ! lxc profile set default security.privileged true || false
+
+  lxc init testimage c1
+
+  # Host devices and host paths are forbidden
+  ! lxc config device add c1 char unix-char path=/dev/kvm || false
+  ! lxc config device add c1 tmp disk source=/tmp path=/mnt || false
+  ! lxc profile device add default tmp disk source=/tmp path=/mnt || false
+
+  # Host paths can be allowed under a prefix
+  lxc project switch default
+  lxc project set p1 restricted.devices.disk.paths="${TEST_DIR}"
+  lxc project switch p1
+  mkdir -p "${TEST_DIR}/shared"
+  lxc config device add c1 shared disk source="${TEST_DIR}/shared" path=/mnt
+  ! lxc config device add c1 tmp disk source=/tmp path=/tmp || false
+
+  # Symlinks under an allowed prefix are resolved
+  ln -s /tmp "${TEST_DIR}/tmp-link"
+  ! lxc config device add c1 tmp disk source="${TEST_DIR}/tmp-link" path=/tmp || false
+  rm "${TEST_DIR}/tmp-link"
+
+  # Restrictions can be relaxed
+  lxc project switch default
+  lxc project set p1 restricted.containers.privilege=allow
+  lxc project switch p1
+  lxc config set c1 security.privileged true
+
+  # Restrictions can't be tightened while instances violate them
+  lxc project switch default
+  ! lxc project set p1 restricted.containers.privilege=unprivileged || false
+  ! lxc project unset p1 restricted.devices.disk.paths || false
+  lxc project switch p1
+
+  # Turning off the restrictions is always possible
+  lxc project switch default
+  lxc project set p1 restricted=false
+  lxc project switch p1
+  lxc config device add c1 tmp disk source=/tmp path=/tmp
+
+  # Snapshots taken before the restrictions can't be restored
+  lxc snapshot c1 snap0
+  lxc config device remove c1 tmp
+  lxc project switch default
+  lxc project set p1 restricted=true
+  lxc project switch p1
+  ! lxc restore c1 snap0 || false
+  lxc delete c1/snap0
+
+  lxc delete c1
+  lxc image delete testimage
+  lxc project switch default
+  lxc project delete p1
 }
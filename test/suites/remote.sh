@@ -170,3 +170,96 @@ test_remote_usage() {
 
   kill_lxd "$LXD2_DIR"
 }
+
+test_remote_restricted() {
+  # shellcheck disable=2039
+  local LXD_CONF_RESTRICTED fingerprint
+
+  lxc project create foo
+  lxc project create bar
+
+  # Add a certificate only allowed to access the foo project.
+  gen_cert restricted
+  lxc config trust add "${LXD_CONF}/restricted.crt" --restricted --projects foo
+  fingerprint="$(lxc query "/1.0/certificates?recursion=1" | jq -r '.[] | select(.restricted) | .fingerprint')"
+  [ -n "${fingerprint}" ]
+  lxc query "/1.0/certificates/${fingerprint}" | jq -r '.projects[]' | grep -qx foo
+
+  LXD_CONF_RESTRICTED=$(mktemp -d -p "${TEST_DIR}" XXX)
+  cp "${LXD_CONF}/restricted.crt" "${LXD_CONF_RESTRICTED}/client.crt"
+  cp "${LXD_CONF}/restricted.key" "${LXD_CONF_RESTRICTED}/client.key"
+  LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote remote add restricted "${LXD_ADDR}" --accept-certificate
+
+  # The restricted client only sees its own project.
+  LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote project list restricted: | grep -q foo
+  ! LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote project list restricted: | grep -q bar || false
+  LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote profile list restricted: --project foo
+  ! LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote profile list restricted: --project bar || false
+
+  # The restricted client can't administer the server.
+  ! LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote config set restricted: images.auto_update_interval 10 || false
+  ! LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote project set restricted:foo user.foo bar || false
+  ! LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote project create restricted:baz || false
+
+  # The restricted client only sees its own certificate.
+  [ "$(LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote config trust list restricted: --format csv | wc -l)" = "1" ]
+
+  # The restricted client only gets the overall health status.
+  LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote query restricted:/1.0/health | jq -r .status | grep -qE "^(ok|degraded)$"
+  [ "$(LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote query restricted:/1.0/health | jq -r .checks)" = "null" ]
+
+  # Events of other projects and logging events are off-limits.
+  ! LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote query "restricted:/1.0/events?project=bar" || false
+  ! LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote query "restricted:/1.0/events?project=foo&type=logging" || false
+
+  # Granting access to another project takes effect right away.
+  lxc query -X PATCH -d '{"projects": ["foo", "bar"]}' "/1.0/certificates/${fingerprint}"
+  LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote profile list restricted: --project bar
+
+  lxc config trust remove "${fingerprint}"
+  ! LXD_CONF="${LXD_CONF_RESTRICTED}" lxc_remote project list restricted: || false
+  rm -rf "${LXD_CONF_RESTRICTED}"
+
+  lxc project delete foo
+  lxc project delete bar
+}
+
+test_remote_token() {
+  # shellcheck disable=2039
+  local LXD_CONF_TOKEN token fingerprint
+
+  # Tokens require the name of the new client.
+  ! lxc query -X POST -d '{"token": true, "type": "client"}' /1.0/certificates || false
+
+  # Tokens are admin-only operations.
+  token="$(lxc config trust add --name token-client --restricted --projects default | tail -n1)"
+  lxc operation list | grep -q "Certificate add token"
+
+  # Adding the remote with the token needs neither the trust password nor
+  # accepting the server certificate.
+  lxc config unset core.trust_password
+  LXD_CONF_TOKEN=$(mktemp -d -p "${TEST_DIR}" XXX)
+  LXD_CONF="${LXD_CONF_TOKEN}" lxc_remote remote add token-remote "${token}"
+  LXD_CONF="${LXD_CONF_TOKEN}" lxc_remote info token-remote: | grep -q 'auth: trusted'
+
+  # The certificate gets the name and restrictions requested with the token.
+  fingerprint="$(lxc query "/1.0/certificates?recursion=1" | jq -r '.[] | select(.name == "token-client") | .fingerprint')"
+  [ -n "${fingerprint}" ]
+  [ "$(lxc query "/1.0/certificates/${fingerprint}" | jq -r '.restricted')" = "true" ]
+
+  # Restricted clients don't see the pending tokens, even those of the
+  # default project they have access to.
+  lxc config trust add --name token-other | tail -n1
+  ! LXD_CONF="${LXD_CONF_TOKEN}" lxc_remote operation list token-remote: | grep -q "Certificate add token" || false
+  ! LXD_CONF="${LXD_CONF_TOKEN}" lxc_remote query "token-remote:/1.0/operations?recursion=1" | grep -q secret || false
+  lxc operation list --format csv | grep "Certificate add token" | cut -d, -f1 | xargs -r -n1 lxc operation delete
+
+  # Tokens are single-use.
+  rm -rf "${LXD_CONF_TOKEN}"
+  LXD_CONF_TOKEN=$(mktemp -d -p "${TEST_DIR}" XXX)
+  ! LXD_CONF="${LXD_CONF_TOKEN}" lxc_remote remote add token-remote "${token}" || false
+
+  lxc config trust remove "${fingerprint}"
+  lxc config set core.trust_password foo
+  rm -rf "${LXD_CONF_TOKEN}"
+}
@@ -0,0 +1,65 @@
+test_storage_pool_move_volumes() {
+  lxd_backend=$(storage_backend "$LXD_DIR")
+  if [ "$lxd_backend" != "dir" ]; then
+    echo "==> SKIP: moving storage pool volumes is only tested on dir"
+    return
+  fi
+
+  ensure_import_testimage
+
+  # shellcheck disable=2039
+  local storage_pool target_pool
+  storage_pool="lxdtest-$(basename "${LXD_DIR}")"
+  target_pool="${storage_pool}-target"
+
+  lxc storage create "${target_pool}" dir
+
+  lxc init testimage c1 -s "${storage_pool}"
+  lxc snapshot c1 snap0
+  lxc storage volume create "${storage_pool}" vol1
+  lxc storage volume snapshot "${storage_pool}" vol1 snap0
+  lxc storage volume attach "${storage_pool}" vol1 c1 /mnt
+
+  # Running instances block the move.
+  lxc start c1
+  ! lxc storage move-volumes "${storage_pool}" "${target_pool}" || false
+  lxc stop c1 --force
+
+  # So do running instances of other pools using the custom volumes.
+  lxc init testimage c2 -s "${target_pool}"
+  lxc storage volume attach "${storage_pool}" vol1 c2 /mnt
+  lxc start c2
+  ! lxc storage move-volumes "${storage_pool}" "${target_pool}" || false
+  lxc delete c2 --force
+
+  # The target pool must differ and exist.
+  ! lxc storage move-volumes "${storage_pool}" "${storage_pool}" || false
+  ! lxc storage move-volumes "${storage_pool}" invalid || false
+
+  lxc storage move-volumes "${storage_pool}" "${target_pool}"
+
+  # Everything now lives on the target pool.
+  [ -d "${LXD_DIR}/storage-pools/${target_pool}/containers/c1" ]
+  [ -d "${LXD_DIR}/storage-pools/${target_pool}/containers-snapshots/c1/snap0" ]
+  [ -d "${LXD_DIR}/storage-pools/${target_pool}/custom/vol1" ]
+  [ ! -d "${LXD_DIR}/storage-pools/${storage_pool}/containers/c1" ]
+  [ ! -d "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol1" ]
+  lxc storage volume show "${target_pool}" vol1/snap0
+  ! lxc storage volume show "${storage_pool}" vol1 || false
+  lxc config device get c1 root pool | grep -q "^${target_pool}$"
+  lxc config device get c1 vol1 pool | grep -q "^${target_pool}$"
+  [ "$(lxc query /1.0/instances/c1/snapshots/snap0 | jq -r .devices.root.pool)" = "${target_pool}" ]
+
+  lxc start c1
+  lxc exec c1 -- mountpoint /mnt
+  lxc stop c1 --force
+
+  # And back again.
+  lxc storage move-volumes "${target_pool}" "${storage_pool}"
+  lxc config device get c1 root pool | grep -q "^${storage_pool}$"
+  lxc storage volume show "${storage_pool}" vol1/snap0
+
+  lxc delete c1
+  lxc storage volume delete "${storage_pool}" vol1
+  lxc storage delete "${target_pool}"
+}
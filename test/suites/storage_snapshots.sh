@@ -73,3 +73,49 @@ test_storage_volume_snapshots() {
   LXD_DIR="${LXD_DIR}"
   kill_lxd "${LXD_STORAGE_DIR}"
 }
+
+test_storage_volume_snapshots_expiry() {
+  # shellcheck disable=2039
+  local storage_pool storage_volume
+  storage_pool="lxdtest-$(basename "${LXD_DIR}")"
+  storage_volume="${storage_pool}-vol"
+
+  lxc storage volume create "${storage_pool}" "${storage_volume}"
+
+  # Invalid values are rejected
+  ! lxc storage volume set "${storage_pool}" "${storage_volume}" snapshots.schedule "foo" || false
+  ! lxc storage volume set "${storage_pool}" "${storage_volume}" snapshots.expiry "foo" || false
+  ! lxc storage set "${storage_pool}" volume.snapshots.expiry "foo" || false
+
+  # No expiry by default
+  lxc storage volume snapshot "${storage_pool}" "${storage_volume}"
+  lxc query "/1.0/storage-pools/${storage_pool}/volumes/custom/${storage_volume}/snapshots/snap0" | grep -q '"expires_at": "0001-01-01T00:00:00Z"'
+
+  # Expiry and pattern taken from the pool defaults
+  lxc storage set "${storage_pool}" volume.snapshots.expiry 1d
+  lxc storage set "${storage_pool}" volume.snapshots.pattern "pool%d"
+  lxc storage volume snapshot "${storage_pool}" "${storage_volume}"
+  ! lxc query "/1.0/storage-pools/${storage_pool}/volumes/custom/${storage_volume}/snapshots/pool0" | grep -q '"expires_at": "0001-01-01T00:00:00Z"' || false
+
+  # Volume keys override the pool defaults
+  lxc storage volume set "${storage_pool}" "${storage_volume}" snapshots.pattern "vol%d"
+  lxc storage volume snapshot "${storage_pool}" "${storage_volume}"
+  lxc query "/1.0/storage-pools/${storage_pool}/volumes/custom/${storage_volume}/snapshots/vol0"
+
+  # The expiry date can be changed
+  lxc query -X PUT -d '{"description": "", "expires_at": "0001-01-01T00:00:00Z"}' "/1.0/storage-pools/${storage_pool}/volumes/custom/${storage_volume}/snapshots/vol0"
+  lxc query "/1.0/storage-pools/${storage_pool}/volumes/custom/${storage_volume}/snapshots/vol0" | grep -q '"expires_at": "0001-01-01T00:00:00Z"'
+
+  # Expired snapshots get pruned
+  lxc query -X PUT -d '{"description": "", "expires_at": "2000-01-01T00:00:00Z"}' "/1.0/storage-pools/${storage_pool}/volumes/custom/${storage_volume}/snapshots/vol0"
+  # shellcheck disable=SC2034
+  for i in $(seq 90); do
+    lxc query "/1.0/storage-pools/${storage_pool}/volumes/custom/${storage_volume}/snapshots/vol0" >/dev/null 2>&1 || break
+    sleep 1
+  done
+  ! lxc query "/1.0/storage-pools/${storage_pool}/volumes/custom/${storage_volume}/snapshots/vol0" || false
+
+  lxc storage unset "${storage_pool}" volume.snapshots.expiry
+  lxc storage unset "${storage_pool}" volume.snapshots.pattern
+  lxc storage volume delete "${storage_pool}" "${storage_volume}"
+}
@@ -0,0 +1,67 @@
+test_storage_volume_block() {
+  lxd_backend=$(storage_backend "$LXD_DIR")
+  if [ "$lxd_backend" != "dir" ]; then
+    echo "==> SKIP: block custom volumes are only supported on dir"
+    return
+  fi
+
+  if ! which qemu-img >/dev/null 2>&1; then
+    echo "==> SKIP: qemu-img is required for block custom volumes"
+    return
+  fi
+
+  ensure_import_testimage
+
+  # shellcheck disable=2039
+  local storage_pool
+  storage_pool="lxdtest-$(basename "${LXD_DIR}")"
+
+  # Invalid content types are rejected
+  ! lxc storage volume create "${storage_pool}" vol1 --type=foo || false
+
+  # Block volumes get a size by default
+  lxc storage volume create "${storage_pool}" vol1 --type=block
+  lxc storage volume show "${storage_pool}" vol1 | grep -q "content_type: block"
+  lxc storage volume get "${storage_pool}" vol1 size | grep -q "10GB"
+  [ -f "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol1/root.img" ]
+
+  # Filesystem volumes remain the default
+  lxc storage volume create "${storage_pool}" vol2
+  lxc storage volume show "${storage_pool}" vol2 | grep -q "content_type: filesystem"
+  lxc storage volume delete "${storage_pool}" vol2
+
+  # Block volumes can be grown but not shrunk
+  lxc storage volume set "${storage_pool}" vol1 size 11GB
+  [ "$(stat -c %s "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol1/root.img")" = "11000000000" ]
+  ! lxc storage volume set "${storage_pool}" vol1 size 1GB || false
+
+  # Snapshots, restores and copies
+  lxc storage volume snapshot "${storage_pool}" vol1 snap0
+  [ -f "${LXD_DIR}/storage-pools/${storage_pool}/custom-snapshots/vol1/snap0/root.img" ]
+  lxc storage volume restore "${storage_pool}" vol1 snap0
+  lxc storage volume copy "${storage_pool}/vol1" "${storage_pool}/vol2"
+  lxc storage volume show "${storage_pool}" vol2 | grep -q "content_type: block"
+  lxc storage volume delete "${storage_pool}" vol2
+
+  # Block volumes cannot be attached to containers
+  lxc init testimage c1
+  ! lxc storage volume attach "${storage_pool}" vol1 c1 || false
+  lxc config device add c1 vol1 disk pool="${storage_pool}" source=vol1 path=/mnt
+  ! lxc start c1 || false
+  lxc delete -f c1
+
+  # Import of raw and qcow2 disk images
+  qemu-img create -f qcow2 "${LXD_DIR}/disk.qcow2" 1G
+  lxc storage volume import "${storage_pool}" "${LXD_DIR}/disk.qcow2" vol3 --type=block
+  lxc storage volume show "${storage_pool}" vol3 | grep -q "content_type: block"
+  [ "$(stat -c %s "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol3/root.img")" = "1073741824" ]
+  lxc storage volume delete "${storage_pool}" vol3
+
+  truncate -s 10M "${LXD_DIR}/disk.raw"
+  lxc storage volume import "${storage_pool}" "${LXD_DIR}/disk.raw" vol3 --type=block
+  lxc storage volume delete "${storage_pool}" vol3
+  rm -f "${LXD_DIR}/disk.qcow2" "${LXD_DIR}/disk.raw"
+
+  lxc storage volume delete "${storage_pool}" vol1/snap0
+  lxc storage volume delete "${storage_pool}" vol1
+}
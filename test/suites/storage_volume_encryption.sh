@@ -0,0 +1,112 @@
+test_storage_volume_encryption() {
+  lxd_backend=$(storage_backend "$LXD_DIR")
+  if [ "$lxd_backend" != "dir" ] && [ "$lxd_backend" != "lvm" ] && [ "$lxd_backend" != "ceph" ]; then
+    echo "==> SKIP: encrypted volumes are only supported on ceph, dir and lvm"
+    return
+  fi
+
+  if ! which qemu-img >/dev/null 2>&1 || ! which cryptsetup >/dev/null 2>&1; then
+    echo "==> SKIP: qemu-img and cryptsetup are required for encrypted volumes"
+    return
+  fi
+
+  # shellcheck disable=2039
+  local storage_pool
+  storage_pool="lxdtest-$(basename "${LXD_DIR}")"
+
+  if [ "$lxd_backend" != "dir" ]; then
+    test_storage_volume_encryption_block "${storage_pool}"
+    return
+  fi
+
+  # Invalid encryption types and filesystem volumes are rejected
+  ! lxc storage volume create "${storage_pool}" vol1 --type=block block.encryption=foo || false
+  ! lxc storage volume create "${storage_pool}" vol1 block.encryption=luks2 || false
+
+  # Encrypted block volumes get a LUKS header on top of their usable size
+  lxc storage volume create "${storage_pool}" vol1 --type=block block.encryption=luks2
+  cryptsetup isLuks "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol1/root.img"
+  [ "$(stat -c %s "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol1/root.img")" = "10016777216" ]
+  uuid=$(lxc storage volume get "${storage_pool}" vol1 volatile.encryption.uuid)
+  [ "$(cryptsetup luksUUID "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol1/root.img")" = "${uuid}" ]
+  [ -f "${LXD_DIR}/storage-keys/${uuid}.key" ]
+
+  # The encryption settings cannot be changed
+  ! lxc storage volume unset "${storage_pool}" vol1 block.encryption || false
+
+  # Growing keeps the header out of the usable size
+  lxc storage volume set "${storage_pool}" vol1 size 11GB
+  [ "$(stat -c %s "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol1/root.img")" = "11016777216" ]
+
+  # Snapshots and copies carry the encryption state
+  lxc storage volume snapshot "${storage_pool}" vol1 snap0
+  cryptsetup isLuks "${LXD_DIR}/storage-pools/${storage_pool}/custom-snapshots/vol1/snap0/root.img"
+  lxc storage volume copy "${storage_pool}/vol1" "${storage_pool}/vol2"
+  lxc storage volume get "${storage_pool}" vol2 block.encryption | grep -q luks2
+  cryptsetup isLuks "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol2/root.img"
+
+  # The key is removed with the last volume or snapshot using it
+  lxc storage volume delete "${storage_pool}" vol2
+  lxc storage volume delete "${storage_pool}" vol1
+  [ -f "${LXD_DIR}/storage-keys/${uuid}.key" ]
+  lxc storage volume delete "${storage_pool}" vol1/snap0
+  [ ! -e "${LXD_DIR}/storage-keys/${uuid}.key" ]
+
+  # The pool can encrypt new block volumes by default
+  lxc storage set "${storage_pool}" volume.block.encryption luks2
+  lxc storage volume create "${storage_pool}" vol1 --type=block
+  lxc storage volume get "${storage_pool}" vol1 block.encryption | grep -q luks2
+  cryptsetup isLuks "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol1/root.img"
+  lxc storage volume delete "${storage_pool}" vol1
+
+  # Imported disk images are encrypted too
+  truncate -s 10M "${LXD_DIR}/disk.raw"
+  lxc storage volume import "${storage_pool}" "${LXD_DIR}/disk.raw" vol1 --type=block
+  cryptsetup isLuks "${LXD_DIR}/storage-pools/${storage_pool}/custom/vol1/root.img"
+  lxc storage volume delete "${storage_pool}" vol1
+  rm -f "${LXD_DIR}/disk.raw"
+
+  lxc storage unset "${storage_pool}" volume.block.encryption
+  [ "$(find "${LXD_DIR}/storage-keys" -name '*.key' | wc -l)" = "0" ]
+}
+
+test_storage_volume_encryption_block() {
+  # shellcheck disable=2039
+  local storage_pool
+  storage_pool="$1"
+
+  ensure_import_testimage
+
+  # Invalid encryption types are rejected
+  ! lxc storage volume create "${storage_pool}" vol1 block.encryption=foo || false
+
+  # Encrypted volumes get a key and are unlocked when mounted
+  lxc storage volume create "${storage_pool}" vol1 block.encryption=luks2
+  [ "$(find "${LXD_DIR}/storage-keys" -name '*.key' | wc -l)" = "1" ]
+  lxc launch testimage c1
+  lxc storage volume attach "${storage_pool}" vol1 c1 /mnt
+  lxc exec c1 -- touch /mnt/foo
+  [ -e "/dev/mapper/lxd_${storage_pool}_custom_vol1" ]
+  lxc storage volume detach "${storage_pool}" vol1 c1
+  [ ! -e "/dev/mapper/lxd_${storage_pool}_custom_vol1" ]
+  lxc delete -f c1
+
+  # The encryption settings cannot be changed
+  ! lxc storage volume unset "${storage_pool}" vol1 block.encryption || false
+
+  # Snapshots and copies share the key, which is removed with the last volume using it
+  lxc storage volume snapshot "${storage_pool}" vol1 snap0
+  lxc storage volume copy "${storage_pool}/vol1" "${storage_pool}/vol2"
+  lxc storage volume delete "${storage_pool}" vol1
+  [ "$(find "${LXD_DIR}/storage-keys" -name '*.key' | wc -l)" = "1" ]
+  lxc storage volume delete "${storage_pool}" vol2
+  [ "$(find "${LXD_DIR}/storage-keys" -name '*.key' | wc -l)" = "0" ]
+
+  # The pool can encrypt new custom volumes by default
+  lxc storage set "${storage_pool}" volume.block.encryption luks2
+  lxc storage volume create "${storage_pool}" vol1
+  lxc storage volume get "${storage_pool}" vol1 block.encryption | grep -q luks2
+  [ "$(find "${LXD_DIR}/storage-keys" -name '*.key' | wc -l)" = "1" ]
+  lxc storage volume delete "${storage_pool}" vol1
+  lxc storage unset "${storage_pool}" volume.block.encryption
+}
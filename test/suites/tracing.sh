@@ -0,0 +1,39 @@
+test_tracing() {
+  # shellcheck disable=2039
+  local out port lxd_backend
+  out="${TEST_DIR}/tracing.out"
+  port="$(local_tcp_port)"
+  lxd_backend=$(storage_backend "$LXD_DIR")
+
+  # Invalid endpoints are rejected
+  ! lxc config set core.tracing_endpoint "127.0.0.1:${port}" || false
+  ! lxc config set core.tracing_endpoint "grpc://127.0.0.1:${port}" || false
+
+  # A collector stand-in recording the exports and acknowledging them
+  socat "TCP-LISTEN:${port},bind=127.0.0.1,reuseaddr,fork" SYSTEM:"timeout 1 cat >> ${out}; printf 'HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n'" &
+  socat_pid=$!
+  sleep 0.5
+
+  lxc config set core.tracing_endpoint "http://127.0.0.1:${port}"
+
+  ensure_import_testimage
+  lxc init testimage traced
+
+  # Spans are exported in batches
+  sleep 5
+
+  grep -qa "POST /1.0/\(containers\|instances\)" "${out}"
+  grep -qa "Creating container" "${out}"
+  if [ "${lxd_backend}" = "dir" ]; then
+    grep -qa "storage CreateVolume" "${out}"
+  fi
+
+  # Nothing is exported once disabled
+  lxc config unset core.tracing_endpoint
+  rm -f "${out}"
+  lxc delete traced
+  sleep 5
+  [ ! -e "${out}" ]
+
+  kill -9 "${socat_pid}" || true
+}
@@ -0,0 +1,25 @@
+test_warnings() {
+  # shellcheck disable=2039
+  local uuid
+
+  # Record a warning about an image failing to update
+  lxd sql global "INSERT INTO warnings (node_id, project_id, entity_url, uuid, type_code, status, first_seen_date, last_seen_date, updated_date, last_message, count) VALUES (1, 1, '/1.0/images/abcd', 'e0c8d8e4-4e3e-4c2c-9d37-0a6b4f6a1a3c', 16, 1, '2020-10-12 09:00:00', '2020-10-12 09:00:00', '2020-10-12 09:00:00', 'Failed to fetch image', 1)"
+  uuid="e0c8d8e4-4e3e-4c2c-9d37-0a6b4f6a1a3c"
+
+  lxc warning list | grep -q "${uuid}"
+  lxc warning show "${uuid}" | grep -q "^type: Failed to update image$"
+  lxc warning show "${uuid}" | grep -q "^status: new$"
+  my_curl "https://${LXD_ADDR}/1.0/warnings?project=default" | jq -r ".metadata[]" | grep -q "/1.0/warnings/${uuid}"
+
+  # Acknowledged warnings are hidden by default
+  lxc warning ack "${uuid}"
+  lxc warning show "${uuid}" | grep -q "^status: acknowledged$"
+  ! lxc warning list | grep -q "${uuid}" || false
+  lxc warning list --all | grep -q "${uuid}"
+
+  # Only the server can resolve warnings
+  my_curl -X PUT -d '{"status": "resolved"}' "https://${LXD_ADDR}/1.0/warnings/${uuid}" | jq -r .error_code | grep -q "^400$"
+
+  lxc warning delete "${uuid}"
+  ! lxc warning show "${uuid}" || false
+}
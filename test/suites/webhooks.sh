@@ -0,0 +1,70 @@
+test_webhooks() {
+  # shellcheck disable=2039
+  local out port
+  out="${TEST_DIR}/webhooks.out"
+  port="$(local_tcp_port)"
+
+  # Invalid webhooks are rejected
+  ! lxc webhook create broken "ftp://127.0.0.1:${port}" || false
+  ! lxc webhook create broken "http://127.0.0.1:${port}" --projects=missing || false
+
+  # An endpoint stand-in recording the deliveries and acknowledging them
+  socat "TCP-LISTEN:${port},bind=127.0.0.1,reuseaddr,fork" SYSTEM:"timeout 1 cat >> ${out}; printf 'HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n'" &
+  socat_pid=$!
+  sleep 0.5
+
+  lxc webhook create hook "http://127.0.0.1:${port}" --secret=s3cret --types=container --projects=default
+  lxc webhook list | grep -q hook
+  ! lxc webhook create hook "http://127.0.0.1:${port}" || false
+
+  ensure_import_testimage
+  lxc init testimage hooked
+  sleep 2
+
+  grep -qa "X-Lxd-Event: container-created" "${out}"
+  grep -qa "X-Lxd-Signature-256: sha256=" "${out}"
+  grep -qa '"source":"/1.0/containers/hooked"' "${out}"
+  lxc webhook show hook | grep -q "last_delivery_status: success"
+
+  # The secret is write-only
+  ! lxc webhook show hook | grep -q s3cret || false
+  ! lxc query /1.0/webhooks/hook | grep -q secret || false
+
+  # Updating the webhook without a secret keeps it
+  lxc query -X PATCH -d '{"description": "Hook"}' /1.0/webhooks/hook
+  rm -f "${out}"
+  lxc config set hooked user.foo bar
+  sleep 2
+  grep -qa "X-Lxd-Signature-256: sha256=" "${out}"
+
+  # Events not matching the filters aren't sent
+  lxc webhook edit hook <<EOF2
+url: http://127.0.0.1:${port}
+types:
+- image
+EOF2
+  lxc webhook show hook | grep -q "^- image"
+
+  rm -f "${out}"
+  lxc config set hooked user.foo bar
+  sleep 2
+  [ ! -e "${out}" ]
+
+  # Failed deliveries are recorded, client errors not being retried
+  kill -9 "${socat_pid}" || true
+  sleep 0.5
+  socat "TCP-LISTEN:${port},bind=127.0.0.1,reuseaddr,fork" SYSTEM:"timeout 1 cat > /dev/null; printf 'HTTP/1.1 410 Gone\r\nContent-Length: 0\r\n\r\n'" &
+  socat_pid=$!
+  sleep 0.5
+
+  lxc query -X PATCH -d '{"actions": ["container-deleted"], "types": []}' /1.0/webhooks/hook
+  lxc delete hooked
+  sleep 2
+  lxc webhook show hook | grep -q "last_delivery_status: failure"
+  lxc webhook show hook | grep -q "failed_deliveries: 1"
+
+  kill -9 "${socat_pid}" || true
+  lxc webhook delete hook
+  ! lxc webhook show hook || false
+  rm -f "${out}"
+}